@@ -0,0 +1,46 @@
+package encoding
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ASCIIPrintable 可打印 ASCII 字符（0x20-0x7E），便于组合自定义字符表
+var ASCIIPrintable = &unicode.RangeTable{
+	R16:         []unicode.Range16{{Lo: 0x20, Hi: 0x7E, Stride: 1}},
+	LatinOffset: 1,
+}
+
+// Alphabet 调用方预期的字符集合
+//
+// 配置到 DetectorConfig.Alphabets 后，候选评分的字符有效性检查改用这些字符表而不是内置的通用范围，
+// 适合仪器导出数据等字符分布已知的专用语料，例如只包含数字、ASCII 和希腊字母的科学数据。
+// 换行符和制表符始终视为有效。
+type Alphabet struct {
+	// Name 字符表名称
+	Name string
+
+	// Ranges 允许的 Unicode 范围（如 ASCIIPrintable、unicode.Greek）
+	Ranges []*unicode.RangeTable
+
+	// Runes 额外允许的单个字符（如 "°±µ"）
+	Runes string
+}
+
+// Contains 检查字符是否属于该字符表
+func (a Alphabet) Contains(r rune) bool {
+	return unicode.IsOneOf(a.Ranges, r) || strings.ContainsRune(a.Runes, r)
+}
+
+// inAlphabets 检查字符是否属于任一字符表
+func inAlphabets(alphabets []Alphabet, r rune) bool {
+	if r == '\n' || r == '\r' || r == '\t' {
+		return true
+	}
+	for _, alphabet := range alphabets {
+		if alphabet.Contains(r) {
+			return true
+		}
+	}
+	return false
+}
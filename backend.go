@@ -0,0 +1,63 @@
+package encoding
+
+import (
+	"github.com/saintfish/chardet"
+)
+
+// CharsetMatch 字符集检测后端返回的单个候选
+type CharsetMatch struct {
+	// Charset 字符集名称（会经过规范化映射到本包的编码常量）
+	Charset string `json:"charset"`
+
+	// Language 语言（可选）
+	Language string `json:"language,omitempty"`
+
+	// Confidence 置信度（0-100，与 chardet 保持一致）
+	Confidence int `json:"confidence"`
+}
+
+// CharsetBackend 字符集检测后端接口
+//
+// 默认使用 saintfish/chardet，可通过 DetectorConfig.Backend 替换为其他实现
+// （例如基于 ICU 的检测器或内部模型）。返回结果应按置信度降序排列。
+type CharsetBackend interface {
+	// DetectAll 返回所有可能的字符集候选
+	DetectAll(data []byte) ([]CharsetMatch, error)
+}
+
+// chardetBackend 基于 saintfish/chardet 的默认检测后端
+type chardetBackend struct{}
+
+// NewChardetBackend 创建基于 chardet 的检测后端
+func NewChardetBackend() CharsetBackend {
+	return &chardetBackend{}
+}
+
+// DetectAll 返回 chardet 检测到的所有候选
+func (b *chardetBackend) DetectAll(data []byte) ([]CharsetMatch, error) {
+	results, err := chardet.NewTextDetector().DetectAll(data)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]CharsetMatch, 0, len(results))
+	for _, result := range results {
+		matches = append(matches, CharsetMatch{
+			Charset:    result.Charset,
+			Language:   result.Language,
+			Confidence: result.Confidence,
+		})
+	}
+	return matches, nil
+}
+
+// backend 获取检测器使用的字符集检测后端
+func (d *defaultDetector) backend() CharsetBackend {
+	if d.config.Backend != nil {
+		return d.config.Backend
+	}
+	return defaultCharsetBackend
+}
+
+// defaultCharsetBackend 默认字符集检测后端
+var defaultCharsetBackend CharsetBackend = &chardetBackend{}
@@ -0,0 +1,496 @@
+package encoding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultBatchProcessor 实现 BatchProcessor 接口
+type defaultBatchProcessor struct {
+	fileProcessor FileProcessor
+	config        *ProcessorConfig
+	lifecycle     lifecycle
+}
+
+// NewBatchProcessor 创建新的批量处理器
+func NewBatchProcessor(config *ProcessorConfig) BatchProcessor {
+	if config == nil {
+		config = GetDefaultProcessorConfig()
+	}
+
+	return &defaultBatchProcessor{
+		fileProcessor: NewFileProcessor(config),
+		config:        config,
+	}
+}
+
+// ProcessFiles 按调度策略批量就地处理文件
+func (bp *defaultBatchProcessor) ProcessFiles(ctx context.Context, files []string, options *BatchOptions) (*BatchResult, error) {
+	if err := bp.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer bp.lifecycle.release()
+
+	if options == nil {
+		options = &BatchOptions{}
+	}
+	result, err := bp.processFiles(ctx, files, options)
+	return result, saveManifest(options, err)
+}
+
+// ProcessDirectory 遍历目录树，批量就地处理匹配过滤条件的文件
+func (bp *defaultBatchProcessor) ProcessDirectory(dir string, options *BatchOptions) (*BatchResult, error) {
+	return bp.ProcessDirectoryContext(context.Background(), dir, options)
+}
+
+// ProcessDirectoryContext 遍历目录树，批量就地处理匹配过滤条件的文件
+//
+// 跳过隐藏目录和目录选项文件；未设置 DirOptionsRoot 时以 dir 为目录选项的查找边界。
+// 检测器配置了 SiblingContextWeight 时，因置信度不足而失败的文件按同目录兄弟文件的主导编码重新处理。
+func (bp *defaultBatchProcessor) ProcessDirectoryContext(ctx context.Context, dir string, options *BatchOptions) (*BatchResult, error) {
+	if err := bp.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer bp.lifecycle.release()
+
+	dirOptions := BatchOptions{}
+	if options != nil {
+		dirOptions = *options
+	}
+	if dirOptions.DirOptionsRoot == "" {
+		dirOptions.DirOptionsRoot = dir
+	}
+
+	files, binaries, err := collectDirectoryFiles(ctx, longPath(dir), &dirOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := bp.processFiles(ctx, files, &dirOptions)
+	if result != nil {
+		result.Skipped = append(result.Skipped, binaries...)
+	}
+
+	if detectorConfig := bp.config.DetectorConfig; err == nil && detectorConfig != nil && detectorConfig.SiblingContextWeight > 0 {
+		start := time.Now()
+		bp.retryWithSiblingContext(result, &dirOptions, detectorConfig.SiblingContextWeight)
+		result.Duration += time.Since(start)
+	}
+	return result, saveManifest(&dirOptions, err)
+}
+
+// saveManifest 写回转换清单（包括因上下文取消而中断的处理已完成的部分），返回处理错误或写入错误
+func saveManifest(options *BatchOptions, err error) error {
+	if options.Manifest == nil {
+		return err
+	}
+	if saveErr := options.Manifest.Save(); err == nil {
+		err = saveErr
+	}
+	return err
+}
+
+// recordManifest 将就地转换成功的文件记录到转换清单（试运行、已处理和透传的文件除外；
+// 无法记录的文件下次运行时重新处理）
+func (o *BatchOptions) recordManifest(fileResult *BatchFileResult) {
+	if o.Manifest == nil || fileResult.Err != nil || o.jobFileOptions(fileResult.Job).DryRun {
+		return
+	}
+	if result := fileResult.Result; !result.AlreadyProcessed && !result.Passthrough {
+		o.Manifest.Record(fileResult.Job.Path, result)
+	}
+}
+
+// jobFileOptions 返回任务使用的文件处理选项（目录选项覆盖后的选项优先，均未设置时为默认选项）
+func (o *BatchOptions) jobFileOptions(job *BatchJob) *FileProcessOptions {
+	if job.options != nil {
+		return job.options
+	}
+	if o.FileOptions != nil {
+		return o.FileOptions
+	}
+	return defaultFileProcessOptions()
+}
+
+// collectDirectoryFiles 遍历目录树，返回通过过滤条件的普通文件，以及按 SkipBinary 跳过的二进制文件
+func collectDirectoryFiles(ctx context.Context, dir string, options *BatchOptions) (files, binaries []string, err error) {
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return &FileOperationError{Op: "walk", File: path, Err: err}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path == dir {
+				return nil
+			}
+			// 跳过隐藏目录（如 .git）和超出最大深度的目录
+			if strings.HasPrefix(info.Name(), ".") || options.exceedsDepth(dir, path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || info.Name() == DirOptionsFile {
+			return nil
+		}
+		if options.Manifest != nil && options.Manifest.isManifestFile(path) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			rel = path
+		}
+		if !options.matches(rel) {
+			return nil
+		}
+		if options.SkipBinary && isBinaryFile(path) {
+			binaries = append(binaries, displayPath(path))
+			return nil
+		}
+		files = append(files, displayPath(path))
+		return nil
+	})
+	return files, binaries, err
+}
+
+// exceedsDepth 检查子目录中的文件是否超出 MaxDepth（目录下的文件深度为 1）
+func (o *BatchOptions) exceedsDepth(root, dir string) bool {
+	if o.MaxDepth <= 0 {
+		return false
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return false
+	}
+	return strings.Count(rel, string(filepath.Separator))+2 > o.MaxDepth
+}
+
+// isBinaryFile 按文件开头的样本检查文件是否疑似二进制（无法读取时返回 false，由后续处理报告错误）
+func isBinaryFile(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	sample := make([]byte, DefaultSampleSize)
+	n, err := io.ReadFull(file, sample)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false
+	}
+	return IsProbablyBinary(sample[:n])
+}
+
+// matches 检查相对路径是否通过扩展名和 Include/Exclude 过滤
+func (o *BatchOptions) matches(rel string) bool {
+	return matchesFileFilters(rel, o.Include, o.Exclude, o.Extensions)
+}
+
+// matchesFileFilters 检查相对路径是否通过扩展名（不区分大小写，可省略点）和 Include/Exclude 过滤
+func matchesFileFilters(rel string, include, exclude, extensions []string) bool {
+	if len(extensions) > 0 {
+		ext := strings.ToLower(filepath.Ext(rel))
+		matched := false
+		for _, allowed := range extensions {
+			allowed = strings.ToLower(allowed)
+			if !strings.HasPrefix(allowed, ".") {
+				allowed = "." + allowed
+			}
+			if ext == allowed {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return (&DirOptions{Include: include, Exclude: exclude}).Allows(rel)
+}
+
+// retryWithSiblingContext 按同目录兄弟文件的主导编码重新处理因检测置信度不足而失败的文件
+func (bp *defaultBatchProcessor) retryWithSiblingContext(result *BatchResult, options *BatchOptions, weight float64) {
+	siblings := newSiblingContext()
+	for _, fileResult := range result.Results {
+		if fileResult.Err == nil && !fileResult.Result.Passthrough && !fileResult.Result.AlreadyProcessed {
+			siblings.observe(fileResult.Job.Path, fileResult.Result.SourceEncoding)
+		}
+	}
+
+	gate := bp.config.DetectorConfig
+	if gate == nil {
+		gate = GetDefaultDetectorConfig()
+	}
+	detectorConfig := *gate
+	detectorConfig.MinConfidence = 0
+	detectorConfig.MinConfidenceByEncoding = nil
+	detectorConfig.EnableCache = false // 只检测少数失败的文件，不需要缓存
+	detector := NewDetector(&detectorConfig)
+
+	for _, fileResult := range result.Results {
+		var encodingErr *EncodingError
+		if !errors.As(fileResult.Err, &encodingErr) || encodingErr.Op != OperationDetect {
+			continue
+		}
+		dominant, share := siblings.dominant(fileResult.Job.Path)
+		if dominant == "" {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(fileResult.Job.Path)
+		if err != nil {
+			continue
+		}
+		boosted, ok := boostWithContext(detector, data, nil, dominant, share, weight)
+		fileOptions := options.FileOptions
+		if fileResult.Job.options != nil {
+			fileOptions = fileResult.Job.options
+		}
+		if fileOptions == nil {
+			fileOptions = defaultFileProcessOptions()
+		}
+		if !ok || boosted.Confidence < fileOptions.MinConfidence {
+			continue
+		}
+		if threshold, ok := gate.minConfidenceOverride(boosted.Encoding); ok && boosted.Confidence < threshold {
+			continue
+		}
+
+		retryOptions := *fileOptions
+		retryOptions.SourceEncoding = boosted.Encoding
+		processed, err := bp.fileProcessor.ProcessFileInPlace(fileResult.Job.Path, &retryOptions)
+		if err != nil {
+			continue
+		}
+
+		processed.Warnings = append(processed.Warnings, Warning{
+			Code:    WarningLowConfidence,
+			Message: fmt.Sprintf("accepted %s at confidence %.2f boosted by sibling files", boosted.Encoding, boosted.Confidence),
+			File:    fileResult.Job.Path,
+		})
+		fileResult.Result, fileResult.Err, fileResult.Error = processed, nil, ""
+		fileResult.ContextBoosted = true
+		result.FailureCount--
+		result.recordSuccess(processed)
+		options.recordManifest(fileResult)
+	}
+}
+
+// recordSuccess 将成功处理的文件计入汇总
+func (r *BatchResult) recordSuccess(fileResult *FileProcessResult) {
+	r.SuccessCount++
+	if fileResult.AlreadyProcessed {
+		r.AlreadyProcessed = append(r.AlreadyProcessed, fileResult.InputFile)
+		return
+	}
+	r.TotalBytes += fileResult.BytesProcessed
+	r.LostRunes = MergeLostRunes(r.LostRunes, fileResult.LostRunes)
+	if r.SourceEncodings == nil {
+		r.SourceEncodings = make(map[string]int)
+	}
+	r.SourceEncodings[fileResult.SourceEncoding]++
+	if fileResult.Passthrough {
+		r.Passthrough = append(r.Passthrough, fileResult.InputFile)
+	}
+	r.Warnings = append(r.Warnings, fileResult.Warnings...)
+}
+
+// processFiles 批量就地处理文件（调用方负责获取生命周期）
+func (bp *defaultBatchProcessor) processFiles(ctx context.Context, files []string, options *BatchOptions) (*BatchResult, error) {
+	start := time.Now()
+	result := &BatchResult{}
+	var mutex sync.Mutex
+	record := func(fileResult *BatchFileResult) {
+		options.recordManifest(fileResult)
+		mutex.Lock()
+		result.Results = append(result.Results, fileResult)
+		if errors.Is(fileResult.Err, ErrBinaryFile) || errors.Is(fileResult.Err, ErrConversionInProgress) {
+			// 拒绝转换的二进制文件和其他工作者正在转换的文件记为跳过而不是失败
+			fileResult.Error = fileResult.Err.Error()
+			result.Skipped = append(result.Skipped, fileResult.Job.Path)
+		} else if fileResult.Err != nil {
+			fileResult.Error = fileResult.Err.Error()
+			result.FailureCount++
+		} else {
+			result.recordSuccess(fileResult.Result)
+		}
+		mutex.Unlock()
+
+		if options.OnFileDone != nil {
+			options.OnFileDone(fileResult)
+		}
+	}
+	cancel := func(job *BatchJob) {
+		mutex.Lock()
+		result.Cancelled = append(result.Cancelled, job.Path)
+		mutex.Unlock()
+	}
+
+	var resolver *dirOptionsResolver
+	if options.DirOptionsRoot != "" {
+		resolver = newDirOptionsResolver(options.DirOptionsRoot)
+	}
+
+	// 收集文件信息，无法访问的文件直接记为失败
+	jobs := make([]*BatchJob, 0, len(files))
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			record(&BatchFileResult{
+				Job: &BatchJob{Path: path},
+				Err: &FileOperationError{Op: "stat", File: path, Err: err},
+			})
+			continue
+		}
+		job := &BatchJob{Path: path, Size: info.Size(), ModTime: info.ModTime()}
+
+		// 应用文件所在子树的目录选项
+		if resolver != nil {
+			dirOptions, err := resolver.resolve(path)
+			if err != nil {
+				record(&BatchFileResult{Job: job, Err: err})
+				continue
+			}
+			if !dirOptions.Allows(path) {
+				result.Skipped = append(result.Skipped, path)
+				continue
+			}
+			if dirOptions != nil {
+				job.options = dirOptions.Apply(options.FileOptions)
+			}
+		}
+
+		// 清单中记录的未改动文件直接跳过，不读取内容也不重新检测
+		if options.Manifest != nil {
+			if entry := options.Manifest.Lookup(path, info, options.jobFileOptions(job).TargetEncoding); entry != nil {
+				record(&BatchFileResult{Job: job, Result: &FileProcessResult{
+					InputFile:        path,
+					OutputFile:       path,
+					SourceEncoding:   entry.SourceEncoding,
+					TargetEncoding:   entry.TargetEncoding,
+					AlreadyProcessed: true,
+				}})
+				continue
+			}
+		}
+		jobs = append(jobs, job)
+	}
+
+	orderBatchJobs(jobs, options)
+
+	// 超大文件交由专用工作者处理，避免阻塞大量小文件
+	var normal, huge []*BatchJob
+	for _, job := range jobs {
+		if options.HugeFileThreshold > 0 && job.Size >= options.HugeFileThreshold {
+			huge = append(huge, job)
+		} else {
+			normal = append(normal, job)
+		}
+	}
+
+	concurrency := options.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	bp.runWorkers(ctx, &wg, normal, concurrency, options, record, cancel)
+	bp.runWorkers(ctx, &wg, huge, 1, options, record, cancel)
+	wg.Wait()
+
+	result.Duration = time.Since(start)
+	return result, ctx.Err()
+}
+
+// runWorkers 启动指定数量的工作者按顺序消费任务队列（上下文取消后剩余任务记为已取消）
+func (bp *defaultBatchProcessor) runWorkers(ctx context.Context, wg *sync.WaitGroup, jobs []*BatchJob, workers int, options *BatchOptions, record func(*BatchFileResult), cancel func(*BatchJob)) {
+	if len(jobs) == 0 {
+		return
+	}
+
+	queue := make(chan *BatchJob, len(jobs))
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				if ctx.Err() != nil {
+					cancel(job)
+					continue
+				}
+				fileOptions := options.FileOptions
+				if job.options != nil {
+					fileOptions = job.options
+				}
+				record(bp.processJob(job, fileOptions))
+			}
+		}()
+	}
+}
+
+// processJob 就地处理单个文件，将处理过程中的 panic 转换为该文件的错误
+func (bp *defaultBatchProcessor) processJob(job *BatchJob, options *FileProcessOptions) (fileResult *BatchFileResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			fileResult = &BatchFileResult{
+				Job: job,
+				Err: &FileOperationError{Op: "process", File: job.Path, Err: fmt.Errorf("panic: %v", r)},
+			}
+		}
+	}()
+
+	result, err := bp.fileProcessor.ProcessFileInPlace(job.Path, options)
+	return &BatchFileResult{Job: job, Result: result, Err: err}
+}
+
+// orderBatchJobs 按调度策略排序任务（稳定排序，相同键保持输入顺序）
+func orderBatchJobs(jobs []*BatchJob, options *BatchOptions) {
+	less := options.Less
+	if less == nil {
+		switch options.Order {
+		case BatchOrderSmallestFirst:
+			less = func(a, b *BatchJob) bool { return a.Size < b.Size }
+		case BatchOrderLargestFirst:
+			less = func(a, b *BatchJob) bool { return a.Size > b.Size }
+		case BatchOrderOldestFirst:
+			less = func(a, b *BatchJob) bool { return a.ModTime.Before(b.ModTime) }
+		case BatchOrderNewestFirst:
+			less = func(a, b *BatchJob) bool { return a.ModTime.After(b.ModTime) }
+		default:
+			return
+		}
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return less(jobs[i], jobs[j])
+	})
+}
+
+// Drain 停止接受新的批量任务，等待进行中的批量任务完成后关闭文件处理器
+func (bp *defaultBatchProcessor) Drain(ctx context.Context) error {
+	return bp.lifecycle.drain(ctx, func() {
+		closeComponent(ctx, bp.fileProcessor)
+	})
+}
+
+// Close 关闭批量处理器并等待进行中的批量任务完成
+func (bp *defaultBatchProcessor) Close() error {
+	return bp.Drain(context.Background())
+}
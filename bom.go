@@ -0,0 +1,333 @@
+package encoding
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mirbf/encoding-processor/detector"
+	"golang.org/x/text/transform"
+)
+
+// BOMInfo 带 BOM 的文件信息
+type BOMInfo struct {
+	// Path 文件路径
+	Path string `json:"path"`
+
+	// Encoding BOM 对应的编码
+	Encoding string `json:"encoding"`
+
+	// Size BOM 的字节数
+	Size int `json:"size"`
+}
+
+// FindBOMs 递归列出目录中以 BOM 开头的文件
+//
+// 与迁移计划一致，跳过隐藏目录（如 .git）和非普通文件。
+// 不可见的 BOM 经常导致 shell 脚本和 CSV 解析出错，可配合 FileProcessOptions.BOMPolicy 批量去除。
+func FindBOMs(dir string) ([]BOMInfo, error) {
+	var found []BOMInfo
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return &FileOperationError{Op: "walk", File: path, Err: err}
+		}
+		if info.IsDir() {
+			if path != dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		head, err := readHead(path, 4)
+		if err != nil {
+			return &FileOperationError{Op: "read", File: path, Err: err}
+		}
+		if encoding, size := detector.BOM(head); encoding != "" {
+			found = append(found, BOMInfo{Path: path, Encoding: encoding, Size: size})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// readHead 读取文件开头最多 n 个字节
+func readHead(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, n)
+	read, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return head[:read], nil
+}
+
+// encodedBOM 返回指定 Unicode 编码的 BOM 字节（非 Unicode 编码返回 nil）
+func encodedBOM(encodingName string) []byte {
+	switch encodingName {
+	case EncodingUTF8, EncodingUTF8BOM:
+		return utf8BOM
+	case EncodingUTF16, EncodingUTF16BE:
+		return []byte{0xFE, 0xFF}
+	case EncodingUTF16LE:
+		return []byte{0xFF, 0xFE}
+	case EncodingUTF32, EncodingUTF32BE:
+		return []byte{0x00, 0x00, 0xFE, 0xFF}
+	case EncodingUTF32LE:
+		return []byte{0xFF, 0xFE, 0x00, 0x00}
+	default:
+		return nil
+	}
+}
+
+// bomlessEncodingName 返回自行写入 BOM 的编码（UTF-8-BOM、UTF-16、UTF-32）对应的不写 BOM 的编码，
+// 其他编码返回空字符串
+func bomlessEncodingName(encodingName string) string {
+	switch encodingName {
+	case EncodingUTF8BOM:
+		return EncodingUTF8
+	case EncodingUTF16:
+		return EncodingUTF16BE
+	case EncodingUTF32:
+		return EncodingUTF32BE
+	default:
+		return ""
+	}
+}
+
+// changesBOM 检查 BOM 策略是否会修改数据
+func changesBOM(policy string) bool {
+	return policy == BOMStrip || policy == BOMAdd
+}
+
+// applyBOMPolicy 按 BOM 策略去除或补充指定编码数据开头的 BOM（不修改原切片）
+func applyBOMPolicy(data []byte, encodingName, policy string) []byte {
+	bom := encodedBOM(encodingName)
+	if bom == nil {
+		return data
+	}
+
+	switch policy {
+	case BOMStrip:
+		return bytes.TrimPrefix(data, bom)
+	case BOMAdd:
+		if !bytes.HasPrefix(data, bom) {
+			result := make([]byte, 0, len(bom)+len(data))
+			return append(append(result, bom...), data...)
+		}
+	}
+	return data
+}
+
+// applyBOMPolicyReader 按 BOM 策略包装读取器，去除或补充数据开头的 BOM
+func applyBOMPolicyReader(r io.Reader, encodingName, policy string) io.Reader {
+	bom := encodedBOM(encodingName)
+	if bom == nil || !changesBOM(policy) {
+		return r
+	}
+
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(len(bom))
+	hasBOM := bytes.Equal(head, bom)
+
+	switch {
+	case policy == BOMStrip && hasBOM:
+		br.Discard(len(bom))
+	case policy == BOMAdd && !hasBOM:
+		return io.MultiReader(bytes.NewReader(bom), br)
+	}
+	return br
+}
+
+// bomPolicy 返回转换器生效的 BOM 策略（未设置 BOMPolicy 时按 PreserveBOM 决定保留或去除）
+func (c *defaultConverter) bomPolicy() string {
+	if c.config.BOMPolicy != "" {
+		return c.config.BOMPolicy
+	}
+	if c.config.PreserveBOM {
+		return BOMPreserve
+	}
+	return BOMStrip
+}
+
+// withBOMPolicy 返回使用指定 BOM 策略的转换器副本（策略为空或与当前相同时返回自身）
+func (c *defaultConverter) withBOMPolicy(policy string) *defaultConverter {
+	if policy == "" || policy == c.bomPolicy() {
+		return c
+	}
+
+	config := *c.config
+	config.BOMPolicy = policy
+	return &defaultConverter{config: &config, pool: c.pool, ctx: c.ctx}
+}
+
+// splitSourceBOM 去除源数据开头的 BOM，返回去除后的数据以及源数据是否带 BOM
+//
+// UTF-8-BOM 以及不区分字节序的 UTF-16、UTF-32 的 BOM 属于编码本身而不是内容，不视为带 BOM；
+// 其中 UTF-16、UTF-32 由解码器按 BOM 确定字节序，因此保留 BOM 交给解码器处理。
+func (c *defaultConverter) splitSourceBOM(data []byte, from string) ([]byte, bool) {
+	from = c.resolveName(from)
+	if from == EncodingUTF16 || from == EncodingUTF32 {
+		return data, false
+	}
+	if bom := encodedBOM(from); bom != nil && bytes.HasPrefix(data, bom) {
+		return data[len(bom):], from != EncodingUTF8BOM
+	}
+	return data, false
+}
+
+// applyOutputBOM 按 BOM 策略处理转换结果开头的 BOM，trace 不为 nil 时记录 BOM 的去除和补充
+//
+// 非 Unicode 目标编码无法表示 BOM，源数据的 BOM 总是被去除；UTF-8-BOM 以及不区分字节序的
+// UTF-16、UTF-32 依靠 BOM 标识编码，总是带 BOM。
+func (c *defaultConverter) applyOutputBOM(result []byte, to string, hadBOM bool, trace *conversionTrace) []byte {
+	to = c.resolveName(to)
+	bom := encodedBOM(to)
+	if bom != nil {
+		has := bytes.HasPrefix(result, bom)
+		switch want := c.wantsOutputBOM(to, hadBOM, has); {
+		case has && !want:
+			result = result[len(bom):]
+		case !has && want:
+			withBOM := make([]byte, 0, len(bom)+len(result))
+			result = append(append(withBOM, bom...), result...)
+		}
+	}
+
+	if trace != nil {
+		has := bom != nil && bytes.HasPrefix(result, bom)
+		trace.bomStripped = hadBOM && !has
+		trace.bomAdded = !hadBOM && has
+	}
+	return result
+}
+
+// wantsOutputBOM 按 BOM 策略判断转换结果开头是否应带目标编码（已解析的规范名称）的 BOM，has 为结果是否已带 BOM
+func (c *defaultConverter) wantsOutputBOM(to string, hadBOM, has bool) bool {
+	required := to == EncodingUTF8BOM || to == EncodingUTF16 || to == EncodingUTF32
+	policy := c.bomPolicy()
+	if has {
+		return required || policy != BOMStrip
+	}
+	return required || policy == BOMAdd || (policy == BOMPreserve && hadBOM)
+}
+
+// withStreamBOM 为流式转换器加上与 traceConversion 相同的 BOM 处理：去除输入开头的源编码 BOM，
+// 按 BOM 策略去除或补充输出开头的目标编码 BOM（transformer 为 nil 时表示直接透传）
+func (c *defaultConverter) withStreamBOM(transformer transform.Transformer, from, to string, trace *conversionTrace) transform.Transformer {
+	from, to = c.resolveName(from), c.resolveName(to)
+	state := &streamBOMState{}
+	var chain []transform.Transformer
+	if bom := encodedBOM(from); bom != nil && from != EncodingUTF16 && from != EncodingUTF32 {
+		chain = append(chain, &sourceBOMStripper{bom: bom, counts: from != EncodingUTF8BOM, state: state})
+	}
+	if transformer != nil {
+		chain = append(chain, transformer)
+	}
+	if bom := encodedBOM(to); bom != nil {
+		chain = append(chain, &targetBOMTransformer{converter: c, bom: bom, to: to, state: state, trace: trace})
+	}
+
+	switch len(chain) {
+	case 0:
+		return nil
+	case 1:
+		return chain[0]
+	default:
+		return transform.Chain(chain...)
+	}
+}
+
+// streamBOMState 流式 BOM 处理的共享状态
+type streamBOMState struct {
+	hadBOM bool // 源数据是否带 BOM（与 splitSourceBOM 的含义相同）
+}
+
+// sourceBOMStripper 去除输入开头的源编码 BOM
+type sourceBOMStripper struct {
+	bom    []byte
+	counts bool // 去除的 BOM 是否视为源数据带 BOM（UTF-8-BOM 的 BOM 属于编码本身）
+	state  *streamBOMState
+	done   bool
+}
+
+func (t *sourceBOMStripper) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	if !t.done {
+		if !atEOF && len(src) < len(t.bom) && bytes.HasPrefix(t.bom, src) {
+			return 0, 0, transform.ErrShortSrc
+		}
+		t.done = true
+		if bytes.HasPrefix(src, t.bom) {
+			t.state.hadBOM = t.counts
+			nSrc = len(t.bom)
+		}
+	}
+	n := copy(dst, src[nSrc:])
+	nDst, nSrc = n, nSrc+n
+	if nSrc < len(src) {
+		err = transform.ErrShortDst
+	}
+	return nDst, nSrc, err
+}
+
+func (t *sourceBOMStripper) Reset() {
+	t.done = false
+	t.state.hadBOM = false
+}
+
+// targetBOMTransformer 按 BOM 策略去除或补充输出开头的目标编码 BOM，并将 BOM 的处理情况记录到 trace
+type targetBOMTransformer struct {
+	converter *defaultConverter
+	bom       []byte
+	to        string
+	state     *streamBOMState
+	trace     *conversionTrace
+	done      bool
+}
+
+func (t *targetBOMTransformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	if !t.done {
+		if !atEOF && len(src) < len(t.bom) && bytes.HasPrefix(t.bom, src) {
+			return 0, 0, transform.ErrShortSrc
+		}
+		has := bytes.HasPrefix(src, t.bom)
+		want := t.converter.wantsOutputBOM(t.to, t.state.hadBOM, has)
+		if want && len(dst) < len(t.bom) {
+			return 0, 0, transform.ErrShortDst
+		}
+		t.done = true
+		if has {
+			nSrc = len(t.bom)
+		}
+		if want {
+			nDst = copy(dst, t.bom)
+		}
+		if t.trace != nil {
+			t.trace.bomStripped = t.state.hadBOM && !want
+			t.trace.bomAdded = !t.state.hadBOM && want
+		}
+	}
+	n := copy(dst[nDst:], src[nSrc:])
+	nDst, nSrc = nDst+n, nSrc+n
+	if nSrc < len(src) {
+		err = transform.ErrShortDst
+	}
+	return nDst, nSrc, err
+}
+
+func (t *targetBOMTransformer) Reset() {
+	t.done = false
+}
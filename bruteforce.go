@@ -0,0 +1,138 @@
+package encoding
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mirbf/encoding-processor/converter"
+	"golang.org/x/text/transform"
+)
+
+// DefaultBruteForcePreviewLength BruteForceDecode 预览的最大字符数
+const DefaultBruteForcePreviewLength = 80
+
+// CandidateOutput 按某个编码解码数据得到的候选输出
+type CandidateOutput struct {
+	// Encoding 解码使用的编码
+	Encoding string `json:"encoding"`
+
+	// Equivalent 解码结果完全相同的其他编码（如 GBK 与 GB2312、无高位字节时的各单字节编码）
+	Equivalent []string `json:"equivalent,omitempty"`
+
+	// Text 解码得到的完整文本（无效字节序列替换为 U+FFFD）
+	Text string `json:"text"`
+
+	// Preview 文本开头的预览（最多 DefaultBruteForcePreviewLength 个字符，控制字符替换为 "."）
+	Preview string `json:"preview"`
+
+	// Output 文本按目标编码编码后的数据，目标编码无法表示的字符替换为 "?"
+	Output []byte `json:"-"`
+
+	// InvalidSequences 解码时遇到的无效字节序列数
+	InvalidSequences int `json:"invalid_sequences"`
+
+	// LostRunes 目标编码无法表示而被替换的字符及其次数
+	LostRunes map[string]int64 `json:"lost_runes,omitempty"`
+
+	// Score 检测引擎对解码文本的评分
+	Score ScoreBreakdown `json:"score"`
+}
+
+// BruteForceDecode 按全部支持的编码（包括已注册的编码）逐一解码数据，按评分排序返回全部候选输出
+//
+// 用于取证场景下恢复单个损坏的重要字符串：不做任何取舍，由调用方比对各候选的预览。
+// 没有无效字节序列的候选排在前面，其次按检测引擎的综合得分降序；解码结果相同的编码合并为一个候选，
+// 其余名称记录在 Equivalent 中。target 为输出编码（为空时使用 UTF-8）。数据为空时返回 nil。
+func BruteForceDecode(data []byte, target string) []CandidateOutput {
+	if len(data) == 0 {
+		return nil
+	}
+	if target == "" {
+		target = EncodingUTF8
+	}
+
+	d := newTransientDetector()
+	confidences := make(map[string]float64)
+	if matches, err := d.backend().DetectAll(data); err == nil {
+		for _, match := range matches {
+			name := d.normalizeEncodingName(match.Charset)
+			confidences[name] = max(confidences[name], float64(match.Confidence)/100.0)
+		}
+	}
+
+	class := d.contentClass(data)
+	conv := NewConverter().(*defaultConverter)
+	var outputs []CandidateOutput
+	seen := make(map[string]int)
+	for _, name := range converter.Names() {
+		text, invalid, ok := bruteForceDecode(data, name)
+		if !ok {
+			continue
+		}
+		if i, ok := seen[text]; ok {
+			outputs[i].Equivalent = append(outputs[i].Equivalent, name)
+			continue
+		}
+
+		candidate := &DetectionCandidate{Encoding: name, Confidence: confidences[name], Method: MethodBruteForce}
+		d.scoreDecoded(data, candidate, class, text)
+		output := CandidateOutput{
+			Encoding:         name,
+			Text:             text,
+			Preview:          bruteForcePreview(text),
+			InvalidSequences: invalid,
+			Score:            candidate.Score,
+		}
+		if result, err := conv.ConvertWithOptions([]byte(text), EncodingUTF8, target, nil); err == nil {
+			output.Output, output.LostRunes = result.Data, result.LostRunes
+		}
+		seen[text] = len(outputs)
+		outputs = append(outputs, output)
+	}
+
+	sort.SliceStable(outputs, func(i, j int) bool {
+		a, b := outputs[i], outputs[j]
+		if (a.InvalidSequences == 0) != (b.InvalidSequences == 0) {
+			return a.InvalidSequences == 0
+		}
+		if a.Score.Total != b.Score.Total {
+			return a.Score.Total > b.Score.Total
+		}
+		return a.InvalidSequences < b.InvalidSequences
+	})
+	return outputs
+}
+
+// bruteForceDecode 按编码宽松解码数据，返回文本和新出现的 U+FFFD 数量（即无效字节序列数）
+func bruteForceDecode(data []byte, encodingName string) (string, int, bool) {
+	enc, err := converter.Lookup(encodingName)
+	if err != nil {
+		return "", 0, false
+	}
+	decoded, _, err := transform.Bytes(enc.NewDecoder(), data)
+	if err != nil || !utf8.Valid(decoded) {
+		return "", 0, false
+	}
+	text := string(decoded)
+	invalid := strings.Count(text, string(utf8.RuneError)) - strings.Count(string(data), string(utf8.RuneError))
+	return text, max(invalid, 0), true
+}
+
+// bruteForcePreview 返回文本开头的预览，控制字符（换行和制表符除外）替换为 "."
+func bruteForcePreview(text string) string {
+	var b strings.Builder
+	count := 0
+	for _, r := range text {
+		if count == DefaultBruteForcePreviewLength {
+			b.WriteString("…")
+			break
+		}
+		if r < 0x20 && r != '\n' && r != '\t' || r == 0x7F {
+			r = '.'
+		}
+		b.WriteRune(r)
+		count++
+	}
+	return b.String()
+}
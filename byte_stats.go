@@ -0,0 +1,213 @@
+package encoding
+
+import (
+	"encoding/binary"
+	"math"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mirbf/encoding-processor/detector"
+)
+
+// 字节统计推断的文字族
+const (
+	ScriptFamilyASCII    = "ascii"    // 纯 ASCII 文本
+	ScriptFamilyUnicode  = "unicode"  // 有效的 UTF-8 多字节文本
+	ScriptFamilyUTF16    = "utf16"    // NUL 字节集中在同一奇偶位置，疑似 UTF-16
+	ScriptFamilyCJK      = "cjk"      // 双字节中日韩编码（GBK、Big5、EUC 等）
+	ScriptFamilyCyrillic = "cyrillic" // 高位字节集中在 0xC0-0xFF，疑似西里尔单字节编码
+	ScriptFamilyLatin    = "latin"    // 零散高位字节，疑似西欧单字节编码
+	ScriptFamilyBinary   = "binary"   // 大量控制字符，疑似二进制数据
+)
+
+// 按熵判断二进制数据的阈值（文本即使是双字节中文编码，熵通常也低于 7）
+const (
+	binaryEntropyThreshold = 7.5 // 每字节比特数
+	binaryEntropyMinSize   = 512 // 样本过短时熵偏低，不按熵判断
+)
+
+// ByteStats 字节分布统计
+type ByteStats struct {
+	// Total 总字节数
+	Total int `json:"total"`
+
+	// Histogram 各字节值出现次数
+	Histogram [256]int `json:"histogram"`
+
+	// Printable 可打印 ASCII 字节数（0x20-0x7E）
+	Printable int `json:"printable"`
+
+	// Whitespace 换行、回车和制表符字节数
+	Whitespace int `json:"whitespace"`
+
+	// Control 其他 ASCII 控制字节数（不含 NUL）
+	Control int `json:"control"`
+
+	// NULCount NUL 字节数
+	NULCount int `json:"nul_count"`
+
+	// NULEven 偶数偏移处的 NUL 字节数（与 NULOdd 对比可判断 UTF-16 字节序）
+	NULEven int `json:"nul_even"`
+
+	// NULOdd 奇数偏移处的 NUL 字节数
+	NULOdd int `json:"nul_odd"`
+
+	// HighBit 高位字节数（0x80-0xFF）
+	HighBit int `json:"high_bit"`
+
+	// C1Range C1 控制区字节数（0x80-0x9F）
+	C1Range int `json:"c1_range"`
+
+	// CJKRange 双字节中文编码常用区字节数（0xA1-0xFE）
+	CJKRange int `json:"cjk_range"`
+
+	// UpperHighRange 高位上半区字节数（0xC0-0xFF）
+	UpperHighRange int `json:"upper_high_range"`
+
+	// HighBitRatio 高位字节占比
+	HighBitRatio float64 `json:"high_bit_ratio"`
+
+	// CJKRatio 中文编码常用区字节占比
+	CJKRatio float64 `json:"cjk_ratio"`
+
+	// Entropy 字节分布的香农熵（比特/字节，0-8；压缩或加密数据接近 8）
+	Entropy float64 `json:"entropy"`
+
+	// ValidUTF8 是否为有效的 UTF-8
+	ValidUTF8 bool `json:"valid_utf8"`
+
+	// ScriptFamilies 推断的可能文字族（按可能性降序）
+	ScriptFamilies []string `json:"script_families,omitempty"`
+}
+
+// AnalyzeBytes 统计数据的字节分布并推断可能的文字族
+//
+// 结果可用于编写自定义检测规则或排查误检测。
+func AnalyzeBytes(data []byte) *ByteStats {
+	stats := &ByteStats{Total: len(data)}
+	if len(data) == 0 {
+		return stats
+	}
+
+	for i, b := range data {
+		stats.Histogram[b]++
+		switch {
+		case b == 0x00:
+			stats.NULCount++
+			if i%2 == 0 {
+				stats.NULEven++
+			} else {
+				stats.NULOdd++
+			}
+		case b == '\n' || b == '\r' || b == '\t':
+			stats.Whitespace++
+		case b < 0x20 || b == 0x7F:
+			stats.Control++
+		case b < 0x80:
+			stats.Printable++
+		default:
+			stats.HighBit++
+			if b <= 0x9F {
+				stats.C1Range++
+			}
+			if b >= 0xA1 && b <= 0xFE {
+				stats.CJKRange++
+			}
+			if b >= 0xC0 {
+				stats.UpperHighRange++
+			}
+		}
+	}
+
+	total := float64(stats.Total)
+	stats.HighBitRatio = float64(stats.HighBit) / total
+	stats.CJKRatio = float64(stats.CJKRange) / total
+	for _, count := range stats.Histogram {
+		if count > 0 {
+			p := float64(count) / total
+			stats.Entropy -= p * math.Log2(p)
+		}
+	}
+	stats.ValidUTF8 = utf8.Valid(data)
+	stats.ScriptFamilies = stats.scriptFamilies()
+
+	return stats
+}
+
+// IsProbablyBinary 按字节分布判断数据是否疑似二进制（图片、压缩包、可执行文件等）
+//
+// 有 BOM 的数据、ISO-2022-KR 等带转义序列的 7 位编码（SO/SI 控制字节）和按 4 字节对齐的
+// 无 BOM UTF-32 视为文本；含有不符合 UTF-16 分布的 NUL 字节或控制字符占比过高时视为二进制；
+// 数据足够长、不是有效的 UTF-8 且熵接近每字节 8 比特时视为压缩或加密数据。
+// 传入文件开头的样本（如 DefaultSampleSize 字节）即可。
+func IsProbablyBinary(data []byte) bool {
+	if bomEncoding, _ := detector.BOM(data); bomEncoding != "" {
+		return false
+	}
+	if detector.EscapeSequence(data) != "" || isUTF32Text(data) {
+		return false
+	}
+	stats := AnalyzeBytes(data)
+	if len(stats.ScriptFamilies) > 0 && stats.ScriptFamilies[0] == ScriptFamilyBinary {
+		return true
+	}
+	return !stats.ValidUTF8 && stats.Total >= binaryEntropyMinSize && stats.Entropy >= binaryEntropyThreshold
+}
+
+// isUTF32Text 检查数据是否由 UTF-32LE 或 UTF-32BE 代码单元组成（每个 4 字节单元都是非 NUL 的有效码点）
+func isUTF32Text(data []byte) bool {
+	if len(data) == 0 || len(data)%4 != 0 {
+		return false
+	}
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		valid := true
+		for i := 0; i < len(data) && valid; i += 4 {
+			r := order.Uint32(data[i:])
+			valid = r != 0 && r <= unicode.MaxRune && (r < 0xD800 || r > 0xDFFF)
+		}
+		if valid {
+			return true
+		}
+	}
+	return false
+}
+
+// scriptFamilies 根据字节分布推断可能的文字族
+func (s *ByteStats) scriptFamilies() []string {
+	var families []string
+	total := float64(s.Total)
+
+	// UTF-16 文本中 NUL 集中出现在同一奇偶位置
+	dominant, other := s.NULEven, s.NULOdd
+	if other > dominant {
+		dominant, other = other, dominant
+	}
+	if float64(dominant)/(total/2) > 0.3 && float64(other) <= float64(dominant)*0.1 {
+		families = append(families, ScriptFamilyUTF16)
+	}
+	if float64(s.Control)/total > 0.1 || (s.NULCount > 0 && len(families) == 0) {
+		families = append(families, ScriptFamilyBinary)
+	}
+
+	if s.HighBit == 0 {
+		if len(families) == 0 {
+			families = append(families, ScriptFamilyASCII)
+		}
+		return families
+	}
+
+	if s.ValidUTF8 {
+		families = append(families, ScriptFamilyUnicode)
+	}
+	if s.CJKRatio > 0.3 {
+		families = append(families, ScriptFamilyCJK)
+	}
+	if float64(s.UpperHighRange)/float64(s.HighBit) > 0.7 && s.HighBitRatio > 0.3 {
+		families = append(families, ScriptFamilyCyrillic)
+	}
+	if s.HighBitRatio < 0.3 {
+		families = append(families, ScriptFamilyLatin)
+	}
+
+	return families
+}
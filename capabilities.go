@@ -0,0 +1,62 @@
+package encoding
+
+import (
+	"runtime"
+
+	"github.com/mirbf/encoding-processor/converter"
+)
+
+// 检测引擎（CapabilitiesReport.Engines）
+const (
+	EngineChardet = "chardet" // 内置的 saintfish/chardet 统计检测
+	EngineCustom  = "custom"  // 通过 DetectorConfig.Backend 接入的自定义检测后端
+)
+
+// 可选功能（CapabilitiesReport.Features）
+const (
+	FeatureCharsetTag         = "charset_tag"         // 读写文件的编码扩展属性（Linux、macOS）
+	FeatureParallelConversion = "parallel_conversion" // 大数据分块并行转换（GOMAXPROCS 大于 1）
+)
+
+// CapabilitiesReport 库的版本和能力
+type CapabilitiesReport struct {
+	// Version 库版本号
+	Version string `json:"version"`
+
+	// DetectionSchemaVersion DetectionResult JSON 序列化格式的 schema 版本
+	DetectionSchemaVersion int `json:"detection_schema_version"`
+
+	// GoVersion 构建使用的 Go 版本
+	GoVersion string `json:"go_version"`
+
+	// Encodings 支持转换的编码（包括通过 RegisterEncoding 注册的编码），按名称排序
+	Encodings []string `json:"encodings"`
+
+	// Engines 可用的检测引擎（Engine* 常量）
+	Engines []string `json:"engines"`
+
+	// Features 当前构建和运行环境下启用的可选功能（Feature* 常量）
+	Features []string `json:"features"`
+}
+
+// Capabilities 返回库的版本、支持的编码、检测引擎和启用的可选功能
+//
+// 用于服务启动时记录内嵌转码器的能力，或通过接口提供给远程调用方协商功能。
+// 编码列表在调用时生成，反映此前通过 RegisterEncoding 注册的编码。
+func Capabilities() *CapabilitiesReport {
+	report := &CapabilitiesReport{
+		Version:                Version,
+		DetectionSchemaVersion: DetectionSchemaVersion,
+		GoVersion:              runtime.Version(),
+		Encodings:              converter.Names(),
+		Engines:                []string{EngineChardet, EngineCustom},
+		Features:               []string{},
+	}
+	if charsetTagSupported {
+		report.Features = append(report.Features, FeatureCharsetTag)
+	}
+	if runtime.GOMAXPROCS(0) > 1 {
+		report.Features = append(report.Features, FeatureParallelConversion)
+	}
+	return report
+}
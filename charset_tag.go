@@ -0,0 +1,151 @@
+package encoding
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 编码扩展属性名称
+const (
+	XattrCharset           = "user.charset"           // Linux（freedesktop 约定，值为 IANA 字符集名称）
+	XattrAppleTextEncoding = "com.apple.TextEncoding" // macOS（值为 "名称;CFStringEncoding"，TextEdit 等编辑器使用）
+)
+
+// cfStringEncodings 编码名称到 macOS CFStringEncoding 编号的映射
+var cfStringEncodings = map[string]uint32{
+	EncodingUTF8:        0x08000100,
+	EncodingUTF16:       0x00000100,
+	EncodingUTF16BE:     0x10000100,
+	EncodingUTF16LE:     0x14000100,
+	EncodingUTF32:       0x0C000100,
+	EncodingUTF32BE:     0x18000100,
+	EncodingUTF32LE:     0x1C000100,
+	EncodingGBK:         0x0631,
+	EncodingGB2312:      0x0930,
+	EncodingGB18030:     0x0632,
+	EncodingBIG5:        0x0A03,
+	EncodingShiftJIS:    0x0A01,
+	EncodingEUCJP:       0x0920,
+	EncodingEUCKR:       0x0940,
+	EncodingISO88591:    0x0201,
+	EncodingISO88592:    0x0202,
+	EncodingISO88593:    0x0203,
+	EncodingISO88594:    0x0204,
+	EncodingISO88595:    0x0205,
+	EncodingISO88596:    0x0206,
+	EncodingISO88597:    0x0207,
+	EncodingISO88598:    0x0208,
+	EncodingISO88599:    0x0209,
+	EncodingISO885910:   0x020A,
+	EncodingISO885913:   0x020D,
+	EncodingISO885914:   0x020E,
+	EncodingISO885915:   0x020F,
+	EncodingISO885916:   0x0210,
+	EncodingWindows874:  0x041D,
+	EncodingWindows1250: 0x0501,
+	EncodingWindows1251: 0x0502,
+	EncodingWindows1252: 0x0500,
+	EncodingWindows1253: 0x0503,
+	EncodingWindows1254: 0x0504,
+	EncodingWindows1255: 0x0505,
+	EncodingWindows1256: 0x0506,
+	EncodingWindows1257: 0x0507,
+	EncodingWindows1258: 0x0508,
+	EncodingKOI8R:       0x0A02,
+	EncodingKOI8U:       0x0A08,
+	EncodingCP437:       0x0400,
+	EncodingCP850:       0x0410,
+	EncodingCP852:       0x0412,
+	EncodingCP855:       0x0413,
+	EncodingCP860:       0x0415,
+	EncodingCP862:       0x0417,
+	EncodingCP863:       0x0418,
+	EncodingCP865:       0x041A,
+	EncodingCP866:       0x041B,
+	EncodingMacintosh:   0x0000,
+	EncodingMacCyrillic: 0x0007,
+}
+
+// ianaCharsetNames 与 IANA 首选名称不一致的编码名称
+var ianaCharsetNames = map[string]string{
+	EncodingUTF8BOM: EncodingUTF8,
+	EncodingCP437:   "IBM437",
+	EncodingCP850:   "IBM850",
+	EncodingCP852:   "IBM852",
+	EncodingCP855:   "IBM855",
+	EncodingCP858:   "IBM00858",
+	EncodingCP860:   "IBM860",
+	EncodingCP862:   "IBM862",
+	EncodingCP863:   "IBM863",
+	EncodingCP865:   "IBM865",
+	EncodingCP866:   "IBM866",
+}
+
+// ianaCharsetName 返回写入扩展属性的 IANA 字符集名称
+func ianaCharsetName(encodingName string) string {
+	if name, ok := ianaCharsetNames[encodingName]; ok {
+		return name
+	}
+	return encodingName
+}
+
+// formatAppleTextEncoding 生成 com.apple.TextEncoding 属性值（如 "utf-8;134217984"）
+func formatAppleTextEncoding(encodingName string) string {
+	name := ianaCharsetName(encodingName)
+	value := strings.ToLower(name) + ";"
+	if code, ok := cfStringEncodings[name]; ok {
+		value += strconv.FormatUint(uint64(code), 10)
+	}
+	return value
+}
+
+// parseAppleTextEncoding 解析 com.apple.TextEncoding 属性值（优先按 CFStringEncoding 编号）
+func parseAppleTextEncoding(value string) (string, bool) {
+	name, number, _ := strings.Cut(strings.TrimSpace(value), ";")
+	if code, err := strconv.ParseUint(number, 10, 32); err == nil {
+		for encodingName, cf := range cfStringEncodings {
+			if uint64(cf) == code {
+				return encodingName, true
+			}
+		}
+	}
+	return ResolveEncodingName(name)
+}
+
+// detectCharsetTag 按文件的编码扩展属性检测编码，仅在数据能按该编码无损解码且不是误标的 UTF-8 时采用
+func detectCharsetTag(path string, data []byte) *DetectionResult {
+	tagged, err := ReadCharsetTag(path)
+	if err != nil || tagged == "" {
+		return nil
+	}
+	// 单字节编码几乎能解码任何数据，只检查能否无损解码无法排除过时的属性；
+	// 与编码声明相同，含非 ASCII 字符的有效 UTF-8 不采用其他编码的属性
+	truncated := false
+	if info, err := os.Stat(path); err == nil {
+		truncated = info.Size() > int64(len(data))
+	}
+	if !acceptsHint(tagged, data, truncated) {
+		return nil
+	}
+
+	return &DetectionResult{
+		Encoding:   tagged,
+		Confidence: 0.9,
+		Details: &DetectionDetails{
+			Method: MethodCharsetTag,
+		},
+	}
+}
+
+// ReadCharsetTag 读取文件的编码扩展属性（Linux 为 user.charset，macOS 为 com.apple.TextEncoding），
+// 返回解析后的编码名称；文件没有该属性或无法识别属性值时返回空字符串
+func ReadCharsetTag(path string) (string, error) {
+	return readCharsetTag(path)
+}
+
+// WriteCharsetTag 将编码写入文件的编码扩展属性（Linux 为 user.charset，macOS 为 com.apple.TextEncoding），
+// 供编辑器和 Finder 识别文件编码；平台或文件系统不支持扩展属性时返回 ErrXattrUnsupported
+func WriteCharsetTag(path, encodingName string) error {
+	return writeCharsetTag(path, canonicalEncodingName(encodingName))
+}
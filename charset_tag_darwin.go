@@ -0,0 +1,65 @@
+//go:build darwin
+
+package encoding
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// charsetTagSupported macOS 支持编码扩展属性（com.apple.TextEncoding）
+const charsetTagSupported = true
+
+// readCharsetTag 读取 com.apple.TextEncoding 扩展属性
+func readCharsetTag(path string) (string, error) {
+	buf := make([]byte, 256)
+	n, err := unix.Getxattr(path, XattrAppleTextEncoding, buf)
+	if err != nil {
+		if errors.Is(err, unix.ENOATTR) {
+			return "", nil
+		}
+		return "", xattrError(err)
+	}
+	name, _ := parseAppleTextEncoding(string(buf[:n]))
+	return name, nil
+}
+
+// writeCharsetTag 写入 com.apple.TextEncoding 扩展属性
+func writeCharsetTag(path, encodingName string) error {
+	if err := unix.Setxattr(path, XattrAppleTextEncoding, []byte(formatAppleTextEncoding(encodingName)), 0); err != nil {
+		return xattrError(err)
+	}
+	return nil
+}
+
+// listXattrs 列出文件的扩展属性名称（不包括系统为新文件自动设置的 com.apple.provenance 属性）
+func listXattrs(path string) ([]string, error) {
+	size, err := unix.Listxattr(path, nil)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	size, err = unix.Listxattr(path, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, name := range strings.Split(string(buf[:size]), "\x00") {
+		if name != "" && name != "com.apple.provenance" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// xattrError 将文件系统不支持扩展属性的错误转换为 ErrXattrUnsupported
+func xattrError(err error) error {
+	if errors.Is(err, unix.ENOTSUP) {
+		return fmt.Errorf("%w: %v", ErrXattrUnsupported, err)
+	}
+	return err
+}
@@ -0,0 +1,64 @@
+//go:build linux
+
+package encoding
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+)
+
+// charsetTagSupported Linux 支持编码扩展属性（user.charset）
+const charsetTagSupported = true
+
+// readCharsetTag 读取 user.charset 扩展属性
+func readCharsetTag(path string) (string, error) {
+	buf := make([]byte, 256)
+	n, err := syscall.Getxattr(path, XattrCharset, buf)
+	if err != nil {
+		if errors.Is(err, syscall.ENODATA) {
+			return "", nil
+		}
+		return "", xattrError(err)
+	}
+	name, _ := ResolveEncodingName(string(buf[:n]))
+	return name, nil
+}
+
+// writeCharsetTag 写入 user.charset 扩展属性
+func writeCharsetTag(path, encodingName string) error {
+	if err := syscall.Setxattr(path, XattrCharset, []byte(ianaCharsetName(encodingName)), 0); err != nil {
+		return xattrError(err)
+	}
+	return nil
+}
+
+// listXattrs 列出文件的扩展属性名称（不包括系统为新文件自动设置的 security.* 属性）
+func listXattrs(path string) ([]string, error) {
+	size, err := syscall.Listxattr(path, nil)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	size, err = syscall.Listxattr(path, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, name := range strings.Split(string(buf[:size]), "\x00") {
+		if name != "" && !strings.HasPrefix(name, "security.") {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// xattrError 将文件系统不支持扩展属性的错误转换为 ErrXattrUnsupported
+func xattrError(err error) error {
+	if errors.Is(err, syscall.ENOTSUP) {
+		return fmt.Errorf("%w: %v", ErrXattrUnsupported, err)
+	}
+	return err
+}
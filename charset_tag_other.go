@@ -0,0 +1,21 @@
+//go:build !linux && !darwin
+
+package encoding
+
+// charsetTagSupported 当前平台不支持编码扩展属性
+const charsetTagSupported = false
+
+// readCharsetTag 当前平台不支持编码扩展属性
+func readCharsetTag(path string) (string, error) {
+	return "", ErrXattrUnsupported
+}
+
+// writeCharsetTag 当前平台不支持编码扩展属性
+func writeCharsetTag(path, encodingName string) error {
+	return ErrXattrUnsupported
+}
+
+// listXattrs 当前平台不支持扩展属性
+func listXattrs(path string) ([]string, error) {
+	return nil, nil
+}
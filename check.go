@@ -0,0 +1,303 @@
+package encoding
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mirbf/encoding-processor/detector"
+)
+
+// 内容检查规则
+const (
+	CheckRuleEncoding     = "encoding"      // 文件不是要求的编码
+	CheckRuleBOM          = "bom"           // 文件带有不允许的 BOM
+	CheckRuleLineEnding   = "line_ending"   // 文件包含不符合要求的换行符
+	CheckRuleControlChars = "control_chars" // 文件包含控制字符
+)
+
+// CheckPolicy 内容检查策略
+type CheckPolicy struct {
+	// Encoding 要求的编码（默认 UTF-8）
+	Encoding string `json:"encoding,omitempty"`
+
+	// AllowBOM 是否允许文件以 BOM 开头
+	AllowBOM bool `json:"allow_bom,omitempty"`
+
+	// LineEnding 要求的换行符（LF、CRLF、CR，为空表示不检查）
+	LineEnding string `json:"line_ending,omitempty"`
+
+	// AllowControlChars 是否允许控制字符（制表符、换行符、回车符和换页符始终允许）
+	AllowControlChars bool `json:"allow_control_chars,omitempty"`
+
+	// Include 仅检查匹配的文件（语法同 BatchOptions.Include）
+	Include []string `json:"include,omitempty"`
+
+	// Exclude 不检查匹配的文件（优先于 Include）
+	Exclude []string `json:"exclude,omitempty"`
+
+	// Extensions 仅检查这些扩展名的文件（为空表示不限制）
+	Extensions []string `json:"extensions,omitempty"`
+}
+
+// DefaultCheckPolicy 默认检查策略：UTF-8 无 BOM、LF 换行、不含控制字符
+func DefaultCheckPolicy() *CheckPolicy {
+	return &CheckPolicy{
+		Encoding:   EncodingUTF8,
+		LineEnding: LineEndingLF,
+	}
+}
+
+// CheckViolation 一处违反检查策略的内容
+type CheckViolation struct {
+	// Path 文件路径
+	Path string `json:"path"`
+
+	// Rule 违反的规则（见 CheckRule* 常量）
+	Rule string `json:"rule"`
+
+	// Line 首次违反规则的行号（从 1 开始，0 表示整个文件）
+	Line int `json:"line,omitempty"`
+
+	// Message 说明
+	Message string `json:"message"`
+}
+
+// CheckResult 目录内容检查结果
+type CheckResult struct {
+	// Root 检查的根目录
+	Root string `json:"root"`
+
+	// FilesChecked 检查的文件数
+	FilesChecked int `json:"files_checked"`
+
+	// Skipped 疑似二进制而未检查的文件
+	Skipped []string `json:"skipped,omitempty"`
+
+	// Violations 违反策略的内容（按文件路径排列）
+	Violations []CheckViolation `json:"violations,omitempty"`
+
+	// Duration 检查耗时
+	Duration time.Duration `json:"duration"`
+}
+
+// Passed 检查是否没有任何违反策略的文件
+func (r *CheckResult) Passed() bool {
+	return len(r.Violations) == 0
+}
+
+// ExitCode 返回适合作为进程退出码的结果（通过时为 0，否则为 1）
+func (r *CheckResult) ExitCode() int {
+	if r.Passed() {
+		return 0
+	}
+	return 1
+}
+
+// CheckDirectory 按策略只读检查目录树中的文件，适合作为构建流水线的内容门禁
+//
+// 只检测不修改任何文件；跳过隐藏目录和疑似二进制的文件。policy 为 nil 时使用 DefaultCheckPolicy。
+// 存在违反策略的文件时 CheckResult.Passed 返回 false，error 仅表示检查本身失败。
+func CheckDirectory(dir string, policy *CheckPolicy) (*CheckResult, error) {
+	if policy == nil {
+		policy = DefaultCheckPolicy()
+	}
+	required := canonicalEncodingName(policy.Encoding)
+	if required == "" {
+		required = EncodingUTF8
+	}
+
+	start := time.Now()
+	result := &CheckResult{Root: dir}
+	processor := NewDefault()
+	defer processor.(Drainer).Close()
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return &FileOperationError{Op: "walk", File: path, Err: err}
+		}
+		if info.IsDir() {
+			// 跳过隐藏目录（如 .git）
+			if path != dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			rel = path
+		}
+		if !matchesFileFilters(rel, policy.Include, policy.Exclude, policy.Extensions) {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return &FileOperationError{Op: "read", File: path, Err: err}
+		}
+
+		bomEncoding, bomSize := detector.BOM(data)
+		if IsProbablyBinary(data) {
+			result.Skipped = append(result.Skipped, path)
+			return nil
+		}
+
+		result.FilesChecked++
+		result.Violations = append(result.Violations, checkFile(processor, path, data, bomEncoding, bomSize, required, policy)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// checkFile 检查单个文件的内容是否符合策略
+func checkFile(processor Processor, path string, data []byte, bomEncoding string, bomSize int, required string, policy *CheckPolicy) []CheckViolation {
+	var violations []CheckViolation
+	violate := func(rule string, line int, format string, args ...interface{}) {
+		violations = append(violations, CheckViolation{Path: path, Rule: rule, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if bomEncoding != "" && !policy.AllowBOM {
+		violate(CheckRuleBOM, 1, "file starts with a %s byte order mark", bomEncoding)
+	}
+
+	// 按要求的编码解码文件内容，无法解码时不再检查换行符和控制字符
+	var text string
+	switch {
+	case bomEncoding != "" && bomEncoding != required:
+		violate(CheckRuleEncoding, 0, "file is %s (byte order mark), expected %s", bomEncoding, required)
+		return violations
+	case required == EncodingUTF8:
+		body := data[bomSize:]
+		if !utf8.Valid(body) {
+			violate(CheckRuleEncoding, invalidUTF8Line(body), "file is not valid UTF-8 (detected %s)", detectedName(processor, data))
+			return violations
+		}
+		text = string(body)
+	case detector.IsASCII(data) && !strings.HasPrefix(required, "UTF-"):
+		// 纯 ASCII 文本同时是所有 ASCII 兼容编码的有效文本
+		text = string(data)
+	default:
+		if detected := detectedName(processor, data); detected != required {
+			violate(CheckRuleEncoding, 0, "file is %s, expected %s", detected, required)
+			return violations
+		}
+		decoded, err := processor.Convert(data[bomSize:], required, EncodingUTF8)
+		if err != nil {
+			violate(CheckRuleEncoding, 0, "file cannot be decoded as %s: %v", required, err)
+			return violations
+		}
+		text = string(decoded)
+	}
+
+	if policy.LineEnding != "" {
+		if line, count := findLineEndingViolation(text, policy.LineEnding); count > 0 {
+			violate(CheckRuleLineEnding, line, "%d line break(s) do not use %s", count, lineEndingName(policy.LineEnding))
+		}
+	}
+
+	if !policy.AllowControlChars {
+		line, count, first := 1, 0, 0
+		firstRune := rune(0)
+		for _, r := range text {
+			if r == '\n' {
+				line++
+			}
+			if isControlChar(r) {
+				if count == 0 {
+					first, firstRune = line, r
+				}
+				count++
+			}
+		}
+		if count > 0 {
+			violate(CheckRuleControlChars, first, "%d control character(s), first U+%04X", count, firstRune)
+		}
+	}
+
+	return violations
+}
+
+// findLineEndingViolation 返回首个不符合要求的换行符所在行号及不符合要求的换行符数量
+func findLineEndingViolation(text, lineEnding string) (int, int) {
+	line, first, count := 1, 0, 0
+	for i := 0; i < len(text); i++ {
+		var found string
+		switch text[i] {
+		case '\r':
+			found = LineEndingCR
+			if i+1 < len(text) && text[i+1] == '\n' {
+				found = LineEndingCRLF
+			}
+		case '\n':
+			found = LineEndingLF
+		default:
+			continue
+		}
+
+		if found != lineEnding {
+			if count == 0 {
+				first = line
+			}
+			count++
+		}
+		i += len(found) - 1
+		line++
+	}
+	return first, count
+}
+
+// lineEndingName 返回换行符的名称
+func lineEndingName(lineEnding string) string {
+	switch lineEnding {
+	case LineEndingCRLF:
+		return "CRLF"
+	case LineEndingCR:
+		return "CR"
+	default:
+		return "LF"
+	}
+}
+
+// isControlChar 检查字符是否为不允许的控制字符（C0、DEL 和 C1，制表符、换行符、回车符和换页符除外）
+func isControlChar(r rune) bool {
+	switch r {
+	case '\t', '\n', '\r', '\f':
+		return false
+	}
+	return r < 0x20 || (r >= 0x7F && r <= 0x9F)
+}
+
+// invalidUTF8Line 返回首个无效 UTF-8 字节序列所在行号
+func invalidUTF8Line(data []byte) int {
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size <= 1 {
+			return bytes.Count(data[:i], []byte("\n")) + 1
+		}
+		i += size
+	}
+	return 0
+}
+
+// detectedName 返回检测到的编码名称（检测失败时返回 unknown）
+func detectedName(processor Processor, data []byte) string {
+	detection, err := processor.DetectEncoding(data)
+	if err != nil {
+		return "unknown"
+	}
+	return detection.Encoding
+}
@@ -0,0 +1,255 @@
+package encoding
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/mirbf/encoding-processor/converter"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
+)
+
+// transformerFactory 创建转换器，trace 为转换器记录统计的位置（可以为 nil）
+type transformerFactory func(trace *conversionTrace) (transform.Transformer, error)
+
+// newlineSafeEncodings 换行符字节（0x0A）不会出现在多字节字符中、解码器没有跨字符状态的多字节编码
+var newlineSafeEncodings = map[string]bool{
+	EncodingGBK:       true,
+	EncodingGB2312:    true,
+	EncodingGB18030:   true,
+	EncodingBIG5:      true,
+	EncodingBIG5HKSCS: true,
+	EncodingCP950:     true,
+	EncodingShiftJIS:  true,
+	EncodingCP932:     true,
+	EncodingEUCJP:     true,
+	EncodingEUCKR:     true,
+	EncodingCP949:     true,
+}
+
+// statefulEncodings 以转义序列切换字符集的有状态编码：解码器和编码器的状态贯穿整个数据，
+// 不能按数据块分别新建转换器
+var statefulEncodings = map[string]bool{
+	EncodingISO2022JP: true,
+	EncodingISO2022KR: true,
+	EncodingHZGB2312:  true,
+}
+
+// transformLargeData 按分块边界将大数据拆分，由 GOMAXPROCS 个工作者并行转换后按顺序拼接
+//
+// 每块使用独立的转换器和统计，拼接时按块的顺序汇总到 trace；任一块失败时其余未开始的块不再转换。
+func (c *defaultConverter) transformLargeData(data []byte, boundaries []int, newTransformer transformerFactory, trace *conversionTrace) ([]byte, error) {
+	chunks := len(boundaries) - 1
+	results := make([][]byte, chunks)
+	traces := make([]*conversionTrace, chunks)
+	errs := make([]error, chunks)
+
+	var failed atomic.Bool
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for worker := 0; worker < min(runtime.GOMAXPROCS(0), chunks); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int(next.Add(1) - 1); i < chunks && !failed.Load(); i = int(next.Add(1) - 1) {
+				if trace != nil {
+					traces[i] = &conversionTrace{}
+				}
+				transformer, err := newTransformer(traces[i])
+				if err == nil {
+					results[i], err = c.transformSmallData(data[boundaries[i]:boundaries[i+1]], c.cancellable(transformer))
+				}
+				if err != nil {
+					errs[i] = err
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	size := 0
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert chunk at offset %d: %w", boundaries[i], err)
+		}
+		size += len(results[i])
+	}
+
+	result := make([]byte, 0, size)
+	for i, converted := range results {
+		result = append(result, converted...)
+		trace.mergeChunk(traces[i])
+	}
+	return result, nil
+}
+
+// chunkBoundaries 返回约 chunkSize 字节的分块边界（首项为 0，末项为 len(data)）
+//
+// 边界不会切断多字节字符，且每块可以用新的转换器独立转换：UTF-8 在字符起始字节处分块，
+// UTF-16LE/BE 和 UTF-32LE/BE 按代码单元对齐（不拆分代理对），单字节编码任意分块，
+// GBK、BIG5、Shift_JIS 等多字节编码在换行符之后分块。带 BOM 检测的 UTF-16/UTF-32
+// 和其他有状态或未知的编码不分块，只返回首尾两个边界；输出 BOM 的目标编码和 ISO-2022-JP 等有状态目标编码
+// （每块的编码器会各自写入转义序列）同样不分块。
+func chunkBoundaries(data []byte, from, to string, chunkSize int) []int {
+	boundaries := []int{0}
+	boundary := chunkBoundaryFunc(from)
+	if boundary == nil || to == EncodingUTF16 || to == EncodingUTF32 || statefulEncodings[to] || chunkSize <= 0 {
+		return append(boundaries, len(data))
+	}
+
+	for start := 0; len(data)-start > chunkSize; {
+		end := boundary(data, start, start+chunkSize)
+		if end <= start || end >= len(data) {
+			break
+		}
+		boundaries = append(boundaries, end)
+		start = end
+	}
+	return append(boundaries, len(data))
+}
+
+// chunkBoundaryFunc 返回源编码的分块边界函数：给定块的起点和期望终点，返回实际终点
+// （无法安全分块的编码返回 nil）
+func chunkBoundaryFunc(from string) func(data []byte, start, end int) int {
+	switch from {
+	case EncodingUTF8:
+		return func(data []byte, start, end int) int {
+			for i := end; i > end-4 && i > start; i-- {
+				if data[i]&0xC0 != 0x80 {
+					return i
+				}
+			}
+			return end
+		}
+	case EncodingUTF16LE, EncodingUTF16BE:
+		highByte := 1
+		if from == EncodingUTF16BE {
+			highByte = 0
+		}
+		return func(data []byte, start, end int) int {
+			end -= (end - start) % 2
+			// 不在高代理项之后分块
+			if end-start >= 4 && data[end-2+highByte]&0xFC == 0xD8 {
+				end -= 2
+			}
+			return end
+		}
+	case EncodingUTF32LE, EncodingUTF32BE:
+		return func(data []byte, start, end int) int {
+			return end - (end-start)%4
+		}
+	}
+
+	if newlineSafeEncodings[from] {
+		return func(data []byte, start, end int) int {
+			if i := bytes.LastIndexByte(data[start:end], '\n'); i >= 0 {
+				return start + i + 1
+			}
+			if i := bytes.IndexByte(data[end:], '\n'); i >= 0 {
+				return end + i + 1
+			}
+			return len(data)
+		}
+	}
+
+	if enc, err := converter.Lookup(from); err == nil {
+		if _, ok := enc.(*charmap.Charmap); ok {
+			return func(data []byte, start, end int) int {
+				return end
+			}
+		}
+	}
+	return nil
+}
+
+// completePrefix 返回 data 中以完整字符结尾的最长前缀长度（data 从字符起始处开始）
+//
+// 流式转换按读取的数据块逐块转换，末尾不完整的多字节字符留到下一块，使分块结果与一次性转换一致。
+// UTF-8、UTF-16、UTF-32 按编码规则计算；单字节编码任意分块；其他编码由解码器判断末尾是否完整，
+// GBK、BIG5、Shift_JIS 等多字节编码只需检查最后一个换行符之后的数据。有状态编码（statefulEncodings）
+// 不能逐块转换，由流处理器整个流共用一个转换器。
+func completePrefix(data []byte, from string) int {
+	switch from {
+	case EncodingUTF8, EncodingUTF8BOM:
+		for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(data[i]) {
+				if !utf8.FullRune(data[i:]) {
+					return i
+				}
+				break
+			}
+		}
+		return len(data)
+	case EncodingUTF16, EncodingUTF16LE, EncodingUTF16BE:
+		end := len(data) - len(data)%2
+		highByte := end - 2
+		if from == EncodingUTF16LE || from == EncodingUTF16 && bytes.HasPrefix(data, []byte{0xFF, 0xFE}) {
+			highByte = end - 1
+		}
+		// 不在高代理项之后分块
+		if end >= 2 && data[highByte]&0xFC == 0xD8 {
+			end -= 2
+		}
+		return end
+	case EncodingUTF32, EncodingUTF32LE, EncodingUTF32BE:
+		return len(data) - len(data)%4
+	}
+
+	enc, err := converter.Lookup(from)
+	if err != nil {
+		return len(data)
+	}
+	if _, ok := enc.(*charmap.Charmap); ok {
+		return len(data)
+	}
+	start := 0
+	if newlineSafeEncodings[from] {
+		start = bytes.LastIndexByte(data, '\n') + 1
+	}
+	return start + decodablePrefix(enc.NewDecoder(), data[start:])
+}
+
+// decodablePrefix 返回解码器在数据未结束时（atEOF 为 false）能够消费的前缀长度
+func decodablePrefix(decoder transform.Transformer, data []byte) int {
+	var dst [512]byte
+	consumed := 0
+	for {
+		_, n, err := decoder.Transform(dst[:], data[consumed:], false)
+		consumed += n
+		switch {
+		case err == transform.ErrShortDst && n > 0:
+			continue
+		case err == transform.ErrShortSrc:
+			return consumed
+		default:
+			return len(data)
+		}
+	}
+}
+
+// continuationEncoding 返回流式转换中首块之后的数据块使用的编码
+//
+// BOM 只出现在首块：带 BOM 的 UTF-8 之后按 UTF-8 转换，UTF-16/UTF-32 之后按首块 BOM 确定的字节序
+// （没有 BOM 时为大端序）转换，避免后续数据块重复检测或写入 BOM。first 为首块的源数据或转换结果。
+func continuationEncoding(name string, first []byte) string {
+	switch name {
+	case EncodingUTF8BOM:
+		return EncodingUTF8
+	case EncodingUTF16:
+		if bytes.HasPrefix(first, []byte{0xFF, 0xFE}) {
+			return EncodingUTF16LE
+		}
+		return EncodingUTF16BE
+	case EncodingUTF32:
+		if bytes.HasPrefix(first, []byte{0xFF, 0xFE, 0x00, 0x00}) {
+			return EncodingUTF32LE
+		}
+		return EncodingUTF32BE
+	}
+	return name
+}
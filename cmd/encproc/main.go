@@ -0,0 +1,475 @@
+// Command encproc 基于 encoding-processor 的命令行编码检测和转换工具
+//
+// 用法：
+//
+//	encproc detect [选项] 文件...
+//	encproc convert [选项] 文件...
+//	encproc batch [选项] 文件或目录...
+//
+// detect 检测文件编码；convert 转换单个文件并写入标准输出，指定 --in-place 时就地转换（可以是多个文件）；
+// batch 并发就地转换文件和目录树。文件参数支持通配符（如 "*.txt"），不依赖 shell 展开，
+// 在 Windows 上同样可用。--format json 输出 JSON，默认输出表格。
+//
+// 全部成功时退出状态为 0，有文件处理失败时为 1，参数错误时为 2。
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	encoding "github.com/mirbf/encoding-processor"
+)
+
+// 退出状态
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// 输出格式
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// errUsage 参数错误
+var errUsage = errors.New("usage error")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run 执行子命令并返回退出状态
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+
+	var cmd func(*options, []string, io.Writer) (bool, error)
+	switch args[0] {
+	case "detect":
+		cmd = runDetect
+	case "convert":
+		cmd = runConvert
+	case "batch":
+		cmd = runBatch
+	case "-h", "-help", "--help", "help":
+		usage(stdout)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "encproc: unknown command %q\n", args[0])
+		usage(stderr)
+		return exitUsage
+	}
+
+	opts := &options{}
+	fs := opts.flagSet(args[0], stderr)
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if opts.format != formatTable && opts.format != formatJSON {
+		fmt.Fprintf(stderr, "encproc: unknown format %q\n", opts.format)
+		return exitUsage
+	}
+
+	files, err := expandArgs(fs.Args())
+	if err == nil && len(files) == 0 {
+		err = errors.New("no input files")
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "encproc %s: %v\n", args[0], err)
+		return exitUsage
+	}
+
+	ok, err := cmd(opts, files, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "encproc %s: %v\n", args[0], err)
+	}
+	switch {
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case err != nil:
+		return exitFailure
+	case !ok:
+		return exitFailure
+	}
+	return exitOK
+}
+
+// usage 输出用法说明
+func usage(w io.Writer) {
+	fmt.Fprint(w, `usage: encproc <command> [flags] <files...>
+
+commands:
+  detect   print the detected encoding of each file
+  convert  convert a file to stdout, or files in place with --in-place
+  batch    convert files and directory trees in place concurrently
+
+run "encproc <command> -h" for the flags of a command
+`)
+}
+
+// options 子命令共用的命令行选项
+type options struct {
+	from          string
+	to            string
+	inPlace       bool
+	backup        bool
+	dryRun        bool
+	format        string
+	minConfidence float64
+	concurrency   int
+	extensions    string
+	include       string
+	exclude       string
+	maxDepth      int
+	skipBinary    bool
+	report        string
+	manifest      string
+}
+
+// flagSet 注册子命令的选项
+func (o *options) flagSet(name string, output io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("encproc "+name, flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.StringVar(&o.format, "format", formatTable, "output format: table or json")
+	fs.Float64Var(&o.minConfidence, "min-confidence", 0, "minimum detection confidence (default 0.8)")
+	if name == "detect" {
+		return fs
+	}
+
+	fs.StringVar(&o.from, "from", "", "source encoding (empty to detect)")
+	fs.StringVar(&o.to, "to", encoding.EncodingUTF8, "target encoding")
+	fs.BoolVar(&o.backup, "backup", false, "keep a .bak copy of each converted file")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "report what would be converted without writing")
+	if name == "convert" {
+		fs.BoolVar(&o.inPlace, "in-place", false, "convert files in place instead of writing to stdout")
+	}
+	if name == "batch" {
+		fs.IntVar(&o.concurrency, "concurrency", 4, "number of files converted concurrently")
+		fs.StringVar(&o.extensions, "ext", "", "comma-separated extensions to convert in directories (e.g. txt,csv)")
+		fs.StringVar(&o.include, "include", "", "comma-separated glob patterns of files to convert in directories")
+		fs.StringVar(&o.exclude, "exclude", "", "comma-separated glob patterns of files to leave untouched in directories")
+		fs.IntVar(&o.maxDepth, "max-depth", 0, "maximum directory depth to descend (1 for top-level files only, 0 for unlimited)")
+		fs.BoolVar(&o.skipBinary, "skip-binary", false, "skip files that look like binary data in directories")
+		fs.StringVar(&o.manifest, "manifest", "", "manifest file of converted files; unchanged files listed there are skipped on later runs")
+		fs.StringVar(&o.report, "report", "", "write a report of every scanned file (CSV if the name ends in .csv, JSON otherwise); use with --dry-run")
+	}
+	return fs
+}
+
+// processorConfig 按选项创建处理器配置
+func (o *options) processorConfig() *encoding.ProcessorConfig {
+	config := encoding.GetDefaultProcessorConfig()
+	config.EnableMetrics = false
+	if o.minConfidence > 0 {
+		config.DetectorConfig.MinConfidence = o.minConfidence
+	}
+	return config
+}
+
+// fileOptions 按选项创建文件处理选项
+func (o *options) fileOptions() *encoding.FileProcessOptions {
+	minConfidence := encoding.DefaultMinConfidence
+	if o.minConfidence > 0 {
+		minConfidence = o.minConfidence
+	}
+	return &encoding.FileProcessOptions{
+		SourceEncoding:    o.from,
+		TargetEncoding:    o.to,
+		MinConfidence:     minConfidence,
+		CreateBackup:      o.backup,
+		BackupSuffix:      encoding.DefaultBackupSuffix,
+		OverwriteExisting: true,
+		BufferSize:        encoding.DefaultBufferSize,
+		PreserveMode:      true,
+		PreserveTime:      true,
+		DryRun:            o.dryRun,
+	}
+}
+
+// detectReport detect 子命令的单个文件结果
+type detectReport struct {
+	File       string  `json:"file"`
+	Encoding   string  `json:"encoding,omitempty"`
+	Confidence float64 `json:"confidence,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// runDetect 检测各文件的编码
+func runDetect(opts *options, files []string, stdout io.Writer) (bool, error) {
+	processor := encoding.NewProcessor(opts.processorConfig())
+
+	ok := true
+	reports := make([]detectReport, 0, len(files))
+	for _, file := range files {
+		report := detectReport{File: file}
+		result, err := processor.DetectFileEncoding(file)
+		if err != nil {
+			report.Error = err.Error()
+			ok = false
+		} else {
+			report.Encoding, report.Confidence = result.Encoding, result.Confidence
+		}
+		reports = append(reports, report)
+	}
+
+	if opts.format == formatJSON {
+		return ok, writeJSON(stdout, reports)
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tENCODING\tCONFIDENCE")
+	for _, report := range reports {
+		if report.Error != "" {
+			fmt.Fprintf(tw, "%s\t-\terror: %s\n", report.File, report.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.2f\n", report.File, report.Encoding, report.Confidence)
+	}
+	return ok, tw.Flush()
+}
+
+// fileReport convert 子命令的单个文件结果
+type fileReport struct {
+	File   string                      `json:"file"`
+	Result *encoding.FileProcessResult `json:"result,omitempty"`
+	Error  string                      `json:"error,omitempty"`
+}
+
+// runConvert 将单个文件转换后写入标准输出，或就地转换文件
+func runConvert(opts *options, files []string, stdout io.Writer) (bool, error) {
+	if !opts.inPlace && !opts.dryRun {
+		if len(files) != 1 {
+			return false, fmt.Errorf("%w: multiple files require --in-place", errUsage)
+		}
+		if opts.backup {
+			return false, fmt.Errorf("%w: --backup requires --in-place", errUsage)
+		}
+		return convertToStdout(opts, files[0], stdout)
+	}
+
+	fp := encoding.NewFileProcessor(opts.processorConfig())
+	ok := true
+	reports := make([]fileReport, 0, len(files))
+	for _, file := range files {
+		report := fileReport{File: file}
+		result, err := fp.ProcessFileInPlace(file, opts.fileOptions())
+		if err != nil {
+			report.Error = err.Error()
+			ok = false
+		}
+		report.Result = result
+		reports = append(reports, report)
+	}
+
+	if opts.format == formatJSON {
+		return ok, writeJSON(stdout, reports)
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tSOURCE\tTARGET\tCONFIDENCE\tSTATUS")
+	for _, report := range reports {
+		if report.Error != "" {
+			fmt.Fprintf(tw, "%s\t-\t-\t-\terror: %s\n", report.File, report.Error)
+			continue
+		}
+		writeResultRow(tw, report.File, report.Result, opts.dryRun)
+	}
+	return ok, tw.Flush()
+}
+
+// convertToStdout 将文件转换后写入标准输出（流式处理，与 encoding.RunFilter 行为一致）
+func convertToStdout(opts *options, file string, stdout io.Writer) (bool, error) {
+	input, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer input.Close()
+
+	err = encoding.RunFilter(input, stdout, encoding.FilterArgs{
+		From:          opts.from,
+		To:            opts.to,
+		MinConfidence: opts.minConfidence,
+	})
+	return err == nil, err
+}
+
+// runBatch 并发就地转换文件和目录树
+func runBatch(opts *options, files []string, stdout io.Writer) (bool, error) {
+	bp := encoding.NewBatchProcessor(opts.processorConfig())
+	batchOptions := &encoding.BatchOptions{
+		FileOptions: opts.fileOptions(),
+		Concurrency: opts.concurrency,
+		Extensions:  splitList(opts.extensions),
+		Include:     splitList(opts.include),
+		Exclude:     splitList(opts.exclude),
+		MaxDepth:    opts.maxDepth,
+		SkipBinary:  opts.skipBinary,
+	}
+	if opts.manifest != "" {
+		manifest, err := encoding.LoadConversionManifest(opts.manifest)
+		if err != nil {
+			return false, err
+		}
+		batchOptions.Manifest = manifest
+	}
+
+	var plain []string
+	result := &encoding.BatchResult{}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil || !info.IsDir() {
+			plain = append(plain, file)
+			continue
+		}
+		dirResult, err := bp.ProcessDirectoryContext(context.Background(), file, batchOptions)
+		if err != nil {
+			return false, err
+		}
+		mergeBatchResult(result, dirResult)
+	}
+	if len(plain) > 0 {
+		filesResult, err := bp.ProcessFiles(context.Background(), plain, batchOptions)
+		if err != nil {
+			return false, err
+		}
+		mergeBatchResult(result, filesResult)
+	}
+
+	ok := result.FailureCount == 0
+	if opts.report != "" {
+		if err := writeReport(opts.report, result); err != nil {
+			return false, err
+		}
+	}
+	if opts.format == formatJSON {
+		return ok, writeJSON(stdout, result)
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tSOURCE\tTARGET\tCONFIDENCE\tSTATUS")
+	for _, fileResult := range result.Results {
+		if errors.Is(fileResult.Err, encoding.ErrBinaryFile) {
+			fmt.Fprintf(tw, "%s\t-\t-\t-\tskipped (binary)\n", fileResult.Job.Path)
+			continue
+		}
+		if fileResult.Err != nil {
+			fmt.Fprintf(tw, "%s\t-\t-\t-\terror: %s\n", fileResult.Job.Path, fileResult.Error)
+			continue
+		}
+		writeResultRow(tw, fileResult.Job.Path, fileResult.Result, opts.dryRun)
+	}
+	if err := tw.Flush(); err != nil {
+		return ok, err
+	}
+	_, err := fmt.Fprintf(stdout, "\n%d converted, %d failed, %d skipped in %s\n",
+		result.SuccessCount, result.FailureCount, len(result.Skipped), result.Duration.Round(1e6))
+	return ok, err
+}
+
+// writeReport 将批量处理结果的试运行报告写入文件（扩展名为 .csv 时输出 CSV，否则输出 JSON）
+func writeReport(path string, result *encoding.BatchResult) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	report := encoding.NewDryRunReport(result)
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = report.WriteCSV(file)
+	} else {
+		err = report.WriteJSON(file)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// splitList 拆分逗号分隔的选项值（为空时返回 nil）
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// mergeBatchResult 将 src 的结果合并到 dst
+func mergeBatchResult(dst, src *encoding.BatchResult) {
+	dst.Results = append(dst.Results, src.Results...)
+	dst.SuccessCount += src.SuccessCount
+	dst.FailureCount += src.FailureCount
+	dst.Skipped = append(dst.Skipped, src.Skipped...)
+	dst.Passthrough = append(dst.Passthrough, src.Passthrough...)
+	dst.Cancelled = append(dst.Cancelled, src.Cancelled...)
+	dst.AlreadyProcessed = append(dst.AlreadyProcessed, src.AlreadyProcessed...)
+	dst.Warnings = append(dst.Warnings, src.Warnings...)
+	dst.TotalBytes += src.TotalBytes
+	for name, count := range src.SourceEncodings {
+		if dst.SourceEncodings == nil {
+			dst.SourceEncodings = make(map[string]int)
+		}
+		dst.SourceEncodings[name] += count
+	}
+	dst.LostRunes = encoding.MergeLostRunes(dst.LostRunes, src.LostRunes)
+	dst.Duration += src.Duration
+}
+
+// writeResultRow 输出文件处理结果的表格行
+func writeResultRow(w io.Writer, file string, result *encoding.FileProcessResult, dryRun bool) {
+	status := "converted"
+	switch {
+	case result.AlreadyProcessed:
+		status = "already processed"
+	case result.Passthrough:
+		status = "passthrough (review)"
+	case dryRun && result.WouldChange:
+		status = "dry run (would convert)"
+	case dryRun:
+		status = "dry run (unchanged)"
+	}
+	if result.BackupFile != "" {
+		status += ", backup " + result.BackupFile
+	}
+	if len(result.Warnings) > 0 {
+		status += fmt.Sprintf(", %d warnings", len(result.Warnings))
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%.2f\t%s\n", file, result.SourceEncoding, result.TargetEncoding, result.DetectionConfidence, status)
+}
+
+// writeJSON 以缩进格式输出 JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// expandArgs 展开参数中的通配符；没有匹配文件的通配符视为错误
+func expandArgs(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		if !strings.ContainsAny(arg, "*?[") {
+			files = append(files, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", arg)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	encoding "github.com/mirbf/encoding-processor"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	gbk, err := encoding.NewDefault().Convert([]byte("命令行转换的中文内容"), encoding.EncodingUTF8, encoding.EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), gbk, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 单个文件写入标准输出
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"convert", "--from", "gbk", filepath.Join(dir, "a.txt")}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("convert exited with %d: %s", code, stderr.String())
+	}
+	if stdout.String() != "命令行转换的中文内容" {
+		t.Errorf("Unexpected stdout %q", stdout.String())
+	}
+
+	// 多个文件需要 --in-place
+	if code := run([]string{"convert", filepath.Join(dir, "*.txt")}, &stdout, &stderr); code != exitUsage {
+		t.Errorf("Expected usage error, got %d", code)
+	}
+
+	// 通配符就地转换并输出 JSON
+	stdout.Reset()
+	if code := run([]string{"convert", "--from", "gbk", "--in-place", "--backup", "--format", "json", filepath.Join(dir, "*.txt")}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("convert --in-place exited with %d: %s", code, stderr.String())
+	}
+	var reports []fileReport
+	if err := json.Unmarshal(stdout.Bytes(), &reports); err != nil || len(reports) != 2 {
+		t.Fatalf("Unexpected JSON output %s (%v)", stdout.String(), err)
+	}
+	for _, report := range reports {
+		data, _ := os.ReadFile(report.File)
+		if string(data) != "命令行转换的中文内容" || report.Result.BackupFile == "" {
+			t.Errorf("Unexpected conversion of %s: %q (backup %q)", report.File, data, report.Result.BackupFile)
+		}
+	}
+
+	// 检测失败的文件使退出状态为 1
+	stdout.Reset()
+	if code := run([]string{"detect", filepath.Join(dir, "a.txt"), filepath.Join(dir, "missing.txt")}, &stdout, &stderr); code != exitFailure {
+		t.Errorf("Expected failure exit code, got %d", code)
+	}
+	if !strings.Contains(stdout.String(), "UTF-8") || !strings.Contains(stdout.String(), "missing.txt") {
+		t.Errorf("Unexpected detect output %q", stdout.String())
+	}
+}
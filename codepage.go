@@ -0,0 +1,26 @@
+package encoding
+
+import "github.com/mirbf/encoding-processor/converter"
+
+// EncodingFromCodePage 根据 Windows 代码页编号获取编码名称（如 936 -> GBK、1251 -> WINDOWS-1251）
+//
+// 所有接受编码名称的接口同样接受 "cp936"、"936"、"windows-936" 等代码页形式的名称。
+func EncodingFromCodePage(codePage int) (string, error) {
+	return converter.EncodingFromCodePage(codePage)
+}
+
+// ResolveEncodingName 将编码名称解析为本包的编码常量（如 "utf8"、"UTF_8" -> UTF-8，"csGBK"、"cp936" -> GBK，
+// "latin1" -> ISO-8859-1），支持注册的别名、Windows 代码页形式以及 IANA 和 WHATWG 定义的名称和别名
+//
+// 所有接受编码名称的接口都按此规则解析名称；无法解析时返回 false。
+func ResolveEncodingName(name string) (string, bool) {
+	return converter.Resolve(name)
+}
+
+// canonicalEncodingName 将编码名称解析为本包的编码常量，无法解析的名称原样返回
+func canonicalEncodingName(name string) string {
+	if resolved, ok := converter.Resolve(name); ok {
+		return resolved
+	}
+	return name
+}
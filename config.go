@@ -10,6 +10,10 @@ type DetectorConfig struct {
 	// MinConfidence 最小置信度（默认 0.6）
 	MinConfidence float64 `json:"min_confidence"`
 
+	// MinConfidenceByEncoding 按编码设置的最小置信度（编码名称 -> 阈值，覆盖 MinConfidence），
+	// 误判代价高的编码可要求更高的置信度，如 {"SHIFT_JIS": 0.95, "UTF-8": 0.7}
+	MinConfidenceByEncoding map[string]float64 `json:"min_confidence_by_encoding,omitempty"`
+
 	// SupportedEncodings 支持的编码列表
 	SupportedEncodings []string `json:"supported_encodings"`
 
@@ -22,11 +26,138 @@ type DetectorConfig struct {
 	// CacheTTL 缓存过期时间（默认 1 小时）
 	CacheTTL time.Duration `json:"cache_ttl"`
 
+	// CacheJanitorInterval 后台清理过期缓存条目的间隔（默认 5 分钟，负数表示不启动后台清理，只在查找时删除）
+	// 清理协程在第一次缓存检测结果时启动，缓存清空后退出
+	CacheJanitorInterval time.Duration `json:"cache_janitor_interval"`
+
 	// EnableLanguageDetection 是否启用语言检测
 	EnableLanguageDetection bool `json:"enable_language_detection"`
 
 	// PreferredEncodings 优先编码列表（检测时优先考虑）
 	PreferredEncodings []string `json:"preferred_encodings"`
+
+	// Backend 字符集检测后端（nil 表示使用内置的 chardet 后端）
+	Backend CharsetBackend `json:"-"`
+
+	// Metrics 性能监控器（记录每次检测的耗时、错误，实现 EncodingMetricsCollector 时还记录字节数和编码分布，
+	// 实现 CacheMetricsCollector 时记录检测结果缓存的命中、未命中和淘汰次数）
+	Metrics MetricsCollector `json:"-"`
+
+	// PostScorers 候选解码后的二次评分器（nil 表示使用内置评分器，空切片表示禁用）
+	PostScorers []PostScorer `json:"-"`
+
+	// ContentClass 内容类别提示（auto、code、prose，默认 auto 自动判断）
+	ContentClass string `json:"content_class"`
+
+	// Ensemble 智能检测集成投票配置（nil 表示使用默认配置）
+	Ensemble *EnsembleConfig `json:"ensemble,omitempty"`
+
+	// Scoring 候选编码评分的权重和语言模型（nil 表示使用默认配置：中文、日文、韩文模型）
+	Scoring *ScoringConfig `json:"scoring,omitempty"`
+
+	// Rules 检测覆盖规则（在统计检测之前按顺序求值）
+	Rules []DetectionRule `json:"rules,omitempty"`
+
+	// GarbledPatterns 额外的乱码特征模式（与内置模式库一起参与候选评分）
+	GarbledPatterns []GarbledPattern `json:"-"`
+
+	// PrescoreSize 分级评分的切片大小：样本超过该大小时先按开头的切片为全部候选评分，
+	// 只对得分最高的 PrescoreTopK 个候选转换完整样本（字节，0 表示不分级）
+	PrescoreSize int `json:"prescore_size,omitempty"`
+
+	// PrescoreTopK 分级评分时转换完整样本的候选数量（默认 3）
+	PrescoreTopK int `json:"prescore_top_k,omitempty"`
+
+	// Alphabets 预期的字符表（设置后字符有效性评分改用这些字符表而不是内置的通用范围，
+	// 并按全部候选编码解码评分，而不仅是中文编码）
+	Alphabets []Alphabet `json:"-"`
+
+	// UseDeclaredCharset 是否参考文档内的编码声明（XML 声明、HTML meta、CSS @charset、编码魔法注释），
+	// 数据能按声明的编码无损解码时采用声明
+	UseDeclaredCharset bool `json:"use_declared_charset"`
+
+	// SiblingContextWeight 目录处理时同目录兄弟文件主导编码的权重：低置信度文件的主导编码
+	// 置信度提升 SiblingContextWeight * 主导编码占比（0 表示不使用上下文）
+	SiblingContextWeight float64 `json:"sibling_context_weight,omitempty"`
+
+	// FailureSampling 检测失败或置信度偏低时采集输入样本（nil 表示不采集），用于积累疑难数据调优启发式
+	FailureSampling *FailureSamplingConfig `json:"-"`
+}
+
+// MinConfidenceFor 返回指定编码的最小置信度（MinConfidenceByEncoding 未设置该编码时为 MinConfidence）
+func (c *DetectorConfig) MinConfidenceFor(encodingName string) float64 {
+	if threshold, ok := c.minConfidenceOverride(encodingName); ok {
+		return threshold
+	}
+	return c.MinConfidence
+}
+
+// minConfidenceOverride 查找 MinConfidenceByEncoding 中为指定编码设置的最小置信度（名称按别名解析后比较）
+func (c *DetectorConfig) minConfidenceOverride(encodingName string) (float64, bool) {
+	if len(c.MinConfidenceByEncoding) == 0 {
+		return 0, false
+	}
+	if threshold, ok := c.MinConfidenceByEncoding[encodingName]; ok {
+		return threshold, true
+	}
+	encodingName = canonicalEncodingName(encodingName)
+	for name, threshold := range c.MinConfidenceByEncoding {
+		if canonicalEncodingName(name) == encodingName {
+			return threshold, true
+		}
+	}
+	return 0, false
+}
+
+// EnsembleConfig 智能检测集成投票配置
+//
+// 智能检测会同时运行传统检测（chardet + 置信度阈值）和候选评分两条路径，
+// 集成器按权重合并两者的投票：
+//
+//	combined = ScoringWeight * score + TraditionalWeight * calibrated(traditional)
+//
+// 其中 traditional 仅在候选编码与传统检测结果一致、且传统置信度不低于
+// TraditionalMinConfidence 时计入。各方法的置信度在参与计算前先乘以
+// Calibration 中对应的校准系数。
+type EnsembleConfig struct {
+	// ScoringWeight 候选评分投票权重（默认 1.0）
+	ScoringWeight float64 `json:"scoring_weight"`
+
+	// TraditionalWeight 传统检测投票权重（默认 0.2）
+	TraditionalWeight float64 `json:"traditional_weight"`
+
+	// TraditionalMinConfidence 传统检测参与投票的最小置信度（默认 0.8）
+	TraditionalMinConfidence float64 `json:"traditional_min_confidence"`
+
+	// Calibration 各检测方法的置信度校准系数（方法名 -> 系数，缺省为 1.0）
+	Calibration map[string]float64 `json:"calibration,omitempty"`
+
+	// TieMargin 得分差小于该值时视为平局（默认 0.01）
+	TieMargin float64 `json:"tie_margin"`
+
+	// TieBreaker 平局判定规则（preferred、traditional、confidence，默认 preferred）
+	TieBreaker string `json:"tie_breaker"`
+}
+
+// ScoringConfig 智能检测候选编码评分配置
+//
+// 候选得分 = 置信度 * ConfidenceWeight + 文字得分 * ScriptWeight + 字符有效性 * ValidityWeight
+// + 无乱码程度 * GarbledWeight，其中文字得分取各语言模型得分的最大值。
+type ScoringConfig struct {
+	// ConfidenceWeight 检测置信度权重（默认 0.4）
+	ConfidenceWeight float64 `json:"confidence_weight"`
+
+	// ScriptWeight 语言模型文字得分权重（默认 0.3）
+	ScriptWeight float64 `json:"script_weight"`
+
+	// ValidityWeight 字符有效性权重（默认 0.2）
+	ValidityWeight float64 `json:"validity_weight"`
+
+	// GarbledWeight 乱码检测权重（默认 0.1）
+	GarbledWeight float64 `json:"garbled_weight"`
+
+	// Models 参与评分的语言模型（为空时使用默认的中文、日文、韩文模型）
+	Models []*LanguageModel `json:"-"`
 }
 
 // ConverterConfig 转换器配置
@@ -37,6 +168,35 @@ type ConverterConfig struct {
 	// InvalidCharReplacement 无效字符替换字符
 	InvalidCharReplacement string `json:"invalid_char_replacement"`
 
+	// UnmappableAction 目标编码无法表示字符时的处理方式（substitute、skip、stop、escape，默认 substitute）
+	UnmappableAction string `json:"unmappable_action,omitempty"`
+
+	// SubstitutionCallback 自定义替换回调，返回无法表示的字符的替换字符串（设置后优先于 UnmappableAction）
+	SubstitutionCallback func(r rune) string `json:"-"`
+
+	// InvalidBytePolicy 源数据中无效字节序列的处理策略（replace、skip、hex_escape、numeric_char_ref、fail，
+	// 为空时解码为 U+FFFD）；未设置 UnmappableAction 和 SubstitutionCallback 时同样作用于目标编码无法表示的字符
+	InvalidBytePolicy string `json:"invalid_byte_policy,omitempty"`
+
+	// InvalidByteReplacement replace 策略下替换无效字节序列的字符（0 表示 U+FFFD）
+	InvalidByteReplacement rune `json:"invalid_byte_replacement,omitempty"`
+
+	// CompatibilityMode 兼容模式（icu 表示接受 ICU 转换器名称，默认替换字符为 U+001A）
+	CompatibilityMode string `json:"compatibility_mode,omitempty"`
+
+	// MaxErrors 非严格模式下允许的最大错误数（替换字符与无效字节序列，0 表示不限制）
+	MaxErrors int64 `json:"max_errors"`
+
+	// MaxErrorRate 非严格模式下允许的最大错误率（错误数 / 源数据字节数，0 表示不限制）
+	MaxErrorRate float64 `json:"max_error_rate"`
+
+	// MaxReplacementOffsets ConvertResult.Replacements 记录的最大替换偏移数量（0 表示使用默认值，负数表示不记录）
+	MaxReplacementOffsets int `json:"max_replacement_offsets,omitempty"`
+
+	// ReplacementHook 非严格模式下按源数据顺序对每处替换调用的回调，返回错误时中止转换
+	// （返回的 EncodingError 包含该处的位置）；作用于整块转换（Convert、SmartConvert）和文件处理
+	ReplacementHook func(replacement Replacement) error `json:"-"`
+
 	// BufferSize 转换缓冲区大小
 	BufferSize int `json:"buffer_size"`
 
@@ -46,14 +206,43 @@ type ConverterConfig struct {
 	// ChunkSize 大文件分块大小（字节，默认 1MB）
 	ChunkSize int64 `json:"chunk_size"`
 
-	// PreserveBOM 是否保留 BOM
+	// PreserveBOM 是否保留源数据的 BOM（默认 false；未设置 BOMPolicy 时生效：true 对应 BOMPreserve，false 对应 BOMStrip）
 	PreserveBOM bool `json:"preserve_bom"`
 
-	// NormalizeLineEndings 是否规范化换行符
+	// BOMPolicy 输出 BOM 策略（BOMPreserve、BOMStrip、BOMAdd，为空时按 PreserveBOM 决定），
+	// 作用于整块转换；可通过 ConvertOptions 按次覆盖
+	BOMPolicy string `json:"bom_policy,omitempty"`
+
+	// NormalizeLineEndings 是否将换行符（LF、CRLF、CR）统一为 TargetLineEnding
+	// 作用于整块转换、流式转换和文件处理，改写的数量记录在转换结果中
 	NormalizeLineEndings bool `json:"normalize_line_endings"`
 
 	// TargetLineEnding 目标换行符（LF, CRLF, CR）
 	TargetLineEnding string `json:"target_line_ending"`
+
+	// PivotStrategy 中转策略（direct、buffered、validated，默认 direct）
+	// direct 直接串联解码器与编码器；buffered 先解码到 UTF-8 缓冲区再编码；
+	// validated 在 buffered 基础上校验中间 UTF-8 的有效性
+	PivotStrategy string `json:"pivot_strategy"`
+
+	// PivotHook 中转回调（仅 buffered/validated 策略调用），可用于捕获中间文本
+	PivotHook func(info *PivotInfo) `json:"-"`
+
+	// PositionMapInterval 位置映射采样间隔（源字节数，0 表示不生成，1 表示完整映射）
+	PositionMapInterval int `json:"position_map_interval"`
+
+	// FinalNewline 末尾换行符策略（preserve、ensure、strip，默认 preserve）
+	// 作用于整块转换（Convert、SmartConvert）和文件处理（包括超过流式阈值的大文件），StreamProcessor 分块转换不应用
+	FinalNewline string `json:"final_newline"`
+
+	// AllowedEncodings 允许转换的编码白名单（为空表示不限制）
+	AllowedEncodings []string `json:"allowed_encodings,omitempty"`
+
+	// DeniedEncodings 禁止转换的编码黑名单（优先于白名单）
+	DeniedEncodings []string `json:"denied_encodings,omitempty"`
+
+	// Metrics 性能监控器（记录每次转换的耗时、错误，实现 EncodingMetricsCollector 时还记录字节数和丢失的字符）
+	Metrics MetricsCollector `json:"-"`
 }
 
 // ProcessorConfig 处理器配置（集成配置）
@@ -61,12 +250,24 @@ type ProcessorConfig struct {
 	// DetectorConfig 检测器配置
 	DetectorConfig *DetectorConfig `json:"detector_config"`
 
+	// Detector 自定义检测器（nil 表示按 DetectorConfig 创建默认检测器）
+	Detector Detector `json:"-"`
+
+	// SmartDetection 文件处理和流处理是否使用 SmartDetectEncoding（基于内容分析的智能检测）检测源编码
+	SmartDetection bool `json:"smart_detection,omitempty"`
+
 	// ConverterConfig 转换器配置
 	ConverterConfig *ConverterConfig `json:"converter_config"`
 
 	// EnableMetrics 是否启用性能监控
 	EnableMetrics bool `json:"enable_metrics"`
 
+	// Metrics 性能监控器（EnableMetrics 为 true 时传递给未设置 Metrics 的检测器和转换器配置）
+	Metrics MetricsCollector `json:"-"`
+
+	// TracerProvider 分布式追踪器（nil 表示不追踪），为检测、转换、文件处理和流处理创建跨度
+	TracerProvider TracerProvider `json:"-"`
+
 	// LogLevel 日志级别
 	LogLevel string `json:"log_level"`
 
@@ -76,7 +277,23 @@ type ProcessorConfig struct {
 	// TempDir 临时文件目录
 	TempDir string `json:"temp_dir"`
 
-	// MaxFileSize 最大文件大小（字节，0 表示无限制）
+	// MaxFileSize 文件大小硬限制，超过时拒绝处理（字节，0 表示无限制）
+	MaxFileSize int64 `json:"max_file_size"`
+
+	// StreamingThreshold 文件大小软限制，超过时改用流式处理而不是整体读入内存（字节，0 表示不切换）
+	StreamingThreshold int64 `json:"streaming_threshold"`
+
+	// AlternativeMargin SmartConvert 的歧义判定阈值：次优候选编码与首选编码的综合得分差距
+	// 不超过该值时，同时返回按次优候选转换的结果（0 表示不返回）
+	AlternativeMargin float64 `json:"alternative_margin,omitempty"`
+}
+
+// ValidatorConfig 编码校验器配置
+type ValidatorConfig struct {
+	// MaxViolations 最多报告的无效字节序列数量（0 表示使用默认值，负数表示不限制）
+	MaxViolations int `json:"max_violations"`
+
+	// MaxFileSize ValidateFile 的文件大小限制（字节，0 表示无限制）
 	MaxFileSize int64 `json:"max_file_size"`
 }
 
@@ -88,6 +305,7 @@ func GetDefaultDetectorConfig() *DetectorConfig {
 		EnableCache:             true,
 		CacheSize:               DefaultCacheSize,
 		CacheTTL:                DefaultCacheTTL,
+		CacheJanitorInterval:    DefaultCacheJanitorInterval,
 		EnableLanguageDetection: false,
 		SupportedEncodings: []string{
 			EncodingUTF8,
@@ -98,9 +316,16 @@ func GetDefaultDetectorConfig() *DetectorConfig {
 			EncodingGB2312,
 			EncodingGB18030,
 			EncodingBIG5,
+			EncodingBIG5HKSCS,
+			EncodingCP950,
+			EncodingHZGB2312,
 			EncodingShiftJIS,
+			EncodingCP932,
 			EncodingEUCJP,
+			EncodingISO2022JP,
 			EncodingEUCKR,
+			EncodingCP949,
+			EncodingISO2022KR,
 			EncodingISO88591,
 			EncodingWindows1252,
 		},
@@ -109,6 +334,38 @@ func GetDefaultDetectorConfig() *DetectorConfig {
 			EncodingGBK,
 			EncodingBIG5,
 		},
+		ContentClass:       ContentClassAuto,
+		Ensemble:           GetDefaultEnsembleConfig(),
+		UseDeclaredCharset: true,
+	}
+}
+
+// GetDefaultEnsembleConfig 获取默认集成投票配置
+func GetDefaultEnsembleConfig() *EnsembleConfig {
+	return &EnsembleConfig{
+		ScoringWeight:            1.0,
+		TraditionalWeight:        0.2,
+		TraditionalMinConfidence: 0.8,
+		Calibration: map[string]float64{
+			MethodChardet:           1.0,
+			MethodChineseHeuristic:  1.0,
+			MethodJapaneseHeuristic: 1.0,
+			MethodKoreanHeuristic:   1.0,
+			MethodTraditional:       1.0,
+		},
+		TieMargin:  0.01,
+		TieBreaker: TieBreakPreferred,
+	}
+}
+
+// GetDefaultScoringConfig 获取默认候选评分配置
+func GetDefaultScoringConfig() *ScoringConfig {
+	return &ScoringConfig{
+		ConfidenceWeight: 0.4,
+		ScriptWeight:     0.3,
+		ValidityWeight:   0.2,
+		GarbledWeight:    0.1,
+		Models:           []*LanguageModel{ChineseLanguageModel(), JapaneseLanguageModel(), KoreanLanguageModel()},
 	}
 }
 
@@ -123,18 +380,29 @@ func GetDefaultConverterConfig() *ConverterConfig {
 		PreserveBOM:            false,
 		NormalizeLineEndings:   false,
 		TargetLineEnding:       LineEndingLF,
+		PivotStrategy:          PivotDirect,
+		FinalNewline:           FinalNewlinePreserve,
 	}
 }
 
 // GetDefaultProcessorConfig 获取默认处理器配置
 func GetDefaultProcessorConfig() *ProcessorConfig {
 	return &ProcessorConfig{
-		DetectorConfig:  GetDefaultDetectorConfig(),
-		ConverterConfig: GetDefaultConverterConfig(),
-		EnableMetrics:   true,
-		LogLevel:        "info",
-		Logger:          nil, // 使用默认日志记录器
-		TempDir:         "",  // 使用系统临时目录
-		MaxFileSize:     DefaultMaxFileSize,
+		DetectorConfig:     GetDefaultDetectorConfig(),
+		ConverterConfig:    GetDefaultConverterConfig(),
+		EnableMetrics:      true,
+		LogLevel:           "info",
+		Logger:             nil, // 使用默认日志记录器
+		TempDir:            "",  // 使用系统临时目录
+		MaxFileSize:        DefaultMaxFileSize,
+		StreamingThreshold: DefaultStreamingThreshold,
 	}
-}
\ No newline at end of file
+}
+
+// GetDefaultValidatorConfig 获取默认编码校验器配置
+func GetDefaultValidatorConfig() *ValidatorConfig {
+	return &ValidatorConfig{
+		MaxViolations: DefaultMaxViolations,
+		MaxFileSize:   DefaultMaxFileSize,
+	}
+}
@@ -2,9 +2,16 @@ package encoding
 
 import "time"
 
+// Version 库版本号
+const Version = "1.0.0"
+
+// DetectionSchemaVersion DetectionResult JSON 序列化格式的 schema 版本
+const DetectionSchemaVersion = 1
+
 // 支持的编码格式
 const (
 	EncodingUTF8        = "UTF-8"
+	EncodingUTF8BOM     = "UTF-8-BOM" // 带 BOM 的 UTF-8（编码时写入 BOM，解码时去除 BOM）
 	EncodingUTF16       = "UTF-16"
 	EncodingUTF16LE     = "UTF-16LE"
 	EncodingUTF16BE     = "UTF-16BE"
@@ -14,21 +21,57 @@ const (
 	EncodingGBK         = "GBK"
 	EncodingGB2312      = "GB2312"
 	EncodingGB18030     = "GB18030"
-	EncodingBIG5        = "BIG5"
+	EncodingHZGB2312    = "HZ-GB-2312" // 7 位转义编码（~{ 与 ~} 之间为 GB2312）
+	EncodingBIG5        = "BIG5"       // 包含 HKSCS 扩展（与 BIG5-HKSCS 相同）
+	EncodingBIG5HKSCS   = "BIG5-HKSCS" // 香港增补字符集
+	EncodingCP950       = "CP950"      // Windows 繁体中文代码页（Big5 加微软扩展，不含 HKSCS）
 	EncodingShiftJIS    = "SHIFT_JIS"
+	EncodingCP932       = "CP932" // Windows 日文代码页（Shift_JIS 加 NEC、IBM 扩展）
 	EncodingEUCJP       = "EUC-JP"
+	EncodingISO2022JP   = "ISO-2022-JP" // 7 位转义编码，常见于旧的日文邮件
 	EncodingEUCKR       = "EUC-KR"
+	EncodingCP949       = "CP949"       // Windows 韩文代码页（统一韩文码，EUC-KR 的超集）
+	EncodingISO2022KR   = "ISO-2022-KR" // 7 位转义编码（SO/SI 切换 KS X 1001）
 	EncodingISO88591    = "ISO-8859-1"
 	EncodingISO88592    = "ISO-8859-2"
+	EncodingISO88593    = "ISO-8859-3"
+	EncodingISO88594    = "ISO-8859-4"
 	EncodingISO88595    = "ISO-8859-5"
+	EncodingISO88596    = "ISO-8859-6"
+	EncodingISO88597    = "ISO-8859-7"
+	EncodingISO88598    = "ISO-8859-8"
+	EncodingISO88598I   = "ISO-8859-8-I" // 逻辑顺序的希伯来文，字节映射与 ISO-8859-8 相同
+	EncodingISO88599    = "ISO-8859-9"
+	EncodingISO885910   = "ISO-8859-10"
+	EncodingISO885913   = "ISO-8859-13"
+	EncodingISO885914   = "ISO-8859-14"
 	EncodingISO885915   = "ISO-8859-15"
+	EncodingISO885916   = "ISO-8859-16"
+	EncodingWindows874  = "WINDOWS-874"
 	EncodingWindows1250 = "WINDOWS-1250"
 	EncodingWindows1251 = "WINDOWS-1251"
 	EncodingWindows1252 = "WINDOWS-1252"
+	EncodingWindows1253 = "WINDOWS-1253"
 	EncodingWindows1254 = "WINDOWS-1254"
+	EncodingWindows1255 = "WINDOWS-1255"
+	EncodingWindows1256 = "WINDOWS-1256"
+	EncodingWindows1257 = "WINDOWS-1257"
+	EncodingWindows1258 = "WINDOWS-1258"
+	EncodingTIS620      = "TIS-620" // 按 WINDOWS-874 实现（TIS-620 的超集）
 	EncodingKOI8R       = "KOI8-R"
+	EncodingKOI8U       = "KOI8-U"
+	EncodingCP437       = "CP437"
+	EncodingCP850       = "CP850"
+	EncodingCP852       = "CP852"
+	EncodingCP855       = "CP855"
+	EncodingCP858       = "CP858"
+	EncodingCP860       = "CP860"
+	EncodingCP862       = "CP862"
+	EncodingCP863       = "CP863"
+	EncodingCP865       = "CP865"
 	EncodingCP866       = "CP866"
 	EncodingMacintosh   = "MACINTOSH"
+	EncodingMacCyrillic = "X-MAC-CYRILLIC"
 )
 
 // 操作类型
@@ -39,22 +82,144 @@ const (
 	OperationValidate = "validate"
 )
 
+// 检测方法名称
+const (
+	MethodChardet           = "chardet"                // chardet 统计检测
+	MethodChineseHeuristic  = "chinese_heuristic"      // 中文字节特征启发式
+	MethodJapaneseHeuristic = "japanese_heuristic"     // 日文字节特征启发式（假名）
+	MethodKoreanHeuristic   = "korean_heuristic"       // 韩文字节特征启发式（谚文）
+	MethodTraditional       = "traditional"            // 传统检测路径
+	MethodEnsemble          = "ensemble"               // 集成投票
+	MethodRule              = "rule"                   // 检测覆盖规则
+	MethodContext           = "context"                // 同目录兄弟文件编码上下文
+	MethodSpecified         = "specified"              // 调用方指定的源编码
+	MethodDeclaration       = "declaration"            // 文档内的编码声明
+	MethodRawLatin1         = "raw-latin1 passthrough" // 无法检测时按 ISO-8859-1 逐字节透传
+	MethodCharsetTag        = "charset_tag"            // 文件的编码扩展属性
+	MethodContentType       = "content_type"           // HTTP Content-Type 头的 charset 参数
+	MethodBruteForce        = "brute_force"            // 穷举解码（BruteForceDecode）
+)
+
+// 集成投票平局判定规则
+const (
+	TieBreakPreferred   = "preferred"   // 按 PreferredEncodings 顺序
+	TieBreakTraditional = "traditional" // 优先传统检测结果
+	TieBreakConfidence  = "confidence"  // 优先原始置信度较高者
+)
+
+// 批量处理调度顺序
+const (
+	BatchOrderInput         = "input"          // 按输入顺序
+	BatchOrderSmallestFirst = "smallest_first" // 小文件优先
+	BatchOrderLargestFirst  = "largest_first"  // 大文件优先
+	BatchOrderOldestFirst   = "oldest_first"   // 修改时间较早者优先
+	BatchOrderNewestFirst   = "newest_first"   // 修改时间较晚者优先
+)
+
+// BOM 处理策略
+const (
+	BOMPreserve = "preserve" // 保持原样
+	BOMStrip    = "strip"    // 去除开头的 BOM
+	BOMAdd      = "add"      // 确保 Unicode 输出以 BOM 开头
+)
+
+// 样式表和脚本内编码声明的处理策略
+const (
+	CharsetDeclarationPreserve = "preserve" // 保持原样
+	CharsetDeclarationRewrite  = "rewrite"  // 改写为目标编码
+	CharsetDeclarationRemove   = "remove"   // 删除声明
+)
+
+// 兼容模式
+const (
+	CompatibilityICU = "icu" // 接受 ICU 转换器名称及别名，并使用 ICU 的默认替换行为
+)
+
+// 无法表示字符的处理方式（对应 ICU 的 from-Unicode 回调）
+const (
+	UnmappableSubstitute = "substitute" // 替换为替换字符（默认）
+	UnmappableSkip       = "skip"       // 跳过无法表示的字符
+	UnmappableStop       = "stop"       // 返回错误，等同于严格模式
+	UnmappableEscape     = "escape"     // 替换为 ICU 风格的转义序列，如 %U4E2D
+)
+
+// 无效字节序列和无法表示字符的有损处理策略（ConverterConfig.InvalidBytePolicy）
+const (
+	InvalidByteReplace        = "replace"          // 无效字节序列替换为 InvalidByteReplacement，无法表示的字符替换为 InvalidCharReplacement
+	InvalidByteSkip           = "skip"             // 丢弃
+	InvalidByteHexEscape      = "hex_escape"       // 按字节输出 \xNN（无法表示的字符输出其 UTF-8 字节）
+	InvalidByteNumericCharRef = "numeric_char_ref" // 输出数值字符引用 &#xNNNN;（无效字节按 ISO-8859-1 解释）
+	InvalidByteFail           = "fail"             // 返回错误，无法表示的字符等同于严格模式
+)
+
 // 默认配置值
 const (
-	DefaultSampleSize         = 8192        // 默认检测样本大小
-	DefaultMinConfidence      = 0.8         // 默认最小置信度
-	DefaultBufferSize         = 8192        // 默认缓冲区大小
-	DefaultInvalidChar        = "?"         // 默认无效字符替换
-	DefaultBackupSuffix       = ".bak"      // 默认备份后缀
-	DefaultChunkSize          = 1024 * 1024 // 默认分块大小 (1MB)
-	DefaultMaxFileSize        = 100 << 20   // 默认最大文件大小 (100MB)
-	DefaultCacheSize          = 1000        // 默认缓存大小
-	DefaultCacheTTL           = time.Hour   // 默认缓存过期时间
+	DefaultSampleSize            = 8192            // 默认检测样本大小
+	DefaultMinConfidence         = 0.8             // 默认最小置信度
+	DefaultBufferSize            = 8192            // 默认缓冲区大小
+	DefaultInvalidChar           = "?"             // 默认无效字符替换
+	DefaultBackupSuffix          = ".bak"          // 默认备份后缀
+	DefaultSidecarSuffix         = ".encmeta.json" // 默认元数据旁路文件后缀
+	DefaultChunkSize             = 1024 * 1024     // 默认分块大小 (1MB)
+	DefaultMaxFileSize           = 100 << 20       // 默认最大文件大小 (100MB)
+	DefaultStreamingThreshold    = 32 << 20        // 默认流式处理阈值 (32MB)
+	DefaultCacheSize             = 1000            // 默认缓存大小
+	DefaultCacheTTL              = time.Hour       // 默认缓存过期时间
+	DefaultCacheJanitorInterval  = 5 * time.Minute // 默认过期缓存条目的清理间隔
+	DefaultGarbledThreshold      = 0.3             // 默认乱码判定阈值
+	DefaultMinLanguageScore      = 0.75            // 默认最小语言得分
+	DefaultPrescoreTopK          = 3               // 默认分级评分时转换完整样本的候选数量
+	DefaultMaxViolations         = 10              // 默认校验报告的最大无效字节序列数量
+	DefaultMaxReplacementOffsets = 100             // 默认转换结果记录的最大替换偏移数量
 )
 
+// 语言代码（ISO 639-1，与检测结果的 Language 字段一致）
+const (
+	LanguageChinese  = "zh"
+	LanguageJapanese = "ja"
+	LanguageKorean   = "ko"
+	LanguageRussian  = "ru"
+	LanguageEnglish  = "en"
+	LanguageGerman   = "de"
+	LanguageFrench   = "fr"
+	LanguageSpanish  = "es"
+)
+
+// DirOptionsFile 目录级默认选项文件名（见 DirOptions）
+const DirOptionsFile = ".encproc.json"
+
 // 换行符常量
 const (
 	LineEndingLF   = "\n"   // Unix/Linux 换行符
 	LineEndingCRLF = "\r\n" // Windows 换行符
 	LineEndingCR   = "\r"   // Classic Mac 换行符
-)
\ No newline at end of file
+)
+
+// 末尾换行符策略
+const (
+	FinalNewlinePreserve = "preserve" // 保持与源数据一致
+	FinalNewlineEnsure   = "ensure"   // 确保以换行符结尾
+	FinalNewlineStrip    = "strip"    // 去除末尾换行符
+)
+
+// 转换中转策略
+const (
+	PivotDirect    = "direct"    // 直接串联转换器
+	PivotBuffered  = "buffered"  // 解码到 UTF-8 缓冲区后再编码
+	PivotValidated = "validated" // 缓冲中转并校验中间 UTF-8
+)
+
+// 内容类别
+const (
+	ContentClassAuto  = "auto"  // 自动判断
+	ContentClassCode  = "code"  // 程序源代码
+	ContentClassProse = "prose" // 普通文本
+)
+
+// 日志级别（ProcessorConfig.LogLevel）
+const (
+	LogLevelDebug = "debug" // 输出检测结果等调试信息
+	LogLevelInfo  = "info"  // 输出替换字符、创建备份等处理事件
+	LogLevelWarn  = "warn"  // 只输出回退等需要注意的事件
+	LogLevelError = "error" // 只输出错误
+)
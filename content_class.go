@@ -0,0 +1,118 @@
+package encoding
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+// codeKeywords 常见编程语言关键字（用于估算关键字密度）
+var codeKeywords = []string{
+	"func", "function", "def", "class", "return", "import", "package",
+	"include", "public", "private", "static", "void", "const", "var",
+	"let", "if", "else", "for", "while", "switch", "case", "struct",
+	"namespace", "using", "begin", "end", "then", "elif", "echo",
+}
+
+// codeLinePrefixes 常见的代码行或注释行前缀
+var codeLinePrefixes = [][]byte{
+	[]byte("//"), []byte("/*"), []byte("*"), []byte("#"), []byte("--"), []byte("'"),
+}
+
+// DetectContentClass 根据 shebang、括号和关键字密度判断数据是源代码还是普通文本
+func DetectContentClass(data []byte) string {
+	if len(data) == 0 {
+		return ContentClassProse
+	}
+
+	// shebang 直接判定为代码
+	if bytes.HasPrefix(data, []byte("#!")) {
+		return ContentClassCode
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	nonEmpty := 0
+	codeLines := 0
+	for _, line := range lines {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		nonEmpty++
+		if isCodeLine(line) {
+			codeLines++
+		}
+	}
+
+	if nonEmpty == 0 {
+		return ContentClassProse
+	}
+
+	if float64(codeLines)/float64(nonEmpty) >= 0.3 {
+		return ContentClassCode
+	}
+	return ContentClassProse
+}
+
+// isCodeLine 判断单行是否具有代码特征
+func isCodeLine(line []byte) bool {
+	switch line[len(line)-1] {
+	case ';', '{', '}', ')', ':':
+		return true
+	}
+
+	for _, prefix := range codeLinePrefixes {
+		if bytes.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return false
+	}
+	for _, keyword := range codeKeywords {
+		if fields[0] == keyword {
+			return true
+		}
+	}
+	return false
+}
+
+// contentClass 获取生效的内容类别
+func (d *defaultDetector) contentClass(data []byte) string {
+	switch d.config.ContentClass {
+	case ContentClassCode, ContentClassProse:
+		return d.config.ContentClass
+	default:
+		return DetectContentClass(data)
+	}
+}
+
+// isCodeSymbol 检查字符是否为源代码中常见的合法符号
+func isCodeSymbol(r rune) bool {
+	switch {
+	case r == '\f' || r == '\v':
+		return true
+	case r >= 0xA0 && r <= 0xFF: // Latin-1 符号（©、°、± 等）
+		return true
+	case r >= 0x2000 && r <= 0x206F: // 通用标点
+		return true
+	case r >= 0x2190 && r <= 0x22FF: // 箭头与数学运算符
+		return true
+	case r >= 0x2500 && r <= 0x257F: // 制表符（注释中的框线）
+		return true
+	}
+	return false
+}
+
+// nonASCIIText 提取文本中的非 ASCII 字符
+func nonASCIIText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if r >= utf8.RuneSelf {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
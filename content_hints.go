@@ -0,0 +1,210 @@
+package encoding
+
+import (
+	"bytes"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mirbf/encoding-processor/converter"
+	"github.com/mirbf/encoding-processor/detector"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
+)
+
+// 编码提示来源（DetectionDetails.HintSource）
+const (
+	HintSourceBOM            = "bom"             // 字节顺序标记
+	HintSourceHeader         = "header"          // 调用方传入的 HTTP Content-Type 头
+	HintSourceContentType    = "content_type"    // 数据中的 Content-Type 头（如保存的 HTTP 响应、邮件）
+	HintSourceXMLDeclaration = "xml_declaration" // XML 声明
+	HintSourceMeta           = "meta"            // HTML meta 标签
+	HintSourceHeuristic      = "heuristic"       // 字节特征检测
+)
+
+// contentTypeLinePattern 数据开头的 Content-Type 头
+var contentTypeLinePattern = regexp.MustCompile(`(?im)^content-type:[ \t]*([^\r\n]+)`)
+
+// ContentHint 数据中或传输层的编码提示
+type ContentHint struct {
+	// Source 提示来源（HintSource* 常量）
+	Source string `json:"source"`
+
+	// Charset 声明的字符集标签
+	Charset string `json:"charset"`
+
+	// Encoding 按 WHATWG 规则解析得到的编码名称（无法识别时为空）
+	Encoding string `json:"encoding,omitempty"`
+
+	// Accepted 是否被采用
+	Accepted bool `json:"accepted"`
+}
+
+// DetectWithContentHints 结合编码提示检测数据编码，仿照 WHATWG 的编码嗅探顺序
+//
+// 依次参考 BOM、contentType（HTTP Content-Type 头，可以为空）、数据开头的 Content-Type 头、
+// XML 声明和 HTML meta 标签（前 1024 字节），标签按 WHATWG 规则解析（如 latin1 -> WINDOWS-1252）。
+// 提示只在样本能按其编码无损解码时采用；声明为单字节或多字节旧编码但数据是包含非 ASCII 字符的
+// 有效 UTF-8 时按 UTF-8 处理。没有可采用的提示时使用字节特征检测。
+// 结果的 Details.HintSource 说明胜出的来源，Details.Hints 列出发现的全部提示。
+func (d *defaultDetector) DetectWithContentHints(data []byte, contentType string) (*DetectionResult, error) {
+	if len(data) == 0 {
+		return nil, &EncodingError{
+			Op:  OperationDetect,
+			Err: ErrInvalidInput,
+		}
+	}
+
+	if bomResult := d.detectBOM(data); bomResult != nil {
+		bomResult.Details.HintSource = HintSourceBOM
+		return bomResult, nil
+	}
+
+	sample := data
+	truncated := d.config.SampleSize > 0 && len(sample) > d.config.SampleSize
+	if truncated {
+		sample = sample[:d.config.SampleSize]
+	}
+
+	hints := collectContentHints(sample, contentType)
+	for i := range hints {
+		hint := &hints[i]
+		if hint.Encoding == "" || !acceptsHint(hint.Encoding, sample, truncated) {
+			continue
+		}
+		hint.Accepted = true
+
+		method, confidence := MethodDeclaration, 0.9
+		if hint.Source == HintSourceHeader {
+			method, confidence = MethodContentType, 0.95
+		}
+		return &DetectionResult{
+			Encoding:   hint.Encoding,
+			Confidence: confidence,
+			Details: &DetectionDetails{
+				Method:     method,
+				Charset:    hint.Charset,
+				HintSource: hint.Source,
+				Hints:      hints,
+			},
+		}, nil
+	}
+
+	result, err := d.DetectEncoding(data)
+	if err != nil {
+		return nil, err
+	}
+	details := &DetectionDetails{}
+	if result.Details != nil {
+		copied := *result.Details
+		details = &copied
+	}
+	details.HintSource = HintSourceHeuristic
+	details.Hints = hints
+	heuristic := *result
+	heuristic.Details = details
+	return &heuristic, nil
+}
+
+// collectContentHints 按优先级收集编码提示（传输层的 Content-Type 头优先）
+func collectContentHints(data []byte, contentType string) []ContentHint {
+	var hints []ContentHint
+	if charset := contentTypeCharset(contentType); charset != "" {
+		hints = append(hints, newContentHint(HintSourceHeader, charset))
+	}
+
+	head := data
+	if len(head) > declarationScanLimit {
+		head = head[:declarationScanLimit]
+	}
+	if match := contentTypeLinePattern.FindSubmatch(head); match != nil {
+		if charset := contentTypeCharset(string(match[1])); charset != "" {
+			hints = append(hints, newContentHint(HintSourceContentType, charset))
+		}
+	}
+	if match := xmlDeclarationPattern.FindSubmatch(head); match != nil {
+		hints = append(hints, newContentHint(HintSourceXMLDeclaration, string(match[1])))
+	}
+	if match := htmlMetaPattern.FindSubmatch(head); match != nil {
+		hints = append(hints, newContentHint(HintSourceMeta, string(match[1])))
+	}
+	return hints
+}
+
+// contentTypeCharset 返回 Content-Type 值中的 charset 参数（没有时返回空字符串）
+func contentTypeCharset(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return params["charset"]
+}
+
+// newContentHint 创建编码提示，按 WHATWG 规则解析字符集标签
+func newContentHint(source, charset string) ContentHint {
+	hint := ContentHint{Source: source, Charset: charset}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return hint
+	}
+	name, err := htmlindex.Name(enc)
+	if err != nil {
+		return hint
+	}
+
+	// WHATWG 预扫描：文档内声明的 UTF-16 视为 UTF-8（能读出 ASCII 声明的数据不可能是 UTF-16），
+	// x-user-defined 视为 windows-1252
+	switch {
+	case source != HintSourceHeader && strings.HasPrefix(name, "utf-16"):
+		name = EncodingUTF8
+	case name == "x-user-defined":
+		name = EncodingWindows1252
+	}
+	if resolved, ok := converter.Resolve(name); ok {
+		hint.Encoding = resolved
+	}
+	return hint
+}
+
+// acceptsHint 检查样本能否按提示的编码无损解码，且不是被误标为旧编码的 UTF-8
+func acceptsHint(encodingName string, sample []byte, truncated bool) bool {
+	if encodingName != EncodingUTF8 && !detector.IsASCII(sample) && utf8.Valid(trimIncompleteUTF8(sample, truncated)) {
+		return false
+	}
+	enc, err := converter.Lookup(encodingName)
+	if err != nil {
+		return false
+	}
+	return decodesCleanly(enc, sample, truncated)
+}
+
+// decodesCleanly 检查样本能否无损解码（truncated 为 true 时忽略末尾被截断的不完整字符）
+func decodesCleanly(enc encoding.Encoding, sample []byte, truncated bool) bool {
+	dst := make([]byte, 4*len(sample)+utf8.UTFMax)
+	nDst, _, err := enc.NewDecoder().Transform(dst, sample, !truncated)
+	if err != nil && !(truncated && err == transform.ErrShortSrc) {
+		return false
+	}
+	return !bytes.ContainsRune(dst[:nDst], utf8.RuneError)
+}
+
+// trimIncompleteUTF8 去除截断样本末尾不完整的 UTF-8 字符
+func trimIncompleteUTF8(sample []byte, truncated bool) []byte {
+	if !truncated {
+		return sample
+	}
+	for i := 1; i < utf8.UTFMax && i <= len(sample); i++ {
+		if utf8.RuneStart(sample[len(sample)-i]) {
+			if !utf8.FullRune(sample[len(sample)-i:]) {
+				return sample[:len(sample)-i]
+			}
+			break
+		}
+	}
+	return sample
+}
@@ -0,0 +1,139 @@
+package encoding
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+)
+
+// replacementChar UTF-8 编码的 U+FFFD
+var replacementChar = []byte(string(utf8.RuneError))
+
+// conversionTrace 单次转换过程中收集的内存占用和质量统计
+type conversionTrace struct {
+	usage MemoryUsage
+
+	// invalidSequences 源数据中的无效字节序列数
+	invalidSequences int64
+
+	// replacedChars 因目标编码无法表示而被替换的字符数
+	replacedChars int64
+
+	// lostRunes 各个被替换或丢弃的字符出现次数
+	lostRunes map[rune]int64
+
+	// replacements 各处替换的位置（整块转换发生替换后由 reportReplacements 生成）
+	replacements *ReplacementReport
+
+	// bomStripped 源数据的 BOM 是否被去除
+	bomStripped bool
+
+	// bomAdded 是否为输出补充了源数据没有的 BOM
+	bomAdded bool
+
+	// lineEndingsConverted 被统一为目标换行符的换行符数量
+	lineEndingsConverted int64
+}
+
+// memory 返回内存占用记录（t 为 nil 时返回 nil）
+func (t *conversionTrace) memory() *MemoryUsage {
+	if t == nil {
+		return nil
+	}
+	return &t.usage
+}
+
+// recordLost 记录一个因目标编码无法表示而被替换或丢弃的字符
+func (t *conversionTrace) recordLost(r rune) {
+	t.replacedChars++
+	if t.lostRunes == nil {
+		t.lostRunes = make(map[rune]int64)
+	}
+	t.lostRunes[r]++
+}
+
+// mergeChunk 汇总分块转换中单个分块的统计（t 或 chunk 为 nil 时忽略）
+func (t *conversionTrace) mergeChunk(chunk *conversionTrace) {
+	if t == nil || chunk == nil {
+		return
+	}
+	t.invalidSequences += chunk.invalidSequences
+	t.replacedChars += chunk.replacedChars
+	for r, count := range chunk.lostRunes {
+		if t.lostRunes == nil {
+			t.lostRunes = make(map[rune]int64, len(chunk.lostRunes))
+		}
+		t.lostRunes[r] += count
+	}
+}
+
+// lostHistogram 返回以字符为键的丢失字符直方图（没有丢失字符时返回 nil）
+func (t *conversionTrace) lostHistogram() map[string]int64 {
+	if t == nil {
+		return nil
+	}
+	return mergeLostRunes(nil, t.lostRunes)
+}
+
+// mergeLostRunes 将按字符统计的次数累加到直方图中（dst 为 nil 且有数据时新建）
+func mergeLostRunes(dst map[string]int64, src map[rune]int64) map[string]int64 {
+	for r, count := range src {
+		if dst == nil {
+			dst = make(map[string]int64, len(src))
+		}
+		dst[string(r)] += count
+	}
+	return dst
+}
+
+// MergeLostRunes 合并丢失字符直方图，返回合并结果（dst 为 nil 且有数据时新建）
+func MergeLostRunes(dst, src map[string]int64) map[string]int64 {
+	for char, count := range src {
+		if dst == nil {
+			dst = make(map[string]int64, len(src))
+		}
+		dst[char] += count
+	}
+	return dst
+}
+
+// errors 返回替换字符与无效字节序列总数
+func (t *conversionTrace) errors() int64 {
+	return t.invalidSequences + t.replacedChars
+}
+
+// checkErrorThreshold 检查错误数是否超过阈值（maxErrors、maxRate 为 0 表示不限制）
+func checkErrorThreshold(errors, bytes, maxErrors int64, maxRate float64) error {
+	exceeded := maxErrors > 0 && errors > maxErrors
+	if maxRate > 0 && bytes > 0 && float64(errors)/float64(bytes) > maxRate {
+		exceeded = true
+	}
+	if !exceeded {
+		return nil
+	}
+	return &ErrorThresholdError{
+		Errors:       errors,
+		Bytes:        bytes,
+		MaxErrors:    maxErrors,
+		MaxErrorRate: maxRate,
+	}
+}
+
+// invalidCountingDecoder 统计解码器输出的 U+FFFD，即源数据中无法解码的字节序列
+type invalidCountingDecoder struct {
+	decoder transform.Transformer
+	trace   *conversionTrace
+}
+
+// Transform 实现 transform.Transformer 接口
+func (d *invalidCountingDecoder) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	nDst, nSrc, err = d.decoder.Transform(dst, src, atEOF)
+	d.trace.invalidSequences += int64(bytes.Count(dst[:nDst], replacementChar))
+	return nDst, nSrc, err
+}
+
+// Reset 实现 transform.Transformer 接口
+func (d *invalidCountingDecoder) Reset() {
+	d.decoder.Reset()
+}
@@ -2,18 +2,14 @@ package encoding
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"sync"
 	"time"
 
+	"github.com/mirbf/encoding-processor/converter"
 	"golang.org/x/text/encoding"
-	"golang.org/x/text/encoding/charmap"
-	"golang.org/x/text/encoding/japanese"
-	"golang.org/x/text/encoding/korean"
-	"golang.org/x/text/encoding/simplifiedchinese"
-	"golang.org/x/text/encoding/traditionalchinese"
-	"golang.org/x/text/encoding/unicode"
 	"golang.org/x/text/transform"
 )
 
@@ -21,6 +17,8 @@ import (
 type defaultConverter struct {
 	config *ConverterConfig
 	pool   *transformerPool
+	ctx    context.Context // 非 nil 时转换过程中检查取消（见 withContext）
+	labels MetricsLabels   // 上下文中的指标标签（见 withContext）
 	mutex  sync.RWMutex
 }
 
@@ -40,7 +38,7 @@ func NewConverter(config ...*ConverterConfig) Converter {
 	}
 
 	return &defaultConverter{
-		config: cfg,
+		config: canonicalEncodingLists(cfg),
 		pool: &transformerPool{
 			pools: make(map[string]*sync.Pool),
 		},
@@ -49,12 +47,176 @@ func NewConverter(config ...*ConverterConfig) Converter {
 
 // Convert 在指定编码之间转换
 func (c *defaultConverter) Convert(data []byte, from, to string) ([]byte, error) {
+	start := time.Now()
+	var trace *conversionTrace
+	if c.hasErrorThreshold() || c.config.ReplacementHook != nil || c.config.Metrics != nil {
+		trace = &conversionTrace{}
+	}
+
+	result, err := c.traceConversion(data, from, to, trace)
+	c.recordConversion(start, data, trace, err)
+	return result, err
+}
+
+// ConvertContext 在指定编码之间转换，上下文取消或超时后中止转换并返回上下文错误
+func (c *defaultConverter) ConvertContext(ctx context.Context, data []byte, from, to string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &EncodingError{
+			Op:       OperationConvert,
+			Encoding: fmt.Sprintf("%s->%s", from, to),
+			Err:      err,
+		}
+	}
+	return c.withContext(ctx).Convert(data, from, to)
+}
+
+// ConvertWithOptions 按单次转换选项在指定编码之间转换，返回包含 BOM 处理情况的转换结果
+func (c *defaultConverter) ConvertWithOptions(data []byte, from, to string, options *ConvertOptions) (*ConvertResult, error) {
+	start := time.Now()
+	converter := c
+	if options != nil {
+		converter = c.withBOMPolicy(options.BOMPolicy)
+	}
+
+	result, trace, err := converter.convertWithTrace(data, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &ConvertResult{
+		Data:                 result,
+		SourceEncoding:       from,
+		TargetEncoding:       to,
+		BytesProcessed:       int64(len(data)),
+		ConversionTime:       time.Since(start),
+		SourceFinalNewline:   HasFinalNewline(data, from),
+		TargetFinalNewline:   HasFinalNewline(result, to),
+		Memory:               trace.usage,
+		LostRunes:            trace.lostHistogram(),
+		Replacements:         trace.replacements,
+		BOMStripped:          trace.bomStripped,
+		BOMAdded:             trace.bomAdded,
+		LineEndingsConverted: trace.lineEndingsConverted,
+		Warnings:             trace.warnings(),
+	}, nil
+}
+
+// convertWithTrace 执行转换并返回近似内存占用和质量统计，同时记录到配置的性能监控器
+func (c *defaultConverter) convertWithTrace(data []byte, from, to string) ([]byte, *conversionTrace, error) {
+	start := time.Now()
+	trace := &conversionTrace{}
+	result, err := c.traceConversion(data, from, to, trace)
+	c.recordConversion(start, data, trace, err)
+	if err != nil {
+		return nil, trace, err
+	}
+	return result, trace, nil
+}
+
+// traceConversion 执行转换，trace 不为 nil 时将近似内存占用和质量统计记录到 trace
+func (c *defaultConverter) traceConversion(data []byte, from, to string, trace *conversionTrace) ([]byte, error) {
+	body, hadBOM := c.splitSourceBOM(data, from)
+	result, err := c.convertBytes(body, from, to, trace)
+	if err != nil {
+		shiftErrorPosition(err, len(data)-len(body))
+		return nil, err
+	}
+	if err := c.checkErrorThreshold(trace, int64(len(data)), from, to); err != nil {
+		return nil, err
+	}
+	if err := c.reportReplacements(trace, body, len(data)-len(body), from, to); err != nil {
+		return nil, err
+	}
+	if result, err = c.applyLineEndings(result, to, trace); err != nil {
+		return nil, err
+	}
+
+	final := c.applyOutputBOM(c.applyFinalNewline(data, from, result, to), to, hadBOM, trace)
+	if trace != nil && len(final) > len(result) {
+		// 追加换行符或 BOM 时重新分配了输出缓冲区
+		trace.usage.finish(len(data), cap(final))
+	}
+	return final, nil
+}
+
+// recordConversion 向配置的性能监控器记录一次转换（成功时同时记录字节数、近似内存占用和丢失的字符）
+func (c *defaultConverter) recordConversion(start time.Time, data []byte, trace *conversionTrace, err error) {
+	recordOperation(c.config.Metrics, OperationConvert, start, err, c.labels)
+	if err != nil {
+		return
+	}
+	recordBytes(c.config.Metrics, int64(len(data)), c.labels)
+	if trace == nil {
+		return
+	}
+	recordMemory(c.config.Metrics, trace.usage, c.labels)
+	if collector, ok := c.config.Metrics.(EncodingMetricsCollector); ok {
+		collector.RecordLostRunes(trace.lostHistogram())
+	}
+}
+
+// hasErrorThreshold 检查是否配置了非严格模式下的错误阈值
+func (c *defaultConverter) hasErrorThreshold() bool {
+	return !c.strict() && (c.config.MaxErrors > 0 || c.config.MaxErrorRate > 0)
+}
+
+// checkErrorThreshold 检查转换错误是否超过配置的阈值（trace 为 nil 或未配置阈值时忽略）
+func (c *defaultConverter) checkErrorThreshold(trace *conversionTrace, size int64, from, to string) error {
+	if trace == nil || !c.hasErrorThreshold() {
+		return nil
+	}
+
+	if err := checkErrorThreshold(trace.errors(), size, c.config.MaxErrors, c.config.MaxErrorRate); err != nil {
+		return &EncodingError{
+			Op:       OperationConvert,
+			Encoding: fmt.Sprintf("%s->%s", from, to),
+			Err:      err,
+		}
+	}
+	return nil
+}
+
+// convertBytes 执行编码转换（不应用末尾换行符策略，供分块/流式调用）
+//
+// trace 不为 nil 时记录近似内存占用、无效字节序列数和替换字符数。
+func (c *defaultConverter) convertBytes(data []byte, from, to string, trace *conversionTrace) ([]byte, error) {
+	from, to = c.resolveName(from), c.resolveName(to)
+	result, err := c.transcode(data, from, to, trace)
+	if err != nil || to != EncodingUTF8BOM || from == to || len(result) == 0 {
+		return result, err
+	}
+
+	// 带 BOM 的 UTF-8 按 UTF-8 转换后统一补充 BOM，避免分块转换时每块重复写入
+	if !bytes.HasPrefix(result, utf8BOM) {
+		withBOM := make([]byte, 0, len(utf8BOM)+len(result))
+		result = append(append(withBOM, utf8BOM...), result...)
+		trace.memory().finish(len(data), cap(result))
+	}
+	return result, nil
+}
+
+// transcode 执行编码转换的核心流程
+func (c *defaultConverter) transcode(data []byte, from, to string, trace *conversionTrace) ([]byte, error) {
+	usage := trace.memory()
+
+	// 检查编码是否被允许（即使数据为空也要拒绝不允许的编码）
+	for _, name := range []string{from, to} {
+		if err := c.checkEncodingAllowed(name); err != nil {
+			return nil, &EncodingError{
+				Op:       OperationConvert,
+				Encoding: name,
+				Err:      err,
+			}
+		}
+	}
+
 	if len(data) == 0 {
+		usage.finish(0, 0)
 		return []byte{}, nil
 	}
 
 	// 如果源编码和目标编码相同，直接返回
 	if from == to {
+		usage.finish(len(data), 0)
 		return data, nil
 	}
 
@@ -64,10 +226,64 @@ func (c *defaultConverter) Convert(data []byte, from, to string) ([]byte, error)
 		_ = time.Since(start)
 	}()
 
+	// 带 BOM 的 UTF-8 按 UTF-8 编码，BOM 由 convertBytes 统一补充
+	encoderName := to
+	if to == EncodingUTF8BOM {
+		encoderName = EncodingUTF8
+	}
+	if _, _, err := c.codecs(from, encoderName, nil); err != nil {
+		return nil, err
+	}
+
+	// 缓冲中转策略：先完整解码为 UTF-8，再编码到目标编码
+	if c.config.PivotStrategy == PivotBuffered || c.config.PivotStrategy == PivotValidated {
+		return c.convertViaPivot(data, from, to, encoderName, trace)
+	}
+
+	// 创建转换管道: 源编码 -> UTF-8 -> 目标编码（分块并行转换时每块使用独立的管道）
+	newTransformer := func(chunkTrace *conversionTrace) (transform.Transformer, error) {
+		decoder, encoder, err := c.codecs(from, encoderName, chunkTrace)
+		if err != nil {
+			return nil, err
+		}
+		return chainCodecs(from, to, decoder, encoder), nil
+	}
+	if from != EncodingUTF8 && to != EncodingUTF8 {
+		usage.addIntermediate(transformChainOverhead)
+	}
+
+	// 执行转换
+	result, err := c.doTransform(data, from, encoderName, newTransformer, trace)
+	if err != nil {
+		encErr := &EncodingError{
+			Op:       OperationConvert,
+			Encoding: fmt.Sprintf("%s->%s", from, to),
+			Err:      err,
+		}
+		if c.strict() {
+			c.locateUnconvertible(encErr, data, from, encoderName)
+		}
+		return nil, encErr
+	}
+
+	usage.addIntermediate(transformReaderOverhead)
+	if int64(len(data)) > c.config.ChunkSize {
+		// 分块转换时各块的输出在拼接前同时存在
+		usage.addIntermediate(int64(len(result)))
+	}
+	usage.finish(len(data), cap(result))
+
+	return result, nil
+}
+
+// codecs 按转换器配置获取解码器和编码器
+//
+// trace 不为 nil 时统计源数据中的无效字节序列；非严格模式下编码器按配置替换无法表示的字符。
+func (c *defaultConverter) codecs(from, to string, trace *conversionTrace) (decoder, encoder transform.Transformer, err error) {
 	// 获取源编码解码器
-	fromDecoder, err := c.getDecoder(from)
+	decoder, err = c.getDecoder(from)
 	if err != nil {
-		return nil, &EncodingError{
+		return nil, nil, &EncodingError{
 			Op:       OperationConvert,
 			Encoding: from,
 			Err:      fmt.Errorf("failed to get decoder for %s: %w", from, err),
@@ -75,39 +291,120 @@ func (c *defaultConverter) Convert(data []byte, from, to string) ([]byte, error)
 	}
 
 	// 获取目标编码编码器
-	toEncoder, err := c.getEncoder(to)
+	encoder, err = c.getEncoder(to)
 	if err != nil {
-		return nil, &EncodingError{
+		return nil, nil, &EncodingError{
 			Op:       OperationConvert,
 			Encoding: to,
 			Err:      fmt.Errorf("failed to get encoder for %s: %w", to, err),
 		}
 	}
 
-	// 创建转换管道: 源编码 -> UTF-8 -> 目标编码
-	var transformer transform.Transformer
-	if from == EncodingUTF8 {
+	switch {
+	case c.config.InvalidBytePolicy != "":
+		decoder = c.newInvalidBytesDecoder(decoder, from, trace)
+	case trace != nil && from != EncodingUTF8:
+		decoder = &invalidCountingDecoder{decoder: decoder, trace: trace}
+	}
+
+	// 非严格模式下逐字符替换目标编码无法表示的字符
+	if !c.strict() {
+		encoder = c.newReplacingEncoder(encoder, to, trace)
+	}
+
+	// 源编码为 UTF-8 时不经过解码器（见 chainCodecs），由编码器先按策略处理无效字节
+	if c.config.InvalidBytePolicy != "" && from == EncodingUTF8 {
+		encoder = transform.Chain(decoder, encoder)
+	}
+	return decoder, encoder, nil
+}
+
+// chainCodecs 串联解码器和编码器，源或目标为 UTF-8 时省略对应的环节
+func chainCodecs(from, to string, decoder, encoder transform.Transformer) transform.Transformer {
+	switch {
+	case from == EncodingUTF8:
 		// 源编码是 UTF-8，直接编码到目标编码
-		transformer = toEncoder
-	} else if to == EncodingUTF8 {
+		return encoder
+	case to == EncodingUTF8:
 		// 目标编码是 UTF-8，直接从源编码解码
-		transformer = fromDecoder
-	} else {
+		return decoder
+	default:
 		// 两步转换：源编码 -> UTF-8 -> 目标编码
-		transformer = transform.Chain(fromDecoder, toEncoder)
+		return transform.Chain(decoder, encoder)
 	}
+}
 
-	// 执行转换
-	result, err := c.doTransform(data, transformer)
+// newStreamTransformer 按转换器配置创建流式转换器（无需转换、统一换行符或处理 BOM 时返回 nil）
+//
+// 与整块转换共用编码名称解析、白名单检查、严格模式、替换规则和 BOM 策略；
+// 带 BOM 的 UTF-8 目标由编码器在流开头写入一次 BOM。
+func (c *defaultConverter) newStreamTransformer(from, to string, trace *conversionTrace) (transform.Transformer, error) {
+	codec, err := c.newCodecTransformer(from, to, trace)
 	if err != nil {
-		return nil, &EncodingError{
-			Op:       OperationConvert,
-			Encoding: fmt.Sprintf("%s->%s", from, to),
-			Err:      err,
+		return nil, err
+	}
+
+	transformer := codec
+	if normalizer := c.lineEndingNormalizer(c.resolveName(to)); normalizer != nil {
+		normalizer.trace = trace
+		transformer = normalizer
+		if codec != nil {
+			transformer = transform.Chain(codec, normalizer)
+		}
+	}
+	return c.withStreamBOM(transformer, from, to, trace), nil
+}
+
+// newCodecTransformer 创建源编码到目标编码的解码器和编码器管道，不统一换行符
+// （源编码和目标编码相同时返回 nil）
+func (c *defaultConverter) newCodecTransformer(from, to string, trace *conversionTrace) (transform.Transformer, error) {
+	from, to = c.resolveName(from), c.resolveName(to)
+	for _, name := range []string{from, to} {
+		if err := c.checkEncodingAllowed(name); err != nil {
+			return nil, &EncodingError{
+				Op:       OperationConvert,
+				Encoding: name,
+				Err:      err,
+			}
 		}
 	}
+	if from == to {
+		return nil, nil
+	}
 
-	return result, nil
+	decoder, encoder, err := c.codecs(from, to, trace)
+	if err != nil {
+		return nil, err
+	}
+	return chainCodecs(from, to, decoder, encoder), nil
+}
+
+// withStrictMode 返回启用严格模式的转换器副本（已是严格模式时返回自身）
+func (c *defaultConverter) withStrictMode() *defaultConverter {
+	if c.strict() {
+		return c
+	}
+
+	config := *c.config
+	config.StrictMode = true
+	return &defaultConverter{config: &config, pool: c.pool}
+}
+
+// withContext 返回在转换过程中检查上下文、按上下文中的指标标签记录指标的转换器副本
+// （上下文不可取消且不带标签时返回自身）
+func (c *defaultConverter) withContext(ctx context.Context) *defaultConverter {
+	if ctx == nil {
+		return c
+	}
+	labels := MetricsLabelsFromContext(ctx)
+	if ctx.Done() == nil && len(labels) == 0 {
+		return c
+	}
+	copied := &defaultConverter{config: c.config, pool: c.pool, labels: labels}
+	if ctx.Done() != nil {
+		copied.ctx = ctx
+	}
+	return copied
 }
 
 // ConvertToUTF8 转换为 UTF-8 编码
@@ -142,85 +439,165 @@ func (c *defaultConverter) getEncoder(encodingName string) (transform.Transforme
 	return enc.NewEncoder(), nil
 }
 
-// getEncoding 根据编码名称获取编码实例
-func (c *defaultConverter) getEncoding(name string) (encoding.Encoding, error) {
-	switch name {
-	case EncodingUTF8:
-		return unicode.UTF8, nil
-	case EncodingUTF16:
-		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM), nil
-	case EncodingUTF16LE:
-		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), nil
-	case EncodingUTF16BE:
-		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), nil
-	case EncodingUTF32:
-		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM), nil // UTF32 not directly supported, use UTF16
-	case EncodingUTF32LE:
-		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), nil
-	case EncodingUTF32BE:
-		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), nil
-
-	// 中文编码
-	case EncodingGBK, EncodingGB2312:
-		return simplifiedchinese.GBK, nil
-	case EncodingGB18030:
-		return simplifiedchinese.GB18030, nil
-	case EncodingBIG5:
-		return traditionalchinese.Big5, nil
-
-	// 日文编码
-	case EncodingShiftJIS:
-		return japanese.ShiftJIS, nil
-	case EncodingEUCJP:
-		return japanese.EUCJP, nil
-
-	// 韩文编码
-	case EncodingEUCKR:
-		return korean.EUCKR, nil
-
-	// 西欧编码
-	case EncodingISO88591:
-		return charmap.ISO8859_1, nil
-	case EncodingISO88592:
-		return charmap.ISO8859_2, nil
-	case EncodingISO88595:
-		return charmap.ISO8859_5, nil
-	case EncodingISO885915:
-		return charmap.ISO8859_15, nil
-	case EncodingWindows1250:
-		return charmap.Windows1250, nil
-	case EncodingWindows1251:
-		return charmap.Windows1251, nil
-	case EncodingWindows1252:
-		return charmap.Windows1252, nil
-	case EncodingWindows1254:
-		return charmap.Windows1254, nil
-	case EncodingKOI8R:
-		return charmap.KOI8R, nil
-	case EncodingCP866:
-		return charmap.CodePage866, nil
-	case EncodingMacintosh:
-		return charmap.Macintosh, nil
+// strict 检查是否在目标编码无法表示字符时返回错误
+func (c *defaultConverter) strict() bool {
+	return c.config.StrictMode || c.config.UnmappableAction == UnmappableStop || c.config.InvalidBytePolicy == InvalidByteFail
+}
+
+// resolveName 将代码页形式的名称以及 ICU 兼容模式下的 ICU 转换器名称解析为本包的编码名称
+func (c *defaultConverter) resolveName(name string) string {
+	if c.config.CompatibilityMode == CompatibilityICU {
+		if resolved, ok := ResolveICUName(name); ok {
+			return resolved
+		}
+	}
+	return canonicalEncodingName(name)
+}
 
+// replacementString 返回无法表示的字符的固定替换字符串
+func (c *defaultConverter) replacementString() string {
+	switch {
+	case c.config.UnmappableAction == UnmappableSkip:
+		return ""
+	case c.config.CompatibilityMode == CompatibilityICU:
+		return icuSubstitution
 	default:
-		return nil, fmt.Errorf("unsupported encoding: %s", name)
+		return c.config.InvalidCharReplacement
 	}
 }
 
+// newReplacingEncoder 包装 to 编码的编码器，按 SubstitutionCallback、UnmappableAction 或 InvalidBytePolicy 替换无法表示的字符
+//
+// 替换字符串本身无法用目标编码表示时直接丢弃无法表示的字符。
+func (c *defaultConverter) newReplacingEncoder(encoder transform.Transformer, to string, trace *conversionTrace) transform.Transformer {
+	replacing := &replacingEncoder{encoder: encoder, trace: trace}
+
+	callback := c.config.SubstitutionCallback
+	if callback == nil && c.config.UnmappableAction == UnmappableEscape {
+		callback = icuEscape
+	}
+	if callback == nil && c.config.UnmappableAction == "" {
+		callback = c.unmappableCallback()
+	}
+
+	if callback == nil {
+		replacement, _, err := transform.Bytes(encoder, []byte(c.replacementString()))
+		if err == nil {
+			replacing.replacement = replacement
+		}
+		encoder.Reset()
+		return replacing
+	}
+
+	// 回调返回的替换字符串使用独立的编码器编码，避免干扰主编码器的状态
+	if to == EncodingUTF8BOM {
+		to = EncodingUTF8
+	}
+	substitutionEncoder, err := c.getEncoder(to)
+	if err != nil {
+		return replacing
+	}
+	replacing.substitute = func(r rune) []byte {
+		replacement, _, err := transform.Bytes(substitutionEncoder, []byte(callback(r)))
+		if err != nil {
+			return nil
+		}
+		return replacement
+	}
+	return replacing
+}
+
+// canonicalEncodingLists 返回白名单/黑名单各项统一为规范编码名称的配置
+// （各项已是规范名称时返回原配置，否则返回副本，不修改调用方的配置）
+func canonicalEncodingLists(config *ConverterConfig) *ConverterConfig {
+	resolver := &defaultConverter{config: config}
+	allowed, allowedChanged := resolver.resolveNames(config.AllowedEncodings)
+	denied, deniedChanged := resolver.resolveNames(config.DeniedEncodings)
+	if !allowedChanged && !deniedChanged {
+		return config
+	}
+
+	copied := *config
+	copied.AllowedEncodings = allowed
+	copied.DeniedEncodings = denied
+	return &copied
+}
+
+// resolveNames 将编码名称列表解析为规范名称，changed 表示是否有名称被改写
+func (c *defaultConverter) resolveNames(names []string) (resolved []string, changed bool) {
+	resolved = make([]string, len(names))
+	for i, name := range names {
+		resolved[i] = c.resolveName(name)
+		changed = changed || resolved[i] != name
+	}
+	if !changed {
+		return names, false
+	}
+	return resolved, true
+}
+
+// checkEncodingAllowed 检查编码是否满足白名单/黑名单限制（按规范名称比较）
+func (c *defaultConverter) checkEncodingAllowed(name string) error {
+	name = c.resolveName(name)
+	for _, denied := range c.config.DeniedEncodings {
+		if name == denied {
+			return ErrEncodingNotAllowed
+		}
+	}
+
+	if len(c.config.AllowedEncodings) == 0 {
+		return nil
+	}
+
+	for _, allowed := range c.config.AllowedEncodings {
+		if name == allowed {
+			return nil
+		}
+	}
+	return ErrEncodingNotAllowed
+}
+
+// getEncoding 根据编码名称获取编码实例
+func (c *defaultConverter) getEncoding(name string) (encoding.Encoding, error) {
+	name = c.resolveName(name)
+	if err := c.checkEncodingAllowed(name); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, name)
+	}
+
+	return converter.Lookup(name)
+}
+
 // doTransform 执行实际的转换操作
-func (c *defaultConverter) doTransform(data []byte, transformer transform.Transformer) ([]byte, error) {
+//
+// from 和 to 为 data 的编码和转换器的输出编码，用于确定大数据的安全分块边界；
+// newTransformer 为每个分块创建独立的转换器，trace 不为 nil 时汇总各块的统计。
+func (c *defaultConverter) doTransform(data []byte, from, to string, newTransformer transformerFactory, trace *conversionTrace) ([]byte, error) {
 	// 检查内存限制
 	if c.config.MaxMemoryUsage > 0 && int64(len(data)) > c.config.MaxMemoryUsage {
 		return nil, ErrInsufficientMemory
 	}
 
-	// 对于大数据，使用分块处理
+	// 对于大数据，按安全边界分块并行处理
 	if int64(len(data)) > c.config.ChunkSize {
-		return c.transformLargeData(data, transformer)
+		if boundaries := chunkBoundaries(data, from, to, int(c.config.ChunkSize)); len(boundaries) > 2 {
+			return c.transformLargeData(data, boundaries, newTransformer, trace)
+		}
 	}
 
-	// 小数据直接转换
-	return c.transformSmallData(data, transformer)
+	// 小数据（或无法安全分块的数据）直接转换
+	transformer, err := newTransformer(trace)
+	if err != nil {
+		return nil, err
+	}
+	return c.transformSmallData(data, c.cancellable(transformer))
+}
+
+// cancellable 为转换器包装上下文检查（未设置上下文时返回原转换器）
+func (c *defaultConverter) cancellable(transformer transform.Transformer) transform.Transformer {
+	if c.ctx == nil {
+		return transformer
+	}
+	return &contextTransformer{ctx: c.ctx, Transformer: transformer}
 }
 
 // transformSmallData 转换小数据
@@ -228,7 +605,7 @@ func (c *defaultConverter) transformSmallData(data []byte, transformer transform
 	reader := transform.NewReader(bytes.NewReader(data), transformer)
 	result, err := io.ReadAll(reader)
 	if err != nil {
-		if c.config.StrictMode {
+		if c.strict() || (c.ctx != nil && c.ctx.Err() != nil) {
 			return nil, fmt.Errorf("conversion failed: %w", err)
 		}
 		// 非严格模式下，尝试忽略错误继续转换
@@ -237,29 +614,6 @@ func (c *defaultConverter) transformSmallData(data []byte, transformer transform
 	return result, nil
 }
 
-// transformLargeData 转换大数据（分块处理）
-func (c *defaultConverter) transformLargeData(data []byte, transformer transform.Transformer) ([]byte, error) {
-	var result bytes.Buffer
-	chunkSize := int(c.config.ChunkSize)
-	
-	for offset := 0; offset < len(data); offset += chunkSize {
-		end := offset + chunkSize
-		if end > len(data) {
-			end = len(data)
-		}
-		
-		chunk := data[offset:end]
-		converted, err := c.transformSmallData(chunk, transformer)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert chunk at offset %d: %w", offset, err)
-		}
-		
-		result.Write(converted)
-	}
-	
-	return result.Bytes(), nil
-}
-
 // transformWithErrorRecovery 带错误恢复的转换
 func (c *defaultConverter) transformWithErrorRecovery(data []byte, transformer transform.Transformer) ([]byte, error) {
 	var result bytes.Buffer
@@ -296,6 +650,19 @@ func (c *defaultConverter) transformWithErrorRecovery(data []byte, transformer t
 	return result.Bytes(), nil
 }
 
+// Drain 释放池化的转换器
+func (c *defaultConverter) Drain(ctx context.Context) error {
+	c.pool.mutex.Lock()
+	c.pool.pools = make(map[string]*sync.Pool)
+	c.pool.mutex.Unlock()
+	return nil
+}
+
+// Close 释放转换器资源
+func (c *defaultConverter) Close() error {
+	return c.Drain(context.Background())
+}
+
 // getTransformer 从池中获取转换器
 func (c *defaultConverter) getTransformer(key string) transform.Transformer {
 	c.pool.mutex.RLock()
@@ -0,0 +1,94 @@
+package converter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// codePages Windows 代码页编号到编码名称的映射
+var codePages = map[int]string{
+	65001: "UTF-8",
+	1200:  "UTF-16LE",
+	1201:  "UTF-16BE",
+	12000: "UTF-32LE",
+	12001: "UTF-32BE",
+	936:   "GBK",
+	20936: "GB2312",
+	54936: "GB18030",
+	950:   "CP950",
+	932:   "CP932",
+	20932: "EUC-JP",
+	51932: "EUC-JP",
+	949:   "CP949",
+	51949: "EUC-KR",
+	28591: "ISO-8859-1",
+	28592: "ISO-8859-2",
+	28593: "ISO-8859-3",
+	28594: "ISO-8859-4",
+	28595: "ISO-8859-5",
+	28596: "ISO-8859-6",
+	28597: "ISO-8859-7",
+	28598: "ISO-8859-8",
+	38598: "ISO-8859-8-I",
+	28599: "ISO-8859-9",
+	28603: "ISO-8859-13",
+	28605: "ISO-8859-15",
+	874:   "WINDOWS-874",
+	1250:  "WINDOWS-1250",
+	1251:  "WINDOWS-1251",
+	1252:  "WINDOWS-1252",
+	1253:  "WINDOWS-1253",
+	1254:  "WINDOWS-1254",
+	1255:  "WINDOWS-1255",
+	1256:  "WINDOWS-1256",
+	1257:  "WINDOWS-1257",
+	1258:  "WINDOWS-1258",
+	20866: "KOI8-R",
+	21866: "KOI8-U",
+	437:   "CP437",
+	850:   "CP850",
+	852:   "CP852",
+	855:   "CP855",
+	858:   "CP858",
+	860:   "CP860",
+	862:   "CP862",
+	863:   "CP863",
+	865:   "CP865",
+	866:   "CP866",
+	10000: "MACINTOSH",
+	10007: "X-MAC-CYRILLIC",
+}
+
+// codePagePrefixes 代码页名称的常见前缀（已转为小写并去除分隔符）
+var codePagePrefixes = []string{"windows", "codepage", "cp", "ms", "ibm"}
+
+// EncodingFromCodePage 根据 Windows 代码页编号获取编码名称（如 936 -> GBK）
+func EncodingFromCodePage(codePage int) (string, error) {
+	name, ok := codePages[codePage]
+	if !ok {
+		return "", fmt.Errorf("%w: code page %d", ErrUnsupportedEncoding, codePage)
+	}
+	return name, nil
+}
+
+// ResolveCodePage 将代码页形式的名称（如 "cp936"、"936"、"windows-936"）解析为编码名称
+//
+// 比较时忽略大小写以及 "-"、"_"、空格分隔符；不是代码页形式或代码页不受支持时返回 false。
+func ResolveCodePage(name string) (string, bool) {
+	normalized := strings.ToLower(name)
+	normalized = strings.NewReplacer("-", "", "_", "", " ", "").Replace(normalized)
+	for _, prefix := range codePagePrefixes {
+		if strings.HasPrefix(normalized, prefix) {
+			normalized = normalized[len(prefix):]
+			break
+		}
+	}
+
+	codePage, err := strconv.Atoi(normalized)
+	if err != nil || codePage <= 0 {
+		return "", false
+	}
+	encoding, ok := codePages[codePage]
+	return encoding, ok
+}
@@ -0,0 +1,221 @@
+// Package converter 提供指定源编码和目标编码的轻量级转换
+//
+// 本包只依赖 golang.org/x/text，不引入 chardet 和 encoding 包的检测器，
+// 适合始终明确知道编码的调用方。编码名称与 encoding 包的 Encoding* 常量一致。
+//
+// 需要自动检测、文件处理、指标统计等完整能力时请使用 encoding 包。
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/encoding/unicode/utf32"
+	"golang.org/x/text/transform"
+)
+
+// ErrUnsupportedEncoding 不支持的编码
+var ErrUnsupportedEncoding = errors.New("unsupported encoding")
+
+// DefaultReplacement Convert 使用的默认替换字符串
+const DefaultReplacement = "?"
+
+// encodings 编码名称到 x/text 编码实现的映射（可通过 Register 扩展）
+var encodings = map[string]encoding.Encoding{
+	"UTF-8":     unicode.UTF8,
+	"UTF-8-BOM": unicode.UTF8BOM,
+	"UTF-16":    unicode.UTF16(unicode.BigEndian, unicode.UseBOM),
+	"UTF-16LE":  unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+	"UTF-16BE":  unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+	"UTF-32":    utf32.UTF32(utf32.BigEndian, utf32.UseBOM),
+	"UTF-32LE":  utf32.UTF32(utf32.LittleEndian, utf32.IgnoreBOM),
+	"UTF-32BE":  utf32.UTF32(utf32.BigEndian, utf32.IgnoreBOM),
+
+	// 中文编码（x/text 的 Big5 包含 HKSCS 扩展，CP950 为不含 HKSCS 的 Windows 代码页）
+	"GBK":        simplifiedchinese.GBK,
+	"GB2312":     simplifiedchinese.GBK,
+	"GB18030":    simplifiedchinese.GB18030,
+	"HZ-GB-2312": simplifiedchinese.HZGB2312,
+	"BIG5":       traditionalchinese.Big5,
+	"BIG5-HKSCS": traditionalchinese.Big5,
+	"CP950":      CP950,
+
+	// 日文编码（x/text 的 Shift_JIS 即 CP932，包含 NEC、IBM 扩展字符）
+	"SHIFT_JIS":   japanese.ShiftJIS,
+	"EUC-JP":      japanese.EUCJP,
+	"ISO-2022-JP": japanese.ISO2022JP,
+	"CP932":       japanese.ShiftJIS,
+
+	// 韩文编码（x/text 的 EUC-KR 即 CP949，包含统一韩文码的扩展音节）
+	"EUC-KR":      korean.EUCKR,
+	"ISO-2022-KR": ISO2022KR,
+	"CP949":       korean.EUCKR,
+
+	// ISO-8859 系列（ISO-8859-11 使用 TIS-620，ISO-8859-12 未发布）
+	"ISO-8859-1":   charmap.ISO8859_1,
+	"ISO-8859-2":   charmap.ISO8859_2,
+	"ISO-8859-3":   charmap.ISO8859_3,
+	"ISO-8859-4":   charmap.ISO8859_4,
+	"ISO-8859-5":   charmap.ISO8859_5,
+	"ISO-8859-6":   charmap.ISO8859_6,
+	"ISO-8859-7":   charmap.ISO8859_7,
+	"ISO-8859-8":   charmap.ISO8859_8,
+	"ISO-8859-8-I": charmap.ISO8859_8I,
+	"ISO-8859-9":   charmap.ISO8859_9,
+	"ISO-8859-10":  charmap.ISO8859_10,
+	"ISO-8859-13":  charmap.ISO8859_13,
+	"ISO-8859-14":  charmap.ISO8859_14,
+	"ISO-8859-15":  charmap.ISO8859_15,
+	"ISO-8859-16":  charmap.ISO8859_16,
+
+	// Windows 代码页
+	"WINDOWS-874":  charmap.Windows874,
+	"WINDOWS-1250": charmap.Windows1250,
+	"WINDOWS-1251": charmap.Windows1251,
+	"WINDOWS-1252": charmap.Windows1252,
+	"WINDOWS-1253": charmap.Windows1253,
+	"WINDOWS-1254": charmap.Windows1254,
+	"WINDOWS-1255": charmap.Windows1255,
+	"WINDOWS-1256": charmap.Windows1256,
+	"WINDOWS-1257": charmap.Windows1257,
+	"WINDOWS-1258": charmap.Windows1258,
+	"TIS-620":      charmap.Windows874,
+
+	// DOS 代码页
+	"CP437": charmap.CodePage437,
+	"CP850": charmap.CodePage850,
+	"CP852": charmap.CodePage852,
+	"CP855": charmap.CodePage855,
+	"CP858": charmap.CodePage858,
+	"CP860": charmap.CodePage860,
+	"CP862": charmap.CodePage862,
+	"CP863": charmap.CodePage863,
+	"CP865": charmap.CodePage865,
+	"CP866": charmap.CodePage866,
+
+	// 其他单字节编码（EBCDIC 与 ASCII 不兼容，需要时通过 Register 注册）
+	"KOI8-R":         charmap.KOI8R,
+	"KOI8-U":         charmap.KOI8U,
+	"MACINTOSH":      charmap.Macintosh,
+	"X-MAC-CYRILLIC": charmap.MacintoshCyrillic,
+}
+
+// Lookup 根据编码名称获取 x/text 编码实现（名称按 Resolve 解析，接受别名、代码页形式以及 IANA/WHATWG 名称）
+func Lookup(name string) (encoding.Encoding, error) {
+	enc, ok := lookupRegistered(name)
+	if !ok {
+		if resolved, isKnown := Resolve(name); isKnown {
+			enc, ok = lookupRegistered(resolved)
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, name)
+	}
+	return enc, nil
+}
+
+// Convert 将数据从 from 编码转换为 to 编码，目标编码无法表示的字符替换为 DefaultReplacement
+func Convert(data []byte, from, to string) ([]byte, error) {
+	t, err := newTransformer(from, to, false)
+	if err != nil {
+		return nil, err
+	}
+	result, _, err := transform.Bytes(t, data)
+	return result, err
+}
+
+// ConvertStrict 将数据从 from 编码转换为 to 编码，目标编码无法表示字符时返回错误
+func ConvertStrict(data []byte, from, to string) ([]byte, error) {
+	t, err := newTransformer(from, to, true)
+	if err != nil {
+		return nil, err
+	}
+	result, _, err := transform.Bytes(t, data)
+	return result, err
+}
+
+// NewReader 返回从 r 读取 from 编码数据并输出 to 编码数据的读取器
+func NewReader(r io.Reader, from, to string) (io.Reader, error) {
+	t, err := newTransformer(from, to, false)
+	if err != nil {
+		return nil, err
+	}
+	return transform.NewReader(r, t), nil
+}
+
+// NewWriter 返回将 from 编码数据转换为 to 编码后写入 w 的写入器（调用方需 Close 以刷新剩余数据）
+func NewWriter(w io.Writer, from, to string) (io.WriteCloser, error) {
+	t, err := newTransformer(from, to, false)
+	if err != nil {
+		return nil, err
+	}
+	return transform.NewWriter(w, t), nil
+}
+
+// newTransformer 创建 from 编码经 UTF-8 到 to 编码的转换器
+func newTransformer(from, to string, strict bool) (transform.Transformer, error) {
+	fromEnc, err := Lookup(from)
+	if err != nil {
+		return nil, err
+	}
+	toEnc, err := Lookup(to)
+	if err != nil {
+		return nil, err
+	}
+	if from == to {
+		return transform.Nop, nil
+	}
+
+	encoder := transform.Transformer(toEnc.NewEncoder())
+	if !strict {
+		replacement, _, err := transform.Bytes(toEnc.NewEncoder(), []byte(DefaultReplacement))
+		if err != nil {
+			replacement = nil
+		}
+		encoder = &replacingEncoder{encoder: encoder, replacement: replacement}
+	}
+	return transform.Chain(fromEnc.NewDecoder(), encoder), nil
+}
+
+// replacingEncoder 将目标编码无法表示的字符替换为预先编码的替换字节
+type replacingEncoder struct {
+	encoder     transform.Transformer
+	replacement []byte
+}
+
+// Transform 实现 transform.Transformer 接口
+func (e *replacingEncoder) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for {
+		n, m, err := e.encoder.Transform(dst[nDst:], src[nSrc:], atEOF)
+		nDst += n
+		nSrc += m
+		if err == nil || err == transform.ErrShortDst || err == transform.ErrShortSrc {
+			return nDst, nSrc, err
+		}
+
+		// 跳过无法表示的字符并写入替换字节
+		_, size := utf8.DecodeRune(src[nSrc:])
+		if size == 0 {
+			return nDst, nSrc, err
+		}
+		if len(dst)-nDst < len(e.replacement) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], e.replacement)
+		nSrc += size
+	}
+}
+
+// Reset 实现 transform.Transformer 接口
+func (e *replacingEncoder) Reset() {
+	e.encoder.Reset()
+}
@@ -0,0 +1,263 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestConvert 测试指定编码之间的转换
+func TestConvert(t *testing.T) {
+	gbk, err := Convert([]byte("你好"), "UTF-8", "GBK")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(gbk) != "\xc4\xe3\xba\xc3" {
+		t.Errorf("Unexpected GBK bytes %q", gbk)
+	}
+
+	utf8Text, err := Convert(gbk, "GBK", "UTF-8-BOM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(utf8Text) != "\ufeff你好" {
+		t.Errorf("Unexpected UTF-8-BOM text %q", utf8Text)
+	}
+
+	latin1, err := Convert([]byte("café 咖啡"), "UTF-8", "ISO-8859-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(latin1) != "caf\xe9 ??" {
+		t.Errorf("Unexpected replacement result %q", latin1)
+	}
+
+	if _, err := ConvertStrict([]byte("咖啡"), "UTF-8", "ISO-8859-1"); err == nil {
+		t.Error("Expected strict conversion to fail for unrepresentable characters")
+	}
+
+	if _, err := Convert([]byte("x"), "UTF-8", "X-UNKNOWN"); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("Expected ErrUnsupportedEncoding, got %v", err)
+	}
+}
+
+// TestReaderWriter 测试流式读取器与写入器
+func TestReaderWriter(t *testing.T) {
+	r, err := NewReader(strings.NewReader("\xc4\xe3\xba\xc3"), "GBK", "UTF-8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != "你好" {
+		t.Errorf("Unexpected reader output %q", decoded)
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "UTF-8", "GBK")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("你好")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "\xc4\xe3\xba\xc3" {
+		t.Errorf("Unexpected writer output %q", buf.String())
+	}
+}
+
+// TestCodePageAliases 测试 Windows 代码页形式的编码名称
+func TestCodePageAliases(t *testing.T) {
+	for _, name := range []string{"cp936", "936", "windows-936", "CP_936", "MS936"} {
+		if resolved, ok := ResolveCodePage(name); !ok || resolved != "GBK" {
+			t.Errorf("Expected %q to resolve to GBK, got %q %v", name, resolved, ok)
+		}
+	}
+	if _, ok := ResolveCodePage("ISO-8859-1"); ok {
+		t.Error("Expected non-code-page name not to resolve")
+	}
+
+	if name, err := EncodingFromCodePage(1251); err != nil || name != "WINDOWS-1251" {
+		t.Errorf("Expected WINDOWS-1251, got %q %v", name, err)
+	}
+	if _, err := EncodingFromCodePage(37); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("Expected ErrUnsupportedEncoding for code page 37, got %v", err)
+	}
+
+	out, err := Convert([]byte("\xc4\xe3\xba\xc3"), "cp936", "65001")
+	if err != nil || string(out) != "你好" {
+		t.Errorf("Expected code page conversion, got %q %v", out, err)
+	}
+}
+
+// TestUTF32 测试 UTF-32 编解码（有无 BOM、两种字节序）
+func TestUTF32(t *testing.T) {
+	tests := []struct {
+		encoding string
+		expected string
+	}{
+		{"UTF-32LE", "A\x00\x00\x00\x00\xf6\x01\x00"},
+		{"UTF-32BE", "\x00\x00\x00A\x00\x01\xf6\x00"},
+		{"UTF-32", "\x00\x00\xfe\xff\x00\x00\x00A\x00\x01\xf6\x00"},
+	}
+
+	for _, test := range tests {
+		encoded, err := Convert([]byte("A😀"), "UTF-8", test.encoding)
+		if err != nil {
+			t.Fatalf("%s: %v", test.encoding, err)
+		}
+		if string(encoded) != test.expected {
+			t.Errorf("%s: unexpected bytes %x", test.encoding, encoded)
+		}
+
+		decoded, err := Convert(encoded, test.encoding, "UTF-8")
+		if err != nil || string(decoded) != "A😀" {
+			t.Errorf("%s: round trip failed: %q (%v)", test.encoding, decoded, err)
+		}
+	}
+}
+
+// TestResolve 测试 IANA、WHATWG 名称及别名的解析
+func TestResolve(t *testing.T) {
+	tests := map[string]string{
+		"utf8":           "UTF-8",
+		"UTF_8":          "UTF-8",
+		"utf-16le":       "UTF-16LE",
+		"csGBK":          "GBK",
+		"gb2312":         "GB2312",
+		"latin1":         "ISO-8859-1",
+		"cp936":          "GBK",
+		"sjis":           "SHIFT_JIS",
+		"ks_c_5601-1987": "EUC-KR",
+		"ibm866":         "CP866",
+		"x-mac-roman":    "MACINTOSH",
+		"ISO-8859-9":     "ISO-8859-9",
+		"latin3":         "ISO-8859-3",
+		"iso-8859-6-i":   "ISO-8859-6",
+		"visual":         "ISO-8859-8",
+		"cp1255":         "WINDOWS-1255",
+		"ibm850":         "CP850",
+		"IBM00858":       "CP858",
+		"tis-620":        "TIS-620",
+		"koi8-u":         "KOI8-U",
+		"x-mac-cyrillic": "X-MAC-CYRILLIC",
+	}
+	for name, expected := range tests {
+		if resolved, ok := Resolve(name); !ok || resolved != expected {
+			t.Errorf("Resolve(%q) = %q, %v; expected %q", name, resolved, ok, expected)
+		}
+	}
+
+	// IANA 定义但未内置的编码（EBCDIC）不按 WHATWG 解析
+	for _, name := range []string{"IBM037", "no-such-charset", ""} {
+		if resolved, ok := Resolve(name); ok {
+			t.Errorf("Expected %q to be unresolved, got %q", name, resolved)
+		}
+	}
+
+	if _, err := Convert([]byte("data"), "utf-8", "latin1"); err != nil {
+		t.Errorf("Expected aliases to be accepted by Convert: %v", err)
+	}
+}
+
+// TestEscapeEncodings 测试 ISO-2022-JP、ISO-2022-KR 和 HZ-GB-2312 转换
+func TestEscapeEncodings(t *testing.T) {
+	for _, tc := range []struct {
+		encoding string
+		text     string
+	}{
+		{"ISO-2022-JP", "日本語のメール\nSubject: テスト"},
+		{"ISO-2022-KR", "한국어 메일\nSubject: 테스트"},
+		{"HZ-GB-2312", "中文邮件 ~ Subject: 测试"},
+	} {
+		encoded, err := ConvertStrict([]byte(tc.text), "UTF-8", tc.encoding)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.encoding, err)
+		}
+		for _, b := range encoded {
+			if b >= 0x80 {
+				t.Fatalf("%s: expected 7-bit output, got %q", tc.encoding, encoded)
+			}
+		}
+		decoded, err := Convert(encoded, tc.encoding, "UTF-8")
+		if err != nil {
+			t.Fatalf("%s: %v", tc.encoding, err)
+		}
+		if string(decoded) != tc.text {
+			t.Errorf("%s: round trip mismatch %q", tc.encoding, decoded)
+		}
+	}
+
+	// ISO-2022-KR：指定序列、SO/SI 切换，行首回到 ASCII 状态
+	decoded, err := Convert([]byte("\x1b$)C\x0e\x30\x21\x0fA\x0e\x30\x21\nA"), "ISO-2022-KR", "UTF-8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != "가A가\nA" {
+		t.Errorf("Unexpected ISO-2022-KR decoding %q", decoded)
+	}
+
+	// 无法表示的字符替换为 "?" 时先切换回 ASCII
+	encoded, err := Convert([]byte("가😀가"), "UTF-8", "ISO-2022-KR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(encoded) != "\x1b$)C\x0e\x30\x21\x0f?\x0e\x30\x21\x0f" {
+		t.Errorf("Unexpected ISO-2022-KR replacement %q", encoded)
+	}
+
+	if resolved, ok := Resolve("hz"); !ok || resolved != "HZ-GB-2312" {
+		t.Errorf("Expected HZ alias to resolve to HZ-GB-2312, got %q", resolved)
+	}
+}
+
+// TestVendorCodePages 测试 CP932、CP949、CP950 和 BIG5-HKSCS
+func TestVendorCodePages(t *testing.T) {
+	// CP950 不含 HKSCS：HKSCS 区解码为 U+FFFD，只在 HKSCS 中收录的字符无法编码
+	hkscs := []byte("\x88\x40")
+	if decoded, _ := Convert(hkscs, "CP950", "UTF-8"); string(decoded) != "�" {
+		t.Errorf("Expected HKSCS bytes to be invalid in CP950, got %q", decoded)
+	}
+	if decoded, _ := Convert(hkscs, "BIG5-HKSCS", "UTF-8"); string(decoded) != "㇀" {
+		t.Errorf("Unexpected BIG5-HKSCS decoding %q", decoded)
+	}
+	if _, err := ConvertStrict([]byte("㇀"), "UTF-8", "CP950"); err == nil {
+		t.Error("Expected HKSCS character to be unsupported by CP950")
+	}
+
+	// 微软扩展的制表符和 Big5 标准区字符
+	encoded, err := ConvertStrict([]byte("繁體═"), "UTF-8", "CP950")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded, _ := Convert(encoded, "CP950", "UTF-8"); string(decoded) != "繁體═" {
+		t.Errorf("Unexpected CP950 round trip %q", decoded)
+	}
+
+	// NEC 特殊字符和统一韩文码扩展音节
+	if encoded, err := ConvertStrict([]byte("①"), "UTF-8", "CP932"); err != nil || string(encoded) != "\x87\x40" {
+		t.Errorf("Unexpected CP932 encoding %q %v", encoded, err)
+	}
+	if encoded, err := ConvertStrict([]byte("똠"), "UTF-8", "CP949"); err != nil || string(encoded) != "\x8c\x63" {
+		t.Errorf("Unexpected CP949 encoding %q %v", encoded, err)
+	}
+
+	for name, expected := range map[string]string{
+		"windows-31j": "CP932",
+		"cp932":       "CP932",
+		"uhc":         "CP949",
+		"ms950":       "CP950",
+		"big5-hkscs":  "BIG5-HKSCS",
+	} {
+		if resolved, ok := Resolve(name); !ok || resolved != expected {
+			t.Errorf("Resolve(%q) = %q, %v; expected %q", name, resolved, ok, expected)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package converter
+
+import (
+	"errors"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/transform"
+)
+
+// ISO-2022-KR（RFC 1557）的控制序列
+const (
+	iso2022KRShiftOut = 0x0E // SO：切换到 KS X 1001
+	iso2022KRShiftIn  = 0x0F // SI：切换回 ASCII
+)
+
+// iso2022KRDesignator 文本开头的字符集指定序列 ESC $ ) C
+var iso2022KRDesignator = []byte("\x1b$)C")
+
+// errISO2022KRUnsupported 字符无法用 ISO-2022-KR 表示
+var errISO2022KRUnsupported = errors.New("converter: rune not supported by ISO-2022-KR")
+
+// ISO2022KR ISO-2022-KR 编码（x/text 未提供实现）
+//
+// 双字节字符为去掉最高位的 EUC-KR 编码，由 SO/SI 切换；行首总是 ASCII 状态。
+// 编码时在第一个 SO 之前写入一次字符集指定序列，纯 ASCII 文本的编码结果与 ASCII 相同。
+var ISO2022KR encoding.Encoding = iso2022KR{}
+
+type iso2022KR struct{}
+
+// NewDecoder 实现 encoding.Encoding 接口
+func (iso2022KR) NewDecoder() *encoding.Decoder {
+	return &encoding.Decoder{Transformer: &iso2022KRDecoder{euckr: korean.EUCKR.NewDecoder()}}
+}
+
+// NewEncoder 实现 encoding.Encoding 接口
+func (iso2022KR) NewEncoder() *encoding.Encoder {
+	return &encoding.Encoder{Transformer: &iso2022KREncoder{euckr: korean.EUCKR.NewEncoder()}}
+}
+
+// iso2022KRDecoder ISO-2022-KR 解码器
+type iso2022KRDecoder struct {
+	euckr   transform.Transformer
+	shifted bool
+}
+
+// Transform 实现 transform.Transformer 接口
+func (d *iso2022KRDecoder) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		b := src[nSrc]
+		switch {
+		case b == 0x1B:
+			// 字符集指定序列只改变后续 SO 的含义，不产生输出
+			rest := src[nSrc:]
+			if len(rest) < len(iso2022KRDesignator) && !atEOF && string(rest) == string(iso2022KRDesignator[:len(rest)]) {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			if len(rest) >= len(iso2022KRDesignator) && string(rest[:len(iso2022KRDesignator)]) == string(iso2022KRDesignator) {
+				nSrc += len(iso2022KRDesignator)
+				continue
+			}
+			if len(dst)-nDst < utf8.RuneLen(utf8.RuneError) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			nDst += utf8.EncodeRune(dst[nDst:], utf8.RuneError)
+			nSrc++
+		case b == iso2022KRShiftOut:
+			d.shifted = true
+			nSrc++
+		case b == iso2022KRShiftIn:
+			d.shifted = false
+			nSrc++
+		case d.shifted && b > 0x20 && b < 0x7F:
+			if nSrc+1 >= len(src) {
+				if !atEOF {
+					return nDst, nSrc, transform.ErrShortSrc
+				}
+				if len(dst)-nDst < utf8.RuneLen(utf8.RuneError) {
+					return nDst, nSrc, transform.ErrShortDst
+				}
+				nDst += utf8.EncodeRune(dst[nDst:], utf8.RuneError)
+				nSrc++
+				continue
+			}
+			var buf [utf8.UTFMax]byte
+			n, _, _ := d.euckr.Transform(buf[:], []byte{b | 0x80, src[nSrc+1] | 0x80}, true)
+			if len(dst)-nDst < n {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			nDst += copy(dst[nDst:], buf[:n])
+			nSrc += 2
+		case b < 0x80:
+			// 换行后回到 ASCII 状态
+			if b == '\n' || b == '\r' {
+				d.shifted = false
+			}
+			if nDst >= len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			dst[nDst] = b
+			nDst++
+			nSrc++
+		default:
+			if len(dst)-nDst < utf8.RuneLen(utf8.RuneError) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			nDst += utf8.EncodeRune(dst[nDst:], utf8.RuneError)
+			nSrc++
+		}
+	}
+	return nDst, nSrc, nil
+}
+
+// Reset 实现 transform.Transformer 接口
+func (d *iso2022KRDecoder) Reset() {
+	d.shifted = false
+}
+
+// iso2022KREncoder ISO-2022-KR 编码器
+type iso2022KREncoder struct {
+	euckr       transform.Transformer
+	wroteHeader bool
+	shifted     bool
+}
+
+// Transform 实现 transform.Transformer 接口
+func (e *iso2022KREncoder) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		r, size := rune(src[nSrc]), 1
+		if r >= utf8.RuneSelf {
+			if !atEOF && !utf8.FullRune(src[nSrc:]) {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			r, size = utf8.DecodeRune(src[nSrc:])
+		}
+
+		if r < utf8.RuneSelf && r != iso2022KRShiftOut && r != iso2022KRShiftIn && r != 0x1B {
+			need := 1
+			if e.shifted {
+				need++
+			}
+			if len(dst)-nDst < need {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			if e.shifted {
+				dst[nDst] = iso2022KRShiftIn
+				nDst++
+				e.shifted = false
+			}
+			dst[nDst] = byte(r)
+			nDst++
+			nSrc++
+			continue
+		}
+
+		var buf [4]byte
+		n, _, err := e.euckr.Transform(buf[:], src[nSrc:nSrc+size], true)
+		if err != nil || n != 2 || buf[0] < 0xA1 || buf[1] < 0xA1 {
+			// CP949 扩展区的字符不在 KS X 1001 中，同样无法表示；
+			// 先切换回 ASCII 状态，使调用方写入的替换字符按 ASCII 解释
+			if e.shifted {
+				if nDst >= len(dst) {
+					return nDst, nSrc, transform.ErrShortDst
+				}
+				dst[nDst] = iso2022KRShiftIn
+				nDst++
+				e.shifted = false
+			}
+			return nDst, nSrc, errISO2022KRUnsupported
+		}
+
+		need := 2
+		if !e.shifted {
+			need++
+		}
+		if !e.wroteHeader {
+			need += len(iso2022KRDesignator)
+		}
+		if len(dst)-nDst < need {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		if !e.wroteHeader {
+			nDst += copy(dst[nDst:], iso2022KRDesignator)
+			e.wroteHeader = true
+		}
+		if !e.shifted {
+			dst[nDst] = iso2022KRShiftOut
+			nDst++
+			e.shifted = true
+		}
+		dst[nDst] = buf[0] &^ 0x80
+		dst[nDst+1] = buf[1] &^ 0x80
+		nDst += 2
+		nSrc += size
+	}
+
+	// 输出以 ASCII 状态结束
+	if atEOF && e.shifted {
+		if nDst >= len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		dst[nDst] = iso2022KRShiftIn
+		nDst++
+		e.shifted = false
+	}
+	return nDst, nSrc, nil
+}
+
+// Reset 实现 transform.Transformer 接口
+func (e *iso2022KREncoder) Reset() {
+	e.wroteHeader = false
+	e.shifted = false
+}
@@ -0,0 +1,85 @@
+package converter
+
+import (
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// mimeNames MIME 首选名称（大写）与本包编码名称不一致的映射
+var mimeNames = map[string]string{
+	"IBM437":   "CP437",
+	"IBM850":   "CP850",
+	"IBM852":   "CP852",
+	"IBM855":   "CP855",
+	"IBM00858": "CP858",
+	"IBM860":   "CP860",
+	"IBM862":   "CP862",
+	"IBM863":   "CP863",
+	"IBM865":   "CP865",
+	"IBM866":   "CP866",
+}
+
+// Resolve 将编码名称解析为本包的编码名称
+//
+// 依次尝试：精确名称、注册的别名、忽略大小写并将 "_" 视为 "-" 的名称、Windows 代码页形式
+// （如 "cp936"）、IANA 名称及别名（如 "csGBK"、"latin1"）和 WHATWG 标签（如 "utf8"、"sjis"）。
+// 无法解析为已注册的编码时返回 false。
+func Resolve(name string) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+	if _, ok := lookupRegistered(name); ok {
+		if resolved, isAlias := ResolveAlias(name); isAlias {
+			return resolved, true
+		}
+		return name, true
+	}
+
+	upper := strings.ToUpper(name)
+	for _, candidate := range []string{upper, strings.ReplaceAll(upper, "_", "-")} {
+		if isRegistered(candidate) {
+			return candidate, true
+		}
+	}
+
+	if resolved, ok := ResolveCodePage(name); ok && isRegistered(resolved) {
+		return resolved, true
+	}
+
+	// IANA 已定义且有实现的名称不再按 WHATWG 解析：WHATWG 将部分编码映射为超集
+	// （如 ISO-8859-9 -> windows-1254），对转换而言并不等价
+	if enc, err := ianaindex.IANA.Encoding(name); err == nil && enc != nil {
+		return mimeName(enc)
+	}
+	if enc, err := htmlindex.Get(name); err == nil {
+		return mimeName(enc)
+	}
+	return "", false
+}
+
+// mimeName 按 x/text 编码实现的 MIME 首选名称查找本包的编码名称
+func mimeName(enc encoding.Encoding) (string, bool) {
+	if enc == nil {
+		return "", false
+	}
+	name, err := ianaindex.MIME.Name(enc)
+	if err != nil || name == "" {
+		return "", false
+	}
+	name = strings.ToUpper(name)
+	if mapped, ok := mimeNames[name]; ok {
+		name = mapped
+	}
+	return name, isRegistered(name)
+}
+
+// isRegistered 检查编码名称是否已注册（不解析别名）
+func isRegistered(name string) bool {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	_, ok := encodings[name]
+	return ok
+}
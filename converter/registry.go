@@ -0,0 +1,80 @@
+package converter
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/encoding"
+)
+
+var (
+	// registryMutex 保护 encodings 和 aliases
+	registryMutex sync.RWMutex
+
+	// aliases 别名（大写）到编码名称的映射
+	aliases = map[string]string{
+		// 双向文本的显式（-E）与隐式（-I）变体，字节映射与基础编码相同
+		"ISO-8859-6-E": "ISO-8859-6",
+		"ISO-8859-6-I": "ISO-8859-6",
+		"ISO-8859-8-E": "ISO-8859-8",
+
+		// HZ 编码的常用简称
+		"HZ": "HZ-GB-2312",
+
+		// Windows 厂商代码页的常用名称
+		"WINDOWS-31J": "CP932",
+		"UHC":         "CP949",
+	}
+)
+
+// Register 注册编码实现，aliases 为可选的别名（比较时忽略大小写）
+//
+// 注册后 Lookup、Convert 等函数即可使用该编码；名称已存在时替换原有实现。
+// name 为空或 enc 为 nil 时 panic。
+func Register(name string, enc encoding.Encoding, aliasNames ...string) {
+	if name == "" || enc == nil {
+		panic("converter: Register requires a name and an encoding")
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	encodings[name] = enc
+	for _, alias := range aliasNames {
+		aliases[strings.ToUpper(alias)] = name
+	}
+}
+
+// ResolveAlias 将注册的别名解析为编码名称（不是别名时返回 false）
+func ResolveAlias(name string) (string, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	resolved, ok := aliases[strings.ToUpper(name)]
+	return resolved, ok
+}
+
+// Names 返回全部内置和已注册编码的名称（按字母顺序）
+func Names() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	names := make([]string, 0, len(encodings))
+	for name := range encodings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupRegistered 按编码名称或别名查找编码实现
+func lookupRegistered(name string) (encoding.Encoding, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	if enc, ok := encodings[name]; ok {
+		return enc, true
+	}
+	if resolved, ok := aliases[strings.ToUpper(name)]; ok {
+		enc, ok := encodings[resolved]
+		return enc, ok
+	}
+	return nil, false
+}
@@ -0,0 +1,136 @@
+package converter
+
+import (
+	"errors"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/transform"
+)
+
+// errCP950Unsupported 字符无法用 CP950 表示（如只在 HKSCS 中收录的香港字）
+var errCP950Unsupported = errors.New("converter: rune not supported by CP950")
+
+// CP950 Windows 繁体中文代码页（Big5 加微软扩展，不含 HKSCS）
+//
+// x/text 的 Big5 实现按 WHATWG 规范包含 HKSCS 扩展（即 BIG5-HKSCS），CP950 在其基础上
+// 将 HKSCS 区（前导字节 81-A0、FA-FE 以及 C6A1-C8FE）视为无效：解码为 U+FFFD，
+// 编码时只在 HKSCS 区有编码的字符返回错误。
+var CP950 encoding.Encoding = cp950{}
+
+type cp950 struct{}
+
+// NewDecoder 实现 encoding.Encoding 接口
+func (cp950) NewDecoder() *encoding.Decoder {
+	return &encoding.Decoder{Transformer: &cp950Decoder{big5: traditionalchinese.Big5.NewDecoder()}}
+}
+
+// NewEncoder 实现 encoding.Encoding 接口
+func (cp950) NewEncoder() *encoding.Encoder {
+	return &encoding.Encoder{Transformer: &cp950Encoder{big5: traditionalchinese.Big5.NewEncoder()}}
+}
+
+// isCP950Pair 检查 Big5 双字节序列是否位于 CP950 的编码范围（Big5 标准区及微软扩展 F9D6-F9FE）
+func isCP950Pair(lead, trail byte) bool {
+	if lead < 0xA1 || lead > 0xF9 {
+		return false
+	}
+	if trail < 0x40 || trail > 0x7E && trail < 0xA1 || trail == 0xFF {
+		return false
+	}
+	// C6A1-C8FE 是 ETEN 扩展和 HKSCS 使用的保留区
+	return !(lead == 0xC6 && trail >= 0xA1 || lead == 0xC7 || lead == 0xC8)
+}
+
+// cp950Decoder CP950 解码器
+type cp950Decoder struct {
+	big5 transform.Transformer
+}
+
+// Transform 实现 transform.Transformer 接口
+func (d *cp950Decoder) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		b := src[nSrc]
+		if b < utf8.RuneSelf {
+			if nDst >= len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			dst[nDst] = b
+			nDst++
+			nSrc++
+			continue
+		}
+
+		size := 1
+		if b >= 0x81 && b <= 0xFE {
+			if nSrc+1 >= len(src) && !atEOF {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			if nSrc+1 < len(src) {
+				size = 2
+			}
+		}
+
+		var buf [2 * utf8.UTFMax]byte
+		n := utf8.EncodeRune(buf[:], utf8.RuneError)
+		if size == 2 && isCP950Pair(b, src[nSrc+1]) {
+			n, _, _ = d.big5.Transform(buf[:], src[nSrc:nSrc+2], true)
+		} else if size == 2 && src[nSrc+1] < utf8.RuneSelf && (src[nSrc+1] < 0x40 || src[nSrc+1] > 0x7E) {
+			// 尾字节不合法时只跳过前导字节，尾字节按 ASCII 重新处理
+			size = 1
+		}
+		if len(dst)-nDst < n {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], buf[:n])
+		nSrc += size
+	}
+	return nDst, nSrc, nil
+}
+
+// Reset 实现 transform.Transformer 接口
+func (d *cp950Decoder) Reset() {
+	d.big5.Reset()
+}
+
+// cp950Encoder CP950 编码器
+type cp950Encoder struct {
+	big5 transform.Transformer
+}
+
+// Transform 实现 transform.Transformer 接口
+func (e *cp950Encoder) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		if src[nSrc] < utf8.RuneSelf {
+			if nDst >= len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			dst[nDst] = src[nSrc]
+			nDst++
+			nSrc++
+			continue
+		}
+		if !atEOF && !utf8.FullRune(src[nSrc:]) {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+		_, size := utf8.DecodeRune(src[nSrc:])
+
+		var buf [2]byte
+		n, _, err := e.big5.Transform(buf[:], src[nSrc:nSrc+size], true)
+		if err != nil || n != 2 || !isCP950Pair(buf[0], buf[1]) {
+			return nDst, nSrc, errCP950Unsupported
+		}
+		if len(dst)-nDst < 2 {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], buf[:])
+		nSrc += size
+	}
+	return nDst, nSrc, nil
+}
+
+// Reset 实现 transform.Transformer 接口
+func (e *cp950Encoder) Reset() {
+	e.big5.Reset()
+}
@@ -0,0 +1,157 @@
+package encoding
+
+import (
+	"bytes"
+	"mime"
+	"regexp"
+
+	"github.com/mirbf/encoding-processor/converter"
+)
+
+// 按扩展名识别的样式表和脚本文件（@charset 声明和 BOM 处理策略仅作用于这些文件）
+var (
+	stylesheetExtensions = map[string]bool{".css": true, ".scss": true, ".less": true}
+	scriptExtensions     = map[string]bool{".js": true, ".mjs": true, ".cjs": true}
+)
+
+// declarationScanLimit 查找 HTML meta 声明的范围（与 HTML 规范的预扫描长度一致）
+const declarationScanLimit = 1024
+
+// 文档内编码声明的匹配模式
+var (
+	xmlDeclarationPattern = regexp.MustCompile(`^<\?xml[^>]*?\sencoding\s*=\s*["']([-_.:a-zA-Z0-9]+)["']`)
+	cssCharsetPattern     = regexp.MustCompile(`^@charset\s+["']([-_.:a-zA-Z0-9]+)["']\s*;`)
+	htmlMetaPattern       = regexp.MustCompile(`(?i)<meta\s[^>]*?charset\s*=\s*["']?\s*([-_.:a-zA-Z0-9]+)`)
+	magicCommentPattern   = regexp.MustCompile(`^[ \t\f]*#.*?coding[:=][ \t]*([-_.a-zA-Z0-9]+)`)
+)
+
+// ExtractDeclaredCharset 提取数据或 HTTP Content-Type 中声明的字符集
+//
+// 按优先级依次检查 Content-Type 头的 charset 参数、XML 声明、CSS @charset、HTML meta 标签
+// （前 1024 字节）以及 Python/Ruby 的编码魔法注释（前两行）。contentType 可以为空。
+// 能识别的名称返回本包的编码名称，无法识别的名称原样返回；没有声明时返回 false。
+func ExtractDeclaredCharset(data []byte, contentType string) (string, bool) {
+	if contentType != "" {
+		if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+			return resolveDeclaredCharset(params["charset"]), true
+		}
+	}
+
+	data = bytes.TrimPrefix(data, utf8BOM)
+	head := data
+	if len(head) > declarationScanLimit {
+		head = head[:declarationScanLimit]
+	}
+
+	for _, pattern := range []*regexp.Regexp{xmlDeclarationPattern, cssCharsetPattern, htmlMetaPattern} {
+		if match := pattern.FindSubmatch(head); match != nil {
+			return resolveDeclaredCharset(string(match[1])), true
+		}
+	}
+
+	// PEP 263：编码声明必须位于前两行
+	lines := bytes.SplitN(head, []byte("\n"), 3)
+	for i := 0; i < len(lines) && i < 2; i++ {
+		if match := magicCommentPattern.FindSubmatch(bytes.TrimRight(lines[i], "\r")); match != nil {
+			return resolveDeclaredCharset(string(match[1])), true
+		}
+	}
+
+	return "", false
+}
+
+// resolveDeclaredCharset 将声明的字符集名称解析为本包的编码名称（无法识别时原样返回）
+func resolveDeclaredCharset(name string) string {
+	if resolved, ok := ResolveICUName(name); ok {
+		return resolved
+	}
+	if resolved, ok := converter.ResolveCodePage(name); ok {
+		return resolved
+	}
+	return name
+}
+
+// multiByteLegacyEncodings 双字节旧编码
+var multiByteLegacyEncodings = map[string]bool{
+	EncodingGBK: true, EncodingGB2312: true, EncodingGB18030: true,
+	EncodingBIG5: true, EncodingBIG5HKSCS: true, EncodingCP950: true,
+	EncodingShiftJIS: true, EncodingCP932: true, EncodingEUCJP: true,
+	EncodingEUCKR: true, EncodingCP949: true,
+}
+
+// detectDeclaration 按文档内的编码声明检测编码
+//
+// 声明只在数据能按其无损解码、不是被误标的 UTF-8 时采用。单字节编码几乎能无损解码任何数据，
+// 声明为单字节编码时还要求独立检测不指向双字节编码（见 declarationAgrees）。
+func (d *defaultDetector) detectDeclaration(data []byte) *DetectionResult {
+	if !d.config.UseDeclaredCharset {
+		return nil
+	}
+
+	declared, ok := ExtractDeclaredCharset(data, "")
+	if !ok || !acceptsHint(declared, data, false) || !d.declarationAgrees(declared, data) {
+		return nil
+	}
+
+	return &DetectionResult{
+		Encoding:   declared,
+		Confidence: 0.9,
+		Details: &DetectionDetails{
+			Method: MethodDeclaration,
+		},
+	}
+}
+
+// declarationAgrees 检查独立检测是否与声明的编码相符
+//
+// 双字节编码能无损解码本身即是证据；单字节编码要求高位字节不全是成对出现（双字节文本的特征），
+// 且字符集检测后端的首选结果不是双字节编码。
+func (d *defaultDetector) declarationAgrees(declared string, data []byte) bool {
+	if multiByteLegacyEncodings[declared] {
+		return true
+	}
+	if highBytesPaired(data) {
+		return false
+	}
+	matches, err := d.backend().DetectAll(data)
+	return err == nil && len(matches) > 0 && !multiByteLegacyEncodings[d.normalizeEncodingName(matches[0].Charset)]
+}
+
+// highBytesPaired 检查数据中连续的高位字节（0x80-0xFF）是否都以偶数长度出现
+func highBytesPaired(data []byte) bool {
+	run, found := 0, false
+	for _, b := range data {
+		if b >= 0x80 {
+			run++
+			found = true
+			continue
+		}
+		if run%2 != 0 {
+			return false
+		}
+		run = 0
+	}
+	return found && run%2 == 0
+}
+
+// RewriteCSSCharset 改写 UTF-8 样式表开头的 @charset 声明（charset 为空时删除声明及其后的换行符）
+//
+// 开头的 BOM 保持不变；没有声明的样式表原样返回。
+func RewriteCSSCharset(text []byte, charset string) []byte {
+	body := bytes.TrimPrefix(text, utf8BOM)
+	loc := cssCharsetPattern.FindIndex(body)
+	if loc == nil {
+		return text
+	}
+
+	prefix := text[:len(text)-len(body)]
+	rest := body[loc[1]:]
+	result := make([]byte, 0, len(text))
+	result = append(result, prefix...)
+	if charset == "" {
+		rest = bytes.TrimPrefix(bytes.TrimPrefix(rest, []byte("\r")), []byte("\n"))
+	} else {
+		result = append(result, `@charset "`+charset+`";`...)
+	}
+	return append(result, rest...)
+}
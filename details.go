@@ -0,0 +1,182 @@
+package encoding
+
+// DetectionDetails 检测详情
+//
+// 常用信息以类型化字段提供；自定义检测后端或规则附加的其他信息保存在 Extensions 中。
+// Get、Set 和 Map 按旧版 map[string]interface{} 的键名访问详情，便于迁移。
+type DetectionDetails struct {
+	// Method 产生结果的检测方法
+	Method string `json:"method,omitempty"`
+
+	// SourceMethod 集成投票中胜出候选的原始检测方法
+	SourceMethod string `json:"source_method,omitempty"`
+
+	// BOM 是否通过字节顺序标记检测
+	BOM bool `json:"bom,omitempty"`
+
+	// Charset 检测后端报告的原始字符集名称
+	Charset string `json:"charset,omitempty"`
+
+	// HasNonASCII 数据是否包含非 ASCII 字符（UTF-8 校验时设置）
+	HasNonASCII bool `json:"has_non_ascii,omitempty"`
+
+	// Score 集成投票的综合得分
+	Score float64 `json:"score,omitempty"`
+
+	// ScoreBreakdown 胜出候选的得分组成
+	ScoreBreakdown *ScoreBreakdown `json:"score_breakdown,omitempty"`
+
+	// Votes 各检测方法的投票
+	Votes []EnsembleVote `json:"votes,omitempty"`
+
+	// ConvertedPreview 按检测结果解码后的文本
+	ConvertedPreview string `json:"converted_preview,omitempty"`
+
+	// CandidatesCount 参与评分的候选数量
+	CandidatesCount int `json:"candidates_count,omitempty"`
+
+	// ContentClass 内容类别（code 或 prose）
+	ContentClass string `json:"content_class,omitempty"`
+
+	// Rule 命中的检测覆盖规则名称
+	Rule string `json:"rule,omitempty"`
+
+	// HintSource 结合编码提示检测时胜出的来源（HintSource* 常量）
+	HintSource string `json:"hint_source,omitempty"`
+
+	// Hints 结合编码提示检测时发现的全部提示
+	Hints []ContentHint `json:"hints,omitempty"`
+
+	// Extensions 其他扩展信息
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// Get 按旧版详情键名获取值（d 为 nil 或键不存在时返回 false）
+func (d *DetectionDetails) Get(key string) (interface{}, bool) {
+	if d == nil {
+		return nil, false
+	}
+	value, ok := d.Map()[key]
+	return value, ok
+}
+
+// Set 按旧版详情键名设置值，未知键或值类型不符时保存到 Extensions
+func (d *DetectionDetails) Set(key string, value interface{}) {
+	var ok bool
+	switch key {
+	case "method":
+		d.Method, ok = value.(string)
+	case "source_method":
+		d.SourceMethod, ok = value.(string)
+	case "bom":
+		d.BOM, ok = value.(bool)
+	case "charset":
+		d.Charset, ok = value.(string)
+	case "has_non_ascii":
+		d.HasNonASCII, ok = value.(bool)
+	case "score":
+		d.Score, ok = value.(float64)
+	case "score_breakdown":
+		switch v := value.(type) {
+		case ScoreBreakdown:
+			d.ScoreBreakdown, ok = &v, true
+		case *ScoreBreakdown:
+			d.ScoreBreakdown, ok = v, true
+		}
+	case "votes":
+		d.Votes, ok = value.([]EnsembleVote)
+	case "converted_text":
+		d.ConvertedPreview, ok = value.(string)
+	case "candidates_count":
+		switch v := value.(type) {
+		case int:
+			d.CandidatesCount, ok = v, true
+		case float64:
+			// JSON 解码得到的数字
+			d.CandidatesCount, ok = int(v), true
+		}
+	case "content_class":
+		d.ContentClass, ok = value.(string)
+	case "rule":
+		d.Rule, ok = value.(string)
+	case "hint_source":
+		d.HintSource, ok = value.(string)
+	case "hints":
+		d.Hints, ok = value.([]ContentHint)
+	}
+
+	if !ok {
+		if d.Extensions == nil {
+			d.Extensions = make(map[string]interface{})
+		}
+		d.Extensions[key] = value
+	}
+}
+
+// Map 以旧版 map[string]interface{} 形式返回详情（只包含已设置的字段）
+func (d *DetectionDetails) Map() map[string]interface{} {
+	if d == nil {
+		return nil
+	}
+
+	details := make(map[string]interface{}, len(d.Extensions))
+	for key, value := range d.Extensions {
+		details[key] = value
+	}
+
+	if d.Method != "" {
+		details["method"] = d.Method
+	}
+	if d.SourceMethod != "" {
+		details["source_method"] = d.SourceMethod
+	}
+	if d.BOM {
+		details["bom"] = true
+	}
+	if d.Charset != "" {
+		details["charset"] = d.Charset
+	}
+	if d.HasNonASCII {
+		details["has_non_ascii"] = true
+	}
+	if d.Score != 0 {
+		details["score"] = d.Score
+	}
+	if d.ScoreBreakdown != nil {
+		details["score_breakdown"] = *d.ScoreBreakdown
+	}
+	if d.Votes != nil {
+		details["votes"] = d.Votes
+	}
+	if d.ConvertedPreview != "" {
+		details["converted_text"] = d.ConvertedPreview
+	}
+	if d.CandidatesCount != 0 {
+		details["candidates_count"] = d.CandidatesCount
+	}
+	if d.ContentClass != "" {
+		details["content_class"] = d.ContentClass
+	}
+	if d.Rule != "" {
+		details["rule"] = d.Rule
+	}
+	if d.HintSource != "" {
+		details["hint_source"] = d.HintSource
+	}
+	if d.Hints != nil {
+		details["hints"] = d.Hints
+	}
+	return details
+}
+
+// detailsFromMap 由旧版详情映射构造类型化详情
+func detailsFromMap(details map[string]interface{}) *DetectionDetails {
+	if details == nil {
+		return nil
+	}
+	typed := &DetectionDetails{}
+	for key, value := range details {
+		typed.Set(key, value)
+	}
+	return typed
+}
@@ -0,0 +1,172 @@
+package encoding
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// detectionCache 按最近使用顺序淘汰的检测结果缓存
+//
+// 容量和过期时间有界：查找、插入和淘汰均为 O(1)，过期的条目在查找时删除，
+// 或由后台清理协程定期删除，缓存已满时淘汰最久未使用的条目。
+//
+// 清理协程在插入第一个条目时才启动，缓存清空后自行退出，下次插入时重新启动，
+// 因此未使用或未关闭的缓存不会长期占用协程。
+type detectionCache struct {
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List // 最近使用的条目在前
+	mutex   sync.Mutex
+
+	janitorInterval time.Duration // 不大于 0 时不启动后台清理
+	janitorStop     chan struct{} // 非 nil 表示清理协程正在运行
+	closed          bool
+}
+
+// cacheEntry 缓存条目
+type cacheEntry struct {
+	key     string
+	result  *DetectionResult
+	expires time.Time
+}
+
+// newDetectionCache 创建检测结果缓存（size、ttl 不大于 0 时使用默认值）
+func newDetectionCache(size int, ttl time.Duration) *detectionCache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &detectionCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get 查找未过期的检测结果，命中时将条目移到最近使用的位置
+func (c *detectionCache) get(key string) (*DetectionResult, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.result, true
+}
+
+// put 缓存检测结果，返回是否因缓存已满淘汰了条目
+func (c *detectionCache) put(key string, result *DetectionResult) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	expires := time.Now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.result, entry.expires = result, expires
+		c.order.MoveToFront(elem)
+		return false
+	}
+
+	evicted := false
+	if c.order.Len() >= c.size {
+		c.remove(c.order.Back())
+		evicted = true
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, result: result, expires: expires})
+	if c.janitorInterval > 0 && c.janitorStop == nil && !c.closed {
+		c.janitorStop = make(chan struct{})
+		go c.runJanitor(c.janitorStop)
+	}
+	return evicted
+}
+
+// purgeExpired 删除所有过期条目，返回删除的数量
+func (c *detectionCache) purgeExpired() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.purgeExpiredLocked()
+}
+
+// purgeExpiredLocked 删除所有过期条目，调用方需持有锁
+func (c *detectionCache) purgeExpiredLocked() int {
+	now := time.Now()
+	purged := 0
+	for elem := c.order.Front(); elem != nil; {
+		next := elem.Next()
+		if now.After(elem.Value.(*cacheEntry).expires) {
+			c.remove(elem)
+			purged++
+		}
+		elem = next
+	}
+	return purged
+}
+
+// runJanitor 每隔 janitorInterval 删除一次过期条目，缓存清空或关闭后退出
+func (c *detectionCache) runJanitor(stop chan struct{}) {
+	ticker := time.NewTicker(c.janitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.mutex.Lock()
+			c.purgeExpiredLocked()
+			idle := c.order.Len() == 0 && c.janitorStop == stop
+			if idle {
+				c.janitorStop = nil
+			}
+			c.mutex.Unlock()
+			if idle {
+				return
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// janitorRunning 返回后台清理协程是否正在运行
+func (c *detectionCache) janitorRunning() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.janitorStop != nil
+}
+
+// close 停止后台清理协程并清空缓存，之后的插入不再启动清理协程（可以重复调用）
+func (c *detectionCache) close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.closed = true
+	if c.janitorStop != nil {
+		close(c.janitorStop)
+		c.janitorStop = nil
+	}
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+// len 返回缓存条目数（包括尚未删除的过期条目）
+func (c *detectionCache) len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.order.Len()
+}
+
+// remove 删除条目，调用方需持有锁
+func (c *detectionCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).key)
+}
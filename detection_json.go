@@ -0,0 +1,75 @@
+package encoding
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// detectionResultJSON DetectionResult 的版本化序列化格式
+type detectionResultJSON struct {
+	SchemaVersion int               `json:"schema_version"`
+	Encoding      string            `json:"encoding"`
+	Confidence    float64           `json:"confidence"`
+	Language      string            `json:"language,omitempty"`
+	Details       *DetectionDetails `json:"details,omitempty"`
+}
+
+// MarshalJSON 按当前 schema 版本序列化检测结果
+func (r DetectionResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(detectionResultJSON{
+		SchemaVersion: DetectionSchemaVersion,
+		Encoding:      r.Encoding,
+		Confidence:    r.Confidence,
+		Language:      r.Language,
+		Details:       r.Details,
+	})
+}
+
+// UnmarshalJSON 反序列化检测结果
+//
+// 不含 schema_version 的数据按旧格式（details 为任意对象）解析；
+// schema 版本高于当前支持的版本时返回 ErrUnsupportedSchemaVersion。
+func (r *DetectionResult) UnmarshalJSON(data []byte) error {
+	var header struct {
+		SchemaVersion int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return err
+	}
+
+	if header.SchemaVersion > DetectionSchemaVersion {
+		return fmt.Errorf("%w: detection result schema %d (supported up to %d)", ErrUnsupportedSchemaVersion, header.SchemaVersion, DetectionSchemaVersion)
+	}
+
+	if header.SchemaVersion == 0 {
+		// 旧格式：details 为任意键值对象
+		var legacy struct {
+			Encoding   string                 `json:"encoding"`
+			Confidence float64                `json:"confidence"`
+			Language   string                 `json:"language"`
+			Details    map[string]interface{} `json:"details"`
+		}
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return err
+		}
+		*r = DetectionResult{
+			Encoding:   legacy.Encoding,
+			Confidence: legacy.Confidence,
+			Language:   legacy.Language,
+			Details:    detailsFromMap(legacy.Details),
+		}
+		return nil
+	}
+
+	var in detectionResultJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*r = DetectionResult{
+		Encoding:   in.Encoding,
+		Confidence: in.Confidence,
+		Language:   in.Language,
+		Details:    in.Details,
+	}
+	return nil
+}
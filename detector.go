@@ -1,16 +1,19 @@
 package encoding
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"regexp"
 	"sort"
 	"sync"
 	"time"
 	"unicode/utf8"
 
-	"github.com/saintfish/chardet"
+	"github.com/mirbf/encoding-processor/converter"
+	"github.com/mirbf/encoding-processor/detector"
 	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/encoding/traditionalchinese"
 	"golang.org/x/text/transform"
@@ -18,29 +21,47 @@ import (
 
 // defaultDetector 实现 Detector 接口
 type defaultDetector struct {
-	config *DetectorConfig
-	cache  *detectionCache
-	mutex  sync.RWMutex
+	config  *DetectorConfig
+	cache   *detectionCache
+	garbled *GarbledScorer
+	mutex   sync.RWMutex
 }
 
-// detectionCache 检测结果缓存
-type detectionCache struct {
-	cache map[string]*cacheEntry
-	mutex sync.RWMutex
+// DetectionCandidate 检测候选结果
+type DetectionCandidate struct {
+	Encoding      string         `json:"encoding"`
+	Confidence    float64        `json:"confidence"`
+	Method        string         `json:"method"`
+	ConvertedText string         `json:"converted_text,omitempty"`
+	Score         ScoreBreakdown `json:"score"`
+	ContentClass  string         `json:"content_class,omitempty"`
 }
 
-type cacheEntry struct {
-	result    *DetectionResult
-	timestamp time.Time
-}
+// ScoreBreakdown 候选编码得分的组成（各项均为加权后的贡献值）
+type ScoreBreakdown struct {
+	// BaseConfidence 基础置信度贡献
+	BaseConfidence float64 `json:"base_confidence"`
 
-// DetectionCandidate 检测候选结果
-type DetectionCandidate struct {
-	Encoding      string
-	Confidence    float64
-	Method        string
-	ConvertedText string
-	Score         float64
+	// ScriptScore 文字（脚本）质量贡献
+	ScriptScore float64 `json:"script_score"`
+
+	// ValidityScore 字符有效性贡献
+	ValidityScore float64 `json:"validity_score"`
+
+	// GarbledPenalty 乱码特征扣分
+	GarbledPenalty float64 `json:"garbled_penalty"`
+
+	// HintBonus 提示加分（如传统检测结果一致）
+	HintBonus float64 `json:"hint_bonus"`
+
+	// PostAdjustments 二次评分器的调整值（评分器名称 -> 调整值）
+	PostAdjustments map[string]float64 `json:"post_adjustments,omitempty"`
+
+	// ConversionFailed 候选编码无法解码数据
+	ConversionFailed bool `json:"conversion_failed,omitempty"`
+
+	// Total 综合得分
+	Total float64 `json:"total"`
 }
 
 // NewDetector 创建新的检测器
@@ -53,20 +74,47 @@ func NewDetector(config ...*DetectorConfig) Detector {
 	}
 
 	detector := &defaultDetector{
-		config: cfg,
+		config:  cfg,
+		garbled: defaultGarbledScorer,
+	}
+
+	if len(cfg.GarbledPatterns) > 0 {
+		detector.garbled = NewGarbledScorer(append(DefaultGarbledPatterns(), cfg.GarbledPatterns...)...)
 	}
 
 	if cfg.EnableCache {
-		detector.cache = &detectionCache{
-			cache: make(map[string]*cacheEntry),
+		detector.cache = newDetectionCache(cfg.CacheSize, cfg.CacheTTL)
+
+		// 清理协程在第一次缓存检测结果时才启动，缓存清空后退出
+		if cfg.CacheJanitorInterval >= 0 {
+			detector.cache.janitorInterval = cfg.CacheJanitorInterval
+			if detector.cache.janitorInterval == 0 {
+				detector.cache.janitorInterval = DefaultCacheJanitorInterval
+			}
 		}
 	}
 
 	return detector
 }
 
+// newTransientDetector 创建一次性使用的默认检测器（不启用缓存，不启动后台清理协程）
+func newTransientDetector() *defaultDetector {
+	config := GetDefaultDetectorConfig()
+	config.EnableCache = false
+	return NewDetector(config).(*defaultDetector)
+}
+
 // SmartDetectEncoding 智能编码检测
 func (d *defaultDetector) SmartDetectEncoding(data []byte) (*DetectionResult, error) {
+	start := time.Now()
+	result, err := d.smartDetectEncoding("", data)
+	d.recordDetection(start, result, err, nil)
+	d.sampleFailure("", data, result, err)
+	return result, err
+}
+
+// smartDetectEncoding 智能编码检测（不采集失败样本），path 用于匹配检测覆盖规则（可以为空）
+func (d *defaultDetector) smartDetectEncoding(path string, data []byte) (*DetectionResult, error) {
 	if len(data) == 0 {
 		return nil, &EncodingError{
 			Op:  OperationDetect,
@@ -74,6 +122,11 @@ func (d *defaultDetector) SmartDetectEncoding(data []byte) (*DetectionResult, er
 		}
 	}
 
+	// 检测覆盖规则优先
+	if result := d.applyRules(path, data); result != nil {
+		return result, nil
+	}
+
 	// 使用改进的检测策略
 	result := d.detectEncodingAccurately(data)
 	if result == nil {
@@ -84,7 +137,13 @@ func (d *defaultDetector) SmartDetectEncoding(data []byte) (*DetectionResult, er
 		}
 	}
 
-	return result, nil
+	return d.vendorVariant(result, data), nil
+}
+
+// vendorVariant 数据包含厂商扩展字符时将检测结果的编码替换为对应的 Windows 代码页（如 SHIFT_JIS -> CP932）
+func (d *defaultDetector) vendorVariant(result *DetectionResult, data []byte) *DetectionResult {
+	result.Encoding = detector.VendorVariant(result.Encoding, data)
+	return result
 }
 
 // detectEncodingAccurately 使用多种策略精确检测编码 - 基于内容分析
@@ -104,8 +163,8 @@ func (d *defaultDetector) detectEncodingAccurately(data []byte) *DetectionResult
 		return &DetectionResult{
 			Encoding:   "ASCII",
 			Confidence: 0.95,
-			Details: map[string]interface{}{
-				"method": "ascii_detection",
+			Details: &DetectionDetails{
+				Method: "ascii_detection",
 			},
 		}
 	}
@@ -115,37 +174,24 @@ func (d *defaultDetector) detectEncodingAccurately(data []byte) *DetectionResult
 		return &DetectionResult{
 			Encoding:   EncodingUTF8,
 			Confidence: 0.99,
-			Details: map[string]interface{}{
-				"method": "utf8_validation",
+			Details: &DetectionDetails{
+				Method: "utf8_validation",
 			},
 		}
 	}
+
+	// 5. 文档内的编码声明
+	if declared := d.detectDeclaration(data); declared != nil {
+		return declared
+	}
 	
-	// 5. 使用chardet库检测
-	detector := chardet.NewTextDetector()
-	results, err := detector.DetectAll(data)
-	if err == nil && len(results) > 0 {
-		// 找最高置信度的结果
-		bestResult := results[0]
-		for _, result := range results {
-			if result.Confidence > bestResult.Confidence {
-				bestResult = result
-			}
-		}
-		
-		encoding := d.normalizeEncodingName(bestResult.Charset)
-		return &DetectionResult{
-			Encoding:   encoding,
-			Confidence: float64(bestResult.Confidence) / 100.0,
-			Details: map[string]interface{}{
-				"method":  "chardet",
-				"charset": bestResult.Charset,
-			},
-		}
+	// 6. 集成投票：合并候选评分与传统检测结果
+	if ensembleResult := d.detectByEnsemble(data); ensembleResult != nil {
+		return ensembleResult
 	}
 	
-	// 6. 使用传统检测作为最后手段
-	traditionalResult, _ := d.DetectEncoding(data)
+	// 7. 使用传统检测作为最后手段
+	traditionalResult, _ := d.detectEncoding(data)
 	if traditionalResult != nil {
 		return traditionalResult
 	}
@@ -155,58 +201,36 @@ func (d *defaultDetector) detectEncodingAccurately(data []byte) *DetectionResult
 
 // isASCII 检查是否为纯ASCII
 func (d *defaultDetector) isASCII(data []byte) bool {
-	for _, b := range data {
-		if b > 127 {
-			return false
-		}
-	}
-	return true
+	return detector.IsASCII(data)
 }
 
 // detectSpecialEncodings 检测特殊编码 - 仅基于内容
 func (d *defaultDetector) detectSpecialEncodings(data []byte) *DetectionResult {
-	dataStr := string(data)
-	
-	// HZ编码检测 - 查找HZ特征标记
-	if d.containsString(dataStr, "~{") && d.containsString(dataStr, "~}") {
-		return &DetectionResult{
-			Encoding:   "HZ",
-			Confidence: 0.85,
-			Details: map[string]interface{}{
-				"method": "hz_pattern_detection",
-			},
-		}
-	}
-	
-	return nil
-}
-
-// containsString 检查字符串是否包含子串
-func (d *defaultDetector) containsString(s, substr string) bool {
-	return len(s) >= len(substr) && 
-		   (len(substr) == 0 || 
-		    d.indexOf(s, substr) >= 0)
-}
-
-// indexOf 查找子串在字符串中的位置
-func (d *defaultDetector) indexOf(s, substr string) int {
-	if len(substr) == 0 {
-		return 0
-	}
-	if len(substr) > len(s) {
-		return -1
+	// ISO-2022-JP、ISO-2022-KR、HZ-GB-2312 等 7 位编码按转义序列识别
+	encoding := detector.EscapeSequence(data)
+	if encoding == "" {
+		return nil
 	}
-	
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return i
-		}
+	return &DetectionResult{
+		Encoding:   encoding,
+		Confidence: 0.95,
+		Details: &DetectionDetails{
+			Method: detector.MethodEscapeSequence,
+		},
 	}
-	return -1
 }
 
 // DetectEncoding 检测数据的编码格式
 func (d *defaultDetector) DetectEncoding(data []byte) (*DetectionResult, error) {
+	start := time.Now()
+	result, err := d.detectEncoding(data)
+	d.recordDetection(start, result, err, nil)
+	d.sampleFailure("", data, result, err)
+	return result, err
+}
+
+// detectEncoding 检测数据的编码格式（不采集失败样本）
+func (d *defaultDetector) detectEncoding(data []byte) (*DetectionResult, error) {
 	if len(data) == 0 {
 		return nil, &EncodingError{
 			Op:  OperationDetect,
@@ -214,11 +238,9 @@ func (d *defaultDetector) DetectEncoding(data []byte) (*DetectionResult, error)
 		}
 	}
 
-	// 检查缓存
-	if d.cache != nil {
-		if cached := d.getCachedResult(data); cached != nil {
-			return cached, nil
-		}
+	// 检测覆盖规则优先于缓存和统计检测
+	if result := d.applyRules("", data); result != nil {
+		return result, nil
 	}
 
 	// 限制检测样本大小
@@ -227,26 +249,43 @@ func (d *defaultDetector) DetectEncoding(data []byte) (*DetectionResult, error)
 		data = data[:sampleSize]
 	}
 
+	// 检查缓存（以样本为键，与缓存结果时一致）
+	if cached := d.getCachedResult(data); cached != nil {
+		return cached, nil
+	}
+
 	// 首先尝试检测 BOM
 	if bomResult := d.detectBOM(data); bomResult != nil {
 		d.cacheResult(data, bomResult)
 		return bomResult, nil
 	}
 
+	// 转义序列编码是 7 位数据，需要在 UTF-8 检查之前识别
+	if specialResult := d.detectSpecialEncodings(data); specialResult != nil {
+		d.cacheResult(data, specialResult)
+		return specialResult, nil
+	}
+
 	// 检查是否是有效的 UTF-8
 	if utf8Result := d.detectUTF8(data); utf8Result != nil {
 		d.cacheResult(data, utf8Result)
 		return utf8Result, nil
 	}
 
-	// 使用 chardet 进行检测
-	detector := chardet.NewTextDetector()
-	results, err := detector.DetectAll(data)
+	// 数据能按文档内声明的编码无损解码时采用声明
+	if declared := d.detectDeclaration(data); declared != nil {
+		declared = d.vendorVariant(declared, data)
+		d.cacheResult(data, declared)
+		return declared, nil
+	}
+
+	// 使用字符集检测后端（默认 chardet）进行检测
+	results, err := d.backend().DetectAll(data)
 	if err != nil {
 		return nil, &EncodingError{
 			Op:       OperationDetect,
 			Encoding: "unknown",
-			Err:      fmt.Errorf("chardet detection failed: %w", err),
+			Err:      fmt.Errorf("charset backend detection failed: %w", err),
 		}
 	}
 
@@ -278,20 +317,46 @@ func (d *defaultDetector) DetectEncoding(data []byte) (*DetectionResult, error)
 	}
 
 	// 检查置信度
-	if bestResult.Confidence < d.config.MinConfidence {
+	if minConfidence := d.config.MinConfidenceFor(bestResult.Encoding); bestResult.Confidence < minConfidence {
 		return nil, &EncodingError{
 			Op:       OperationDetect,
 			Encoding: bestResult.Encoding,
-			Err:      fmt.Errorf("confidence too low: %.2f < %.2f", bestResult.Confidence, d.config.MinConfidence),
+			Err:      fmt.Errorf("confidence too low: %.2f < %.2f", bestResult.Confidence, minConfidence),
 		}
 	}
 
 	// 缓存结果
+	bestResult = d.vendorVariant(bestResult, data)
 	d.cacheResult(data, bestResult)
 
 	return bestResult, nil
 }
 
+// DetectEncodingContext 检测数据的编码格式，上下文已取消或超时时返回上下文错误
+//
+// 检测只处理采样数据，耗时有限，因此在检测前后检查上下文。
+// 上下文中的指标标签（WithMetricsLabels）随检测一并记录。
+func (d *defaultDetector) DetectEncodingContext(ctx context.Context, data []byte) (*DetectionResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &EncodingError{Op: OperationDetect, Err: err}
+	}
+	start := time.Now()
+	result, err := d.detectEncoding(data)
+	d.recordDetection(start, result, err, MetricsLabelsFromContext(ctx))
+	d.sampleFailure("", data, result, err)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, &EncodingError{Op: OperationDetect, Err: ctxErr}
+	}
+	return result, err
+}
+
+// DetectReaderEncoding 读取最多 SampleSize 字节检测编码，返回检测结果和重放已读取数据的读取器
+//
+// 返回的读取器先输出已读取的样本，再输出 r 的剩余数据；检测失败时读取器仍然有效。
+func (d *defaultDetector) DetectReaderEncoding(r io.Reader) (*DetectionResult, io.Reader, error) {
+	return detectReaderEncoding(d, r, d.config.SampleSize)
+}
+
 // DetectFileEncoding 检测文件的编码格式
 func (d *defaultDetector) DetectFileEncoding(filename string) (*DetectionResult, error) {
 	data, err := ioutil.ReadFile(filename)
@@ -303,7 +368,7 @@ func (d *defaultDetector) DetectFileEncoding(filename string) (*DetectionResult,
 		}
 	}
 
-	result, err := d.DetectEncoding(data)
+	result, err := d.DetectEncodingWithPath(filename, data)
 	if err != nil {
 		if encErr, ok := err.(*EncodingError); ok {
 			encErr.File = filename
@@ -353,9 +418,9 @@ func (d *defaultDetector) detectUTF8(data []byte) *DetectionResult {
 		return &DetectionResult{
 			Encoding:   EncodingUTF8,
 			Confidence: confidence,
-			Details: map[string]interface{}{
-				"method": "utf8_validation",
-				"has_non_ascii": hasNonASCII,
+			Details: &DetectionDetails{
+				Method:      "utf8_validation",
+				HasNonASCII: hasNonASCII,
 			},
 		}
 	}
@@ -365,74 +430,23 @@ func (d *defaultDetector) detectUTF8(data []byte) *DetectionResult {
 
 // detectBOM 检测字节顺序标记
 func (d *defaultDetector) detectBOM(data []byte) *DetectionResult {
-	if len(data) < 2 {
+	encoding, _ := detector.BOM(data)
+	if encoding == "" {
 		return nil
 	}
 
-	// UTF-8 BOM: EF BB BF
-	if len(data) >= 3 && data[0] == 0xEF && data[1] == 0xBB && data[2] == 0xBF {
-		return &DetectionResult{
-			Encoding:   EncodingUTF8,
-			Confidence: 1.0,
-			Details: map[string]interface{}{
-				"bom": true,
-				"method": "bom_detection",
-			},
-		}
-	}
-
-	// UTF-16 LE BOM: FF FE
-	if data[0] == 0xFF && data[1] == 0xFE {
-		// 检查是否是 UTF-32 LE
-		if len(data) >= 4 && data[2] == 0x00 && data[3] == 0x00 {
-			return &DetectionResult{
-				Encoding:   EncodingUTF32LE,
-				Confidence: 1.0,
-				Details: map[string]interface{}{
-					"bom": true,
-					"method": "bom_detection",
-				},
-			}
-		}
-		return &DetectionResult{
-			Encoding:   EncodingUTF16LE,
-			Confidence: 1.0,
-			Details: map[string]interface{}{
-				"bom": true,
-				"method": "bom_detection",
-			},
-		}
-	}
-
-	// UTF-16 BE BOM: FE FF
-	if data[0] == 0xFE && data[1] == 0xFF {
-		return &DetectionResult{
-			Encoding:   EncodingUTF16BE,
-			Confidence: 1.0,
-			Details: map[string]interface{}{
-				"bom": true,
-				"method": "bom_detection",
-			},
-		}
-	}
-
-	// UTF-32 BE BOM: 00 00 FE FF
-	if len(data) >= 4 && data[0] == 0x00 && data[1] == 0x00 && data[2] == 0xFE && data[3] == 0xFF {
-		return &DetectionResult{
-			Encoding:   EncodingUTF32BE,
-			Confidence: 1.0,
-			Details: map[string]interface{}{
-				"bom": true,
-				"method": "bom_detection",
-			},
-		}
+	return &DetectionResult{
+		Encoding:   encoding,
+		Confidence: 1.0,
+		Details: &DetectionDetails{
+			BOM:    true,
+			Method: detector.MethodBOM,
+		},
 	}
-
-	return nil
 }
 
 // selectBestResult 选择最佳检测结果
-func (d *defaultDetector) selectBestResult(results []chardet.Result) *DetectionResult {
+func (d *defaultDetector) selectBestResult(results []CharsetMatch) *DetectionResult {
 	if len(results) == 0 {
 		return nil
 	}
@@ -445,9 +459,9 @@ func (d *defaultDetector) selectBestResult(results []chardet.Result) *DetectionR
 					Encoding:   preferred,
 					Confidence: float64(result.Confidence) / 100.0,
 					Language:   result.Language,
-					Details: map[string]interface{}{
-						"method": "chardet",
-						"charset": result.Charset,
+					Details: &DetectionDetails{
+						Method:  MethodChardet,
+						Charset: result.Charset,
 					},
 				}
 			}
@@ -462,46 +476,16 @@ func (d *defaultDetector) selectBestResult(results []chardet.Result) *DetectionR
 		Encoding:   encoding,
 		Confidence: float64(best.Confidence) / 100.0,
 		Language:   best.Language,
-		Details: map[string]interface{}{
-			"method": "chardet",
-			"charset": best.Charset,
+		Details: &DetectionDetails{
+			Method:  MethodChardet,
+			Charset: best.Charset,
 		},
 	}
 }
 
 // normalizeEncodingName 规范化编码名称
 func (d *defaultDetector) normalizeEncodingName(charset string) string {
-	// 映射 chardet 的编码名称到我们的标准名称
-	mapping := map[string]string{
-		"UTF-8":        EncodingUTF8,
-		"UTF-16":       EncodingUTF16,
-		"UTF-16LE":     EncodingUTF16LE,
-		"UTF-16BE":     EncodingUTF16BE,
-		"UTF-32":       EncodingUTF32,
-		"UTF-32LE":     EncodingUTF32LE,
-		"UTF-32BE":     EncodingUTF32BE,
-		"GB2312":       "GB2312", // 保持GB2312独立
-		"GBK":          EncodingGBK,
-		"GB18030":      EncodingGB18030,
-		"GB-18030":     EncodingGB18030, // 添加变体支持
-		"Big5":         EncodingBIG5,
-		"BIG5":         EncodingBIG5,
-		"Shift_JIS":    EncodingShiftJIS,
-		"EUC-JP":       EncodingEUCJP,
-		"EUC-KR":       EncodingEUCKR,
-		"EUC-CN":       "EUC-CN", // 添加EUC-CN支持
-		"HZ":           "HZ",     // 添加HZ编码支持
-		"HZ-GB-2312":   "HZ",
-		"ISO-8859-1":   EncodingISO88591,
-		"windows-1252": EncodingWindows1252,
-		"KOI8-R":       EncodingKOI8R,
-	}
-
-	if normalized, exists := mapping[charset]; exists {
-		return normalized
-	}
-
-	return charset
+	return detector.NormalizeCharset(charset)
 }
 
 // isEncodingSupported 检查编码是否在支持列表中
@@ -518,49 +502,52 @@ func (d *defaultDetector) isEncodingSupported(encoding string) bool {
 	return false
 }
 
-// getCachedResult 获取缓存的检测结果
-func (d *defaultDetector) getCachedResult(data []byte) *DetectionResult {
-	if d.cache == nil {
-		return nil
+// Drain 停止缓存清理协程并释放检测结果缓存，并导出支持的性能监控器中缓冲的指标
+func (d *defaultDetector) Drain(ctx context.Context) error {
+	if d.cache != nil {
+		d.cache.close()
+	}
+	if flusher, ok := d.config.Metrics.(FlushingMetricsCollector); ok {
+		return flusher.Flush()
 	}
+	return nil
+}
 
-	key := d.generateCacheKey(data)
-	d.cache.mutex.RLock()
-	defer d.cache.mutex.RUnlock()
+// Close 释放检测器资源
+func (d *defaultDetector) Close() error {
+	return d.Drain(context.Background())
+}
 
-	entry, exists := d.cache.cache[key]
-	if !exists {
-		return nil
+// recordDetection 向配置的性能监控器记录一次检测（成功时同时记录检测到的编码，labels 非空时按标签记录）
+func (d *defaultDetector) recordDetection(start time.Time, result *DetectionResult, err error, labels MetricsLabels) {
+	recordOperation(d.config.Metrics, OperationDetect, start, err, labels)
+	if collector, ok := d.config.Metrics.(EncodingMetricsCollector); ok && err == nil && result != nil {
+		collector.RecordEncoding(result.Encoding)
 	}
+}
 
-	// 检查是否过期
-	if time.Since(entry.timestamp) > d.config.CacheTTL {
-		// 异步删除过期项
-		go d.removeExpiredCacheEntry(key)
+// getCachedResult 获取缓存的检测结果，并向支持的性能监控器记录命中情况
+func (d *defaultDetector) getCachedResult(data []byte) *DetectionResult {
+	if d.cache == nil {
 		return nil
 	}
 
-	return entry.result
+	result, hit := d.cache.get(d.generateCacheKey(data))
+	if metrics, ok := d.config.Metrics.(CacheMetricsCollector); ok {
+		metrics.RecordCacheAccess(hit)
+	}
+	return result
 }
 
-// cacheResult 缓存检测结果
+// cacheResult 缓存检测结果（缓存已满时淘汰最久未使用的条目）
 func (d *defaultDetector) cacheResult(data []byte, result *DetectionResult) {
 	if d.cache == nil {
 		return
 	}
 
-	key := d.generateCacheKey(data)
-	d.cache.mutex.Lock()
-	defer d.cache.mutex.Unlock()
-
-	// 如果缓存已满，删除最旧的条目
-	if len(d.cache.cache) >= d.config.CacheSize {
-		d.evictOldestEntry()
-	}
-
-	d.cache.cache[key] = &cacheEntry{
-		result:    result,
-		timestamp: time.Now(),
+	evicted := d.cache.put(d.generateCacheKey(data), result)
+	if metrics, ok := d.config.Metrics.(CacheMetricsCollector); ok && evicted {
+		metrics.RecordCacheEviction()
 	}
 }
 
@@ -571,154 +558,184 @@ func (d *defaultDetector) generateCacheKey(data []byte) string {
 	return fmt.Sprintf("%x", hash)
 }
 
-// removeExpiredCacheEntry 删除过期的缓存项
-func (d *defaultDetector) removeExpiredCacheEntry(key string) {
-	d.cache.mutex.Lock()
-	defer d.cache.mutex.Unlock()
-	delete(d.cache.cache, key)
-}
-
-// evictOldestEntry 删除最旧的缓存项
-func (d *defaultDetector) evictOldestEntry() {
-	var oldestKey string
-	var oldestTime time.Time
-
-	for key, entry := range d.cache.cache {
-		if oldestKey == "" || entry.timestamp.Before(oldestTime) {
-			oldestKey = key
-			oldestTime = entry.timestamp
-		}
-	}
-
-	if oldestKey != "" {
-		delete(d.cache.cache, oldestKey)
-	}
-}
-
 // getAllCandidates 获取所有候选编码
 func (d *defaultDetector) getAllCandidates(data []byte) []*DetectionCandidate {
 	var candidates []*DetectionCandidate
 	
-	// 1. chardet检测结果
-	detector := chardet.NewTextDetector()
-	if results, err := detector.DetectAll(data); err == nil {
+	// 1. 字符集检测后端（默认 chardet）结果
+	if results, err := d.backend().DetectAll(data); err == nil {
 		for _, result := range results {
 			encoding := d.normalizeEncodingName(result.Charset)
 			candidates = append(candidates, &DetectionCandidate{
 				Encoding:   encoding,
 				Confidence: float64(result.Confidence) / 100.0,
-				Method:     "chardet",
+				Method:     MethodChardet,
 			})
 		}
 	}
 	
-	// 2. 为中文编码增加额外候选
+	// 2. 为中文、日文、韩文编码增加额外候选
 	if d.containsChineseBytes(data) {
-		chineseEncodings := []string{EncodingGBK, EncodingGB18030, EncodingBIG5}
-		for _, enc := range chineseEncodings {
-			found := false
-			for _, candidate := range candidates {
-				if candidate.Encoding == enc {
-					found = true
-					break
-				}
-			}
-			if !found {
-				candidates = append(candidates, &DetectionCandidate{
-					Encoding:   enc,
-					Confidence: 0.05, // 低置信度候选
-					Method:     "chinese_heuristic",
-				})
+		candidates = appendHeuristicCandidates(candidates, MethodChineseHeuristic, EncodingGBK, EncodingGB18030, EncodingBIG5)
+	}
+	if d.containsJapaneseBytes(data) {
+		candidates = appendHeuristicCandidates(candidates, MethodJapaneseHeuristic, EncodingShiftJIS, EncodingEUCJP)
+	}
+	if d.containsKoreanBytes(data) {
+		candidates = appendHeuristicCandidates(candidates, MethodKoreanHeuristic, EncodingEUCKR)
+	}
+	
+	return candidates
+}
+
+// appendHeuristicCandidates 将启发式推断的编码作为低置信度候选加入（已有的候选编码不重复加入）
+func appendHeuristicCandidates(candidates []*DetectionCandidate, method string, encodings ...string) []*DetectionCandidate {
+	for _, enc := range encodings {
+		found := false
+		for _, candidate := range candidates {
+			if candidate.Encoding == enc {
+				found = true
+				break
 			}
 		}
+		if !found {
+			candidates = append(candidates, &DetectionCandidate{
+				Encoding:   enc,
+				Confidence: 0.05, // 低置信度候选
+				Method:     method,
+			})
+		}
 	}
-	
 	return candidates
 }
 
 // scoreCandidates 对候选编码进行评分
+//
+// 配置了 PrescoreSize 时分级评分：先按样本开头的切片为全部候选评分，
+// 只对得分最高的 PrescoreTopK 个候选转换完整样本重新评分，其余候选保留切片得分。
 func (d *defaultDetector) scoreCandidates(data []byte, candidates []*DetectionCandidate) []*DetectionCandidate {
-	for _, candidate := range candidates {
-		// 尝试转换为UTF-8
-		convertedText := d.tryConvert(data, candidate.Encoding)
-		candidate.ConvertedText = convertedText
-		
-		// 计算综合得分
-		score := d.calculateScore(data, candidate, convertedText)
-		candidate.Score = score
+	class := d.contentClass(data)
+	topK := d.config.PrescoreTopK
+	if topK <= 0 {
+		topK = DefaultPrescoreTopK
 	}
-	
-	// 按得分排序
-	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].Score > candidates[j].Score
-	})
-	
+
+	if slice := prescoreSlice(data, d.config.PrescoreSize); len(slice) < len(data) && len(candidates) > topK {
+		for _, candidate := range candidates {
+			d.scoreCandidate(slice, candidate, class)
+		}
+		sortCandidates(candidates)
+		for _, candidate := range candidates[:topK] {
+			d.scoreCandidate(data, candidate, class)
+		}
+	} else {
+		for _, candidate := range candidates {
+			d.scoreCandidate(data, candidate, class)
+		}
+	}
+
+	sortCandidates(candidates)
 	return candidates
 }
 
-// calculateScore 计算候选编码的综合得分
-func (d *defaultDetector) calculateScore(data []byte, candidate *DetectionCandidate, convertedText string) float64 {
-	score := candidate.Confidence * 0.4 // 基础置信度权重40%
+// scoreCandidate 按数据为单个候选编码评分
+func (d *defaultDetector) scoreCandidate(data []byte, candidate *DetectionCandidate, class string) {
+	// 尝试转换为UTF-8
+	d.scoreDecoded(data, candidate, class, d.tryConvert(data, candidate.Encoding))
+}
+
+// scoreDecoded 按已解码的文本为单个候选编码评分（convertedText 为空表示无法解码）
+func (d *defaultDetector) scoreDecoded(data []byte, candidate *DetectionCandidate, class, convertedText string) {
+	candidate.ContentClass = class
+	candidate.ConvertedText = convertedText
+
+	// 计算综合得分
+	candidate.Score = d.calculateScore(data, candidate, convertedText)
+
+	// 对解码文本进行二次评分
+	if convertedText != "" {
+		for _, scorer := range d.postScorers() {
+			adjustment := scorer.PostScore(candidate, convertedText)
+			if adjustment == 0 {
+				continue
+			}
+			if candidate.Score.PostAdjustments == nil {
+				candidate.Score.PostAdjustments = make(map[string]float64)
+			}
+			candidate.Score.PostAdjustments[scorer.Name()] = adjustment
+			candidate.Score.Total += adjustment
+		}
+	}
+}
+
+// sortCandidates 按综合得分降序排列候选编码
+func sortCandidates(candidates []*DetectionCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score.Total > candidates[j].Score.Total
+	})
+}
+
+// prescoreSlice 返回分级评分使用的样本切片（在最后一个换行符处截断以免切断多字节字符，
+// size 为 0 或样本不超过 size 时返回完整样本）
+func prescoreSlice(data []byte, size int) []byte {
+	if size <= 0 || len(data) <= size {
+		return data
+	}
+	slice := data[:size]
+	if i := bytes.LastIndexByte(slice, '\n'); i > 0 {
+		slice = slice[:i+1]
+	}
+	return slice
+}
+
+// calculateScore 计算候选编码的综合得分及其组成
+func (d *defaultDetector) calculateScore(data []byte, candidate *DetectionCandidate, convertedText string) ScoreBreakdown {
+	scoring := d.scoring()
+	breakdown := ScoreBreakdown{
+		BaseConfidence: candidate.Confidence * scoring.ConfidenceWeight,
+	}
 	
 	if convertedText == "" {
-		return score * 0.1 // 转换失败大幅降低得分
+		// 转换失败大幅降低得分
+		breakdown.ConversionFailed = true
+		breakdown.Total = breakdown.BaseConfidence * 0.1
+		return breakdown
 	}
 	
-	// 1. 检查是否包含有效的中文字符
-	chineseScore := d.scoreChineseCharacters(convertedText)
-	score += chineseScore * 0.3 // 中文字符得分权重30%
+	// 源代码中的非英文内容通常只出现在注释和字符串里，仅按非ASCII部分评分
+	scriptText := convertedText
+	if candidate.ContentClass == ContentClassCode {
+		scriptText = nonASCIIText(convertedText)
+	}
+	
+	// 1. 按语言模型评分文字
+	breakdown.ScriptScore = scoring.scriptScore(scriptText) * scoring.ScriptWeight
 	
 	// 2. 检查字符合理性
-	validityScore := d.scoreCharacterValidity(convertedText)
-	score += validityScore * 0.2 // 字符有效性权重20%
+	breakdown.ValidityScore = d.scoreCharacterValidityFor(convertedText, candidate.ContentClass) * scoring.ValidityWeight
 	
 	// 3. 检查是否有乱码特征
-	garbledScore := d.scoreGarbledText(convertedText)
-	score += garbledScore * 0.1 // 乱码检测权重10%
+	breakdown.GarbledPenalty = (1.0 - d.scoreGarbledText(convertedText)) * scoring.GarbledWeight
 	
-	return score
+	breakdown.Total = breakdown.BaseConfidence + breakdown.ScriptScore + breakdown.ValidityScore + scoring.GarbledWeight - breakdown.GarbledPenalty
+	return breakdown
 }
 
-// scoreChineseCharacters 评分中文字符质量
-func (d *defaultDetector) scoreChineseCharacters(text string) float64 {
-	if text == "" {
-		return 0
-	}
-	
-	totalRunes := 0
-	chineseRunes := 0
-	commonChineseRunes := 0
-	
-	// 常见中文字符范围
-	commonChineseChars := map[rune]bool{
-		'的': true, '一': true, '是': true, '在': true, '不': true,
-		'了': true, '有': true, '和': true, '人': true, '这': true,
-		'中': true, '大': true, '为': true, '上': true, '个': true,
-		'文': true, '件': true, '作': true, '者': true, '时': true,
+// scoring 返回候选评分配置
+func (d *defaultDetector) scoring() *ScoringConfig {
+	if d.config.Scoring != nil {
+		return d.config.Scoring
 	}
-	
-	for _, r := range text {
-		totalRunes++
-		if r >= 0x4e00 && r <= 0x9fff {
-			chineseRunes++
-			if commonChineseChars[r] {
-				commonChineseRunes++
-			}
-		}
-	}
-	
-	if totalRunes == 0 {
-		return 0
-	}
-	
-	chineseRatio := float64(chineseRunes) / float64(totalRunes)
-	commonRatio := float64(commonChineseRunes) / float64(totalRunes)
-	
-	return chineseRatio*0.7 + commonRatio*0.3
+	return defaultScoringConfig
 }
 
 // scoreCharacterValidity 评分字符有效性
 func (d *defaultDetector) scoreCharacterValidity(text string) float64 {
+	return d.scoreCharacterValidityFor(text, ContentClassProse)
+}
+
+// scoreCharacterValidityFor 按内容类别评分字符有效性
+func (d *defaultDetector) scoreCharacterValidityFor(text, class string) float64 {
 	if text == "" {
 		return 0
 	}
@@ -730,7 +747,7 @@ func (d *defaultDetector) scoreCharacterValidity(text string) float64 {
 		totalChars++
 		
 		// 检查是否是有效字符
-		if d.isValidCharacter(r) {
+		if d.isValidCharacter(r) || (class == ContentClassCode && isCodeSymbol(r)) {
 			validChars++
 		}
 	}
@@ -744,6 +761,11 @@ func (d *defaultDetector) scoreCharacterValidity(text string) float64 {
 
 // isValidCharacter 检查字符是否有效
 func (d *defaultDetector) isValidCharacter(r rune) bool {
+	// 调用方指定的字符表
+	if len(d.config.Alphabets) > 0 {
+		return inAlphabets(d.config.Alphabets, r)
+	}
+
 	// ASCII字符
 	if r >= 32 && r <= 126 {
 		return true
@@ -754,6 +776,11 @@ func (d *defaultDetector) isValidCharacter(r rune) bool {
 		return true
 	}
 	
+	// 语言模型的文字
+	if d.scoring().inScripts(r) {
+		return true
+	}
+	
 	// 中文标点符号
 	if (r >= 0x3000 && r <= 0x303f) || // CJK符号和标点
 		(r >= 0xff00 && r <= 0xffef) {  // 全角ASCII
@@ -768,67 +795,103 @@ func (d *defaultDetector) isValidCharacter(r rune) bool {
 	return false
 }
 
-// scoreGarbledText 检测乱码特征
+// scoreGarbledText 检测乱码特征，返回 0-1 之间的得分，乱码越少得分越高
 func (d *defaultDetector) scoreGarbledText(text string) float64 {
 	if text == "" {
 		return 0
 	}
-	
-	// 乱码特征检测
-	garbledPatterns := []*regexp.Regexp{
-		regexp.MustCompile(`[��]+`),           // 替换字符
-		regexp.MustCompile(`[\x00-\x08\x0B\x0C\x0E-\x1F\x7F]+`), // 控制字符
-		regexp.MustCompile(`[ÿþ]+`),          // 常见乱码字符
-	}
-	
-	garbledCount := 0
-	for _, pattern := range garbledPatterns {
-		if pattern.MatchString(text) {
-			garbledCount++
+	return 1.0 - d.garbled.Score(text)
+}
+
+// containsChineseBytes 检查是否包含中文字节特征
+func (d *defaultDetector) containsChineseBytes(data []byte) bool {
+	// GBK/GB2312、BIG5 的字节范围均为 A1-FE，超过30%的字节在该范围内视为中文
+	return AnalyzeBytes(data).CJKRatio > 0.3
+}
+
+// containsJapaneseBytes 检查是否包含日文字节特征
+func (d *defaultDetector) containsJapaneseBytes(data []byte) bool {
+	// 日文文本中假名通常占多字节字符的三成以上，按 Shift_JIS 和 EUC-JP 的字节结构分别统计
+	return multibyteShare(data, shiftJISCharLen, isShiftJISKana) > 0.3 ||
+		multibyteShare(data, eucCharLen, isEUCJPKana) > 0.3
+}
+
+// containsKoreanBytes 检查是否包含韩文字节特征
+func (d *defaultDetector) containsKoreanBytes(data []byte) bool {
+	// 韩文文本几乎全部由谚文音节组成：EUC-KR 的谚文区（B0-C8 行）或 CP949 的扩展谚文区。
+	// 扩展区收录的是罕用音节，且与 Shift_JIS 假名的字节范围重叠，因此还要求多数字符位于 EUC-KR 谚文区
+	return multibyteShare(data, eucCharLen, isKoreanHangul) > 0.8 &&
+		multibyteShare(data, eucCharLen, isKSHangul) > 0.5
+}
+
+// multibyteShare 按编码的字节结构拆分多字节字符，返回满足 match 的字符占多字节字符的比例
+//
+// charLen 返回以 data[i] 开头的字符长度（单字节字符返回 1），不完整的字符不计入。
+func multibyteShare(data []byte, charLen func(b byte) int, match func(lead, trail byte) bool) float64 {
+	var multibyte, matched int
+	for i := 0; i < len(data); {
+		n := charLen(data[i])
+		if n > 1 && i+n <= len(data) {
+			multibyte++
+			if n == 2 && match(data[i], data[i+1]) {
+				matched++
+			}
 		}
+		i += n
 	}
-	
-	// 返回0-1之间的得分，乱码越少得分越高
-	return 1.0 - float64(garbledCount)/float64(len(garbledPatterns))
+	if multibyte == 0 {
+		return 0
+	}
+	return float64(matched) / float64(multibyte)
 }
 
-// selectBestCandidate 选择最佳候选结果
-func (d *defaultDetector) selectBestCandidate(candidates []*DetectionCandidate, traditionalResult *DetectionResult) *DetectionCandidate {
-	if len(candidates) == 0 {
-		return nil
+// shiftJISCharLen 返回 Shift_JIS 中以 b 开头的字符长度
+func shiftJISCharLen(b byte) int {
+	if b >= 0x81 && b <= 0x9F || b >= 0xE0 && b <= 0xFC {
+		return 2
 	}
-	
-	// 如果传统方法有高置信度结果，优先考虑
-	if traditionalResult != nil && traditionalResult.Confidence >= 0.8 {
-		for _, candidate := range candidates {
-			if candidate.Encoding == traditionalResult.Encoding {
-				candidate.Score += 0.2 // 加分
-				break
-			}
-		}
-		
-		// 重新排序
-		sort.Slice(candidates, func(i, j int) bool {
-			return candidates[i].Score > candidates[j].Score
-		})
+	return 1
+}
+
+// eucCharLen 返回 EUC-JP、EUC-KR 中以 b 开头的字符长度（EUC-KR 按 CP949 扩展的前导字节范围）
+func eucCharLen(b byte) int {
+	switch {
+	case b == 0x8F:
+		return 3
+	case b >= 0x81 && b <= 0xFE:
+		return 2
 	}
-	
-	return candidates[0]
+	return 1
 }
 
-// containsChineseBytes 检查是否包含中文字节特征
-func (d *defaultDetector) containsChineseBytes(data []byte) bool {
-	chineseByteCount := 0
-	for _, b := range data {
-		// GBK/GB2312: A1-FE
-		// BIG5: A1-FE
-		if b >= 0xA1 && b <= 0xFE {
-			chineseByteCount++
-		}
+// isShiftJISKana 检查 Shift_JIS 双字节字符是否是平假名或片假名
+func isShiftJISKana(lead, trail byte) bool {
+	return lead == 0x82 && trail >= 0x9F && trail <= 0xF1 ||
+		lead == 0x83 && trail >= 0x40 && trail <= 0x96
+}
+
+// isEUCJPKana 检查 EUC-JP 双字节字符是否是平假名或片假名
+func isEUCJPKana(lead, trail byte) bool {
+	return lead == 0xA4 && trail >= 0xA1 && trail <= 0xF3 ||
+		lead == 0xA5 && trail >= 0xA1 && trail <= 0xF6
+}
+
+// isKSHangul 检查 EUC-KR 双字节字符是否位于 KS X 1001 谚文区
+func isKSHangul(lead, trail byte) bool {
+	return lead >= 0xB0 && lead <= 0xC8 && trail >= 0xA1 && trail <= 0xFE
+}
+
+// isKoreanHangul 检查 EUC-KR/CP949 双字节字符是否是谚文音节
+func isKoreanHangul(lead, trail byte) bool {
+	if isKSHangul(lead, trail) {
+		return true
 	}
-	
-	// 如果超过30%的字节在中文范围内
-	return float64(chineseByteCount)/float64(len(data)) > 0.3
+	// CP949 扩展区：前导 81-C6，尾字节 41-5A、61-7A、81-FE（C6 行只到 52）
+	if lead < 0x81 || lead > 0xC6 || lead == 0xC6 && trail > 0x52 {
+		return false
+	}
+	return trail >= 0x41 && trail <= 0x5A || trail >= 0x61 && trail <= 0x7A || trail >= 0x81 && trail <= 0xA0 ||
+		lead <= 0xA0 && trail >= 0xA1 && trail <= 0xFE
 }
 
 // tryConvert 尝试转换编码
@@ -843,7 +906,15 @@ func (d *defaultDetector) tryConvert(data []byte, encoding string) string {
 	case EncodingBIG5:
 		decoder = traditionalchinese.Big5.NewDecoder()
 	default:
-		return ""
+		// 指定了字符表时按全部候选编码解码评分，否则只解码语言模型的常用编码
+		if len(d.config.Alphabets) == 0 && !d.scoring().decodes(encoding) {
+			return ""
+		}
+		enc, err := converter.Lookup(encoding)
+		if err != nil {
+			return ""
+		}
+		decoder = enc.NewDecoder()
 	}
 	
 	if decoder == nil {
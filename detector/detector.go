@@ -0,0 +1,202 @@
+// Package detector 提供轻量级的编码检测
+//
+// 本包只依赖标准库和 chardet，不引入 golang.org/x/text 的编码表和转换器，
+// 适合只需要判断编码、不需要转换的工具（例如文件索引代理）。
+// 返回的编码名称与 encoding 包的 Encoding* 常量一致。
+//
+// 需要候选评分、集成投票、检测规则等完整能力时请使用 encoding 包的 Detector。
+package detector
+
+import (
+	"bytes"
+	"errors"
+	"unicode/utf8"
+
+	"github.com/saintfish/chardet"
+)
+
+var (
+	// ErrInvalidInput 无效输入
+	ErrInvalidInput = errors.New("invalid input data")
+
+	// ErrDetectionFailed 检测失败
+	ErrDetectionFailed = errors.New("encoding detection failed")
+)
+
+// 检测方法
+const (
+	MethodBOM            = "bom_detection"
+	MethodEscapeSequence = "escape_sequence"
+	MethodUTF8           = "utf8_validation"
+	MethodChardet        = "chardet"
+)
+
+// Result 检测结果
+type Result struct {
+	// Encoding 检测到的编码名称
+	Encoding string `json:"encoding"`
+
+	// Confidence 置信度（0-1）
+	Confidence float64 `json:"confidence"`
+
+	// Language 语言（仅 chardet 检测时可能提供）
+	Language string `json:"language,omitempty"`
+
+	// Method 检测方法
+	Method string `json:"method"`
+}
+
+// Detect 依次通过 BOM、转义序列、UTF-8 有效性和 chardet 检测数据的编码
+func Detect(data []byte) (*Result, error) {
+	if len(data) == 0 {
+		return nil, ErrInvalidInput
+	}
+
+	if encoding, _ := BOM(data); encoding != "" {
+		return &Result{Encoding: encoding, Confidence: 1.0, Method: MethodBOM}, nil
+	}
+
+	// 转义序列编码是 7 位数据，同样是有效的 UTF-8，需要在 UTF-8 检查之前识别
+	if encoding := EscapeSequence(data); encoding != "" {
+		return &Result{Encoding: encoding, Confidence: 0.95, Method: MethodEscapeSequence}, nil
+	}
+
+	if utf8.Valid(data) {
+		// 纯 ASCII 文本同样是有效的 UTF-8，但证据较弱
+		confidence := 0.99
+		if IsASCII(data) {
+			confidence = 0.85
+		}
+		return &Result{Encoding: "UTF-8", Confidence: confidence, Method: MethodUTF8}, nil
+	}
+
+	best, err := chardet.NewTextDetector().DetectBest(data)
+	if err != nil || best == nil {
+		return nil, ErrDetectionFailed
+	}
+
+	return &Result{
+		Encoding:   VendorVariant(NormalizeCharset(best.Charset), data),
+		Confidence: float64(best.Confidence) / 100.0,
+		Language:   best.Language,
+		Method:     MethodChardet,
+	}, nil
+}
+
+// BOM 检测数据开头的字节顺序标记，返回对应的编码名称和 BOM 长度（没有 BOM 时返回空字符串）
+func BOM(data []byte) (encoding string, size int) {
+	switch {
+	case len(data) >= 3 && data[0] == 0xEF && data[1] == 0xBB && data[2] == 0xBF:
+		return "UTF-8", 3
+	case len(data) >= 4 && data[0] == 0xFF && data[1] == 0xFE && data[2] == 0x00 && data[3] == 0x00:
+		return "UTF-32LE", 4
+	case len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE:
+		return "UTF-16LE", 2
+	case len(data) >= 2 && data[0] == 0xFE && data[1] == 0xFF:
+		return "UTF-16BE", 2
+	case len(data) >= 4 && data[0] == 0x00 && data[1] == 0x00 && data[2] == 0xFE && data[3] == 0xFF:
+		return "UTF-32BE", 4
+	}
+	return "", 0
+}
+
+// EscapeSequence 按转义序列识别 7 位编码，返回 ISO-2022-KR、ISO-2022-JP 或 HZ-GB-2312（不是这些编码时返回空字符串）
+//
+// ISO-2022-KR 以字符集指定序列 ESC $ ) C 标识；ISO-2022-JP 以切换到 JIS X 0208（ESC $ B、ESC $ @）
+// 或 JIS X 0201（ESC ( J、ESC ( I）的序列标识；HZ-GB-2312 要求每个 ~{ 之后到对应的 ~}
+// （最后一段可以到数据末尾）为成对的 GB2312 字节（0x21-0x7E），以免误判包含 "~{" 的普通文本。
+func EscapeSequence(data []byte) string {
+	if !IsASCII(data) {
+		return ""
+	}
+
+	switch {
+	case bytes.Contains(data, []byte("\x1b$)C")):
+		return "ISO-2022-KR"
+	case bytes.Contains(data, []byte("\x1b$B")) || bytes.Contains(data, []byte("\x1b$@")) ||
+		bytes.Contains(data, []byte("\x1b(J")) || bytes.Contains(data, []byte("\x1b(I")):
+		return "ISO-2022-JP"
+	case isHZ(data):
+		return "HZ-GB-2312"
+	}
+	return ""
+}
+
+// isHZ 检查数据是否包含格式正确的 HZ 双字节段（~{ 与 ~} 之间或 ~{ 到数据末尾为成对的 0x21-0x7E 字节）
+func isHZ(data []byte) bool {
+	segments := 0
+	for {
+		start := bytes.Index(data, []byte("~{"))
+		if start < 0 {
+			return segments > 0
+		}
+		data = data[start+2:]
+		end := bytes.Index(data, []byte("~}"))
+		last := end < 0
+		if last {
+			end = len(data)
+		}
+		// 最后一段可能被采样截断，不要求字节成对
+		if end == 0 || end%2 != 0 && !last {
+			return false
+		}
+		for _, b := range data[:end] {
+			if b < 0x21 || b > 0x7E {
+				return false
+			}
+		}
+		if last {
+			return true
+		}
+		data = data[end+2:]
+		segments++
+	}
+}
+
+// IsASCII 检查数据是否为纯 ASCII
+func IsASCII(data []byte) bool {
+	for _, b := range data {
+		if b > 127 {
+			return false
+		}
+	}
+	return true
+}
+
+// charsetNames chardet 编码名称到标准名称的映射
+var charsetNames = map[string]string{
+	"UTF-8":        "UTF-8",
+	"UTF-16":       "UTF-16",
+	"UTF-16LE":     "UTF-16LE",
+	"UTF-16BE":     "UTF-16BE",
+	"UTF-32":       "UTF-32",
+	"UTF-32LE":     "UTF-32LE",
+	"UTF-32BE":     "UTF-32BE",
+	"GB2312":       "GB2312", // 保持GB2312独立
+	"GBK":          "GBK",
+	"GB18030":      "GB18030",
+	"GB-18030":     "GB18030", // 添加变体支持
+	"Big5":         "BIG5",
+	"BIG5":         "BIG5",
+	"Shift_JIS":    "SHIFT_JIS",
+	"EUC-JP":       "EUC-JP",
+	"EUC-KR":       "EUC-KR",
+	"EUC-CN":       "EUC-CN", // 添加EUC-CN支持
+	"HZ":           "HZ-GB-2312",
+	"HZ-GB-2312":   "HZ-GB-2312",
+	"ISO-2022-JP":  "ISO-2022-JP",
+	"ISO-2022-KR":  "ISO-2022-KR",
+	"ISO-8859-1":   "ISO-8859-1",
+	"windows-1251": "WINDOWS-1251",
+	"windows-1252": "WINDOWS-1252",
+	"windows-1256": "WINDOWS-1256",
+	"KOI8-R":       "KOI8-R",
+}
+
+// NormalizeCharset 将 chardet 的编码名称映射为标准名称（未知名称原样返回）
+func NormalizeCharset(charset string) string {
+	if normalized, exists := charsetNames[charset]; exists {
+		return normalized
+	}
+	return charset
+}
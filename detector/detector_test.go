@@ -0,0 +1,84 @@
+package detector
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestDetect 测试 BOM、UTF-8 和 chardet 检测路径
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		encoding string
+		method   string
+	}{
+		{"UTF-8 BOM", []byte("\xef\xbb\xbfhello"), "UTF-8", MethodBOM},
+		{"UTF-16LE BOM", []byte{0xFF, 0xFE, 'h', 0x00}, "UTF-16LE", MethodBOM},
+		{"UTF-32LE BOM", []byte{0xFF, 0xFE, 0x00, 0x00}, "UTF-32LE", MethodBOM},
+		{"ISO-2022-JP", []byte("\x1b$B$3$s$K$A$O\x1b(B"), "ISO-2022-JP", MethodEscapeSequence},
+		{"ISO-2022-KR", []byte("\x1b$)C\x0e\x30\x21\x0f"), "ISO-2022-KR", MethodEscapeSequence},
+		{"HZ-GB-2312", []byte("~{<:Ky2;S{~}"), "HZ-GB-2312", MethodEscapeSequence},
+		{"Tilde braces", []byte("a ~{ b ~}"), "UTF-8", MethodUTF8},
+		{"UTF-8 text", []byte("你好，世界"), "UTF-8", MethodUTF8},
+		{"ASCII text", []byte("hello world"), "UTF-8", MethodUTF8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Detect(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Encoding != tt.encoding || result.Method != tt.method {
+				t.Errorf("Expected %s via %s, got %s via %s", tt.encoding, tt.method, result.Encoding, result.Method)
+			}
+		})
+	}
+
+	// 非 UTF-8 数据交给 chardet
+	result, err := Detect([]byte("\x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd\x90\xa2\x8a\x45\x81\x41\x93\xfa\x96\x7b\x8c\xea\x82\xcc\x83\x65\x83\x4c\x83\x58\x83\x67\x82\xc5\x82\xb7\x81\x42"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Method != MethodChardet {
+		t.Errorf("Expected chardet detection, got %s", result.Method)
+	}
+
+	if _, err := Detect(nil); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
+	}
+}
+
+// TestNormalizeCharset 测试 chardet 名称映射
+func TestNormalizeCharset(t *testing.T) {
+	if got := NormalizeCharset("Shift_JIS"); got != "SHIFT_JIS" {
+		t.Errorf("Expected SHIFT_JIS, got %s", got)
+	}
+	if got := NormalizeCharset("x-unknown"); got != "x-unknown" {
+		t.Errorf("Expected unknown names to pass through, got %s", got)
+	}
+}
+
+// TestVendorVariant 测试按厂商扩展字符识别 Windows 代码页
+func TestVendorVariant(t *testing.T) {
+	tests := []struct {
+		encoding string
+		data     string
+		expected string
+	}{
+		{"SHIFT_JIS", "\x82\xa0\x87\x40", "CP932"},
+		{"SHIFT_JIS", "\x82\xa0\x82\xa2", "SHIFT_JIS"},
+		{"EUC-KR", "\xb0\xa1\x8c\x63", "CP949"},
+		{"EUC-KR", "\xb0\xa1\xb0\xa2", "EUC-KR"},
+		{"BIG5", "\xc1\x63\x88\x40", "BIG5-HKSCS"},
+		{"BIG5", "\xc1\x63\xf9\xf9", "CP950"},
+		{"BIG5", "\xc1\x63\xc5\xe9", "BIG5"},
+		{"GBK", "\x87\x40", "GBK"},
+	}
+	for _, tt := range tests {
+		if got := VendorVariant(tt.encoding, []byte(tt.data)); got != tt.expected {
+			t.Errorf("VendorVariant(%s, %q) = %s, expected %s", tt.encoding, tt.data, got, tt.expected)
+		}
+	}
+}
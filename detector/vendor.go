@@ -0,0 +1,50 @@
+package detector
+
+// VendorVariant 按数据中的厂商扩展字符返回对应的 Windows 代码页变体
+//
+// SHIFT_JIS 包含 NEC、IBM 扩展字符或外字时为 CP932；EUC-KR 包含统一韩文码扩展音节时为 CP949；
+// BIG5 包含 HKSCS 字符时为 BIG5-HKSCS，包含微软扩展字符（F9D6-F9FE、欧元符号）时为 CP950。
+// 其他编码或数据中没有扩展字符时原样返回。
+func VendorVariant(encoding string, data []byte) string {
+	switch encoding {
+	case "SHIFT_JIS":
+		for i := 0; i < len(data); i++ {
+			b := data[i]
+			if b < 0x81 || b >= 0xA0 && b < 0xE0 || b > 0xFC || i+1 == len(data) {
+				continue
+			}
+			// 0x87 为 NEC 特殊字符，0xED-0xEE 为 NEC 选定 IBM 扩展，0xF0-0xF9 为外字，0xFA-0xFC 为 IBM 扩展
+			if b == 0x87 || b >= 0xED {
+				return "CP932"
+			}
+			i++
+		}
+	case "EUC-KR":
+		for i := 0; i+1 < len(data); i++ {
+			if data[i] < 0x81 || data[i] == 0xFF {
+				continue
+			}
+			if data[i] < 0xA1 || data[i+1] < 0xA1 {
+				return "CP949"
+			}
+			i++
+		}
+	case "BIG5":
+		variant := encoding
+		for i := 0; i+1 < len(data); i++ {
+			lead, trail := data[i], data[i+1]
+			if lead < 0x81 || lead == 0xFF {
+				continue
+			}
+			switch {
+			case lead < 0xA1 || lead >= 0xFA || lead == 0xC6 && trail >= 0xA1 || lead == 0xC7 || lead == 0xC8:
+				return "BIG5-HKSCS"
+			case lead == 0xF9 && trail >= 0xD6 || lead == 0xA3 && trail == 0xE1:
+				variant = "CP950"
+			}
+			i++
+		}
+		return variant
+	}
+	return encoding
+}
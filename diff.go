@@ -0,0 +1,135 @@
+package encoding
+
+// diffSyncLength 重新对齐时要求连续相同的字符数
+const diffSyncLength = 4
+
+// diffSearchWindow 重新对齐时向前搜索的最大字符数
+const diffSearchWindow = 64
+
+// TextDiff 转换前后文本的字符级差异摘要
+type TextDiff struct {
+	// SourceEncoding 原始数据的编码
+	SourceEncoding string `json:"source_encoding"`
+
+	// OriginalRunes 原始文本的字符数
+	OriginalRunes int `json:"original_runes"`
+
+	// ConvertedRunes 转换后文本的字符数
+	ConvertedRunes int `json:"converted_runes"`
+
+	// ChangedRunes 被替换或丢失的原始字符数
+	ChangedRunes int `json:"changed_runes"`
+
+	// Changes 各处差异（按位置递增排列）
+	Changes []RuneChange `json:"changes,omitempty"`
+}
+
+// RuneChange 一处字符差异
+type RuneChange struct {
+	// Offset 差异在原始文本中的字符偏移
+	Offset int `json:"offset"`
+
+	// Line 差异所在行号（从 1 开始）
+	Line int `json:"line"`
+
+	// Column 差异所在列号（从 1 开始，按字符计）
+	Column int `json:"column"`
+
+	// Original 原始文本片段
+	Original string `json:"original"`
+
+	// Converted 转换后的文本片段（为空表示被删除）
+	Converted string `json:"converted"`
+}
+
+// Identical 检查转换前后文本是否完全一致
+func (d *TextDiff) Identical() bool {
+	return d != nil && len(d.Changes) == 0 && d.OriginalRunes == d.ConvertedRunes
+}
+
+// DiffConverted 解码原始数据并与转换结果（UTF-8 文本）逐字符比较，生成差异摘要
+//
+// 非 UTF-8 目标编码的转换结果需要先解码为 UTF-8。转换通常逐字符对应，
+// 出现差异后在有限窗口内寻找重新对齐的位置，找不到时将剩余部分视为一处差异。
+// 源编码不受支持时返回 nil。
+func DiffConverted(original []byte, converted []byte, sourceEncoding string) *TextDiff {
+	text, err := NewConverter().Convert(original, sourceEncoding, EncodingUTF8)
+	if err != nil {
+		return nil
+	}
+
+	a, b := []rune(string(text)), []rune(string(converted))
+	diff := &TextDiff{
+		SourceEncoding: sourceEncoding,
+		OriginalRunes:  len(a),
+		ConvertedRunes: len(b),
+	}
+
+	line, column := 1, 1
+	advance := func(runes []rune) {
+		for _, r := range runes {
+			if r == '\n' {
+				line++
+				column = 1
+			} else {
+				column++
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if i < len(a) && j < len(b) && a[i] == b[j] {
+			advance(a[i : i+1])
+			i++
+			j++
+			continue
+		}
+
+		ni, nj := resyncRunes(a, b, i, j)
+		diff.Changes = append(diff.Changes, RuneChange{
+			Offset:    i,
+			Line:      line,
+			Column:    column,
+			Original:  string(a[i:ni]),
+			Converted: string(b[j:nj]),
+		})
+		diff.ChangedRunes += ni - i
+		advance(a[i:ni])
+		i, j = ni, nj
+	}
+
+	return diff
+}
+
+// resyncRunes 从差异位置开始寻找两段文本重新对齐的最近位置（按两侧跳过的字符总数）
+func resyncRunes(a, b []rune, i, j int) (int, int) {
+	for total := 1; total <= 2*diffSearchWindow; total++ {
+		for skipA := 0; skipA <= total; skipA++ {
+			skipB := total - skipA
+			if skipA > diffSearchWindow || skipB > diffSearchWindow {
+				continue
+			}
+			if runesAligned(a, b, i+skipA, j+skipB) {
+				return i + skipA, j + skipB
+			}
+		}
+	}
+	return len(a), len(b)
+}
+
+// runesAligned 检查两段文本从指定位置起是否连续相同（到达末尾时要求两侧同时结束）
+func runesAligned(a, b []rune, i, j int) bool {
+	if i > len(a) || j > len(b) {
+		return false
+	}
+	for k := 0; k < diffSyncLength; k++ {
+		if i+k == len(a) || j+k == len(b) {
+			return i+k == len(a) && j+k == len(b)
+		}
+		if a[i+k] != b[j+k] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,175 @@
+package encoding
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DirOptions 目录级默认选项
+//
+// 从目录中的 .encproc.json 读取，作用于该目录及其所有子目录；
+// 子目录中的选项文件逐项覆盖上级目录的设置，未设置的项沿用上级目录。
+type DirOptions struct {
+	// TargetEncoding 目标编码（覆盖整体设置）
+	TargetEncoding string `json:"target_encoding,omitempty"`
+
+	// BOMPolicy BOM 处理策略（BOMPreserve、BOMStrip、BOMAdd）
+	BOMPolicy string `json:"bom_policy,omitempty"`
+
+	// Include 仅处理匹配的文件（filepath.Match 语法，不区分大小写；
+	// 不含路径分隔符时匹配文件名，否则匹配相对于选项文件所在目录的路径）
+	Include []string `json:"include,omitempty"`
+
+	// Exclude 不处理匹配的文件（语法同 Include，优先于 Include）
+	Exclude []string `json:"exclude,omitempty"`
+
+	// base Include/Exclude 相对路径的基准目录
+	base string
+}
+
+// LoadDirOptions 读取目录中的 .encproc.json 选项文件（文件不存在时返回 nil）
+func LoadDirOptions(dir string) (*DirOptions, error) {
+	path := filepath.Join(dir, DirOptionsFile)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &FileOperationError{Op: "read_dir_options", File: path, Err: err}
+	}
+
+	var options DirOptions
+	if err := json.Unmarshal(data, &options); err != nil {
+		return nil, &FileOperationError{Op: "parse_dir_options", File: path, Err: err}
+	}
+	options.base = dir
+	return &options, nil
+}
+
+// Allows 检查文件是否通过 Include/Exclude 过滤
+func (o *DirOptions) Allows(path string) bool {
+	if o == nil {
+		return true
+	}
+
+	rel := path
+	if o.base != "" {
+		if r, err := filepath.Rel(o.base, path); err == nil {
+			rel = r
+		}
+	}
+
+	for _, pattern := range o.Exclude {
+		if matchPathPattern(pattern, rel) {
+			return false
+		}
+	}
+	if len(o.Include) == 0 {
+		return true
+	}
+	for _, pattern := range o.Include {
+		if matchPathPattern(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply 返回应用目录选项后的文件处理选项副本（options 为 nil 时使用默认选项）
+func (o *DirOptions) Apply(options *FileProcessOptions) *FileProcessOptions {
+	if options == nil {
+		options = defaultFileProcessOptions()
+	}
+	if o == nil {
+		return options
+	}
+
+	applied := *options
+	if o.TargetEncoding != "" {
+		applied.TargetEncoding = o.TargetEncoding
+	}
+	if o.BOMPolicy != "" {
+		applied.BOMPolicy = o.BOMPolicy
+	}
+	return &applied
+}
+
+// merge 用子目录选项覆盖当前选项，返回合并后的新选项
+func (o *DirOptions) merge(child *DirOptions) *DirOptions {
+	if o == nil {
+		return child
+	}
+	if child == nil {
+		return o
+	}
+
+	merged := *o
+	if child.TargetEncoding != "" {
+		merged.TargetEncoding = child.TargetEncoding
+	}
+	if child.BOMPolicy != "" {
+		merged.BOMPolicy = child.BOMPolicy
+	}
+	// 过滤规则整体替换，避免上下级模式的相对路径基准混淆
+	if child.Include != nil || child.Exclude != nil {
+		merged.Include = child.Include
+		merged.Exclude = child.Exclude
+		merged.base = child.base
+	}
+	return &merged
+}
+
+// dirOptionsResolver 在根目录范围内解析并缓存各目录生效的选项
+type dirOptionsResolver struct {
+	root  string
+	mutex sync.Mutex
+	cache map[string]*DirOptions
+}
+
+// newDirOptionsResolver 创建以 root 为边界的目录选项解析器
+func newDirOptionsResolver(root string) *dirOptionsResolver {
+	return &dirOptionsResolver{
+		root:  filepath.Clean(longPath(root)),
+		cache: make(map[string]*DirOptions),
+	}
+}
+
+// resolve 返回文件所在目录生效的选项（根目录之外的文件返回 nil）
+func (r *dirOptionsResolver) resolve(path string) (*DirOptions, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.resolveDir(filepath.Dir(filepath.Clean(longPath(path))))
+}
+
+// resolveDir 递归合并从根目录到 dir 的各级选项文件
+func (r *dirOptionsResolver) resolveDir(dir string) (*DirOptions, error) {
+	if options, ok := r.cache[dir]; ok {
+		return options, nil
+	}
+
+	rel, err := filepath.Rel(r.root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, nil
+	}
+
+	var parent *DirOptions
+	if dir != r.root {
+		parent, err = r.resolveDir(filepath.Dir(dir))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	own, err := LoadDirOptions(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	options := parent.merge(own)
+	r.cache[dir] = options
+	return options, nil
+}
@@ -0,0 +1,183 @@
+package encoding
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// 试运行报告中的文件状态
+const (
+	DryRunStatusConvert   = "convert"   // 实际处理时会改变文件内容
+	DryRunStatusUnchanged = "unchanged" // 文件内容不会改变
+	DryRunStatusSkipped   = "skipped"   // 被过滤条件跳过或疑似二进制
+	DryRunStatusFailed    = "failed"    // 检测或转换失败，实际处理时同样会失败
+	DryRunStatusCancelled = "cancelled" // 因上下文取消而未检查
+)
+
+// dryRunCSVHeader 试运行报告 CSV 格式的表头
+var dryRunCSVHeader = []string{
+	"path", "status", "source_encoding", "target_encoding", "confidence", "would_convert",
+	"passthrough", "size", "estimated_size", "size_delta", "error",
+}
+
+// DryRunEntry 试运行报告中单个文件的条目
+type DryRunEntry struct {
+	// Path 文件路径
+	Path string `json:"path"`
+
+	// Status 文件状态（DryRunStatusConvert 等）
+	Status string `json:"status"`
+
+	// SourceEncoding 检测到的源编码
+	SourceEncoding string `json:"source_encoding,omitempty"`
+
+	// TargetEncoding 目标编码
+	TargetEncoding string `json:"target_encoding,omitempty"`
+
+	// Confidence 检测置信度
+	Confidence float64 `json:"confidence"`
+
+	// WouldConvert 实际处理时是否会改变文件内容
+	WouldConvert bool `json:"would_convert"`
+
+	// Passthrough 是否会按 ISO-8859-1 透传（需要复查）
+	Passthrough bool `json:"passthrough,omitempty"`
+
+	// Size 文件大小
+	Size int64 `json:"size"`
+
+	// EstimatedSize 预计的输出大小
+	EstimatedSize int64 `json:"estimated_size"`
+
+	// SizeDelta 预计的大小变化（EstimatedSize - Size）
+	SizeDelta int64 `json:"size_delta"`
+
+	// Error 失败或跳过的原因
+	Error string `json:"error,omitempty"`
+}
+
+// DryRunReport 批量试运行报告，供就地转换前复查
+type DryRunReport struct {
+	// Entries 各文件条目（按路径排序）
+	Entries []DryRunEntry `json:"entries"`
+
+	// ConvertCount 会被改变的文件数
+	ConvertCount int `json:"convert_count"`
+
+	// UnchangedCount 内容不会改变的文件数
+	UnchangedCount int `json:"unchanged_count"`
+
+	// SkippedCount 跳过的文件数
+	SkippedCount int `json:"skipped_count"`
+
+	// FailureCount 检测或转换失败的文件数
+	FailureCount int `json:"failure_count"`
+
+	// TotalSize 检查的文件总大小
+	TotalSize int64 `json:"total_size"`
+
+	// SizeDelta 预计的总大小变化
+	SizeDelta int64 `json:"size_delta"`
+}
+
+// NewDryRunReport 由批量试运行（FileOptions.DryRun）的结果生成报告
+//
+// 报告包含所有检查过的文件，以及被过滤、疑似二进制或因取消而未处理的文件。
+func NewDryRunReport(result *BatchResult) *DryRunReport {
+	report := &DryRunReport{Entries: []DryRunEntry{}}
+	seen := make(map[string]bool)
+	for _, fileResult := range result.Results {
+		entry := DryRunEntry{Path: fileResult.Job.Path, Size: fileResult.Job.Size, Error: fileResult.Error}
+		switch {
+		case errors.Is(fileResult.Err, ErrBinaryFile):
+			entry.Status = DryRunStatusSkipped
+		case fileResult.Err != nil:
+			entry.Status = DryRunStatusFailed
+		default:
+			r := fileResult.Result
+			entry.SourceEncoding = r.SourceEncoding
+			entry.TargetEncoding = r.TargetEncoding
+			entry.Confidence = r.DetectionConfidence
+			entry.WouldConvert = r.WouldChange
+			entry.Passthrough = r.Passthrough
+			entry.Size = r.BytesProcessed
+			entry.EstimatedSize = r.EstimatedSize
+			entry.SizeDelta = r.EstimatedSize - r.BytesProcessed
+			entry.Status = DryRunStatusUnchanged
+			if r.WouldChange {
+				entry.Status = DryRunStatusConvert
+			}
+		}
+		seen[entry.Path] = true
+		report.add(entry)
+	}
+	for _, path := range result.Skipped {
+		if !seen[path] {
+			report.add(DryRunEntry{Path: path, Status: DryRunStatusSkipped})
+		}
+	}
+	for _, path := range result.Cancelled {
+		report.add(DryRunEntry{Path: path, Status: DryRunStatusCancelled})
+	}
+
+	sort.Slice(report.Entries, func(i, j int) bool {
+		return report.Entries[i].Path < report.Entries[j].Path
+	})
+	return report
+}
+
+// add 添加条目并更新汇总
+func (r *DryRunReport) add(entry DryRunEntry) {
+	r.Entries = append(r.Entries, entry)
+	switch entry.Status {
+	case DryRunStatusConvert:
+		r.ConvertCount++
+	case DryRunStatusUnchanged:
+		r.UnchangedCount++
+	case DryRunStatusSkipped:
+		r.SkippedCount++
+	case DryRunStatusFailed:
+		r.FailureCount++
+	}
+	r.TotalSize += entry.Size
+	r.SizeDelta += entry.SizeDelta
+}
+
+// WriteJSON 以缩进格式输出报告的 JSON
+func (r *DryRunReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteCSV 以 CSV 格式输出报告条目（第一行为表头，不含汇总）
+func (r *DryRunReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(dryRunCSVHeader); err != nil {
+		return err
+	}
+	for _, entry := range r.Entries {
+		record := []string{
+			entry.Path,
+			entry.Status,
+			entry.SourceEncoding,
+			entry.TargetEncoding,
+			strconv.FormatFloat(entry.Confidence, 'f', 2, 64),
+			strconv.FormatBool(entry.WouldConvert),
+			strconv.FormatBool(entry.Passthrough),
+			strconv.FormatInt(entry.Size, 10),
+			strconv.FormatInt(entry.EstimatedSize, 10),
+			strconv.FormatInt(entry.SizeDelta, 10),
+			entry.Error,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -1,8 +1,27 @@
 package encoding
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
 )
 
 func TestBasicDetection(t *testing.T) {
@@ -205,6 +224,226 @@ func TestDefaultWithMetrics(t *testing.T) {
 	}
 }
 
+func TestProcessorRecordsMetrics(t *testing.T) {
+	processor, metrics := NewDefaultWithMetrics()
+	defer processor.(Drainer).Close()
+
+	text := []byte("这是一段用于统计的中文文本，包含足够的字符。")
+	result, err := processor.SmartConvert(text, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := processor.Convert([]byte("中"), EncodingUTF8, EncodingISO88591); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := processor.Convert(text, "NO-SUCH-ENCODING", EncodingUTF8); err == nil {
+		t.Fatal("Expected unsupported encoding error")
+	}
+
+	// 1 次检测、2 次成功的转换和 1 次失败的转换
+	stats := metrics.GetStats()
+	if stats.TotalOperations != 4 || stats.SuccessOperations != 3 || stats.FailedOperations != 1 {
+		t.Errorf("Unexpected operation counts %d/%d/%d", stats.TotalOperations, stats.SuccessOperations, stats.FailedOperations)
+	}
+	if want := int64(len(text) + len("中")); stats.TotalBytes != want {
+		t.Errorf("Expected %d bytes, got %d", want, stats.TotalBytes)
+	}
+	if stats.EncodingDistribution[result.SourceEncoding] != 1 {
+		t.Errorf("Expected detected encoding %s in distribution %v", result.SourceEncoding, stats.EncodingDistribution)
+	}
+	if stats.LostRunes["中"] != 1 {
+		t.Errorf("Expected lost rune to be recorded, got %v", stats.LostRunes)
+	}
+
+	// 未启用性能监控时不记录
+	config := GetDefaultProcessorConfig()
+	config.EnableMetrics = false
+	config.Metrics = NewMetricsCollector()
+	if _, err := NewProcessor(config).Convert(text, EncodingUTF8, EncodingGBK); err != nil {
+		t.Fatal(err)
+	}
+	if stats := config.Metrics.GetStats(); stats.TotalOperations != 0 {
+		t.Errorf("Expected no operations recorded, got %d", stats.TotalOperations)
+	}
+}
+
+// recordingTracer 记录所有跨度的追踪器
+type recordingTracer struct {
+	mutex sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	name       string
+	parent     *recordedSpan
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+type spanKey struct{}
+
+func (t *recordingTracer) Tracer(name string) Tracer {
+	return t
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	parent, _ := ctx.Value(spanKey{}).(*recordedSpan)
+	span := &recordedSpan{name: name, parent: parent, attributes: make(map[string]interface{})}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (s *recordedSpan) SetAttributes(attributes ...SpanAttribute) {
+	for _, attribute := range attributes {
+		s.attributes[attribute.Key] = attribute.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error) { s.err = err }
+func (s *recordedSpan) End()                  { s.ended = true }
+
+func TestTracing(t *testing.T) {
+	tracer := &recordingTracer{}
+	config := GetDefaultProcessorConfig()
+	config.TracerProvider = tracer
+	processor := NewProcessor(config)
+
+	ctx, parent := tracer.Start(context.Background(), "request")
+	gbk, err := processor.(ContextConverter).ConvertContext(ctx, []byte("中文"), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := processor.Convert(gbk, "NO-SUCH-ENCODING", EncodingUTF8); err == nil {
+		t.Fatal("Expected unsupported encoding error")
+	}
+	if _, err := processor.DetectEncoding([]byte("plain ascii text")); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	if err := os.WriteFile(input, []byte("文件内容，用于追踪文件处理。"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileProcessor(config).ProcessFile(input, filepath.Join(dir, "output.txt"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := tracer.spans[1:]
+	if len(spans) != 4 {
+		t.Fatalf("Expected 4 spans, got %d", len(spans))
+	}
+	for i, name := range []string{SpanConvert, SpanConvert, SpanDetectEncoding, SpanProcessFile} {
+		if spans[i].name != name || !spans[i].ended {
+			t.Errorf("Span %d: expected ended %s, got %s (ended %v)", i, name, spans[i].name, spans[i].ended)
+		}
+	}
+	if spans[0].parent != parent || spans[0].attributes[AttributeTargetEncoding] != EncodingGBK || spans[0].attributes[AttributeBytes] != int64(len("中文")) {
+		t.Errorf("Unexpected convert span %+v", spans[0])
+	}
+	if spans[1].err == nil {
+		t.Error("Expected error to be recorded on span")
+	}
+	if spans[2].attributes[AttributeSourceEncoding] == nil || spans[2].attributes[AttributeConfidence] == nil {
+		t.Errorf("Expected detection result on span, got %v", spans[2].attributes)
+	}
+	if spans[3].attributes[AttributeTargetEncoding] != EncodingUTF8 {
+		t.Errorf("Unexpected file span attributes %v", spans[3].attributes)
+	}
+}
+
+func TestSlogLogging(t *testing.T) {
+	var buf bytes.Buffer
+	config := GetDefaultProcessorConfig()
+	config.Logger = NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	config.LogLevel = LogLevelInfo
+
+	file := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(file, []byte("Grüße aus dem Büro, 中文"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	options := &FileProcessOptions{
+		TargetEncoding:    EncodingISO88591,
+		CreateBackup:      true,
+		BackupSuffix:      DefaultBackupSuffix,
+		OverwriteExisting: true,
+	}
+	result, err := NewFileProcessor(config).ProcessFile(file, file, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logs := buf.String()
+	for _, want := range []string{
+		"msg=\"characters replaced\"",
+		"replaced=2",
+		"msg=\"backup created\"",
+		"backup=" + result.BackupFile,
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("Expected %q in logs:\n%s", want, logs)
+		}
+	}
+	// LogLevel 为 info 时不输出调试信息
+	if strings.Contains(logs, "encoding detected") {
+		t.Errorf("Expected debug events to be filtered:\n%s", logs)
+	}
+
+	buf.Reset()
+	config.LogLevel = LogLevelDebug
+	if _, err := NewProcessor(config).SmartConvert([]byte("Grüße"), EncodingUTF16LE); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "msg=\"encoding detected\" encoding=UTF-8") {
+		t.Errorf("Expected detection event, got:\n%s", buf.String())
+	}
+}
+
+func TestExplainDetection(t *testing.T) {
+	processor := NewDefault()
+
+	explanation, err := processor.(ExplainingDetector).ExplainDetection([]byte("\ufeffhello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if explanation.Decision != ExplainCheckBOM || explanation.Result == nil || explanation.CacheHit {
+		t.Errorf("Unexpected BOM explanation %+v", explanation)
+	}
+
+	gbk, err := processor.Convert([]byte(strings.Repeat("这是一段用于说明检测过程的简体中文文本。", 10)), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	explanation, err = processor.(ExplainingDetector).ExplainDetection(gbk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 说明的结果与 DetectEncoding 一致（包括失败）
+	detected, err := processor.DetectEncoding(gbk)
+	if err != nil {
+		if explanation.Result != nil || explanation.Error != err.Error() {
+			t.Errorf("Expected explained error %q, got %+v", err, explanation)
+		}
+	} else if explanation.Result == nil || explanation.Result.Encoding != detected.Encoding {
+		t.Errorf("Expected explained result %s, got %+v", detected.Encoding, explanation.Result)
+	}
+	if len(explanation.Checks) != 7 || explanation.Checks[4].Name != ExplainCheckUTF8 || explanation.Checks[4].Matched {
+		t.Errorf("Unexpected checks %+v", explanation.Checks)
+	}
+	if len(explanation.BackendMatches) == 0 || len(explanation.Candidates) == 0 || explanation.Reason == "" {
+		t.Errorf("Expected backend matches, candidates and reason, got %+v", explanation)
+	}
+
+	// 再次检测同一样本时命中缓存（失败的检测不缓存）
+	if explanation, err = processor.(ExplainingDetector).ExplainDetection([]byte("\ufeffhello")); err != nil || !explanation.CacheHit {
+		t.Errorf("Expected cache hit, got %+v (%v)", explanation, err)
+	}
+}
+
 func TestErrorHandling(t *testing.T) {
 	processor := NewDefault()
 
@@ -265,4 +504,3601 @@ func TestMetricsCollector(t *testing.T) {
 	if stats.TotalOperations != 0 {
 		t.Errorf("Expected 0 total operations after reset, got %d", stats.TotalOperations)
 	}
-}
\ No newline at end of file
+}
+func TestConverterAllowedEncodings(t *testing.T) {
+	config := GetDefaultConverterConfig()
+	config.AllowedEncodings = []string{EncodingUTF8, EncodingGBK}
+	config.DeniedEncodings = []string{EncodingGBK}
+	converter := NewConverter(config)
+
+	if _, err := converter.Convert([]byte("test"), EncodingUTF8, EncodingBIG5); !errors.Is(err, ErrEncodingNotAllowed) {
+		t.Errorf("Expected ErrEncodingNotAllowed for BIG5, got %v", err)
+	}
+
+	if _, err := converter.Convert([]byte("test"), EncodingUTF8, EncodingGBK); !errors.Is(err, ErrEncodingNotAllowed) {
+		t.Errorf("Expected ErrEncodingNotAllowed for denied GBK, got %v", err)
+	}
+
+	if _, err := converter.Convert([]byte("test"), EncodingUTF8, EncodingUTF8); err != nil {
+		t.Errorf("Unexpected error for allowed encoding: %v", err)
+	}
+
+	// 名单项和编码名称按规范名称比较，别名和大小写不同的写法同样生效
+	config = GetDefaultConverterConfig()
+	config.DeniedEncodings = []string{"gbk", "cp936", "Big5"}
+	converter = NewConverter(config)
+	for _, name := range []string{EncodingGBK, "gbk", "CP936", EncodingBIG5, "big5"} {
+		if _, err := converter.Convert([]byte("test"), EncodingUTF8, name); !errors.Is(err, ErrEncodingNotAllowed) {
+			t.Errorf("Expected ErrEncodingNotAllowed for denied %s, got %v", name, err)
+		}
+	}
+	if config.DeniedEncodings[0] != "gbk" {
+		t.Errorf("Expected caller's config to stay unchanged, got %v", config.DeniedEncodings)
+	}
+
+	config = GetDefaultConverterConfig()
+	config.AllowedEncodings = []string{"utf-8", "gbk"}
+	converter = NewConverter(config)
+	if _, err := converter.Convert([]byte("test"), EncodingUTF8, EncodingGBK); err != nil {
+		t.Errorf("Unexpected error for lower-case allow-list: %v", err)
+	}
+	if _, err := converter.Convert([]byte("test"), EncodingUTF8, EncodingBIG5); !errors.Is(err, ErrEncodingNotAllowed) {
+		t.Errorf("Expected ErrEncodingNotAllowed for BIG5, got %v", err)
+	}
+}
+
+func TestFinalNewlinePolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		input    string
+		expected string
+	}{
+		{"preserve with newline", FinalNewlinePreserve, "line\n", "line\n"},
+		{"preserve without newline", FinalNewlinePreserve, "line", "line"},
+		{"ensure", FinalNewlineEnsure, "line", "line\n"},
+		{"strip", FinalNewlineStrip, "line\r\n\n", "line"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := GetDefaultConverterConfig()
+			config.FinalNewline = tt.policy
+			converter := NewConverter(config)
+
+			result, err := converter.ConvertString(tt.input, EncodingUTF8, EncodingGBK)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	if !HasFinalNewline([]byte{'a', 0x00, '\n', 0x00}, EncodingUTF16LE) {
+		t.Error("Expected UTF-16LE data to end with newline")
+	}
+}
+
+func TestPivotStrategyBuffered(t *testing.T) {
+	config := GetDefaultConverterConfig()
+	config.PivotStrategy = PivotValidated
+
+	var captured *PivotInfo
+	config.PivotHook = func(info *PivotInfo) {
+		captured = info
+	}
+	converter := NewConverter(config)
+
+	gbk, err := NewDefault().ConvertString("中文测试", EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	result, err := converter.ConvertString(gbk, EncodingGBK, EncodingGB18030)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if captured == nil || string(captured.Intermediate) != "中文测试" {
+		t.Fatalf("Expected pivot hook to capture intermediate text, got %+v", captured)
+	}
+
+	back, err := converter.ConvertString(result, EncodingGB18030, EncodingUTF8)
+	if err != nil || back != "中文测试" {
+		t.Errorf("Expected round trip to succeed, got %q (%v)", back, err)
+	}
+}
+
+func TestBuildPositionMap(t *testing.T) {
+	gbk, err := NewDefault().Convert([]byte("ab中文c"), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	positionMap, err := BuildPositionMap(gbk, EncodingGBK, EncodingUTF8, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// "c" 在 GBK 中位于偏移 6，在 UTF-8 中位于偏移 8
+	if got := positionMap.SourceToOutput(6); got != 8 {
+		t.Errorf("Expected output offset 8, got %d", got)
+	}
+	if got := positionMap.OutputToSource(8); got != 6 {
+		t.Errorf("Expected source offset 6, got %d", got)
+	}
+
+	// 位置映射与应用了 BOM、末尾换行符和换行符策略的实际结果一致
+	source := "\ufeffab中文\r\nc 这是一段用于检测的中文内容\n\n"
+	tests := []struct {
+		name      string
+		configure func(config *ConverterConfig)
+	}{
+		{"strip final newlines", func(config *ConverterConfig) { config.FinalNewline = FinalNewlineStrip }},
+		{"ensure final newline", func(config *ConverterConfig) { config.FinalNewline = FinalNewlineEnsure }},
+		{"add BOM", func(config *ConverterConfig) { config.BOMPolicy = BOMAdd }},
+		{"preserve BOM", func(config *ConverterConfig) { config.BOMPolicy = BOMPreserve }},
+		{"normalize to LF", func(config *ConverterConfig) {
+			config.NormalizeLineEndings = true
+			config.TargetLineEnding = LineEndingLF
+			config.FinalNewline = FinalNewlineStrip
+		}},
+		{"normalize to CRLF", func(config *ConverterConfig) {
+			config.NormalizeLineEndings = true
+			config.TargetLineEnding = LineEndingCRLF
+			config.BOMPolicy = BOMAdd
+		}},
+	}
+	for _, tt := range tests {
+		for _, target := range []string{EncodingUTF8, EncodingGBK} {
+			config := GetDefaultProcessorConfig()
+			config.ConverterConfig.PositionMapInterval = 1
+			tt.configure(config.ConverterConfig)
+			result, err := NewProcessor(config).SmartConvert([]byte(source), target)
+			if err != nil {
+				t.Fatalf("%s to %s: %v", tt.name, target, err)
+			}
+			for _, entry := range result.PositionMap.Entries {
+				if entry.OutputOffset > int64(len(result.Data)) {
+					t.Errorf("%s to %s: output offset %d beyond %d-byte result", tt.name, target, entry.OutputOffset, len(result.Data))
+				}
+			}
+			offset := result.PositionMap.SourceToOutput(int64(strings.Index(source, "c")))
+			if offset >= int64(len(result.Data)) || result.Data[offset] != 'c' {
+				t.Errorf("%s to %s: expected \"c\" at output offset %d, got %q", tt.name, target, offset, result.Data)
+			}
+		}
+	}
+}
+
+func TestPlanAndExecuteMigration(t *testing.T) {
+	dir := t.TempDir()
+
+	gbk, err := NewDefault().Convert([]byte(strings.Repeat("这是一个需要迁移到统一编码的中文文件内容。", 20)), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "gbk.txt"), gbk, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "utf8.txt"), []byte("已经是 UTF-8 的文件"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := PlanMigration(dir, EncodingUTF8)
+	if err != nil {
+		t.Fatalf("PlanMigration failed: %v", err)
+	}
+
+	if plan.SkipCount != 1 {
+		t.Errorf("Expected 1 skipped file, got %d", plan.SkipCount)
+	}
+	if plan.ConvertCount+plan.FlagCount != 1 {
+		t.Errorf("Expected GBK file to be converted or flagged, got plan %+v", plan)
+	}
+
+	report, err := ExecutePlan(plan)
+	if err != nil {
+		t.Fatalf("ExecutePlan failed: %v", err)
+	}
+	if len(report.Results) != plan.ConvertCount {
+		t.Errorf("Expected %d converted files, got %d (failed: %v)", plan.ConvertCount, len(report.Results), report.Failed)
+	}
+	for _, result := range report.Results {
+		if result.BackupFile == "" {
+			t.Errorf("Expected ExecutePlan to back up %s by default", result.InputFile)
+		}
+	}
+
+	// 调用方关闭备份时不应创建备份文件
+	noBackup := t.TempDir()
+	if err := os.WriteFile(filepath.Join(noBackup, "utf8.txt"), []byte("迁移时不需要备份的 UTF-8 文件"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	plan, err = PlanMigration(noBackup, EncodingGBK)
+	if err != nil {
+		t.Fatalf("PlanMigration failed: %v", err)
+	}
+	if plan.ConvertCount != 1 {
+		t.Fatalf("Expected 1 file to convert, got plan %+v", plan)
+	}
+	report, err = ExecutePlanWithOptions(plan, &FileProcessOptions{CreateBackup: false})
+	if err != nil {
+		t.Fatalf("ExecutePlanWithOptions failed: %v", err)
+	}
+	if len(report.Results) != 1 {
+		t.Fatalf("Expected 1 converted file, got %d (failed: %v)", len(report.Results), report.Failed)
+	}
+	if result := report.Results[0]; result.BackupFile != "" {
+		t.Errorf("Expected no backup when CreateBackup is false, got %s", result.BackupFile)
+	}
+	if _, err := os.Stat(filepath.Join(noBackup, "utf8.txt"+DefaultBackupSuffix)); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup file, got %v", err)
+	}
+
+	// 未设置检测器配置时使用默认配置，与 NewProcessor 一致
+	if _, err := NewMigrationPlanner(&ProcessorConfig{}).PlanMigration(noBackup, EncodingUTF8); err != nil {
+		t.Errorf("PlanMigration with a zero config failed: %v", err)
+	}
+}
+
+func TestSidecarWriteAndRevert(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "latin1.txt")
+	if err := os.WriteFile(file, []byte("caf\xe9 na\xefve r\xe9sum\xe9 \xe0 la carte"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := GetDefaultProcessorConfig()
+	config.DetectorConfig.MinConfidence = 0
+	fp := NewFileProcessor(config)
+	result, err := fp.ProcessFile(file, file, &FileProcessOptions{
+		TargetEncoding:    EncodingUTF8,
+		OverwriteExisting: true,
+		WriteSidecar:      true,
+	})
+	if err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+
+	meta, err := ReadSidecar(file)
+	if err != nil {
+		t.Fatalf("ReadSidecar failed: %v", err)
+	}
+	if meta.OriginalEncoding != result.SourceEncoding || meta.ToolVersion != Version {
+		t.Errorf("Unexpected sidecar metadata: %+v", meta)
+	}
+
+	if err := os.Chmod(file, 0600); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RevertFromSidecar(file); err != nil {
+		t.Fatalf("RevertFromSidecar failed: %v", err)
+	}
+	reverted, _ := os.ReadFile(file)
+	if string(reverted) != "caf\xe9 na\xefve r\xe9sum\xe9 \xe0 la carte" {
+		t.Errorf("Expected original content after revert, got %q", reverted)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("Expected revert to keep file mode 0600, got %v", info.Mode().Perm())
+	}
+	// 原子替换：文件被临时文件整体替换而不是原地截断改写
+	if os.SameFile(before, info) {
+		t.Error("Expected revert to replace the file atomically instead of rewriting it in place")
+	}
+	if leftovers, _ := filepath.Glob(file + ".*.tmp"); len(leftovers) != 0 {
+		t.Errorf("Expected no temporary file after revert, got %v", leftovers)
+	}
+}
+
+type staticBackend struct {
+	matches []CharsetMatch
+}
+
+func (b *staticBackend) DetectAll(data []byte) ([]CharsetMatch, error) {
+	return b.matches, nil
+}
+
+func TestCustomCharsetBackend(t *testing.T) {
+	config := GetDefaultDetectorConfig()
+	config.EnableCache = false
+	config.Backend = &staticBackend{matches: []CharsetMatch{{Charset: "windows-1252", Confidence: 95}}}
+	detector := NewDetector(config)
+
+	result, err := detector.DetectEncoding([]byte("caf\xe9"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Encoding != EncodingWindows1252 {
+		t.Errorf("Expected encoding from custom backend, got %s", result.Encoding)
+	}
+}
+
+func TestConvertJSON(t *testing.T) {
+	input := []byte(`{"name":"café 中文 😀","note":"a\"b"}`)
+
+	result, err := ConvertJSON(input, EncodingISO88591)
+	if err != nil {
+		t.Fatalf("ConvertJSON failed: %v", err)
+	}
+
+	expected := "{\"name\":\"caf\xe9 \\u4e2d\\u6587 \\ud83d\\ude00\",\"note\":\"a\\\"b\"}"
+	if string(result.Data) != expected {
+		t.Errorf("Expected %q, got %q", expected, result.Data)
+	}
+
+	decoded, err := NewDefault().Convert(result.Data, EncodingISO88591, EncodingUTF8)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var value map[string]string
+	if err := json.Unmarshal(decoded, &value); err != nil || value["name"] != "café 中文 😀" {
+		t.Errorf("Expected output to remain valid JSON with same content, got %v (%v)", value, err)
+	}
+}
+
+func TestConvertProperties(t *testing.T) {
+	input := []byte("# \\u4e2d comment\ngreeting=\\u4f60\\u597d\npath=C:\\\\u0041\n")
+
+	expanded, err := ConvertProperties(input, &PropertiesOptions{
+		SourceEncoding: EncodingISO88591,
+		TargetEncoding: EncodingUTF8,
+		EscapeMode:     EscapeModeExpand,
+	})
+	if err != nil {
+		t.Fatalf("ConvertProperties failed: %v", err)
+	}
+	expected := "# \\u4e2d comment\ngreeting=你好\npath=C:\\\\u0041\n"
+	if string(expanded.Data) != expected {
+		t.Errorf("Expected %q, got %q", expected, expanded.Data)
+	}
+
+	generated, err := ConvertProperties(expanded.Data, &PropertiesOptions{
+		SourceEncoding: EncodingUTF8,
+		TargetEncoding: EncodingISO88591,
+		EscapeMode:     EscapeModeGenerate,
+	})
+	if err != nil {
+		t.Fatalf("ConvertProperties failed: %v", err)
+	}
+	if !strings.Contains(string(generated.Data), "greeting=\\u4F60\\u597D") {
+		t.Errorf("Expected generated escapes, got %q", generated.Data)
+	}
+}
+
+func TestLabeledMetrics(t *testing.T) {
+	metrics := NewMetricsCollector().(LabeledMetricsCollector)
+
+	ctx := WithMetricsLabels(context.Background(), MetricsLabels{"tenant": "acme"})
+	ctx = WithMetricsLabels(ctx, MetricsLabels{"pipeline": "import"})
+	labels := MetricsLabelsFromContext(ctx)
+
+	metrics.RecordOperationWithLabels(OperationConvert, 10, labels)
+	metrics.RecordBytesWithLabels(100, labels)
+	metrics.RecordErrorWithLabels(OperationConvert, ErrConversionFailed, MetricsLabels{"tenant": "acme"})
+	metrics.RecordOperationWithLabels(OperationConvert, 10, MetricsLabels{"tenant": "other"})
+
+	acme := metrics.GetStatsByLabel("tenant", "acme")
+	if acme.TotalOperations != 2 || acme.FailedOperations != 1 || acme.TotalBytes != 100 {
+		t.Errorf("Unexpected stats for tenant acme: %+v", acme)
+	}
+
+	if stats := metrics.GetStats(); stats.TotalOperations != 3 {
+		t.Errorf("Expected 3 global operations, got %d", stats.TotalOperations)
+	}
+
+	if _, ok := metrics.GetLabeledStats()["pipeline=import,tenant=acme"]; !ok {
+		t.Error("Expected stats for canonical label set")
+	}
+}
+
+// TestLabeledMetricsPropagation 测试带上下文的转换、智能转换和流处理按上下文中的标签记录指标
+func TestLabeledMetricsPropagation(t *testing.T) {
+	metrics := NewMetricsCollector().(LabeledMetricsCollector)
+	config := GetDefaultProcessorConfig()
+	config.DetectorConfig.EnableCache = false
+	config.Metrics = metrics
+	processor := NewProcessor(config)
+	streamProcessor := NewStreamProcessor(config)
+
+	data := []byte("按租户统计的指标")
+	ctx := WithMetricsLabels(context.Background(), MetricsLabels{"tenant": "acme"})
+	if _, err := processor.(ContextConverter).ConvertContext(ctx, data, EncodingUTF8, EncodingGBK); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := processor.(ContextSmartConverter).SmartConvertContext(ctx, data, EncodingGBK); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if _, err := streamProcessor.ProcessReaderWriter(ctx, bytes.NewReader(data), &out, &StreamOptions{
+		TargetEncoding:      EncodingGBK,
+		BufferSize:          DefaultBufferSize,
+		DetectionSampleSize: DefaultSampleSize,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// 不带标签的调用只计入全局统计
+	if _, err := processor.Convert(data, EncodingUTF8, EncodingGBK); err != nil {
+		t.Fatal(err)
+	}
+
+	// ConvertContext 1 次转换，SmartConvertContext 检测和转换各 1 次，流处理检测和处理各 1 次
+	acme := metrics.GetStatsByLabel("tenant", "acme")
+	if acme.TotalOperations != 5 || acme.TotalBytes != 3*int64(len(data)) {
+		t.Errorf("Unexpected stats for tenant acme: %+v", acme)
+	}
+	if stats := metrics.GetStats(); stats.TotalOperations != 6 {
+		t.Errorf("Expected 6 global operations, got %d", stats.TotalOperations)
+	}
+}
+
+func TestProcessorDrain(t *testing.T) {
+	processor := NewDefault()
+	drainer, ok := processor.(Drainer)
+	if !ok {
+		t.Fatal("Expected default processor to implement Drainer")
+	}
+
+	// 模拟一个进行中的操作
+	p := processor.(*defaultProcessor)
+	if err := p.lifecycle.acquire(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := drainer.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected drain to time out while operation in flight, got %v", err)
+	}
+
+	if _, err := processor.Convert([]byte("test"), EncodingUTF8, EncodingGBK); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after drain started, got %v", err)
+	}
+
+	p.lifecycle.release()
+	if err := drainer.Close(); err != nil {
+		t.Errorf("Expected close to succeed after operations finished, got %v", err)
+	}
+}
+
+// flushingMetrics 记录 Flush 调用次数的性能监控器
+type flushingMetrics struct {
+	MetricsCollector
+	flushes int
+}
+
+func (m *flushingMetrics) Flush() error {
+	m.flushes++
+	return nil
+}
+
+// TestProcessorCloseFlushesMetrics 测试通过 Processor 接口关闭处理器时导出指标
+func TestProcessorCloseFlushesMetrics(t *testing.T) {
+	metrics := &flushingMetrics{MetricsCollector: NewMetricsCollector()}
+	config := GetDefaultProcessorConfig()
+	config.DetectorConfig.Metrics = metrics
+
+	var sp StreamProcessor = NewStreamProcessor(config)
+	var processor Processor = sp.(*defaultStreamProcessor).processor
+	if _, err := processor.DetectEncoding([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sp.(Drainer).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := processor.(Drainer).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if metrics.flushes != 1 {
+		t.Errorf("Expected metrics to be flushed once, got %d", metrics.flushes)
+	}
+	if _, err := processor.DetectEncoding([]byte("hello")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after close, got %v", err)
+	}
+}
+
+func TestMemoryUsageReporting(t *testing.T) {
+	processor := NewMemoryEfficient()
+	data := []byte(strings.Repeat("内存占用测试，", 50))
+
+	result, err := processor.SmartConvert(data, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	memory := result.Memory
+	if memory.InputBytes != int64(len(data)) || memory.OutputBytes < int64(len(result.Data)) {
+		t.Errorf("Unexpected memory usage: %+v", memory)
+	}
+	if memory.PeakBytes != memory.InputBytes+memory.OutputBytes+memory.IntermediateBytes {
+		t.Errorf("Peak should sum input, output and intermediate buffers: %+v", memory)
+	}
+
+	// 流式处理的峰值应受缓冲区大小限制，而不是随输入大小增长
+	input := strings.Repeat("stream memory bound\n", 50000)
+	var output strings.Builder
+	streamResult, err := NewStreamProcessor(nil).ProcessReaderWriter(context.Background(), strings.NewReader(input), &output, &StreamOptions{
+		SourceEncoding: EncodingUTF8,
+		TargetEncoding: EncodingGBK,
+		BufferSize:     4096,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if streamResult.Memory.PeakBytes <= 0 || streamResult.Memory.PeakBytes >= int64(len(input)) {
+		t.Errorf("Expected stream peak memory to be bounded by buffers, got %+v", streamResult.Memory)
+	}
+
+	metrics := NewMetricsCollector().(LabeledMetricsCollector)
+	metrics.RecordMemoryUsageWithLabels(memory, MetricsLabels{"tenant": "acme"})
+	metrics.RecordMemoryUsageWithLabels(streamResult.Memory, nil)
+	stats := metrics.GetStats()
+	if stats.MemoryOperations != 2 || stats.TotalMemoryUsage != memory.PeakBytes+streamResult.Memory.PeakBytes {
+		t.Errorf("Unexpected aggregate memory stats: %+v", stats)
+	}
+	if acme := metrics.GetStatsByLabel("tenant", "acme"); acme.PeakMemoryUsage != memory.PeakBytes {
+		t.Errorf("Expected labeled peak %d, got %d", memory.PeakBytes, acme.PeakMemoryUsage)
+	}
+
+	// 处理器在每次转换和流处理后自动记录内存占用
+	config := GetDefaultProcessorConfig()
+	config.Metrics = NewMetricsCollector()
+	result, err = NewProcessor(config).SmartConvert(data, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithMetricsLabels(context.Background(), MetricsLabels{"tenant": "acme"})
+	output.Reset()
+	streamResult, err = NewStreamProcessor(config).ProcessReaderWriter(ctx, strings.NewReader(input), &output, &StreamOptions{
+		SourceEncoding: EncodingUTF8,
+		TargetEncoding: EncodingGBK,
+		BufferSize:     4096,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats = config.Metrics.GetStats()
+	if stats.MemoryOperations != 2 || stats.TotalMemoryUsage != result.Memory.PeakBytes+streamResult.Memory.PeakBytes {
+		t.Errorf("Expected recorded memory usage, got %+v", stats)
+	}
+	if acme := config.Metrics.(LabeledMetricsCollector).GetStatsByLabel("tenant", "acme"); acme.PeakMemoryUsage != streamResult.Memory.PeakBytes {
+		t.Errorf("Expected labeled stream peak %d, got %d", streamResult.Memory.PeakBytes, acme.PeakMemoryUsage)
+	}
+}
+
+func TestBatchProcessingOrder(t *testing.T) {
+	dir := t.TempDir()
+	sizes := map[string]int{"medium.txt": 200, "small.txt": 10, "huge.txt": 5000, "large.txt": 1000}
+	var files []string
+	for name, size := range sizes {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(strings.Repeat("a", size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+	files = append(files, filepath.Join(dir, "missing.txt"))
+
+	var mutex sync.Mutex
+	var order []string
+	result, err := NewBatchProcessor(nil).ProcessFiles(context.Background(), files, &BatchOptions{
+		FileOptions: &FileProcessOptions{
+			TargetEncoding:    EncodingUTF8,
+			OverwriteExisting: true,
+		},
+		Order:             BatchOrderSmallestFirst,
+		HugeFileThreshold: 4096,
+		OnFileDone: func(fileResult *BatchFileResult) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if fileResult.Job.Size < 4096 && fileResult.Err == nil {
+				order = append(order, filepath.Base(fileResult.Job.Path))
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("ProcessFiles failed: %v", err)
+	}
+
+	if result.SuccessCount != 4 || result.FailureCount != 1 {
+		t.Errorf("Expected 4 successes and 1 failure, got %d and %d", result.SuccessCount, result.FailureCount)
+	}
+
+	expected := []string{"small.txt", "medium.txt", "large.txt"}
+	if strings.Join(order, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected order %v, got %v", expected, order)
+	}
+}
+
+func TestFileSizeLimits(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "large.txt")
+	text := strings.Repeat("流式处理大文件测试。\n", 200)
+	if err := os.WriteFile(input, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := GetDefaultProcessorConfig()
+	config.MaxFileSize = 100
+	fp := NewFileProcessor(config)
+
+	// 超过硬限制时拒绝处理
+	output := filepath.Join(dir, "out.txt")
+	_, err := fp.ProcessFile(input, output, &FileProcessOptions{TargetEncoding: EncodingGBK})
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("Expected ErrFileTooLarge, got %v", err)
+	}
+
+	// 单次调用覆盖硬限制，并超过软限制改用流式处理
+	result, err := fp.ProcessFile(input, output, &FileProcessOptions{
+		TargetEncoding:     EncodingGBK,
+		MaxFileSize:        -1,
+		StreamingThreshold: 1024,
+		WriteSidecar:       true,
+	})
+	if err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+	if !result.Streamed {
+		t.Error("Expected file above the soft limit to be streamed")
+	}
+
+	converted, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := NewDefault().Convert(converted, EncodingGBK, EncodingUTF8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(back) != text {
+		t.Error("Streamed conversion did not round-trip")
+	}
+
+	meta, err := ReadSidecar(output)
+	if err != nil {
+		t.Fatalf("ReadSidecar failed: %v", err)
+	}
+	if meta.ConvertedSize != int64(len(converted)) {
+		t.Errorf("Expected sidecar converted size %d, got %d", len(converted), meta.ConvertedSize)
+	}
+}
+
+func TestNewTransformer(t *testing.T) {
+	transformer, err := NewTransformer(EncodingUTF8, EncodingISO88591, WithReplacement("*"), WithBOM(BOMStrip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, _, err := transform.String(transformer, "\xef\xbb\xbfcafé — 咖啡")
+	if err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+	if output != "caf\xe9 * **" {
+		t.Errorf("Unexpected output %q", output)
+	}
+
+	strict, err := NewTransformer(EncodingUTF8, EncodingISO88591, WithStrict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := transform.String(strict, "咖啡"); err == nil {
+		t.Error("Expected strict transformer to fail on unencodable characters")
+	}
+
+	withBOM, err := NewTransformer(EncodingGBK, EncodingUTF16LE, WithBOM(BOMAdd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, _, err = transform.String(withBOM, "\xc4\xe3\xba\xc3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "\xff\xfe\x60\x4f\x7d\x59" {
+		t.Errorf("Unexpected UTF-16LE output % x", output)
+	}
+
+	if _, err := NewTransformer(EncodingUTF8, "NOPE"); err == nil {
+		t.Error("Expected error for unsupported encoding")
+	}
+}
+
+func TestTransformerBOMPolicy(t *testing.T) {
+	// 编码器自行写入 BOM 的目标编码也只输出一个 BOM，去除时不输出 BOM
+	tests := []struct {
+		to, policy, input, expected string
+	}{
+		{EncodingUTF16, BOMAdd, "hi", "\xfe\xff\x00h\x00i"},
+		{EncodingUTF16, BOMAdd, "\ufeffhi", "\xfe\xff\x00h\x00i"},
+		{EncodingUTF16, BOMStrip, "\ufeffhi", "\x00h\x00i"},
+		{EncodingUTF16, BOMPreserve, "\ufeffhi", "\xfe\xff\x00h\x00i"},
+		{EncodingUTF32, BOMAdd, "hi", "\x00\x00\xfe\xff\x00\x00\x00h\x00\x00\x00i"},
+		{EncodingUTF32, BOMStrip, "hi", "\x00\x00\x00h\x00\x00\x00i"},
+		{EncodingUTF8BOM, BOMAdd, "\ufeffhi", "\xef\xbb\xbfhi"},
+		{EncodingUTF8BOM, BOMStrip, "hi", "hi"},
+		{EncodingUTF16LE, BOMAdd, "\ufeffhi", "\xff\xfeh\x00i\x00"},
+		{EncodingUTF16LE, BOMStrip, "\ufeffhi", "h\x00i\x00"},
+	}
+	for _, tt := range tests {
+		transformer, err := NewTransformer(EncodingUTF8, tt.to, WithBOM(tt.policy))
+		if err != nil {
+			t.Fatal(err)
+		}
+		output, _, err := transform.String(transformer, tt.input)
+		if err != nil || output != tt.expected {
+			t.Errorf("%s %s %q: expected % x, got % x (%v)", tt.to, tt.policy, tt.input, tt.expected, output, err)
+		}
+
+		w, r := ConvertPipe(EncodingUTF8, tt.to, WithBOM(tt.policy))
+		go func(input string) {
+			w.Write([]byte(input))
+			w.Close()
+		}(tt.input)
+		piped, err := io.ReadAll(r)
+		if err != nil || string(piped) != tt.expected {
+			t.Errorf("ConvertPipe %s %s %q: expected % x, got % x (%v)", tt.to, tt.policy, tt.input, tt.expected, piped, err)
+		}
+	}
+}
+
+func TestUTF8BOMEncoding(t *testing.T) {
+	converter := NewConverter(nil)
+
+	withBOM, err := converter.Convert([]byte("\xc4\xe3\xba\xc3"), EncodingGBK, EncodingUTF8BOM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(withBOM) != "\xef\xbb\xbf你好" {
+		t.Errorf("Expected BOM-prefixed UTF-8, got %q", withBOM)
+	}
+
+	withoutBOM, err := converter.Convert(withBOM, EncodingUTF8BOM, EncodingUTF8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(withoutBOM) != "你好" {
+		t.Errorf("Expected BOM to be stripped, got %q", withoutBOM)
+	}
+
+	// 分块转换时只在开头写入一次 BOM
+	config := GetDefaultConverterConfig()
+	config.ChunkSize = 16
+	large := strings.Repeat("分块", 100)
+	chunked, err := NewConverter(config).Convert([]byte(large), EncodingUTF8, EncodingUTF8BOM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(chunked), "\ufeff") != 1 || !strings.HasPrefix(string(chunked), "\ufeff") {
+		t.Error("Expected exactly one leading BOM in chunked conversion")
+	}
+
+	var output strings.Builder
+	_, err = NewStreamProcessor(nil).ProcessReaderWriter(context.Background(), strings.NewReader(large), &output, &StreamOptions{
+		SourceEncoding: EncodingUTF8,
+		TargetEncoding: EncodingUTF8BOM,
+		BufferSize:     30,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "\ufeff"+large {
+		t.Error("Expected stream output to contain a single leading BOM")
+	}
+}
+
+func TestAutoTune(t *testing.T) {
+	config, err := AutoTune(calibrationWorkload())
+	if err != nil {
+		t.Fatalf("AutoTune failed: %v", err)
+	}
+
+	validBuffer := false
+	for _, size := range autoTuneBufferSizes {
+		if config.BufferSize == size {
+			validBuffer = true
+		}
+	}
+	if !validBuffer || config.ChunkSize <= 0 {
+		t.Errorf("Unexpected tuned config: buffer %d, chunk %d", config.BufferSize, config.ChunkSize)
+	}
+
+	if _, err := AutoTune(strings.NewReader("")); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for empty workload, got %v", err)
+	}
+}
+
+func TestStreamQualityStats(t *testing.T) {
+	sp := NewStreamProcessor(nil)
+
+	var latin1 strings.Builder
+	result, err := sp.ProcessReaderWriter(context.Background(), strings.NewReader("\ufeffcafé\n咖啡\nend"), &latin1, &StreamOptions{
+		SourceEncoding: EncodingUTF8,
+		TargetEncoding: EncodingISO88591,
+		BufferSize:     64,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 源 BOM 与 Convert 一样被去除，不会变成替换字符
+	if latin1.String() != "caf\xe9\n??\nend" {
+		t.Errorf("Unexpected output %q", latin1.String())
+	}
+	if result.LinesProcessed != 3 || result.CharactersReplaced != 2 || result.InvalidSequences != 0 {
+		t.Errorf("Unexpected stats: lines %d, replaced %d, invalid %d",
+			result.LinesProcessed, result.CharactersReplaced, result.InvalidSequences)
+	}
+	if !result.SourceBOMSeen || result.TargetBOMSeen {
+		t.Errorf("Unexpected BOM flags: source %v, target %v", result.SourceBOMSeen, result.TargetBOMSeen)
+	}
+
+	var utf8Output strings.Builder
+	result, err = sp.ProcessReaderWriter(context.Background(), strings.NewReader("\xc4\xe3\xff\xba\xc3\n"), &utf8Output, &StreamOptions{
+		SourceEncoding: EncodingGBK,
+		TargetEncoding: EncodingUTF8BOM,
+		BufferSize:     64,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.LinesProcessed != 1 || result.InvalidSequences != 1 || result.CharactersReplaced != 0 {
+		t.Errorf("Unexpected stats: lines %d, replaced %d, invalid %d",
+			result.LinesProcessed, result.CharactersReplaced, result.InvalidSequences)
+	}
+	if result.SourceBOMSeen || !result.TargetBOMSeen {
+		t.Errorf("Unexpected BOM flags: source %v, target %v", result.SourceBOMSeen, result.TargetBOMSeen)
+	}
+}
+
+func TestErrorThreshold(t *testing.T) {
+	config := GetDefaultConverterConfig()
+	config.MaxErrors = 1
+
+	converter := NewConverter(config)
+	if _, err := converter.Convert([]byte("咖啡 café"), EncodingUTF8, EncodingISO88591); !errors.Is(err, ErrTooManyErrors) {
+		t.Fatalf("Expected ErrTooManyErrors, got %v", err)
+	}
+
+	result, err := converter.Convert([]byte("咖 café"), EncodingUTF8, EncodingISO88591)
+	if err != nil {
+		t.Fatalf("Expected conversion within threshold to succeed: %v", err)
+	}
+	if string(result) != "? caf\xe9" {
+		t.Errorf("Unexpected result %q", result)
+	}
+
+	var output strings.Builder
+	_, err = NewStreamProcessor(nil).ProcessReaderWriter(context.Background(), strings.NewReader(strings.Repeat("\xff\xfe\xfd", 100)), &output, &StreamOptions{
+		SourceEncoding: EncodingGBK,
+		TargetEncoding: EncodingUTF8,
+		BufferSize:     64,
+		MaxErrorRate:   0.1,
+	})
+	var thresholdErr *ErrorThresholdError
+	if !errors.As(err, &thresholdErr) {
+		t.Fatalf("Expected ErrorThresholdError, got %v", err)
+	}
+	if thresholdErr.Bytes != 64 || thresholdErr.MaxErrorRate != 0.1 {
+		t.Errorf("Expected abort after first chunk, got %+v", thresholdErr)
+	}
+}
+
+func TestConvertPipe(t *testing.T) {
+	w, r := ConvertPipe(EncodingGBK, EncodingUTF8)
+
+	go func() {
+		// 逐字节写入，验证跨写入边界的多字节字符
+		for _, b := range []byte(strings.Repeat("\xc4\xe3\xba\xc3", 100)) {
+			if _, err := w.Write([]byte{b}); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+		w.Close()
+	}()
+
+	output, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != strings.Repeat("你好", 100) {
+		t.Errorf("Unexpected pipe output %q", output)
+	}
+
+	w, r = ConvertPipe(EncodingUTF8, "X-UNKNOWN")
+	if _, err := w.Write([]byte("x")); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("Expected ErrUnsupportedEncoding from writer, got %v", err)
+	}
+	if _, err := io.ReadAll(r); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("Expected ErrUnsupportedEncoding from reader, got %v", err)
+	}
+}
+
+func TestFindBOMsAndStrip(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"script.sh":       []byte("\xef\xbb\xbfecho 你好\n"),
+		"plain.txt":       []byte("no bom here\n"),
+		"data.csv":        {0xFF, 0xFE, 'a', 0x00, ',', 0x00, 'b', 0x00},
+		".git/HEAD":       []byte("\xef\xbb\xbfref"),
+		"nested/conf.ini": []byte("\xef\xbb\xbf[section]\n"),
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	boms, err := FindBOMs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[string]string)
+	var paths []string
+	for _, info := range boms {
+		found[filepath.Base(info.Path)] = info.Encoding
+		paths = append(paths, info.Path)
+	}
+	if len(found) != 3 || found["script.sh"] != EncodingUTF8 || found["data.csv"] != EncodingUTF16LE || found["conf.ini"] != EncodingUTF8 {
+		t.Fatalf("Unexpected BOM inventory: %v", found)
+	}
+
+	result, err := NewBatchProcessor(nil).ProcessFiles(context.Background(), paths, &BatchOptions{
+		FileOptions: &FileProcessOptions{
+			TargetEncoding:    EncodingUTF8,
+			OverwriteExisting: true,
+			BOMPolicy:         BOMStrip,
+		},
+	})
+	if err != nil || result.FailureCount != 0 {
+		t.Fatalf("Batch strip failed: %v %+v", err, result)
+	}
+
+	script, _ := os.ReadFile(filepath.Join(dir, "script.sh"))
+	if string(script) != "echo 你好\n" {
+		t.Errorf("Expected BOM to be stripped, got %q", script)
+	}
+	csv, _ := os.ReadFile(filepath.Join(dir, "data.csv"))
+	if string(csv) != "a,b" {
+		t.Errorf("Expected UTF-16LE CSV converted without BOM, got %q", csv)
+	}
+
+	if boms, _ := FindBOMs(dir); len(boms) != 0 {
+		t.Errorf("Expected no BOMs after strip, got %v", boms)
+	}
+}
+
+func TestICUCompatibility(t *testing.T) {
+	for alias, expected := range map[string]string{
+		"windows-31j":        EncodingShiftJIS,
+		"ISO_8859-1:1987":    EncodingISO88591,
+		"IBM-5348_P100-1997": EncodingWindows1252,
+		"utf16_littleendian": EncodingUTF16LE,
+	} {
+		if got, ok := ResolveICUName(alias); !ok || got != expected {
+			t.Errorf("ResolveICUName(%q) = %q, %v; expected %q", alias, got, ok, expected)
+		}
+	}
+	if name, _ := ICUCanonicalName(EncodingGBK); name != "windows-936-2000" {
+		t.Errorf("Expected ICU canonical name windows-936-2000, got %q", name)
+	}
+
+	tests := []struct {
+		action   string
+		callback func(r rune) string
+		expected string
+	}{
+		{"", nil, "\x1a\x1a caf\xe9"},
+		{UnmappableSkip, nil, " caf\xe9"},
+		{UnmappableEscape, nil, "%U5496%U5561 caf\xe9"},
+		{"", func(r rune) string { return fmt.Sprintf("&#%d;", r) }, "&#21654;&#21857; caf\xe9"},
+	}
+	for _, tt := range tests {
+		config := GetDefaultConverterConfig()
+		config.CompatibilityMode = CompatibilityICU
+		config.UnmappableAction = tt.action
+		config.SubstitutionCallback = tt.callback
+
+		result, err := NewConverter(config).Convert([]byte("咖啡 café"), "utf8", "ibm-5348_P100-1997")
+		if err != nil {
+			t.Fatalf("Convert with action %q failed: %v", tt.action, err)
+		}
+		if string(result) != tt.expected {
+			t.Errorf("Action %q: expected %q, got %q", tt.action, tt.expected, result)
+		}
+	}
+
+	config := GetDefaultConverterConfig()
+	config.UnmappableAction = UnmappableStop
+	if _, err := NewConverter(config).Convert([]byte("咖啡"), EncodingUTF8, EncodingISO88591); err == nil {
+		t.Error("Expected stop action to fail on unrepresentable characters")
+	}
+}
+
+func TestStreamHonorsConverterConfig(t *testing.T) {
+	ctx := context.Background()
+
+	config := GetDefaultProcessorConfig()
+	config.ConverterConfig.InvalidCharReplacement = "*"
+	sp := NewStreamProcessor(config)
+
+	reader, err := sp.ProcessReader(ctx, strings.NewReader("咖啡 café"), EncodingUTF8, EncodingISO88591)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != "** caf\xe9" {
+		t.Errorf("Expected configured replacement in reader output, got %q", output)
+	}
+
+	// 流选项的严格模式对分块转换生效
+	var buf strings.Builder
+	_, err = sp.ProcessReaderWriter(ctx, strings.NewReader("咖啡"), &buf, &StreamOptions{
+		SourceEncoding: EncodingUTF8,
+		TargetEncoding: EncodingISO88591,
+		StrictMode:     true,
+	})
+	if err == nil {
+		t.Errorf("Expected strict stream conversion to fail, got %q", buf.String())
+	}
+
+	strictConfig := GetDefaultProcessorConfig()
+	strictConfig.ConverterConfig.StrictMode = true
+	strictConfig.ConverterConfig.DeniedEncodings = []string{EncodingBIG5}
+	strict := NewStreamProcessor(strictConfig)
+
+	writer, err := strict.ProcessWriter(ctx, &buf, EncodingUTF8, EncodingISO88591)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Write([]byte("咖啡")); err == nil {
+		t.Error("Expected strict writer to reject unrepresentable characters")
+	}
+
+	if _, err := strict.ProcessReader(ctx, strings.NewReader("x"), EncodingBIG5, EncodingUTF8); !errors.Is(err, ErrEncodingNotAllowed) {
+		t.Errorf("Expected ErrEncodingNotAllowed for denied encoding, got %v", err)
+	}
+}
+
+func TestOutputFileMode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secret.txt")
+	if err := os.WriteFile(path, []byte("机密内容，请勿外传\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0600|os.ModeSetgid); err != nil {
+		t.Fatal(err)
+	}
+
+	processor := NewFileProcessor(nil)
+	options := &FileProcessOptions{
+		TargetEncoding:    EncodingUTF8,
+		OverwriteExisting: true,
+		PreserveMode:      true,
+		CreateBackup:      true,
+		BackupSuffix:      DefaultBackupSuffix,
+	}
+	result, err := processor.ProcessFileInPlace(path, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, _ := os.Stat(path)
+	if want := os.FileMode(0600) | os.ModeSetgid; info.Mode()&(os.ModePerm|os.ModeSetgid) != want {
+		t.Errorf("Expected mode %v to be preserved, got %v", want, info.Mode())
+	}
+	if backup, err := os.Stat(result.BackupFile); err != nil || backup.Mode().Perm() != 0600 {
+		t.Errorf("Expected backup with mode 0600, got %v (%v)", backup, err)
+	}
+	if leftovers, _ := filepath.Glob(path + ".*.tmp"); len(leftovers) != 0 {
+		t.Errorf("Expected temp file to be removed, got %v", leftovers)
+	}
+
+	// OutputMode 优先于 PreserveMode
+	output := filepath.Join(dir, "out.txt")
+	options.CreateBackup = false
+	options.OutputMode = 0640
+	if _, err := processor.ProcessFile(path, output, options); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(output); info.Mode().Perm() != 0640 {
+		t.Errorf("Expected OutputMode 0640, got %v", info.Mode())
+	}
+
+	// 临时文件随机命名：用户已有的同名 .tmp 文件不被删除或覆盖，流式处理同样设置权限
+	userTemp := output + ".tmp"
+	if err := os.WriteFile(userTemp, []byte("user data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, threshold := range []int64{0, 8} {
+		options.StreamingThreshold = threshold
+		result, err := processor.ProcessFile(path, output, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Streamed != (threshold > 0) {
+			t.Errorf("threshold %d: unexpected Streamed %v", threshold, result.Streamed)
+		}
+		if info, _ := os.Stat(output); info.Mode().Perm() != 0640 {
+			t.Errorf("threshold %d: expected OutputMode 0640, got %v", threshold, info.Mode())
+		}
+		if data, err := os.ReadFile(userTemp); err != nil || string(data) != "user data" {
+			t.Errorf("threshold %d: expected user .tmp file to be untouched, got %q (%v)", threshold, data, err)
+		}
+		if leftovers, _ := filepath.Glob(output + ".*.tmp"); len(leftovers) != 0 {
+			t.Errorf("threshold %d: expected temp files to be removed, got %v", threshold, leftovers)
+		}
+	}
+}
+
+func TestDirOptionsFile(t *testing.T) {
+	dir := t.TempDir()
+	content := strings.Repeat("这是一个用于测试目录选项文件的中文内容。", 20)
+	files := map[string]string{
+		DirOptionsFile:             `{"exclude": ["*.log"]}`,
+		"notes.log":                content,
+		"legacy/" + DirOptionsFile: `{"target_encoding": "GBK"}`,
+		"legacy/readme.txt":        content,
+		"web/" + DirOptionsFile:    `{"bom_policy": "add"}`,
+		"web/index.html":           content,
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plan, err := PlanMigration(dir, EncodingUTF8)
+	if err != nil {
+		t.Fatalf("PlanMigration failed: %v", err)
+	}
+	actions := make(map[string]*MigrationItem)
+	for _, item := range plan.Items {
+		rel, _ := filepath.Rel(dir, item.Path)
+		actions[filepath.ToSlash(rel)] = item
+	}
+	if len(actions) != 3 {
+		t.Fatalf("Expected options files to be ignored, got %v", actions)
+	}
+	if item := actions["notes.log"]; item.Action != MigrationActionSkip {
+		t.Errorf("Expected notes.log to be excluded, got %+v", item)
+	}
+	if item := actions["legacy/readme.txt"]; item.Action != MigrationActionConvert || item.TargetEncoding != EncodingGBK {
+		t.Errorf("Expected legacy file to be converted to GBK, got %+v", item)
+	}
+	if item := actions["web/index.html"]; item.Action != MigrationActionConvert || item.BOMPolicy != BOMAdd {
+		t.Errorf("Expected web file to gain a BOM, got %+v", item)
+	}
+
+	if report, err := ExecutePlan(plan); err != nil || len(report.Failed) != 0 {
+		t.Fatalf("ExecutePlan failed: %v %v", err, report)
+	}
+	legacy, _ := os.ReadFile(filepath.Join(dir, "legacy", "readme.txt"))
+	if decoded, err := NewDefault().Convert(legacy, EncodingGBK, EncodingUTF8); err != nil || string(decoded) != content {
+		t.Errorf("Expected legacy file in GBK, got %q (%v)", legacy, err)
+	}
+	web, _ := os.ReadFile(filepath.Join(dir, "web", "index.html"))
+	if string(web) != "\ufeff"+content {
+		t.Errorf("Expected web file with BOM, got %q", web[:8])
+	}
+
+	// 批量处理同样遵循目录选项
+	result, err := NewBatchProcessor(nil).ProcessFiles(context.Background(), []string{filepath.Join(dir, "notes.log")}, &BatchOptions{
+		DirOptionsRoot: dir,
+	})
+	if err != nil || len(result.Skipped) != 1 || len(result.Results) != 0 {
+		t.Errorf("Expected notes.log to be skipped by batch processing, got %+v (%v)", result, err)
+	}
+}
+
+func TestProvenanceHeader(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tool.py")
+	body := "print('" + strings.Repeat("这是一个需要记录转换来源的中文脚本内容。", 20) + "')\n"
+	if err := os.WriteFile(path, []byte("#!/usr/bin/env python\n"+body), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	options := &FileProcessOptions{
+		TargetEncoding:    EncodingGBK,
+		OverwriteExisting: true,
+		Provenance:        &ProvenanceOptions{Template: "from {original} to {target}"},
+	}
+	// 注释头的往返转换不计入指标：只记录一次检测和一次转换
+	metricsConfig := GetDefaultProcessorConfig()
+	metricsConfig.EnableMetrics = true
+	metricsConfig.Metrics = NewMetricsCollector()
+	if _, err := NewFileProcessor(metricsConfig).ProcessFileInPlace(path, options); err != nil {
+		t.Fatal(err)
+	}
+	if stats := metricsConfig.Metrics.GetStats(); stats.TotalOperations != 2 || stats.TotalBytes != int64(len("#!/usr/bin/env python\n"+body)) {
+		t.Errorf("Expected one detection and one conversion of the file, got %d operations and %d bytes", stats.TotalOperations, stats.TotalBytes)
+	}
+	data, _ := os.ReadFile(path)
+	decoded, _ := NewDefault().Convert(data, EncodingGBK, EncodingUTF8)
+	if string(decoded) != "#!/usr/bin/env python\n# encproc: from UTF-8 to GBK\n"+body {
+		t.Fatalf("Expected header after shebang, got %q", decoded[:64])
+	}
+
+	// 再次转换时替换已有注释头而不是重复插入
+	config := GetDefaultProcessorConfig()
+	config.DetectorConfig.Rules = []DetectionRule{{PathPattern: "*.py", Encoding: EncodingGBK}}
+	options.TargetEncoding = EncodingUTF8
+	if _, err := NewFileProcessor(config).ProcessFileInPlace(path, options); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "#!/usr/bin/env python\n# encproc: from GBK to UTF-8\n"+body {
+		t.Fatalf("Expected single replaced header, got %q", data[:64])
+	}
+
+	options.Provenance.Strip = true
+	if _, err := NewFileProcessor(nil).ProcessFileInPlace(path, options); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "#!/usr/bin/env python\n"+body {
+		t.Errorf("Expected header to be stripped, got %q", data[:64])
+	}
+
+	// 流式处理的大文件无法插入注释头，返回错误而不是静默输出没有注释头的文件
+	options.Provenance.Strip = false
+	options.StreamingThreshold = 64
+	before, _ := os.ReadFile(path)
+	if _, err := NewFileProcessor(nil).ProcessFileInPlace(path, options); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("Expected ErrInvalidConfiguration for streamed provenance, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, before) {
+		t.Error("Expected streamed file to be left unchanged")
+	}
+	options.Provenance = nil
+	if result, err := NewFileProcessor(nil).ProcessFileInPlace(path, options); err != nil || !result.Streamed {
+		t.Errorf("Expected streaming without provenance to succeed, got %+v (%v)", result, err)
+	}
+
+	header := (&ProvenanceOptions{}).ProvenanceHeader("index.html", EncodingBIG5, EncodingUTF8, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), LineEndingLF)
+	if string(header) != "<!-- encproc: converted from BIG5 to UTF-8 on 2024-05-01 -->\n" {
+		t.Errorf("Unexpected HTML header %q", header)
+	}
+}
+
+func TestDiffConverted(t *testing.T) {
+	original := []byte("café — naïve\nprice: 5€ only")
+	latin1, err := NewDefault().Convert(original, EncodingUTF8, EncodingISO88591)
+	if err != nil {
+		t.Fatal(err)
+	}
+	converted, _ := NewDefault().Convert(latin1, EncodingISO88591, EncodingUTF8)
+
+	diff := DiffConverted(original, converted, EncodingUTF8)
+	if diff == nil || diff.ChangedRunes != 2 || len(diff.Changes) != 2 {
+		t.Fatalf("Expected 2 changed runes, got %+v", diff)
+	}
+	if c := diff.Changes[0]; c.Offset != 5 || c.Line != 1 || c.Column != 6 || c.Original != "—" || c.Converted != "?" {
+		t.Errorf("Unexpected first change %+v", c)
+	}
+	if c := diff.Changes[1]; c.Line != 2 || c.Column != 9 || c.Original != "€" {
+		t.Errorf("Unexpected second change %+v", c)
+	}
+
+	// 被跳过和被转义的字符
+	diff = DiffConverted(original, []byte("café  naïve\nprice: 5%U20AC only"), EncodingUTF8)
+	if len(diff.Changes) != 2 || diff.Changes[0].Converted != "" || diff.Changes[1].Converted != "%U20AC" {
+		t.Errorf("Unexpected changes %+v", diff.Changes)
+	}
+	if !DiffConverted(original, original, EncodingUTF8).Identical() {
+		t.Error("Expected identical diff for unchanged text")
+	}
+
+	// 试运行预览差异
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := NewFileProcessor(nil).ProcessFile(path, path, &FileProcessOptions{
+		TargetEncoding:    EncodingISO88591,
+		OverwriteExisting: true,
+		DryRun:            true,
+		DiffPreview:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Diff == nil || result.Diff.ChangedRunes != 2 {
+		t.Errorf("Expected dry-run diff with 2 changed runes, got %+v", result.Diff)
+	}
+	if data, _ := os.ReadFile(path); string(data) != string(original) {
+		t.Error("Dry run should not modify the file")
+	}
+}
+
+func TestLostRunesHistogram(t *testing.T) {
+	text := "Hello — world — “quoted” — ok"
+	result, err := NewDefault().SmartConvert([]byte(text), EncodingISO88591)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.LostRunes["—"] != 3 || result.LostRunes["“"] != 1 || result.LostRunes["”"] != 1 || len(result.LostRunes) != 3 {
+		t.Errorf("Unexpected lost runes %v", result.LostRunes)
+	}
+
+	var output bytes.Buffer
+	streamResult, err := NewStreamProcessor(nil).ProcessReaderWriter(context.Background(), strings.NewReader(text), &output, &StreamOptions{
+		SourceEncoding: EncodingUTF8,
+		TargetEncoding: EncodingISO88591,
+	})
+	if err != nil || streamResult.LostRunes["—"] != 3 {
+		t.Errorf("Expected stream to report lost em-dashes, got %v (%v)", streamResult, err)
+	}
+
+	// 批量处理汇总各文件的丢失字符
+	dir := t.TempDir()
+	var files []string
+	for _, name := range []string{"a.txt", "b.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+	batch, err := NewBatchProcessor(nil).ProcessFiles(context.Background(), files, &BatchOptions{
+		FileOptions: &FileProcessOptions{TargetEncoding: EncodingISO88591, OverwriteExisting: true},
+	})
+	if err != nil || batch.FailureCount != 0 {
+		t.Fatalf("Batch failed: %v %+v", err, batch)
+	}
+	if batch.LostRunes["—"] != 6 {
+		t.Errorf("Expected 6 lost em-dashes across batch, got %v", batch.LostRunes)
+	}
+
+	metrics := NewMetricsCollector().(*defaultMetricsCollector)
+	metrics.RecordLostRunes(batch.LostRunes)
+	metrics.RecordLostRunes(result.LostRunes)
+	if stats := metrics.GetStats(); stats.LostRunes["—"] != 9 {
+		t.Errorf("Expected 9 lost em-dashes in metrics, got %v", stats.LostRunes)
+	}
+}
+
+func TestSmartConvertAlternative(t *testing.T) {
+	gbk, err := NewDefault().Convert([]byte(strings.Repeat("中文编码检测有时会在相近的候选之间摇摆。", 20)), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 低置信度的歧义数据
+	config := GetDefaultProcessorConfig()
+	config.DetectorConfig.MinConfidence = 0
+	if result, err := NewProcessor(config).SmartConvert(gbk, EncodingUTF8); err != nil || result.Alternative != nil {
+		t.Fatalf("Expected no alternative without margin, got %+v (%v)", result, err)
+	}
+
+	config.AlternativeMargin = 10
+	processor := NewProcessor(config)
+	result, err := processor.SmartConvert(gbk, EncodingUTF8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alt := result.Alternative
+	if alt == nil || alt.Encoding == result.SourceEncoding || alt.Margin > config.AlternativeMargin {
+		t.Fatalf("Expected alternative conversion, got %+v", alt)
+	}
+	expected, err := processor.Convert(gbk, alt.Encoding, EncodingUTF8)
+	if err != nil || !bytes.Equal(expected, alt.Data) {
+		t.Errorf("Alternative data does not match conversion from %s", alt.Encoding)
+	}
+}
+
+func TestValidateAsLanguage(t *testing.T) {
+	russian := "Съешь же ещё этих мягких французских булок, да выпей чаю. Москва — столица России."
+	cp1251, err := NewDefault().Convert([]byte(russian), EncodingUTF8, EncodingWindows1251)
+	if err != nil {
+		t.Fatal(err)
+	}
+	misread, _ := NewDefault().Convert(cp1251, EncodingKOI8R, EncodingUTF8)
+
+	if score := ValidateAsLanguage(russian, LanguageRussian); score < DefaultMinLanguageScore {
+		t.Errorf("Expected Russian text to score high, got %.2f", score)
+	}
+	if score := ValidateAsLanguage(string(misread), LanguageRussian); score >= DefaultMinLanguageScore {
+		t.Errorf("Expected misread KOI8-R text to score low, got %.2f", score)
+	}
+	if score := ValidateAsLanguage(russian, LanguageEnglish); score != 0 {
+		t.Errorf("Expected Cyrillic text to score 0 as English, got %.2f", score)
+	}
+	if ValidateAsLanguage("これは日本語のテストです。", LanguageJapanese) < DefaultMinLanguageScore ||
+		ValidateAsLanguage("这是一个测试文件", LanguageJapanese) != 0 {
+		t.Error("Expected kana to distinguish Japanese from Chinese")
+	}
+
+	// 文件处理在语言得分不足时放弃转换
+	path := filepath.Join(t.TempDir(), "archive.txt")
+	if err := os.WriteFile(path, cp1251, 0644); err != nil {
+		t.Fatal(err)
+	}
+	options := &FileProcessOptions{TargetEncoding: EncodingUTF8, OverwriteExisting: true, ExpectedLanguage: LanguageRussian}
+
+	config := GetDefaultProcessorConfig()
+	config.DetectorConfig.Rules = []DetectionRule{{PathPattern: "*.txt", Encoding: EncodingKOI8R}}
+	if _, err := NewFileProcessor(config).ProcessFileInPlace(path, options); !errors.Is(err, ErrLanguageMismatch) {
+		t.Fatalf("Expected ErrLanguageMismatch, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, cp1251) {
+		t.Fatal("File should be unchanged after language mismatch")
+	}
+
+	config.DetectorConfig.Rules[0].Encoding = EncodingWindows1251
+	if _, err := NewFileProcessor(config).ProcessFileInPlace(path, options); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != russian {
+		t.Errorf("Expected converted Russian text, got %q", data)
+	}
+}
+
+func TestCodePageNames(t *testing.T) {
+	if name, err := EncodingFromCodePage(950); err != nil || name != EncodingCP950 {
+		t.Errorf("Expected CP950 for code page 950, got %q %v", name, err)
+	}
+
+	converter := NewConverter()
+	gbk, err := converter.Convert([]byte("你好"), EncodingUTF8, "cp936")
+	if err != nil || string(gbk) != "\xc4\xe3\xba\xc3" {
+		t.Fatalf("Expected GBK output for cp936, got %q %v", gbk, err)
+	}
+
+	// 白名单按规范名称匹配
+	config := GetDefaultConverterConfig()
+	config.AllowedEncodings = []string{EncodingUTF8, EncodingWindows1251}
+	if _, err := NewConverter(config).Convert([]byte("привет"), EncodingUTF8, "windows-1251"); err != nil {
+		t.Errorf("Expected windows-1251 to be allowed, got %v", err)
+	}
+	if _, err := NewConverter(config).Convert([]byte("test"), "cp950", EncodingUTF8); !errors.Is(err, ErrEncodingNotAllowed) {
+		t.Errorf("Expected cp950 to be rejected as CP950, got %v", err)
+	}
+
+	var output bytes.Buffer
+	result, err := NewStreamProcessor(nil).ProcessReaderWriter(context.Background(), strings.NewReader("\xc4\xe3\xba\xc3"), &output, &StreamOptions{
+		SourceEncoding: "936",
+		TargetEncoding: "cp65001",
+	})
+	if err != nil || output.String() != "你好" || result.TargetEncoding != EncodingUTF8 {
+		t.Errorf("Expected stream conversion with code page names, got %q %+v %v", output.String(), result, err)
+	}
+}
+
+func TestSiblingContextBoost(t *testing.T) {
+	dir := t.TempDir()
+	processor := NewDefault()
+	write := func(name, text string) {
+		data, err := processor.Convert([]byte(text), EncodingUTF8, EncodingGBK)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"a.py", "b.py", "c.py"} {
+		write(name, "# 这是一个用于测试兄弟文件上下文的中文注释\n")
+	}
+	write("short.txt", "中文测试")
+
+	config := GetDefaultProcessorConfig()
+	config.DetectorConfig.Rules = []DetectionRule{{PathPattern: "*.py", Encoding: EncodingGBK}}
+	plan, err := NewMigrationPlanner(config).PlanMigration(dir, EncodingUTF8)
+	if err != nil {
+		t.Fatalf("PlanMigration failed: %v", err)
+	}
+	if plan.FlagCount != 1 {
+		t.Fatalf("Expected short file to be flagged without context, got %+v", plan)
+	}
+
+	config.DetectorConfig.SiblingContextWeight = 0.8
+	planner := NewMigrationPlanner(config)
+	plan, err = planner.PlanMigration(dir, EncodingUTF8)
+	if err != nil {
+		t.Fatalf("PlanMigration failed: %v", err)
+	}
+	if plan.FlagCount != 0 || plan.ConvertCount != 4 {
+		t.Fatalf("Expected all files to be converted with context, got %+v", plan)
+	}
+	for _, item := range plan.Items {
+		if filepath.Base(item.Path) == "short.txt" && (!item.ContextBoosted || item.SourceEncoding != EncodingGBK) {
+			t.Errorf("Expected short file to be boosted to GBK, got %+v", item)
+		}
+	}
+
+	if report, err := planner.ExecutePlan(plan); err != nil || len(report.Failed) != 0 {
+		t.Fatalf("ExecutePlan failed: %v %v", err, report)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "short.txt")); string(data) != "中文测试" {
+		t.Errorf("Expected short file converted from GBK, got %q", data)
+	}
+}
+
+func TestProcessDirectory(t *testing.T) {
+	dir := t.TempDir()
+	processor := NewDefault()
+	files := map[string]string{
+		"a.py":            "# 批量目录转换测试中的中文注释\n",
+		"b.py":            "# 另一个使用相同编码的源文件\n",
+		"short.txt":       "中文测试",
+		"docs/readme.md":  "说明文档",
+		"docs/skip.log":   "日志文件",
+		".git/config.txt": "隐藏目录",
+	}
+	for name, text := range files {
+		data, err := processor.Convert([]byte(text), EncodingUTF8, EncodingGBK)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := GetDefaultProcessorConfig()
+	config.DetectorConfig.Rules = []DetectionRule{{PathPattern: "*.py", Encoding: EncodingGBK}}
+	config.DetectorConfig.SiblingContextWeight = 0.8
+	result, err := NewBatchProcessor(config).ProcessDirectory(dir, &BatchOptions{
+		FileOptions: &FileProcessOptions{
+			TargetEncoding:    EncodingUTF8,
+			MinConfidence:     DefaultMinConfidence,
+			OverwriteExisting: true,
+		},
+		Extensions: []string{"py", ".txt"},
+		Exclude:    []string{"docs/*"},
+	})
+	if err != nil {
+		t.Fatalf("ProcessDirectory failed: %v", err)
+	}
+	if len(result.Results) != 3 || result.SuccessCount != 3 || result.SourceEncodings[EncodingGBK] != 3 {
+		t.Fatalf("Expected three GBK files to be converted, got %+v", result)
+	}
+	for _, fileResult := range result.Results {
+		if filepath.Base(fileResult.Job.Path) == "short.txt" && !fileResult.ContextBoosted {
+			t.Errorf("Expected short file to be converted using sibling context, got %+v", fileResult)
+		}
+	}
+
+	for name, text := range files {
+		data, _ := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		converted := string(data) == text
+		if expected := filepath.Ext(name) != ".md" && filepath.Ext(name) != ".log" && !strings.HasPrefix(name, "."); converted != expected {
+			t.Errorf("%s: expected converted=%v, got %q", name, expected, data)
+		}
+	}
+
+	// 未设置检测器配置时不使用兄弟文件上下文，检测失败的文件照常报告
+	zeroDir := t.TempDir()
+	short, _ := processor.Convert([]byte("中文测试"), EncodingUTF8, EncodingGBK)
+	if err := os.WriteFile(filepath.Join(zeroDir, "short.txt"), short, 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err = NewBatchProcessor(&ProcessorConfig{}).ProcessDirectory(zeroDir, &BatchOptions{
+		FileOptions: &FileProcessOptions{
+			TargetEncoding:    EncodingUTF8,
+			MinConfidence:     DefaultMinConfidence,
+			OverwriteExisting: true,
+		},
+	})
+	if err != nil || len(result.Results) != 1 {
+		t.Fatalf("ProcessDirectory with a zero config failed: %+v (%v)", result, err)
+	}
+}
+
+func TestProcessDirectoryDepthAndBinary(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"top.txt":          []byte("top level text\n"),
+		"logo.txt":         []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x01\x00\x00\x00\x01\x00"),
+		"sub/nested.txt":   []byte("nested text\n"),
+		"sub/deep/far.txt": []byte("too deep\n"),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := NewBatchProcessor(nil).ProcessDirectory(dir, &BatchOptions{
+		FileOptions: &FileProcessOptions{TargetEncoding: EncodingUTF8, OverwriteExisting: true},
+		Extensions:  []string{"txt"},
+		MaxDepth:    2,
+		SkipBinary:  true,
+	})
+	if err != nil {
+		t.Fatalf("ProcessDirectory failed: %v", err)
+	}
+
+	var processed []string
+	for _, fileResult := range result.Results {
+		rel, _ := filepath.Rel(dir, fileResult.Job.Path)
+		processed = append(processed, filepath.ToSlash(rel))
+	}
+	slices.Sort(processed)
+	if strings.Join(processed, ",") != "sub/nested.txt,top.txt" {
+		t.Errorf("Expected only files within depth 2 to be processed, got %v", processed)
+	}
+	if len(result.Skipped) != 1 || filepath.Base(result.Skipped[0]) != "logo.txt" {
+		t.Errorf("Expected binary file to be skipped, got %v", result.Skipped)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "logo.txt")); !bytes.Equal(data, files["logo.txt"]) {
+		t.Errorf("Expected binary file to be left untouched, got %q", data)
+	}
+}
+
+func TestBinaryFileGuard(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x01\x00\x00\x00\x01\x00\x08\x06")
+	compressed := make([]byte, 4096)
+	seed := uint32(1)
+	for i := range compressed {
+		// 伪随机字节，避开 NUL 和控制字符，只能按熵识别
+		seed = seed*1103515245 + 12345
+		compressed[i] = byte(0x20 + (seed>>16)%224)
+	}
+	text, _ := NewDefault().Convert([]byte(strings.Repeat("批量转换二进制文件检测。", 100)), EncodingUTF8, EncodingGBK)
+	utf16 := []byte{'h', 0, 'i', 0, '\n', 0}
+	mail := strings.Repeat("제목: 회의록\n본문입니다.\n", 20)
+	iso2022kr, _ := NewDefault().Convert([]byte(mail), EncodingUTF8, EncodingISO2022KR)
+	utf32 := strings.Repeat("无 BOM 的 UTF-32 文本\n", 20)
+	utf32le, _ := NewDefault().Convert([]byte(utf32), EncodingUTF8, EncodingUTF32LE)
+
+	for name, tc := range map[string]struct {
+		data   []byte
+		binary bool
+	}{
+		"png":        {png, true},
+		"compressed": {compressed, true},
+		"gbk":        {text, false},
+		"utf16":      {utf16, false},
+		"iso2022kr":  {iso2022kr, false},
+		"utf32le":    {utf32le, false},
+		"empty":      {nil, false},
+	} {
+		if got := IsProbablyBinary(tc.data); got != tc.binary {
+			t.Errorf("%s: IsProbablyBinary = %v, expected %v (entropy %.2f)", name, got, tc.binary, AnalyzeBytes(tc.data).Entropy)
+		}
+	}
+
+	dir := t.TempDir()
+	image := filepath.Join(dir, "image.txt")
+	if err := os.WriteFile(image, png, 0644); err != nil {
+		t.Fatal(err)
+	}
+	options := &FileProcessOptions{TargetEncoding: EncodingUTF8, FallbackToLatin1: true, OverwriteExisting: true}
+	if _, err := NewFileProcessor(nil).ProcessFileInPlace(image, options); !errors.Is(err, ErrBinaryFile) {
+		t.Fatalf("Expected ErrBinaryFile, got %v", err)
+	}
+	if data, _ := os.ReadFile(image); !bytes.Equal(data, png) {
+		t.Errorf("Expected binary file to be left untouched, got %q", data)
+	}
+
+	result, err := NewBatchProcessor(nil).ProcessFiles(context.Background(), []string{image}, &BatchOptions{FileOptions: options})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FailureCount != 0 || len(result.Skipped) != 1 || result.Skipped[0] != image {
+		t.Errorf("Expected binary file to be skipped rather than failed, got %+v", result)
+	}
+
+	// 含 SO/SI 控制字节的 ISO-2022-KR 邮件和无 BOM 的 UTF-32LE 文本不被当作二进制拒绝
+	for _, tc := range []struct {
+		name, encoding, text string
+		data                 []byte
+	}{
+		{"mail.txt", EncodingISO2022KR, mail, iso2022kr},
+		{"utf32.txt", EncodingUTF32LE, utf32, utf32le},
+	} {
+		path := filepath.Join(dir, tc.name)
+		if err := os.WriteFile(path, tc.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewFileProcessor(nil).ProcessFileInPlace(path, &FileProcessOptions{SourceEncoding: tc.encoding, TargetEncoding: EncodingUTF8, OverwriteExisting: true}); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if data, _ := os.ReadFile(path); string(data) != tc.text {
+			t.Errorf("%s: unexpected conversion %q", tc.name, data)
+		}
+	}
+}
+
+func TestDryRunReport(t *testing.T) {
+	dir := t.TempDir()
+	text := strings.Repeat("这是一个用于生成试运行报告的中文文本文件。\n", 5)
+	gbk, err := NewDefault().Convert([]byte(text), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"legacy.txt": gbk,
+		"ascii.txt":  []byte("already plain ascii\n"),
+		"image.txt":  []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x01\x00"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := NewBatchProcessor(nil).ProcessDirectory(dir, &BatchOptions{
+		FileOptions: &FileProcessOptions{SourceEncoding: EncodingGBK, TargetEncoding: EncodingUTF8, OverwriteExisting: true, DryRun: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := NewDryRunReport(result)
+	if len(report.Entries) != 3 || report.ConvertCount != 1 || report.UnchangedCount != 1 || report.SkippedCount != 1 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	for _, entry := range report.Entries {
+		switch filepath.Base(entry.Path) {
+		case "legacy.txt":
+			if entry.Status != DryRunStatusConvert || !entry.WouldConvert || entry.EstimatedSize != int64(len(text)) || entry.SizeDelta != int64(len(text)-len(gbk)) {
+				t.Errorf("Unexpected entry for GBK file %+v", entry)
+			}
+		case "ascii.txt":
+			if entry.Status != DryRunStatusUnchanged || entry.WouldConvert || entry.SizeDelta != 0 {
+				t.Errorf("Unexpected entry for ASCII file %+v", entry)
+			}
+		case "image.txt":
+			if entry.Status != DryRunStatusSkipped || entry.Error == "" {
+				t.Errorf("Unexpected entry for binary file %+v", entry)
+			}
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "legacy.txt")); !bytes.Equal(data, gbk) {
+		t.Error("Dry run should not modify the file")
+	}
+
+	var csvOutput bytes.Buffer
+	if err := report.WriteCSV(&csvOutput); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(csvOutput.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "path,status,") || !strings.Contains(csvOutput.String(), ",convert,") {
+		t.Errorf("Unexpected CSV report %q", csvOutput.String())
+	}
+
+	var jsonOutput bytes.Buffer
+	if err := report.WriteJSON(&jsonOutput); err != nil {
+		t.Fatal(err)
+	}
+	var decoded DryRunReport
+	if err := json.Unmarshal(jsonOutput.Bytes(), &decoded); err != nil || !reflect.DeepEqual(&decoded, report) {
+		t.Errorf("Expected JSON report to round trip, got %+v (%v)", decoded, err)
+	}
+
+	// 置信度低于 MinConfidence 时试运行与实际处理一样拒绝，而不是报告会被转换
+	path := filepath.Join(t.TempDir(), "plain.txt")
+	if err := os.WriteFile(path, []byte("already plain ascii\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	options := &FileProcessOptions{TargetEncoding: EncodingUTF16LE, MinConfidence: 0.99, OverwriteExisting: true}
+	var realErr *EncodingError
+	if _, err := NewFileProcessor(nil).ProcessFile(path, path, options); !errors.As(err, &realErr) || realErr.Op != OperationDetect {
+		t.Fatalf("Expected low-confidence detection error, got %v", err)
+	}
+	options.DryRun = true
+	var dryErr *EncodingError
+	if result, err := NewFileProcessor(nil).ProcessFile(path, path, options); !errors.As(err, &dryErr) || dryErr.Op != OperationDetect {
+		t.Errorf("Expected dry run to reject low confidence like the real run, got %+v (%v)", result, err)
+	}
+}
+
+func TestConversionManifest(t *testing.T) {
+	dir := t.TempDir()
+	gbk, err := NewDefault().Convert([]byte("转换清单记录已转换的文件"), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), gbk, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manifestPath := filepath.Join(dir, ".encproc-manifest.json")
+	run := func() *BatchResult {
+		manifest, err := LoadConversionManifest(manifestPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := NewBatchProcessor(nil).ProcessDirectory(dir, &BatchOptions{
+			FileOptions: &FileProcessOptions{SourceEncoding: EncodingGBK, TargetEncoding: EncodingUTF8, OverwriteExisting: true},
+			Manifest:    manifest,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	if result := run(); result.SuccessCount != 2 || len(result.AlreadyProcessed) != 0 {
+		t.Fatalf("Expected both files to be converted on the first run, got %+v", result)
+	}
+
+	// 第二次运行只按大小和修改时间跳过（源编码仍指定为 GBK，若重新转换会损坏文件）
+	if result := run(); len(result.AlreadyProcessed) != 2 {
+		t.Fatalf("Expected both files to be skipped on the second run, got %+v", result)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(data) != "转换清单记录已转换的文件" {
+		t.Errorf("Expected converted file to be left alone, got %q", data)
+	}
+
+	// 修改过的文件重新处理
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), append(gbk, gbk...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result := run()
+	if len(result.AlreadyProcessed) != 1 || len(result.Results) != 2 || result.SuccessCount != 2 {
+		t.Errorf("Expected only the modified file to be converted, got %+v", result)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "b.txt")); string(data) != strings.Repeat("转换清单记录已转换的文件", 2) {
+		t.Errorf("Expected modified file to be converted, got %q", data)
+	}
+}
+
+// upperConverter 仅实现 ByteConverter 的测试替身
+type upperConverter struct{}
+
+func (upperConverter) Convert(data []byte, from, to string) ([]byte, error) {
+	return bytes.ToUpper(data), nil
+}
+
+func TestRoleInterfaces(t *testing.T) {
+	processor := NewDefault()
+	var (
+		_ ByteDetector    = processor
+		_ FileDetector    = processor
+		_ StringConverter = processor
+	)
+
+	convert := func(c ByteConverter, text string) string {
+		data, err := c.Convert([]byte(text), EncodingUTF8, EncodingUTF8)
+		if err != nil {
+			t.Fatalf("Convert failed: %v", err)
+		}
+		return string(data)
+	}
+	if got := convert(processor, "abc"); got != "abc" {
+		t.Errorf("Expected processor to satisfy ByteConverter, got %q", got)
+	}
+	if got := convert(upperConverter{}, "abc"); got != "ABC" {
+		t.Errorf("Expected mock to satisfy ByteConverter, got %q", got)
+	}
+}
+
+func TestBatchWorkerPool(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i := 0; i < 20; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("file%02d.txt", i))
+		text := "plain ascii content\n"
+		if i == 3 {
+			text = "包含无法用 GBK 表示的字符 😀\n"
+		}
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+
+	// 单个文件处理中的 panic 只影响该文件
+	config := GetDefaultProcessorConfig()
+	config.ConverterConfig.SubstitutionCallback = func(r rune) string { panic("unexpected rune") }
+	options := &BatchOptions{
+		FileOptions: &FileProcessOptions{
+			TargetEncoding:    EncodingGBK,
+			OverwriteExisting: true,
+		},
+		Concurrency: 4,
+	}
+	result, err := NewBatchProcessor(config).ProcessFiles(context.Background(), files, options)
+	if err != nil {
+		t.Fatalf("ProcessFiles failed: %v", err)
+	}
+	if result.SuccessCount != 19 || result.FailureCount != 1 {
+		t.Fatalf("Expected one isolated failure, got %d successes and %d failures", result.SuccessCount, result.FailureCount)
+	}
+	for _, fileResult := range result.Results {
+		if fileResult.Err != nil && (filepath.Base(fileResult.Job.Path) != "file03.txt" || !strings.Contains(fileResult.Error, "panic")) {
+			t.Errorf("Unexpected failure for %s: %v", fileResult.Job.Path, fileResult.Err)
+		}
+	}
+
+	// 取消后剩余文件记为已取消
+	ctx, cancel := context.WithCancel(context.Background())
+	options.Concurrency = 1
+	options.OnFileDone = func(*BatchFileResult) { cancel() }
+	result, err = NewBatchProcessor(nil).ProcessDirectoryContext(ctx, dir, options)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if len(result.Results) != 1 || len(result.Cancelled) != len(files)-1 {
+		t.Errorf("Expected 1 processed and %d cancelled files, got %d and %d", len(files)-1, len(result.Results), len(result.Cancelled))
+	}
+}
+
+func TestExtendedLengthPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		extended string
+	}{
+		{`C:\data\file.txt`, `\\?\C:\data\file.txt`},
+		{`\\server\share\dir\file.txt`, `\\?\UNC\server\share\dir\file.txt`},
+		{`\\?\C:\data\file.txt`, `\\?\C:\data\file.txt`},
+		{`\\?\UNC\server\share\file.txt`, `\\?\UNC\server\share\file.txt`},
+		{`\\.\pipe\name`, `\\.\pipe\name`},
+		{`relative\file.txt`, `relative\file.txt`},
+	}
+
+	for _, test := range tests {
+		extended := extendedLengthPath(test.path)
+		if extended != test.extended {
+			t.Errorf("extendedLengthPath(%q) = %q, expected %q", test.path, extended, test.extended)
+		}
+		if !hasExtendedPrefix(test.path) && trimExtendedPrefix(extended) != test.path {
+			t.Errorf("trimExtendedPrefix(%q) = %q, expected %q", extended, trimExtendedPrefix(extended), test.path)
+		}
+	}
+
+	// 备份文件名保持扩展长度前缀，还原后为普通路径
+	backup := extendedLengthPath(`\\server\share\file.txt`) + DefaultBackupSuffix
+	if trimExtendedPrefix(backup) != `\\server\share\file.txt`+DefaultBackupSuffix {
+		t.Errorf("Unexpected backup path %q", trimExtendedPrefix(backup))
+	}
+}
+
+func TestCheckDirectory(t *testing.T) {
+	dir := t.TempDir()
+	gbk, err := NewDefault().Convert([]byte(strings.Repeat("这是一个使用GBK编码的中文文件。\n", 10)), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"good.txt":         "纯净的 UTF-8 文本\n第二行\n",
+		"bom.txt":          "\ufeff带 BOM 的文本\n",
+		"crlf.txt":         "line one\r\nline two\r\n",
+		"control.txt":      "line one\nbell \a here\n",
+		"legacy.txt":       string(gbk),
+		"image.bin":        "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00",
+		"vendor/skip.txt":  "\ufeffvendored\r\n",
+		".git/config.text": "\ufeffhidden\r\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	policy := DefaultCheckPolicy()
+	policy.Exclude = []string{"vendor/*"}
+	result, err := CheckDirectory(dir, policy)
+	if err != nil {
+		t.Fatalf("CheckDirectory failed: %v", err)
+	}
+	if result.Passed() || result.ExitCode() != 1 {
+		t.Fatalf("Expected check to fail, got %+v", result)
+	}
+	if result.FilesChecked != 5 || len(result.Skipped) != 1 {
+		t.Errorf("Expected 5 checked and 1 skipped file, got %d and %v", result.FilesChecked, result.Skipped)
+	}
+
+	rules := make(map[string]string)
+	for _, violation := range result.Violations {
+		rules[filepath.Base(violation.Path)] += violation.Rule + " "
+	}
+	expected := map[string]string{
+		"bom.txt":     CheckRuleBOM + " ",
+		"crlf.txt":    CheckRuleLineEnding + " ",
+		"control.txt": CheckRuleControlChars + " ",
+		"legacy.txt":  CheckRuleEncoding + " ",
+	}
+	if fmt.Sprint(rules) != fmt.Sprint(expected) {
+		t.Errorf("Expected violations %v, got %v", expected, rules)
+	}
+
+	// 检查不修改任何文件
+	if data, _ := os.ReadFile(filepath.Join(dir, "bom.txt")); string(data) != files["bom.txt"] {
+		t.Errorf("Expected check to be read-only, got %q", data)
+	}
+}
+
+func TestStreamingFinalNewline(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "large.txt")
+	body := strings.Repeat("流式处理末尾换行符测试。\n", 500)
+
+	tests := []struct {
+		policy   string
+		source   string
+		target   string
+		expected string
+	}{
+		{FinalNewlineStrip, body + "\n\n", EncodingUTF8, strings.TrimRight(body, "\n")},
+		{FinalNewlineEnsure, strings.TrimRight(body, "\n"), EncodingUTF8, body},
+		{FinalNewlineEnsure, body, EncodingUTF8, body},
+		{FinalNewlineStrip, body + "\r\n", EncodingUTF16LE, strings.TrimRight(body, "\n")},
+	}
+
+	for _, test := range tests {
+		if err := os.WriteFile(input, []byte(test.source), 0644); err != nil {
+			t.Fatal(err)
+		}
+		config := GetDefaultProcessorConfig()
+		config.ConverterConfig.FinalNewline = test.policy
+		output := filepath.Join(dir, "out.txt")
+		result, err := NewFileProcessor(config).ProcessFile(input, output, &FileProcessOptions{
+			TargetEncoding:     test.target,
+			OverwriteExisting:  true,
+			StreamingThreshold: 1024,
+		})
+		if err != nil {
+			t.Fatalf("%s: ProcessFile failed: %v", test.policy, err)
+		}
+		if !result.Streamed {
+			t.Fatalf("%s: expected streaming path", test.policy)
+		}
+
+		data, _ := os.ReadFile(output)
+		decoded, err := NewDefault().Convert(data, test.target, EncodingUTF8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(decoded) != test.expected {
+			t.Errorf("%s to %s: unexpected ending %q", test.policy, test.target, decoded[len(decoded)-10:])
+		}
+	}
+}
+
+func TestConvertRanges(t *testing.T) {
+	text := "开始游戏"
+	header, trailer := []byte{0xDE, 0xAD, 0xBE, 0xEF}, []byte{0x00, 0xCA, 0xFE}
+	data := append(append(append([]byte(nil), header...), text...), trailer...)
+	region := ByteRange{Offset: int64(len(header)), Length: int64(len(text))}
+
+	// 固定长度：UTF-8 转为 GBK 后变短，不足部分填充，其余字节偏移不变
+	output, report, err := ConvertRanges(data, &RangeConvertOptions{
+		Ranges:         []ByteRange{region},
+		SourceEncoding: EncodingUTF8,
+		TargetEncoding: EncodingGBK,
+	})
+	if err != nil {
+		t.Fatalf("ConvertRanges failed: %v", err)
+	}
+	if len(output) != len(data) || !bytes.Equal(output[:4], header) || !bytes.Equal(output[len(output)-3:], trailer) {
+		t.Fatalf("Expected surrounding bytes to be copied verbatim, got %x", output)
+	}
+	if r := report.Ranges[0]; r.ConvertedLength != 8 || r.Padding != 4 || report.BytesCopied != 7 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	if decoded, _ := NewDefault().Convert(output[4:12], EncodingGBK, EncodingUTF8); string(decoded) != text {
+		t.Errorf("Expected converted region %q, got %q", text, decoded)
+	}
+
+	// 反向转换变长时，固定长度模式拒绝，允许改变长度时成功
+	back := &RangeConvertOptions{
+		Ranges:         []ByteRange{{Offset: 4, Length: 8}},
+		SourceEncoding: EncodingGBK,
+		TargetEncoding: EncodingUTF8,
+	}
+	if _, _, err := ConvertRanges(output, back); !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("Expected ErrInvalidRange for oversized result, got %v", err)
+	}
+	back.AllowResize = true
+	restored, _, err := ConvertRanges(output, back)
+	if err != nil || !bytes.Equal(restored[:16], data[:16]) || len(restored) != len(data)+4 {
+		t.Errorf("Expected resized conversion, got %x (%v)", restored, err)
+	}
+
+	// 越界和重叠的区间
+	for _, ranges := range [][]ByteRange{
+		{{Offset: 10, Length: 100}},
+		{{Offset: 4, Length: 6}, {Offset: 8, Length: 2}},
+	} {
+		if _, _, err := ConvertRanges(data, &RangeConvertOptions{Ranges: ranges, SourceEncoding: EncodingUTF8}); !errors.Is(err, ErrInvalidRange) {
+			t.Errorf("Expected ErrInvalidRange for %v, got %v", ranges, err)
+		}
+	}
+}
+
+func TestUTF32File(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "utf32.txt")
+	data := []byte{0xFF, 0xFE, 0x00, 0x00}
+	for _, r := range "UTF-32 文本 😀\n" {
+		data = append(data, byte(r), byte(r>>8), byte(r>>16), 0)
+	}
+	if err := os.WriteFile(input, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, "utf8.txt")
+	result, err := NewFileProcessor(nil).ProcessFile(input, output, &FileProcessOptions{
+		TargetEncoding: EncodingUTF8,
+		BOMPolicy:      BOMStrip,
+	})
+	if err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+	if result.SourceEncoding != EncodingUTF32LE {
+		t.Errorf("Expected UTF-32LE detection, got %s", result.SourceEncoding)
+	}
+	if converted, _ := os.ReadFile(output); string(converted) != "UTF-32 文本 😀\n" {
+		t.Errorf("Unexpected conversion %q", converted)
+	}
+
+	// 转换回 UTF-32LE 并添加 BOM 后与原文件一致
+	back := filepath.Join(dir, "back.txt")
+	if _, err := NewFileProcessor(nil).ProcessFile(output, back, &FileProcessOptions{
+		TargetEncoding: EncodingUTF32LE,
+		BOMPolicy:      BOMAdd,
+	}); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+	if roundTrip, _ := os.ReadFile(back); !bytes.Equal(roundTrip, data) {
+		t.Errorf("Round trip mismatch: %x", roundTrip)
+	}
+}
+
+func TestCharsetDeclaration(t *testing.T) {
+	dir := t.TempDir()
+	body := "body { font-family: \"" + strings.Repeat("微软雅黑", 10) + "\"; }\n"
+	gbk, err := NewDefault().Convert([]byte("@charset \"GBK\";\n"+body), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		policy   string
+		expected string
+	}{
+		{CharsetDeclarationRewrite, "@charset \"UTF-8\";\n" + body},
+		{CharsetDeclarationRemove, body},
+	}
+	for _, test := range tests {
+		path := filepath.Join(dir, test.policy+".css")
+		if err := os.WriteFile(path, gbk, 0644); err != nil {
+			t.Fatal(err)
+		}
+		options := &FileProcessOptions{
+			TargetEncoding:     EncodingUTF8,
+			OverwriteExisting:  true,
+			CharsetDeclaration: test.policy,
+		}
+		result, err := NewFileProcessor(nil).ProcessFileInPlace(path, options)
+		if err != nil {
+			t.Fatalf("%s: %v", test.policy, err)
+		}
+		if result.SourceEncoding != EncodingGBK {
+			t.Errorf("%s: expected declaration to hint GBK, got %s", test.policy, result.SourceEncoding)
+		}
+		if data, _ := os.ReadFile(path); string(data) != test.expected {
+			t.Errorf("%s: unexpected output %q", test.policy, data)
+		}
+	}
+
+	// 改写为非 UTF-8 编码的声明时往返转换不计入指标
+	stylesheet := filepath.Join(dir, "gbk.css")
+	utf8Stylesheet := "@charset \"UTF-8\";\n" + body
+	if err := os.WriteFile(stylesheet, []byte(utf8Stylesheet), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := GetDefaultProcessorConfig()
+	config.EnableMetrics = true
+	config.Metrics = NewMetricsCollector()
+	rewrite := &FileProcessOptions{TargetEncoding: EncodingGBK, OverwriteExisting: true, CharsetDeclaration: CharsetDeclarationRewrite}
+	if _, err := NewFileProcessor(config).ProcessFileInPlace(stylesheet, rewrite); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(stylesheet); !bytes.Equal(data, gbk) {
+		t.Errorf("Expected GBK declaration, got %q", data)
+	}
+	if stats := config.Metrics.GetStats(); stats.TotalOperations != 2 || stats.TotalBytes != int64(len(utf8Stylesheet)) {
+		t.Errorf("Expected one detection and one conversion of the stylesheet, got %d operations and %d bytes", stats.TotalOperations, stats.TotalBytes)
+	}
+
+	// UTF-8 脚本去除开头的 BOM
+	script := filepath.Join(dir, "app.js")
+	if err := os.WriteFile(script, []byte("\ufeffconsole.log('中文');\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	options := &FileProcessOptions{TargetEncoding: EncodingUTF8, OverwriteExisting: true, CharsetDeclaration: CharsetDeclarationRemove}
+	if _, err := NewFileProcessor(nil).ProcessFileInPlace(script, options); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(script); string(data) != "console.log('中文');\n" {
+		t.Errorf("Expected script BOM to be removed, got %q", data)
+	}
+
+	// 流式处理的大文件无法改写，返回错误
+	if err := os.WriteFile(script, []byte("\ufeffconsole.log('中文');\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	options.StreamingThreshold = 8
+	if _, err := NewFileProcessor(nil).ProcessFileInPlace(script, options); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("Expected ErrInvalidConfiguration for streamed script, got %v", err)
+	}
+
+	if rewritten := RewriteCSSCharset([]byte("\ufeff@charset 'latin1'; a {}"), EncodingGBK); string(rewritten) != "\ufeff@charset \"GBK\"; a {}" {
+		t.Errorf("Unexpected rewritten stylesheet %q", rewritten)
+	}
+}
+
+func TestContextVariants(t *testing.T) {
+	text := "<meta charset=\"gbk\">" + strings.Repeat("这是用于测试上下文取消的中文内容。", 20)
+	gbk, err := NewDefault().Convert([]byte(text), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	processor := NewProcessor(nil)
+	result, err := processor.(ContextSmartConverter).SmartConvertContext(context.Background(), gbk, EncodingUTF8)
+	if err != nil || result.SourceEncoding != EncodingGBK {
+		t.Fatalf("Expected GBK conversion with background context, got %v (%v)", result, err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := processor.(ContextDetector).DetectEncodingContext(cancelled, gbk); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected DetectEncodingContext to be cancelled, got %v", err)
+	}
+	if _, err := processor.(ContextConverter).ConvertContext(cancelled, gbk, EncodingGBK, EncodingUTF8); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected ConvertContext to be cancelled, got %v", err)
+	}
+	if _, err := processor.(ContextSmartConverter).SmartConvertContext(cancelled, gbk, EncodingUTF8); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected SmartConvertContext to be cancelled, got %v", err)
+	}
+
+	// 转换过程中取消
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	config := GetDefaultConverterConfig()
+	config.SubstitutionCallback = func(r rune) string {
+		cancel()
+		return "?"
+	}
+	data := []byte("😀" + strings.Repeat("plain ascii text ", 10000))
+	if _, err := NewConverter(config).(ContextConverter).ConvertContext(ctx, data, EncodingUTF8, EncodingGBK); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected conversion to stop after cancellation, got %v", err)
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	report, err := SelfTest()
+	if err != nil {
+		t.Fatalf("SelfTest failed: %v", err)
+	}
+	if !report.Passed() || len(report.Results) != len(selfTestCorpus) {
+		t.Errorf("Expected all %d samples to convert correctly, got %+v", len(selfTestCorpus), report.Results)
+	}
+	if report.DetectionAccuracy <= 0 || report.ConversionThroughput <= 0 || report.Platform == "" {
+		t.Errorf("Expected accuracy, throughput and platform to be reported, got %+v", report)
+	}
+}
+
+func TestDetectReaderEncoding(t *testing.T) {
+	config := GetDefaultDetectorConfig()
+	config.SampleSize = 64
+	data := []byte(strings.Repeat("流式读取的中文内容。", 50))
+
+	result, replay, err := NewDetector(config).(ReaderDetector).DetectReaderEncoding(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("DetectReaderEncoding failed: %v", err)
+	}
+	if result.Encoding != EncodingUTF8 {
+		t.Errorf("Expected UTF-8, got %s", result.Encoding)
+	}
+	if replayed, _ := io.ReadAll(replay); !bytes.Equal(replayed, data) {
+		t.Errorf("Expected replay reader to return all %d bytes, got %d", len(data), len(replayed))
+	}
+
+	// 检测失败时读取器仍然可用
+	_, replay, err = NewDetector().(ReaderDetector).DetectReaderEncoding(strings.NewReader(""))
+	if err == nil {
+		t.Error("Expected empty reader to fail detection")
+	}
+	if replay == nil {
+		t.Error("Expected replay reader even when detection fails")
+	}
+}
+
+func TestConverterBOMPolicy(t *testing.T) {
+	withBOM := []byte("\ufeff你好")
+	converter := NewConverter()
+
+	// 默认去除 BOM（PreserveBOM 为 false）；非 Unicode 目标编码无法表示 BOM，总是去除
+	tests := []struct {
+		to       string
+		policy   string
+		expected string
+		stripped bool
+		added    bool
+	}{
+		{EncodingUTF16LE, "", "\x60\x4f\x7d\x59", true, false},
+		{EncodingUTF16LE, BOMPreserve, "\xff\xfe\x60\x4f\x7d\x59", false, false},
+		{EncodingGBK, "", "\xc4\xe3\xba\xc3", true, false},
+		{EncodingUTF16LE, BOMStrip, "\x60\x4f\x7d\x59", true, false},
+		{EncodingUTF8, BOMStrip, "你好", true, false},
+	}
+	for _, test := range tests {
+		result, err := converter.(OptionsConverter).ConvertWithOptions(withBOM, EncodingUTF8, test.to, &ConvertOptions{BOMPolicy: test.policy})
+		if err != nil {
+			t.Fatalf("%s/%s: %v", test.to, test.policy, err)
+		}
+		if string(result.Data) != test.expected || result.BOMStripped != test.stripped || result.BOMAdded != test.added {
+			t.Errorf("%s/%s: unexpected result % x (stripped=%v added=%v)", test.to, test.policy, result.Data, result.BOMStripped, result.BOMAdded)
+		}
+	}
+
+	result, err := converter.(OptionsConverter).ConvertWithOptions([]byte("\xc4\xe3\xba\xc3"), EncodingGBK, EncodingUTF8, &ConvertOptions{BOMPolicy: BOMAdd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Data) != "\ufeff你好" || !result.BOMAdded {
+		t.Errorf("Expected BOM to be added, got %q (added=%v)", result.Data, result.BOMAdded)
+	}
+
+	// 严格模式下源数据的 BOM 不再导致无法表示字符的错误
+	config := GetDefaultConverterConfig()
+	config.StrictMode = true
+	if _, err := NewConverter(config).Convert(withBOM, EncodingUTF8, EncodingGBK); err != nil {
+		t.Errorf("Expected BOM to be dropped for GBK in strict mode, got %v", err)
+	}
+
+	// PreserveBOM 为 false 且未设置 BOMPolicy 时去除 BOM
+	config = GetDefaultConverterConfig()
+	config.PreserveBOM = false
+	if data, err := NewConverter(config).Convert(withBOM, EncodingUTF8, EncodingUTF16LE); err != nil || string(data) != "\x60\x4f\x7d\x59" {
+		t.Errorf("Expected PreserveBOM=false to strip BOM, got % x (%v)", data, err)
+	}
+}
+
+func TestLatin1Passthrough(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte{0x81, 0xfe, 0x02, 0xc3, 0x28, 0xa0, 0xff, 0x9d, 0x10, 0xee}
+	input := filepath.Join(dir, "blob.txt")
+	if err := os.WriteFile(input, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	options := &FileProcessOptions{
+		TargetEncoding:    EncodingUTF8,
+		MinConfidence:     0.99,
+		OverwriteExisting: true,
+		AllowBinary:       true,
+	}
+	fp := NewFileProcessor(nil)
+	if _, err := fp.ProcessFile(input, filepath.Join(dir, "strict.txt"), options); err == nil {
+		t.Fatal("Expected detection error without fallback")
+	}
+
+	options.FallbackToLatin1 = true
+	output := filepath.Join(dir, "out.txt")
+	result, err := fp.ProcessFile(input, output, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Passthrough || result.SourceEncoding != EncodingISO88591 {
+		t.Errorf("Expected raw Latin-1 passthrough, got %+v", result)
+	}
+
+	// 按 ISO-8859-1 转回后应与原始字节完全一致
+	converted, _ := os.ReadFile(output)
+	restored, err := NewDefault().Convert(converted, EncodingUTF8, EncodingISO88591)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(restored, data) {
+		t.Errorf("Expected round trip to restore original bytes, got %x", restored)
+	}
+}
+
+func TestLineEndingNormalization(t *testing.T) {
+	config := GetDefaultConverterConfig()
+	config.NormalizeLineEndings = true
+	config.TargetLineEnding = LineEndingCRLF
+	converter := NewConverter(config)
+
+	input := "第一行\r\n第二行\r第三行\n第四行"
+	result, err := converter.(OptionsConverter).ConvertWithOptions([]byte(input), EncodingUTF8, EncodingGBK, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := NewDefault().ConvertString(string(result.Data), EncodingGBK, EncodingUTF8)
+	if text != "第一行\r\n第二行\r\n第三行\r\n第四行" || result.LineEndingsConverted != 2 {
+		t.Errorf("Unexpected normalization %q (%d converted)", text, result.LineEndingsConverted)
+	}
+
+	// 源编码与目标编码相同时同样统一换行符，UTF-16 按编码单元比较
+	config.TargetLineEnding = LineEndingLF
+	utf16, err := NewConverter(config).Convert([]byte("a\r\nb\rc"), EncodingUTF8, EncodingUTF16LE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(utf16, []byte{'a', 0, '\n', 0, 'b', 0, '\n', 0, 'c', 0}) {
+		t.Errorf("Unexpected UTF-16LE output %x", utf16)
+	}
+	if same, _ := NewConverter(config).Convert([]byte("x\r\ny"), EncodingUTF8, EncodingUTF8); string(same) != "x\ny" {
+		t.Errorf("Expected UTF-8 line endings to be normalized, got %q", same)
+	}
+
+	// 流处理时跨数据块的 CRLF 只计一次
+	processorConfig := GetDefaultProcessorConfig()
+	processorConfig.ConverterConfig = config
+	var output bytes.Buffer
+	streamResult, err := NewStreamProcessor(processorConfig).ProcessReaderWriter(context.Background(), strings.NewReader("ab\r\ncd\r\n"), &output, &StreamOptions{
+		SourceEncoding: EncodingUTF8,
+		TargetEncoding: EncodingUTF8,
+		BufferSize:     3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "ab\ncd\n" || streamResult.LineEndingsConverted != 2 {
+		t.Errorf("Unexpected stream output %q (%d converted)", output.String(), streamResult.LineEndingsConverted)
+	}
+}
+
+func TestRegisterEncoding(t *testing.T) {
+	RegisterEncoding("IBM037", charmap.CodePage037, "ebcdic-us", "ebcdic-cp-us")
+
+	ebcdic, err := NewDefault().Convert([]byte("HELLO 1"), EncodingUTF8, "EBCDIC-US")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ebcdic, []byte{0xC8, 0xC5, 0xD3, 0xD3, 0xD6, 0x40, 0xF1}) {
+		t.Errorf("Unexpected EBCDIC bytes %x", ebcdic)
+	}
+
+	result, err := NewDefault().(OptionsConverter).ConvertWithOptions(ebcdic, "ebcdic-cp-us", EncodingUTF8, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Data) != "HELLO 1" {
+		t.Errorf("Expected round trip through registered encoding, got %q", result.Data)
+	}
+	if name := canonicalEncodingName("Ebcdic-Us"); name != "IBM037" {
+		t.Errorf("Expected alias to resolve to IBM037, got %s", name)
+	}
+}
+
+func TestRunFilter(t *testing.T) {
+	gbk, err := NewDefault().Convert([]byte("第一行\n第二行"), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var args FilterArgs
+	fs := flag.NewFlagSet("filter", flag.ContinueOnError)
+	args.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-from", "cp936", "-eol", "crlf", "-final-newline", "ensure"}); err != nil {
+		t.Fatal(err)
+	}
+	var output bytes.Buffer
+	if err := RunFilter(bytes.NewReader(gbk), &output, args); err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "第一行\r\n第二行\r\n" {
+		t.Errorf("Unexpected filter output %q", output.String())
+	}
+
+	// 只检测编码
+	output.Reset()
+	if err := RunFilter(strings.NewReader("纯 UTF-8 文本"), &output, FilterArgs{DetectOnly: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(output.String(), EncodingUTF8+"\t") {
+		t.Errorf("Unexpected detection output %q", output.String())
+	}
+
+	// 检测失败时使用备用编码
+	output.Reset()
+	blob := []byte{0x81, 0xfe, 0xc3, 0x28, 0xa0, 0xff}
+	if err := RunFilter(bytes.NewReader(blob), &output, FilterArgs{MinConfidence: 0.99}); err == nil {
+		t.Error("Expected detection error without fallback")
+	}
+	output.Reset()
+	if err := RunFilter(bytes.NewReader(blob), &output, FilterArgs{MinConfidence: 0.99, Fallback: EncodingISO88591}); err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "\u0081þÃ(\u00a0ÿ" {
+		t.Errorf("Unexpected fallback output %q", output.String())
+	}
+
+	if err := RunFilter(strings.NewReader("x"), &output, FilterArgs{BOM: "keep"}); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("Expected invalid configuration error, got %v", err)
+	}
+}
+
+func TestCharsetTag(t *testing.T) {
+	if value := formatAppleTextEncoding(EncodingUTF8); value != "utf-8;134217984" {
+		t.Errorf("Unexpected com.apple.TextEncoding value %q", value)
+	}
+	if name, ok := parseAppleTextEncoding("GB18030;1586"); !ok || name != EncodingGB18030 {
+		t.Errorf("Expected GB18030, got %q", name)
+	}
+	// CFStringEncoding 编号唯一，解析时不会映射到其他编码（CP866 为 0x041B，0x0413 是 CP855）
+	seen := make(map[uint32]string)
+	for name, code := range cfStringEncodings {
+		if other, ok := seen[code]; ok {
+			t.Errorf("CFStringEncoding %#x shared by %s and %s", code, name, other)
+		}
+		seen[code] = name
+	}
+	if name, _ := parseAppleTextEncoding("ibm866;1051"); name != EncodingCP866 {
+		t.Errorf("Expected CP866 for 0x041B, got %q", name)
+	}
+	if name, _ := parseAppleTextEncoding("ibm855;1043"); name != EncodingCP855 {
+		t.Errorf("Expected CP855 for 0x0413, got %q", name)
+	}
+
+	dir := t.TempDir()
+	gbk, err := NewDefault().Convert([]byte("中文内容"), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(dir, "input.txt")
+	if err := os.WriteFile(input, gbk, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteCharsetTag(input, "cp936"); err != nil {
+		if errors.Is(err, ErrXattrUnsupported) {
+			t.Skipf("extended attributes not supported: %v", err)
+		}
+		t.Fatal(err)
+	}
+
+	// 输入文件的编码扩展属性作为检测提示，输出文件写入目标编码
+	output := filepath.Join(dir, "output.txt")
+	options := &FileProcessOptions{
+		TargetEncoding:    EncodingUTF8,
+		OverwriteExisting: true,
+		UseCharsetTag:     true,
+		WriteCharsetTag:   true,
+	}
+	result, err := NewFileProcessor(nil).ProcessFile(input, output, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SourceEncoding != EncodingGBK {
+		t.Errorf("Expected charset tag to hint GBK, got %s", result.SourceEncoding)
+	}
+	if tagged, err := ReadCharsetTag(output); err != nil || tagged != EncodingUTF8 {
+		t.Errorf("Expected output to be tagged UTF-8, got %q (%v)", tagged, err)
+	}
+
+	// 过时的单字节编码属性不采用：单字节编码能解码任何数据，含非 ASCII 字符的有效 UTF-8 不按属性解码
+	stale := filepath.Join(dir, "stale.txt")
+	if err := os.WriteFile(stale, []byte("中文内容"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteCharsetTag(stale, EncodingWindows1252); err != nil {
+		t.Fatal(err)
+	}
+	if detection := detectCharsetTag(stale, []byte("中文内容")); detection != nil {
+		t.Errorf("Expected stale windows-1252 tag to be ignored, got %s", detection.Encoding)
+	}
+}
+
+func TestMinConfidenceByEncoding(t *testing.T) {
+	data := []byte{0x82, 0xa0, 0x82, 0xa2, 0x82, 0xa4}
+	detect := func(match CharsetMatch) (*DetectionResult, error) {
+		config := GetDefaultDetectorConfig()
+		config.EnableCache = false
+		config.UseDeclaredCharset = false
+		config.Backend = &staticBackend{matches: []CharsetMatch{match}}
+		config.MinConfidenceByEncoding = map[string]float64{"shift_jis": 0.95, EncodingGBK: 0.7}
+		return NewDetector(config).DetectEncoding(data)
+	}
+
+	// Shift_JIS 要求更高的置信度
+	if _, err := detect(CharsetMatch{Charset: "Shift_JIS", Confidence: 90}); err == nil {
+		t.Error("Expected Shift_JIS at 0.90 to be rejected")
+	}
+	if result, err := detect(CharsetMatch{Charset: "Shift_JIS", Confidence: 96}); err != nil || result.Encoding != EncodingShiftJIS {
+		t.Errorf("Expected Shift_JIS at 0.96 to be accepted, got %v, %v", result, err)
+	}
+
+	// GBK 低于全局 MinConfidence 但满足单独的阈值，未单独设置的编码仍使用全局阈值
+	if result, err := detect(CharsetMatch{Charset: "GBK", Confidence: 75}); err != nil || result.Encoding != EncodingGBK {
+		t.Errorf("Expected GBK at 0.75 to be accepted, got %v, %v", result, err)
+	}
+	if _, err := detect(CharsetMatch{Charset: "EUC-KR", Confidence: 75}); err == nil {
+		t.Error("Expected EUC-KR at 0.75 to be rejected by the global threshold")
+	}
+}
+
+func TestExtendedCharmaps(t *testing.T) {
+	tests := []struct {
+		encoding string
+		text     string
+		encoded  []byte
+	}{
+		{EncodingWindows1255, "שלום", []byte{0xF9, 0xEC, 0xE5, 0xED}},
+		{EncodingISO88596, "سلام", []byte{0xD3, 0xE4, 0xC7, 0xE5}},
+		{EncodingTIS620, "สวัสดี", []byte{0xCA, 0xC7, 0xD1, 0xCA, 0xB4, 0xD5}},
+		{EncodingISO88597, "Γειά", []byte{0xC3, 0xE5, 0xE9, 0xDC}},
+		{EncodingCP437, "╔═╗", []byte{0xC9, 0xCD, 0xBB}},
+		{EncodingKOI8U, "Їжак", []byte{0xB7, 0xD6, 0xC1, 0xCB}},
+	}
+
+	processor := NewDefault()
+	for _, tt := range tests {
+		encoded, err := processor.Convert([]byte(tt.text), EncodingUTF8, tt.encoding)
+		if err != nil {
+			t.Errorf("%s: %v", tt.encoding, err)
+			continue
+		}
+		if !bytes.Equal(encoded, tt.encoded) {
+			t.Errorf("%s: expected %x, got %x", tt.encoding, tt.encoded, encoded)
+		}
+		decoded, err := processor.Convert(encoded, tt.encoding, EncodingUTF8)
+		if err != nil || string(decoded) != tt.text {
+			t.Errorf("%s: expected round trip to %q, got %q (%v)", tt.encoding, tt.text, decoded, err)
+		}
+	}
+}
+
+func TestTransliterationCompanion(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	if err := os.WriteFile(input, []byte("Café “中文” ＡＢＣ—ok\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, "output.txt")
+	options := &FileProcessOptions{
+		SourceEncoding:    EncodingUTF8,
+		TargetEncoding:    EncodingGB18030,
+		OverwriteExisting: true,
+		Transliteration:   &TransliterationOptions{},
+	}
+	fp := NewFileProcessor(GetDefaultProcessorConfig())
+	result, err := fp.ProcessFile(input, output, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TransliteratedFile != filepath.Join(dir, "output.ascii.txt") || result.Untransliterated != 2 {
+		t.Errorf("Unexpected companion %s (%d untransliterated)", result.TransliteratedFile, result.Untransliterated)
+	}
+	companion, err := os.ReadFile(result.TransliteratedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(companion) != "Cafe \"??\" ABC-ok\n" {
+		t.Errorf("Unexpected companion content %q", companion)
+	}
+
+	// 自定义音译优先于替换字符
+	pinyin := map[rune]string{'中': "zhong", '文': "wen"}
+	options.Transliteration = &TransliterationOptions{
+		Suffix: ".pinyin",
+		Transliterator: func(r rune) (string, bool) {
+			s, ok := pinyin[r]
+			return s, ok
+		},
+	}
+	result, err = fp.ProcessFile(input, output, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	companion, err = os.ReadFile(filepath.Join(dir, "output.pinyin.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(companion) != "Cafe \"zhongwen\" ABC-ok\n" || result.Untransliterated != 0 {
+		t.Errorf("Unexpected companion content %q", companion)
+	}
+
+	// 副本由源文本生成：有损的目标编码中被替换为 ? 的字符仍然音译，且计入无法音译的字符数；
+	// 流式处理的大文件同样如此
+	options.TargetEncoding = EncodingISO88591
+	options.Transliteration = &TransliterationOptions{}
+	for _, streamed := range []bool{false, true} {
+		if streamed {
+			options.StreamingThreshold = 1
+		}
+		result, err = fp.ProcessFile(input, output, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		companion, err = os.ReadFile(result.TransliteratedFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(companion) != "Cafe \"??\" ABC-ok\n" || result.Untransliterated != 2 || result.Streamed != streamed {
+			t.Errorf("Unexpected companion from lossy output %q (%d untransliterated, streamed %v)", companion, result.Untransliterated, result.Streamed)
+		}
+	}
+}
+
+func TestIdempotentInPlaceConversion(t *testing.T) {
+	dir := t.TempDir()
+	gbk, err := NewDefault().Convert([]byte("共享存储上的中文文件"), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "data.txt")
+	if err := os.WriteFile(file, gbk, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewDirIdempotencyStore(filepath.Join(dir, ".idempotency"), time.Minute)
+	newOptions := func(worker string) *BatchOptions {
+		return &BatchOptions{FileOptions: &FileProcessOptions{
+			SourceEncoding:    EncodingGBK,
+			TargetEncoding:    EncodingUTF8,
+			OverwriteExisting: true,
+			Idempotency:       &IdempotencyOptions{Store: store, Root: dir, Worker: worker},
+		}}
+	}
+
+	first, err := NewBatchProcessor(nil).ProcessFiles(context.Background(), []string{file}, newOptions("a"))
+	if err != nil || first.SuccessCount != 1 || len(first.AlreadyProcessed) != 0 {
+		t.Fatalf("Unexpected first run %+v (%v)", first, err)
+	}
+
+	// 另一个工作者随后处理同一文件：已转换的内容不会按 GBK 再转换一次
+	second, err := NewBatchProcessor(nil).ProcessFiles(context.Background(), []string{file}, newOptions("b"))
+	if err != nil || second.SuccessCount != 1 || len(second.AlreadyProcessed) != 1 {
+		t.Fatalf("Unexpected second run %+v (%v)", second, err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "共享存储上的中文文件" {
+		t.Errorf("File converted twice: %q", data)
+	}
+
+	// 其他工作者已声明但尚未完成的内容不算已处理：单文件返回 ErrConversionInProgress，批量处理记为跳过
+	pending := filepath.Join(dir, "pending.txt")
+	if err := os.WriteFile(pending, gbk, 0644); err != nil {
+		t.Fatal(err)
+	}
+	checksum, err := fileChecksum(pending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claim := newOptions("d").FileOptions.Idempotency.newRecord(pending, checksum, EncodingUTF8)
+	if _, claimed, err := store.Claim(claim); err != nil || !claimed {
+		t.Fatalf("Expected claim to succeed (%v)", err)
+	}
+	if _, err := NewFileProcessor(nil).ProcessFileInPlace(pending, newOptions("e").FileOptions); !errors.Is(err, ErrConversionInProgress) {
+		t.Errorf("Expected ErrConversionInProgress, got %v", err)
+	}
+	third, err := NewBatchProcessor(nil).ProcessFiles(context.Background(), []string{pending}, newOptions("e"))
+	if err != nil || third.FailureCount != 0 || len(third.AlreadyProcessed) != 0 || len(third.Skipped) != 1 {
+		t.Errorf("Unexpected run while claimed %+v (%v)", third, err)
+	}
+	if data, _ := os.ReadFile(pending); !bytes.Equal(data, gbk) {
+		t.Errorf("Expected claimed file to be left untouched, got %q", data)
+	}
+
+	// 多个工作者同时接管同一个过期声明时只有一个成功
+	stale := filepath.Join(dir, ".stale")
+	claim.State, claim.UpdatedAt = IdempotencyClaimed, time.Now().Add(-time.Hour)
+	data, _ = json.Marshal(claim)
+	if err := os.MkdirAll(stale, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(stale, claim.Key+".json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	store = NewDirIdempotencyStore(stale, time.Minute)
+	var claims int
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(worker string) {
+			defer wg.Done()
+			if _, claimed, err := store.Claim(&IdempotencyRecord{Key: claim.Key, Worker: worker}); err == nil && claimed {
+				mutex.Lock()
+				claims++
+				mutex.Unlock()
+			}
+		}(fmt.Sprint(i))
+	}
+	wg.Wait()
+	if claims != 1 {
+		t.Errorf("Expected exactly one worker to take over the stale claim, got %d", claims)
+	}
+
+	// 转换失败时释放声明
+	store = NewDirIdempotencyStore(filepath.Join(dir, ".failed"), 0)
+	options := newOptions("c").FileOptions
+	options.SourceEncoding = "NO-SUCH-ENCODING"
+	if _, err := NewFileProcessor(nil).ProcessFileInPlace(file, options); err == nil {
+		t.Fatal("Expected conversion error")
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, ".failed"))
+	if len(entries) != 0 {
+		t.Errorf("Expected claim to be released, found %d records", len(entries))
+	}
+}
+
+func TestParallelChunkedConversion(t *testing.T) {
+	text := strings.Repeat("并行分块转换 parallel chunks 😀\n", 200) + strings.Repeat("无换行的长行", 100)
+	chunked := GetDefaultConverterConfig()
+	chunked.ChunkSize = 64
+	whole := GetDefaultConverterConfig()
+	whole.ChunkSize = int64(len(text)) * 8
+
+	for _, tc := range []struct{ from, to string }{
+		{EncodingUTF8, EncodingGB18030},
+		{EncodingUTF8, EncodingUTF16LE},
+		{EncodingUTF8, EncodingUTF16},
+		{EncodingUTF8, EncodingISO88591},
+		{EncodingUTF8, EncodingISO2022JP},
+		{EncodingUTF8, EncodingHZGB2312},
+	} {
+		source, err := NewConverter(whole).Convert([]byte(text), EncodingUTF8, tc.from)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := NewConverter(whole).(OptionsConverter).ConvertWithOptions(source, tc.from, tc.to, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 正向和反向转换的分块结果都与整体转换一致（包括丢失字符统计）
+		result, err := NewConverter(chunked).(OptionsConverter).ConvertWithOptions(source, tc.from, tc.to, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(result.Data, encoded.Data) || !reflect.DeepEqual(result.LostRunes, encoded.LostRunes) {
+			t.Errorf("%s -> %s: chunked conversion differs from whole conversion", tc.from, tc.to)
+		}
+		if tc.to == EncodingISO88591 || statefulEncodings[tc.to] {
+			continue
+		}
+		back, err := NewConverter(chunked).Convert(encoded.Data, tc.to, EncodingUTF8)
+		if err != nil || string(back) != text {
+			t.Errorf("%s -> UTF-8: chunked round trip failed (%v)", tc.to, err)
+		}
+	}
+
+	// 分块边界不切断字符：UTF-16 不拆分代理对，GBK 在换行符之后分块
+	utf16, _ := NewConverter(whole).Convert([]byte(text), EncodingUTF8, EncodingUTF16LE)
+	for _, boundary := range chunkBoundaries(utf16, EncodingUTF16LE, EncodingUTF8, 64) {
+		if boundary%2 != 0 || boundary >= 2 && utf16[boundary-1]&0xFC == 0xD8 {
+			t.Errorf("Unsafe UTF-16 boundary at %d", boundary)
+		}
+	}
+	gbk, _ := NewConverter(whole).Convert([]byte(text), EncodingUTF8, EncodingGB18030)
+	for _, boundary := range chunkBoundaries(gbk, EncodingGB18030, EncodingUTF8, 64) {
+		if boundary != 0 && boundary != len(gbk) && gbk[boundary-1] != '\n' {
+			t.Errorf("Unsafe GB18030 boundary at %d", boundary)
+		}
+	}
+	if boundaries := chunkBoundaries(utf16, EncodingUTF16, EncodingUTF8, 64); len(boundaries) != 2 {
+		t.Errorf("Expected BOM-detecting UTF-16 to be converted in one piece, got %d boundaries", len(boundaries))
+	}
+}
+
+func TestResultWarnings(t *testing.T) {
+	text := "Hello — world"
+	result, err := NewConverter().(OptionsConverter).ConvertWithOptions([]byte(text), EncodingUTF8, EncodingISO88591, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0].Code != WarningCharactersReplaced || result.Warnings[0].Count != 1 {
+		t.Errorf("Expected characters_replaced warning, got %v", result.Warnings)
+	}
+
+	// 就地转换：替换字符和未重新写入的扩展属性都记录为警告
+	dir := t.TempDir()
+	file := filepath.Join(dir, "tagged.txt")
+	if err := os.WriteFile(file, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tagged := WriteCharsetTag(file, EncodingUTF8) == nil
+	batch, err := NewBatchProcessor(nil).ProcessFiles(context.Background(), []string{file}, &BatchOptions{
+		FileOptions: &FileProcessOptions{SourceEncoding: EncodingUTF8, TargetEncoding: EncodingISO88591, OverwriteExisting: true},
+	})
+	if err != nil || batch.FailureCount != 0 {
+		t.Fatalf("Batch failed: %v %+v", err, batch)
+	}
+	codes := make(map[string]bool)
+	for _, warning := range batch.Warnings {
+		codes[warning.Code] = true
+		if warning.File != file {
+			t.Errorf("Expected warning for %s, got %v", file, warning)
+		}
+	}
+	if !codes[WarningCharactersReplaced] || codes[WarningXattrDropped] != tagged {
+		t.Errorf("Unexpected batch warnings %v (tagged %v)", batch.Warnings, tagged)
+	}
+
+	// 透传的文件记录低置信度警告
+	blob := filepath.Join(dir, "blob.txt")
+	if err := os.WriteFile(blob, []byte{0x81, 0xfe, 0x02, 0xc3, 0x28, 0xa0, 0xff, 0x9d, 0x10, 0xee}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	passthrough, err := NewFileProcessor(nil).ProcessFile(blob, filepath.Join(dir, "out.txt"), &FileProcessOptions{
+		TargetEncoding:    EncodingUTF8,
+		MinConfidence:     0.99,
+		FallbackToLatin1:  true,
+		OverwriteExisting: true,
+		AllowBinary:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(passthrough.Warnings) != 1 || passthrough.Warnings[0].Code != WarningLowConfidence {
+		t.Errorf("Expected low_confidence warning, got %v", passthrough.Warnings)
+	}
+}
+
+func TestStreamChunkBoundaries(t *testing.T) {
+	text := strings.Repeat("中文内容 テスト 𠜎 — ok\n", 40)
+	cases := []struct{ from, to string }{
+		{EncodingUTF8, EncodingGBK},
+		{EncodingGBK, EncodingUTF8},
+		{EncodingGB18030, EncodingUTF8},
+		{EncodingShiftJIS, EncodingUTF8},
+		{EncodingUTF16, EncodingUTF8},
+		{EncodingUTF16LE, EncodingUTF8},
+		{EncodingUTF32, EncodingUTF8},
+		{EncodingUTF8, EncodingUTF16},
+		{EncodingUTF8, EncodingUTF8BOM},
+	}
+	processor := NewDefault()
+	for _, tc := range cases {
+		// UTF-16/UTF-32 源数据以小端序 BOM 开头，检验首块之后沿用 BOM 确定的字节序
+		var input []byte
+		var err error
+		switch tc.from {
+		case EncodingUTF16, EncodingUTF32:
+			target := map[string]string{EncodingUTF16: EncodingUTF16LE, EncodingUTF32: EncodingUTF32LE}[tc.from]
+			var converted *ConvertResult
+			converted, err = processor.(OptionsConverter).ConvertWithOptions([]byte("\uFEFF"+text), EncodingUTF8, target, &ConvertOptions{BOMPolicy: BOMPreserve})
+			if err == nil {
+				input = converted.Data
+			}
+		default:
+			input, err = processor.Convert([]byte(text), EncodingUTF8, tc.from)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := processor.Convert(input, tc.from, tc.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, size := range []int{1, 5, 7} {
+			var output bytes.Buffer
+			result, err := NewStreamProcessor(nil).ProcessReaderWriter(context.Background(), bytes.NewReader(input), &output, &StreamOptions{
+				SourceEncoding: tc.from,
+				TargetEncoding: tc.to,
+				BufferSize:     size,
+			})
+			if err != nil {
+				t.Fatalf("%s->%s with %d-byte chunks: %v", tc.from, tc.to, size, err)
+			}
+			if !bytes.Equal(output.Bytes(), expected) || result.InvalidSequences != 0 || result.BytesRead != int64(len(input)) {
+				t.Errorf("%s->%s with %d-byte chunks differs from single-shot conversion (%d invalid)", tc.from, tc.to, size, result.InvalidSequences)
+			}
+		}
+	}
+}
+
+func TestStreamSourceBOM(t *testing.T) {
+	text := "带 BOM 的中文内容 test\n第二行\n"
+	sources := []string{EncodingUTF8, EncodingUTF16BE, EncodingUTF16LE}
+	targets := []string{EncodingGBK, EncodingUTF8, EncodingUTF16LE}
+	for _, policy := range []string{BOMStrip, BOMPreserve, BOMAdd} {
+		config := GetDefaultProcessorConfig()
+		config.ConverterConfig.BOMPolicy = policy
+		processor := NewProcessor(config)
+		for _, from := range sources {
+			input, err := processor.(OptionsConverter).ConvertWithOptions([]byte("\ufeff"+text), EncodingUTF8, from, &ConvertOptions{BOMPolicy: BOMPreserve})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, to := range targets {
+				name := fmt.Sprintf("%s %s->%s", policy, from, to)
+				expected, err := processor.Convert(input.Data, from, to)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+
+				for _, size := range []int{1, 3, 64} {
+					var output bytes.Buffer
+					if _, err := NewStreamProcessor(config).ProcessReaderWriter(context.Background(), bytes.NewReader(input.Data), &output, &StreamOptions{
+						SourceEncoding: from,
+						TargetEncoding: to,
+						BufferSize:     size,
+					}); err != nil {
+						t.Fatalf("%s: %v", name, err)
+					}
+					if !bytes.Equal(output.Bytes(), expected) {
+						t.Errorf("%s with %d-byte chunks: stream %x differs from Convert %x", name, size, output.Bytes(), expected)
+					}
+				}
+
+				reader, err := NewStreamProcessor(config).ProcessReader(context.Background(), iotest.OneByteReader(bytes.NewReader(input.Data)), from, to)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				if output, err := io.ReadAll(reader); err != nil || !bytes.Equal(output, expected) {
+					t.Errorf("%s: ProcessReader %x differs from Convert %x (%v)", name, output, expected, err)
+				}
+
+				path := filepath.Join(t.TempDir(), "bom.txt")
+				if err := os.WriteFile(path, input.Data, 0644); err != nil {
+					t.Fatal(err)
+				}
+				result, err := NewFileProcessor(config).ProcessFileInPlace(path, &FileProcessOptions{
+					SourceEncoding:     from,
+					TargetEncoding:     to,
+					OverwriteExisting:  true,
+					StreamingThreshold: 1,
+				})
+				if err != nil || !result.Streamed {
+					t.Fatalf("%s: expected streamed file processing, got %+v (%v)", name, result, err)
+				}
+				if output, _ := os.ReadFile(path); !bytes.Equal(output, expected) {
+					t.Errorf("%s: streamed file %x differs from Convert %x", name, output, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	RegisterEncoding("X-CAPABILITIES-TEST", charmap.CodePage037)
+	report := Capabilities()
+	if report.Version != Version || report.GoVersion == "" {
+		t.Errorf("Unexpected version info %+v", report)
+	}
+	for _, name := range []string{EncodingUTF8, EncodingGB18030, "X-CAPABILITIES-TEST"} {
+		if !slices.Contains(report.Encodings, name) {
+			t.Errorf("Expected %s in supported encodings", name)
+		}
+	}
+	if !slices.Contains(report.Engines, EngineChardet) {
+		t.Errorf("Expected chardet engine, got %v", report.Engines)
+	}
+	if slices.Contains(report.Features, FeatureCharsetTag) != charsetTagSupported {
+		t.Errorf("Unexpected features %v", report.Features)
+	}
+}
+
+func TestDetectionCacheLRU(t *testing.T) {
+	cache := newDetectionCache(2, time.Hour)
+	a, b, c := &DetectionResult{Encoding: "a"}, &DetectionResult{Encoding: "b"}, &DetectionResult{Encoding: "c"}
+	cache.put("a", a)
+	cache.put("b", b)
+	if _, ok := cache.get("a"); !ok {
+		t.Fatal("Expected a to be cached")
+	}
+	// a 最近被使用，缓存已满时淘汰 b
+	if !cache.put("c", c) {
+		t.Error("Expected eviction when cache is full")
+	}
+	if _, ok := cache.get("b"); ok {
+		t.Error("Expected least recently used entry to be evicted")
+	}
+	if result, ok := cache.get("a"); !ok || result != a || cache.len() != 2 {
+		t.Errorf("Unexpected cache state %v %v (len %d)", result, ok, cache.len())
+	}
+
+	expiring := newDetectionCache(2, time.Nanosecond)
+	expiring.put("a", a)
+	time.Sleep(time.Millisecond)
+	if _, ok := expiring.get("a"); ok || expiring.len() != 0 {
+		t.Error("Expected expired entry to be removed on lookup")
+	}
+
+	// 检测结果缓存的命中情况计入性能监控
+	processor, metrics := NewDefaultWithMetrics()
+	data := []byte("cached detection sample")
+	for i := 0; i < 3; i++ {
+		if _, err := processor.DetectEncoding(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats := metrics.GetStats(); stats.CacheHits != 2 || stats.CacheMisses != 1 {
+		t.Errorf("Expected 2 hits and 1 miss, got %d/%d", stats.CacheHits, stats.CacheMisses)
+	}
+}
+
+func TestDetectionCacheJanitor(t *testing.T) {
+	config := GetDefaultDetectorConfig()
+	config.CacheTTL = time.Millisecond
+	config.CacheJanitorInterval = time.Millisecond
+	detector := NewDetector(config).(*defaultDetector)
+	defer detector.Close()
+
+	// 清理协程在第一次缓存检测结果时才启动
+	if detector.cache.janitorRunning() {
+		t.Fatal("Expected no janitor before the first cached result")
+	}
+	if _, err := detector.DetectEncoding([]byte("janitor sample")); err != nil {
+		t.Fatal(err)
+	}
+	if !detector.cache.janitorRunning() && detector.cache.len() != 0 {
+		t.Fatal("Expected janitor to start on the first cached result")
+	}
+	// 过期条目无需查找即被后台协程删除，缓存清空后协程退出
+	deadline := time.Now().Add(time.Second)
+	for detector.cache.len() != 0 || detector.cache.janitorRunning() {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected janitor to purge expired entries and exit")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	config.CacheTTL = time.Hour
+	detector = NewDetector(config).(*defaultDetector)
+	if _, err := detector.DetectEncoding([]byte("janitor sample")); err != nil {
+		t.Fatal(err)
+	}
+	if !detector.cache.janitorRunning() {
+		t.Fatal("Expected janitor to run while entries are cached")
+	}
+	if err := detector.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if detector.cache.janitorRunning() {
+		t.Error("Expected janitor to stop on Close")
+	}
+
+	config.CacheJanitorInterval = -1
+	detector = NewDetector(config).(*defaultDetector)
+	if _, err := detector.DetectEncoding([]byte("janitor sample")); err != nil {
+		t.Fatal(err)
+	}
+	if detector.cache.janitorRunning() {
+		t.Error("Expected no janitor with negative interval")
+	}
+}
+
+// TestTransientDetectorsStartNoGoroutines 测试按调用创建检测器的函数不遗留后台协程
+func TestTransientDetectorsStartNoGoroutines(t *testing.T) {
+	// 只统计检测缓存的清理协程：chardet 的短期工作协程在调用返回后仍可能在退出中，总协程数不稳定
+	before := janitorGoroutines()
+	for i := 0; i < 50; i++ {
+		data := []byte(fmt.Sprint("key=value", i, "\n"))
+		if _, err := ConvertProperties(data, nil); err != nil {
+			t.Fatal(err)
+		}
+		BruteForceDecode(data, EncodingUTF8)
+	}
+	if after := janitorGoroutines(); after > before {
+		t.Errorf("Expected no new cache janitor goroutines, had %d before and %d after", before, after)
+	}
+}
+
+// janitorGoroutines 返回正在运行的检测缓存清理协程数量
+func janitorGoroutines() int {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return strings.Count(string(buf[:n]), "(*detectionCache).runJanitor")
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// basicDetector 只实现 Detector 基本方法、不实现任何扩展接口的自定义检测器
+type basicDetector struct{}
+
+func (basicDetector) DetectEncoding(data []byte) (*DetectionResult, error) {
+	return &DetectionResult{Encoding: EncodingGBK, Confidence: 1.0}, nil
+}
+
+func (d basicDetector) DetectFileEncoding(filename string) (*DetectionResult, error) {
+	return d.DetectEncoding(nil)
+}
+
+func (basicDetector) DetectBestEncoding(data []byte) (string, error) {
+	return EncodingGBK, nil
+}
+
+func (d basicDetector) SmartDetectEncoding(data []byte) (*DetectionResult, error) {
+	return d.DetectEncoding(data)
+}
+
+// TestExtensionInterfaces 测试只实现基本接口的自定义检测器：处理器退回到基本方法或返回错误
+func TestExtensionInterfaces(t *testing.T) {
+	config := GetDefaultProcessorConfig()
+	config.Detector = basicDetector{}
+	processor := NewProcessor(config)
+
+	gbk, err := NewDefault().Convert([]byte("自定义检测器"), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := processor.(ContextSmartConverter).SmartConvertContext(context.Background(), gbk, EncodingUTF8)
+	if err != nil || string(result.Data) != "自定义检测器" {
+		t.Fatalf("Unexpected smart conversion %v (%v)", result, err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := processor.(ContextDetector).DetectEncodingContext(cancelled, gbk); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	detection, replay, err := processor.(ReaderDetector).DetectReaderEncoding(bytes.NewReader(gbk))
+	if err != nil || detection.Encoding != EncodingGBK {
+		t.Errorf("Unexpected reader detection %v (%v)", detection, err)
+	}
+	if data, _ := io.ReadAll(replay); !bytes.Equal(data, gbk) {
+		t.Errorf("Expected replayed data, got %q", data)
+	}
+	if _, err := processor.(CandidateDetector).DetectAllEncodings(gbk); err == nil {
+		t.Error("Expected error from detector without CandidateDetector")
+	}
+	if _, err := processor.(ExplainingDetector).ExplainDetection(gbk); err == nil {
+		t.Error("Expected error from detector without ExplainingDetector")
+	}
+}
+
+// smartOnlyDetector 只支持智能检测的自定义检测器（普通检测总是失败）
+type smartOnlyDetector struct {
+	Detector
+	calls int
+}
+
+func (d *smartOnlyDetector) DetectEncoding(data []byte) (*DetectionResult, error) {
+	return nil, ErrDetectionFailed
+}
+
+func (d *smartOnlyDetector) SmartDetectEncoding(data []byte) (*DetectionResult, error) {
+	d.calls++
+	return &DetectionResult{Encoding: EncodingGBK, Confidence: 1.0}, nil
+}
+
+func TestCustomSmartDetector(t *testing.T) {
+	detector := &smartOnlyDetector{Detector: NewDetector()}
+	config := GetDefaultProcessorConfig()
+	config.Detector = detector
+	config.SmartDetection = true
+
+	gbk, err := NewDefault().Convert([]byte("短中文"), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	if err := os.WriteFile(input, gbk, 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := NewFileProcessor(config).ProcessFile(input, filepath.Join(dir, "output.txt"), &FileProcessOptions{
+		TargetEncoding:    EncodingUTF8,
+		OverwriteExisting: true,
+	})
+	if err != nil || result.SourceEncoding != EncodingGBK {
+		t.Fatalf("Expected file processor to use the custom smart detector, got %+v (%v)", result, err)
+	}
+
+	var output bytes.Buffer
+	if _, err := NewStreamProcessor(config).ProcessReaderWriter(context.Background(), bytes.NewReader(gbk), &output, &StreamOptions{
+		TargetEncoding:      EncodingUTF8,
+		DetectionSampleSize: DefaultSampleSize,
+	}); err != nil || output.String() != "短中文" {
+		t.Fatalf("Expected stream processor to use the custom smart detector, got %q (%v)", output.String(), err)
+	}
+	if detector.calls != 2 {
+		t.Errorf("Expected 2 smart detections, got %d", detector.calls)
+	}
+}
+
+// TestEscapeSequenceEncodings 测试 ISO-2022-JP、ISO-2022-KR、HZ-GB-2312 的检测与转换
+func TestEscapeSequenceEncodings(t *testing.T) {
+	processor := NewDefault()
+	for _, tc := range []struct {
+		encoding string
+		text     string
+	}{
+		{EncodingISO2022JP, "件名: 会議の議事録\n本文はこちらです。"},
+		{EncodingISO2022KR, "제목: 회의록\n본문입니다."},
+		{EncodingHZGB2312, "主题：会议纪要\n正文如下。"},
+	} {
+		data, err := processor.Convert([]byte(tc.text), EncodingUTF8, tc.encoding)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.encoding, err)
+		}
+
+		for _, detect := range []func([]byte) (*DetectionResult, error){processor.DetectEncoding, processor.SmartDetectEncoding} {
+			result, err := detect(data)
+			if err != nil {
+				t.Fatalf("%s: %v", tc.encoding, err)
+			}
+			if result.Encoding != tc.encoding || result.Details.Method != "escape_sequence" {
+				t.Errorf("Expected %s by escape sequence, got %s by %s", tc.encoding, result.Encoding, result.Details.Method)
+			}
+		}
+
+		converted, err := processor.SmartConvertString(string(data), EncodingUTF8)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.encoding, err)
+		}
+		if converted.Text != tc.text {
+			t.Errorf("%s: unexpected conversion %q", tc.encoding, converted.Text)
+		}
+	}
+}
+
+// TestStreamEscapeSequenceEncodings 测试有状态编码分块流式转换的结果与一次性转换一致
+func TestStreamEscapeSequenceEncodings(t *testing.T) {
+	processor := NewDefault()
+	for _, tc := range []struct {
+		encoding string
+		text     string
+	}{
+		{EncodingISO2022JP, strings.Repeat("件名: 会議の議事録 abc\n", 200)},
+		{EncodingISO2022KR, strings.Repeat("제목: 회의록 abc\n", 200)},
+		{EncodingHZGB2312, strings.Repeat("主题：会议纪要 abc\n", 200)},
+	} {
+		encoded, err := processor.Convert([]byte(tc.text), EncodingUTF8, tc.encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, dir := range []struct{ from, to string }{{tc.encoding, EncodingUTF8}, {EncodingUTF8, tc.encoding}} {
+			input := encoded
+			if dir.from == EncodingUTF8 {
+				input = []byte(tc.text)
+			}
+			expected, err := processor.Convert(input, dir.from, dir.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, size := range []int{7, 1000} {
+				var output bytes.Buffer
+				result, err := NewStreamProcessor(nil).ProcessReaderWriter(context.Background(), bytes.NewReader(input), &output, &StreamOptions{
+					SourceEncoding: dir.from,
+					TargetEncoding: dir.to,
+					BufferSize:     size,
+				})
+				if err != nil {
+					t.Fatalf("%s->%s with %d-byte chunks: %v", dir.from, dir.to, size, err)
+				}
+				if !bytes.Equal(output.Bytes(), expected) || result.BytesWritten != int64(len(expected)) {
+					t.Errorf("%s->%s with %d-byte chunks: got %d bytes, single-shot %d", dir.from, dir.to, size, output.Len(), len(expected))
+				}
+			}
+		}
+	}
+}
+
+// TestValidateBytes 测试按声明的编码校验数据并报告无效字节序列
+func TestValidateBytes(t *testing.T) {
+	validator := NewValidator()
+
+	gbk, _ := NewDefault().Convert([]byte("中文测试"), EncodingUTF8, EncodingGBK)
+	for _, tc := range []struct {
+		encoding string
+		data     []byte
+	}{
+		{EncodingGBK, gbk},
+		{EncodingUTF8, []byte("替换字符\uFFFD有效")},
+		{EncodingISO2022JP, []byte("\x1b$B$3$s\x1b(B")},
+	} {
+		result, err := validator.ValidateBytes(tc.data, tc.encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Valid || result.ViolationCount != 0 {
+			t.Errorf("%s: expected valid data, got %+v", tc.encoding, result)
+		}
+	}
+
+	result, err := validator.ValidateBytes([]byte("ok\xff\xfeok\xe4\xb8"), "utf8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || result.Encoding != EncodingUTF8 || result.ViolationCount != 3 {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	if v := result.Violations[2]; v.Offset != 6 || !bytes.Equal(v.Bytes, []byte("\xe4\xb8")) || v.String() != "offset 6: E4 B8" {
+		t.Errorf("Unexpected violation %v", v)
+	}
+
+	// 只报告前 MaxViolations 处，总数仍完整统计
+	limited := NewValidator(&ValidatorConfig{MaxViolations: 1})
+	result, _ = limited.ValidateBytes([]byte("\xffa\xffb\xff"), EncodingGBK)
+	if result.ViolationCount != 3 || len(result.Violations) != 1 || result.Violations[0].Offset != 0 {
+		t.Errorf("Unexpected limited result %+v", result)
+	}
+
+	if _, err := validator.ValidateBytes(nil, "no-such-encoding"); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("Expected ErrUnsupportedEncoding, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "hebrew.txt")
+	os.WriteFile(path, []byte("abc\xa1 \xe9"), 0644)
+	result, err = validator.ValidateFile(path, "ISO-8859-8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ViolationCount != 1 || result.Violations[0].Offset != 3 {
+		t.Errorf("Unexpected file result %+v", result)
+	}
+}
+
+// TestStrictConversionErrorPosition 测试严格模式转换失败时报告出错字符的位置
+func TestStrictConversionErrorPosition(t *testing.T) {
+	processor := NewStrictMode()
+	source := "\ufeffline one\nnaïve 中文"
+
+	_, err := processor.Convert([]byte(source), EncodingUTF8, EncodingISO88591)
+	var encErr *EncodingError
+	if !errors.As(err, &encErr) {
+		t.Fatalf("Expected EncodingError, got %v", err)
+	}
+	// 偏移按原始数据（包括 BOM）计算，列号按字符计
+	if encErr.ByteOffset != 19 || encErr.Line != 2 || encErr.Column != 7 || !bytes.Equal(encErr.Bytes, []byte("中")) {
+		t.Errorf("Unexpected position: offset %d, line %d, column %d, bytes % X", encErr.ByteOffset, encErr.Line, encErr.Column, encErr.Bytes)
+	}
+	if !strings.Contains(err.Error(), "line 2, column 7 (byte offset 19: E4 B8 AD)") {
+		t.Errorf("Unexpected error message %q", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "source.txt")
+	os.WriteFile(path, []byte(source), 0644)
+	config := GetDefaultProcessorConfig()
+	config.ConverterConfig.StrictMode = true
+	_, err = NewFileProcessor(config).ProcessFile(path, path+".out", &FileProcessOptions{
+		SourceEncoding: EncodingUTF8,
+		TargetEncoding: EncodingISO88591,
+	})
+	if !errors.As(err, &encErr) || encErr.File != path || encErr.Line != 2 {
+		t.Errorf("Expected file position in error, got %v", err)
+	}
+
+	// 流式转换报告流中的字节偏移
+	var output bytes.Buffer
+	_, err = NewStreamProcessor(nil).ProcessReaderWriter(context.Background(), strings.NewReader(strings.Repeat("a", 100)+"中"), &output, &StreamOptions{
+		SourceEncoding: EncodingUTF8,
+		TargetEncoding: EncodingISO88591,
+		BufferSize:     64,
+		StrictMode:     true,
+	})
+	if !errors.As(err, &encErr) || encErr.ByteOffset != 100 || encErr.Line != 0 {
+		t.Errorf("Expected stream byte offset 100, got %v", err)
+	}
+}
+
+// TestReplacementReport 测试非严格模式下报告替换位置并通过回调拒绝转换
+func TestReplacementReport(t *testing.T) {
+	source := []byte("a中b\xffc文")
+
+	converter := NewConverter()
+	result, err := converter.(OptionsConverter).ConvertWithOptions(source, EncodingUTF8, EncodingISO88591, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Data) != "a?b?c?" {
+		t.Errorf("Unexpected output %q", result.Data)
+	}
+	if result.Replacements == nil || result.Replacements.Count != 3 || !slices.Equal(result.Replacements.Offsets, []int64{1, 5, 7}) {
+		t.Errorf("Unexpected replacements %+v", result.Replacements)
+	}
+
+	clean, _ := converter.(OptionsConverter).ConvertWithOptions([]byte("abc"), EncodingUTF8, EncodingISO88591, nil)
+	if clean.Replacements != nil {
+		t.Errorf("Expected no replacements, got %+v", clean.Replacements)
+	}
+
+	// 记录偏移的数量受 MaxReplacementOffsets 限制，总数不受影响
+	config := GetDefaultConverterConfig()
+	config.MaxReplacementOffsets = 1
+	var seen []Replacement
+	config.ReplacementHook = func(replacement Replacement) error {
+		seen = append(seen, replacement)
+		return nil
+	}
+	result, err = NewConverter(config).(OptionsConverter).ConvertWithOptions(source, EncodingUTF8, EncodingISO88591, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Replacements.Count != 3 || len(result.Replacements.Offsets) != 1 || len(seen) != 3 {
+		t.Errorf("Unexpected replacements %+v, hook calls %d", result.Replacements, len(seen))
+	}
+	if seen[0].Rune != '中' || seen[0].Invalid || !seen[1].Invalid || seen[2].Column != 6 {
+		t.Errorf("Unexpected replacement details %+v", seen)
+	}
+
+	// 回调返回错误时中止转换
+	errLossy := errors.New("lossy conversion")
+	config.ReplacementHook = func(replacement Replacement) error {
+		if replacement.Invalid {
+			return errLossy
+		}
+		return nil
+	}
+	_, err = NewConverter(config).Convert(source, EncodingUTF8, EncodingISO88591)
+	var encErr *EncodingError
+	if !errors.Is(err, errLossy) || !errors.As(err, &encErr) || encErr.ByteOffset != 5 {
+		t.Errorf("Expected hook error at byte 5, got %v", err)
+	}
+}
+
+// TestInvalidBytePolicy 测试无效字节序列和无法表示字符的处理策略
+func TestInvalidBytePolicy(t *testing.T) {
+	gbk, _ := NewDefault().Convert([]byte("中文"), EncodingUTF8, EncodingGBK)
+	source := append(append([]byte("a\xff"), gbk...), "b"...)
+
+	tests := []struct {
+		policy   string
+		from     string
+		data     []byte
+		to       string
+		expected string
+	}{
+		{InvalidByteReplace, EncodingGBK, source, EncodingUTF8, "a*中文b"},
+		{InvalidByteSkip, EncodingGBK, source, EncodingUTF8, "a中文b"},
+		{InvalidByteHexEscape, EncodingGBK, source, EncodingUTF8, `a\xFF中文b`},
+		{InvalidByteNumericCharRef, EncodingGBK, source, EncodingUTF8, "a&#xFF;中文b"},
+		{InvalidByteHexEscape, EncodingUTF8, []byte("a\xffé中"), EncodingISO88591, "a\\xFF\xe9\\xE4\\xB8\\xAD"},
+		{InvalidByteNumericCharRef, EncodingUTF8, []byte("a\xffé中"), EncodingISO88591, "a&#xFF;\xe9&#x4E2D;"},
+		{InvalidByteSkip, EncodingUTF8, []byte("a\xffé中"), EncodingISO88591, "a\xe9"},
+	}
+	for _, tt := range tests {
+		config := GetDefaultConverterConfig()
+		config.InvalidBytePolicy = tt.policy
+		config.InvalidByteReplacement = '*'
+		result, err := NewConverter(config).(OptionsConverter).ConvertWithOptions(tt.data, tt.from, tt.to, nil)
+		if err != nil {
+			t.Fatalf("%s %s->%s: %v", tt.policy, tt.from, tt.to, err)
+		}
+		if string(result.Data) != tt.expected {
+			t.Errorf("%s %s->%s: expected %q, got %q", tt.policy, tt.from, tt.to, tt.expected, result.Data)
+		}
+		if result.Replacements == nil || result.Replacements.Offsets[0] != 1 {
+			t.Errorf("%s %s->%s: unexpected replacements %+v", tt.policy, tt.from, tt.to, result.Replacements)
+		}
+	}
+
+	// fail 策略返回带位置的错误，即使目标编码为 UTF-8
+	config := GetDefaultConverterConfig()
+	config.InvalidBytePolicy = InvalidByteFail
+	_, err := NewConverter(config).Convert(source, EncodingGBK, EncodingUTF8)
+	var encErr *EncodingError
+	if !errors.Is(err, ErrInvalidByteSequence) || !errors.As(err, &encErr) || encErr.ByteOffset != 1 || !bytes.Equal(encErr.Bytes, []byte{0xff}) {
+		t.Errorf("Expected invalid byte sequence at offset 1, got %v", err)
+	}
+	if _, err := NewConverter(config).Convert([]byte("中"), EncodingUTF8, EncodingISO88591); err == nil {
+		t.Error("Expected unmappable character to fail")
+	}
+}
@@ -0,0 +1,212 @@
+package encoding
+
+import (
+	"sort"
+)
+
+// EnsembleVote 集成投票中单个检测方法的投票
+type EnsembleVote struct {
+	// Method 投票的检测方法
+	Method string `json:"method"`
+
+	// Encoding 投票支持的编码
+	Encoding string `json:"encoding"`
+
+	// Vote 校准后的投票值
+	Vote float64 `json:"vote"`
+
+	// Weight 该方法在集成中的权重
+	Weight float64 `json:"weight"`
+}
+
+// ensembleCandidate 集成投票中的候选及其合并得分
+type ensembleCandidate struct {
+	candidate *DetectionCandidate
+	combined  float64
+	votes     []EnsembleVote
+}
+
+// ensembleConfig 获取生效的集成投票配置
+func (d *defaultDetector) ensembleConfig() *EnsembleConfig {
+	if d.config.Ensemble != nil {
+		return d.config.Ensemble
+	}
+	return GetDefaultEnsembleConfig()
+}
+
+// calibrate 按检测方法校准置信度
+func (d *defaultDetector) calibrate(method string, confidence float64) float64 {
+	cfg := d.ensembleConfig()
+	if factor, ok := cfg.Calibration[method]; ok {
+		confidence *= factor
+	}
+	if confidence > 1.0 {
+		confidence = 1.0
+	}
+	if confidence < 0 {
+		confidence = 0
+	}
+	return confidence
+}
+
+// DetectAllEncodings 返回所有候选编码及其得分组成（按综合得分降序）
+func (d *defaultDetector) DetectAllEncodings(data []byte) ([]*DetectionCandidate, error) {
+	if len(data) == 0 {
+		return nil, &EncodingError{
+			Op:  OperationDetect,
+			Err: ErrInvalidInput,
+		}
+	}
+
+	ranked, _ := d.rankCandidates(data)
+	if len(ranked) == 0 {
+		return nil, &EncodingError{
+			Op:       OperationDetect,
+			Encoding: "unknown",
+			Err:      ErrDetectionFailed,
+		}
+	}
+
+	candidates := make([]*DetectionCandidate, 0, len(ranked))
+	for _, entry := range ranked {
+		candidates = append(candidates, entry.candidate)
+	}
+	return candidates, nil
+}
+
+// detectByEnsemble 运行候选评分与传统检测两条路径并合并投票
+func (d *defaultDetector) detectByEnsemble(data []byte) *DetectionResult {
+	ranked, traditional := d.rankCandidates(data)
+	if len(ranked) == 0 {
+		return nil
+	}
+
+	winner := d.selectWinner(ranked, traditional)
+	breakdown := winner.candidate.Score
+
+	return &DetectionResult{
+		Encoding:   winner.candidate.Encoding,
+		Confidence: winner.candidate.Confidence,
+		Details: &DetectionDetails{
+			Method:           MethodEnsemble,
+			SourceMethod:     winner.candidate.Method,
+			Score:            winner.combined,
+			ScoreBreakdown:   &breakdown,
+			Votes:            winner.votes,
+			ConvertedPreview: winner.candidate.ConvertedText,
+			CandidatesCount:  len(ranked),
+			ContentClass:     winner.candidate.ContentClass,
+		},
+	}
+}
+
+// rankCandidates 收集、校准并评分所有候选，按合并得分降序返回
+func (d *defaultDetector) rankCandidates(data []byte) ([]*ensembleCandidate, *DetectionResult) {
+	candidates := d.getAllCandidates(data)
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	for _, candidate := range candidates {
+		candidate.Confidence = d.calibrate(candidate.Method, candidate.Confidence)
+	}
+	candidates = d.scoreCandidates(data, candidates)
+
+	traditional, _ := d.detectEncoding(data)
+	return d.combineCandidates(candidates, traditional), traditional
+}
+
+// combineCandidates 按集成配置合并各方法投票，返回按合并得分降序排列的候选
+func (d *defaultDetector) combineCandidates(candidates []*DetectionCandidate, traditional *DetectionResult) []*ensembleCandidate {
+	cfg := d.ensembleConfig()
+
+	var traditionalVote float64
+	if traditional != nil && traditional.Confidence >= cfg.TraditionalMinConfidence {
+		traditionalVote = d.calibrate(MethodTraditional, traditional.Confidence)
+	}
+
+	combined := make([]*ensembleCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		entry := &ensembleCandidate{
+			candidate: candidate,
+			votes: []EnsembleVote{{
+				Method:   candidate.Method,
+				Encoding: candidate.Encoding,
+				Vote:     candidate.Score.Total,
+				Weight:   cfg.ScoringWeight,
+			}},
+		}
+		entry.combined = cfg.ScoringWeight * candidate.Score.Total
+
+		if traditionalVote > 0 && candidate.Encoding == traditional.Encoding {
+			entry.votes = append(entry.votes, EnsembleVote{
+				Method:   MethodTraditional,
+				Encoding: traditional.Encoding,
+				Vote:     traditionalVote,
+				Weight:   cfg.TraditionalWeight,
+			})
+			candidate.Score.HintBonus = cfg.TraditionalWeight * traditionalVote
+			entry.combined += candidate.Score.HintBonus
+		}
+		candidate.Score.Total = entry.combined
+
+		combined = append(combined, entry)
+	}
+
+	sort.SliceStable(combined, func(i, j int) bool {
+		return combined[i].combined > combined[j].combined
+	})
+
+	return combined
+}
+
+// selectWinner 在已排序的候选中选出最佳者，得分相近时按平局规则判定
+func (d *defaultDetector) selectWinner(combined []*ensembleCandidate, traditional *DetectionResult) *ensembleCandidate {
+	cfg := d.ensembleConfig()
+
+	// 收集与最高分差距在 TieMargin 内的候选
+	tied := []*ensembleCandidate{combined[0]}
+	for _, entry := range combined[1:] {
+		if combined[0].combined-entry.combined > cfg.TieMargin {
+			break
+		}
+		tied = append(tied, entry)
+	}
+	if len(tied) == 1 {
+		return tied[0]
+	}
+
+	return d.breakTie(tied, traditional, cfg.TieBreaker)
+}
+
+// breakTie 按平局判定规则在得分相近的候选中选择
+func (d *defaultDetector) breakTie(tied []*ensembleCandidate, traditional *DetectionResult, rule string) *ensembleCandidate {
+	switch rule {
+	case TieBreakTraditional:
+		if traditional != nil {
+			for _, entry := range tied {
+				if entry.candidate.Encoding == traditional.Encoding {
+					return entry
+				}
+			}
+		}
+	case TieBreakConfidence:
+		best := tied[0]
+		for _, entry := range tied[1:] {
+			if entry.candidate.Confidence > best.candidate.Confidence {
+				best = entry
+			}
+		}
+		return best
+	default:
+		for _, preferred := range d.config.PreferredEncodings {
+			for _, entry := range tied {
+				if entry.candidate.Encoding == preferred {
+					return entry
+				}
+			}
+		}
+	}
+
+	return tied[0]
+}
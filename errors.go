@@ -3,21 +3,24 @@ package encoding
 import (
 	"errors"
 	"fmt"
+
+	"github.com/mirbf/encoding-processor/converter"
+	"github.com/mirbf/encoding-processor/detector"
 )
 
 // 预定义错误
 var (
 	// ErrUnsupportedEncoding 不支持的编码
-	ErrUnsupportedEncoding = errors.New("unsupported encoding")
+	ErrUnsupportedEncoding = converter.ErrUnsupportedEncoding
 
 	// ErrDetectionFailed 检测失败
-	ErrDetectionFailed = errors.New("encoding detection failed")
+	ErrDetectionFailed = detector.ErrDetectionFailed
 
 	// ErrConversionFailed 转换失败
 	ErrConversionFailed = errors.New("encoding conversion failed")
 
 	// ErrInvalidInput 无效输入
-	ErrInvalidInput = errors.New("invalid input data")
+	ErrInvalidInput = detector.ErrInvalidInput
 
 	// ErrFileTooLarge 文件过大
 	ErrFileTooLarge = errors.New("file too large")
@@ -33,6 +36,39 @@ var (
 
 	// ErrInvalidConfiguration 无效配置
 	ErrInvalidConfiguration = errors.New("invalid configuration")
+
+	// ErrClosed 组件已关闭
+	ErrClosed = errors.New("component closed")
+
+	// ErrEncodingNotAllowed 编码不在允许列表中
+	ErrEncodingNotAllowed = errors.New("encoding not allowed")
+
+	// ErrUnsupportedSchemaVersion 序列化数据的 schema 版本高于当前支持的版本
+	ErrUnsupportedSchemaVersion = errors.New("unsupported schema version")
+
+	// ErrTooManyErrors 非严格模式下替换字符和无效字节序列超过阈值
+	ErrTooManyErrors = errors.New("too many conversion errors")
+
+	// ErrLanguageMismatch 解码后的文本不像预期的语言
+	ErrLanguageMismatch = errors.New("decoded text does not match expected language")
+
+	// ErrInvalidRange 字节区间越界、重叠或转换结果超出区间长度
+	ErrInvalidRange = errors.New("invalid byte range")
+
+	// ErrSelfTestFailed 自检时转换结果与已知数据不一致
+	ErrSelfTestFailed = errors.New("self-test failed")
+
+	// ErrXattrUnsupported 平台或文件系统不支持扩展属性
+	ErrXattrUnsupported = errors.New("extended attributes not supported")
+
+	// ErrInvalidByteSequence 源数据包含无效字节序列（InvalidBytePolicy 为 fail）
+	ErrInvalidByteSequence = errors.New("invalid byte sequence")
+
+	// ErrBinaryFile 文件疑似二进制数据（见 IsProbablyBinary），拒绝转换
+	ErrBinaryFile = errors.New("file appears to be binary")
+
+	// ErrConversionInProgress 相同内容已被其他工作者声明、尚未转换完成（见 IdempotencyOptions）
+	ErrConversionInProgress = errors.New("conversion in progress by another worker")
 )
 
 // EncodingError 编码相关错误
@@ -41,13 +77,27 @@ type EncodingError struct {
 	Encoding string // 相关编码
 	File     string // 相关文件（可选）
 	Err      error  // 原始错误
+
+	// 严格模式转换失败时第一个无法转换的字符在源数据中的位置（Bytes 为 nil 表示位置未知；
+	// 流式转换只记录字节偏移，Line 和 Column 为 0）
+	ByteOffset int64  // 字节偏移（从 0 开始）
+	Line       int    // 行号（从 1 开始）
+	Column     int    // 列号（从 1 开始，按字符计）
+	Bytes      []byte // 无法转换的源字节序列
 }
 
 func (e *EncodingError) Error() string {
+	var position string
+	switch {
+	case e.Line > 0:
+		position = fmt.Sprintf(" at line %d, column %d (byte offset %d: % X)", e.Line, e.Column, e.ByteOffset, e.Bytes)
+	case e.Bytes != nil:
+		position = fmt.Sprintf(" at byte offset %d (% X)", e.ByteOffset, e.Bytes)
+	}
 	if e.File != "" {
-		return fmt.Sprintf("encoding %s in %s for file %s: %v", e.Encoding, e.Op, e.File, e.Err)
+		return fmt.Sprintf("encoding %s in %s for file %s%s: %v", e.Encoding, e.Op, e.File, position, e.Err)
 	}
-	return fmt.Sprintf("encoding %s in %s: %v", e.Encoding, e.Op, e.Err)
+	return fmt.Sprintf("encoding %s in %s%s: %v", e.Encoding, e.Op, position, e.Err)
 }
 
 func (e *EncodingError) Unwrap() error {
@@ -67,4 +117,23 @@ func (e *FileOperationError) Error() string {
 
 func (e *FileOperationError) Unwrap() error {
 	return e.Err
-}
\ No newline at end of file
+}
+
+// ErrorThresholdError 非严格模式下转换错误超过 MaxErrors 或 MaxErrorRate 时返回的错误
+//
+// 大量替换通常意味着源编码判断错误，调用方可据此改用其他编码重试。
+type ErrorThresholdError struct {
+	Errors       int64   // 替换字符与无效字节序列总数
+	Bytes        int64   // 已处理的源数据字节数
+	MaxErrors    int64   // 允许的最大错误数（0 表示不限制）
+	MaxErrorRate float64 // 允许的最大错误率（0 表示不限制）
+}
+
+func (e *ErrorThresholdError) Error() string {
+	return fmt.Sprintf("%v: %d errors in %d bytes (max errors %d, max error rate %.4f)",
+		ErrTooManyErrors, e.Errors, e.Bytes, e.MaxErrors, e.MaxErrorRate)
+}
+
+func (e *ErrorThresholdError) Unwrap() error {
+	return ErrTooManyErrors
+}
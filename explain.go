@@ -0,0 +1,202 @@
+package encoding
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// 检测说明中的检查项（按 DetectEncoding 的执行顺序）
+const (
+	ExplainCheckRule           = "rule"            // 检测覆盖规则
+	ExplainCheckCache          = "cache"           // 检测结果缓存
+	ExplainCheckBOM            = "bom"             // 字节顺序标记
+	ExplainCheckEscapeSequence = "escape_sequence" // 7 位编码的转义序列
+	ExplainCheckUTF8           = "utf8"            // UTF-8 有效性
+	ExplainCheckDeclaration    = "declaration"     // 文档内的编码声明
+	ExplainCheckBackend        = "backend"         // 字符集检测后端（默认 chardet）
+)
+
+// DetectionCheck 检测过程中的一项检查
+type DetectionCheck struct {
+	// Name 检查项（ExplainCheck* 常量）
+	Name string `json:"name"`
+
+	// Matched 该检查是否得出了结论
+	Matched bool `json:"matched"`
+
+	// Encoding 检查得出的编码
+	Encoding string `json:"encoding,omitempty"`
+
+	// Confidence 检查得出的置信度
+	Confidence float64 `json:"confidence,omitempty"`
+
+	// Detail 检查结果说明
+	Detail string `json:"detail"`
+}
+
+// DetectionExplanation 检测过程说明
+type DetectionExplanation struct {
+	// Result 与 DetectEncoding 相同的检测结果（检测失败时为 nil）
+	Result *DetectionResult `json:"result,omitempty"`
+
+	// Error 检测失败的原因
+	Error string `json:"error,omitempty"`
+
+	// SampleSize 参与检测的样本字节数
+	SampleSize int `json:"sample_size"`
+
+	// CacheHit 样本是否命中检测结果缓存
+	CacheHit bool `json:"cache_hit"`
+
+	// Checks 按执行顺序排列的全部检查（决定结果的检查之后的检查同样列出，便于比较）
+	Checks []DetectionCheck `json:"checks"`
+
+	// BackendMatches 字符集检测后端报告的全部候选及置信度（0-100）
+	BackendMatches []CharsetMatch `json:"backend_matches,omitempty"`
+
+	// Candidates 启发式评分的全部候选及得分组成（与 DetectAllEncodings 相同）
+	Candidates []*DetectionCandidate `json:"candidates,omitempty"`
+
+	// Decision 决定结果的检查项
+	Decision string `json:"decision,omitempty"`
+
+	// Reason 选择该结果的原因
+	Reason string `json:"reason"`
+}
+
+// ExplainDetection 按 DetectEncoding 的步骤检测编码，并报告每项检查的结果和选择胜出编码的原因
+func (d *defaultDetector) ExplainDetection(data []byte) (*DetectionExplanation, error) {
+	if len(data) == 0 {
+		return nil, &EncodingError{
+			Op:  OperationDetect,
+			Err: ErrInvalidInput,
+		}
+	}
+
+	sample := data
+	if d.config.SampleSize > 0 && len(sample) > d.config.SampleSize {
+		sample = sample[:d.config.SampleSize]
+	}
+	explanation := &DetectionExplanation{SampleSize: len(sample)}
+
+	// 规则作用于完整数据，其余检查作用于样本
+	rule := d.applyRules("", data)
+	explanation.addCheck(ExplainCheckRule, rule, "no detection rule matched", func(r *DetectionResult) string {
+		return fmt.Sprintf("detection rule %q matched", r.Details.Rule)
+	})
+
+	if d.cache != nil {
+		_, explanation.CacheHit = d.cache.get(d.generateCacheKey(sample))
+	}
+	if explanation.CacheHit {
+		explanation.Checks = append(explanation.Checks, DetectionCheck{Name: ExplainCheckCache, Detail: "sample found in detection cache"})
+	} else {
+		explanation.Checks = append(explanation.Checks, DetectionCheck{Name: ExplainCheckCache, Detail: "sample not cached"})
+	}
+
+	explanation.addCheck(ExplainCheckBOM, d.detectBOM(sample), "no byte order mark", func(*DetectionResult) string {
+		return "data starts with a byte order mark"
+	})
+	explanation.addCheck(ExplainCheckEscapeSequence, d.detectSpecialEncodings(sample), "no 7-bit escape sequences", func(*DetectionResult) string {
+		return "data contains 7-bit encoding escape sequences"
+	})
+	explanation.addCheck(ExplainCheckUTF8, d.detectUTF8(sample), utf8Detail(sample), func(r *DetectionResult) string {
+		if r.Details.HasNonASCII {
+			return "sample is valid UTF-8 with non-ASCII characters"
+		}
+		return "sample is pure ASCII, reported as UTF-8"
+	})
+	explanation.addCheck(ExplainCheckDeclaration, d.detectDeclaration(sample), "no usable charset declaration", func(r *DetectionResult) string {
+		return fmt.Sprintf("document declares %s and decodes without loss", r.Encoding)
+	})
+	d.explainBackend(explanation, sample)
+
+	explanation.Candidates, _ = d.DetectAllEncodings(sample)
+
+	result, err := d.detectEncoding(data)
+	explanation.Result = result
+	if err != nil {
+		explanation.Error = err.Error()
+	}
+	explanation.explainDecision()
+	return explanation, nil
+}
+
+// addCheck 记录一项检查的结果（result 为 nil 表示未得出结论）
+func (e *DetectionExplanation) addCheck(name string, result *DetectionResult, miss string, hit func(*DetectionResult) string) {
+	check := DetectionCheck{Name: name, Detail: miss}
+	if result != nil {
+		check.Matched = true
+		check.Encoding = result.Encoding
+		check.Confidence = result.Confidence
+		check.Detail = hit(result)
+	}
+	e.Checks = append(e.Checks, check)
+}
+
+// explainBackend 记录字符集检测后端的全部候选，以及按优先编码、支持列表和最小置信度的选择过程
+func (d *defaultDetector) explainBackend(explanation *DetectionExplanation, sample []byte) {
+	check := DetectionCheck{Name: ExplainCheckBackend}
+	matches, err := d.backend().DetectAll(sample)
+	explanation.BackendMatches = matches
+
+	best := d.selectBestResult(matches)
+	if err != nil || best == nil {
+		check.Detail = "charset backend reported no candidates"
+		if err != nil {
+			check.Detail = fmt.Sprintf("charset backend failed: %v", err)
+		}
+		explanation.Checks = append(explanation.Checks, check)
+		return
+	}
+
+	// 优先编码列表中的候选先于置信度最高的候选
+	check.Encoding, check.Confidence = best.Encoding, best.Confidence
+	chosen := fmt.Sprintf("highest-confidence candidate %s of %d", best.Encoding, len(matches))
+	if best.Details.Charset != matches[0].Charset {
+		chosen = fmt.Sprintf("preferred encoding %s (confidence %.2f) chosen over highest-confidence %s (%.2f)",
+			best.Encoding, best.Confidence, d.normalizeEncodingName(matches[0].Charset), float64(matches[0].Confidence)/100)
+	}
+	switch minConfidence := d.config.MinConfidenceFor(best.Encoding); {
+	case !d.isEncodingSupported(best.Encoding):
+		check.Detail = chosen + ", but it is not in the supported encodings"
+	case best.Confidence < minConfidence:
+		check.Detail = fmt.Sprintf("%s, but its confidence is below the minimum %.2f", chosen, minConfidence)
+	default:
+		check.Matched = true
+		check.Detail = chosen
+	}
+	explanation.Checks = append(explanation.Checks, check)
+}
+
+// explainDecision 以第一个得出结论的检查作为决定结果的检查
+func (e *DetectionExplanation) explainDecision() {
+	for _, check := range e.Checks {
+		if check.Matched {
+			e.Decision = check.Name
+			e.Reason = check.Detail
+			break
+		}
+	}
+
+	switch {
+	case e.Result == nil && e.Decision == "":
+		e.Reason = "no check produced an acceptable result; " + e.Checks[len(e.Checks)-1].Detail
+	case e.Result == nil:
+		e.Reason = fmt.Sprintf("%s, but detection failed: %s", e.Reason, e.Error)
+	case e.CacheHit:
+		e.Reason += " (result served from cache)"
+	}
+}
+
+// utf8Detail 返回 UTF-8 检查未通过时的说明（第一个无效字节的偏移）
+func utf8Detail(sample []byte) string {
+	for offset := 0; offset < len(sample); {
+		r, size := utf8.DecodeRune(sample[offset:])
+		if r == utf8.RuneError && size <= 1 {
+			return fmt.Sprintf("invalid UTF-8 at byte offset %d", offset)
+		}
+		offset += size
+	}
+	return "sample is not valid UTF-8"
+}
@@ -0,0 +1,68 @@
+package encoding
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+)
+
+// 扩展接口（ContextDetector、ContextConverter 等）的调用辅助：组件未实现扩展接口时
+// 退回到基本接口，无法退回时返回错误
+
+// detectEncodingContext 检测器实现 ContextDetector 时按上下文检测，否则在检测前后检查上下文
+func detectEncodingContext(ctx context.Context, d ByteDetector, data []byte) (*DetectionResult, error) {
+	if cd, ok := d.(ContextDetector); ok {
+		return cd.DetectEncodingContext(ctx, data)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, &EncodingError{Op: OperationDetect, Err: err}
+	}
+	result, err := d.DetectEncoding(data)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, &EncodingError{Op: OperationDetect, Err: ctxErr}
+	}
+	return result, err
+}
+
+// detectReaderEncoding 读取最多 sampleSize 字节检测编码，返回检测结果和重放已读取数据的读取器
+//
+// 返回的读取器先输出已读取的样本，再输出 r 的剩余数据；检测失败时读取器仍然有效。
+func detectReaderEncoding(d ByteDetector, r io.Reader, sampleSize int) (*DetectionResult, io.Reader, error) {
+	if sampleSize <= 0 {
+		sampleSize = DefaultSampleSize
+	}
+	sample := make([]byte, sampleSize)
+	n, err := io.ReadFull(r, sample)
+	sample = sample[:n]
+	replay := io.MultiReader(bytes.NewReader(sample), r)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, replay, &EncodingError{
+			Op:  OperationDetect,
+			Err: fmt.Errorf("failed to read sample: %w", err),
+		}
+	}
+
+	result, err := d.DetectEncoding(sample)
+	return result, replay, err
+}
+
+// convertContext 转换器实现 ContextConverter 时按上下文转换，否则在转换前后检查上下文
+func convertContext(ctx context.Context, c ByteConverter, data []byte, from, to string) ([]byte, error) {
+	if cc, ok := c.(ContextConverter); ok {
+		return cc.ConvertContext(ctx, data, from, to)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, &EncodingError{Op: OperationConvert, Encoding: fmt.Sprintf("%s->%s", from, to), Err: err}
+	}
+	result, err := c.Convert(data, from, to)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, &EncodingError{Op: OperationConvert, Encoding: fmt.Sprintf("%s->%s", from, to), Err: ctxErr}
+	}
+	return result, err
+}
+
+// unsupportedExtension 返回组件未实现所需扩展接口的错误
+func unsupportedExtension(op string, component interface{}, extension string) error {
+	return &EncodingError{Op: op, Err: fmt.Errorf("%T does not implement %s", component, extension)}
+}
@@ -1,6 +1,10 @@
 package encoding
 
-import "log"
+import (
+	"fmt"
+	"io"
+	"log"
+)
 
 // 工厂函数
 
@@ -13,9 +17,10 @@ func NewDefault() Processor {
 func NewDefaultWithMetrics() (Processor, MetricsCollector) {
 	config := GetDefaultProcessorConfig()
 	config.EnableMetrics = true
+	metrics := NewMetricsCollector()
+	config.Metrics = metrics
 	
 	processor := NewProcessor(config)
-	metrics := NewMetricsCollector()
 	
 	return processor, metrics
 }
@@ -35,13 +40,14 @@ func NewWithLogger(logger Logger) Processor {
 // NewWithConfig 使用自定义配置创建处理器
 func NewWithConfig(detectorConfig *DetectorConfig, converterConfig *ConverterConfig) Processor {
 	config := &ProcessorConfig{
-		DetectorConfig:  detectorConfig,
-		ConverterConfig: converterConfig,
-		EnableMetrics:   true,
-		LogLevel:        "info",
-		Logger:          nil,
-		TempDir:         "",
-		MaxFileSize:     DefaultMaxFileSize,
+		DetectorConfig:     detectorConfig,
+		ConverterConfig:    converterConfig,
+		EnableMetrics:      true,
+		LogLevel:           "info",
+		Logger:             nil,
+		TempDir:            "",
+		MaxFileSize:        DefaultMaxFileSize,
+		StreamingThreshold: DefaultStreamingThreshold,
 	}
 	return NewProcessor(config)
 }
@@ -55,6 +61,11 @@ func NewQuick() Processor {
 
 // NewForCLI 创建适合命令行工具的处理器
 func NewForCLI() Processor {
+	return NewProcessor(cliProcessorConfig())
+}
+
+// cliProcessorConfig 命令行工具使用的处理器配置（NewForCLI 和 RunFilter 共用）
+func cliProcessorConfig() *ProcessorConfig {
 	config := GetDefaultProcessorConfig()
 	
 	// 命令行工具通常需要更详细的检测
@@ -66,7 +77,7 @@ func NewForCLI() Processor {
 	config.ConverterConfig.StrictMode = false
 	config.ConverterConfig.BufferSize = 32768
 	
-	return NewProcessor(config)
+	return config
 }
 
 // NewForWebService 创建适合 Web 服务的处理器
@@ -77,6 +88,7 @@ func NewForWebService() Processor {
 	config.DetectorConfig.SampleSize = 4096
 	config.DetectorConfig.EnableCache = true
 	config.DetectorConfig.CacheSize = 5000
+	config.DetectorConfig.PrescoreSize = 1024 // 智能检测先按 1KB 切片为候选评分
 	
 	// 启用性能监控
 	config.EnableMetrics = true
@@ -106,6 +118,11 @@ func NewForBatchProcessing() Processor {
 
 // NewHighPerformance 创建高性能处理器
 func NewHighPerformance() Processor {
+	return NewProcessor(highPerformanceConfig())
+}
+
+// highPerformanceConfig 获取高性能处理器配置
+func highPerformanceConfig() *ProcessorConfig {
 	config := GetDefaultProcessorConfig()
 	
 	// 高性能配置
@@ -120,6 +137,23 @@ func NewHighPerformance() Processor {
 	config.EnableMetrics = true
 	config.MaxFileSize = 1024 * 1024 * 1024 // 1GB
 	
+	return config
+}
+
+// NewHighPerformanceCalibrated 创建高性能处理器，并在启动时运行一次简短的吞吐量校准
+//
+// sample 为有代表性的工作负载（nil 时使用内置的合成负载），校准失败时使用 NewHighPerformance 的默认配置。
+func NewHighPerformanceCalibrated(sample io.Reader) Processor {
+	config := highPerformanceConfig()
+
+	if sample == nil {
+		sample = calibrationWorkload()
+	}
+	if tuned, err := AutoTune(sample); err == nil {
+		config.ConverterConfig.BufferSize = tuned.BufferSize
+		config.ConverterConfig.ChunkSize = tuned.ChunkSize
+	}
+
 	return NewProcessor(config)
 }
 
@@ -136,6 +170,7 @@ func NewMemoryEfficient() Processor {
 	config.ConverterConfig.MaxMemoryUsage = 10 * 1024 * 1024 // 10MB
 	
 	config.MaxFileSize = 50 * 1024 * 1024 // 50MB
+	config.StreamingThreshold = 8 * 1024 * 1024 // 8MB 以上的文件使用流式处理
 	
 	return NewProcessor(config)
 }
@@ -177,11 +212,7 @@ func NewSmartProcessor(config ...*ProcessorConfig) Processor {
 		}
 	}
 	
-	return &defaultProcessor{
-		config:    cfg,
-		detector:  NewDetector(cfg.DetectorConfig),
-		converter: NewConverter(cfg.ConverterConfig),
-	}
+	return NewProcessor(cfg)
 }
 
 // NewZipFileProcessor 专门用于ZIP文件名检测的处理器
@@ -207,23 +238,31 @@ func NewZipFileProcessor() Processor {
 	return NewSmartProcessor(config)
 }
 
-// 默认日志记录器实现
+// 默认日志记录器实现（键值对输出为 key=value）
 type defaultLogger struct{}
 
 func (l *defaultLogger) Debug(msg string, fields ...interface{}) {
-	log.Printf("[DEBUG] "+msg, fields...)
+	l.print("DEBUG", msg, fields)
 }
 
 func (l *defaultLogger) Info(msg string, fields ...interface{}) {
-	log.Printf("[INFO] "+msg, fields...)
+	l.print("INFO", msg, fields)
 }
 
 func (l *defaultLogger) Warn(msg string, fields ...interface{}) {
-	log.Printf("[WARN] "+msg, fields...)
+	l.print("WARN", msg, fields)
 }
 
 func (l *defaultLogger) Error(msg string, fields ...interface{}) {
-	log.Printf("[ERROR] "+msg, fields...)
+	l.print("ERROR", msg, fields)
+}
+
+func (l *defaultLogger) print(level, msg string, fields []interface{}) {
+	line := fmt.Sprintf("[%s] %s", level, msg)
+	for i := 0; i+1 < len(fields); i += 2 {
+		line += fmt.Sprintf(" %v=%v", fields[i], fields[i+1])
+	}
+	log.Print(line)
 }
 
 // getDefaultLogger 获取默认日志记录器
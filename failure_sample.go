@@ -0,0 +1,169 @@
+package encoding
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultFailureSampleSize 默认失败样本的最大字节数
+const DefaultFailureSampleSize = 4096
+
+// FailureSample 检测失败或置信度偏低的输入样本，用于离线分析疑难数据
+type FailureSample struct {
+	// Time 采样时间
+	Time time.Time `json:"time"`
+
+	// Path 输入文件路径（仅结合路径检测时可用）
+	Path string `json:"path,omitempty"`
+
+	// Size 原始输入大小（字节）
+	Size int `json:"size"`
+
+	// Data 截断到 MaxSampleSize 的样本数据（Anonymize 时已脱敏，OmitData 时为空）
+	Data []byte `json:"data,omitempty"`
+
+	// Hash 原始输入的 SHA-256（十六进制，仅 Hash 为 true 时记录）
+	Hash string `json:"hash,omitempty"`
+
+	// Encoding 检测到的编码（检测失败时为错误中的编码或空）
+	Encoding string `json:"encoding,omitempty"`
+
+	// Confidence 检测置信度（检测失败时为 0）
+	Confidence float64 `json:"confidence"`
+
+	// Error 检测错误信息（置信度偏低但检测成功时为空）
+	Error string `json:"error,omitempty"`
+}
+
+// FailureSampleSink 失败样本的接收器
+//
+// 接收器可能被多个 goroutine 同时调用，实现需要自行保证并发安全。
+type FailureSampleSink interface {
+	WriteSample(sample *FailureSample) error
+}
+
+// FailureSamplingConfig 失败样本采集配置
+type FailureSamplingConfig struct {
+	// Sink 样本接收器（为 nil 时不采集）；写入错误会被忽略，不影响检测结果
+	Sink FailureSampleSink
+
+	// MaxSampleSize 样本最大字节数（默认 4096）
+	MaxSampleSize int
+
+	// LowConfidence 检测成功但置信度低于该值时同样采集（0 表示只采集检测失败）
+	LowConfidence float64
+
+	// Hash 是否记录原始输入的 SHA-256，便于去重
+	Hash bool
+
+	// Anonymize 是否脱敏：ASCII 字母替换为 x/X，数字替换为 0；
+	// 高位字节保持不变，非 UTF-8 数据中紧跟高位字节的字节（可能是 Big5、Shift_JIS、GBK 等
+	// 双字节编码的尾字节）也保持不变，编码特征不受影响
+	Anonymize bool
+
+	// OmitData 是否不记录样本数据（与 Hash 配合时只保留指纹）
+	OmitData bool
+}
+
+// jsonLinesSampleSink 以 JSON Lines 格式写出样本的接收器
+type jsonLinesSampleSink struct {
+	writer io.Writer
+	mutex  sync.Mutex
+}
+
+// NewJSONLinesSampleSink 创建以 JSON Lines 格式（每行一个样本）写入 w 的接收器
+func NewJSONLinesSampleSink(w io.Writer) FailureSampleSink {
+	return &jsonLinesSampleSink{writer: w}
+}
+
+// WriteSample 写出一个样本
+func (s *jsonLinesSampleSink) WriteSample(sample *FailureSample) error {
+	line, err := json.Marshal(sample)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, err = s.writer.Write(append(line, '\n'))
+	return err
+}
+
+// sampleFailure 在检测失败或置信度偏低时向配置的接收器提交样本
+func (d *defaultDetector) sampleFailure(path string, data []byte, result *DetectionResult, err error) {
+	sampling := d.config.FailureSampling
+	if sampling == nil || sampling.Sink == nil {
+		return
+	}
+
+	sample := &FailureSample{
+		Time: time.Now(),
+		Path: path,
+		Size: len(data),
+	}
+	if err != nil {
+		// 空输入不是疑难数据
+		if len(data) == 0 {
+			return
+		}
+		sample.Error = err.Error()
+		if encodingErr, ok := err.(*EncodingError); ok && encodingErr.Encoding != "unknown" {
+			sample.Encoding = encodingErr.Encoding
+		}
+	} else {
+		if result == nil || result.Confidence >= sampling.LowConfidence {
+			return
+		}
+		sample.Encoding = result.Encoding
+		sample.Confidence = result.Confidence
+	}
+
+	if sampling.Hash {
+		sample.Hash = fmt.Sprintf("%x", sha256.Sum256(data))
+	}
+	if !sampling.OmitData {
+		sample.Data = captureSample(data, sampling)
+	}
+
+	_ = sampling.Sink.WriteSample(sample)
+}
+
+// captureSample 截断并按需脱敏样本数据
+func captureSample(data []byte, sampling *FailureSamplingConfig) []byte {
+	size := sampling.MaxSampleSize
+	if size <= 0 {
+		size = DefaultFailureSampleSize
+	}
+	truncated := len(data) > size
+	if truncated {
+		data = data[:size]
+	}
+
+	captured := make([]byte, len(data))
+	copy(captured, data)
+	if sampling.Anonymize {
+		// 双字节编码的尾字节可能落在 ASCII 字母和数字范围（如 Big5 B36F、Shift_JIS 8341），
+		// 替换后会改变字符；UTF-8 的多字节序列只含高位字节，无需跳过
+		skipTrail := !utf8.Valid(trimIncompleteUTF8(captured, truncated))
+		for i := 0; i < len(captured); i++ {
+			switch b := captured[i]; {
+			case b >= 0x80:
+				if skipTrail {
+					i++
+				}
+			case b >= 'a' && b <= 'z':
+				captured[i] = 'x'
+			case b >= 'A' && b <= 'Z':
+				captured[i] = 'X'
+			case b >= '0' && b <= '9':
+				captured[i] = '0'
+			}
+		}
+	}
+	return captured
+}
@@ -1,17 +1,26 @@
 package encoding
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
 // defaultFileProcessor 实现 FileProcessor 接口
 type defaultFileProcessor struct {
 	processor Processor
+	rewriter  Converter // 不记录指标的转换器，用于改写注释头和编码声明时的往返转换
+	stream    *defaultStreamProcessor
 	config    *ProcessorConfig
+	logger    Logger
+	lifecycle lifecycle
 }
 
 // NewFileProcessor 创建新的文件处理器
@@ -20,27 +29,93 @@ func NewFileProcessor(config *ProcessorConfig) FileProcessor {
 		config = GetDefaultProcessorConfig()
 	}
 
+	processor := NewProcessor(config)
 	return &defaultFileProcessor{
-		processor: NewProcessor(config),
+		processor: processor,
+		rewriter:  NewConverter(withoutMetrics(config.ConverterConfig)),
+		stream:    &defaultStreamProcessor{processor: processor, config: config},
 		config:    config,
+		logger:    newLogger(config),
 	}
 }
 
+// withoutMetrics 返回不记录指标的转换器配置副本（未设置 Metrics 的配置保持不变）
+func withoutMetrics(config *ConverterConfig) *ConverterConfig {
+	if config == nil || config.Metrics == nil {
+		return config
+	}
+	copied := *config
+	copied.Metrics = nil
+	return &copied
+}
+
 // ProcessFile 处理文件（检测并转换编码）
+//
+// 在 Windows 上超长路径（包括 UNC 路径）按扩展长度形式访问，结果中的路径仍为普通形式。
 func (fp *defaultFileProcessor) ProcessFile(inputFile, outputFile string, options *FileProcessOptions) (*FileProcessResult, error) {
-	if options == nil {
-		options = &FileProcessOptions{
-			TargetEncoding:    EncodingUTF8,
-			MinConfidence:     DefaultMinConfidence,
-			CreateBackup:      true,
-			BackupSuffix:      DefaultBackupSuffix,
-			OverwriteExisting: false,
-			BufferSize:        DefaultBufferSize,
-			PreserveMode:      true,
-			PreserveTime:      true,
-			DryRun:            false,
+	if err := fp.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer fp.lifecycle.release()
+
+	_, span := startSpan(context.Background(), fp.config, SpanProcessFile)
+	result, err := fp.processFile(longPath(inputFile), longPath(outputFile), options)
+	if err == nil && result != nil {
+		span.SetAttributes(
+			SpanAttribute{Key: AttributeSourceEncoding, Value: result.SourceEncoding},
+			SpanAttribute{Key: AttributeTargetEncoding, Value: result.TargetEncoding},
+			SpanAttribute{Key: AttributeBytes, Value: result.BytesProcessed},
+			SpanAttribute{Key: AttributeConfidence, Value: result.DetectionConfidence},
+		)
+	}
+	endSpan(span, err)
+	if result != nil {
+		result.InputFile = inputFile
+		result.OutputFile = outputFile
+		result.BackupFile = displayPath(result.BackupFile)
+		result.SidecarFile = displayPath(result.SidecarFile)
+		result.TransliteratedFile = displayPath(result.TransliteratedFile)
+	}
+	return result, err
+}
+
+// processFile 检测并转换文件编码，设置了幂等选项时相同内容只转换一次（调用方负责获取生命周期）
+func (fp *defaultFileProcessor) processFile(inputFile, outputFile string, options *FileProcessOptions) (*FileProcessResult, error) {
+	if options != nil && options.Idempotency != nil && options.Idempotency.Store != nil && !options.DryRun {
+		return fp.processFileOnce(inputFile, outputFile, options)
+	}
+	return fp.produceFile(inputFile, outputFile, options)
+}
+
+// produceFile 检测并转换文件编码，按需为输出文件写入编码扩展属性
+func (fp *defaultFileProcessor) produceFile(inputFile, outputFile string, options *FileProcessOptions) (*FileProcessResult, error) {
+	result, err := fp.convertFile(inputFile, outputFile, options)
+	if err != nil || options == nil || options.DryRun {
+		return result, err
+	}
+
+	if options.WriteCharsetTag {
+		if err := WriteCharsetTag(outputFile, result.TargetEncoding); err != nil {
+			return nil, &FileOperationError{
+				Op:   "write_charset_tag",
+				File: outputFile,
+				Err:  err,
+			}
 		}
 	}
+	return result, nil
+}
+
+// convertFile 检测并转换文件编码
+func (fp *defaultFileProcessor) convertFile(inputFile, outputFile string, options *FileProcessOptions) (*FileProcessResult, error) {
+	if options == nil {
+		options = defaultFileProcessOptions()
+	}
+	if target := canonicalEncodingName(options.TargetEncoding); target != options.TargetEncoding {
+		resolved := *options
+		resolved.TargetEncoding = target
+		options = &resolved
+	}
 
 	start := time.Now()
 
@@ -54,8 +129,9 @@ func (fp *defaultFileProcessor) ProcessFile(inputFile, outputFile string, option
 		}
 	}
 
-	// 检查文件大小限制
-	if fp.config.MaxFileSize > 0 && inputInfo.Size() > fp.config.MaxFileSize {
+	// 检查文件大小硬限制
+	softLimit, hardLimit := fp.sizeLimits(options)
+	if hardLimit > 0 && inputInfo.Size() > hardLimit {
 		return nil, &FileOperationError{
 			Op:   "size_check",
 			File: inputFile,
@@ -74,6 +150,20 @@ func (fp *defaultFileProcessor) ProcessFile(inputFile, outputFile string, option
 		}
 	}
 
+	// 拒绝转换疑似二进制的文件
+	if !options.AllowBinary && isBinaryFile(inputFile) {
+		return nil, &FileOperationError{
+			Op:   "binary_check",
+			File: inputFile,
+			Err:  ErrBinaryFile,
+		}
+	}
+
+	// 超过软限制的文件改用流式处理
+	if softLimit > 0 && inputInfo.Size() > softLimit {
+		return fp.processFileStreaming(inputFile, outputFile, inputInfo, options)
+	}
+
 	// 如果是试运行模式，只检测编码
 	if options.DryRun {
 		return fp.dryRunProcess(inputFile, outputFile, options)
@@ -90,31 +180,56 @@ func (fp *defaultFileProcessor) ProcessFile(inputFile, outputFile string, option
 	}
 
 	// 检测编码
-	detection, err := fp.processor.DetectEncoding(data)
+	detection, err := fp.detectSource(inputFile, data, options)
 	if err != nil {
 		return nil, err
 	}
 
-	// 检查检测置信度
-	if detection.Confidence < options.MinConfidence {
-		return nil, &EncodingError{
-			Op:       OperationDetect,
-			Encoding: detection.Encoding,
-			File:     inputFile,
-			Err:      fmt.Errorf("detection confidence %.2f below threshold %.2f", detection.Confidence, options.MinConfidence),
+	// 确认解码后的文本符合预期语言（透传的数据无从确认，留待复查）
+	if !isPassthrough(detection) {
+		if err := fp.checkLanguage(inputFile, data, detection.Encoding, options); err != nil {
+			return nil, err
 		}
 	}
 
-	// 如果源编码和目标编码相同，只需复制文件
-	if detection.Encoding == options.TargetEncoding {
+	// 如果源编码和目标编码相同且无需调整换行符、BOM、注释头和编码声明，只需复制文件
+	rewriteDeclaration := options.CharsetDeclaration != "" && options.CharsetDeclaration != CharsetDeclarationPreserve
+	if fp.copiesUnchanged(detection, options) {
 		return fp.copyFile(inputFile, outputFile, inputInfo, options, detection)
 	}
 
 	// 转换编码
-	convertedData, err := fp.processor.Convert(data, detection.Encoding, options.TargetEncoding)
+	convertedData, trace, err := fp.convert(data, detection.Encoding, options.TargetEncoding)
 	if err != nil {
+		if encErr, ok := err.(*EncodingError); ok {
+			encErr.File = inputFile
+		}
 		return nil, err
 	}
+	var text []byte
+	if options.Transliteration != nil {
+		if text, err = fp.decodedText(data, convertedData, detection.Encoding, options.TargetEncoding); err != nil {
+			return nil, err
+		}
+	}
+	logReplacements(fp.logger, displayPath(inputFile), detection.Encoding, options.TargetEncoding, trace)
+	warnings := withFile(trace.warnings(), inputFile)
+	if isPassthrough(detection) {
+		warnings = append(warnings, passthroughWarning(inputFile))
+	}
+	if options.Provenance != nil {
+		convertedData, err = fp.applyProvenance(outputFile, convertedData, detection.Encoding, options)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if rewriteDeclaration {
+		convertedData, err = fp.applyCharsetDeclaration(outputFile, convertedData, options)
+		if err != nil {
+			return nil, err
+		}
+	}
+	convertedData = applyBOMPolicy(convertedData, options.TargetEncoding, options.BOMPolicy)
 
 	// 创建备份（如果需要）
 	var backupFile string
@@ -136,21 +251,303 @@ func (fp *defaultFileProcessor) ProcessFile(inputFile, outputFile string, option
 	}
 
 	// 写入转换后的数据
-	err = fp.writeFileWithRecovery(outputFile, convertedData, inputInfo, options, backupFile)
+	writeWarnings, err := fp.writeFileWithRecovery(outputFile, convertedData, inputInfo, options, backupFile)
 	if err != nil {
 		return nil, err
 	}
+	warnings = append(warnings, writeWarnings...)
 
-	return &FileProcessResult{
+	// 写入元数据旁路文件（如果需要）
+	var sidecarFile string
+	if options.WriteSidecar {
+		sidecarFile, err = writeSidecar(outputFile, data, convertedData, detection, options.TargetEncoding, backupFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &FileProcessResult{
 		InputFile:           inputFile,
 		OutputFile:          outputFile,
 		BackupFile:          backupFile,
+		SidecarFile:         sidecarFile,
 		SourceEncoding:      detection.Encoding,
 		TargetEncoding:      options.TargetEncoding,
 		BytesProcessed:      int64(len(data)),
 		ProcessingTime:      time.Since(start),
 		DetectionConfidence: detection.Confidence,
-	}, nil
+		Passthrough:         isPassthrough(detection),
+		LostRunes:           trace.lostHistogram(),
+		Warnings:            warnings,
+	}
+
+	// 由解码后的源文本生成 ASCII 音译副本（如果需要）
+	if options.Transliteration != nil {
+		result.TransliteratedFile, result.Untransliterated, err = writeTransliteration(outputFile, bytes.NewReader(text), options.Transliteration)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// decodedText 返回源数据解码后的 UTF-8 文本：目标编码为 UTF-8 时直接使用转换结果，否则按源编码解码一次
+func (fp *defaultFileProcessor) decodedText(data, converted []byte, sourceEncoding, targetEncoding string) ([]byte, error) {
+	if targetEncoding == EncodingUTF8 || targetEncoding == EncodingUTF8BOM {
+		return converted, nil
+	}
+	return fp.processor.Convert(data, sourceEncoding, EncodingUTF8)
+}
+
+// copiesUnchanged 检查文件是否无需转换，只需原样复制（源编码和目标编码相同且无需调整换行符、BOM、注释头和编码声明）
+func (fp *defaultFileProcessor) copiesUnchanged(detection *DetectionResult, options *FileProcessOptions) bool {
+	stripProvenance := options.Provenance != nil && options.Provenance.Strip
+	rewriteDeclaration := options.CharsetDeclaration != "" && options.CharsetDeclaration != CharsetDeclarationPreserve
+	return detection.Encoding == options.TargetEncoding && fp.preservesFinalNewline() && !fp.normalizesLineEndings() &&
+		!changesBOM(options.BOMPolicy) && !stripProvenance && !rewriteDeclaration
+}
+
+// estimateOutput 试运行时估算转换后的输出大小以及文件内容是否会改变
+//
+// data 为完整文件内容或开头样本，size 为文件大小；按样本估算时按转换前后的长度比例推算，
+// 样本转换失败时按原大小估算。
+func (fp *defaultFileProcessor) estimateOutput(data []byte, size int64, detection *DetectionResult, options *FileProcessOptions) (wouldChange bool, estimated int64) {
+	if fp.copiesUnchanged(detection, options) {
+		return false, size
+	}
+
+	converted, _, err := fp.convert(data, detection.Encoding, options.TargetEncoding)
+	if err != nil || len(data) == 0 {
+		return detection.Encoding != options.TargetEncoding, size
+	}
+	converted = applyBOMPolicy(converted, options.TargetEncoding, options.BOMPolicy)
+	if int64(len(data)) == size {
+		return !bytes.Equal(converted, data), int64(len(converted))
+	}
+	return !bytes.Equal(converted, data), size * int64(len(converted)) / int64(len(data))
+}
+
+// checkLanguage 按源编码解码数据并检查语言得分（未设置 ExpectedLanguage 时跳过）
+func (fp *defaultFileProcessor) checkLanguage(file string, data []byte, encoding string, options *FileProcessOptions) error {
+	if options.ExpectedLanguage == "" {
+		return nil
+	}
+
+	text, err := fp.processor.Convert(data, encoding, EncodingUTF8)
+	if err != nil {
+		return err
+	}
+
+	minScore := options.MinLanguageScore
+	if minScore <= 0 {
+		minScore = DefaultMinLanguageScore
+	}
+	if score := ValidateAsLanguage(string(text), options.ExpectedLanguage); score < minScore {
+		return &EncodingError{
+			Op:       OperationValidate,
+			Encoding: encoding,
+			File:     file,
+			Err:      fmt.Errorf("%w: %s score %.2f below threshold %.2f", ErrLanguageMismatch, options.ExpectedLanguage, score, minScore),
+		}
+	}
+	return nil
+}
+
+// convert 转换文件数据，处理器支持时同时返回转换统计（否则统计为 nil）
+func (fp *defaultFileProcessor) convert(data []byte, from, to string) ([]byte, *conversionTrace, error) {
+	if tc, ok := fp.processor.(tracedConverter); ok {
+		result, trace, err := tc.convertTraced(data, from, to)
+		if err != nil {
+			return nil, nil, err
+		}
+		return result, trace, nil
+	}
+
+	result, err := fp.processor.Convert(data, from, to)
+	return result, nil, err
+}
+
+// Drain 停止接受新的文件处理请求，等待进行中的文件处理完成后关闭底层处理器
+func (fp *defaultFileProcessor) Drain(ctx context.Context) error {
+	var closeErr error
+	if err := fp.lifecycle.drain(ctx, func() {
+		closeErr = closeComponent(ctx, fp.processor)
+	}); err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// Close 关闭文件处理器并等待进行中的文件处理完成
+func (fp *defaultFileProcessor) Close() error {
+	return fp.Drain(context.Background())
+}
+
+// detect 检测文件数据的编码（支持时结合路径匹配检测覆盖规则；指定了源编码时直接使用，
+// 启用 UseCharsetTag 时优先参考编码扩展属性，启用 SmartDetection 时使用智能检测）
+func (fp *defaultFileProcessor) detect(path string, data []byte, options *FileProcessOptions) (*DetectionResult, error) {
+	if options.SourceEncoding != "" {
+		return &DetectionResult{
+			Encoding:   canonicalEncodingName(options.SourceEncoding),
+			Confidence: 1.0,
+			Details:    &DetectionDetails{Method: MethodSpecified},
+		}, nil
+	}
+
+	if options.UseCharsetTag {
+		if tagged := detectCharsetTag(path, data); tagged != nil {
+			return tagged, nil
+		}
+	}
+
+	if fp.config.SmartDetection {
+		if spd, ok := fp.processor.(smartPathDetector); ok {
+			return spd.SmartDetectEncodingWithPath(path, data)
+		}
+		return fp.processor.SmartDetectEncoding(data)
+	}
+	if pd, ok := fp.processor.(pathDetector); ok {
+		return pd.DetectEncodingWithPath(path, data)
+	}
+	return fp.processor.DetectEncoding(data)
+}
+
+// detectSource 检测源编码并检查置信度
+//
+// 检测失败或置信度不足时，启用 FallbackToLatin1 则按 ISO-8859-1 透传，否则返回检测错误。
+func (fp *defaultFileProcessor) detectSource(path string, data []byte, options *FileProcessOptions) (*DetectionResult, error) {
+	detection, err := fp.detect(path, data, options)
+	if err == nil {
+		logDetection(fp.logger, displayPath(path), detection)
+	}
+	if err == nil && detection.Confidence < options.MinConfidence {
+		err = &EncodingError{
+			Op:       OperationDetect,
+			Encoding: detection.Encoding,
+			File:     path,
+			Err:      fmt.Errorf("detection confidence %.2f below threshold %.2f", detection.Confidence, options.MinConfidence),
+		}
+	}
+
+	var encodingErr *EncodingError
+	if err != nil && options.FallbackToLatin1 && errors.As(err, &encodingErr) && encodingErr.Op == OperationDetect {
+		fp.logger.Warn("detection failed, falling back to ISO-8859-1 passthrough", "file", displayPath(path), "error", err)
+		return &DetectionResult{
+			Encoding:   EncodingISO88591,
+			Confidence: 0,
+			Details:    &DetectionDetails{Method: MethodRawLatin1},
+		}, nil
+	}
+	return detection, err
+}
+
+// isPassthrough 检查检测结果是否为 ISO-8859-1 透传
+func isPassthrough(detection *DetectionResult) bool {
+	return detection.Details != nil && detection.Details.Method == MethodRawLatin1
+}
+
+// sizeLimits 获取本次调用生效的软限制（切换到流式处理）和硬限制（拒绝处理），0 表示不限制
+func (fp *defaultFileProcessor) sizeLimits(options *FileProcessOptions) (soft, hard int64) {
+	soft, hard = fp.config.StreamingThreshold, fp.config.MaxFileSize
+	if options.StreamingThreshold != 0 {
+		soft = options.StreamingThreshold
+	}
+	if options.MaxFileSize != 0 {
+		hard = options.MaxFileSize
+	}
+	if soft < 0 {
+		soft = 0
+	}
+	if hard < 0 {
+		hard = 0
+	}
+	return soft, hard
+}
+
+// applyProvenance 在转换后的数据中去除已有的转换来源注释头并插入新的注释头
+//
+// 注释头在 UTF-8 文本上处理，非 UTF-8 目标编码需要先解码再重新编码；
+// 往返转换使用不记录指标的转换器，一次文件处理只计一次转换。
+func (fp *defaultFileProcessor) applyProvenance(path string, data []byte, original string, options *FileProcessOptions) ([]byte, error) {
+	target := options.TargetEncoding
+	text := data
+	if target != EncodingUTF8 {
+		var err error
+		if text, err = fp.rewriter.Convert(data, target, EncodingUTF8); err != nil {
+			return nil, err
+		}
+	}
+
+	text = options.Provenance.addProvenanceHeader(path, text, original, target)
+
+	if target != EncodingUTF8 {
+		return fp.rewriter.Convert(text, EncodingUTF8, target)
+	}
+	return text, nil
+}
+
+// applyCharsetDeclaration 按策略改写或删除样式表的 @charset 声明，并去除 UTF-8 脚本开头的 BOM
+//
+// 与注释头相同，声明在 UTF-8 文本上处理，非 UTF-8 目标编码使用不记录指标的转换器解码再重新编码。
+func (fp *defaultFileProcessor) applyCharsetDeclaration(path string, data []byte, options *FileProcessOptions) ([]byte, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	target := options.TargetEncoding
+
+	if scriptExtensions[ext] {
+		if target == EncodingUTF8 {
+			return applyBOMPolicy(data, target, BOMStrip), nil
+		}
+		return data, nil
+	}
+	if !stylesheetExtensions[ext] {
+		return data, nil
+	}
+
+	text := data
+	if target != EncodingUTF8 {
+		var err error
+		if text, err = fp.rewriter.Convert(data, target, EncodingUTF8); err != nil {
+			return nil, err
+		}
+	}
+
+	charset := ""
+	if options.CharsetDeclaration == CharsetDeclarationRewrite && (target == EncodingUTF8 || !isUnicodeEncoding(target)) {
+		charset = target
+	}
+	text = RewriteCSSCharset(text, charset)
+
+	if target != EncodingUTF8 {
+		return fp.rewriter.Convert(text, EncodingUTF8, target)
+	}
+	return text, nil
+}
+
+// normalizesLineEndings 检查转换器配置是否需要统一换行符
+func (fp *defaultFileProcessor) normalizesLineEndings() bool {
+	cfg := fp.config.ConverterConfig
+	return cfg != nil && cfg.NormalizeLineEndings
+}
+
+// preservesFinalNewline 检查转换器配置是否保持末尾换行符不变
+func (fp *defaultFileProcessor) preservesFinalNewline() bool {
+	cfg := fp.config.ConverterConfig
+	return cfg == nil || cfg.FinalNewline == "" || cfg.FinalNewline == FinalNewlinePreserve
+}
+
+// defaultFileProcessOptions 返回默认文件处理选项
+func defaultFileProcessOptions() *FileProcessOptions {
+	return &FileProcessOptions{
+		TargetEncoding:    EncodingUTF8,
+		MinConfidence:     DefaultMinConfidence,
+		CreateBackup:      true,
+		BackupSuffix:      DefaultBackupSuffix,
+		OverwriteExisting: false,
+		BufferSize:        DefaultBufferSize,
+		PreserveMode:      true,
+		PreserveTime:      true,
+		DryRun:            false,
+	}
 }
 
 // ProcessFileInPlace 就地处理文件（直接修改源文件）
@@ -160,6 +557,11 @@ func (fp *defaultFileProcessor) ProcessFileInPlace(file string, options *FilePro
 
 // ProcessFileToBytes 读取文件并转换编码，返回字节数组
 func (fp *defaultFileProcessor) ProcessFileToBytes(filename, targetEncoding string) ([]byte, error) {
+	if err := fp.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer fp.lifecycle.release()
+
 	// 读取文件
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -203,11 +605,26 @@ func (fp *defaultFileProcessor) dryRunProcess(inputFile, outputFile string, opti
 	}
 
 	// 检测编码
-	detection, err := fp.processor.DetectEncoding(data)
+	detection, err := fp.detectSource(inputFile, data, options)
 	if err != nil {
 		return nil, err
 	}
 
+	// 预览转换差异（如果需要）
+	var diff *TextDiff
+	if options.DiffPreview {
+		diff, err = fp.previewDiff(data, detection.Encoding, options.TargetEncoding)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var warnings []Warning
+	if isPassthrough(detection) {
+		warnings = append(warnings, passthroughWarning(inputFile))
+	}
+	wouldChange, estimatedSize := fp.estimateOutput(data, int64(len(data)), detection, options)
+
 	return &FileProcessResult{
 		InputFile:           inputFile,
 		OutputFile:          outputFile,
@@ -217,9 +634,28 @@ func (fp *defaultFileProcessor) dryRunProcess(inputFile, outputFile string, opti
 		BytesProcessed:      int64(len(data)),
 		ProcessingTime:      time.Since(start),
 		DetectionConfidence: detection.Confidence,
+		Passthrough:         isPassthrough(detection),
+		Diff:                diff,
+		WouldChange:         wouldChange,
+		EstimatedSize:       estimatedSize,
+		Warnings:            warnings,
 	}, nil
 }
 
+// previewDiff 转换数据并解码转换结果，与原始文本比较生成差异摘要
+func (fp *defaultFileProcessor) previewDiff(data []byte, from, to string) (*TextDiff, error) {
+	converted, err := fp.processor.Convert(data, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if to != EncodingUTF8 {
+		if converted, err = fp.processor.Convert(converted, to, EncodingUTF8); err != nil {
+			return nil, err
+		}
+	}
+	return DiffConverted(data, converted, from), nil
+}
+
 // copyFile 复制文件（当源编码和目标编码相同时）
 func (fp *defaultFileProcessor) copyFile(inputFile, outputFile string, inputInfo os.FileInfo, options *FileProcessOptions, detection *DetectionResult) (*FileProcessResult, error) {
 	start := time.Now()
@@ -243,21 +679,49 @@ func (fp *defaultFileProcessor) copyFile(inputFile, outputFile string, inputInfo
 	}
 
 	// 写入文件
-	err = fp.writeFileWithRecovery(outputFile, data, inputInfo, options, backupFile)
+	warnings, err := fp.writeFileWithRecovery(outputFile, data, inputInfo, options, backupFile)
 	if err != nil {
 		return nil, err
 	}
+	if isPassthrough(detection) {
+		warnings = append(warnings, passthroughWarning(inputFile))
+	}
 
-	return &FileProcessResult{
+	// 写入元数据旁路文件（如果需要）
+	var sidecarFile string
+	if options.WriteSidecar {
+		sidecarFile, err = writeSidecar(outputFile, data, data, detection, options.TargetEncoding, backupFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &FileProcessResult{
 		InputFile:           inputFile,
 		OutputFile:          outputFile,
 		BackupFile:          backupFile,
+		SidecarFile:         sidecarFile,
 		SourceEncoding:      detection.Encoding,
 		TargetEncoding:      options.TargetEncoding,
 		BytesProcessed:      int64(len(data)),
 		ProcessingTime:      time.Since(start),
 		DetectionConfidence: detection.Confidence,
-	}, nil
+		Passthrough:         isPassthrough(detection),
+		Warnings:            warnings,
+	}
+
+	// 生成 ASCII 音译副本（如果需要）
+	if options.Transliteration != nil {
+		text, err := fp.processor.Convert(data, detection.Encoding, EncodingUTF8)
+		if err != nil {
+			return nil, err
+		}
+		result.TransliteratedFile, result.Untransliterated, err = writeTransliteration(outputFile, bytes.NewReader(text), options.Transliteration)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
 }
 
 // createBackup 创建备份文件
@@ -270,8 +734,8 @@ func (fp *defaultFileProcessor) createBackup(filename, suffix string) (string, e
 		backupFile = fmt.Sprintf("%s.%s%s", filename, timestamp, suffix)
 	}
 
-	// 复制文件到备份位置
-	data, err := ioutil.ReadFile(filename)
+	// 复制文件到备份位置（流式复制，避免大文件整体读入内存）
+	src, err := os.Open(filename)
 	if err != nil {
 		return "", &FileOperationError{
 			Op:   "read_for_backup",
@@ -279,8 +743,15 @@ func (fp *defaultFileProcessor) createBackup(filename, suffix string) (string, e
 			Err:  err,
 		}
 	}
+	defer src.Close()
 
-	err = ioutil.WriteFile(backupFile, data, 0644)
+	// 备份文件与原文件权限一致，避免内容以更宽松的权限暴露
+	perm := os.FileMode(0644)
+	if info, err := src.Stat(); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	dst, err := os.OpenFile(backupFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
 	if err != nil {
 		return "", &FileOperationError{
 			Op:   "create_backup",
@@ -289,30 +760,66 @@ func (fp *defaultFileProcessor) createBackup(filename, suffix string) (string, e
 		}
 	}
 
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(backupFile)
+		return "", &FileOperationError{
+			Op:   "create_backup",
+			File: backupFile,
+			Err:  err,
+		}
+	}
+	if err := dst.Close(); err != nil {
+		return "", &FileOperationError{
+			Op:   "create_backup",
+			File: backupFile,
+			Err:  err,
+		}
+	}
+
+	fp.logger.Info("backup created", "file", displayPath(filename), "backup", displayPath(backupFile))
 	return backupFile, nil
 }
 
-// writeFileWithRecovery 带恢复机制的文件写入
-func (fp *defaultFileProcessor) writeFileWithRecovery(filename string, data []byte, originalInfo os.FileInfo, options *FileProcessOptions, backupFile string) error {
-	// 创建临时文件
-	tempFile := filename + ".tmp"
-
-	// 写入临时文件
-	err := ioutil.WriteFile(tempFile, data, 0644)
+// writeFileWithRecovery 带恢复机制的文件写入，返回替换文件时产生的警告
+func (fp *defaultFileProcessor) writeFileWithRecovery(filename string, data []byte, originalInfo os.FileInfo, options *FileProcessOptions, backupFile string) ([]Warning, error) {
+	// 写入临时文件（写入前即使用最终权限）
+	mode, _ := outputFileMode(originalInfo, options)
+	out, err := createTempFile(filename, mode)
 	if err != nil {
-		return &FileOperationError{
+		return nil, &FileOperationError{
+			Op:   "write_temp",
+			File: filename,
+			Err:  err,
+		}
+	}
+	tempFile := out.Name()
+	_, err = out.Write(data)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempFile)
+		return nil, &FileOperationError{
 			Op:   "write_temp",
 			File: tempFile,
 			Err:  err,
 		}
 	}
 
-	// 设置文件权限
-	if options.PreserveMode && originalInfo != nil {
-		err = os.Chmod(tempFile, originalInfo.Mode())
+	return fp.replaceWithTemp(tempFile, filename, originalInfo, options, backupFile)
+}
+
+// replaceWithTemp 设置临时文件权限后原子替换目标文件，并按需恢复时间戳
+//
+// 时间戳无法恢复、被替换的文件带有扩展属性（随原文件一起丢失）时返回警告而不是错误。
+func (fp *defaultFileProcessor) replaceWithTemp(tempFile, filename string, originalInfo os.FileInfo, options *FileProcessOptions, backupFile string) ([]Warning, error) {
+	// 设置精确的文件权限（恢复被 umask 屏蔽的权限位及 setuid、setgid、sticky 位）
+	if mode, exact := outputFileMode(originalInfo, options); exact {
+		err := os.Chmod(tempFile, mode)
 		if err != nil {
 			os.Remove(tempFile) // 清理临时文件
-			return &FileOperationError{
+			return nil, &FileOperationError{
 				Op:   "chmod",
 				File: tempFile,
 				Err:  err,
@@ -320,31 +827,76 @@ func (fp *defaultFileProcessor) writeFileWithRecovery(filename string, data []by
 		}
 	}
 
+	// 重命名会丢失被替换文件的扩展属性（之后会重新写入的编码标记除外）
+	dropped := droppedXattrs(filename, options)
+
 	// 原子性替换文件
-	err = os.Rename(tempFile, filename)
+	err := os.Rename(tempFile, filename)
 	if err != nil {
 		os.Remove(tempFile) // 清理临时文件
 		// 如果有备份文件，尝试恢复
 		if backupFile != "" {
 			fp.restoreFromBackup(filename, backupFile)
 		}
-		return &FileOperationError{
+		return nil, &FileOperationError{
 			Op:   "rename",
 			File: filename,
 			Err:  err,
 		}
 	}
 
-	// 设置文件时间戳
+	var warnings []Warning
+	if len(dropped) > 0 {
+		warnings = append(warnings, Warning{
+			Code:    WarningXattrDropped,
+			Message: fmt.Sprintf("extended attributes not carried over: %s", strings.Join(dropped, ", ")),
+			File:    filename,
+			Count:   int64(len(dropped)),
+		})
+	}
+
+	// 设置文件时间戳（失败不是致命错误，记录为警告）
 	if options.PreserveTime && originalInfo != nil {
 		err = os.Chtimes(filename, originalInfo.ModTime(), originalInfo.ModTime())
 		if err != nil {
-			// 时间戳设置失败不是致命错误，只记录警告
-			// 这里可以通过日志记录器记录警告
+			warnings = append(warnings, Warning{
+				Code:    WarningTimestampNotPreserved,
+				Message: err.Error(),
+				File:    filename,
+			})
 		}
 	}
 
-	return nil
+	return warnings, nil
+}
+
+// outputFileMode 返回输出文件权限，exact 表示需要精确设置（否则按 0644 创建并受 umask 限制）
+func outputFileMode(originalInfo os.FileInfo, options *FileProcessOptions) (mode os.FileMode, exact bool) {
+	switch {
+	case options.OutputMode != 0:
+		return options.OutputMode, true
+	case options.PreserveMode && originalInfo != nil:
+		return originalInfo.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky), true
+	default:
+		return 0644, false
+	}
+}
+
+// createTempFile 在 path 所在目录创建随机命名的临时文件（path.*.tmp）并设置为最终权限
+//
+// 并发写入同一输出文件的临时文件互不覆盖，也不会删除用户已有的同名 .tmp 文件；
+// 临时文件以 0600 创建，写入内容前设置权限，内容不会以更宽松的权限暴露。
+func createTempFile(path string, mode os.FileMode) (*os.File, error) {
+	out, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	if err := out.Chmod(mode.Perm()); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return nil, err
+	}
+	return out, nil
 }
 
 // restoreFromBackup 从备份恢复文件
@@ -0,0 +1,249 @@
+package encoding
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// processFileStreaming 流式处理超过软限制的大文件
+//
+// 仅读取样本检测编码，随后边读边转换写入临时文件并原子替换输出文件，内存占用与文件大小无关。
+// 末尾换行符策略由 finalNewlineWriter 在写出时应用。
+func (fp *defaultFileProcessor) processFileStreaming(inputFile, outputFile string, inputInfo os.FileInfo, options *FileProcessOptions) (*FileProcessResult, error) {
+	start := time.Now()
+
+	if err := checkStreamingOptions(outputFile, options); err != nil {
+		return nil, err
+	}
+
+	in, err := os.Open(inputFile)
+	if err != nil {
+		return nil, &FileOperationError{
+			Op:   "open",
+			File: inputFile,
+			Err:  err,
+		}
+	}
+	defer in.Close()
+
+	// 读取样本检测编码
+	sampleSize := DefaultSampleSize
+	if fp.config.DetectorConfig != nil && fp.config.DetectorConfig.SampleSize > 0 {
+		sampleSize = fp.config.DetectorConfig.SampleSize
+	}
+	sample := make([]byte, sampleSize)
+	n, err := io.ReadFull(in, sample)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, &FileOperationError{
+			Op:   "read",
+			File: inputFile,
+			Err:  err,
+		}
+	}
+
+	detection, err := fp.detectSource(inputFile, sample[:n], options)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按样本确认解码后的文本符合预期语言（透传的数据无从确认，留待复查）
+	if !isPassthrough(detection) {
+		if err := fp.checkLanguage(inputFile, sample[:n], detection.Encoding, options); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &FileProcessResult{
+		InputFile:           inputFile,
+		OutputFile:          outputFile,
+		SourceEncoding:      detection.Encoding,
+		TargetEncoding:      options.TargetEncoding,
+		BytesProcessed:      inputInfo.Size(),
+		DetectionConfidence: detection.Confidence,
+		Passthrough:         isPassthrough(detection),
+		Streamed:            true,
+	}
+	if result.Passthrough {
+		result.Warnings = append(result.Warnings, passthroughWarning(inputFile))
+	}
+
+	if options.DryRun {
+		result.WouldChange, result.EstimatedSize = fp.estimateOutput(sample[:n], inputInfo.Size(), detection, options)
+		result.ProcessingTime = time.Since(start)
+		return result, nil
+	}
+
+	if _, err := in.Seek(0, io.SeekStart); err != nil {
+		return nil, &FileOperationError{
+			Op:   "seek",
+			File: inputFile,
+			Err:  err,
+		}
+	}
+
+	// 创建备份（如果需要）
+	if options.CreateBackup && inputFile == outputFile {
+		result.BackupFile, err = fp.createBackup(inputFile, options.BackupSuffix)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 确保输出目录存在
+	outputDir := filepath.Dir(outputFile)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, &FileOperationError{
+			Op:   "mkdir",
+			File: outputDir,
+			Err:  err,
+		}
+	}
+
+	// 边读边转换写入临时文件，同时计算校验和
+	originalHash := sha256.New()
+	convertedHash := sha256.New()
+	var trace conversionTrace
+	reader, err := fp.stream.createTransformReader(io.TeeReader(in, originalHash), detection.Encoding, options.TargetEncoding, &trace)
+	if err != nil {
+		return nil, &EncodingError{
+			Op:       OperationConvert,
+			Encoding: fmt.Sprintf("%s->%s", detection.Encoding, options.TargetEncoding),
+			File:     inputFile,
+			Err:      err,
+		}
+	}
+
+	mode, _ := outputFileMode(inputInfo, options)
+	out, err := createTempFile(outputFile, mode)
+	if err != nil {
+		return nil, &FileOperationError{
+			Op:   "write_temp",
+			File: outputFile,
+			Err:  err,
+		}
+	}
+	tempFile := out.Name()
+
+	var dst io.Writer = io.MultiWriter(out, convertedHash)
+	var newlineWriter *finalNewlineWriter
+	if !fp.preservesFinalNewline() {
+		cfg := fp.config.ConverterConfig
+		newlineWriter = newFinalNewlineWriter(dst, options.TargetEncoding, cfg.FinalNewline, cfg.TargetLineEnding)
+		dst = newlineWriter
+	}
+	written, err := io.Copy(dst, applyBOMPolicyReader(reader, options.TargetEncoding, options.BOMPolicy))
+	if newlineWriter != nil {
+		if err == nil {
+			err = newlineWriter.Close()
+		}
+		written = newlineWriter.written
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempFile)
+		return nil, &FileOperationError{
+			Op:   "write_temp",
+			File: tempFile,
+			Err:  err,
+		}
+	}
+
+	// 替换过多时放弃输出，保留原文件
+	if c, ok := fp.stream.converter(); ok {
+		if err := c.checkErrorThreshold(&trace, inputInfo.Size(), detection.Encoding, options.TargetEncoding); err != nil {
+			os.Remove(tempFile)
+			if ee, ok := err.(*EncodingError); ok {
+				ee.File = inputFile
+			}
+			return nil, err
+		}
+	}
+
+	// 替换输出文件前（就地转换时源文件仍未被覆盖）由源文件流式解码生成 ASCII 音译副本
+	if options.Transliteration != nil {
+		result.TransliteratedFile, result.Untransliterated, err = fp.transliterateSource(inputFile, outputFile, detection.Encoding, options.Transliteration)
+		if err != nil {
+			os.Remove(tempFile)
+			return nil, err
+		}
+	}
+
+	warnings, err := fp.replaceWithTemp(tempFile, outputFile, inputInfo, options, result.BackupFile)
+	if err != nil {
+		return nil, err
+	}
+	result.LostRunes = trace.lostHistogram()
+	result.Warnings = append(append(result.Warnings, withFile(trace.warnings(), inputFile)...), warnings...)
+
+	// 写入元数据旁路文件（如果需要）
+	if options.WriteSidecar {
+		result.SidecarFile, err = saveSidecar(&SidecarMetadata{
+			File:             outputFile,
+			OriginalEncoding: detection.Encoding,
+			TargetEncoding:   options.TargetEncoding,
+			Confidence:       detection.Confidence,
+			OriginalSHA256:   fmt.Sprintf("%x", originalHash.Sum(nil)),
+			ConvertedSHA256:  fmt.Sprintf("%x", convertedHash.Sum(nil)),
+			OriginalSize:     inputInfo.Size(),
+			ConvertedSize:    written,
+			BackupFile:       result.BackupFile,
+			ToolVersion:      Version,
+			ConvertedAt:      time.Now(),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result.ProcessingTime = time.Since(start)
+	return result, nil
+}
+
+// transliterateSource 按源编码流式解码源文件，生成输出文件的 ASCII 音译副本
+func (fp *defaultFileProcessor) transliterateSource(inputFile, outputFile, sourceEncoding string, options *TransliterationOptions) (string, int64, error) {
+	in, err := os.Open(inputFile)
+	if err != nil {
+		return "", 0, &FileOperationError{Op: "transliterate", File: inputFile, Err: err}
+	}
+	defer in.Close()
+
+	text, err := fp.stream.createTransformReader(in, sourceEncoding, EncodingUTF8, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	return writeTransliteration(outputFile, text, options)
+}
+
+// checkStreamingOptions 检查流式处理能否应用文件开头的改写选项
+//
+// 转换来源注释头和 @charset 声明在整个文件的 UTF-8 文本上处理，流式处理无法应用。
+// 与其输出缺少注释头或保留旧声明的文件，不如返回错误，由调用方调大 StreamingThreshold。
+func checkStreamingOptions(path string, options *FileProcessOptions) error {
+	var option string
+	if options.Provenance != nil {
+		if _, ok := options.Provenance.syntaxFor(path); ok {
+			option = "Provenance"
+		}
+	}
+	if options.CharsetDeclaration != "" && options.CharsetDeclaration != CharsetDeclarationPreserve {
+		ext := strings.ToLower(filepath.Ext(path))
+		if stylesheetExtensions[ext] || scriptExtensions[ext] && options.TargetEncoding == EncodingUTF8 {
+			option = "CharsetDeclaration"
+		}
+	}
+	if option == "" {
+		return nil
+	}
+	return &FileOperationError{
+		Op:   "stream",
+		File: path,
+		Err:  fmt.Errorf("%w: %s is not applied to files streamed above StreamingThreshold", ErrInvalidConfiguration, option),
+	}
+}
@@ -0,0 +1,207 @@
+package encoding
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FilterArgs RunFilter 的参数，字段与 RegisterFlags 注册的命令行选项一一对应
+type FilterArgs struct {
+	// From 源编码（为空时自动检测）
+	From string
+
+	// To 目标编码（默认 UTF-8）
+	To string
+
+	// Fallback 检测失败或置信度不足时使用的源编码（为空时返回检测错误）
+	Fallback string
+
+	// MinConfidence 最小置信度（0 表示使用命令行默认值 0.7）
+	MinConfidence float64
+
+	// SampleSize 检测样本大小（字节，0 表示使用命令行默认值 16384）
+	SampleSize int
+
+	// Strict 严格模式（目标编码无法表示字符时报错）
+	Strict bool
+
+	// Replacement 目标编码无法表示的字符的替换字符串（为空时使用 "?"）
+	Replacement string
+
+	// MaxErrors 非严格模式下允许的最大错误数（0 表示不限制）
+	MaxErrors int64
+
+	// MaxErrorRate 非严格模式下允许的最大错误率（0 表示不限制）
+	MaxErrorRate float64
+
+	// BOM 输出 BOM 策略（preserve、strip、add，为空时保持原样）
+	BOM string
+
+	// LineEnding 统一换行符（lf、crlf、cr，为空时保持原样）
+	LineEnding string
+
+	// FinalNewline 末尾换行符策略（preserve、ensure、strip，为空时保持原样）
+	FinalNewline string
+
+	// DetectOnly 只检测编码，向输出写入编码名称和置信度（以制表符分隔）
+	DetectOnly bool
+}
+
+// RegisterFlags 将参数注册为命令行选项，供内部命令行工具共用同一套选项名称
+func (a *FilterArgs) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&a.From, "from", a.From, "source encoding (empty to detect)")
+	fs.StringVar(&a.To, "to", a.To, "target encoding (default UTF-8)")
+	fs.StringVar(&a.Fallback, "fallback", a.Fallback, "source encoding to use when detection fails")
+	fs.Float64Var(&a.MinConfidence, "min-confidence", a.MinConfidence, "minimum detection confidence")
+	fs.IntVar(&a.SampleSize, "sample-size", a.SampleSize, "detection sample size in bytes")
+	fs.BoolVar(&a.Strict, "strict", a.Strict, "fail on characters the target encoding cannot represent")
+	fs.StringVar(&a.Replacement, "replacement", a.Replacement, "replacement for unrepresentable characters")
+	fs.Int64Var(&a.MaxErrors, "max-errors", a.MaxErrors, "maximum replaced characters and invalid sequences")
+	fs.Float64Var(&a.MaxErrorRate, "max-error-rate", a.MaxErrorRate, "maximum errors per input byte")
+	fs.StringVar(&a.BOM, "bom", a.BOM, "BOM policy: preserve, strip or add")
+	fs.StringVar(&a.LineEnding, "eol", a.LineEnding, "normalize line endings: lf, crlf or cr")
+	fs.StringVar(&a.FinalNewline, "final-newline", a.FinalNewline, "final newline policy: preserve, ensure or strip")
+	fs.BoolVar(&a.DetectOnly, "detect", a.DetectOnly, "only print the detected encoding and confidence")
+}
+
+// RunFilter 从 stdin 读取数据，检测（或按 From 指定）源编码并转换为目标编码后写入 stdout
+//
+// 行为与文件的流式处理一致：边读边写，内存占用与输入大小无关。由于输出已经写出，
+// 超过 MaxErrors 或 MaxErrorRate 时只能在结束后返回错误，调用方应据此以非零状态退出。
+// 输入为空时不输出任何内容。
+func RunFilter(stdin io.Reader, stdout io.Writer, args FilterArgs) error {
+	config, err := args.processorConfig()
+	if err != nil {
+		return err
+	}
+	sp := NewStreamProcessor(config).(*defaultStreamProcessor)
+	defer sp.Close()
+
+	target := canonicalEncodingName(args.To)
+	if target == "" {
+		target = EncodingUTF8
+	}
+
+	input := &countingReader{reader: stdin}
+	var reader io.Reader = input
+	source := canonicalEncodingName(args.From)
+	if source == "" || args.DetectOnly {
+		detection, replay, err := detectReaderEncoding(sp.processor, input, config.DetectorConfig.SampleSize)
+		reader = replay
+		switch {
+		case err == nil && detection.Confidence >= config.DetectorConfig.MinConfidenceFor(detection.Encoding):
+		case errors.Is(err, ErrInvalidInput):
+			// 空输入
+			return nil
+		case args.Fallback != "":
+			detection = &DetectionResult{Encoding: canonicalEncodingName(args.Fallback)}
+		case err != nil:
+			return err
+		default:
+			return &EncodingError{
+				Op:       OperationDetect,
+				Encoding: detection.Encoding,
+				Err:      fmt.Errorf("detection confidence %.2f below threshold %.2f", detection.Confidence, config.DetectorConfig.MinConfidenceFor(detection.Encoding)),
+			}
+		}
+
+		if args.DetectOnly {
+			_, err := fmt.Fprintf(stdout, "%s\t%.2f\n", detection.Encoding, detection.Confidence)
+			return err
+		}
+		source = detection.Encoding
+	}
+
+	var trace conversionTrace
+	converted, err := sp.createTransformReader(reader, source, target, &trace)
+	if err != nil {
+		return err
+	}
+
+	var dst io.Writer = stdout
+	var newlineWriter *finalNewlineWriter
+	cfg := config.ConverterConfig
+	if cfg.FinalNewline == FinalNewlineEnsure || cfg.FinalNewline == FinalNewlineStrip {
+		newlineWriter = newFinalNewlineWriter(stdout, target, cfg.FinalNewline, cfg.TargetLineEnding)
+		dst = newlineWriter
+	}
+	_, err = io.Copy(dst, applyBOMPolicyReader(converted, target, args.BOM))
+	if err == nil && newlineWriter != nil {
+		err = newlineWriter.Close()
+	}
+	if err != nil {
+		return &EncodingError{
+			Op:       OperationConvert,
+			Encoding: fmt.Sprintf("%s->%s", source, target),
+			Err:      err,
+		}
+	}
+
+	if c, ok := sp.converter(); ok {
+		return c.checkErrorThreshold(&trace, input.count, source, target)
+	}
+	return nil
+}
+
+// processorConfig 将参数映射为处理器配置（以命令行工具的默认配置为基础）
+func (a *FilterArgs) processorConfig() (*ProcessorConfig, error) {
+	config := cliProcessorConfig()
+	config.EnableMetrics = false
+	if a.MinConfidence > 0 {
+		config.DetectorConfig.MinConfidence = a.MinConfidence
+	}
+	if a.SampleSize > 0 {
+		config.DetectorConfig.SampleSize = a.SampleSize
+	}
+
+	cfg := config.ConverterConfig
+	cfg.StrictMode = a.Strict
+	if a.Replacement != "" {
+		cfg.InvalidCharReplacement = a.Replacement
+	}
+	cfg.MaxErrors = a.MaxErrors
+	cfg.MaxErrorRate = a.MaxErrorRate
+
+	switch strings.ToLower(a.LineEnding) {
+	case "":
+	case "lf":
+		cfg.NormalizeLineEndings, cfg.TargetLineEnding = true, LineEndingLF
+	case "crlf":
+		cfg.NormalizeLineEndings, cfg.TargetLineEnding = true, LineEndingCRLF
+	case "cr":
+		cfg.NormalizeLineEndings, cfg.TargetLineEnding = true, LineEndingCR
+	default:
+		return nil, fmt.Errorf("%w: unknown line ending %q", ErrInvalidConfiguration, a.LineEnding)
+	}
+
+	switch a.FinalNewline {
+	case "":
+	case FinalNewlinePreserve, FinalNewlineEnsure, FinalNewlineStrip:
+		cfg.FinalNewline = a.FinalNewline
+	default:
+		return nil, fmt.Errorf("%w: unknown final newline policy %q", ErrInvalidConfiguration, a.FinalNewline)
+	}
+
+	switch a.BOM {
+	case "", BOMPreserve, BOMStrip, BOMAdd:
+	default:
+		return nil, fmt.Errorf("%w: unknown BOM policy %q", ErrInvalidConfiguration, a.BOM)
+	}
+	return config, nil
+}
+
+// countingReader 统计已读取字节数的读取器
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+// Read 实现 io.Reader 接口
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
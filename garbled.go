@@ -0,0 +1,125 @@
+package encoding
+
+import (
+	"regexp"
+	"strings"
+)
+
+// cp1252Continuation UTF-8 续字节（0x80-0xBF）按 Windows-1252/Latin-1 解码后的字符
+const cp1252Continuation = `\x{80}-\x{BF}\x{152}\x{153}\x{160}\x{161}\x{178}\x{17D}\x{17E}\x{192}\x{2C6}\x{2DC}` +
+	`\x{2013}\x{2014}\x{2018}-\x{201A}\x{201C}-\x{201E}\x{2020}-\x{2022}\x{2026}\x{2030}\x{2039}\x{203A}\x{20AC}\x{2122}`
+
+// GarbledPattern 乱码特征模式
+//
+// Pattern 和 Runes 任一命中即视为命中该模式。
+type GarbledPattern struct {
+	// Name 模式名称
+	Name string
+
+	// Pattern 匹配乱码特征的正则表达式（可选）
+	Pattern *regexp.Regexp
+
+	// Runes 乱码特征字符集合，文本包含其中任一字符即命中（可选）
+	Runes string
+
+	// Weight 命中时增加的乱码得分（0 按 1 计算）
+	Weight float64
+}
+
+// matches 检查文本是否命中该模式
+func (p GarbledPattern) matches(text string) bool {
+	if p.Runes != "" && strings.ContainsAny(text, p.Runes) {
+		return true
+	}
+	return p.Pattern != nil && p.Pattern.MatchString(text)
+}
+
+// weight 返回模式权重
+func (p GarbledPattern) weight() float64 {
+	if p.Weight <= 0 {
+		return 1
+	}
+	return p.Weight
+}
+
+// DefaultGarbledPatterns 返回内置的乱码特征模式库
+func DefaultGarbledPatterns() []GarbledPattern {
+	return []GarbledPattern{
+		{Name: "replacement_char", Pattern: regexp.MustCompile(`\x{FFFD}+`), Weight: 0.35},
+		{Name: "control_char", Pattern: regexp.MustCompile(`[\x00-\x08\x0B\x0C\x0E-\x1F\x7F]+`), Weight: 0.35},
+		{Name: "bom_as_latin1", Pattern: regexp.MustCompile(`[ÿþ]+`), Weight: 0.3},
+		// UTF-8 中文按 Latin-1 解码，如 "中文" -> "ä¸­æ–‡"
+		{Name: "utf8_cjk_as_latin1", Pattern: regexp.MustCompile(`[\x{E0}-\x{EF}][` + cp1252Continuation + `]{2}`), Weight: 0.5},
+		// UTF-8 西文字符按 Latin-1 解码，如 "é" -> "Ã©"
+		{Name: "utf8_latin_as_latin1", Pattern: regexp.MustCompile(`[ÂÃ][` + cp1252Continuation + `]`), Weight: 0.35},
+		// UTF-8 的 U+FFFD 按 GBK 解码
+		{Name: "gbk_kunjinkao", Runes: "锟斤拷", Weight: 0.5},
+		// 未初始化内存（0xCC、0xCD）按 GBK 解码
+		{Name: "gbk_uninitialized", Pattern: regexp.MustCompile(`烫{2,}|屯{2,}`), Weight: 0.5},
+		// UTF-8 中文标点按 GBK 解码，如 "，" -> "锛?"、"。" -> "銆?"
+		{Name: "utf8_punct_as_gbk", Runes: "锛銆", Weight: 0.35},
+		// 私用区字符通常来自编码表中未定义的区域
+		{Name: "private_use", Pattern: regexp.MustCompile(`[\x{E000}-\x{F8FF}]`), Weight: 0.2},
+	}
+}
+
+// GarbledScorer 按乱码特征模式为文本评分
+type GarbledScorer struct {
+	// Patterns 乱码特征模式
+	Patterns []GarbledPattern
+
+	// Threshold LooksGarbled 的判定阈值（得分不低于该值视为乱码）
+	Threshold float64
+}
+
+// NewGarbledScorer 创建乱码评分器（未指定模式时使用 DefaultGarbledPatterns）
+func NewGarbledScorer(patterns ...GarbledPattern) *GarbledScorer {
+	if len(patterns) == 0 {
+		patterns = DefaultGarbledPatterns()
+	}
+	return &GarbledScorer{
+		Patterns:  patterns,
+		Threshold: DefaultGarbledThreshold,
+	}
+}
+
+// Score 返回 0-1 之间的乱码得分，为命中模式的权重之和（上限为 1）
+//
+// 空文本和未配置模式时返回 0。
+func (s *GarbledScorer) Score(text string) float64 {
+	if text == "" {
+		return 0
+	}
+
+	score := 0.0
+	for _, p := range s.Patterns {
+		if p.matches(text) {
+			score += p.weight()
+		}
+	}
+	if score > 1 {
+		return 1
+	}
+	return score
+}
+
+// LooksGarbled 检查文本的乱码得分是否达到阈值
+func (s *GarbledScorer) LooksGarbled(text string) bool {
+	score := s.Score(text)
+	return score > 0 && score >= s.Threshold
+}
+
+// defaultGarbledScorer 包级函数和未配置额外模式的检测器共用的默认评分器
+var defaultGarbledScorer = NewGarbledScorer()
+
+// ScoreGarbled 使用检测器内部的乱码特征为文本评分（0 表示无乱码特征，1 表示严重乱码）
+//
+// 可用于检查来自数据库、API 等其他来源的字符串是否存在编码错误。
+func ScoreGarbled(text string) float64 {
+	return defaultGarbledScorer.Score(text)
+}
+
+// LooksGarbled 使用检测器内部的乱码特征判断文本是否像乱码
+func LooksGarbled(text string) bool {
+	return defaultGarbledScorer.LooksGarbled(text)
+}
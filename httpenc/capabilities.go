@@ -0,0 +1,27 @@
+package httpenc
+
+import (
+	"encoding/json"
+	"net/http"
+
+	encoding "github.com/mirbf/encoding-processor"
+)
+
+// CapabilitiesHandler 返回以 JSON 输出 encoding.Capabilities() 的处理函数
+//
+// 挂载在服务的固定路径（如 /capabilities）上，远程调用方可以据此确认支持的编码和功能。
+// 只接受 GET 和 HEAD 请求，其他方法返回 405。
+func CapabilitiesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method == http.MethodHead {
+			return
+		}
+		json.NewEncoder(w).Encode(encoding.Capabilities())
+	})
+}
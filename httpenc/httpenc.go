@@ -0,0 +1,251 @@
+// Package httpenc 提供 net/http 中间件，在处理函数读取之前将请求体转码为 UTF-8，
+// 并可按 Accept-Charset 重新编码文本响应
+//
+// 请求体的编码按 Content-Type 的 charset 参数、XML 声明、HTML meta 标签和字节特征依次判断
+// （见 encoding.ContentHintDetector），处理函数看到的始终是 UTF-8 数据，
+// Content-Type 的 charset 参数同步改为 utf-8。只处理文本类请求体（text/*、JSON、XML、JavaScript
+// 和表单），multipart 和二进制请求体原样传递。
+package httpenc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	encoding "github.com/mirbf/encoding-processor"
+)
+
+// DefaultMaxBodySize 默认允许转码的最大请求体大小
+const DefaultMaxBodySize = 10 * 1024 * 1024
+
+// formContentType 表单请求体的媒体类型
+const formContentType = "application/x-www-form-urlencoded"
+
+// utf8BOM UTF-8 字节顺序标记
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// Options 中间件选项
+type Options struct {
+	// Processor 检测和转换使用的处理器（默认 encoding.NewForWebService()）；
+	// 自定义处理器未实现 encoding.ContentHintDetector 时只按字节特征检测请求体编码
+	Processor encoding.Processor
+
+	// MaxBodySize 转码的最大请求体大小（字节，默认 10MB）；超过时返回 413
+	MaxBodySize int64
+
+	// FallbackEncoding 请求体编码无法确定时使用的编码（为空时返回 415）
+	FallbackEncoding string
+
+	// EncodeResponses 是否按请求的 Accept-Charset 重新编码文本响应（默认 false）
+	//
+	// 只在客户端不接受 UTF-8 时重新编码；HTML 和 XML 中目标编码无法表示的字符写为数字字符引用，
+	// 其他文本替换为 "?"。处理函数已在 Content-Type 中声明非 UTF-8 字符集的响应原样输出。
+	EncodeResponses bool
+}
+
+// contextKey 请求上下文中保存原始编码的键
+type contextKey struct{}
+
+// RequestEncoding 返回中间件检测到的请求体原始编码（请求体未经转码时返回 false）
+func RequestEncoding(r *http.Request) (string, bool) {
+	encodingName, ok := r.Context().Value(contextKey{}).(string)
+	return encodingName, ok
+}
+
+// Middleware 返回转码请求体（以及按需重新编码响应）的中间件
+func Middleware(options *Options) func(http.Handler) http.Handler {
+	m := newMiddleware(options)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r, status := m.transcodeRequest(r)
+			if status != 0 {
+				http.Error(w, http.StatusText(status), status)
+				return
+			}
+
+			if !m.options.EncodeResponses {
+				next.ServeHTTP(w, r)
+				return
+			}
+			target := negotiateCharset(r.Header.Get("Accept-Charset"))
+			w.Header().Add("Vary", "Accept-Charset")
+			if target == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			rw := &responseWriter{ResponseWriter: w, target: target}
+			defer rw.close()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// middleware 应用默认值后的中间件配置
+type middleware struct {
+	options   Options
+	processor encoding.Processor
+}
+
+// newMiddleware 应用默认选项
+func newMiddleware(options *Options) *middleware {
+	m := &middleware{}
+	if options != nil {
+		m.options = *options
+	}
+	if m.options.MaxBodySize <= 0 {
+		m.options.MaxBodySize = DefaultMaxBodySize
+	}
+	m.processor = m.options.Processor
+	if m.processor == nil {
+		m.processor = encoding.NewForWebService()
+	}
+	return m
+}
+
+// transcodeRequest 将文本请求体转码为 UTF-8，返回新的请求；失败时返回 HTTP 状态码
+func (m *middleware) transcodeRequest(r *http.Request) (*http.Request, int) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return r, 0
+	}
+	contentType := r.Header.Get("Content-Type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !isTextual(mediaType) {
+		return r, 0
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, m.options.MaxBodySize+1))
+	r.Body.Close()
+	if err != nil {
+		return r, http.StatusBadRequest
+	}
+	if int64(len(body)) > m.options.MaxBodySize {
+		return r, http.StatusRequestEntityTooLarge
+	}
+
+	var source string
+	var converted []byte
+	if mediaType == formContentType {
+		source, converted, err = m.transcodeForm(body, contentType)
+	} else {
+		source, converted, err = m.transcode(body, contentType)
+	}
+	if err != nil {
+		return r, http.StatusUnsupportedMediaType
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), contextKey{}, source))
+	r.Body = io.NopCloser(bytes.NewReader(converted))
+	r.ContentLength = int64(len(converted))
+	r.Header = r.Header.Clone()
+	r.Header.Set("Content-Length", strconv.Itoa(len(converted)))
+	params["charset"] = "utf-8"
+	r.Header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+	return r, 0
+}
+
+// transcode 检测请求体编码并转换为 UTF-8（去除 BOM）
+func (m *middleware) transcode(body []byte, contentType string) (string, []byte, error) {
+	source, err := m.detect(body, contentType)
+	if err != nil {
+		return "", nil, err
+	}
+	if source == "ASCII" {
+		return source, body, nil
+	}
+	oc, ok := m.processor.(encoding.OptionsConverter)
+	if !ok {
+		converted, err := m.processor.Convert(body, source, encoding.EncodingUTF8)
+		if err != nil {
+			return "", nil, err
+		}
+		return source, bytes.TrimPrefix(converted, utf8BOM), nil
+	}
+	result, err := oc.ConvertWithOptions(body, source, encoding.EncodingUTF8, &encoding.ConvertOptions{BOMPolicy: encoding.BOMStrip})
+	if err != nil {
+		return "", nil, err
+	}
+	return source, result.Data, nil
+}
+
+// transcodeForm 转码表单请求体：编码作用于百分号解码后的字节，因此逐个转换键和值后重新编码
+func (m *middleware) transcodeForm(body []byte, contentType string) (string, []byte, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return "", nil, err
+	}
+
+	// 按全部键和值的原始字节检测编码
+	var raw bytes.Buffer
+	for key, list := range values {
+		raw.WriteString(key)
+		raw.WriteByte('\n')
+		for _, value := range list {
+			raw.WriteString(value)
+			raw.WriteByte('\n')
+		}
+	}
+	if raw.Len() == 0 {
+		return encoding.EncodingUTF8, body, nil
+	}
+	source, err := m.detect(raw.Bytes(), contentType)
+	if err != nil {
+		return "", nil, err
+	}
+	if source == "ASCII" || source == encoding.EncodingUTF8 {
+		return source, body, nil
+	}
+
+	converted := make(url.Values, len(values))
+	for key, list := range values {
+		utf8Key, err := m.processor.ConvertString(key, source, encoding.EncodingUTF8)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, value := range list {
+			utf8Value, err := m.processor.ConvertString(value, source, encoding.EncodingUTF8)
+			if err != nil {
+				return "", nil, err
+			}
+			converted[utf8Key] = append(converted[utf8Key], utf8Value)
+		}
+	}
+	return source, []byte(converted.Encode()), nil
+}
+
+// detect 按 Content-Type 和数据内容检测编码，失败时使用 FallbackEncoding
+func (m *middleware) detect(data []byte, contentType string) (string, error) {
+	var result *encoding.DetectionResult
+	var err error
+	if hd, ok := m.processor.(encoding.ContentHintDetector); ok {
+		result, err = hd.DetectWithContentHints(data, contentType)
+	} else {
+		result, err = m.processor.DetectEncoding(data)
+	}
+	if err == nil {
+		return result.Encoding, nil
+	}
+	if m.options.FallbackEncoding != "" {
+		return m.options.FallbackEncoding, nil
+	}
+	return "", err
+}
+
+// isTextual 检查媒体类型是否为文本类请求体
+func isTextual(mediaType string) bool {
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", formContentType:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package httpenc
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	encoding "github.com/mirbf/encoding-processor"
+)
+
+func TestMiddleware(t *testing.T) {
+	var body, contentType, source string
+	handler := Middleware(&Options{EncodeResponses: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body, contentType = string(data), r.Header.Get("Content-Type")
+		source, _ = RequestEncoding(r)
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, "你好，世界 ✓")
+	}))
+
+	gbk, err := encoding.NewDefault().Convert([]byte("中文请求内容"), encoding.EncodingUTF8, encoding.EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(gbk)))
+	request.Header.Set("Content-Type", "text/plain; charset=gbk")
+	request.Header.Set("Accept-Charset", "gbk, utf-8;q=0.5")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if body != "中文请求内容" || contentType != "text/plain; charset=utf-8" || source != encoding.EncodingGBK {
+		t.Errorf("Unexpected request %q (%s, source %s)", body, contentType, source)
+	}
+
+	// 响应按 Accept-Charset 重新编码，GBK 无法表示的字符替换为 "?"
+	if got := recorder.Header().Get("Content-Type"); got != "text/plain; charset=gbk" {
+		t.Errorf("Unexpected response Content-Type %q", got)
+	}
+	response, err := encoding.NewDefault().Convert(recorder.Body.Bytes(), encoding.EncodingGBK, encoding.EncodingUTF8)
+	if err != nil || string(response) != "你好，世界 ?" {
+		t.Errorf("Unexpected response %q (%v)", response, err)
+	}
+
+	// 接受 UTF-8 的客户端收到原始响应
+	request = httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Accept-Charset", "utf-8, gbk;q=0.8")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Body.String() != "你好，世界 ✓" {
+		t.Errorf("Expected UTF-8 response, got %q", recorder.Body.String())
+	}
+}
+
+func TestMiddlewareForm(t *testing.T) {
+	var name string
+	handler := Middleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name = r.FormValue("name")
+	}))
+
+	big5, err := encoding.NewDefault().Convert([]byte("繁體中文姓名"), encoding.EncodingUTF8, encoding.EncodingBIG5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := url.Values{"name": {string(big5)}}.Encode()
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=big5")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	if name != "繁體中文姓名" {
+		t.Errorf("Expected form value to be transcoded, got %q", name)
+	}
+}
+
+func TestMiddlewareRejectsOversizedBody(t *testing.T) {
+	handler := Middleware(&Options{MaxBodySize: 4})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called")
+	}))
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too large"))
+	request.Header.Set("Content-Type", "text/plain")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413, got %d", recorder.Code)
+	}
+}
+
+func TestCapabilitiesHandler(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	CapabilitiesHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/capabilities", nil))
+
+	var report encoding.CapabilitiesReport
+	if err := json.NewDecoder(recorder.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Version != encoding.Version || !slices.Contains(report.Encodings, encoding.EncodingGBK) {
+		t.Errorf("Unexpected capabilities %+v", report)
+	}
+
+	recorder = httptest.NewRecorder()
+	CapabilitiesHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/capabilities", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", recorder.Code)
+	}
+}
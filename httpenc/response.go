@@ -0,0 +1,163 @@
+package httpenc
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	encoding "github.com/mirbf/encoding-processor"
+	"github.com/mirbf/encoding-processor/converter"
+	textencoding "golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+)
+
+// negotiateCharset 按 Accept-Charset 选择响应编码，客户端接受 UTF-8 或没有可用的字符集时返回空字符串
+//
+// 选择 q 值最高的受支持字符集，q 值相同时优先 UTF-8；"*" 视为接受 UTF-8。
+func negotiateCharset(acceptCharset string) string {
+	if acceptCharset == "" {
+		return ""
+	}
+
+	best, bestQ, utf8Q := "", 0.0, 0.0
+	for _, part := range strings.Split(acceptCharset, ",") {
+		name, q := parseQuality(part)
+		if name == "" || q <= 0 {
+			continue
+		}
+		if name == "*" {
+			utf8Q = max(utf8Q, q)
+			continue
+		}
+		resolved, ok := encoding.ResolveEncodingName(name)
+		if !ok {
+			continue
+		}
+		if resolved == encoding.EncodingUTF8 {
+			utf8Q = max(utf8Q, q)
+		} else if q > bestQ {
+			best, bestQ = resolved, q
+		}
+	}
+	if best == "" || utf8Q >= bestQ {
+		return ""
+	}
+	return best
+}
+
+// parseQuality 解析 "名称;q=0.5" 形式的列表项（没有 q 参数时为 1）
+func parseQuality(part string) (string, float64) {
+	name, params, _ := strings.Cut(part, ";")
+	q := 1.0
+	for _, param := range strings.Split(params, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if ok && strings.EqualFold(key, "q") {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return "", 0
+			}
+			q = parsed
+		}
+	}
+	return strings.TrimSpace(name), q
+}
+
+// responseWriter 将 UTF-8 文本响应重新编码为目标编码的写入器
+//
+// 响应头延迟到第一次写入时发送，以便在处理函数未设置 Content-Type 时按内容判断类型。
+type responseWriter struct {
+	http.ResponseWriter
+	target string
+
+	// status 处理函数设置的状态码（0 表示尚未设置）
+	status int
+
+	// wroteHeader 是否已向底层写入器发送响应头
+	wroteHeader bool
+
+	// encoder 重新编码的写入器（为 nil 时原样写出）
+	encoder io.WriteCloser
+}
+
+// WriteHeader 记录状态码，响应头在第一次写入或处理结束时发送
+func (rw *responseWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+}
+
+// Write 实现 io.Writer 接口
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.sendHeader(p)
+	}
+	if rw.encoder == nil {
+		return rw.ResponseWriter.Write(p)
+	}
+	return rw.encoder.Write(p)
+}
+
+// sendHeader 按响应类型决定是否重新编码并发送响应头
+func (rw *responseWriter) sendHeader(p []byte) {
+	rw.wroteHeader = true
+	header := rw.Header()
+	contentType := header.Get("Content-Type")
+	if contentType == "" && len(p) > 0 {
+		contentType = http.DetectContentType(p)
+		header.Set("Content-Type", contentType)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err == nil && isEncodableResponse(mediaType) && (params["charset"] == "" || strings.EqualFold(params["charset"], "utf-8")) {
+		if encoder, err := newResponseEncoder(rw.ResponseWriter, rw.target, mediaType); err == nil {
+			params["charset"] = strings.ToLower(rw.target)
+			header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+			header.Del("Content-Length")
+			rw.encoder = encoder
+		}
+	}
+
+	if rw.status != 0 {
+		rw.ResponseWriter.WriteHeader(rw.status)
+	}
+}
+
+// close 刷新重新编码的剩余数据；处理函数只设置了状态码时发送响应头
+func (rw *responseWriter) close() {
+	if !rw.wroteHeader {
+		if rw.status == 0 {
+			return
+		}
+		rw.wroteHeader = true
+		rw.ResponseWriter.WriteHeader(rw.status)
+		return
+	}
+	if rw.encoder != nil {
+		rw.encoder.Close()
+	}
+}
+
+// newResponseEncoder 创建响应编码写入器：HTML 和 XML 中无法表示的字符写为数字字符引用，其他文本替换为 "?"
+func newResponseEncoder(w io.Writer, target, mediaType string) (io.WriteCloser, error) {
+	if mediaType == "text/html" || strings.HasSuffix(mediaType, "/xml") || strings.HasSuffix(mediaType, "+xml") {
+		enc, err := converter.Lookup(target)
+		if err != nil {
+			return nil, err
+		}
+		return transform.NewWriter(w, textencoding.HTMLEscapeUnsupported(enc.NewEncoder())), nil
+	}
+	return converter.NewWriter(w, encoding.EncodingUTF8, target)
+}
+
+// isEncodableResponse 检查响应是否为需要按 Accept-Charset 重新编码的文本
+//
+// JSON 规定使用 UTF-8，不重新编码。
+func isEncodableResponse(mediaType string) bool {
+	switch {
+	case strings.HasPrefix(mediaType, "text/"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	return mediaType == "application/xml" || mediaType == "application/javascript"
+}
@@ -0,0 +1,116 @@
+package encoding
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// icuSubstitution ICU 单字节转换器默认的替换字符（SUB）
+const icuSubstitution = "\x1a"
+
+// icuConverters 本包编码名称对应的 ICU 规范转换器名称及常用别名（第一个为规范名称）
+var icuConverters = map[string][]string{
+	EncodingUTF8:        {"UTF-8", "ibm-1208", "cp1208", "unicode-1-1-utf-8", "unicode-2-0-utf-8"},
+	EncodingUTF16:       {"UTF-16", "ISO-10646-UCS-2", "ibm-1204", "ibm-1205", "unicode", "csUnicode", "ucs-2"},
+	EncodingUTF16BE:     {"UTF-16BE", "x-utf-16be", "UnicodeBigUnmarked", "ibm-1200", "ibm-1201", "cp1200", "cp1201", "UTF16_BigEndian"},
+	EncodingUTF16LE:     {"UTF-16LE", "x-utf-16le", "UnicodeLittleUnmarked", "ibm-1202", "ibm-1203", "UTF16_LittleEndian"},
+	EncodingUTF32:       {"UTF-32", "ISO-10646-UCS-4", "ibm-1236", "ibm-1237", "csUCS4", "ucs-4"},
+	EncodingUTF32BE:     {"UTF-32BE", "UTF32_BigEndian", "ibm-1232", "ibm-1233"},
+	EncodingUTF32LE:     {"UTF-32LE", "UTF32_LittleEndian", "ibm-1234", "ibm-1235"},
+	EncodingGBK:         {"windows-936-2000", "GBK", "CP936", "MS936", "windows-936"},
+	EncodingGB2312:      {"ibm-1383_P110-1999", "ibm-1383", "GB2312", "csGB2312", "cp1383", "EUC-CN", "ibm-eucCN"},
+	EncodingGB18030:     {"GB18030", "ibm-1392", "windows-54936"},
+	EncodingBIG5:        {"windows-950-2000", "Big5", "csBig5", "windows-950", "x-windows-950", "x-big5", "ms950"},
+	EncodingShiftJIS:    {"ibm-943_P15A-2003", "ibm-943", "Shift_JIS", "MS_Kanji", "csShiftJIS", "windows-31j", "csWindows31J", "x-sjis", "x-ms-cp932", "cp932", "windows-932", "cp943c", "ms932", "pck", "sjis"},
+	EncodingEUCJP:       {"ibm-33722_P12A_P12A-2009_U2", "ibm-33722", "ibm-5050", "EUC-JP", "csEUCPkdFmtJapanese", "X-EUC-JP", "eucjis", "ujis"},
+	EncodingEUCKR:       {"ibm-970_P110_P110-2006_U2", "ibm-970", "EUC-KR", "csEUCKR", "ibm-eucKR", "KSC_5601", "cp970"},
+	EncodingISO88591:    {"ISO-8859-1", "ibm-819", "IBM819", "cp819", "latin1", "8859_1", "csISOLatin1", "iso-ir-100", "ISO_8859-1:1987", "l1"},
+	EncodingISO88592:    {"ibm-912_P100-1995", "ibm-912", "ISO-8859-2", "ISO_8859-2:1987", "latin2", "csISOLatin2", "iso-ir-101", "l2", "8859_2", "cp912"},
+	EncodingISO88593:    {"ibm-913_P100-2000", "ibm-913", "ISO-8859-3", "ISO_8859-3:1988", "latin3", "csISOLatin3", "iso-ir-109", "l3", "8859_3", "cp913"},
+	EncodingISO88594:    {"ibm-914_P100-1995", "ibm-914", "ISO-8859-4", "ISO_8859-4:1988", "latin4", "csISOLatin4", "iso-ir-110", "l4", "8859_4", "cp914"},
+	EncodingISO88595:    {"ibm-915_P100-1995", "ibm-915", "ISO-8859-5", "ISO_8859-5:1988", "cyrillic", "csISOLatinCyrillic", "iso-ir-144", "8859_5", "cp915"},
+	EncodingISO88596:    {"ibm-1089_P100-1995", "ibm-1089", "ISO-8859-6", "ISO_8859-6:1987", "arabic", "csISOLatinArabic", "iso-ir-127", "ASMO-708", "ECMA-114", "8859_6", "cp1089"},
+	EncodingISO88597:    {"ibm-9005_X110-2007", "ibm-9005", "ISO-8859-7", "ISO_8859-7:1987", "greek", "greek8", "ELOT_928", "ECMA-118", "csISOLatinGreek", "iso-ir-126", "8859_7"},
+	EncodingISO88598:    {"ibm-5012_P100-1999", "ibm-5012", "ISO-8859-8", "ISO_8859-8:1988", "hebrew", "csISOLatinHebrew", "iso-ir-138", "8859_8", "ISO-8859-8-I", "ISO-8859-8-E"},
+	EncodingISO88599:    {"ibm-920_P100-1995", "ibm-920", "ISO-8859-9", "ISO_8859-9:1989", "latin5", "csISOLatin5", "iso-ir-148", "l5", "8859_9", "cp920", "ECMA-128", "turkish"},
+	EncodingISO885910:   {"iso-8859_10-1998", "ISO-8859-10", "ISO_8859-10:1992", "iso-ir-157", "l6", "csISOLatin6", "latin6"},
+	EncodingISO885913:   {"ibm-921_P100-1995", "ibm-921", "ISO-8859-13", "8859_13", "cp921"},
+	EncodingISO885914:   {"iso-8859_14-1998", "ISO-8859-14", "ISO_8859-14:1998", "iso-ir-199", "latin8", "iso-celtic", "l8"},
+	EncodingISO885915:   {"ibm-923_P100-1998", "ibm-923", "ISO-8859-15", "Latin-9", "l9", "8859_15", "latin0", "csisolatin0", "csisolatin9", "cp923"},
+	EncodingISO885916:   {"iso-8859_16-2001", "ISO-8859-16", "ISO_8859-16:2001", "iso-ir-226", "latin10", "l10"},
+	EncodingWindows874:  {"windows-874-2000", "windows-874", "MS874", "x-windows-874"},
+	EncodingWindows1250: {"ibm-5346_P100-1998", "ibm-5346", "windows-1250", "cp1250"},
+	EncodingWindows1251: {"ibm-5347_P100-1998", "ibm-5347", "windows-1251", "cp1251", "ANSI1251"},
+	EncodingWindows1252: {"ibm-5348_P100-1997", "ibm-5348", "windows-1252", "cp1252"},
+	EncodingWindows1253: {"ibm-5349_P100-1998", "ibm-5349", "windows-1253", "cp1253"},
+	EncodingWindows1254: {"ibm-5350_P100-1998", "ibm-5350", "windows-1254", "cp1254"},
+	EncodingWindows1255: {"ibm-9447_P100-2002", "ibm-9447", "windows-1255", "cp1255"},
+	EncodingWindows1256: {"ibm-9448_X100-2005", "ibm-9448", "windows-1256", "cp1256"},
+	EncodingWindows1257: {"ibm-9449_P100-2002", "ibm-9449", "windows-1257", "cp1257"},
+	EncodingWindows1258: {"ibm-5354_P100-1998", "ibm-5354", "windows-1258", "cp1258"},
+	EncodingTIS620:      {"ibm-874_P100-1995", "ibm-874", "ibm-9066", "cp874", "TIS-620", "tis620.2533", "eucTH", "x-IBM874"},
+	EncodingKOI8R:       {"ibm-878_P100-1996", "ibm-878", "KOI8-R", "koi8", "csKOI8R", "windows-20866", "cp878"},
+	EncodingKOI8U:       {"ibm-1168_P100-2002", "ibm-1168", "KOI8-U", "windows-21866"},
+	EncodingCP437:       {"ibm-437_P100-1995", "ibm-437", "IBM437", "cp437", "437", "csPC8CodePage437", "windows-437"},
+	EncodingCP850:       {"ibm-850_P100-1995", "ibm-850", "IBM850", "cp850", "850", "csPC850Multilingual", "windows-850"},
+	EncodingCP852:       {"ibm-852_P100-1995", "ibm-852", "IBM852", "cp852", "852", "csPCp852", "windows-852"},
+	EncodingCP855:       {"ibm-855_P100-1995", "ibm-855", "IBM855", "cp855", "855", "csIBM855", "csPCp855", "windows-855"},
+	EncodingCP858:       {"ibm-858_P100-1997", "ibm-858", "IBM00858", "CCSID00858", "CP00858", "PC-Multilingual-850+euro", "cp858", "windows-858"},
+	EncodingCP860:       {"ibm-860_P100-1995", "ibm-860", "IBM860", "cp860", "860", "csIBM860"},
+	EncodingCP862:       {"ibm-862_P100-1995", "ibm-862", "IBM862", "cp862", "862", "csPC862LatinHebrew", "DOS-862", "windows-862"},
+	EncodingCP863:       {"ibm-863_P100-1995", "ibm-863", "IBM863", "cp863", "863", "csIBM863"},
+	EncodingCP865:       {"ibm-865_P100-1995", "ibm-865", "IBM865", "cp865", "865", "csIBM865"},
+	EncodingCP866:       {"ibm-866_P100-1995", "ibm-866", "IBM866", "cp866", "csIBM866"},
+	EncodingMacintosh:   {"macos-0_2-10.2", "macintosh", "mac", "csMacintosh", "windows-10000", "macroman", "x-macroman"},
+	EncodingMacCyrillic: {"macos-7_3-10.2", "x-mac-cyrillic", "MacCyrillic", "windows-10007", "mac-cyrillic"},
+}
+
+// icuAliases 规范化的 ICU 名称到本包编码名称的映射
+var icuAliases = func() map[string]string {
+	aliases := make(map[string]string)
+	for name, icuNames := range icuConverters {
+		for _, alias := range icuNames {
+			aliases[normalizeICUName(alias)] = name
+		}
+	}
+	return aliases
+}()
+
+// normalizeICUName 按 ICU 的名称比较规则规范化名称（忽略大小写和非字母数字字符）
+func normalizeICUName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// ResolveICUName 将 ICU 转换器名称或别名解析为本包的编码名称
+//
+// 与 ICU 一样比较名称时忽略大小写以及 "-"、"_"、空格等分隔符。
+func ResolveICUName(name string) (string, bool) {
+	encoding, ok := icuAliases[normalizeICUName(name)]
+	return encoding, ok
+}
+
+// ICUCanonicalName 返回本包编码名称对应的 ICU 规范转换器名称
+func ICUCanonicalName(encodingName string) (string, bool) {
+	names, ok := icuConverters[encodingName]
+	if !ok {
+		return "", false
+	}
+	return names[0], true
+}
+
+// icuEscape 按 ICU 默认转义格式（UCNV_ESCAPE_ICU）转义字符，如 %U4E2D，增补平面字符按代理对转义
+func icuEscape(r rune) string {
+	if r > 0xFFFF {
+		high, low := utf16.EncodeRune(r)
+		return fmt.Sprintf("%%U%04X%%U%04X", high, low)
+	}
+	return fmt.Sprintf("%%U%04X", r)
+}
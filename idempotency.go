@@ -0,0 +1,378 @@
+package encoding
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// 幂等记录状态
+const (
+	IdempotencyClaimed   = "claimed"   // 已声明，正在转换
+	IdempotencyCompleted = "completed" // 已转换完成
+)
+
+// IdempotencyRecord 以路径和内容校验和为键的转换记录
+type IdempotencyRecord struct {
+	// Key 幂等键（相对路径、输入内容 SHA-256 和目标编码的 SHA-256，十六进制）
+	Key string `json:"key"`
+
+	// Path 计算键时使用的路径（相对于 IdempotencyOptions.Root，使用 / 分隔）
+	Path string `json:"path"`
+
+	// Checksum 输入内容的 SHA-256（十六进制）
+	Checksum string `json:"checksum"`
+
+	// OutputChecksum 输出内容的 SHA-256（完成后记录）
+	OutputChecksum string `json:"output_checksum,omitempty"`
+
+	// SourceEncoding 源编码（完成后记录）
+	SourceEncoding string `json:"source_encoding,omitempty"`
+
+	// TargetEncoding 目标编码
+	TargetEncoding string `json:"target_encoding"`
+
+	// Worker 声明或完成转换的工作者
+	Worker string `json:"worker"`
+
+	// State 记录状态（IdempotencyClaimed 或 IdempotencyCompleted）
+	State string `json:"state"`
+
+	// UpdatedAt 最后更新时间
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IdempotencyStore 幂等记录的存储
+//
+// 多个进程（可能位于不同机器）共享同一存储，实现需要保证 Claim 的原子性：
+// 同一个键只有一个调用方能声明成功。
+type IdempotencyStore interface {
+	// Claim 声明键；键已被声明或已完成时返回已有记录和 false
+	Claim(record *IdempotencyRecord) (*IdempotencyRecord, bool, error)
+
+	// Complete 将记录标记为已完成（键不存在时创建）
+	Complete(record *IdempotencyRecord) error
+
+	// Release 删除声明，使其他工作者可以重新处理（转换失败时调用）
+	Release(key string) error
+}
+
+// IdempotencyOptions 幂等转换选项
+type IdempotencyOptions struct {
+	// Store 幂等记录存储
+	Store IdempotencyStore `json:"-"`
+
+	// Root 计算键时路径的基准目录（为空时使用绝对路径）；
+	// 各机器挂载共享存储的位置不同时设置为各自的挂载点
+	Root string `json:"root,omitempty"`
+
+	// Worker 工作者标识（默认为 主机名:进程号）
+	Worker string `json:"worker,omitempty"`
+}
+
+// key 计算路径、内容校验和与目标编码对应的幂等键
+func (o *IdempotencyOptions) key(path, checksum, targetEncoding string) string {
+	hash := sha256.New()
+	io.WriteString(hash, o.relPath(path))
+	hash.Write([]byte{0})
+	io.WriteString(hash, checksum)
+	hash.Write([]byte{0})
+	if resolved, ok := ResolveEncodingName(targetEncoding); ok {
+		targetEncoding = resolved
+	}
+	io.WriteString(hash, targetEncoding)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// relPath 返回相对于 Root 的路径（无法计算时使用绝对路径），统一使用 / 分隔
+func (o *IdempotencyOptions) relPath(path string) string {
+	path = displayPath(path)
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if o.Root != "" {
+		if root, err := filepath.Abs(o.Root); err == nil {
+			if rel, err := filepath.Rel(root, path); err == nil {
+				path = rel
+			}
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// worker 返回工作者标识
+func (o *IdempotencyOptions) worker() string {
+	if o.Worker != "" {
+		return o.Worker
+	}
+	host, _ := os.Hostname()
+	return host + ":" + strconv.Itoa(os.Getpid())
+}
+
+// newRecord 创建文件当前内容对应的幂等记录
+func (o *IdempotencyOptions) newRecord(path, checksum, targetEncoding string) *IdempotencyRecord {
+	return &IdempotencyRecord{
+		Key:            o.key(path, checksum, targetEncoding),
+		Path:           o.relPath(path),
+		Checksum:       checksum,
+		TargetEncoding: targetEncoding,
+		Worker:         o.worker(),
+	}
+}
+
+// processFileOnce 按幂等键转换文件：相同路径、内容和目标编码只转换一次
+//
+// 转换前以输入内容声明键：已被其他工作者完成时跳过，已被声明但尚未完成时返回 ErrConversionInProgress；
+// 转换失败时释放声明。转换成功后同时为输出内容记录完成状态，之后对已转换文件的就地处理也会跳过。
+func (fp *defaultFileProcessor) processFileOnce(inputFile, outputFile string, options *FileProcessOptions) (*FileProcessResult, error) {
+	idempotency := options.Idempotency
+	checksum, err := fileChecksum(inputFile)
+	if err != nil {
+		return nil, &FileOperationError{
+			Op:   "checksum",
+			File: inputFile,
+			Err:  err,
+		}
+	}
+
+	record := idempotency.newRecord(inputFile, checksum, options.TargetEncoding)
+	existing, claimed, err := idempotency.Store.Claim(record)
+	if err != nil {
+		return nil, &FileOperationError{
+			Op:   "idempotency_claim",
+			File: inputFile,
+			Err:  err,
+		}
+	}
+	if !claimed {
+		if existing.State != IdempotencyCompleted {
+			return nil, &FileOperationError{
+				Op:   "idempotency_claim",
+				File: inputFile,
+				Err:  fmt.Errorf("%w (claimed by %s)", ErrConversionInProgress, existing.Worker),
+			}
+		}
+		return &FileProcessResult{
+			InputFile:        inputFile,
+			OutputFile:       outputFile,
+			SourceEncoding:   existing.SourceEncoding,
+			TargetEncoding:   options.TargetEncoding,
+			AlreadyProcessed: true,
+		}, nil
+	}
+
+	result, err := fp.produceFile(inputFile, outputFile, options)
+	if err != nil {
+		idempotency.Store.Release(record.Key)
+		return result, err
+	}
+
+	outputChecksum, err := fileChecksum(outputFile)
+	if err != nil {
+		idempotency.Store.Release(record.Key)
+		return nil, &FileOperationError{
+			Op:   "checksum",
+			File: outputFile,
+			Err:  err,
+		}
+	}
+	record.SourceEncoding = result.SourceEncoding
+	record.OutputChecksum = outputChecksum
+	if err := idempotency.Store.Complete(record); err != nil {
+		return nil, &FileOperationError{
+			Op:   "idempotency_complete",
+			File: inputFile,
+			Err:  err,
+		}
+	}
+
+	output := idempotency.newRecord(outputFile, outputChecksum, options.TargetEncoding)
+	if output.Key != record.Key {
+		output.SourceEncoding = result.SourceEncoding
+		output.OutputChecksum = outputChecksum
+		if err := idempotency.Store.Complete(output); err != nil {
+			return nil, &FileOperationError{
+				Op:   "idempotency_complete",
+				File: outputFile,
+				Err:  err,
+			}
+		}
+	}
+	return result, nil
+}
+
+// fileChecksum 计算文件内容的 SHA-256（十六进制）
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// dirIdempotencyStore 每个键一个 JSON 文件的目录存储
+type dirIdempotencyStore struct {
+	dir        string
+	staleAfter time.Duration
+}
+
+// NewDirIdempotencyStore 创建以目录保存幂等记录的存储，目录可以位于多台机器共享的存储上
+//
+// 声明通过独占创建文件（O_EXCL）实现；staleAfter 大于 0 时，超过该时长仍未完成的声明
+// 视为工作者已中断，可以被重新声明。
+func NewDirIdempotencyStore(dir string, staleAfter time.Duration) IdempotencyStore {
+	return &dirIdempotencyStore{dir: dir, staleAfter: staleAfter}
+}
+
+// Claim 实现 IdempotencyStore 接口
+func (s *dirIdempotencyStore) Claim(record *IdempotencyRecord) (*IdempotencyRecord, bool, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, false, err
+	}
+
+	path := s.path(record.Key)
+	for attempt := 0; attempt < 2; attempt++ {
+		record.State = IdempotencyClaimed
+		record.UpdatedAt = time.Now()
+		data, err := json.Marshal(record)
+		if err != nil {
+			return nil, false, err
+		}
+
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = file.Write(data)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, false, err
+			}
+			return record, true, nil
+		}
+		if !os.IsExist(err) {
+			return nil, false, err
+		}
+
+		existing, err := s.read(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			// 其他工作者刚创建、尚未写完的记录
+			return &IdempotencyRecord{Key: record.Key, State: IdempotencyClaimed}, false, nil
+		}
+		if existing.State == IdempotencyClaimed && s.staleAfter > 0 && time.Since(existing.UpdatedAt) > s.staleAfter {
+			taken, err := s.takeOver(path, existing)
+			if err != nil {
+				return nil, false, err
+			}
+			if taken {
+				continue
+			}
+		}
+		return existing, false, nil
+	}
+	return nil, false, fmt.Errorf("idempotency key %s is contended", record.Key)
+}
+
+// takeOver 删除过期的声明，返回是否可以重新声明
+//
+// 多个工作者可能同时发现同一个过期声明：删除前独占创建接管锁文件，持有锁期间重新读取记录，
+// 确认仍是同一个过期声明才删除，避免删除其他工作者接管后写入的新声明。接管锁本身超过 staleAfter
+// （接管中途中断）时删除，下次声明时重试。
+func (s *dirIdempotencyStore) takeOver(path string, stale *IdempotencyRecord) (bool, error) {
+	lock := path + ".takeover"
+	file, err := os.OpenFile(lock, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > s.staleAfter {
+			os.Remove(lock)
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	file.Close()
+	defer os.Remove(lock)
+
+	current, err := s.read(path)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil || current.State != IdempotencyClaimed || current.Worker != stale.Worker || !current.UpdatedAt.Equal(stale.UpdatedAt) {
+		return false, nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	return true, nil
+}
+
+// Complete 实现 IdempotencyStore 接口，通过临时文件和重命名原子地写入记录
+func (s *dirIdempotencyStore) Complete(record *IdempotencyRecord) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	record.State = IdempotencyCompleted
+	record.UpdatedAt = time.Now()
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(s.dir, "."+record.Key+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), s.path(record.Key))
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+	}
+	return err
+}
+
+// Release 实现 IdempotencyStore 接口
+func (s *dirIdempotencyStore) Release(key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// path 返回键对应的记录文件路径
+func (s *dirIdempotencyStore) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
+
+// read 读取记录文件
+func (s *dirIdempotencyStore) read(path string) (*IdempotencyRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var record IdempotencyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
@@ -6,13 +6,37 @@ import (
 	"time"
 )
 
-// Detector 编码检测器接口
-type Detector interface {
+// ByteDetector 字节数据编码检测接口
+//
+// 以下角色接口组合成 Detector 与 Converter，只使用部分功能的调用方可以仅依赖
+// （以及在测试中仅模拟）所需的小接口。
+type ByteDetector interface {
 	// DetectEncoding 检测数据的编码格式
 	DetectEncoding(data []byte) (*DetectionResult, error)
+}
 
+// FileDetector 文件编码检测接口
+type FileDetector interface {
 	// DetectFileEncoding 检测文件的编码格式
 	DetectFileEncoding(filename string) (*DetectionResult, error)
+}
+
+// ByteConverter 字节数据编码转换接口
+type ByteConverter interface {
+	// Convert 在指定编码之间转换
+	Convert(data []byte, from, to string) ([]byte, error)
+}
+
+// StringConverter 字符串编码转换接口
+type StringConverter interface {
+	// ConvertString 字符串编码转换
+	ConvertString(text, from, to string) (string, error)
+}
+
+// Detector 编码检测器接口
+type Detector interface {
+	ByteDetector
+	FileDetector
 
 	// DetectBestEncoding 检测最可能的编码格式（简化版本）
 	DetectBestEncoding(data []byte) (string, error)
@@ -23,17 +47,17 @@ type Detector interface {
 
 // Converter 编码转换器接口
 type Converter interface {
-	// Convert 在指定编码之间转换
-	Convert(data []byte, from, to string) ([]byte, error)
+	ByteConverter
+	StringConverter
 
 	// ConvertToUTF8 转换为 UTF-8 编码
 	ConvertToUTF8(data []byte, from string) ([]byte, error)
-
-	// ConvertString 字符串编码转换
-	ConvertString(text, from, to string) (string, error)
 }
 
 // Processor 编码处理器接口，集成检测和转换功能
+//
+// 默认实现还实现 Drainer：长期运行的服务在退出前应调用 Drain 或 Close，等待进行中的操作完成
+// 并释放检测缓存、池化的转换器等资源，关闭后的调用返回 ErrClosed。
 type Processor interface {
 	Detector
 	Converter
@@ -45,7 +69,71 @@ type Processor interface {
 	SmartConvertString(text, target string) (*StringConvertResult, error)
 }
 
-// StreamProcessor 流式处理接口
+// 以下扩展接口由默认的检测器、转换器和处理器实现，但不属于 Detector、Converter 和 Processor，
+// 使自定义实现不必随新功能增加方法；调用方通过类型断言使用，例如：
+//
+//	if cd, ok := processor.(encoding.ContextDetector); ok {
+//		result, err = cd.DetectEncodingContext(ctx, data)
+//	}
+
+// ContextDetector 支持取消的编码检测接口
+type ContextDetector interface {
+	// DetectEncodingContext 检测数据的编码格式（支持取消）
+	DetectEncodingContext(ctx context.Context, data []byte) (*DetectionResult, error)
+}
+
+// CandidateDetector 返回全部候选编码的检测接口
+type CandidateDetector interface {
+	// DetectAllEncodings 返回所有候选编码及其得分组成（按综合得分降序）
+	DetectAllEncodings(data []byte) ([]*DetectionCandidate, error)
+}
+
+// ReaderDetector 从读取器检测编码的接口
+type ReaderDetector interface {
+	// DetectReaderEncoding 读取样本检测编码，返回检测结果和重放已读取数据的读取器
+	DetectReaderEncoding(r io.Reader) (*DetectionResult, io.Reader, error)
+}
+
+// ContentHintDetector 结合内容中的编码提示检测编码的接口
+type ContentHintDetector interface {
+	// DetectWithContentHints 结合 BOM、Content-Type、XML 声明和 HTML meta 等编码提示检测编码
+	DetectWithContentHints(data []byte, contentType string) (*DetectionResult, error)
+}
+
+// ExplainingDetector 报告检测过程的接口
+type ExplainingDetector interface {
+	// ExplainDetection 报告检测的每项检查（BOM、UTF-8 有效性、后端候选、启发式得分、缓存命中）及选择结果的原因
+	ExplainDetection(data []byte) (*DetectionExplanation, error)
+}
+
+// ContextConverter 支持取消和超时的编码转换接口
+type ContextConverter interface {
+	// ConvertContext 在指定编码之间转换（支持取消和超时）
+	ConvertContext(ctx context.Context, data []byte, from, to string) ([]byte, error)
+}
+
+// OptionsConverter 按单次转换选项转换的接口
+type OptionsConverter interface {
+	// ConvertWithOptions 按单次转换选项在指定编码之间转换，返回包含 BOM 处理情况的转换结果
+	ConvertWithOptions(data []byte, from, to string, options *ConvertOptions) (*ConvertResult, error)
+}
+
+// ContextSmartConverter 支持取消和超时的智能转换接口
+type ContextSmartConverter interface {
+	// SmartConvertContext 智能转换（自动检测源编码，支持取消和超时）
+	SmartConvertContext(ctx context.Context, data []byte, target string) (*ConvertResult, error)
+}
+
+// Validator 编码校验接口，检查数据在声明的编码下是否完全有效
+type Validator interface {
+	// ValidateBytes 校验数据在指定编码下是否有效，返回无效字节序列的位置和内容
+	ValidateBytes(data []byte, encoding string) (*ValidationResult, error)
+
+	// ValidateFile 校验文件内容在指定编码下是否有效
+	ValidateFile(filename, encoding string) (*ValidationResult, error)
+}
+
+// StreamProcessor 流式处理接口（默认实现还实现 Drainer）
 type StreamProcessor interface {
 	// ProcessReader 处理输入流
 	ProcessReader(ctx context.Context, r io.Reader, sourceEncoding, targetEncoding string) (io.Reader, error)
@@ -57,7 +145,7 @@ type StreamProcessor interface {
 	ProcessReaderWriter(ctx context.Context, r io.Reader, w io.Writer, options *StreamOptions) (*StreamResult, error)
 }
 
-// FileProcessor 文件处理接口
+// FileProcessor 文件处理接口（默认实现还实现 Drainer）
 type FileProcessor interface {
 	// ProcessFile 处理文件（检测并转换编码）
 	ProcessFile(inputFile, outputFile string, options *FileProcessOptions) (*FileProcessResult, error)
@@ -72,6 +160,18 @@ type FileProcessor interface {
 	ProcessFileToString(filename, targetEncoding string) (string, error)
 }
 
+// BatchProcessor 批量文件处理接口
+type BatchProcessor interface {
+	// ProcessFiles 按调度策略批量就地处理文件
+	ProcessFiles(ctx context.Context, files []string, options *BatchOptions) (*BatchResult, error)
+
+	// ProcessDirectory 遍历目录树，批量就地处理匹配过滤条件的文件
+	ProcessDirectory(dir string, options *BatchOptions) (*BatchResult, error)
+
+	// ProcessDirectoryContext 遍历目录树，批量就地处理匹配过滤条件的文件（支持取消）
+	ProcessDirectoryContext(ctx context.Context, dir string, options *BatchOptions) (*BatchResult, error)
+}
+
 // MetricsCollector 性能监控和统计接口
 type MetricsCollector interface {
 	// GetStats 获取处理统计信息
@@ -87,7 +187,102 @@ type MetricsCollector interface {
 	RecordError(operation string, err error)
 }
 
+// LabeledMetricsCollector 支持按标签（租户、流水线等）聚合的性能监控接口
+type LabeledMetricsCollector interface {
+	MetricsCollector
+
+	// RecordOperationWithLabels 记录带标签的操作
+	RecordOperationWithLabels(operation string, duration time.Duration, labels MetricsLabels)
+
+	// RecordErrorWithLabels 记录带标签的错误
+	RecordErrorWithLabels(operation string, err error, labels MetricsLabels)
+
+	// RecordBytesWithLabels 记录带标签的字节数
+	RecordBytesWithLabels(bytes int64, labels MetricsLabels)
+
+	// RecordMemoryUsageWithLabels 记录带标签的内存占用
+	RecordMemoryUsageWithLabels(usage MemoryUsage, labels MetricsLabels)
+
+	// GetLabeledStats 获取按标签集合聚合的统计信息
+	GetLabeledStats() map[string]*ProcessingStats
+
+	// GetStatsByLabel 获取包含指定标签键值的汇总统计
+	GetStatsByLabel(key, value string) *ProcessingStats
+}
+
+// EncodingMetricsCollector 支持记录处理字节数、编码分布和丢失字符的性能监控接口
+type EncodingMetricsCollector interface {
+	MetricsCollector
+
+	// RecordBytes 记录处理的字节数
+	RecordBytes(bytes int64)
+
+	// RecordEncoding 记录检测到的编码
+	RecordEncoding(encoding string)
+
+	// RecordLostRunes 累计因目标编码无法表示而被替换或丢弃的字符
+	RecordLostRunes(lost map[string]int64)
+}
+
+// CacheMetricsCollector 支持记录缓存命中情况的性能监控接口
+type CacheMetricsCollector interface {
+	MetricsCollector
+
+	// RecordCacheAccess 记录一次缓存查找（hit 表示命中）
+	RecordCacheAccess(hit bool)
+
+	// RecordCacheEviction 记录一次因缓存已满而淘汰条目
+	RecordCacheEviction()
+}
+
+// MemoryMetricsCollector 支持记录近似内存占用的性能监控接口
+type MemoryMetricsCollector interface {
+	MetricsCollector
+
+	// RecordMemoryUsage 记录单次操作的近似内存占用
+	RecordMemoryUsage(usage MemoryUsage)
+}
+
+// FlushingMetricsCollector 缓冲指标、需要在关闭时导出的性能监控接口
+//
+// 检测器关闭（Drain、Close）时调用 Flush，避免服务退出时丢失尚未导出的指标。
+type FlushingMetricsCollector interface {
+	MetricsCollector
+
+	// Flush 导出缓冲的指标
+	Flush() error
+}
+
+// TracerProvider 分布式追踪器的提供者
+//
+// 接口形状与 OpenTelemetry 的 trace.TracerProvider 一致，但不依赖其 SDK，
+// 使用 OpenTelemetry 时通过 otelenc.NewTracerProvider 接入。
+type TracerProvider interface {
+	// Tracer 返回指定名称的追踪器
+	Tracer(name string) Tracer
+}
+
+// Tracer 创建追踪跨度
+type Tracer interface {
+	// Start 开始一个跨度，返回的上下文携带该跨度（ctx 中已有跨度时作为其子跨度）
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span 追踪跨度
+type Span interface {
+	// SetAttributes 设置跨度属性
+	SetAttributes(attributes ...SpanAttribute)
+
+	// RecordError 记录操作错误
+	RecordError(err error)
+
+	// End 结束跨度
+	End()
+}
+
 // Logger 日志记录器接口
+//
+// fields 为交替的键值对（如 "file", path, "encoding", name），与 log/slog 的约定一致，见 NewSlogLogger。
 type Logger interface {
 	Debug(msg string, fields ...interface{})
 	Info(msg string, fields ...interface{})
@@ -0,0 +1,149 @@
+package encoding
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+)
+
+// maxInvalidByteOutput 处理一个字符前要求的最小输出空间（足够容纳一个字符的转义结果）
+const maxInvalidByteOutput = 32
+
+// invalidBytesDecoder 按 InvalidBytePolicy 处理解码器遇到的无效字节序列
+//
+// 逐字符解码以获得每个 U+FFFD 对应的源字节；源数据中正确编码的 U+FFFD 原样输出。
+// trace 不为 nil 时统计无效字节序列数。
+type invalidBytesDecoder struct {
+	decoder     transform.Transformer
+	policy      string
+	replacement []byte // replace 策略输出的 UTF-8 字节
+	genuine     []byte // U+FFFD 在源编码中的字节序列
+	trace       *conversionTrace
+}
+
+// newInvalidBytesDecoder 包装 from 编码的解码器（源编码为 UTF-8 时同样需要解码器以识别无效字节）
+func (c *defaultConverter) newInvalidBytesDecoder(decoder transform.Transformer, from string, trace *conversionTrace) transform.Transformer {
+	replacement := c.config.InvalidByteReplacement
+	if replacement == 0 {
+		replacement = utf8.RuneError
+	}
+	d := &invalidBytesDecoder{
+		decoder:     decoder,
+		policy:      c.config.InvalidBytePolicy,
+		replacement: []byte(string(replacement)),
+		genuine:     replacementChar,
+		trace:       trace,
+	}
+	if enc, err := c.getEncoding(from); err == nil && from != EncodingUTF8 {
+		d.genuine = encodedReplacementChar(enc)
+	}
+	return d
+}
+
+// Transform 实现 transform.Transformer 接口
+func (d *invalidBytesDecoder) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	var buf [2 * utf8.UTFMax]byte
+	for nSrc < len(src) {
+		// 先保证输出空间，避免有状态的解码器在字符已解码后因空间不足而重复处理
+		if len(dst)-nDst < maxInvalidByteOutput {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+
+		n, size, err := decodeNextChar(d.decoder, buf[:], src[nSrc:], atEOF)
+		if err != nil {
+			return nDst, nSrc, err
+		}
+		out := buf[:n]
+		if source := src[nSrc : nSrc+size]; bytes.ContainsRune(out, utf8.RuneError) && !bytes.Equal(source, d.genuine) {
+			if d.policy == InvalidByteFail {
+				return nDst, nSrc, ErrInvalidByteSequence
+			}
+			if d.trace != nil {
+				d.trace.invalidSequences++
+			}
+			out = d.substitute(source)
+		}
+		nDst += copy(dst[nDst:], out)
+		nSrc += size
+	}
+	return nDst, nSrc, nil
+}
+
+// substitute 返回无效字节序列的替换输出
+func (d *invalidBytesDecoder) substitute(source []byte) []byte {
+	switch d.policy {
+	case InvalidByteSkip:
+		return nil
+	case InvalidByteHexEscape:
+		return []byte(hexEscape(source))
+	case InvalidByteNumericCharRef:
+		var b strings.Builder
+		for _, c := range source {
+			fmt.Fprintf(&b, "&#x%02X;", c)
+		}
+		return []byte(b.String())
+	default:
+		return d.replacement
+	}
+}
+
+// Reset 实现 transform.Transformer 接口
+func (d *invalidBytesDecoder) Reset() {
+	d.decoder.Reset()
+}
+
+// hexEscape 将每个字节输出为 \xNN
+func hexEscape(data []byte) string {
+	var b strings.Builder
+	for _, c := range data {
+		fmt.Fprintf(&b, `\x%02X`, c)
+	}
+	return b.String()
+}
+
+// unmappableCallback 返回 InvalidBytePolicy 对应的无法表示字符的替换回调
+//
+// replace 策略使用 InvalidCharReplacement（返回 nil）。
+func (c *defaultConverter) unmappableCallback() func(r rune) string {
+	switch c.config.InvalidBytePolicy {
+	case InvalidByteSkip:
+		return func(rune) string { return "" }
+	case InvalidByteHexEscape:
+		return func(r rune) string { return hexEscape([]byte(string(r))) }
+	case InvalidByteNumericCharRef:
+		return func(r rune) string { return fmt.Sprintf("&#x%04X;", r) }
+	default:
+		return nil
+	}
+}
+
+// decodeNextChar 解码 src 开头的一个字符（包括只改变解码器状态的转义序列）
+//
+// 逐步扩大输入长度，使解码器每次只消费一个字符；数据未结束且 src 可能只是字符的一部分时返回 transform.ErrShortSrc。
+// 解码器无法处理的字节按单个无效字节输出 U+FFFD。
+func decodeNextChar(decoder transform.Transformer, dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for k := 1; k <= len(src) && k <= utf8.UTFMax; k++ {
+		nDst, nSrc, err := decoder.Transform(dst, src[:k], atEOF && k == len(src))
+		if nSrc > 0 {
+			return nDst, nSrc, nil
+		}
+		if err != nil && err != transform.ErrShortSrc {
+			break
+		}
+	}
+	if !atEOF && len(src) < utf8.UTFMax {
+		return 0, 0, transform.ErrShortSrc
+	}
+
+	nDst, nSrc, err = decoder.Transform(dst, src, atEOF)
+	if nSrc == 0 {
+		if err == transform.ErrShortSrc && !atEOF {
+			return 0, 0, err
+		}
+		return copy(dst, replacementChar), 1, nil
+	}
+	return nDst, nSrc, nil
+}
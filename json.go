@@ -0,0 +1,135 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+)
+
+// ConvertJSON 检测 JSON 文档的编码并转换到目标编码
+//
+// 字符串字面量中目标编码无法表示的字符会被改写为 \uXXXX 转义（必要时使用代理对），
+// 保证输出在目标字符集中仍然是合法的 JSON。已有的转义序列保持不变。
+func ConvertJSON(data []byte, target string) (*ConvertResult, error) {
+	start := time.Now()
+	if target == "" {
+		target = EncodingUTF8
+	}
+
+	converter := NewConverter().(*defaultConverter)
+
+	source := sniffJSONEncoding(data)
+	if source == "" {
+		detection, err := newTransientDetector().DetectEncoding(data)
+		if err != nil {
+			return nil, err
+		}
+		source = detection.Encoding
+	}
+
+	text, err := converter.Convert(data, source, EncodingUTF8)
+	if err != nil {
+		return nil, err
+	}
+	text = bytes.TrimPrefix(text, []byte("\xEF\xBB\xBF"))
+
+	if !json.Valid(text) {
+		return nil, &EncodingError{
+			Op:       OperationConvert,
+			Encoding: source,
+			Err:      fmt.Errorf("%w: not a valid JSON document", ErrInvalidInput),
+		}
+	}
+
+	escaped := text
+	if target != EncodingUTF8 {
+		encoder, err := converter.getEncoder(target)
+		if err != nil {
+			return nil, &EncodingError{Op: OperationConvert, Encoding: target, Err: err}
+		}
+		escaped = escapeJSONUnrepresentable(text, encoder)
+	}
+
+	output, err := converter.Convert(escaped, EncodingUTF8, target)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConvertResult{
+		Data:           output,
+		SourceEncoding: source,
+		TargetEncoding: target,
+		BytesProcessed: int64(len(data)),
+		ConversionTime: time.Since(start),
+	}, nil
+}
+
+// sniffJSONEncoding 按 RFC 4627 的零字节模式识别 UTF-16/UTF-32 编码的 JSON
+func sniffJSONEncoding(data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+
+	switch {
+	case data[0] == 0 && data[1] == 0 && data[2] == 0 && data[3] != 0:
+		return EncodingUTF32BE
+	case data[0] != 0 && data[1] == 0 && data[2] == 0 && data[3] == 0:
+		return EncodingUTF32LE
+	case data[0] == 0 && data[1] != 0 && data[2] == 0 && data[3] != 0:
+		return EncodingUTF16BE
+	case data[0] != 0 && data[1] == 0 && data[2] != 0 && data[3] == 0:
+		return EncodingUTF16LE
+	}
+	return ""
+}
+
+// escapeJSONUnrepresentable 将 JSON 字符串字面量中目标编码无法表示的字符改写为 \uXXXX 转义
+func escapeJSONUnrepresentable(text []byte, encoder transform.Transformer) []byte {
+	var out bytes.Buffer
+	out.Grow(len(text))
+
+	inString := false
+	escaped := false
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRune(text[i:])
+		chunk := text[i : i+size]
+		i += size
+
+		switch {
+		case escaped:
+			escaped = false
+		case inString && r == '\\':
+			escaped = true
+		case r == '"':
+			inString = !inString
+		case inString && r >= utf8.RuneSelf && !canEncodeRune(encoder, chunk):
+			writeJSONEscape(&out, r)
+			continue
+		}
+		out.Write(chunk)
+	}
+
+	return out.Bytes()
+}
+
+// canEncodeRune 检查字符能否用目标编码表示
+func canEncodeRune(encoder transform.Transformer, runeBytes []byte) bool {
+	var buf [16]byte
+	encoder.Reset()
+	_, _, err := encoder.Transform(buf[:], runeBytes, true)
+	return err == nil
+}
+
+// writeJSONEscape 写入字符的 \uXXXX 转义（超出 BMP 时写入代理对）
+func writeJSONEscape(out *bytes.Buffer, r rune) {
+	if r > 0xFFFF {
+		r -= 0x10000
+		fmt.Fprintf(out, `\u%04x\u%04x`, 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+		return
+	}
+	fmt.Fprintf(out, `\u%04x`, r)
+}
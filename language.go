@@ -0,0 +1,172 @@
+package encoding
+
+import (
+	"math"
+	"unicode"
+	"unicode/utf8"
+)
+
+// languageModel 语言的文字和字母频率模型
+type languageModel struct {
+	// script 该语言使用的文字
+	script *unicode.RangeTable
+
+	// frequencies 小写字母的出现频率（百分比）
+	frequencies map[rune]float64
+}
+
+// 字母语言的频率模型（常见语料统计的近似值）
+var languageModels = map[string]*languageModel{
+	LanguageEnglish: {script: unicode.Latin, frequencies: map[rune]float64{
+		'e': 12.7, 't': 9.1, 'a': 8.2, 'o': 7.5, 'i': 7.0, 'n': 6.7, 's': 6.3, 'h': 6.1,
+		'r': 6.0, 'd': 4.3, 'l': 4.0, 'c': 2.8, 'u': 2.8, 'm': 2.4, 'w': 2.4, 'f': 2.2,
+		'g': 2.0, 'y': 2.0, 'p': 1.9, 'b': 1.5, 'v': 1.0, 'k': 0.8, 'j': 0.15, 'x': 0.15,
+		'q': 0.1, 'z': 0.07,
+	}},
+	LanguageGerman: {script: unicode.Latin, frequencies: map[rune]float64{
+		'e': 16.4, 'n': 9.8, 'i': 7.6, 's': 7.3, 'r': 7.0, 'a': 6.5, 't': 6.2, 'd': 5.1,
+		'h': 4.6, 'u': 4.2, 'l': 3.4, 'g': 3.0, 'c': 2.7, 'o': 2.6, 'm': 2.5, 'b': 1.9,
+		'w': 1.9, 'f': 1.7, 'k': 1.4, 'z': 1.1, 'ü': 1.0, 'p': 0.7, 'v': 0.8, 'ä': 0.6,
+		'ö': 0.3, 'ß': 0.3, 'j': 0.3,
+	}},
+	LanguageFrench: {script: unicode.Latin, frequencies: map[rune]float64{
+		'e': 14.7, 's': 7.9, 'a': 7.6, 'i': 7.5, 't': 7.2, 'n': 7.1, 'r': 6.7, 'u': 6.3,
+		'o': 5.8, 'l': 5.5, 'd': 3.7, 'c': 3.3, 'p': 3.0, 'm': 3.0, 'é': 1.9, 'v': 1.8,
+		'q': 1.4, 'f': 1.1, 'b': 0.9, 'g': 0.9, 'h': 0.7, 'j': 0.6, 'à': 0.5, 'x': 0.4,
+		'è': 0.3, 'ê': 0.2, 'y': 0.1, 'ç': 0.1,
+	}},
+	LanguageSpanish: {script: unicode.Latin, frequencies: map[rune]float64{
+		'e': 13.7, 'a': 12.5, 'o': 8.7, 's': 8.0, 'r': 6.9, 'n': 6.7, 'i': 6.2, 'd': 5.9,
+		'l': 5.0, 'c': 4.7, 't': 4.6, 'u': 3.9, 'm': 3.2, 'p': 2.5, 'b': 1.4, 'g': 1.0,
+		'v': 0.9, 'y': 0.9, 'q': 0.9, 'ó': 0.8, 'h': 0.7, 'f': 0.7, 'í': 0.5, 'á': 0.5,
+		'j': 0.4, 'é': 0.4, 'z': 0.5, 'ñ': 0.3, 'ú': 0.2,
+	}},
+	LanguageRussian: {script: unicode.Cyrillic, frequencies: map[rune]float64{
+		'о': 10.97, 'е': 8.45, 'а': 8.01, 'и': 7.35, 'н': 6.70, 'т': 6.26, 'с': 5.47, 'р': 4.73,
+		'в': 4.54, 'л': 4.40, 'к': 3.49, 'м': 3.21, 'д': 2.98, 'п': 2.81, 'у': 2.62, 'я': 2.01,
+		'ы': 1.90, 'ь': 1.74, 'г': 1.70, 'з': 1.65, 'б': 1.59, 'ч': 1.44, 'й': 1.21, 'х': 0.97,
+		'ж': 0.94, 'ш': 0.73, 'ю': 0.64, 'ц': 0.48, 'щ': 0.36, 'э': 0.32, 'ф': 0.26, 'ъ': 0.04,
+		'ё': 0.04,
+	}},
+}
+
+// ValidateAsLanguage 评估解码后的文本是否像指定语言，返回 0-1 之间的得分
+//
+// lang 为 ISO 639-1 语言代码（见 Language* 常量）。字母语言的得分为目标文字字母占比与
+// 字母频率分布相似度（余弦相似度）之积；中文、日文、韩文按文字占比及常用字、假名比例评分。
+// 替换字符（U+FFFD）计入分母以降低乱码文本的得分。不支持的语言返回 0。
+func ValidateAsLanguage(text string, lang string) float64 {
+	switch lang {
+	case LanguageChinese:
+		return scoreChinese(text)
+	case LanguageJapanese:
+		return scoreJapanese(text)
+	case LanguageKorean:
+		return scoreScriptShare(text, func(r rune) bool { return unicode.Is(unicode.Hangul, r) })
+	}
+
+	model, ok := languageModels[lang]
+	if !ok {
+		return 0
+	}
+
+	counts := make(map[rune]float64)
+	var inScript, total float64
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			if r == utf8.RuneError {
+				total++
+			}
+			continue
+		}
+		total++
+		if unicode.Is(model.script, r) {
+			inScript++
+			counts[unicode.ToLower(r)]++
+		}
+	}
+	if total == 0 || inScript == 0 {
+		return 0
+	}
+
+	return inScript / total * cosineSimilarity(counts, model.frequencies)
+}
+
+// cosineSimilarity 计算两个字母频率分布的余弦相似度
+func cosineSimilarity(observed, expected map[rune]float64) float64 {
+	var dot, normObserved, normExpected float64
+	for r, count := range observed {
+		dot += count * expected[r]
+		normObserved += count * count
+	}
+	for _, freq := range expected {
+		normExpected += freq * freq
+	}
+	if normObserved == 0 || normExpected == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normObserved*normExpected)
+}
+
+// scoreScriptShare 返回字母中属于指定文字的比例（替换字符计入分母）
+func scoreScriptShare(text string, inScript func(r rune) bool) float64 {
+	var matched, total float64
+	for _, r := range text {
+		if !unicode.IsLetter(r) && r != utf8.RuneError {
+			continue
+		}
+		total++
+		if inScript(r) {
+			matched++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return matched / total
+}
+
+// scoreChinese 按汉字占比及常用字（GB2312 一级字库或 Big5 常用字区）比例评分
+func scoreChinese(text string) float64 {
+	var han, common, total float64
+	for _, r := range text {
+		if !unicode.IsLetter(r) && r != utf8.RuneError {
+			continue
+		}
+		total++
+		if unicode.Is(unicode.Han, r) {
+			han++
+			if isCommonHanzi(r) {
+				common++
+			}
+		}
+	}
+	if total == 0 || han == 0 {
+		return 0
+	}
+	return han / total * (0.5 + 0.5*common/han)
+}
+
+// scoreJapanese 按假名与汉字占比评分，并要求出现一定比例的假名以区别于中文
+func scoreJapanese(text string) float64 {
+	var kana, han, total float64
+	for _, r := range text {
+		if !unicode.IsLetter(r) && r != utf8.RuneError {
+			continue
+		}
+		total++
+		switch {
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		}
+	}
+	if total == 0 || kana == 0 {
+		return 0
+	}
+
+	// 日文文本中假名通常占日文字符的三成以上
+	kanaFactor := math.Min(1, kana/(kana+han)/0.3)
+	return (kana + han) / total * kanaFactor
+}
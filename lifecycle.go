@@ -0,0 +1,97 @@
+package encoding
+
+import (
+	"context"
+	"sync"
+)
+
+// Drainer 可优雅关闭的长期运行组件接口
+type Drainer interface {
+	// Drain 停止接受新任务并等待进行中的操作完成，随后释放资源
+	Drain(ctx context.Context) error
+
+	// Close 立即停止接受新任务并等待进行中的操作完成
+	Close() error
+}
+
+// lifecycle 组件生命周期管理：跟踪进行中的操作并支持优雅关闭
+type lifecycle struct {
+	mutex    sync.Mutex
+	closed   bool
+	inflight int
+	idle     chan struct{}
+	released bool
+}
+
+// acquire 登记一个进行中的操作，组件已关闭时返回 ErrClosed
+func (l *lifecycle) acquire() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	l.inflight++
+	return nil
+}
+
+// release 结束一个进行中的操作
+func (l *lifecycle) release() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.inflight--
+	if l.inflight == 0 && l.idle != nil {
+		close(l.idle)
+		l.idle = nil
+	}
+}
+
+// drain 停止接受新操作并等待进行中的操作完成，
+// 全部完成后执行一次 cleanup（重复调用不会再次执行）
+func (l *lifecycle) drain(ctx context.Context, cleanup func()) error {
+	l.mutex.Lock()
+	l.closed = true
+
+	var wait chan struct{}
+	if l.inflight > 0 {
+		if l.idle == nil {
+			l.idle = make(chan struct{})
+		}
+		wait = l.idle
+	}
+	l.mutex.Unlock()
+
+	if wait != nil {
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	l.mutex.Lock()
+	runCleanup := !l.released
+	l.released = true
+	l.mutex.Unlock()
+
+	if runCleanup && cleanup != nil {
+		cleanup()
+	}
+	return nil
+}
+
+// isClosed 检查组件是否已关闭
+func (l *lifecycle) isClosed() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.closed
+}
+
+// closeComponent 若组件支持生命周期管理则关闭它
+func closeComponent(ctx context.Context, component interface{}) error {
+	if drainer, ok := component.(Drainer); ok {
+		return drainer.Drain(ctx)
+	}
+	return nil
+}
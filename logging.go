@@ -0,0 +1,98 @@
+package encoding
+
+import "log/slog"
+
+// slogLogger 基于 log/slog 的日志记录器
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger 创建基于 log/slog 的日志记录器（logger 为 nil 时使用 slog.Default()）
+//
+// fields 按 slog 的约定解释为交替的键值对或 slog.Attr。
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &slogLogger{logger: logger}
+}
+
+func (l *slogLogger) Debug(msg string, fields ...interface{}) { l.logger.Debug(msg, fields...) }
+func (l *slogLogger) Info(msg string, fields ...interface{})  { l.logger.Info(msg, fields...) }
+func (l *slogLogger) Warn(msg string, fields ...interface{})  { l.logger.Warn(msg, fields...) }
+func (l *slogLogger) Error(msg string, fields ...interface{}) { l.logger.Error(msg, fields...) }
+
+// levelLogger 丢弃低于 ProcessorConfig.LogLevel 的日志
+type levelLogger struct {
+	logger Logger
+	level  slog.Level
+}
+
+func (l *levelLogger) Debug(msg string, fields ...interface{}) {
+	if l.level <= slog.LevelDebug {
+		l.logger.Debug(msg, fields...)
+	}
+}
+
+func (l *levelLogger) Info(msg string, fields ...interface{}) {
+	if l.level <= slog.LevelInfo {
+		l.logger.Info(msg, fields...)
+	}
+}
+
+func (l *levelLogger) Warn(msg string, fields ...interface{}) {
+	if l.level <= slog.LevelWarn {
+		l.logger.Warn(msg, fields...)
+	}
+}
+
+func (l *levelLogger) Error(msg string, fields ...interface{}) {
+	l.logger.Error(msg, fields...)
+}
+
+// nopLogger 未配置日志记录器时使用的空实现
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+
+// newLogger 返回按 LogLevel 过滤的配置日志记录器（未配置 Logger 时返回空实现，无法识别的级别按 info 处理）
+func newLogger(config *ProcessorConfig) Logger {
+	if config == nil || config.Logger == nil {
+		return nopLogger{}
+	}
+
+	level := slog.LevelInfo
+	if config.LogLevel != "" {
+		if err := level.UnmarshalText([]byte(config.LogLevel)); err != nil {
+			level = slog.LevelInfo
+		}
+	}
+	return &levelLogger{logger: config.Logger, level: level}
+}
+
+// logDetection 输出检测结果（file 为空表示内存数据）
+func logDetection(logger Logger, file string, detection *DetectionResult) {
+	fields := []interface{}{"encoding", detection.Encoding, "confidence", detection.Confidence}
+	if detection.Details != nil && detection.Details.Method != "" {
+		fields = append(fields, "method", detection.Details.Method)
+	}
+	if file != "" {
+		fields = append([]interface{}{"file", file}, fields...)
+	}
+	logger.Debug("encoding detected", fields...)
+}
+
+// logReplacements 转换中发生替换时输出替换数量
+func logReplacements(logger Logger, file, from, to string, trace *conversionTrace) {
+	if trace == nil || trace.errors() == 0 {
+		return
+	}
+	fields := []interface{}{"from", from, "to", to, "invalid", trace.invalidSequences, "replaced", trace.replacedChars}
+	if file != "" {
+		fields = append([]interface{}{"file", file}, fields...)
+	}
+	logger.Info("characters replaced", fields...)
+}
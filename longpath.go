@@ -0,0 +1,69 @@
+package encoding
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// maxShortPath Windows 未使用扩展长度前缀时可靠支持的路径长度（创建目录时为 MAX_PATH 减去 8.3 文件名长度）
+const maxShortPath = 248
+
+// Windows 扩展长度路径前缀
+const (
+	extendedPathPrefix = `\\?\`
+	extendedUNCPrefix  = `\\?\UNC\`
+	devicePathPrefix   = `\\.\`
+)
+
+// longPath 在 Windows 上将超长路径转换为扩展长度形式，其他平台原样返回
+//
+// 扩展长度路径不经过系统的路径规范化，因此先转换为绝对路径；已带前缀的路径和较短的路径保持不变。
+func longPath(path string) string {
+	if runtime.GOOS != "windows" || path == "" || hasExtendedPrefix(path) {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil || len(abs) < maxShortPath {
+		return path
+	}
+	return extendedLengthPath(abs)
+}
+
+// displayPath 去除 longPath 添加的扩展长度前缀，用于结果中的路径
+func displayPath(path string) string {
+	if runtime.GOOS != "windows" {
+		return path
+	}
+	return trimExtendedPrefix(path)
+}
+
+// hasExtendedPrefix 检查路径是否已是扩展长度或设备路径
+func hasExtendedPrefix(path string) bool {
+	return strings.HasPrefix(path, extendedPathPrefix) || strings.HasPrefix(path, devicePathPrefix)
+}
+
+// extendedLengthPath 将规范化的 Windows 绝对路径转换为扩展长度形式
+// （C:\dir 转换为 \\?\C:\dir，UNC 路径 \\server\share 转换为 \\?\UNC\server\share）
+func extendedLengthPath(path string) string {
+	switch {
+	case hasExtendedPrefix(path):
+		return path
+	case strings.HasPrefix(path, `\\`):
+		return extendedUNCPrefix + path[2:]
+	case len(path) >= 3 && path[1] == ':' && path[2] == '\\':
+		return extendedPathPrefix + path
+	}
+	return path
+}
+
+// trimExtendedPrefix 将扩展长度路径还原为普通的 Windows 路径（UNC 路径还原为 \\server\share 形式）
+func trimExtendedPrefix(path string) string {
+	switch {
+	case strings.HasPrefix(path, extendedUNCPrefix):
+		return `\\` + path[len(extendedUNCPrefix):]
+	case strings.HasPrefix(path, extendedPathPrefix) && len(path) >= len(extendedPathPrefix)+2 && path[len(extendedPathPrefix)+1] == ':':
+		return path[len(extendedPathPrefix):]
+	}
+	return path
+}
@@ -0,0 +1,156 @@
+package encoding
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ManifestEntry 转换清单中一个已转换文件的记录
+type ManifestEntry struct {
+	// Size 转换后的文件大小
+	Size int64 `json:"size"`
+
+	// ModTime 转换后的文件修改时间
+	ModTime time.Time `json:"mod_time"`
+
+	// SourceEncoding 转换前的源编码
+	SourceEncoding string `json:"source_encoding,omitempty"`
+
+	// TargetEncoding 目标编码
+	TargetEncoding string `json:"target_encoding"`
+
+	// ConvertedAt 转换时间
+	ConvertedAt time.Time `json:"converted_at"`
+}
+
+// ConversionManifest 已转换文件清单，使重复运行的批量处理只按文件大小和修改时间跳过未改动的文件
+//
+// 与 IdempotencyOptions 不同，清单不读取文件内容也不重新检测，适合反复处理同一目录树的定时任务；
+// 文件在转换后被修改（大小或修改时间变化）时重新处理。可以被多个工作者并发使用。
+type ConversionManifest struct {
+	path    string
+	mutex   sync.Mutex
+	entries map[string]*ManifestEntry
+}
+
+// manifestFile 清单文件的 JSON 格式
+type manifestFile struct {
+	Entries map[string]*ManifestEntry `json:"entries"`
+}
+
+// LoadConversionManifest 读取清单文件（文件不存在时返回空清单），Save 时写回同一文件
+//
+// 清单以绝对路径记录文件。
+func LoadConversionManifest(path string) (*ConversionManifest, error) {
+	manifest := &ConversionManifest{path: path, entries: make(map[string]*ManifestEntry)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, &FileOperationError{Op: "manifest_load", File: path, Err: err}
+	}
+
+	var file manifestFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, &FileOperationError{Op: "manifest_load", File: path, Err: err}
+	}
+	if file.Entries != nil {
+		manifest.entries = file.Entries
+	}
+	return manifest, nil
+}
+
+// Lookup 返回文件当前状态对应的记录；文件未记录、已被修改或目标编码不同时返回 nil
+func (m *ConversionManifest) Lookup(path string, info os.FileInfo, targetEncoding string) *ManifestEntry {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	entry := m.entries[manifestKey(path)]
+	if entry == nil || entry.Size != info.Size() || !entry.ModTime.Equal(info.ModTime()) ||
+		canonicalEncodingName(entry.TargetEncoding) != canonicalEncodingName(targetEncoding) {
+		return nil
+	}
+	return entry
+}
+
+// Record 按文件当前的大小和修改时间记录转换结果
+func (m *ConversionManifest) Record(path string, result *FileProcessResult) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.entries[manifestKey(path)] = &ManifestEntry{
+		Size:           info.Size(),
+		ModTime:        info.ModTime(),
+		SourceEncoding: result.SourceEncoding,
+		TargetEncoding: result.TargetEncoding,
+		ConvertedAt:    time.Now(),
+	}
+	return nil
+}
+
+// Forget 删除文件的记录，下次运行时重新处理
+func (m *ConversionManifest) Forget(path string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.entries, manifestKey(path))
+}
+
+// Len 返回记录的文件数
+func (m *ConversionManifest) Len() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return len(m.entries)
+}
+
+// Save 通过临时文件和重命名原子地写回清单文件
+func (m *ConversionManifest) Save() error {
+	m.mutex.Lock()
+	data, err := json.MarshalIndent(manifestFile{Entries: m.entries}, "", "  ")
+	m.mutex.Unlock()
+	if err != nil {
+		return &FileOperationError{Op: "manifest_save", File: m.path, Err: err}
+	}
+
+	dir := filepath.Dir(m.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return &FileOperationError{Op: "manifest_save", File: m.path, Err: err}
+	}
+	temp, err := os.CreateTemp(dir, "."+filepath.Base(m.path)+"-*.tmp")
+	if err != nil {
+		return &FileOperationError{Op: "manifest_save", File: m.path, Err: err}
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), m.path)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		return &FileOperationError{Op: "manifest_save", File: m.path, Err: err}
+	}
+	return nil
+}
+
+// isManifestFile 检查路径是否为清单文件本身（目录处理时跳过）
+func (m *ConversionManifest) isManifestFile(path string) bool {
+	return manifestKey(path) == manifestKey(m.path)
+}
+
+// manifestKey 返回清单中文件的键（绝对路径）
+func manifestKey(path string) string {
+	path = displayPath(path)
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return path
+}
@@ -0,0 +1,61 @@
+package encoding
+
+// 转换过程中 golang.org/x/text/transform 内部分配的缓冲区大小（近似值）
+const (
+	// transformReaderOverhead transform.Reader 的源缓冲区和目标缓冲区
+	transformReaderOverhead = 2 * 4096
+
+	// transformChainOverhead transform.Chain 每个中间环节的缓冲区
+	transformChainOverhead = 4096
+)
+
+// MemoryUsage 单次操作的近似内存占用（字节）
+//
+// 各项为按缓冲区容量估算的值，不包含 Go 运行时和调用方自身的开销，
+// 用于确认 BufferSize、ChunkSize 等配置确实限制了内存占用。
+type MemoryUsage struct {
+	// InputBytes 输入数据（或流式处理中常驻的读取缓冲区）大小
+	InputBytes int64 `json:"input_bytes"`
+
+	// OutputBytes 输出缓冲区大小（结果与输入共享内存时为 0）
+	OutputBytes int64 `json:"output_bytes"`
+
+	// IntermediateBytes 中间缓冲区大小（转换器内部缓冲区、中转 UTF-8 文本等）
+	IntermediateBytes int64 `json:"intermediate_bytes"`
+
+	// PeakBytes 峰值内存占用
+	PeakBytes int64 `json:"peak_bytes"`
+}
+
+// addIntermediate 累加中间缓冲区大小（u 为 nil 时忽略）
+func (u *MemoryUsage) addIntermediate(n int64) {
+	if u == nil {
+		return
+	}
+	u.IntermediateBytes += n
+}
+
+// finish 记录输入输出大小并计算峰值（u 为 nil 时忽略）
+func (u *MemoryUsage) finish(input, output int) {
+	if u == nil {
+		return
+	}
+	u.InputBytes = int64(input)
+	u.OutputBytes = int64(output)
+	u.PeakBytes = u.InputBytes + u.OutputBytes + u.IntermediateBytes
+}
+
+// observeChunk 合并流式处理中单个数据块的内存占用
+//
+// 数据块依次处理，因此中间缓冲区和输出缓冲区取最大值，峰值为常驻缓冲区加上最大的单块占用。
+func (u *MemoryUsage) observeChunk(chunk MemoryUsage) {
+	if chunk.IntermediateBytes > u.IntermediateBytes {
+		u.IntermediateBytes = chunk.IntermediateBytes
+	}
+	if chunk.OutputBytes > u.OutputBytes {
+		u.OutputBytes = chunk.OutputBytes
+	}
+	if peak := u.InputBytes + chunk.IntermediateBytes + chunk.OutputBytes; peak > u.PeakBytes {
+		u.PeakBytes = peak
+	}
+}
@@ -1,25 +1,77 @@
 package encoding
 
 import (
+	"context"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
-// defaultMetricsCollector 实现 MetricsCollector 接口
+// defaultMetricsCollector 实现 MetricsCollector、LabeledMetricsCollector、EncodingMetricsCollector、CacheMetricsCollector 和 MemoryMetricsCollector 接口
 type defaultMetricsCollector struct {
-	stats *ProcessingStats
-	mutex sync.RWMutex
+	stats   *ProcessingStats
+	labeled map[string]*ProcessingStats
+	mutex   sync.RWMutex
+}
+
+// MetricsLabels 指标标签集合（如租户 ID、流水线名称）
+type MetricsLabels map[string]string
+
+// String 返回标签集合的规范化字符串（按键排序的 k=v 列表）
+func (l MetricsLabels) String() string {
+	keys := make([]string, 0, len(l))
+	for key := range l {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+"="+l[key])
+	}
+	return strings.Join(parts, ",")
+}
+
+// metricsLabelsKey 上下文中指标标签的键
+type metricsLabelsKey struct{}
+
+// WithMetricsLabels 返回携带指标标签的上下文（与已有标签合并）
+func WithMetricsLabels(ctx context.Context, labels MetricsLabels) context.Context {
+	merged := make(MetricsLabels)
+	for key, value := range MetricsLabelsFromContext(ctx) {
+		merged[key] = value
+	}
+	for key, value := range labels {
+		merged[key] = value
+	}
+	return context.WithValue(ctx, metricsLabelsKey{}, merged)
+}
+
+// MetricsLabelsFromContext 从上下文中获取指标标签
+func MetricsLabelsFromContext(ctx context.Context) MetricsLabels {
+	if ctx == nil {
+		return nil
+	}
+	labels, _ := ctx.Value(metricsLabelsKey{}).(MetricsLabels)
+	return labels
 }
 
 // NewMetricsCollector 创建新的性能监控器
 func NewMetricsCollector() MetricsCollector {
 	return &defaultMetricsCollector{
-		stats: &ProcessingStats{
-			EncodingDistribution: make(map[string]int64),
-			StartTime:            time.Now(),
-			LastUpdateTime:       time.Now(),
-		},
+		stats:   newProcessingStats(),
+		labeled: make(map[string]*ProcessingStats),
+	}
+}
+
+// newProcessingStats 创建空的统计信息
+func newProcessingStats() *ProcessingStats {
+	return &ProcessingStats{
+		EncodingDistribution: make(map[string]int64),
+		StartTime:            time.Now(),
+		LastUpdateTime:       time.Now(),
 	}
 }
 
@@ -34,7 +86,13 @@ func (mc *defaultMetricsCollector) GetStats() *ProcessingStats {
 		SuccessOperations:    atomic.LoadInt64(&mc.stats.SuccessOperations),
 		FailedOperations:     atomic.LoadInt64(&mc.stats.FailedOperations),
 		TotalBytes:           atomic.LoadInt64(&mc.stats.TotalBytes),
+		CacheHits:            atomic.LoadInt64(&mc.stats.CacheHits),
+		CacheMisses:          atomic.LoadInt64(&mc.stats.CacheMisses),
+		CacheEvictions:       atomic.LoadInt64(&mc.stats.CacheEvictions),
 		TotalProcessingTime:  mc.stats.TotalProcessingTime,
+		PeakMemoryUsage:      mc.stats.PeakMemoryUsage,
+		TotalMemoryUsage:     mc.stats.TotalMemoryUsage,
+		MemoryOperations:     mc.stats.MemoryOperations,
 		StartTime:            mc.stats.StartTime,
 		LastUpdateTime:       mc.stats.LastUpdateTime,
 		EncodingDistribution: make(map[string]int64),
@@ -44,6 +102,7 @@ func (mc *defaultMetricsCollector) GetStats() *ProcessingStats {
 	for encoding, count := range mc.stats.EncodingDistribution {
 		statsCopy.EncodingDistribution[encoding] = count
 	}
+	statsCopy.LostRunes = MergeLostRunes(nil, mc.stats.LostRunes)
 
 	// 计算平均处理速度
 	if statsCopy.TotalProcessingTime > 0 {
@@ -62,10 +121,18 @@ func (mc *defaultMetricsCollector) ResetStats() {
 	atomic.StoreInt64(&mc.stats.SuccessOperations, 0)
 	atomic.StoreInt64(&mc.stats.FailedOperations, 0)
 	atomic.StoreInt64(&mc.stats.TotalBytes, 0)
+	atomic.StoreInt64(&mc.stats.CacheHits, 0)
+	atomic.StoreInt64(&mc.stats.CacheMisses, 0)
+	atomic.StoreInt64(&mc.stats.CacheEvictions, 0)
 	mc.stats.TotalProcessingTime = 0
+	mc.stats.PeakMemoryUsage = 0
+	mc.stats.TotalMemoryUsage = 0
+	mc.stats.MemoryOperations = 0
 	mc.stats.StartTime = time.Now()
 	mc.stats.LastUpdateTime = time.Now()
 	mc.stats.EncodingDistribution = make(map[string]int64)
+	mc.stats.LostRunes = nil
+	mc.labeled = make(map[string]*ProcessingStats)
 }
 
 // RecordOperation 记录操作
@@ -96,6 +163,51 @@ func (mc *defaultMetricsCollector) RecordBytes(bytes int64) {
 	atomic.AddInt64(&mc.stats.TotalBytes, bytes)
 }
 
+// RecordCacheAccess 记录一次缓存查找（hit 表示命中）
+func (mc *defaultMetricsCollector) RecordCacheAccess(hit bool) {
+	if hit {
+		atomic.AddInt64(&mc.stats.CacheHits, 1)
+	} else {
+		atomic.AddInt64(&mc.stats.CacheMisses, 1)
+	}
+}
+
+// RecordCacheEviction 记录一次因缓存已满而淘汰条目
+func (mc *defaultMetricsCollector) RecordCacheEviction() {
+	atomic.AddInt64(&mc.stats.CacheEvictions, 1)
+}
+
+// RecordMemoryUsage 记录单次操作的内存占用
+func (mc *defaultMetricsCollector) RecordMemoryUsage(usage MemoryUsage) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	recordMemoryUsage(mc.stats, usage)
+	mc.stats.LastUpdateTime = time.Now()
+}
+
+// recordMemoryUsage 将内存占用计入统计信息，调用方需持有写锁
+func recordMemoryUsage(stats *ProcessingStats, usage MemoryUsage) {
+	if usage.PeakBytes > stats.PeakMemoryUsage {
+		stats.PeakMemoryUsage = usage.PeakBytes
+	}
+	stats.TotalMemoryUsage += usage.PeakBytes
+	stats.MemoryOperations++
+}
+
+// RecordLostRunes 累计因目标编码无法表示而被替换或丢弃的字符（如 ConvertResult.LostRunes）
+func (mc *defaultMetricsCollector) RecordLostRunes(lost map[string]int64) {
+	if len(lost) == 0 {
+		return
+	}
+
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	mc.stats.LostRunes = MergeLostRunes(mc.stats.LostRunes, lost)
+	mc.stats.LastUpdateTime = time.Now()
+}
+
 // RecordEncoding 记录编码类型
 func (mc *defaultMetricsCollector) RecordEncoding(encoding string) {
 	mc.mutex.Lock()
@@ -103,4 +215,188 @@ func (mc *defaultMetricsCollector) RecordEncoding(encoding string) {
 	
 	mc.stats.EncodingDistribution[encoding]++
 	mc.stats.LastUpdateTime = time.Now()
-}
\ No newline at end of file
+}
+// RecordOperationWithLabels 记录带标签的操作（同时计入全局统计）
+func (mc *defaultMetricsCollector) RecordOperationWithLabels(operation string, duration time.Duration, labels MetricsLabels) {
+	mc.RecordOperation(operation, duration)
+	if len(labels) == 0 {
+		return
+	}
+
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	stats := mc.labeledStats(labels)
+	stats.TotalOperations++
+	stats.SuccessOperations++
+	stats.TotalProcessingTime += duration
+	stats.LastUpdateTime = time.Now()
+}
+
+// RecordErrorWithLabels 记录带标签的错误（同时计入全局统计）
+func (mc *defaultMetricsCollector) RecordErrorWithLabels(operation string, err error, labels MetricsLabels) {
+	mc.RecordError(operation, err)
+	if len(labels) == 0 {
+		return
+	}
+
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	stats := mc.labeledStats(labels)
+	stats.TotalOperations++
+	stats.FailedOperations++
+	stats.LastUpdateTime = time.Now()
+}
+
+// RecordBytesWithLabels 记录带标签的字节数（同时计入全局统计）
+func (mc *defaultMetricsCollector) RecordBytesWithLabels(bytes int64, labels MetricsLabels) {
+	mc.RecordBytes(bytes)
+	if len(labels) == 0 {
+		return
+	}
+
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	stats := mc.labeledStats(labels)
+	stats.TotalBytes += bytes
+	stats.LastUpdateTime = time.Now()
+}
+
+// RecordMemoryUsageWithLabels 记录带标签的内存占用（同时计入全局统计）
+func (mc *defaultMetricsCollector) RecordMemoryUsageWithLabels(usage MemoryUsage, labels MetricsLabels) {
+	mc.RecordMemoryUsage(usage)
+	if len(labels) == 0 {
+		return
+	}
+
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	stats := mc.labeledStats(labels)
+	recordMemoryUsage(stats, usage)
+	stats.LastUpdateTime = time.Now()
+}
+
+// GetLabeledStats 获取按标签集合聚合的统计信息（键为 MetricsLabels.String()）
+func (mc *defaultMetricsCollector) GetLabeledStats() map[string]*ProcessingStats {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	result := make(map[string]*ProcessingStats, len(mc.labeled))
+	for key, stats := range mc.labeled {
+		result[key] = copyProcessingStats(stats)
+	}
+	return result
+}
+
+// GetStatsByLabel 获取包含指定标签键值的所有标签集合的汇总统计
+func (mc *defaultMetricsCollector) GetStatsByLabel(key, value string) *ProcessingStats {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	pair := key + "=" + value
+	total := newProcessingStats()
+	for labelKey, stats := range mc.labeled {
+		if !containsLabelPair(labelKey, pair) {
+			continue
+		}
+		total.TotalOperations += stats.TotalOperations
+		total.SuccessOperations += stats.SuccessOperations
+		total.FailedOperations += stats.FailedOperations
+		total.TotalBytes += stats.TotalBytes
+		total.TotalProcessingTime += stats.TotalProcessingTime
+		total.TotalMemoryUsage += stats.TotalMemoryUsage
+		total.MemoryOperations += stats.MemoryOperations
+		if stats.PeakMemoryUsage > total.PeakMemoryUsage {
+			total.PeakMemoryUsage = stats.PeakMemoryUsage
+		}
+		for encoding, count := range stats.EncodingDistribution {
+			total.EncodingDistribution[encoding] += count
+		}
+	}
+
+	if total.TotalProcessingTime > 0 {
+		total.AverageProcessingSpeed = float64(total.TotalBytes) / total.TotalProcessingTime.Seconds()
+	}
+	return total
+}
+
+// labeledStats 获取（必要时创建）标签集合对应的统计信息，调用方需持有写锁
+func (mc *defaultMetricsCollector) labeledStats(labels MetricsLabels) *ProcessingStats {
+	key := labels.String()
+	stats, ok := mc.labeled[key]
+	if !ok {
+		stats = newProcessingStats()
+		mc.labeled[key] = stats
+	}
+	return stats
+}
+
+// containsLabelPair 检查规范化标签字符串是否包含指定的 k=v
+func containsLabelPair(labelKey, pair string) bool {
+	for _, part := range strings.Split(labelKey, ",") {
+		if part == pair {
+			return true
+		}
+	}
+	return false
+}
+
+// copyProcessingStats 复制统计信息
+func copyProcessingStats(stats *ProcessingStats) *ProcessingStats {
+	statsCopy := *stats
+	statsCopy.EncodingDistribution = make(map[string]int64, len(stats.EncodingDistribution))
+	for encoding, count := range stats.EncodingDistribution {
+		statsCopy.EncodingDistribution[encoding] = count
+	}
+	if statsCopy.TotalProcessingTime > 0 {
+		statsCopy.AverageProcessingSpeed = float64(statsCopy.TotalBytes) / statsCopy.TotalProcessingTime.Seconds()
+	}
+	return &statsCopy
+}
+
+// recordOperation 向性能监控器记录一次操作的耗时或错误（metrics 为 nil 时忽略）
+//
+// labels 非空且性能监控器实现 LabeledMetricsCollector 时按标签记录（同时计入全局统计）。
+func recordOperation(metrics MetricsCollector, operation string, start time.Time, err error, labels MetricsLabels) {
+	if metrics == nil {
+		return
+	}
+	if labeled, ok := metrics.(LabeledMetricsCollector); ok && len(labels) > 0 {
+		if err != nil {
+			labeled.RecordErrorWithLabels(operation, err, labels)
+			return
+		}
+		labeled.RecordOperationWithLabels(operation, time.Since(start), labels)
+		return
+	}
+	if err != nil {
+		metrics.RecordError(operation, err)
+		return
+	}
+	metrics.RecordOperation(operation, time.Since(start))
+}
+
+// recordBytes 向支持的性能监控器记录处理的字节数（labels 非空时按标签记录）
+func recordBytes(metrics MetricsCollector, bytes int64, labels MetricsLabels) {
+	if labeled, ok := metrics.(LabeledMetricsCollector); ok && len(labels) > 0 {
+		labeled.RecordBytesWithLabels(bytes, labels)
+		return
+	}
+	if collector, ok := metrics.(EncodingMetricsCollector); ok {
+		collector.RecordBytes(bytes)
+	}
+}
+
+// recordMemory 向支持的性能监控器记录一次操作的近似内存占用（labels 非空时按标签记录）
+func recordMemory(metrics MetricsCollector, usage MemoryUsage, labels MetricsLabels) {
+	if labeled, ok := metrics.(LabeledMetricsCollector); ok && len(labels) > 0 {
+		labeled.RecordMemoryUsageWithLabels(usage, labels)
+		return
+	}
+	if collector, ok := metrics.(MemoryMetricsCollector); ok {
+		collector.RecordMemoryUsage(usage)
+	}
+}
@@ -0,0 +1,490 @@
+package encoding
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 迁移计划动作
+const (
+	MigrationActionConvert = "convert" // 需要转换
+	MigrationActionSkip    = "skip"    // 已是目标编码，跳过
+	MigrationActionFlag    = "flag"    // 需要人工确认
+)
+
+// MigrationPlanner 目录编码迁移规划接口
+type MigrationPlanner interface {
+	// PlanMigration 审计目录并生成迁移计划（只读，不修改任何文件）
+	PlanMigration(dir string, target string) (*MigrationPlan, error)
+
+	// ExecutePlan 执行迁移计划中标记为转换的文件
+	ExecutePlan(plan *MigrationPlan) (*MigrationReport, error)
+}
+
+// OptionsPlanExecutor 按调用方的文件处理选项执行迁移计划的扩展接口（默认实现支持）
+type OptionsPlanExecutor interface {
+	// ExecutePlanWithOptions 按文件处理选项执行迁移计划中标记为转换的文件
+	ExecutePlanWithOptions(plan *MigrationPlan, options *FileProcessOptions) (*MigrationReport, error)
+}
+
+// MigrationItem 迁移计划中的单个文件
+type MigrationItem struct {
+	// Path 文件路径
+	Path string `json:"path"`
+
+	// Action 计划动作（convert、skip、flag）
+	Action string `json:"action"`
+
+	// SourceEncoding 检测到的源编码
+	SourceEncoding string `json:"source_encoding,omitempty"`
+
+	// Confidence 检测置信度
+	Confidence float64 `json:"confidence"`
+
+	// Size 文件大小
+	Size int64 `json:"size"`
+
+	// EstimatedSize 预计转换后大小
+	EstimatedSize int64 `json:"estimated_size"`
+
+	// ModTime 规划时的文件修改时间（执行前用于确认文件未变化）
+	ModTime time.Time `json:"mod_time"`
+
+	// Reason 动作原因或风险说明
+	Reason string `json:"reason,omitempty"`
+
+	// TargetEncoding 目录选项文件覆盖的目标编码（为空时使用计划的目标编码）
+	TargetEncoding string `json:"target_encoding,omitempty"`
+
+	// BOMPolicy 目录选项文件指定的 BOM 处理策略
+	BOMPolicy string `json:"bom_policy,omitempty"`
+
+	// ContextBoosted 源编码是否由同目录兄弟文件的主导编码确定（执行时按该编码转换，不再重新检测）
+	ContextBoosted bool `json:"context_boosted,omitempty"`
+
+	// lowConfidence 是否因检测置信度不足而被标记
+	lowConfidence bool
+}
+
+// MigrationPlan 目录编码迁移计划
+type MigrationPlan struct {
+	// Root 审计的根目录
+	Root string `json:"root"`
+
+	// TargetEncoding 目标编码
+	TargetEncoding string `json:"target_encoding"`
+
+	// Items 计划中的文件
+	Items []*MigrationItem `json:"items"`
+
+	// ConvertCount 需要转换的文件数
+	ConvertCount int `json:"convert_count"`
+
+	// SkipCount 跳过的文件数
+	SkipCount int `json:"skip_count"`
+
+	// FlagCount 需要人工确认的文件数
+	FlagCount int `json:"flag_count"`
+
+	// TotalBytes 需要转换的文件总大小
+	TotalBytes int64 `json:"total_bytes"`
+
+	// EstimatedBytes 预计转换后的总大小
+	EstimatedBytes int64 `json:"estimated_bytes"`
+
+	// EstimatedTime 预计转换耗时
+	EstimatedTime time.Duration `json:"estimated_time"`
+
+	// RiskNotes 风险提示
+	RiskNotes []string `json:"risk_notes,omitempty"`
+
+	// CreatedAt 计划生成时间
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MigrationReport 迁移计划执行报告
+type MigrationReport struct {
+	// Results 成功转换的文件结果
+	Results []*FileProcessResult `json:"results"`
+
+	// Failed 转换失败的文件及错误信息
+	Failed map[string]string `json:"failed,omitempty"`
+
+	// Skipped 执行时跳过的文件（计划外动作或文件已变化）
+	Skipped []string `json:"skipped,omitempty"`
+
+	// LostRunes 所有文件中因目标编码无法表示而被替换或丢弃的字符及其次数
+	LostRunes map[string]int64 `json:"lost_runes,omitempty"`
+
+	// Duration 执行耗时
+	Duration time.Duration `json:"duration"`
+}
+
+// defaultMigrationPlanner 实现 MigrationPlanner 接口
+type defaultMigrationPlanner struct {
+	processor     Processor
+	fileProcessor FileProcessor
+	gate          *DetectorConfig // 原始检测器配置，提供各编码的最小置信度
+	sampleSize    int
+	contextWeight float64
+}
+
+// NewMigrationPlanner 创建新的迁移规划器
+func NewMigrationPlanner(config *ProcessorConfig) MigrationPlanner {
+	if config == nil {
+		config = GetDefaultProcessorConfig()
+	}
+
+	gate := config.DetectorConfig
+	if gate == nil {
+		gate = GetDefaultDetectorConfig()
+	}
+
+	// 规划时需要看到低置信度的检测结果，以便标记而不是直接失败
+	detectorConfig := *gate
+	detectorConfig.MinConfidence = 0
+	detectorConfig.MinConfidenceByEncoding = nil
+	planConfig := *config
+	planConfig.DetectorConfig = &detectorConfig
+
+	return &defaultMigrationPlanner{
+		processor:     NewProcessor(&planConfig),
+		fileProcessor: NewFileProcessor(config),
+		gate:          gate,
+		sampleSize:    gate.SampleSize,
+		contextWeight: gate.SiblingContextWeight,
+	}
+}
+
+// PlanMigration 使用默认配置审计目录并生成迁移计划
+func PlanMigration(dir string, target string) (*MigrationPlan, error) {
+	return NewMigrationPlanner(nil).PlanMigration(dir, target)
+}
+
+// ExecutePlan 使用默认配置执行迁移计划
+func ExecutePlan(plan *MigrationPlan) (*MigrationReport, error) {
+	return NewMigrationPlanner(nil).ExecutePlan(plan)
+}
+
+// ExecutePlanWithOptions 使用默认配置按文件处理选项执行迁移计划
+func ExecutePlanWithOptions(plan *MigrationPlan, options *FileProcessOptions) (*MigrationReport, error) {
+	return NewMigrationPlanner(nil).(OptionsPlanExecutor).ExecutePlanWithOptions(plan, options)
+}
+
+// PlanMigration 审计目录并生成迁移计划
+//
+// 目录树中的 .encproc.json 选项文件可为其所在子树覆盖目标编码、BOM 策略和文件过滤规则。
+// 配置了 SiblingContextWeight 时，置信度不足的文件按同目录兄弟文件的主导编码重新评估。
+func (mp *defaultMigrationPlanner) PlanMigration(dir string, target string) (*MigrationPlan, error) {
+	if target == "" {
+		target = EncodingUTF8
+	}
+
+	plan := &MigrationPlan{
+		Root:           dir,
+		TargetEncoding: target,
+		CreatedAt:      time.Now(),
+	}
+
+	var sampleTime time.Duration
+	var sampledBytes int64
+	resolver := newDirOptionsResolver(dir)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return &FileOperationError{Op: "walk", File: path, Err: err}
+		}
+		if info.IsDir() {
+			// 跳过隐藏目录（如 .git）
+			if path != dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || info.Name() == DirOptionsFile {
+			return nil
+		}
+
+		dirOptions, err := resolver.resolve(path)
+		if err != nil {
+			return err
+		}
+		if !dirOptions.Allows(path) {
+			plan.Items = append(plan.Items, &MigrationItem{
+				Path:    path,
+				Action:  MigrationActionSkip,
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+				Reason:  "excluded by " + DirOptionsFile,
+			})
+			return nil
+		}
+
+		fileTarget, bomPolicy := target, ""
+		if dirOptions != nil {
+			if dirOptions.TargetEncoding != "" {
+				fileTarget = dirOptions.TargetEncoding
+			}
+			bomPolicy = dirOptions.BOMPolicy
+		}
+
+		item, elapsed, sampled := mp.planFile(path, info, fileTarget, bomPolicy)
+		if fileTarget != target {
+			item.TargetEncoding = fileTarget
+		}
+		item.BOMPolicy = bomPolicy
+		sampleTime += elapsed
+		sampledBytes += sampled
+		plan.Items = append(plan.Items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if mp.contextWeight > 0 {
+		elapsed, sampled := mp.applySiblingContext(plan)
+		sampleTime += elapsed
+		sampledBytes += sampled
+	}
+
+	for _, item := range plan.Items {
+		switch item.Action {
+		case MigrationActionConvert:
+			plan.ConvertCount++
+			plan.TotalBytes += item.Size
+			plan.EstimatedBytes += item.EstimatedSize
+		case MigrationActionSkip:
+			plan.SkipCount++
+		case MigrationActionFlag:
+			plan.FlagCount++
+			plan.RiskNotes = append(plan.RiskNotes, fmt.Sprintf("%s: %s", item.Path, item.Reason))
+		}
+	}
+
+	// 按样本转换速度估算总耗时
+	if sampledBytes > 0 {
+		plan.EstimatedTime = time.Duration(float64(sampleTime) * float64(plan.TotalBytes) / float64(sampledBytes))
+	}
+
+	return plan, nil
+}
+
+// planFile 检测单个文件并确定迁移动作，返回样本转换耗时和样本大小
+func (mp *defaultMigrationPlanner) planFile(path string, info os.FileInfo, target, bomPolicy string) (*MigrationItem, time.Duration, int64) {
+	item := &MigrationItem{
+		Path:    path,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+
+	if info.Size() == 0 {
+		item.Action = MigrationActionSkip
+		item.Reason = "empty file"
+		return item, 0, 0
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		item.Action = MigrationActionFlag
+		item.Reason = fmt.Sprintf("read failed: %v", err)
+		return item, 0, 0
+	}
+
+	var detection *DetectionResult
+	if pd, ok := mp.processor.(pathDetector); ok {
+		detection, err = pd.DetectEncodingWithPath(path, data)
+	} else {
+		detection, err = mp.processor.DetectEncoding(data)
+	}
+	if err != nil {
+		item.Action = MigrationActionFlag
+		item.Reason = fmt.Sprintf("detection failed: %v", err)
+		return item, 0, 0
+	}
+
+	return mp.planDetected(item, data, detection, target, bomPolicy)
+}
+
+// planDetected 按检测结果确定迁移动作，返回样本转换耗时和样本大小
+func (mp *defaultMigrationPlanner) planDetected(item *MigrationItem, data []byte, detection *DetectionResult, target, bomPolicy string) (*MigrationItem, time.Duration, int64) {
+	item.SourceEncoding = detection.Encoding
+	item.Confidence = detection.Confidence
+
+	// 已是目标编码且 BOM 策略不会修改文件时跳过
+	if detection.Encoding == target && (!changesBOM(bomPolicy) || bytes.Equal(applyBOMPolicy(data, target, bomPolicy), data)) {
+		item.Action = MigrationActionSkip
+		item.Reason = "already in target encoding"
+		item.EstimatedSize = item.Size
+		return item, 0, 0
+	}
+
+	if minConfidence := mp.gate.MinConfidenceFor(detection.Encoding); detection.Confidence < minConfidence {
+		item.lowConfidence = true
+		item.Action = MigrationActionFlag
+		item.Reason = fmt.Sprintf("low confidence %.2f for %s (threshold %.2f)", detection.Confidence, detection.Encoding, minConfidence)
+		return item, 0, 0
+	}
+
+	// 转换样本以估算输出大小和耗时
+	sample := data
+	if mp.sampleSize > 0 && len(sample) > mp.sampleSize {
+		sample = sample[:mp.sampleSize]
+	}
+	start := time.Now()
+	converted, err := mp.processor.Convert(sample, detection.Encoding, target)
+	elapsed := time.Since(start)
+	if err != nil {
+		item.Action = MigrationActionFlag
+		item.Reason = fmt.Sprintf("trial conversion failed: %v", err)
+		return item, 0, 0
+	}
+
+	item.Action = MigrationActionConvert
+	item.EstimatedSize = int64(float64(len(converted)) / float64(len(sample)) * float64(item.Size))
+	return item, elapsed, int64(len(sample))
+}
+
+// applySiblingContext 按同目录兄弟文件的主导编码重新评估置信度不足的文件，返回样本转换耗时和样本大小
+//
+// 主导编码只统计可靠检测（转换或跳过）的文件，提升后的置信度达到阈值时按该编码重新规划。
+func (mp *defaultMigrationPlanner) applySiblingContext(plan *MigrationPlan) (time.Duration, int64) {
+	siblings := newSiblingContext()
+	for _, item := range plan.Items {
+		if item.SourceEncoding != "" && item.Action != MigrationActionFlag {
+			siblings.observe(item.Path, item.SourceEncoding)
+		}
+	}
+
+	var sampleTime time.Duration
+	var sampledBytes int64
+	for i, item := range plan.Items {
+		if !item.lowConfidence {
+			continue
+		}
+		dominant, share := siblings.dominant(item.Path)
+		if dominant == "" {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(item.Path)
+		if err != nil {
+			continue
+		}
+		detection := &DetectionResult{Encoding: item.SourceEncoding, Confidence: item.Confidence}
+		boosted, ok := boostWithContext(mp.processor, data, detection, dominant, share, mp.contextWeight)
+		if !ok || boosted.Confidence < mp.gate.MinConfidenceFor(boosted.Encoding) {
+			continue
+		}
+
+		target := plan.TargetEncoding
+		if item.TargetEncoding != "" {
+			target = item.TargetEncoding
+		}
+		replanned := &MigrationItem{
+			Path:           item.Path,
+			Size:           item.Size,
+			ModTime:        item.ModTime,
+			TargetEncoding: item.TargetEncoding,
+			BOMPolicy:      item.BOMPolicy,
+		}
+		replanned, elapsed, sampled := mp.planDetected(replanned, data, boosted, target, item.BOMPolicy)
+		if replanned.Action == MigrationActionFlag {
+			continue
+		}
+		replanned.ContextBoosted = true
+		if replanned.Action == MigrationActionConvert {
+			replanned.Reason = fmt.Sprintf("confidence %.2f for %s raised to %.2f by sibling files (%.0f%% %s)",
+				item.Confidence, item.SourceEncoding, boosted.Confidence, share*100, dominant)
+		}
+		plan.Items[i] = replanned
+		sampleTime += elapsed
+		sampledBytes += sampled
+	}
+	return sampleTime, sampledBytes
+}
+
+// ExecutePlan 执行迁移计划中标记为转换的文件（创建备份，保留权限和修改时间）
+func (mp *defaultMigrationPlanner) ExecutePlan(plan *MigrationPlan) (*MigrationReport, error) {
+	return mp.ExecutePlanWithOptions(plan, nil)
+}
+
+// ExecutePlanWithOptions 按文件处理选项执行迁移计划中标记为转换的文件
+//
+// options 为 nil 时与 ExecutePlan 相同。目标编码总是取自计划（各文件的覆盖设置仍然生效），
+// 文件总是就地转换；其余选项（是否创建备份、备份后缀、权限和时间保留等）按调用方的设置，
+// MinConfidence、BackupSuffix、BufferSize 为零值时使用默认值。
+func (mp *defaultMigrationPlanner) ExecutePlanWithOptions(plan *MigrationPlan, options *FileProcessOptions) (*MigrationReport, error) {
+	if plan == nil {
+		return nil, ErrInvalidInput
+	}
+
+	start := time.Now()
+	report := &MigrationReport{
+		Failed: make(map[string]string),
+	}
+
+	if options == nil {
+		options = &FileProcessOptions{
+			CreateBackup: true,
+			PreserveMode: true,
+			PreserveTime: true,
+		}
+	}
+	resolved := *options
+	options = &resolved
+	options.TargetEncoding = plan.TargetEncoding
+	options.OverwriteExisting = true
+	if options.MinConfidence == 0 {
+		options.MinConfidence = mp.gate.MinConfidence
+	}
+	if options.BackupSuffix == "" {
+		options.BackupSuffix = DefaultBackupSuffix
+	}
+	if options.BufferSize == 0 {
+		options.BufferSize = DefaultBufferSize
+	}
+
+	for _, item := range plan.Items {
+		if item.Action != MigrationActionConvert {
+			continue
+		}
+
+		// 确认文件自规划以来未被修改
+		info, err := os.Stat(item.Path)
+		if err != nil {
+			report.Failed[item.Path] = err.Error()
+			continue
+		}
+		if info.Size() != item.Size || !info.ModTime().Equal(item.ModTime) {
+			report.Skipped = append(report.Skipped, item.Path)
+			continue
+		}
+
+		itemOptions := options
+		if item.TargetEncoding != "" || item.BOMPolicy != "" {
+			itemOptions = (&DirOptions{TargetEncoding: item.TargetEncoding, BOMPolicy: item.BOMPolicy}).Apply(options)
+		}
+		if item.ContextBoosted {
+			boostedOptions := *itemOptions
+			boostedOptions.SourceEncoding = item.SourceEncoding
+			itemOptions = &boostedOptions
+		}
+
+		result, err := mp.fileProcessor.ProcessFileInPlace(item.Path, itemOptions)
+		if err != nil {
+			report.Failed[item.Path] = err.Error()
+			continue
+		}
+		report.Results = append(report.Results, result)
+		report.LostRunes = MergeLostRunes(report.LostRunes, result.LostRunes)
+	}
+
+	report.Duration = time.Since(start)
+	return report, nil
+}
@@ -0,0 +1,307 @@
+package encoding
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"golang.org/x/text/transform"
+)
+
+// encodedLineBreak 返回换行控制字符在指定编码下的字节序列
+func encodedLineBreak(encodingName string, lb byte) []byte {
+	switch encodingName {
+	case EncodingUTF16, EncodingUTF16BE:
+		return []byte{0x00, lb}
+	case EncodingUTF16LE:
+		return []byte{lb, 0x00}
+	case EncodingUTF32, EncodingUTF32BE:
+		return []byte{0x00, 0x00, 0x00, lb}
+	case EncodingUTF32LE:
+		return []byte{lb, 0x00, 0x00, 0x00}
+	default:
+		return []byte{lb}
+	}
+}
+
+// countLineBreaks 统计指定编码的数据中 LF 换行符的数量（按编码单元对齐比较）
+func countLineBreaks(data []byte, encodingName string) int {
+	lf := encodedLineBreak(encodingName, '\n')
+	if len(lf) == 1 {
+		return bytes.Count(data, lf)
+	}
+
+	count := 0
+	for i := 0; i+len(lf) <= len(data); i += len(lf) {
+		if bytes.Equal(data[i:i+len(lf)], lf) {
+			count++
+		}
+	}
+	return count
+}
+
+// HasFinalNewline 检查指定编码的数据是否以换行符（LF 或 CR）结尾
+//
+// UTF-16/UTF-32 数据按开头的 BOM 确定字节序（没有 BOM 时为大端序）。
+func HasFinalNewline(data []byte, encodingName string) bool {
+	encodingName = continuationEncoding(encodingName, data)
+	return bytes.HasSuffix(data, encodedLineBreak(encodingName, '\n')) ||
+		bytes.HasSuffix(data, encodedLineBreak(encodingName, '\r'))
+}
+
+// stripFinalNewlines 去除数据末尾所有的换行符
+func stripFinalNewlines(data []byte, encodingName string) []byte {
+	lf := encodedLineBreak(encodingName, '\n')
+	cr := encodedLineBreak(encodingName, '\r')
+	for {
+		switch {
+		case bytes.HasSuffix(data, lf):
+			data = data[:len(data)-len(lf)]
+		case bytes.HasSuffix(data, cr):
+			data = data[:len(data)-len(cr)]
+		default:
+			return data
+		}
+	}
+}
+
+// appendFinalNewline 在数据末尾追加目标换行符（不修改原切片）
+func appendFinalNewline(data []byte, encodingName, lineEnding string) []byte {
+	if lineEnding == "" {
+		lineEnding = LineEndingLF
+	}
+
+	result := make([]byte, 0, len(data)+len(lineEnding)*4)
+	result = append(result, data...)
+	for i := 0; i < len(lineEnding); i++ {
+		result = append(result, encodedLineBreak(encodingName, lineEnding[i])...)
+	}
+	return result
+}
+
+// applyFinalNewline 按转换器配置调整转换结果的末尾换行符
+func (c *defaultConverter) applyFinalNewline(source []byte, from string, result []byte, to string) []byte {
+	if len(result) == 0 {
+		return result
+	}
+
+	switch c.config.FinalNewline {
+	case FinalNewlineEnsure:
+		if !HasFinalNewline(result, to) {
+			return appendFinalNewline(result, to, c.config.TargetLineEnding)
+		}
+	case FinalNewlineStrip:
+		return stripFinalNewlines(result, to)
+	default:
+		had := HasFinalNewline(source, from)
+		has := HasFinalNewline(result, to)
+		if had && !has {
+			return appendFinalNewline(result, to, c.config.TargetLineEnding)
+		}
+		if !had && has {
+			return stripFinalNewlines(result, to)
+		}
+	}
+
+	return result
+}
+
+// finalNewlineWriter 流式输出时按末尾换行符策略（ensure、strip）调整输出结尾
+//
+// 写入时暂存数据末尾连续的换行符（按编码单元对齐），Close 时按策略补充、去除或原样写出，
+// 暂存的数据量仅与末尾连续换行符的长度有关。
+type finalNewlineWriter struct {
+	w          io.Writer
+	encoding   string
+	policy     string
+	lineEnding string
+	unit       int
+	pending    []byte
+	written    int64
+}
+
+// newFinalNewlineWriter 创建按末尾换行符策略调整输出结尾的写入器
+func newFinalNewlineWriter(w io.Writer, encodingName, policy, lineEnding string) *finalNewlineWriter {
+	return &finalNewlineWriter{
+		w:          w,
+		encoding:   encodingName,
+		policy:     policy,
+		lineEnding: lineEnding,
+		unit:       len(encodedLineBreak(encodingName, '\n')),
+	}
+}
+
+// Write 写出末尾换行符之前的数据，暂存末尾的换行符和不完整的编码单元
+func (fw *finalNewlineWriter) Write(p []byte) (int, error) {
+	buf := append(fw.pending, p...)
+
+	// 结尾不完整的编码单元留到下次写入
+	end := len(buf) - int((fw.written+int64(len(buf)))%int64(fw.unit))
+	if end < 0 {
+		end = 0
+	}
+	lf := encodedLineBreak(fw.encoding, '\n')
+	cr := encodedLineBreak(fw.encoding, '\r')
+	cut := end
+	for cut >= fw.unit && (bytes.Equal(buf[cut-fw.unit:cut], lf) || bytes.Equal(buf[cut-fw.unit:cut], cr)) {
+		cut -= fw.unit
+	}
+
+	if cut > 0 {
+		if _, err := fw.w.Write(buf[:cut]); err != nil {
+			return 0, err
+		}
+		fw.written += int64(cut)
+	}
+	fw.pending = append([]byte(nil), buf[cut:]...)
+	return len(p), nil
+}
+
+// Close 按策略写出暂存的末尾换行符（不关闭底层写入器）
+func (fw *finalNewlineWriter) Close() error {
+	tail := fw.pending
+	fw.pending = nil
+
+	if len(tail)%fw.unit == 0 {
+		switch fw.policy {
+		case FinalNewlineStrip:
+			tail = nil
+		case FinalNewlineEnsure:
+			if len(tail) == 0 && fw.written > 0 {
+				tail = appendFinalNewline(nil, fw.encoding, fw.lineEnding)
+			}
+		}
+	}
+
+	if len(tail) == 0 {
+		return nil
+	}
+	n, err := fw.w.Write(tail)
+	fw.written += int64(n)
+	return err
+}
+
+// lineEndingTransformer 将指定编码数据中的换行符（LF、CRLF、CR）统一为目标换行符
+//
+// 按编码单元对齐比较，只处理完整的编码单元；未到数据结尾时，末尾的 CR 暂不处理，
+// 以便与下一块开头的 LF 组成 CRLF。
+type lineEndingTransformer struct {
+	lf, cr    []byte
+	target    []byte
+	targetEnd string
+	unit      int
+	converted int64            // 被改写的换行符数量
+	trace     *conversionTrace // 不为 nil 时同时记录到转换统计
+	pending   []byte           // normalizeChunk 暂存的末尾数据
+}
+
+// newLineEndingTransformer 创建将换行符统一为 lineEnding（为空时为 LF）的转换器
+func newLineEndingTransformer(encodingName, lineEnding string) *lineEndingTransformer {
+	if lineEnding == "" {
+		lineEnding = LineEndingLF
+	}
+	lf := encodedLineBreak(encodingName, '\n')
+	return &lineEndingTransformer{
+		lf:        lf,
+		cr:        encodedLineBreak(encodingName, '\r'),
+		target:    appendFinalNewline(nil, encodingName, lineEnding),
+		targetEnd: lineEnding,
+		unit:      len(lf),
+	}
+}
+
+// Transform 实现 transform.Transformer 接口
+func (t *lineEndingTransformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	u := t.unit
+	for nSrc+u <= len(src) {
+		current := src[nSrc : nSrc+u]
+		var found string
+		switch {
+		case bytes.Equal(current, t.cr):
+			if nSrc+2*u > len(src) && !atEOF {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			found = LineEndingCR
+			if nSrc+2*u <= len(src) && bytes.Equal(src[nSrc+u:nSrc+2*u], t.lf) {
+				found = LineEndingCRLF
+			}
+		case bytes.Equal(current, t.lf):
+			found = LineEndingLF
+		default:
+			if len(dst)-nDst < u {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			nDst += copy(dst[nDst:], current)
+			nSrc += u
+			continue
+		}
+
+		if len(dst)-nDst < len(t.target) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], t.target)
+		nSrc += len(found) * u
+		if found != t.targetEnd {
+			t.converted++
+			if t.trace != nil {
+				t.trace.lineEndingsConverted++
+			}
+		}
+	}
+
+	// 不完整的编码单元留到下次，数据结尾时原样输出
+	if nSrc < len(src) {
+		if !atEOF {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+		if len(dst)-nDst < len(src)-nSrc {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], src[nSrc:])
+		nSrc = len(src)
+	}
+	return nDst, nSrc, nil
+}
+
+// Reset 实现 transform.Transformer 接口
+func (t *lineEndingTransformer) Reset() {
+	t.converted = 0
+	t.pending = nil
+}
+
+// normalizeChunk 统一一个数据块的换行符，atEOF 为 false 时末尾的 CR 和不完整的编码单元暂存到下一块
+func (t *lineEndingTransformer) normalizeChunk(chunk []byte, atEOF bool) []byte {
+	src := append(t.pending, chunk...)
+	// 每个编码单元最多扩展为两个（LF、CR 改写为 CRLF）
+	dst := make([]byte, 2*len(src))
+	nDst, nSrc, _ := t.Transform(dst, src, atEOF)
+	t.pending = append([]byte(nil), src[nSrc:]...)
+	return dst[:nDst]
+}
+
+// lineEndingNormalizer 返回按转换器配置统一换行符的转换器（未启用 NormalizeLineEndings 时返回 nil）
+func (c *defaultConverter) lineEndingNormalizer(encodingName string) *lineEndingTransformer {
+	if !c.config.NormalizeLineEndings {
+		return nil
+	}
+	return newLineEndingTransformer(c.resolveName(encodingName), c.config.TargetLineEnding)
+}
+
+// applyLineEndings 按转换器配置统一转换结果的换行符，trace 不为 nil 时记录改写的换行符数量
+func (c *defaultConverter) applyLineEndings(result []byte, to string, trace *conversionTrace) ([]byte, error) {
+	normalizer := c.lineEndingNormalizer(to)
+	if normalizer == nil || len(result) == 0 {
+		return result, nil
+	}
+
+	normalizer.trace = trace
+	normalized, _, err := transform.Bytes(normalizer, result)
+	if err != nil {
+		return nil, &EncodingError{
+			Op:       OperationConvert,
+			Encoding: to,
+			Err:      fmt.Errorf("failed to normalize line endings: %w", err),
+		}
+	}
+	return normalized, nil
+}
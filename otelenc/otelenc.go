@@ -0,0 +1,86 @@
+// Package otelenc 将 OpenTelemetry 的 trace.TracerProvider 接入处理器的分布式追踪
+//
+// encoding.TracerProvider 不依赖 OpenTelemetry，本包提供转发到 OpenTelemetry 追踪器的适配器：
+// 跨度属性按值的类型转换为对应的 attribute.KeyValue，记录错误时同时将跨度状态设为 Error。
+package otelenc
+
+import (
+	"context"
+	"fmt"
+
+	encoding "github.com/mirbf/encoding-processor"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewTracerProvider 返回将跨度转发到 OpenTelemetry provider 的 encoding.TracerProvider
+//
+// 通常传入 otel.GetTracerProvider()，设置到 ProcessorConfig.TracerProvider。
+func NewTracerProvider(provider trace.TracerProvider) encoding.TracerProvider {
+	return tracerProvider{provider: provider}
+}
+
+// tracerProvider 转发到 OpenTelemetry 的追踪器提供者
+type tracerProvider struct {
+	provider trace.TracerProvider
+}
+
+// Tracer 返回指定名称的追踪器
+func (p tracerProvider) Tracer(name string) encoding.Tracer {
+	return tracer{tracer: p.provider.Tracer(name)}
+}
+
+// tracer 转发到 OpenTelemetry 的追踪器
+type tracer struct {
+	tracer trace.Tracer
+}
+
+// Start 开始一个跨度（ctx 中已有 OpenTelemetry 跨度时作为其子跨度）
+func (t tracer) Start(ctx context.Context, name string) (context.Context, encoding.Span) {
+	ctx, s := t.tracer.Start(ctx, name)
+	return ctx, span{span: s}
+}
+
+// span 转发到 OpenTelemetry 的跨度
+type span struct {
+	span trace.Span
+}
+
+// SetAttributes 按值的类型设置跨度属性
+func (s span) SetAttributes(attributes ...encoding.SpanAttribute) {
+	kvs := make([]attribute.KeyValue, 0, len(attributes))
+	for _, a := range attributes {
+		kvs = append(kvs, keyValue(a))
+	}
+	s.span.SetAttributes(kvs...)
+}
+
+// RecordError 记录错误并将跨度状态设为 Error
+func (s span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End 结束跨度
+func (s span) End() {
+	s.span.End()
+}
+
+// keyValue 将跨度属性转换为 OpenTelemetry 属性（不支持的类型按字符串记录）
+func keyValue(a encoding.SpanAttribute) attribute.KeyValue {
+	switch v := a.Value.(type) {
+	case string:
+		return attribute.String(a.Key, v)
+	case int64:
+		return attribute.Int64(a.Key, v)
+	case int:
+		return attribute.Int(a.Key, v)
+	case float64:
+		return attribute.Float64(a.Key, v)
+	case bool:
+		return attribute.Bool(a.Key, v)
+	default:
+		return attribute.String(a.Key, fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,64 @@
+package otelenc
+
+import (
+	"context"
+	"testing"
+
+	encoding "github.com/mirbf/encoding-processor"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracerProvider(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer provider.Shutdown(context.Background())
+
+	config := encoding.GetDefaultProcessorConfig()
+	config.TracerProvider = NewTracerProvider(provider)
+	processor := encoding.NewProcessor(config)
+
+	// 处理器的跨度是调用方 OpenTelemetry 跨度的子跨度
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	if _, err := processor.(encoding.ContextConverter).ConvertContext(ctx, []byte("中文"), encoding.EncodingUTF8, encoding.EncodingGBK); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+	if _, err := processor.Convert([]byte("data"), "NO-SUCH-ENCODING", encoding.EncodingUTF8); err == nil {
+		t.Fatal("Expected unsupported encoding error")
+	}
+	if _, err := processor.DetectEncoding([]byte("plain ascii text")); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("Expected 4 ended spans, got %d", len(spans))
+	}
+	convert, request, failed, detect := spans[0], spans[1], spans[2], spans[3]
+
+	if convert.Name() != encoding.SpanConvert || convert.Parent().SpanID() != request.SpanContext().SpanID() {
+		t.Errorf("Expected %s span under the request span, got %s (parent %v)", encoding.SpanConvert, convert.Name(), convert.Parent().SpanID())
+	}
+	if convert.InstrumentationScope().Name != encoding.TracerName {
+		t.Errorf("Unexpected instrumentation scope %q", convert.InstrumentationScope().Name)
+	}
+	attributes := attribute.NewSet(convert.Attributes()...)
+	if v, _ := attributes.Value(encoding.AttributeTargetEncoding); v.AsString() != encoding.EncodingGBK {
+		t.Errorf("Unexpected target encoding attribute %v", v)
+	}
+	if v, _ := attributes.Value(encoding.AttributeBytes); v.Type() != attribute.INT64 || v.AsInt64() != int64(len("中文")) {
+		t.Errorf("Expected int64 byte count attribute, got %v", v)
+	}
+
+	if failed.Status().Code != codes.Error || len(failed.Events()) == 0 {
+		t.Errorf("Expected failed conversion to record an error, got status %v and %d events", failed.Status(), len(failed.Events()))
+	}
+
+	attributes = attribute.NewSet(detect.Attributes()...)
+	if v, ok := attributes.Value(encoding.AttributeConfidence); detect.Name() != encoding.SpanDetectEncoding || !ok || v.Type() != attribute.FLOAT64 {
+		t.Errorf("Expected float64 confidence on %s span, got %v", detect.Name(), v)
+	}
+}
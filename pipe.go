@@ -0,0 +1,54 @@
+package encoding
+
+import (
+	"io"
+
+	"golang.org/x/text/transform"
+)
+
+// ConvertPipe 创建相连的写入端和读取端：写入 from 编码的数据，从读取端读出 to 编码的数据
+//
+// 基于 io.Pipe 实现，写入会阻塞直到读取端消费完转换后的数据，从而自然形成背压，
+// 可直接插入现有的生产者/消费者代码。写入端必须 Close 以刷新剩余数据，读取端随后返回 io.EOF。
+// 编码无效时写入端和读取端均返回该错误。
+func ConvertPipe(from, to string, opts ...Option) (io.WriteCloser, io.Reader) {
+	pr, pw := io.Pipe()
+
+	t, err := NewTransformer(from, to, opts...)
+	if err != nil {
+		pw.CloseWithError(err)
+		return &convertPipeWriter{pipe: pw, err: err}, pr
+	}
+
+	return &convertPipeWriter{
+		writer: transform.NewWriter(pw, t),
+		pipe:   pw,
+	}, pr
+}
+
+// convertPipeWriter ConvertPipe 的写入端
+type convertPipeWriter struct {
+	writer *transform.Writer
+	pipe   *io.PipeWriter
+	err    error
+}
+
+// Write 转换数据并写入管道
+func (w *convertPipeWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	return w.writer.Write(p)
+}
+
+// Close 刷新剩余数据并关闭管道，读取端随后返回 io.EOF（刷新失败时返回该错误）
+func (w *convertPipeWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.writer.Close(); err != nil {
+		w.pipe.CloseWithError(err)
+		return err
+	}
+	return w.pipe.Close()
+}
@@ -0,0 +1,127 @@
+package encoding
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+)
+
+// PivotInfo 缓冲中转时捕获的中间 UTF-8 文本信息
+type PivotInfo struct {
+	// SourceEncoding 源编码
+	SourceEncoding string `json:"source_encoding"`
+
+	// TargetEncoding 目标编码
+	TargetEncoding string `json:"target_encoding"`
+
+	// Intermediate 中间 UTF-8 文本
+	Intermediate []byte `json:"-"`
+
+	// ReplacementOffsets 中间文本中替换字符（U+FFFD）的字节偏移
+	ReplacementOffsets []int `json:"replacement_offsets,omitempty"`
+
+	// Valid 中间文本是否为有效的 UTF-8
+	Valid bool `json:"valid"`
+}
+
+// convertViaPivot 先解码到 UTF-8 缓冲区，再编码到目标编码
+//
+// encoderName 为编码器使用的编码名称（目标为带 BOM 的 UTF-8 时为 UTF-8）。
+func (c *defaultConverter) convertViaPivot(data []byte, from, to, encoderName string, trace *conversionTrace) ([]byte, error) {
+	usage := trace.memory()
+	newDecoder := func(chunkTrace *conversionTrace) (transform.Transformer, error) {
+		decoder, _, err := c.codecs(from, encoderName, chunkTrace)
+		return decoder, err
+	}
+	newEncoder := func(chunkTrace *conversionTrace) (transform.Transformer, error) {
+		_, encoder, err := c.codecs(from, encoderName, chunkTrace)
+		return encoder, err
+	}
+
+	intermediate := data
+	if from != EncodingUTF8 {
+		decoded, err := c.doTransform(data, from, EncodingUTF8, newDecoder, trace)
+		if err != nil {
+			return nil, &EncodingError{
+				Op:       OperationConvert,
+				Encoding: fmt.Sprintf("%s->%s", from, EncodingUTF8),
+				Err:      err,
+			}
+		}
+		intermediate = decoded
+	}
+
+	info := &PivotInfo{
+		SourceEncoding:     from,
+		TargetEncoding:     to,
+		Intermediate:       intermediate,
+		ReplacementOffsets: replacementOffsets(intermediate),
+		Valid:              utf8.Valid(intermediate),
+	}
+
+	if c.config.PivotStrategy == PivotValidated && !info.Valid {
+		return nil, &EncodingError{
+			Op:       OperationValidate,
+			Encoding: from,
+			Err:      fmt.Errorf("%w: intermediate text is not valid UTF-8 at byte %d", ErrConversionFailed, firstInvalidUTF8(intermediate)),
+		}
+	}
+
+	if c.config.PivotHook != nil {
+		c.config.PivotHook(info)
+	}
+
+	usage.addIntermediate(transformReaderOverhead)
+	if to == EncodingUTF8 {
+		usage.finish(len(data), cap(intermediate))
+		return intermediate, nil
+	}
+
+	result, err := c.doTransform(intermediate, EncodingUTF8, encoderName, newEncoder, trace)
+	if err != nil {
+		encErr := &EncodingError{
+			Op:       OperationConvert,
+			Encoding: fmt.Sprintf("%s->%s", EncodingUTF8, to),
+			Err:      err,
+		}
+		if c.strict() {
+			// 位置按源数据而不是中间 UTF-8 文本计算
+			c.locateUnconvertible(encErr, data, from, encoderName)
+		}
+		return nil, encErr
+	}
+
+	// 编码到目标编码时中间 UTF-8 文本仍然驻留
+	if from != EncodingUTF8 {
+		usage.addIntermediate(int64(cap(intermediate)))
+	}
+	usage.finish(len(data), cap(result))
+
+	return result, nil
+}
+
+// replacementOffsets 查找 UTF-8 文本中替换字符的字节偏移
+func replacementOffsets(text []byte) []int {
+	var offsets []int
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRune(text[i:])
+		if r == utf8.RuneError && size == 3 {
+			offsets = append(offsets, i)
+		}
+		i += size
+	}
+	return offsets
+}
+
+// firstInvalidUTF8 返回第一个无效 UTF-8 序列的字节偏移（全部有效时返回 -1）
+func firstInvalidUTF8(text []byte) int {
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRune(text[i:])
+		if r == utf8.RuneError && size == 1 {
+			return i
+		}
+		i += size
+	}
+	return -1
+}
@@ -0,0 +1,302 @@
+package encoding
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+)
+
+// PositionMapping 源数据与输出数据之间的一个字节偏移对应关系
+type PositionMapping struct {
+	// SourceOffset 源数据中的字节偏移
+	SourceOffset int64 `json:"source_offset"`
+
+	// OutputOffset 输出数据中的字节偏移
+	OutputOffset int64 `json:"output_offset"`
+}
+
+// PositionMap 源数据与输出数据之间的位置映射
+type PositionMap struct {
+	// Entries 按偏移递增排列的映射项
+	Entries []PositionMapping `json:"entries"`
+
+	// Interval 采样间隔（源字节数，1 表示完整映射）
+	Interval int `json:"interval"`
+}
+
+// SourceToOutput 将源数据偏移映射为输出数据偏移（取不超过该偏移的最近映射项）
+func (m *PositionMap) SourceToOutput(sourceOffset int64) int64 {
+	if m == nil || len(m.Entries) == 0 {
+		return 0
+	}
+	i := sort.Search(len(m.Entries), func(i int) bool {
+		return m.Entries[i].SourceOffset > sourceOffset
+	})
+	if i == 0 {
+		return m.Entries[0].OutputOffset
+	}
+	return m.Entries[i-1].OutputOffset
+}
+
+// OutputToSource 将输出数据偏移映射为源数据偏移（取不超过该偏移的最近映射项）
+func (m *PositionMap) OutputToSource(outputOffset int64) int64 {
+	if m == nil || len(m.Entries) == 0 {
+		return 0
+	}
+	i := sort.Search(len(m.Entries), func(i int) bool {
+		return m.Entries[i].OutputOffset > outputOffset
+	})
+	if i == 0 {
+		return m.Entries[0].SourceOffset
+	}
+	return m.Entries[i-1].SourceOffset
+}
+
+// BuildPositionMap 构建 from 编码数据转换到 to 编码后的位置映射
+//
+// interval 为采样间隔（源字节数），小于等于 1 时记录每个字符的映射。
+// 当转换过程中出现替换字符时，替换部分的输出偏移为近似值。
+func BuildPositionMap(data []byte, from, to string, interval int) (*PositionMap, error) {
+	c := NewConverter().(*defaultConverter)
+	output, err := c.Convert(data, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return c.buildPositionMap(data, from, to, interval, output)
+}
+
+// buildPositionMap 逐字符解码并编码以记录位置映射，output 为同一转换器的实际转换结果
+//
+// 与 traceConversion 一样去除源数据开头的 BOM、统一换行符，并按实际结果开头的 BOM 和
+// 末尾换行符（FinalNewline 策略）调整输出偏移，使所有输出偏移都落在 output 以内。
+func (c *defaultConverter) buildPositionMap(data []byte, from, to string, interval int, output []byte) (*PositionMap, error) {
+	if interval < 1 {
+		interval = 1
+	}
+
+	body, _ := c.splitSourceBOM(data, from)
+	skipped := int64(len(data) - len(body))
+	from, to = c.resolveName(from), c.resolveName(to)
+	// 带 BOM 的 UTF-8 按 UTF-8 编码（与 transcode 相同），BOM 按实际结果计入
+	encoderName := to
+	if to == EncodingUTF8BOM {
+		encoderName = EncodingUTF8
+	}
+
+	var decoder, encoder transform.Transformer
+	if from != EncodingUTF8 {
+		dec, err := c.getDecoder(from)
+		if err != nil {
+			return nil, &EncodingError{Op: OperationConvert, Encoding: from, Err: err}
+		}
+		decoder = dec
+	}
+	if encoderName != EncodingUTF8 {
+		enc, err := c.getEncoder(encoderName)
+		if err != nil {
+			return nil, &EncodingError{Op: OperationConvert, Encoding: to, Err: err}
+		}
+		encoder = enc
+	}
+	normalizer := c.lineEndingNormalizer(to)
+
+	m := &PositionMap{Interval: interval}
+	var outOffset int64
+	if bom := encodedBOM(to); bom != nil && bytes.HasPrefix(output, bom) {
+		outOffset = int64(len(bom))
+	}
+	if skipped > 0 {
+		// 源数据的 BOM 不对应输出中的字符
+		m.Entries = append(m.Entries, PositionMapping{SourceOffset: 0, OutputOffset: outOffset})
+	}
+	nextSample := skipped
+
+	srcOffset := skipped
+	afterCR := false
+	var runeBuf [2 * utf8.UTFMax]byte
+	var encBuf [32]byte
+
+	for srcOffset < int64(len(data)) {
+		// 解码一个字符
+		var decoded []byte
+		var consumed int
+		if decoder == nil {
+			_, size := utf8.DecodeRune(data[srcOffset:])
+			decoded = data[srcOffset : srcOffset+int64(size)]
+			consumed = size
+		} else {
+			nDst, nSrc := decodeOneChar(decoder, runeBuf[:], data[srcOffset:])
+			if nSrc == 0 {
+				// 无法解码的字节按单字节替换处理
+				nSrc = 1
+				nDst = copy(runeBuf[:], string(utf8.RuneError))
+			}
+			decoded = runeBuf[:nDst]
+			consumed = nSrc
+		}
+
+		if srcOffset >= nextSample {
+			m.Entries = append(m.Entries, PositionMapping{
+				SourceOffset: srcOffset,
+				OutputOffset: outOffset,
+			})
+			nextSample = srcOffset + int64(interval)
+		}
+
+		// 编码该字符以计算输出长度（编码器在第一个字符前写入的 BOM 已按实际结果计入）
+		encoded := decoded
+		if encoder != nil {
+			nDst, _, err := encoder.Transform(encBuf[:], decoded, false)
+			encoded = encBuf[:nDst]
+			if srcOffset == skipped {
+				encoded = bytes.TrimPrefix(encoded, encodedBOM(encoderName))
+			}
+			if err != nil && len(encoded) == 0 {
+				encoded = []byte(c.replacementString())
+			}
+		}
+
+		// 统一换行符时 CRLF 整体改写为目标换行符
+		size := int64(len(encoded))
+		if normalizer != nil {
+			switch {
+			case bytes.Equal(encoded, normalizer.lf) && afterCR:
+				size = 0
+			case bytes.Equal(encoded, normalizer.lf) || bytes.Equal(encoded, normalizer.cr):
+				size = int64(len(normalizer.target))
+			}
+			afterCR = bytes.Equal(encoded, normalizer.cr)
+		}
+		outOffset += size
+
+		srcOffset += int64(consumed)
+	}
+
+	// 去除的末尾换行符映射到输出结尾
+	for i := range m.Entries {
+		if m.Entries[i].OutputOffset > int64(len(output)) {
+			m.Entries[i].OutputOffset = int64(len(output))
+		}
+	}
+	return m, nil
+}
+
+// decodeOneChar 逐步扩大输入长度，使解码器每次只消费一个字符（src 为全部剩余数据）
+func decodeOneChar(decoder transform.Transformer, dst, src []byte) (nDst, nSrc int) {
+	nDst, nSrc, _ = decodeNextChar(decoder, dst, src, true)
+	return nDst, nSrc
+}
+
+// locateUnconvertible 将第一个无法编码到目标编码的字符的位置记录到 encErr
+//
+// 用于严格模式转换失败后定位出错的字符；encoderName 为转换使用的目标编码，所有字符都能转换
+// （如因上下文取消而失败）时不修改 encErr。
+func (c *defaultConverter) locateUnconvertible(encErr *EncodingError, data []byte, from, encoderName string) {
+	failInvalid := c.config.InvalidBytePolicy == InvalidByteFail
+	if (encoderName == EncodingUTF8 && !failInvalid) || (c.ctx != nil && c.ctx.Err() != nil) {
+		return
+	}
+	c.scanConversion(data, from, encoderName, func(replacement Replacement, unencodable bool) bool {
+		if !unencodable && !(failInvalid && replacement.Invalid) {
+			return true
+		}
+		encErr.ByteOffset = replacement.ByteOffset
+		encErr.Line = replacement.Line
+		encErr.Column = replacement.Column
+		encErr.Bytes = replacement.Bytes
+		return false
+	})
+}
+
+// scanConversion 逐字符转换源数据，对每个无效字节序列和无法编码到目标编码的字符调用 visit
+//
+// encoderName 为转换使用的目标编码（UTF-8 时只检查无效字节序列），unencodable 表示该字符
+// （包括无效字节序列解码得到的 U+FFFD）无法用目标编码表示；visit 返回 false 时停止扫描。
+func (c *defaultConverter) scanConversion(data []byte, from, encoderName string, visit func(replacement Replacement, unencodable bool) bool) {
+	var decoder, encoder transform.Transformer
+	genuine := replacementChar
+	if from != EncodingUTF8 {
+		enc, err := c.getEncoding(from)
+		if err != nil {
+			return
+		}
+		decoder, genuine = enc.NewDecoder(), encodedReplacementChar(enc)
+	}
+	if encoderName != EncodingUTF8 {
+		var err error
+		if encoder, err = c.getEncoder(encoderName); err != nil {
+			return
+		}
+	}
+
+	var runeBuf [2 * utf8.UTFMax]byte
+	var encBuf [32]byte
+	line, column := 1, 1
+	for offset := 0; offset < len(data); {
+		var decoded []byte
+		size := 0
+		if decoder == nil {
+			_, size = utf8.DecodeRune(data[offset:])
+			decoded = data[offset : offset+size]
+		} else {
+			nDst, nSrc := decodeOneChar(decoder, runeBuf[:], data[offset:])
+			if nSrc == 0 {
+				nSrc, nDst = 1, copy(runeBuf[:], string(utf8.RuneError))
+			}
+			decoded, size = runeBuf[:nDst], nSrc
+		}
+		source := data[offset : offset+size]
+
+		// 源数据中按该编码正确编码的 U+FFFD 不是无效字节序列
+		invalid := bytes.ContainsRune(decoded, utf8.RuneError) && !bytes.Equal(source, genuine)
+		unencodable := false
+		if encoder != nil {
+			encoder.Reset()
+			_, _, err := encoder.Transform(encBuf[:], decoded, true)
+			unencodable = err != nil
+		}
+		if invalid || unencodable {
+			r, _ := utf8.DecodeRune(decoded)
+			replacement := Replacement{
+				ByteOffset: int64(offset),
+				Line:       line,
+				Column:     column,
+				Bytes:      append([]byte(nil), source...),
+				Rune:       r,
+				Invalid:    invalid,
+			}
+			if !visit(replacement, unencodable) {
+				return
+			}
+		}
+
+		if bytes.IndexByte(decoded, '\n') >= 0 {
+			line, column = line+1, 1
+		} else {
+			column += utf8.RuneCount(decoded)
+		}
+		offset += size
+	}
+}
+
+// shiftErrorPosition 将错误中记录的字节偏移后移 n 字节（如源数据开头被去除的 BOM）
+func shiftErrorPosition(err error, n int) {
+	var encErr *EncodingError
+	if n > 0 && errors.As(err, &encErr) && encErr.Bytes != nil {
+		encErr.ByteOffset += int64(n)
+	}
+}
+
+// streamErrorPosition 将数据块内的错误位置换算为流中的字节偏移（数据块从流的 start 字节开始）
+//
+// 行列号只在数据块内计算，因此清零。
+func streamErrorPosition(err error, start int64) {
+	var encErr *EncodingError
+	if errors.As(err, &encErr) && encErr.Bytes != nil {
+		encErr.ByteOffset += start
+		encErr.Line, encErr.Column = 0, 0
+	}
+}
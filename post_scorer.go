@@ -0,0 +1,129 @@
+package encoding
+
+import (
+	"unicode"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// PostScorer 候选解码后的二次评分器
+//
+// 在候选编码完成解码和基础评分后调用，可根据解码文本在目标语言中的统计合理性
+// 对得分进行调整（负值表示扣分），用于捕获基础评分无法区分的误判。
+type PostScorer interface {
+	// Name 评分器名称（记录在得分组成中）
+	Name() string
+
+	// PostScore 返回对候选得分的调整值
+	PostScore(candidate *DetectionCandidate, text string) float64
+}
+
+// PostScorerFunc 函数形式的二次评分器
+type PostScorerFunc struct {
+	// ScorerName 评分器名称
+	ScorerName string
+
+	// Func 评分函数
+	Func func(candidate *DetectionCandidate, text string) float64
+}
+
+// Name 返回评分器名称
+func (f PostScorerFunc) Name() string {
+	return f.ScorerName
+}
+
+// PostScore 调用评分函数
+func (f PostScorerFunc) PostScore(candidate *DetectionCandidate, text string) float64 {
+	return f.Func(candidate, text)
+}
+
+// rareHanziScorer 检测罕用汉字连续出现的二次评分器
+//
+// GBK 与 Big5 互相误解码时，结果往往是大量连续的罕用字。常用字的判定依据：
+// 能编码进 GB2312 一级字库（高字节 0xB0-0xD7）或 Big5 常用字区（高字节 0xA4-0xC6）。
+type rareHanziScorer struct {
+	// Penalty 罕用字比例为 100% 时的最大扣分
+	Penalty float64
+
+	// RunLength 触发额外扣分的罕用字连续长度
+	RunLength int
+}
+
+// NewRareHanziScorer 创建罕用汉字连续出现检测评分器
+func NewRareHanziScorer() PostScorer {
+	return &rareHanziScorer{
+		Penalty:   0.3,
+		RunLength: 4,
+	}
+}
+
+// Name 返回评分器名称
+func (s *rareHanziScorer) Name() string {
+	return "rare_hanzi"
+}
+
+// PostScore 按罕用汉字比例和最长连续长度扣分
+func (s *rareHanziScorer) PostScore(candidate *DetectionCandidate, text string) float64 {
+	hanzi, rare, run, longestRun := 0, 0, 0, 0
+	for _, r := range text {
+		if !unicode.Is(unicode.Han, r) {
+			run = 0
+			continue
+		}
+
+		hanzi++
+		if isCommonHanzi(r) {
+			run = 0
+			continue
+		}
+
+		rare++
+		run++
+		if run > longestRun {
+			longestRun = run
+		}
+	}
+
+	if hanzi < 2 {
+		return 0
+	}
+
+	adjustment := -s.Penalty * float64(rare) / float64(hanzi)
+	if longestRun >= s.RunLength {
+		adjustment -= s.Penalty / 3
+	}
+	return adjustment
+}
+
+// isCommonHanzi 判断汉字是否属于 GB2312 一级字库或 Big5 常用字区
+func isCommonHanzi(r rune) bool {
+	if lead, ok := encodeLeadByte(simplifiedchinese.GBK, r); ok && lead >= 0xB0 && lead <= 0xD7 {
+		return true
+	}
+	if lead, ok := encodeLeadByte(traditionalchinese.Big5, r); ok && lead >= 0xA4 && lead <= 0xC6 {
+		return true
+	}
+	return false
+}
+
+// encodeLeadByte 返回字符在指定双字节编码中的高字节
+func encodeLeadByte(enc encoding.Encoding, r rune) (byte, bool) {
+	encoded, err := enc.NewEncoder().String(string(r))
+	if err != nil || len(encoded) != 2 {
+		return 0, false
+	}
+	return encoded[0], true
+}
+
+// postScorers 获取生效的二次评分器列表
+func (d *defaultDetector) postScorers() []PostScorer {
+	if d.config.PostScorers != nil {
+		return d.config.PostScorers
+	}
+	return defaultPostScorers
+}
+
+// defaultPostScorers 默认二次评分器
+var defaultPostScorers = []PostScorer{NewRareHanziScorer()}
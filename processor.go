@@ -1,6 +1,9 @@
 package encoding
 
 import (
+	"context"
+	"errors"
+	"io"
 	"time"
 )
 
@@ -9,6 +12,8 @@ type defaultProcessor struct {
 	detector  Detector
 	converter Converter
 	config    *ProcessorConfig
+	logger    Logger
+	lifecycle lifecycle
 }
 
 // NewProcessor 创建新的处理器
@@ -17,50 +22,275 @@ func NewProcessor(config *ProcessorConfig) Processor {
 		config = GetDefaultProcessorConfig()
 	}
 
+	detectorConfig, converterConfig := config.DetectorConfig, config.ConverterConfig
+	if config.EnableMetrics && config.Metrics != nil {
+		detectorConfig, converterConfig = withMetrics(config.Metrics, detectorConfig, converterConfig)
+	}
+
+	detector := config.Detector
+	if detector == nil {
+		detector = NewDetector(detectorConfig)
+	}
+
 	return &defaultProcessor{
-		detector:  NewDetector(config.DetectorConfig),
-		converter: NewConverter(config.ConverterConfig),
+		detector:  detector,
+		converter: NewConverter(converterConfig),
 		config:    config,
+		logger:    newLogger(config),
+	}
+}
+
+// withMetrics 返回设置了性能监控器的检测器和转换器配置副本（已设置 Metrics 的配置保持不变）
+func withMetrics(metrics MetricsCollector, detectorConfig *DetectorConfig, converterConfig *ConverterConfig) (*DetectorConfig, *ConverterConfig) {
+	if detectorConfig == nil {
+		detectorConfig = GetDefaultDetectorConfig()
+	}
+	if detectorConfig.Metrics == nil {
+		copied := *detectorConfig
+		copied.Metrics = metrics
+		detectorConfig = &copied
+	}
+
+	if converterConfig == nil {
+		converterConfig = GetDefaultConverterConfig()
+	}
+	if converterConfig.Metrics == nil {
+		copied := *converterConfig
+		copied.Metrics = metrics
+		converterConfig = &copied
 	}
+	return detectorConfig, converterConfig
 }
 
 // DetectEncoding 检测数据的编码格式
 func (p *defaultProcessor) DetectEncoding(data []byte) (*DetectionResult, error) {
-	return p.detector.DetectEncoding(data)
+	if err := p.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.lifecycle.release()
+
+	_, span := startSpan(context.Background(), p.config, SpanDetectEncoding, SpanAttribute{Key: AttributeBytes, Value: int64(len(data))})
+	result, err := p.detector.DetectEncoding(data)
+	endDetectionSpan(span, result, err)
+	return result, err
+}
+
+// DetectEncodingContext 检测数据的编码格式（支持取消）
+func (p *defaultProcessor) DetectEncodingContext(ctx context.Context, data []byte) (*DetectionResult, error) {
+	if err := p.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.lifecycle.release()
+
+	ctx, span := startSpan(ctx, p.config, SpanDetectEncoding, SpanAttribute{Key: AttributeBytes, Value: int64(len(data))})
+	result, err := detectEncodingContext(ctx, p.detector, data)
+	endDetectionSpan(span, result, err)
+	return result, err
+}
+
+// DetectReaderEncoding 读取样本检测编码，返回检测结果和重放已读取数据的读取器
+func (p *defaultProcessor) DetectReaderEncoding(r io.Reader) (*DetectionResult, io.Reader, error) {
+	if err := p.lifecycle.acquire(); err != nil {
+		return nil, r, err
+	}
+	defer p.lifecycle.release()
+
+	if rd, ok := p.detector.(ReaderDetector); ok {
+		return rd.DetectReaderEncoding(r)
+	}
+	var sampleSize int
+	if p.config.DetectorConfig != nil {
+		sampleSize = p.config.DetectorConfig.SampleSize
+	}
+	return detectReaderEncoding(p.detector, r, sampleSize)
 }
 
 // DetectFileEncoding 检测文件的编码格式
 func (p *defaultProcessor) DetectFileEncoding(filename string) (*DetectionResult, error) {
+	if err := p.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.lifecycle.release()
+
 	return p.detector.DetectFileEncoding(filename)
 }
 
+// DetectEncodingWithPath 结合文件路径检测数据编码（路径用于匹配检测覆盖规则）
+func (p *defaultProcessor) DetectEncodingWithPath(path string, data []byte) (*DetectionResult, error) {
+	if err := p.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.lifecycle.release()
+
+	if pd, ok := p.detector.(pathDetector); ok {
+		return pd.DetectEncodingWithPath(path, data)
+	}
+	return p.detector.DetectEncoding(data)
+}
+
 // DetectBestEncoding 检测最可能的编码格式
 func (p *defaultProcessor) DetectBestEncoding(data []byte) (string, error) {
+	if err := p.lifecycle.acquire(); err != nil {
+		return "", err
+	}
+	defer p.lifecycle.release()
+
 	return p.detector.DetectBestEncoding(data)
 }
 
 // SmartDetectEncoding 智能编码检测
 func (p *defaultProcessor) SmartDetectEncoding(data []byte) (*DetectionResult, error) {
+	if err := p.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.lifecycle.release()
+
+	return p.detector.SmartDetectEncoding(data)
+}
+
+// SmartDetectEncodingWithPath 结合文件路径智能检测数据编码（路径用于匹配检测覆盖规则）
+func (p *defaultProcessor) SmartDetectEncodingWithPath(path string, data []byte) (*DetectionResult, error) {
+	if err := p.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.lifecycle.release()
+
+	if spd, ok := p.detector.(smartPathDetector); ok {
+		return spd.SmartDetectEncodingWithPath(path, data)
+	}
 	return p.detector.SmartDetectEncoding(data)
 }
 
+// DetectWithContentHints 结合编码提示检测编码
+func (p *defaultProcessor) DetectWithContentHints(data []byte, contentType string) (*DetectionResult, error) {
+	if err := p.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.lifecycle.release()
+
+	if hd, ok := p.detector.(ContentHintDetector); ok {
+		return hd.DetectWithContentHints(data, contentType)
+	}
+	return nil, unsupportedExtension(OperationDetect, p.detector, "ContentHintDetector")
+}
+
+// DetectAllEncodings 返回所有候选编码及其得分组成
+func (p *defaultProcessor) DetectAllEncodings(data []byte) ([]*DetectionCandidate, error) {
+	if err := p.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.lifecycle.release()
+
+	if cd, ok := p.detector.(CandidateDetector); ok {
+		return cd.DetectAllEncodings(data)
+	}
+	return nil, unsupportedExtension(OperationDetect, p.detector, "CandidateDetector")
+}
+
+// ExplainDetection 报告检测的每项检查及选择结果的原因
+func (p *defaultProcessor) ExplainDetection(data []byte) (*DetectionExplanation, error) {
+	if err := p.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.lifecycle.release()
+
+	if ed, ok := p.detector.(ExplainingDetector); ok {
+		return ed.ExplainDetection(data)
+	}
+	return nil, unsupportedExtension(OperationDetect, p.detector, "ExplainingDetector")
+}
+
 // Convert 在指定编码之间转换
 func (p *defaultProcessor) Convert(data []byte, from, to string) ([]byte, error) {
-	return p.converter.Convert(data, from, to)
+	if err := p.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.lifecycle.release()
+
+	_, span := startSpan(context.Background(), p.config, SpanConvert, conversionAttributes(from, to, len(data))...)
+	result, err := p.converter.Convert(data, from, to)
+	endSpan(span, err)
+	return result, err
+}
+
+// ConvertContext 在指定编码之间转换（支持取消和超时）
+func (p *defaultProcessor) ConvertContext(ctx context.Context, data []byte, from, to string) ([]byte, error) {
+	if err := p.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.lifecycle.release()
+
+	ctx, span := startSpan(ctx, p.config, SpanConvert, conversionAttributes(from, to, len(data))...)
+	result, err := convertContext(ctx, p.converter, data, from, to)
+	endSpan(span, err)
+	return result, err
+}
+
+// ConvertWithOptions 按单次转换选项在指定编码之间转换
+func (p *defaultProcessor) ConvertWithOptions(data []byte, from, to string, options *ConvertOptions) (*ConvertResult, error) {
+	if err := p.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.lifecycle.release()
+
+	_, span := startSpan(context.Background(), p.config, SpanConvert, conversionAttributes(from, to, len(data))...)
+	oc, ok := p.converter.(OptionsConverter)
+	if !ok {
+		err := unsupportedExtension(OperationConvert, p.converter, "OptionsConverter")
+		endSpan(span, err)
+		return nil, err
+	}
+	result, err := oc.ConvertWithOptions(data, from, to, options)
+	endSpan(span, err)
+	return result, err
 }
 
 // ConvertToUTF8 转换为 UTF-8 编码
 func (p *defaultProcessor) ConvertToUTF8(data []byte, from string) ([]byte, error) {
+	if err := p.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.lifecycle.release()
+
 	return p.converter.ConvertToUTF8(data, from)
 }
 
 // ConvertString 字符串编码转换
 func (p *defaultProcessor) ConvertString(text, from, to string) (string, error) {
+	if err := p.lifecycle.acquire(); err != nil {
+		return "", err
+	}
+	defer p.lifecycle.release()
+
 	return p.converter.ConvertString(text, from, to)
 }
 
 // SmartConvert 智能转换（自动检测源编码）
 func (p *defaultProcessor) SmartConvert(data []byte, target string) (*ConvertResult, error) {
+	return p.SmartConvertContext(context.Background(), data, target)
+}
+
+// SmartConvertContext 智能转换（自动检测源编码），上下文取消或超时后中止检测或转换
+func (p *defaultProcessor) SmartConvertContext(ctx context.Context, data []byte, target string) (*ConvertResult, error) {
+	if err := p.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.lifecycle.release()
+
+	ctx, span := startSpan(ctx, p.config, SpanSmartConvert,
+		SpanAttribute{Key: AttributeTargetEncoding, Value: target},
+		SpanAttribute{Key: AttributeBytes, Value: int64(len(data))},
+	)
+	result, err := p.smartConvert(ctx, data, target)
+	if err == nil {
+		span.SetAttributes(SpanAttribute{Key: AttributeSourceEncoding, Value: result.SourceEncoding})
+	}
+	endSpan(span, err)
+	return result, err
+}
+
+// smartConvert 检测源编码并转换（调用方需已持有生命周期）
+func (p *defaultProcessor) smartConvert(ctx context.Context, data []byte, target string) (*ConvertResult, error) {
 	if len(data) == 0 {
 		return &ConvertResult{
 			Data:           []byte{},
@@ -74,28 +304,142 @@ func (p *defaultProcessor) SmartConvert(data []byte, target string) (*ConvertRes
 	start := time.Now()
 
 	// 检测源编码
-	detection, err := p.detector.DetectEncoding(data)
+	detection, err := detectEncodingContext(ctx, p.detector, data)
 	if err != nil {
 		return nil, err
 	}
+	logDetection(p.logger, "", detection)
 
 	// 转换编码
-	convertedData, err := p.converter.Convert(data, detection.Encoding, target)
+	convertedData, trace, err := p.convert(ctx, data, detection.Encoding, target)
 	if err != nil {
 		return nil, err
 	}
+	logReplacements(p.logger, "", detection.Encoding, target, trace)
 
-	return &ConvertResult{
-		Data:           convertedData,
-		SourceEncoding: detection.Encoding,
-		TargetEncoding: target,
-		BytesProcessed: int64(len(data)),
-		ConversionTime: time.Since(start),
-	}, nil
+	result := &ConvertResult{
+		Data:                 convertedData,
+		SourceEncoding:       detection.Encoding,
+		TargetEncoding:       target,
+		BytesProcessed:       int64(len(data)),
+		ConversionTime:       time.Since(start),
+		SourceFinalNewline:   HasFinalNewline(data, detection.Encoding),
+		TargetFinalNewline:   HasFinalNewline(convertedData, target),
+		Memory:               trace.usage,
+		LostRunes:            trace.lostHistogram(),
+		Replacements:         trace.replacements,
+		BOMStripped:          trace.bomStripped,
+		BOMAdded:             trace.bomAdded,
+		LineEndingsConverted: trace.lineEndingsConverted,
+		Warnings:             trace.warnings(),
+	}
+
+	// 得分相近时同时返回次优候选的转换结果（可选）
+	if p.config.AlternativeMargin > 0 {
+		result.Alternative = p.alternativeConversion(ctx, data, detection.Encoding, target)
+	}
+
+	// 生成位置映射（可选）
+	if p.config.ConverterConfig != nil && p.config.ConverterConfig.PositionMapInterval > 0 {
+		if c, ok := p.converter.(*defaultConverter); ok {
+			positionMap, err := c.buildPositionMap(data, detection.Encoding, target, p.config.ConverterConfig.PositionMapInterval, convertedData)
+			if err != nil {
+				return nil, err
+			}
+			result.PositionMap = positionMap
+		}
+	}
+
+	return result, nil
+}
+
+// alternativeConversion 查找与首选编码得分相近的次优候选并转换（没有或转换失败时返回 nil）
+func (p *defaultProcessor) alternativeConversion(ctx context.Context, data []byte, primary, target string) *AlternativeConversion {
+	cd, ok := p.detector.(CandidateDetector)
+	if !ok {
+		return nil
+	}
+	candidates, err := cd.DetectAllEncodings(data)
+	if err != nil {
+		return nil
+	}
+
+	// 候选按综合得分降序排列，首选编码不在候选中时以最高分为准
+	var primaryScore float64
+	var found bool
+	var runnerUp *DetectionCandidate
+	for _, candidate := range candidates {
+		if candidate.Encoding == primary {
+			if !found {
+				primaryScore, found = candidate.Score.Total, true
+			}
+			continue
+		}
+		if runnerUp == nil && !candidate.Score.ConversionFailed {
+			runnerUp = candidate
+		}
+	}
+	if runnerUp == nil {
+		return nil
+	}
+	if !found {
+		primaryScore = candidates[0].Score.Total
+	}
+
+	margin := primaryScore - runnerUp.Score.Total
+	if margin < 0 {
+		margin = -margin
+	}
+	if margin > p.config.AlternativeMargin {
+		return nil
+	}
+
+	converted, _, err := p.convert(ctx, data, runnerUp.Encoding, target)
+	if err != nil {
+		return nil
+	}
+	return &AlternativeConversion{
+		Encoding: runnerUp.Encoding,
+		Data:     converted,
+		Score:    runnerUp.Score.Total,
+		Margin:   margin,
+	}
+}
+
+// convert 执行转换并收集内存占用和质量统计（调用方需已持有生命周期，ctx 用于取消转换）
+func (p *defaultProcessor) convert(ctx context.Context, data []byte, from, to string) ([]byte, *conversionTrace, error) {
+	if c, ok := p.converter.(*defaultConverter); ok {
+		return c.withContext(ctx).convertWithTrace(data, from, to)
+	}
+
+	trace := &conversionTrace{}
+	result, err := convertContext(ctx, p.converter, data, from, to)
+	trace.usage.finish(len(data), cap(result))
+	return result, trace, err
+}
+
+// convertTraced 执行转换并返回质量统计（实现 tracedConverter 接口）
+func (p *defaultProcessor) convertTraced(data []byte, from, to string) ([]byte, *conversionTrace, error) {
+	if err := p.lifecycle.acquire(); err != nil {
+		return nil, nil, err
+	}
+	defer p.lifecycle.release()
+
+	return p.convert(context.Background(), data, from, to)
+}
+
+// tracedConverter 支持返回转换质量统计的处理器
+type tracedConverter interface {
+	convertTraced(data []byte, from, to string) ([]byte, *conversionTrace, error)
 }
 
 // SmartConvertString 智能字符串转换（自动检测源编码）
 func (p *defaultProcessor) SmartConvertString(text, target string) (*StringConvertResult, error) {
+	if err := p.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.lifecycle.release()
+
 	if text == "" {
 		return &StringConvertResult{
 			Text:           "",
@@ -128,4 +472,19 @@ func (p *defaultProcessor) SmartConvertString(text, target string) (*StringConve
 		BytesProcessed: int64(len(data)),
 		ConversionTime: time.Since(start),
 	}, nil
-}
\ No newline at end of file
+}
+// Drain 停止接受新请求，等待进行中的操作完成后释放检测缓存、池化的转换器等资源
+func (p *defaultProcessor) Drain(ctx context.Context) error {
+	var closeErr error
+	if err := p.lifecycle.drain(ctx, func() {
+		closeErr = errors.Join(closeComponent(ctx, p.detector), closeComponent(ctx, p.converter))
+	}); err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// Close 关闭处理器并等待进行中的操作完成
+func (p *defaultProcessor) Close() error {
+	return p.Drain(context.Background())
+}
@@ -0,0 +1,213 @@
+package encoding
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+)
+
+// 属性文件格式
+const (
+	PropertiesFormatJava = "properties" // Java .properties 文件
+	PropertiesFormatINI  = "ini"        // INI 配置文件
+)
+
+// \uXXXX 转义处理方式
+const (
+	EscapeModePreserve = "preserve" // 保留已有转义，仅为目标编码无法表示的字符生成转义
+	EscapeModeExpand   = "expand"   // 将已有转义展开为目标编码可表示的字符
+	EscapeModeGenerate = "generate" // 将所有非 ASCII 字符写为转义（Java native2ascii 风格）
+)
+
+// PropertiesOptions 属性/INI 文件转换选项
+type PropertiesOptions struct {
+	// SourceEncoding 源编码（空值表示自动检测）
+	SourceEncoding string `json:"source_encoding"`
+
+	// TargetEncoding 目标编码（默认 UTF-8）
+	TargetEncoding string `json:"target_encoding"`
+
+	// Format 文件格式（properties、ini，默认 properties）
+	Format string `json:"format"`
+
+	// EscapeMode 转义处理方式（preserve、expand、generate，默认 preserve）
+	EscapeMode string `json:"escape_mode"`
+}
+
+// ConvertProperties 转换 Java .properties 或 INI 文件的编码并处理 \uXXXX 转义
+//
+// 注释行中的转义不会被展开（与 java.util.Properties 的加载行为一致）。
+// 展开时只处理非 ASCII 字符，控制字符和分隔符等转义保持原样以免破坏文件结构。
+func ConvertProperties(data []byte, options *PropertiesOptions) (*ConvertResult, error) {
+	if options == nil {
+		options = &PropertiesOptions{}
+	}
+	start := time.Now()
+
+	target := options.TargetEncoding
+	if target == "" {
+		target = EncodingUTF8
+	}
+
+	source := options.SourceEncoding
+	if source == "" {
+		detection, err := newTransientDetector().DetectEncoding(data)
+		if err != nil {
+			return nil, err
+		}
+		source = detection.Encoding
+	}
+
+	converter := NewConverter().(*defaultConverter)
+	text, err := converter.Convert(data, source, EncodingUTF8)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder, err := converter.getEncoder(target)
+	if err != nil {
+		return nil, &EncodingError{Op: OperationConvert, Encoding: target, Err: err}
+	}
+
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(text, []byte("\n")) {
+		if isPropertiesComment(line, options.Format) {
+			if options.EscapeMode == EscapeModeGenerate {
+				out.Write(generateUnicodeEscapes(line, nil))
+			} else {
+				out.Write(generateUnicodeEscapes(line, encoder))
+			}
+			continue
+		}
+
+		switch options.EscapeMode {
+		case EscapeModeExpand:
+			out.Write(generateUnicodeEscapes(expandUnicodeEscapes(line, encoder), encoder))
+		case EscapeModeGenerate:
+			out.Write(generateUnicodeEscapes(line, nil))
+		default:
+			out.Write(generateUnicodeEscapes(line, encoder))
+		}
+	}
+
+	output, err := converter.Convert(out.Bytes(), EncodingUTF8, target)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConvertResult{
+		Data:           output,
+		SourceEncoding: source,
+		TargetEncoding: target,
+		BytesProcessed: int64(len(data)),
+		ConversionTime: time.Since(start),
+	}, nil
+}
+
+// isPropertiesComment 判断是否为注释行
+func isPropertiesComment(line []byte, format string) bool {
+	trimmed := bytes.TrimLeft(line, " \t\f")
+	if len(trimmed) == 0 {
+		return false
+	}
+	switch trimmed[0] {
+	case '#':
+		return true
+	case '!':
+		return format != PropertiesFormatINI
+	case ';':
+		return format == PropertiesFormatINI
+	}
+	return false
+}
+
+// expandUnicodeEscapes 将 \uXXXX 转义展开为字符（仅展开目标编码可表示的非 ASCII 字符）
+func expandUnicodeEscapes(line []byte, encoder transform.Transformer) []byte {
+	var out bytes.Buffer
+	for i := 0; i < len(line); i++ {
+		if line[i] != '\\' || i+1 >= len(line) {
+			out.WriteByte(line[i])
+			continue
+		}
+
+		if line[i+1] != 'u' {
+			// 其他转义（包括 \\）原样保留
+			out.Write(line[i : i+2])
+			i++
+			continue
+		}
+
+		r, consumed, ok := parseUnicodeEscape(line[i:])
+		if !ok || r < 0xA0 {
+			out.Write(line[i : i+2])
+			i++
+			continue
+		}
+
+		encoded := []byte(string(r))
+		if !canEncodeRune(encoder, encoded) {
+			out.Write(line[i : i+consumed])
+			i += consumed - 1
+			continue
+		}
+
+		out.Write(encoded)
+		i += consumed - 1
+	}
+	return out.Bytes()
+}
+
+// parseUnicodeEscape 解析 \uXXXX 转义（支持代理对），返回字符与消费的字节数
+func parseUnicodeEscape(data []byte) (rune, int, bool) {
+	if len(data) < 6 || data[0] != '\\' || data[1] != 'u' {
+		return 0, 0, false
+	}
+	v, err := strconv.ParseUint(string(data[2:6]), 16, 16)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	r := rune(v)
+	if utf16.IsSurrogate(r) {
+		low, _, ok := parseUnicodeEscape(data[6:])
+		if !ok {
+			return 0, 0, false
+		}
+		combined := utf16.DecodeRune(r, low)
+		if combined == utf8.RuneError {
+			return 0, 0, false
+		}
+		return combined, 12, true
+	}
+	return r, 6, true
+}
+
+// generateUnicodeEscapes 将非 ASCII 字符改写为 \uXXXX 转义
+//
+// encoder 为 nil 时转义所有非 ASCII 字符，否则只转义目标编码无法表示的字符。
+func generateUnicodeEscapes(line []byte, encoder transform.Transformer) []byte {
+	var out bytes.Buffer
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRune(line[i:])
+		chunk := line[i : i+size]
+		i += size
+
+		if r < utf8.RuneSelf || (encoder != nil && canEncodeRune(encoder, chunk)) {
+			out.Write(chunk)
+			continue
+		}
+
+		if r > 0xFFFF {
+			high, low := utf16.EncodeRune(r)
+			fmt.Fprintf(&out, `\u%04X\u%04X`, high, low)
+			continue
+		}
+		fmt.Fprintf(&out, `\u%04X`, r)
+	}
+	return out.Bytes()
+}
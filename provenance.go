@@ -0,0 +1,199 @@
+package encoding
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ProvenanceMarker 转换来源注释头的标记，用于在后续处理时识别并去除已有的注释头
+const ProvenanceMarker = "encproc:"
+
+// DefaultProvenanceTemplate 默认转换来源注释头模板
+const DefaultProvenanceTemplate = "converted from {original} to {target} on {date}"
+
+// CommentSyntax 注释语法（Line 非空时使用行注释，否则使用 BlockStart/BlockEnd 块注释）
+type CommentSyntax struct {
+	// Line 行注释前缀，如 "//"、"#"
+	Line string `json:"line,omitempty"`
+
+	// BlockStart 块注释开始标记，如 "<!--"
+	BlockStart string `json:"block_start,omitempty"`
+
+	// BlockEnd 块注释结束标记，如 "-->"
+	BlockEnd string `json:"block_end,omitempty"`
+}
+
+// ProvenanceOptions 转换来源注释头选项
+//
+// 启用后在转换后的文件开头插入记录原始编码和转换日期的注释头（位于 shebang 和 XML 声明之后），
+// 插入前会去除已有的注释头以避免重复。没有对应注释语法的文件类型不插入注释头。
+type ProvenanceOptions struct {
+	// Template 注释头模板（默认 DefaultProvenanceTemplate），多行模板逐行生成注释；
+	// 支持占位符 {original}、{target}、{date}、{file}、{version}
+	Template string `json:"template,omitempty"`
+
+	// CommentSyntax 按扩展名（含点，不区分大小写）覆盖或补充内置注释语法
+	CommentSyntax map[string]CommentSyntax `json:"comment_syntax,omitempty"`
+
+	// Strip 只去除已有的注释头，不插入新的注释头
+	Strip bool `json:"strip,omitempty"`
+}
+
+// 内置注释语法
+var (
+	slashComment = CommentSyntax{Line: "//"}
+	hashComment  = CommentSyntax{Line: "#"}
+	dashComment  = CommentSyntax{Line: "--"}
+	semiComment  = CommentSyntax{Line: ";"}
+	xmlComment   = CommentSyntax{BlockStart: "<!--", BlockEnd: "-->"}
+	cssComment   = CommentSyntax{BlockStart: "/*", BlockEnd: "*/"}
+
+	defaultCommentSyntax = map[string]CommentSyntax{
+		".go": slashComment, ".c": slashComment, ".h": slashComment, ".cc": slashComment,
+		".cpp": slashComment, ".hpp": slashComment, ".java": slashComment, ".js": slashComment,
+		".ts": slashComment, ".cs": slashComment, ".kt": slashComment, ".rs": slashComment,
+		".swift": slashComment, ".scala": slashComment, ".php": slashComment,
+		".py": hashComment, ".sh": hashComment, ".rb": hashComment, ".pl": hashComment,
+		".yaml": hashComment, ".yml": hashComment, ".toml": hashComment, ".conf": hashComment,
+		".properties": hashComment, ".r": hashComment, ".ps1": hashComment,
+		".sql": dashComment, ".lua": dashComment, ".hs": dashComment,
+		".ini": semiComment, ".asm": semiComment,
+		".html": xmlComment, ".htm": xmlComment, ".xml": xmlComment, ".vue": xmlComment,
+		".md":  xmlComment,
+		".css": cssComment,
+		".bat": {Line: "REM"}, ".cmd": {Line: "REM"},
+		".tex": {Line: "%"},
+	}
+)
+
+// syntaxFor 返回文件路径对应的注释语法
+func (o *ProvenanceOptions) syntaxFor(path string) (CommentSyntax, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if syntax, ok := o.CommentSyntax[ext]; ok {
+		return syntax, syntax.Line != "" || syntax.BlockStart != ""
+	}
+	syntax, ok := defaultCommentSyntax[ext]
+	return syntax, ok
+}
+
+// commentLine 生成带标记的单行注释
+func (s CommentSyntax) commentLine(text string) string {
+	if s.Line != "" {
+		return s.Line + " " + ProvenanceMarker + " " + text
+	}
+	return s.BlockStart + " " + ProvenanceMarker + " " + text + " " + s.BlockEnd
+}
+
+// isHeaderLine 检查一行是否为带标记的注释头
+func (s CommentSyntax) isHeaderLine(line []byte) bool {
+	line = bytes.TrimRight(line, "\r")
+	prefix := s.Line
+	if prefix == "" {
+		prefix = s.BlockStart
+		if !bytes.HasSuffix(line, []byte(s.BlockEnd)) {
+			return false
+		}
+	}
+	return bytes.HasPrefix(line, []byte(prefix+" "+ProvenanceMarker+" "))
+}
+
+// ProvenanceHeader 按模板生成转换来源注释头（UTF-8，每行以 lineEnding 结尾）；
+// 文件类型没有对应注释语法时返回 nil
+func (o *ProvenanceOptions) ProvenanceHeader(path, original, target string, date time.Time, lineEnding string) []byte {
+	syntax, ok := o.syntaxFor(path)
+	if !ok {
+		return nil
+	}
+
+	template := o.Template
+	if template == "" {
+		template = DefaultProvenanceTemplate
+	}
+	text := strings.NewReplacer(
+		"{original}", original,
+		"{target}", target,
+		"{date}", date.Format("2006-01-02"),
+		"{file}", filepath.Base(path),
+		"{version}", Version,
+	).Replace(template)
+
+	var header bytes.Buffer
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		header.WriteString(syntax.commentLine(strings.TrimRight(line, "\r")))
+		header.WriteString(lineEnding)
+	}
+	return header.Bytes()
+}
+
+// StripProvenanceHeader 去除 UTF-8 文本开头（shebang 和 XML 声明之后）的转换来源注释头
+func (o *ProvenanceOptions) StripProvenanceHeader(path string, text []byte) []byte {
+	syntax, ok := o.syntaxFor(path)
+	if !ok {
+		return text
+	}
+
+	offset := preambleLength(text)
+	end := offset
+	for end < len(text) {
+		next := bytes.IndexByte(text[end:], '\n')
+		lineEnd := len(text)
+		if next >= 0 {
+			lineEnd = end + next + 1
+		}
+		if !syntax.isHeaderLine(bytes.TrimSuffix(text[end:lineEnd], []byte("\n"))) {
+			break
+		}
+		end = lineEnd
+	}
+	if end == offset {
+		return text
+	}
+
+	result := make([]byte, 0, len(text)-(end-offset))
+	result = append(result, text[:offset]...)
+	return append(result, text[end:]...)
+}
+
+// addProvenanceHeader 去除已有注释头后在 UTF-8 文本开头插入新的注释头
+func (o *ProvenanceOptions) addProvenanceHeader(path string, text []byte, original, target string) []byte {
+	text = o.StripProvenanceHeader(path, text)
+	if o.Strip {
+		return text
+	}
+
+	lineEnding := LineEndingLF
+	if bytes.Contains(text, []byte("\r\n")) {
+		lineEnding = LineEndingCRLF
+	}
+	header := o.ProvenanceHeader(path, original, target, time.Now(), lineEnding)
+	if header == nil {
+		return text
+	}
+
+	offset := preambleLength(text)
+	result := make([]byte, 0, len(text)+len(lineEnding)+len(header))
+	result = append(result, text[:offset]...)
+	if offset > 0 && text[offset-1] != '\n' && !bytes.Equal(text[:offset], utf8BOM) {
+		// 没有换行符结尾的 shebang 或 XML 声明
+		result = append(result, lineEnding...)
+	}
+	result = append(result, header...)
+	return append(result, text[offset:]...)
+}
+
+// preambleLength 返回必须位于文件开头的内容（UTF-8 BOM、shebang、XML 声明）的长度
+func preambleLength(text []byte) int {
+	n := 0
+	if bytes.HasPrefix(text, utf8BOM) {
+		n = len(utf8BOM)
+	}
+	if !bytes.HasPrefix(text[n:], []byte("#!")) && !bytes.HasPrefix(text[n:], []byte("<?xml")) {
+		return n
+	}
+	if i := bytes.IndexByte(text[n:], '\n'); i >= 0 {
+		return n + i + 1
+	}
+	return len(text)
+}
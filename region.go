@@ -0,0 +1,209 @@
+package encoding
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ByteRange 需要转换的字节区间 [Offset, Offset+Length)
+type ByteRange struct {
+	// Offset 区间起始偏移
+	Offset int64 `json:"offset"`
+
+	// Length 区间长度
+	Length int64 `json:"length"`
+
+	// SourceEncoding 区间的源编码（为空时使用 RangeConvertOptions.SourceEncoding）
+	SourceEncoding string `json:"source_encoding,omitempty"`
+}
+
+// RangeConvertOptions 区间转换选项
+type RangeConvertOptions struct {
+	// Ranges 需要转换的区间（不可重叠，区间之外的字节原样复制）
+	Ranges []ByteRange `json:"ranges"`
+
+	// SourceEncoding 区间的默认源编码
+	SourceEncoding string `json:"source_encoding"`
+
+	// TargetEncoding 目标编码（默认 UTF-8）
+	TargetEncoding string `json:"target_encoding"`
+
+	// AllowResize 是否允许转换结果改变区间长度；为 false 时转换结果必须不超过原区间长度，
+	// 不足部分以 PadByte 填充，从而保持其余数据的偏移不变
+	AllowResize bool `json:"allow_resize"`
+
+	// PadByte 固定长度区间的填充字节（默认 0x00）
+	PadByte byte `json:"pad_byte"`
+
+	// ConverterConfig 转换器配置（nil 表示使用默认配置，末尾换行符策略不作用于区间）
+	ConverterConfig *ConverterConfig `json:"-"`
+}
+
+// RangeResult 单个区间的转换结果
+type RangeResult struct {
+	// Range 原始区间
+	Range ByteRange `json:"range"`
+
+	// OutputOffset 转换结果在输出中的偏移
+	OutputOffset int64 `json:"output_offset"`
+
+	// ConvertedLength 转换结果的长度（不含填充）
+	ConvertedLength int64 `json:"converted_length"`
+
+	// Padding 填充的字节数
+	Padding int64 `json:"padding,omitempty"`
+
+	// LostRunes 因目标编码无法表示而被替换或丢弃的字符及其次数
+	LostRunes map[string]int64 `json:"lost_runes,omitempty"`
+}
+
+// RangeConvertReport 区间转换报告
+type RangeConvertReport struct {
+	// Ranges 各区间结果（按偏移递增排列）
+	Ranges []RangeResult `json:"ranges"`
+
+	// BytesConverted 转换的源字节数
+	BytesConverted int64 `json:"bytes_converted"`
+
+	// BytesCopied 原样复制的字节数
+	BytesCopied int64 `json:"bytes_copied"`
+
+	// OutputSize 输出大小
+	OutputSize int64 `json:"output_size"`
+}
+
+// ConvertRanges 只转换数据中指定的字节区间，其余字节原样复制
+//
+// 适用于二进制格式中嵌入的字符串表（游戏本地化、固件文本修补等）。区间越界、重叠，
+// 或在不允许改变长度时转换结果超出区间长度，都返回 ErrInvalidRange 且不产生输出。
+func ConvertRanges(data []byte, options *RangeConvertOptions) ([]byte, *RangeConvertReport, error) {
+	if options == nil {
+		return nil, nil, ErrInvalidInput
+	}
+	target := canonicalEncodingName(options.TargetEncoding)
+	if target == "" {
+		target = EncodingUTF8
+	}
+
+	ranges, err := validateRanges(options.Ranges, int64(len(data)))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	converter := NewConverter(options.ConverterConfig).(*defaultConverter)
+	report := &RangeConvertReport{}
+	output := make([]byte, 0, len(data))
+	var copied int64
+
+	for _, r := range ranges {
+		output = append(output, data[copied:r.Offset]...)
+		report.BytesCopied += r.Offset - copied
+
+		source := canonicalEncodingName(r.SourceEncoding)
+		if source == "" {
+			source = canonicalEncodingName(options.SourceEncoding)
+		}
+		region := data[r.Offset : r.Offset+r.Length]
+		trace := &conversionTrace{}
+		converted, err := converter.convertBytes(region, source, target, trace)
+		if err == nil {
+			err = converter.checkErrorThreshold(trace, r.Length, source, target)
+		}
+		if err != nil {
+			return nil, nil, &EncodingError{
+				Op:       OperationConvert,
+				Encoding: fmt.Sprintf("%s->%s", source, target),
+				Err:      fmt.Errorf("range at offset %d: %w", r.Offset, err),
+			}
+		}
+
+		result := RangeResult{
+			Range:           r,
+			OutputOffset:    int64(len(output)),
+			ConvertedLength: int64(len(converted)),
+			LostRunes:       trace.lostHistogram(),
+		}
+		if !options.AllowResize {
+			if result.ConvertedLength > r.Length {
+				return nil, nil, fmt.Errorf("%w: range at offset %d converts to %d bytes, exceeding its length %d",
+					ErrInvalidRange, r.Offset, result.ConvertedLength, r.Length)
+			}
+			result.Padding = r.Length - result.ConvertedLength
+		}
+
+		output = append(output, converted...)
+		for i := int64(0); i < result.Padding; i++ {
+			output = append(output, options.PadByte)
+		}
+		report.Ranges = append(report.Ranges, result)
+		report.BytesConverted += r.Length
+		copied = r.Offset + r.Length
+	}
+
+	output = append(output, data[copied:]...)
+	report.BytesCopied += int64(len(data)) - copied
+	report.OutputSize = int64(len(output))
+	return output, report, nil
+}
+
+// ConvertFileRanges 只转换文件中指定的字节区间，并通过临时文件原子写入输出文件（可与输入文件相同）
+func ConvertFileRanges(inputFile, outputFile string, options *RangeConvertOptions) (*RangeConvertReport, error) {
+	info, err := os.Stat(inputFile)
+	if err != nil {
+		return nil, &FileOperationError{Op: "stat", File: inputFile, Err: err}
+	}
+	data, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		return nil, &FileOperationError{Op: "read", File: inputFile, Err: err}
+	}
+
+	output, report, err := ConvertRanges(data, options)
+	if err != nil {
+		if ee, ok := err.(*EncodingError); ok {
+			ee.File = inputFile
+		}
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
+		return nil, &FileOperationError{Op: "mkdir", File: filepath.Dir(outputFile), Err: err}
+	}
+	out, err := createTempFile(outputFile, info.Mode().Perm())
+	if err != nil {
+		return nil, &FileOperationError{Op: "write_temp", File: outputFile, Err: err}
+	}
+	tempFile := out.Name()
+	_, err = out.Write(output)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempFile, outputFile)
+	}
+	if err != nil {
+		os.Remove(tempFile)
+		return nil, &FileOperationError{Op: "write", File: outputFile, Err: err}
+	}
+	return report, nil
+}
+
+// validateRanges 检查区间是否有效，返回按偏移排序的副本
+func validateRanges(ranges []ByteRange, size int64) ([]ByteRange, error) {
+	sorted := append([]ByteRange(nil), ranges...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Offset < sorted[j].Offset })
+
+	var end int64
+	for i, r := range sorted {
+		if r.Offset < 0 || r.Length <= 0 || r.Offset+r.Length > size {
+			return nil, fmt.Errorf("%w: offset %d length %d outside data of %d bytes", ErrInvalidRange, r.Offset, r.Length, size)
+		}
+		if i > 0 && r.Offset < end {
+			return nil, fmt.Errorf("%w: range at offset %d overlaps the previous range", ErrInvalidRange, r.Offset)
+		}
+		end = r.Offset + r.Length
+	}
+	return sorted, nil
+}
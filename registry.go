@@ -0,0 +1,15 @@
+package encoding
+
+import (
+	"github.com/mirbf/encoding-processor/converter"
+	"golang.org/x/text/encoding"
+)
+
+// RegisterEncoding 注册自定义编码（如 EBCDIC、厂商代码页），aliases 为可选的别名（比较时忽略大小写）
+//
+// 注册后该编码可像内置编码一样用于转换、文件处理和流处理，别名在结果中解析为 name；
+// 名称已存在时替换原有实现。自动检测不会返回自定义编码，处理时需显式指定源编码。
+// name 为空或 enc 为 nil 时 panic。
+func RegisterEncoding(name string, enc encoding.Encoding, aliases ...string) {
+	converter.Register(name, enc, aliases...)
+}
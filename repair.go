@@ -0,0 +1,281 @@
+package encoding
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mirbf/encoding-processor/converter"
+)
+
+// repairMinImprovement 接受一步修复所需的最小质量改善
+const repairMinImprovement = 0.01
+
+// RepairStep 一步乱码修复：文本是按 ActualEncoding 编码的字节被误按 DecodedAs 解码的结果
+type RepairStep struct {
+	// DecodedAs 误用的解码编码
+	DecodedAs string `json:"decoded_as"`
+
+	// ActualEncoding 字节实际的编码
+	ActualEncoding string `json:"actual_encoding"`
+}
+
+// String 返回修复步骤的说明，如 "UTF-8 decoded as WINDOWS-1252"
+func (s RepairStep) String() string {
+	return s.ActualEncoding + " decoded as " + s.DecodedAs
+}
+
+// RepairResult 乱码修复结果
+type RepairResult struct {
+	// Text 修复后的文本（未修复时为原文本）
+	Text string `json:"text"`
+
+	// Data 修复后的文本按原编码编码的数据（仅 RepairBytes 设置）
+	Data []byte `json:"-"`
+
+	// Steps 依次撤销的误解码步骤（多层乱码时按从外到内的顺序）
+	Steps []RepairStep `json:"steps,omitempty"`
+
+	// ScoreBefore 修复前的乱码得分（越低越好）
+	ScoreBefore float64 `json:"score_before"`
+
+	// ScoreAfter 修复后的乱码得分
+	ScoreAfter float64 `json:"score_after"`
+}
+
+// Repaired 检查是否进行了修复
+func (r *RepairResult) Repaired() bool {
+	return len(r.Steps) > 0
+}
+
+// Description 返回修复链的说明，如 "UTF-8 decoded as WINDOWS-1252 -> UTF-8 decoded as WINDOWS-1252"
+func (r *RepairResult) Description() string {
+	if !r.Repaired() {
+		return "no repair applied"
+	}
+	parts := make([]string, len(r.Steps))
+	for i, step := range r.Steps {
+		parts[i] = step.String()
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// DefaultRepairSteps 返回内置的常见误解码组合（按尝试顺序）
+func DefaultRepairSteps() []RepairStep {
+	return []RepairStep{
+		{DecodedAs: EncodingWindows1252, ActualEncoding: EncodingUTF8},
+		{DecodedAs: EncodingISO88591, ActualEncoding: EncodingUTF8},
+		{DecodedAs: EncodingGBK, ActualEncoding: EncodingUTF8},
+		{DecodedAs: EncodingBIG5, ActualEncoding: EncodingUTF8},
+		{DecodedAs: EncodingShiftJIS, ActualEncoding: EncodingUTF8},
+		{DecodedAs: EncodingEUCKR, ActualEncoding: EncodingUTF8},
+		{DecodedAs: EncodingWindows1251, ActualEncoding: EncodingUTF8},
+		{DecodedAs: EncodingISO88591, ActualEncoding: EncodingGBK},
+		{DecodedAs: EncodingISO88591, ActualEncoding: EncodingBIG5},
+		{DecodedAs: EncodingISO88591, ActualEncoding: EncodingShiftJIS},
+		{DecodedAs: EncodingWindows1252, ActualEncoding: EncodingWindows1251},
+	}
+}
+
+// Repairer 乱码修复器
+//
+// 修复器反复尝试撤销常见的误解码（按误用的编码还原字节，再按实际编码解码），
+// 只有在还原和解码都无损、且乱码特征明显减少时才接受一步修复，支持多层嵌套的乱码。
+type Repairer struct {
+	// Steps 尝试的误解码组合（默认 DefaultRepairSteps）
+	Steps []RepairStep
+
+	// MaxDepth 最多撤销的误解码层数（默认 3）
+	MaxDepth int
+
+	// Scorer 乱码评分器（默认使用内置模式库）
+	Scorer *GarbledScorer
+}
+
+// NewRepairer 创建使用内置误解码组合的乱码修复器
+func NewRepairer() *Repairer {
+	return &Repairer{
+		Steps:    DefaultRepairSteps(),
+		MaxDepth: 3,
+		Scorer:   defaultGarbledScorer,
+	}
+}
+
+// RepairText 使用默认修复器修复乱码文本
+func RepairText(text string) *RepairResult {
+	return NewRepairer().RepairText(text)
+}
+
+// RepairBytes 使用默认修复器修复按 encodingName 编码的乱码数据
+func RepairBytes(data []byte, encodingName string) (*RepairResult, error) {
+	return NewRepairer().RepairBytes(data, encodingName)
+}
+
+// RepairText 检测并修复乱码文本，返回修复后的文本及撤销的误解码链
+//
+// 修复器搜索不超过 MaxDepth 层的全部可逆误解码链，选择乱码程度最低的结果；
+// 多层乱码的中间结果往往比原文本更像乱码，因此不能逐层贪心选择。
+func (r *Repairer) RepairText(text string) *RepairResult {
+	search := &repairSearch{
+		repairer: r,
+		steps:    r.Steps,
+		maxDepth: r.MaxDepth,
+	}
+	if search.steps == nil {
+		search.steps = DefaultRepairSteps()
+	}
+	if search.maxDepth <= 0 {
+		search.maxDepth = 3
+	}
+
+	quality := r.quality(text)
+	search.bestText, search.bestQuality = text, quality
+	if quality > 0 {
+		search.run(text, nil)
+	}
+
+	result := &RepairResult{
+		Text:        text,
+		ScoreBefore: quality,
+		ScoreAfter:  quality,
+	}
+	if search.bestSteps != nil {
+		result.Text = search.bestText
+		result.Steps = search.bestSteps
+		result.ScoreAfter = search.bestQuality
+	}
+	return result
+}
+
+// repairSearch 误解码链的深度优先搜索状态
+type repairSearch struct {
+	repairer *Repairer
+	steps    []RepairStep
+	maxDepth int
+
+	bestText    string
+	bestSteps   []RepairStep
+	bestQuality float64
+}
+
+// run 从 text 出发继续撤销误解码，记录乱码程度最低的结果（程度相近时取更短的链）
+func (s *repairSearch) run(text string, chain []RepairStep) {
+	if len(chain) >= s.maxDepth || s.bestQuality <= 0 {
+		return
+	}
+	for _, step := range s.steps {
+		candidate, ok := undoDecoding(text, step)
+		if !ok || candidate == text {
+			continue
+		}
+		next := append(append([]RepairStep(nil), chain...), step)
+		if q := s.repairer.quality(candidate); q < s.bestQuality-repairMinImprovement ||
+			(q < s.bestQuality && len(next) <= len(s.bestSteps)) {
+			s.bestText, s.bestSteps, s.bestQuality = candidate, next, q
+		}
+		s.run(candidate, next)
+	}
+}
+
+// RepairBytes 按 encodingName 解码数据后修复乱码，Data 为修复后的文本按原编码编码的结果
+func (r *Repairer) RepairBytes(data []byte, encodingName string) (*RepairResult, error) {
+	encodingName = canonicalEncodingName(encodingName)
+	text, err := NewConverter().Convert(data, encodingName, EncodingUTF8)
+	if err != nil {
+		return nil, err
+	}
+
+	result := r.RepairText(string(text))
+	if !result.Repaired() {
+		result.Data = data
+		return result, nil
+	}
+
+	enc, err := converter.Lookup(encodingName)
+	if err != nil {
+		return nil, &EncodingError{Op: OperationConvert, Encoding: encodingName, Err: err}
+	}
+	result.Data, err = enc.NewEncoder().Bytes([]byte(result.Text))
+	if err != nil {
+		return nil, &EncodingError{Op: OperationConvert, Encoding: encodingName, Err: err}
+	}
+	return result, nil
+}
+
+// undoDecoding 撤销一步误解码：按 DecodedAs 无损还原字节，再按 ActualEncoding 无损解码
+func undoDecoding(text string, step RepairStep) (string, bool) {
+	wrong, err := converter.Lookup(step.DecodedAs)
+	if err != nil {
+		return "", false
+	}
+	raw, err := wrong.NewEncoder().String(text)
+	if err != nil {
+		return "", false
+	}
+
+	if step.ActualEncoding == EncodingUTF8 {
+		return raw, utf8.ValidString(raw)
+	}
+	actual, err := converter.Lookup(step.ActualEncoding)
+	if err != nil {
+		return "", false
+	}
+	decoded, err := actual.NewDecoder().String(raw)
+	if err != nil || strings.ContainsRune(decoded, utf8.RuneError) {
+		return "", false
+	}
+	return decoded, true
+}
+
+// quality 返回文本的乱码程度（乱码特征得分加可疑字符比例，越低越好）
+func (r *Repairer) quality(text string) float64 {
+	scorer := r.Scorer
+	if scorer == nil {
+		scorer = defaultGarbledScorer
+	}
+
+	runes := []rune(text)
+	if len(runes) == 0 {
+		return 0
+	}
+	suspicious := 0
+	for i, c := range runes {
+		if isSuspiciousRune(runes, i, c) {
+			suspicious++
+		}
+	}
+	return scorer.Score(text) + float64(suspicious)/float64(len(runes))
+}
+
+// isSuspiciousRune 检查字符是否常见于乱码：替换字符、C1 控制字符、私用区字符、半角片假名、
+// 非常用汉字、连续三个以上的西欧扩展字符，以及与拉丁字母或西欧符号相邻的西里尔字母
+func isSuspiciousRune(runes []rune, i int, r rune) bool {
+	switch {
+	case r == utf8.RuneError, r >= 0x80 && r <= 0x9F, r >= 0xE000 && r <= 0xF8FF, r >= 0xFF61 && r <= 0xFF9F:
+		return true
+	case unicode.Is(unicode.Han, r):
+		return !isCommonHanzi(r)
+	case isLatinExtended(r):
+		run := 1
+		for j := i - 1; j >= 0 && isLatinExtended(runes[j]); j-- {
+			run++
+		}
+		for j := i + 1; j < len(runes) && isLatinExtended(runes[j]); j++ {
+			run++
+		}
+		return run >= 3
+	case unicode.Is(unicode.Cyrillic, r):
+		return (i > 0 && isForeignToCyrillic(runes[i-1])) || (i+1 < len(runes) && isForeignToCyrillic(runes[i+1]))
+	}
+	return false
+}
+
+// isLatinExtended 检查字符是否为单字节编码误解码常见的西欧扩展字符或符号
+func isLatinExtended(r rune) bool {
+	return (r >= 0xA0 && r <= 0x24F) || strings.ContainsRune("€‚ƒ„…†‡ˆ‰‹Œ‘’“”•–—˜™›œŸ", r)
+}
+
+// isForeignToCyrillic 检查字符是否为正常西里尔文本中不会紧邻字母出现的拉丁字母或西欧符号
+func isForeignToCyrillic(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || isLatinExtended(r)
+}
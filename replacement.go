@@ -0,0 +1,85 @@
+package encoding
+
+import "fmt"
+
+// Replacement 非严格模式转换中丢失的一处源数据（无法解码的字节序列，或目标编码无法表示、
+// 按 InvalidCharReplacement、UnmappableAction 或 SubstitutionCallback 处理的字符）
+type Replacement struct {
+	// ByteOffset 在源数据中的字节偏移（从 0 开始）
+	ByteOffset int64 `json:"byte_offset"`
+
+	// Line 行号（从 1 开始）
+	Line int `json:"line"`
+
+	// Column 列号（从 1 开始，按字符计）
+	Column int `json:"column"`
+
+	// Bytes 源字节序列
+	Bytes []byte `json:"bytes"`
+
+	// Rune 目标编码无法表示的字符（无效字节序列为 U+FFFD）
+	Rune rune `json:"rune"`
+
+	// Invalid 是否为源数据中无法解码的字节序列
+	Invalid bool `json:"invalid"`
+}
+
+// ReplacementReport 转换中的替换统计
+type ReplacementReport struct {
+	// Count 替换总数（无效字节序列与无法表示的字符）
+	Count int64 `json:"count"`
+
+	// Offsets 前 MaxReplacementOffsets 处替换在源数据中的字节偏移
+	Offsets []int64 `json:"offsets,omitempty"`
+}
+
+// maxReplacementOffsets 返回替换统计记录的最大偏移数量（负数表示不记录）
+func (c *defaultConverter) maxReplacementOffsets() int {
+	if c.config.MaxReplacementOffsets == 0 {
+		return DefaultMaxReplacementOffsets
+	}
+	return c.config.MaxReplacementOffsets
+}
+
+// reportReplacements 转换中发生替换时定位各处替换，记录到 trace 并调用 ReplacementHook
+//
+// data 为去除 BOM 后的源数据，offset 为其在原始数据中的起始偏移；回调返回错误时返回带位置的 EncodingError。
+func (c *defaultConverter) reportReplacements(trace *conversionTrace, data []byte, offset int, from, to string) error {
+	if trace == nil || trace.errors() == 0 {
+		return nil
+	}
+
+	from, to = c.resolveName(from), c.resolveName(to)
+	if to == EncodingUTF8BOM {
+		to = EncodingUTF8
+	}
+	report := &ReplacementReport{Count: trace.errors()}
+	limit := c.maxReplacementOffsets()
+	hook := c.config.ReplacementHook
+
+	var hookErr error
+	c.scanConversion(data, from, to, func(replacement Replacement, _ bool) bool {
+		replacement.ByteOffset += int64(offset)
+		if len(report.Offsets) < limit {
+			report.Offsets = append(report.Offsets, replacement.ByteOffset)
+		}
+		if hook != nil {
+			if err := hook(replacement); err != nil {
+				hookErr = &EncodingError{
+					Op:         OperationConvert,
+					Encoding:   fmt.Sprintf("%s->%s", from, to),
+					Err:        err,
+					ByteOffset: replacement.ByteOffset,
+					Line:       replacement.Line,
+					Column:     replacement.Column,
+					Bytes:      replacement.Bytes,
+				}
+				return false
+			}
+		}
+		return hook != nil || len(report.Offsets) < limit
+	})
+
+	trace.replacements = report
+	return hookErr
+}
@@ -0,0 +1,127 @@
+package encoding
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DetectionRule 声明式检测覆盖规则
+//
+// 规则在统计检测之前按顺序求值，第一条匹配的规则直接决定编码。
+// PathPattern 和 Prefix 均设置时需同时满足；两者均未设置的规则不会生效。
+type DetectionRule struct {
+	// Name 规则名称（记录在检测结果详情中）
+	Name string `json:"name"`
+
+	// PathPattern 文件路径匹配模式（filepath.Match 语法，不区分大小写）；
+	// 不含路径分隔符时匹配文件名，否则匹配完整路径
+	PathPattern string `json:"path_pattern,omitempty"`
+
+	// Prefix 数据前缀（数据以该字节序列开头时匹配）
+	Prefix []byte `json:"prefix,omitempty"`
+
+	// Encoding 匹配时使用的编码
+	Encoding string `json:"encoding"`
+
+	// Confidence 匹配时报告的置信度（默认 1.0）
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// Matches 检查规则是否匹配指定路径和数据（path 为空时带路径条件的规则不匹配）
+func (r *DetectionRule) Matches(path string, data []byte) bool {
+	if r.Encoding == "" || (r.PathPattern == "" && len(r.Prefix) == 0) {
+		return false
+	}
+
+	if r.PathPattern != "" {
+		if path == "" || !matchPathPattern(r.PathPattern, path) {
+			return false
+		}
+	}
+
+	if len(r.Prefix) > 0 && !bytes.HasPrefix(data, r.Prefix) {
+		return false
+	}
+
+	return true
+}
+
+// matchPathPattern 不区分大小写地匹配文件名或完整路径
+func matchPathPattern(pattern, path string) bool {
+	pattern = strings.ToLower(filepath.ToSlash(pattern))
+	path = strings.ToLower(filepath.ToSlash(path))
+
+	target := path
+	if !strings.Contains(pattern, "/") {
+		target = filepath.Base(filepath.FromSlash(path))
+		target = strings.ToLower(filepath.ToSlash(target))
+	}
+
+	matched, err := filepath.Match(pattern, target)
+	return err == nil && matched
+}
+
+// applyRules 按顺序求值检测覆盖规则，返回第一条匹配规则的结果
+func (d *defaultDetector) applyRules(path string, data []byte) *DetectionResult {
+	for i := range d.config.Rules {
+		rule := &d.config.Rules[i]
+		if !rule.Matches(path, data) {
+			continue
+		}
+
+		confidence := rule.Confidence
+		if confidence <= 0 {
+			confidence = 1.0
+		}
+		return &DetectionResult{
+			Encoding:   rule.Encoding,
+			Confidence: confidence,
+			Details: &DetectionDetails{
+				Method: MethodRule,
+				Rule:   rule.Name,
+			},
+		}
+	}
+	return nil
+}
+
+// DetectEncodingWithPath 结合文件路径检测数据编码（路径仅用于匹配检测覆盖规则）
+func (d *defaultDetector) DetectEncodingWithPath(path string, data []byte) (*DetectionResult, error) {
+	if len(data) == 0 {
+		return nil, &EncodingError{
+			Op:  OperationDetect,
+			Err: ErrInvalidInput,
+		}
+	}
+
+	start := time.Now()
+	if result := d.applyRules(path, data); result != nil {
+		d.recordDetection(start, result, nil, nil)
+		return result, nil
+	}
+	result, err := d.detectEncoding(data)
+	d.recordDetection(start, result, err, nil)
+	d.sampleFailure(path, data, result, err)
+	return result, err
+}
+
+// SmartDetectEncodingWithPath 结合文件路径智能检测数据编码（路径仅用于匹配检测覆盖规则）
+func (d *defaultDetector) SmartDetectEncodingWithPath(path string, data []byte) (*DetectionResult, error) {
+	start := time.Now()
+	result, err := d.smartDetectEncoding(path, data)
+	d.recordDetection(start, result, err, nil)
+	d.sampleFailure(path, data, result, err)
+	return result, err
+}
+
+// pathDetector 支持结合文件路径检测编码的检测器
+type pathDetector interface {
+	DetectEncodingWithPath(path string, data []byte) (*DetectionResult, error)
+}
+
+// smartPathDetector 支持结合文件路径智能检测编码的检测器
+type smartPathDetector interface {
+	SmartDetectEncodingWithPath(path string, data []byte) (*DetectionResult, error)
+}
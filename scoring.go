@@ -0,0 +1,210 @@
+package encoding
+
+import (
+	"maps"
+	"unicode"
+)
+
+// LanguageModel 候选编码评分使用的语言模型
+//
+// 模型得分 = 文字占比 * (1 - FrequencyWeight) + 频率加权覆盖率 * FrequencyWeight。
+// 文字占比为属于 Scripts 的字符（文本包含 Scripts 的字符时也包括 SharedScripts 的字符）在全部字符中的比例；频率加权覆盖率为各字符（按小写）在
+// Frequencies 中的频率除以最高频率后的平均值，未收录的字符计为 0。
+type LanguageModel struct {
+	// Name 模型名称（如 zh、ja、cyrillic）
+	Name string
+
+	// Scripts 该语言使用的文字，同时视为字符有效性评分中的有效字符
+	Scripts []*unicode.RangeTable
+
+	// SharedScripts 与其他语言共用的文字（如日文中的汉字），仅当文本包含 Scripts 的字符时计入文字占比
+	SharedScripts []*unicode.RangeTable
+
+	// Frequencies 常用字符及其相对频率
+	Frequencies map[rune]float64
+
+	// FrequencyWeight 频率加权覆盖率在模型得分中的权重（0-1）
+	FrequencyWeight float64
+
+	// Encodings 该语言的常用编码，这些候选编码会被解码后评分
+	Encodings []string
+}
+
+// 内置模型名称
+const (
+	ModelCyrillic        = "cyrillic"
+	ModelWesternEuropean = "western_european"
+)
+
+// cjkUnifiedIdeographs CJK 统一表意文字基本区（U+4E00-U+9FFF）
+var cjkUnifiedIdeographs = &unicode.RangeTable{
+	R16: []unicode.Range16{{Lo: 0x4e00, Hi: 0x9fff, Stride: 1}},
+}
+
+// fullwidthKana 平假名和全角片假名（U+3040-U+30FF，不含半角片假名：
+// 中文编码的字节按 Shift_JIS 解码时常得到半角片假名）
+var fullwidthKana = &unicode.RangeTable{
+	R16: []unicode.Range16{{Lo: 0x3040, Hi: 0x30ff, Stride: 1}},
+}
+
+// defaultScoringConfig 未配置 DetectorConfig.Scoring 时使用的评分配置
+var defaultScoringConfig = GetDefaultScoringConfig()
+
+// ChineseLanguageModel 返回中文模型（汉字占比及最常用的 20 个汉字）
+func ChineseLanguageModel() *LanguageModel {
+	frequencies := make(map[rune]float64)
+	for _, r := range "的一是在不了有和人这中大为上个文件作者时" {
+		frequencies[r] = 1
+	}
+	return &LanguageModel{
+		Name:            LanguageChinese,
+		Scripts:         []*unicode.RangeTable{cjkUnifiedIdeographs},
+		Frequencies:     frequencies,
+		FrequencyWeight: 0.3,
+		Encodings:       []string{EncodingGBK, EncodingGB2312, EncodingGB18030, EncodingBIG5},
+	}
+}
+
+// JapaneseLanguageModel 返回日文模型（假名与汉字占比及常用假名频率）
+//
+// 汉字只在文本包含假名时计入文字占比，以免按日文编码误解码的中文文本得分。
+func JapaneseLanguageModel() *LanguageModel {
+	return &LanguageModel{
+		Name:          LanguageJapanese,
+		Scripts:       []*unicode.RangeTable{fullwidthKana},
+		SharedScripts: []*unicode.RangeTable{cjkUnifiedIdeographs},
+		Frequencies: map[rune]float64{
+			'の': 4.5, 'い': 3.9, 'に': 3.3, 'た': 3.2, 'る': 3.0, 'と': 2.8, 'て': 2.8, 'し': 2.7,
+			'は': 2.6, 'か': 2.4, 'な': 2.3, 'を': 2.1, 'が': 2.0, 'で': 1.9, 'ま': 1.8, 'す': 1.8,
+			'う': 1.7, 'こ': 1.4, 'れ': 1.4, 'ら': 1.2, 'も': 1.2, 'っ': 1.1, 'り': 1.0, 'ン': 1.0,
+			'あ': 0.9, 'ー': 0.9, 'さ': 0.9, 'だ': 0.8, 'き': 0.8, 'く': 0.8, 'ス': 0.6, 'ル': 0.5,
+		},
+		FrequencyWeight: 0.3,
+		Encodings:       []string{EncodingShiftJIS, EncodingEUCJP},
+	}
+}
+
+// KoreanLanguageModel 返回韩文模型（谚文占比及常用音节频率）
+func KoreanLanguageModel() *LanguageModel {
+	return &LanguageModel{
+		Name:    LanguageKorean,
+		Scripts: []*unicode.RangeTable{unicode.Hangul},
+		Frequencies: map[rune]float64{
+			'이': 3.5, '다': 3.3, '는': 2.6, '의': 2.2, '에': 2.2, '하': 2.1, '을': 1.9, '고': 1.8,
+			'가': 1.7, '지': 1.6, '기': 1.5, '한': 1.4, '서': 1.3, '로': 1.3, '사': 1.2, '리': 1.1,
+			'으': 1.1, '자': 1.0, '도': 1.0, '들': 0.9, '나': 0.9, '대': 0.9, '시': 0.9, '어': 0.8,
+			'를': 0.8, '수': 0.8, '인': 0.8, '해': 0.7, '정': 0.7, '아': 0.7, '게': 0.6, '적': 0.6,
+		},
+		FrequencyWeight: 0.3,
+		Encodings:       []string{EncodingEUCKR},
+	}
+}
+
+// CyrillicLanguageModel 返回西里尔文模型（按俄文字母频率）
+func CyrillicLanguageModel() *LanguageModel {
+	return &LanguageModel{
+		Name:            ModelCyrillic,
+		Scripts:         []*unicode.RangeTable{unicode.Cyrillic},
+		Frequencies:     maps.Clone(languageModels[LanguageRussian].frequencies),
+		FrequencyWeight: 0.3,
+		Encodings: []string{
+			EncodingWindows1251, EncodingKOI8R, EncodingKOI8U, EncodingISO88595, EncodingCP866, EncodingMacCyrillic,
+		},
+	}
+}
+
+// WesternEuropeanLanguageModel 返回西欧语言模型（英文、德文、法文、西班牙文字母频率的平均值）
+func WesternEuropeanLanguageModel() *LanguageModel {
+	languages := []string{LanguageEnglish, LanguageGerman, LanguageFrench, LanguageSpanish}
+	frequencies := make(map[rune]float64)
+	for _, lang := range languages {
+		for r, freq := range languageModels[lang].frequencies {
+			frequencies[r] += freq / float64(len(languages))
+		}
+	}
+	return &LanguageModel{
+		Name:            ModelWesternEuropean,
+		Scripts:         []*unicode.RangeTable{unicode.Latin},
+		Frequencies:     frequencies,
+		FrequencyWeight: 0.3,
+		Encodings: []string{
+			EncodingWindows1252, EncodingISO88591, EncodingISO885915, EncodingCP850, EncodingMacintosh,
+		},
+	}
+}
+
+// Score 返回文本符合该语言模型的程度（0-1）
+func (m *LanguageModel) Score(text string) float64 {
+	var maxFrequency float64
+	for _, freq := range m.Frequencies {
+		maxFrequency = max(maxFrequency, freq)
+	}
+
+	var total, inScript, inShared, coverage float64
+	for _, r := range text {
+		total++
+		switch {
+		case m.inScripts(r):
+			inScript++
+		case unicode.IsOneOf(m.SharedScripts, r):
+			inShared++
+		}
+		if maxFrequency > 0 {
+			coverage += m.Frequencies[unicode.ToLower(r)] / maxFrequency
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	if inScript > 0 {
+		inScript += inShared
+	}
+	return inScript/total*(1-m.FrequencyWeight) + coverage/total*m.FrequencyWeight
+}
+
+// inScripts 检查字符是否属于模型的文字
+func (m *LanguageModel) inScripts(r rune) bool {
+	return unicode.IsOneOf(m.Scripts, r)
+}
+
+// models 返回参与评分的语言模型
+func (c *ScoringConfig) models() []*LanguageModel {
+	if len(c.Models) == 0 {
+		return defaultScoringConfig.Models
+	}
+	return c.Models
+}
+
+// scriptScore 返回文本在各语言模型中的最高得分
+func (c *ScoringConfig) scriptScore(text string) float64 {
+	if text == "" {
+		return 0
+	}
+	var best float64
+	for _, model := range c.models() {
+		best = max(best, model.Score(text))
+	}
+	return best
+}
+
+// inScripts 检查字符是否属于任一语言模型的文字
+func (c *ScoringConfig) inScripts(r rune) bool {
+	for _, model := range c.models() {
+		if model.inScripts(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodes 检查编码是否是任一语言模型的常用编码
+func (c *ScoringConfig) decodes(encodingName string) bool {
+	for _, model := range c.models() {
+		for _, name := range model.Encodings {
+			if name == encodingName {
+				return true
+			}
+		}
+	}
+	return false
+}
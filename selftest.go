@@ -0,0 +1,189 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// selfTestThroughputSize 测量转换吞吐量时每个样本重复到的数据大小
+const selfTestThroughputSize = 64 * 1024
+
+// selfTestSample 自检语料中的样本：Hex 为 Text 按 Encoding 编码的字节（与本库的转换结果无关）
+type selfTestSample struct {
+	Encoding string
+	Text     string
+	Hex      string
+}
+
+// selfTestCorpus 内置的自检语料（UTF-16 样本带 BOM，用于检查字节序处理）
+var selfTestCorpus = []selfTestSample{
+	{EncodingUTF8, "编码自检：中文、English、日本語。", "e7bc96e7a081e887aae6a380efbc9ae4b8ade69687e38081456e676c697368e38081e697a5e69cace8aa9ee38082"},
+	{EncodingGBK, "这是一个用于编码自检的简体中文样本，包含常见的汉字和标点。", "d5e2cac7d2bbb8f6d3c3d3dab1e0c2ebd7d4bcecb5c4bcf2cce5d6d0cec4d1f9b1bea3acb0fcbaacb3a3bcfbb5c4babad7d6bacdb1eab5e3a1a3"},
+	{EncodingBIG5, "這是一個用於編碼自檢的繁體中文樣本，內有常見的漢字和標點。", "b36fac4fa440add3a5cea9f3bd73bd58a6dbc0cbaabac163c5e9a4a4a4e5bccba5bba141a4baa6b3b160a8a3aababa7ea672a94dbcd0c249a143"},
+	{EncodingShiftJIS, "これは文字コードの自己診断に使う日本語のサンプルです。", "82b182ea82cd95b68e9a8352815b836882cc8ea98cc89066926682c98e6782a493fa967b8cea82cc835483938376838b82c582b78142"},
+	{EncodingEUCKR, "이것은 인코딩 자체 진단을 위한 한국어 샘플입니다.", "c0ccb0cdc0ba20c0cec4dab5f920c0dac3bc20c1f8b4dcc0bb20c0a7c7d120c7d1b1b9beee20bbf9c7c3c0d4b4cfb4d92e"},
+	{EncodingWindows1251, "Это русский образец текста для самопроверки кодировки.", "ddf2ee20f0f3f1f1eae8e920eee1f0e0e7e5f620f2e5eaf1f2e020e4ebff20f1e0eceeeff0eee2e5f0eae820eaeee4e8f0eee2eae82e"},
+	{EncodingISO88591, "Voilà un échantillon français pour l'autotest, très complet.", "566f696ce020756e20e96368616e74696c6c6f6e206672616ee761697320706f7572206c276175746f746573742c207472e87320636f6d706c65742e"},
+	{EncodingUTF16LE, "编码自检：字节序", "fffe167f0178ea81c0681aff575b82828f5e"},
+	{EncodingUTF16BE, "编码自检：字节序", "feff7f16780181ea68c0ff1a5b5782825e8f"},
+}
+
+// SelfTestResult 单个自检样本的结果
+type SelfTestResult struct {
+	// Encoding 样本的实际编码
+	Encoding string `json:"encoding"`
+
+	// Detected 检测到的编码（检测失败时为空）
+	Detected string `json:"detected,omitempty"`
+
+	// Confidence 检测置信度
+	Confidence float64 `json:"confidence"`
+
+	// DetectionCorrect 按检测到的编码解码是否得到原文（兼容的超集编码也视为正确）
+	DetectionCorrect bool `json:"detection_correct"`
+
+	// DecodeCorrect 按实际编码解码是否得到原文
+	DecodeCorrect bool `json:"decode_correct"`
+
+	// EncodeCorrect 原文按实际编码编码是否得到原始字节
+	EncodeCorrect bool `json:"encode_correct"`
+
+	// Error 检测或转换的错误信息
+	Error string `json:"error,omitempty"`
+}
+
+// SelfTestReport 自检报告
+type SelfTestReport struct {
+	// Platform 运行平台（GOOS/GOARCH）
+	Platform string `json:"platform"`
+
+	// Results 各样本的结果
+	Results []SelfTestResult `json:"results"`
+
+	// DetectionAccuracy 检测准确率
+	DetectionAccuracy float64 `json:"detection_accuracy"`
+
+	// ConversionAccuracy 转换（解码和编码）准确率
+	ConversionAccuracy float64 `json:"conversion_accuracy"`
+
+	// DetectionThroughput 检测吞吐量（字节/秒）
+	DetectionThroughput float64 `json:"detection_throughput"`
+
+	// ConversionThroughput 转换为 UTF-8 的吞吐量（字节/秒）
+	ConversionThroughput float64 `json:"conversion_throughput"`
+
+	// Duration 自检总耗时
+	Duration time.Duration `json:"duration"`
+}
+
+// Passed 检查全部样本是否转换正确
+//
+// 检测基于统计，短样本可能检测为其他编码，因此检测准确率仅供参考，不影响是否通过。
+func (r *SelfTestReport) Passed() bool {
+	return r.ConversionAccuracy == 1
+}
+
+// SelfTest 使用默认处理器对内置语料运行检测和转换，报告准确率和吞吐量
+//
+// 用于部署时确认本库在当前平台（字节序、区域设置等）上的行为符合预期。
+// 任一样本转换结果与已知字节不一致时同时返回报告和 ErrSelfTestFailed。
+func SelfTest() (*SelfTestReport, error) {
+	processor := NewDefault()
+	defer processor.(Drainer).Close()
+	return selfTest(processor)
+}
+
+// selfTest 使用指定处理器运行自检
+func selfTest(p Processor) (*SelfTestReport, error) {
+	start := time.Now()
+	report := &SelfTestReport{
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		Results:  make([]SelfTestResult, 0, len(selfTestCorpus)),
+	}
+
+	var detected, converted int
+	var detectBytes, convertBytes int64
+	var detectTime, convertTime time.Duration
+	var failures []string
+	for _, sample := range selfTestCorpus {
+		data, err := hex.DecodeString(sample.Hex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid self-test sample %s: %w", sample.Encoding, err)
+		}
+		result := SelfTestResult{Encoding: sample.Encoding}
+
+		// 检测
+		detectStart := time.Now()
+		detection, err := p.SmartDetectEncoding(data)
+		detectTime += time.Since(detectStart)
+		detectBytes += int64(len(data))
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Detected, result.Confidence = detection.Encoding, detection.Confidence
+			decoded, err := p.Convert(data, detection.Encoding, EncodingUTF8)
+			result.DetectionCorrect = err == nil && selfTestText(decoded) == sample.Text
+		}
+
+		// 解码和编码
+		decoded, err := p.Convert(data, sample.Encoding, EncodingUTF8)
+		result.DecodeCorrect = err == nil && selfTestText(decoded) == sample.Text
+		encoded, err := p.Convert([]byte(sample.Text), EncodingUTF8, sample.Encoding)
+		result.EncodeCorrect = err == nil && bytes.Equal(selfTestBody(encoded, sample.Encoding), selfTestBody(data, sample.Encoding))
+
+		// 吞吐量
+		repeated := bytes.Repeat(data, selfTestThroughputSize/len(data)+1)
+		convertStart := time.Now()
+		if _, err := p.Convert(repeated, sample.Encoding, EncodingUTF8); err == nil {
+			convertTime += time.Since(convertStart)
+			convertBytes += int64(len(repeated))
+		}
+
+		if result.DetectionCorrect {
+			detected++
+		}
+		if result.DecodeCorrect && result.EncodeCorrect {
+			converted++
+		} else {
+			failures = append(failures, sample.Encoding)
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	total := float64(len(selfTestCorpus))
+	report.DetectionAccuracy = float64(detected) / total
+	report.ConversionAccuracy = float64(converted) / total
+	report.DetectionThroughput = throughput(detectBytes, detectTime)
+	report.ConversionThroughput = throughput(convertBytes, convertTime)
+	report.Duration = time.Since(start)
+
+	if len(failures) > 0 {
+		return report, fmt.Errorf("%w: conversion mismatch for %s", ErrSelfTestFailed, strings.Join(failures, ", "))
+	}
+	return report, nil
+}
+
+// selfTestText 去除解码结果开头的 U+FEFF 后返回文本
+func selfTestText(decoded []byte) string {
+	return string(bytes.TrimPrefix(decoded, utf8BOM))
+}
+
+// selfTestBody 去除编码数据开头的 BOM（转换器是否写入 BOM 由配置决定，不作为比较内容）
+func selfTestBody(data []byte, encodingName string) []byte {
+	if bom := encodedBOM(encodingName); bom != nil {
+		return bytes.TrimPrefix(data, bom)
+	}
+	return data
+}
+
+// throughput 计算吞吐量（字节/秒）
+func throughput(n int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(n) / elapsed.Seconds()
+}
@@ -0,0 +1,89 @@
+package encoding
+
+import "path/filepath"
+
+// siblingContext 按目录统计已可靠检测的文件编码分布
+//
+// 实际的文件归档通常在目录内保持编码一致，同目录兄弟文件的主导编码可以帮助
+// 判定样本太小而置信度不足的文件。
+type siblingContext struct {
+	counts map[string]map[string]int
+}
+
+// newSiblingContext 创建空的兄弟文件编码统计
+func newSiblingContext() *siblingContext {
+	return &siblingContext{counts: make(map[string]map[string]int)}
+}
+
+// observe 记录一个可靠检测的文件编码
+func (s *siblingContext) observe(path, encoding string) {
+	dir := filepath.Dir(path)
+	if s.counts[dir] == nil {
+		s.counts[dir] = make(map[string]int)
+	}
+	s.counts[dir][encoding]++
+}
+
+// dominant 返回文件所在目录的主导编码及其占比（没有记录时返回空字符串）
+func (s *siblingContext) dominant(path string) (string, float64) {
+	var best string
+	var bestCount, total int
+	for encoding, count := range s.counts[filepath.Dir(path)] {
+		total += count
+		if count > bestCount || (count == bestCount && encoding < best) {
+			best, bestCount = encoding, count
+		}
+	}
+	if total == 0 {
+		return "", 0
+	}
+	return best, float64(bestCount) / float64(total)
+}
+
+// boostWithContext 按兄弟文件的主导编码提升低置信度检测结果
+//
+// 提升后的置信度为主导编码原有置信度（检测结果或可解码候选中的最高值）加上 weight * 占比，
+// 主导编码无法解码数据或检测器不支持 CandidateDetector 时不提升。返回的结果 Method 为 MethodContext。
+func boostWithContext(d Detector, data []byte, detection *DetectionResult, dominant string, share, weight float64) (*DetectionResult, bool) {
+	if dominant == "" || weight <= 0 {
+		return nil, false
+	}
+
+	base, found := 0.0, false
+	if detection != nil && detection.Encoding == dominant {
+		base, found = detection.Confidence, true
+	}
+	if !found {
+		cd, ok := d.(CandidateDetector)
+		if !ok {
+			return nil, false
+		}
+		candidates, err := cd.DetectAllEncodings(data)
+		if err != nil {
+			return nil, false
+		}
+		for _, candidate := range candidates {
+			if candidate.Encoding == dominant && !candidate.Score.ConversionFailed && (!found || candidate.Confidence > base) {
+				base, found = candidate.Confidence, true
+			}
+		}
+	}
+	if !found {
+		return nil, false
+	}
+
+	confidence := base + weight*share
+	if confidence > 1.0 {
+		confidence = 1.0
+	}
+
+	details := &DetectionDetails{Method: MethodContext}
+	if detection != nil && detection.Details != nil {
+		details.SourceMethod = detection.Details.Method
+	}
+	return &DetectionResult{
+		Encoding:   dominant,
+		Confidence: confidence,
+		Details:    details,
+	}, true
+}
@@ -0,0 +1,154 @@
+package encoding
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// SidecarMetadata 转换文件旁路元数据（.encmeta.json）
+type SidecarMetadata struct {
+	// File 转换后的文件路径
+	File string `json:"file"`
+
+	// OriginalEncoding 原始编码
+	OriginalEncoding string `json:"original_encoding"`
+
+	// TargetEncoding 转换后的编码
+	TargetEncoding string `json:"target_encoding"`
+
+	// Confidence 原始编码检测置信度
+	Confidence float64 `json:"confidence"`
+
+	// OriginalSHA256 原始内容的 SHA-256 校验和
+	OriginalSHA256 string `json:"original_sha256"`
+
+	// ConvertedSHA256 转换后内容的 SHA-256 校验和
+	ConvertedSHA256 string `json:"converted_sha256"`
+
+	// OriginalSize 原始内容大小
+	OriginalSize int64 `json:"original_size"`
+
+	// ConvertedSize 转换后内容大小
+	ConvertedSize int64 `json:"converted_size"`
+
+	// BackupFile 备份文件路径（如果有）
+	BackupFile string `json:"backup_file,omitempty"`
+
+	// ToolVersion 执行转换的库版本
+	ToolVersion string `json:"tool_version"`
+
+	// ConvertedAt 转换时间
+	ConvertedAt time.Time `json:"converted_at"`
+}
+
+// SidecarPath 返回文件对应的元数据旁路文件路径
+func SidecarPath(file string) string {
+	return file + DefaultSidecarSuffix
+}
+
+// ReadSidecar 读取文件对应的元数据旁路文件
+func ReadSidecar(file string) (*SidecarMetadata, error) {
+	path := SidecarPath(file)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, &FileOperationError{Op: "read_sidecar", File: path, Err: err}
+	}
+
+	var meta SidecarMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, &FileOperationError{Op: "parse_sidecar", File: path, Err: err}
+	}
+	return &meta, nil
+}
+
+// RevertFromSidecar 依据旁路元数据将文件转换回原始编码
+//
+// 仅当文件内容与记录的转换后校验和一致时才会执行；通过临时文件原子替换并保留文件权限，成功后删除旁路文件。
+func RevertFromSidecar(file string) error {
+	meta, err := ReadSidecar(file)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return &FileOperationError{Op: "read", File: file, Err: err}
+	}
+
+	if checksum(data) != meta.ConvertedSHA256 {
+		return &FileOperationError{
+			Op:   "revert",
+			File: file,
+			Err:  fmt.Errorf("file content changed since conversion"),
+		}
+	}
+
+	original, err := NewConverter().Convert(data, meta.TargetEncoding, meta.OriginalEncoding)
+	if err != nil {
+		return err
+	}
+
+	if checksum(original) != meta.OriginalSHA256 {
+		return &EncodingError{
+			Op:       OperationConvert,
+			Encoding: meta.OriginalEncoding,
+			File:     file,
+			Err:      fmt.Errorf("%w: reverted content does not match original checksum", ErrConversionFailed),
+		}
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return &FileOperationError{Op: "stat", File: file, Err: err}
+	}
+	config := GetDefaultProcessorConfig()
+	fp := &defaultFileProcessor{config: config, logger: newLogger(config)}
+	if _, err := fp.writeFileWithRecovery(file, original, info, &FileProcessOptions{PreserveMode: true}, ""); err != nil {
+		return err
+	}
+
+	if err := os.Remove(SidecarPath(file)); err != nil && !os.IsNotExist(err) {
+		return &FileOperationError{Op: "remove_sidecar", File: SidecarPath(file), Err: err}
+	}
+	return nil
+}
+
+// writeSidecar 在输出文件旁写入元数据旁路文件
+func writeSidecar(outputFile string, original, converted []byte, detection *DetectionResult, target, backupFile string) (string, error) {
+	return saveSidecar(&SidecarMetadata{
+		File:             outputFile,
+		OriginalEncoding: detection.Encoding,
+		TargetEncoding:   target,
+		Confidence:       detection.Confidence,
+		OriginalSHA256:   checksum(original),
+		ConvertedSHA256:  checksum(converted),
+		OriginalSize:     int64(len(original)),
+		ConvertedSize:    int64(len(converted)),
+		BackupFile:       backupFile,
+		ToolVersion:      Version,
+		ConvertedAt:      time.Now(),
+	})
+}
+
+// saveSidecar 将元数据写入输出文件旁的旁路文件
+func saveSidecar(meta *SidecarMetadata) (string, error) {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	path := SidecarPath(meta.File)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return "", &FileOperationError{Op: "write_sidecar", File: path, Err: err}
+	}
+	return path, nil
+}
+
+// checksum 计算数据的 SHA-256 十六进制校验和
+func checksum(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
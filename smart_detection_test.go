@@ -2,8 +2,13 @@ package encoding
 
 import (
 	"archive/zip"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
+	"unicode"
 )
 
 // TestSmartDetectionZipFile 测试ZIP文件名编码检测
@@ -38,7 +43,7 @@ func TestSmartDetectionZipFile(t *testing.T) {
 	t.Logf("检测结果: %s, 置信度: %.2f", result.Encoding, result.Confidence)
 	
 	// 验证转换结果
-	if convertedText, ok := result.Details["converted_text"].(string); ok {
+	if convertedText := result.Details.ConvertedPreview; convertedText != "" {
 		t.Logf("转换结果: %s", convertedText)
 		
 		// 检查是否包含正确的中文字符
@@ -167,4 +172,687 @@ func BenchmarkSmartDetection(b *testing.B) {
 			b.Fatalf("检测失败: %v", err)
 		}
 	}
-}
\ No newline at end of file
+}
+// TestEnsembleVotes 测试集成投票在 Details 中暴露各方法投票
+func TestEnsembleVotes(t *testing.T) {
+	processor := NewSmartProcessor()
+
+	encoded, err := processor.ConvertString("这是一个用于测试集成投票的中文文件内容，作者在这里写了一些文字。", EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatalf("编码转换失败: %v", err)
+	}
+
+	result, err := processor.SmartDetectEncoding([]byte(encoded))
+	if err != nil {
+		t.Fatalf("智能检测失败: %v", err)
+	}
+
+	if result.Details.Method != MethodEnsemble {
+		t.Fatalf("期望检测方法为 %s，实际为 %v", MethodEnsemble, result.Details.Method)
+	}
+
+	votes := result.Details.Votes
+	if len(votes) == 0 {
+		t.Fatalf("期望 Details 中包含投票信息，实际为 %v", votes)
+	}
+
+	if result.Encoding != EncodingGBK && result.Encoding != EncodingGB18030 {
+		t.Errorf("期望检测结果为 GBK 或 GB18030，实际为 %s", result.Encoding)
+	}
+}
+
+// TestDetectContentClass 测试源代码与普通文本的内容类别判断
+func TestDetectContentClass(t *testing.T) {
+	code := "#include <stdio.h>\n/* 注释 */\nint main() {\n\treturn 0;\n}\n"
+	if class := DetectContentClass([]byte(code)); class != ContentClassCode {
+		t.Errorf("期望识别为 code，实际为 %s", class)
+	}
+
+	prose := "这是一段普通的文字。\n今天天气很好\n"
+	if class := DetectContentClass([]byte(prose)); class != ContentClassProse {
+		t.Errorf("期望识别为 prose，实际为 %s", class)
+	}
+}
+
+// TestDetectAllEncodingsBreakdown 测试候选得分组成
+func TestDetectAllEncodingsBreakdown(t *testing.T) {
+	processor := NewSmartProcessor()
+	encoded, err := processor.ConvertString("这是一个用于解释得分组成的中文句子。", EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatalf("编码转换失败: %v", err)
+	}
+
+	candidates, err := processor.(CandidateDetector).DetectAllEncodings([]byte(encoded))
+	if err != nil {
+		t.Fatalf("检测失败: %v", err)
+	}
+
+	for i, candidate := range candidates {
+		if i > 0 && candidate.Score.Total > candidates[i-1].Score.Total {
+			t.Errorf("候选未按得分降序排列: %s", candidate.Encoding)
+		}
+		if candidate.Score.ConversionFailed {
+			continue
+		}
+		sum := candidate.Score.BaseConfidence + candidate.Score.ScriptScore + candidate.Score.ValidityScore +
+			0.1 - candidate.Score.GarbledPenalty + candidate.Score.HintBonus
+		for _, adjustment := range candidate.Score.PostAdjustments {
+			sum += adjustment
+		}
+		if diff := sum - candidate.Score.Total; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("%s 得分组成之和 %.4f 与总分 %.4f 不一致", candidate.Encoding, sum, candidate.Score.Total)
+		}
+	}
+}
+
+// TestRareHanziScorer 测试 Big5 数据被误解码为 GBK 时的罕用字扣分
+func TestRareHanziScorer(t *testing.T) {
+	scorer := NewRareHanziScorer()
+
+	common := scorer.PostScore(nil, "这是一个常用的中文句子")
+	if common != 0 {
+		t.Errorf("常用字文本不应扣分，实际调整 %.3f", common)
+	}
+
+	big5, err := NewDefault().ConvertString("這是一個繁體中文的句子", EncodingUTF8, EncodingBIG5)
+	if err != nil {
+		t.Fatalf("编码转换失败: %v", err)
+	}
+	misdecoded, err := NewDefault().ConvertString(big5, EncodingGBK, EncodingUTF8)
+	if err != nil {
+		t.Fatalf("误解码失败: %v", err)
+	}
+
+	if adjustment := scorer.PostScore(nil, misdecoded); adjustment >= 0 {
+		t.Errorf("误解码文本 %q 应被扣分，实际调整 %.3f", misdecoded, adjustment)
+	}
+}
+
+func TestDetectionRules(t *testing.T) {
+	config := GetDefaultDetectorConfig()
+	config.Rules = []DetectionRule{
+		{Name: "nfo", PathPattern: "*.nfo", Encoding: EncodingISO88591},
+		{Name: "sjis-magic", Prefix: []byte("SJIS:"), Encoding: EncodingShiftJIS, Confidence: 0.9},
+	}
+	detector := NewDetector(config).(*defaultDetector)
+
+	data := []byte("plain ascii text")
+	result, err := detector.DetectEncodingWithPath("/releases/README.NFO", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Encoding != EncodingISO88591 || result.Details.Rule != "nfo" {
+		t.Errorf("Expected nfo rule to apply, got %+v", result)
+	}
+
+	// 无路径时带路径条件的规则不生效
+	result, err = detector.DetectEncoding(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Encoding == EncodingISO88591 {
+		t.Error("Path rule should not apply without a path")
+	}
+
+	for _, detect := range []func([]byte) (*DetectionResult, error){detector.DetectEncoding, detector.SmartDetectEncoding} {
+		result, err = detect([]byte("SJIS:header"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Encoding != EncodingShiftJIS || result.Confidence != 0.9 || result.Details.Method != MethodRule {
+			t.Errorf("Expected prefix rule to apply, got %+v", result)
+		}
+	}
+}
+
+func TestDetectionResultJSON(t *testing.T) {
+	original := &DetectionResult{
+		Encoding:   EncodingGBK,
+		Confidence: 0.92,
+		Details: &DetectionDetails{
+			Method:         MethodEnsemble,
+			Score:          1.25,
+			ScoreBreakdown: &ScoreBreakdown{BaseConfidence: 0.9, Total: 1.25},
+			Votes:          []EnsembleVote{{Method: MethodChardet, Encoding: EncodingGBK, Vote: 1.25, Weight: 1}},
+			Extensions:     map[string]interface{}{"custom": "value"},
+		},
+	}
+
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"schema_version":1`) || !strings.Contains(string(data), `"extensions":{"custom":"value"}`) {
+		t.Errorf("Unexpected serialized form: %s", data)
+	}
+
+	var decoded DetectionResult
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Encoding != EncodingGBK || decoded.Details.Method != MethodEnsemble {
+		t.Errorf("Unexpected decoded result: %+v", decoded)
+	}
+	if len(decoded.Details.Votes) != 1 {
+		t.Errorf("Expected votes after round trip, got %#v", decoded.Details.Votes)
+	}
+	if decoded.Details.ScoreBreakdown == nil || decoded.Details.ScoreBreakdown.Total != 1.25 {
+		t.Errorf("Expected score breakdown after round trip, got %#v", decoded.Details.ScoreBreakdown)
+	}
+
+	// 旧格式（无 schema_version）
+	legacy := []byte(`{"encoding":"UTF-8","confidence":0.99,"details":{"method":"utf8_validation","candidates_count":3,"custom":"value"}}`)
+	if err := json.Unmarshal(legacy, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Encoding != EncodingUTF8 || decoded.Details.Method != "utf8_validation" || decoded.Details.CandidatesCount != 3 {
+		t.Errorf("Unexpected legacy decode: %+v", decoded.Details)
+	}
+	if value, ok := decoded.Details.Get("custom"); !ok || value != "value" {
+		t.Errorf("Expected unknown legacy key in extensions, got %v", value)
+	}
+
+	future := []byte(`{"schema_version":99,"encoding":"UTF-8"}`)
+	if err := json.Unmarshal(future, &decoded); !errors.Is(err, ErrUnsupportedSchemaVersion) {
+		t.Errorf("Expected ErrUnsupportedSchemaVersion, got %v", err)
+	}
+}
+
+func TestAnalyzeBytes(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		family string
+	}{
+		{"ascii", []byte("plain text\n"), ScriptFamilyASCII},
+		{"utf8", []byte("中文文本"), ScriptFamilyUnicode},
+		{"gbk", []byte("\xd6\xd0\xce\xc4\xce\xc4\xb1\xbe"), ScriptFamilyCJK},
+		{"utf16", []byte("t\x00e\x00x\x00t\x00"), ScriptFamilyUTF16},
+		{"latin1", []byte("caf\xe9 au lait"), ScriptFamilyLatin},
+		{"binary", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x01\x00"), ScriptFamilyBinary},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := AnalyzeBytes(tt.data)
+			if stats.Total != len(tt.data) {
+				t.Errorf("Expected total %d, got %d", len(tt.data), stats.Total)
+			}
+			found := false
+			for _, family := range stats.ScriptFamilies {
+				if family == tt.family {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Expected family %s in %v", tt.family, stats.ScriptFamilies)
+			}
+		})
+	}
+
+	stats := AnalyzeBytes([]byte("a\x00\xff"))
+	if stats.NULCount != 1 || stats.HighBit != 1 || stats.Histogram['a'] != 1 {
+		t.Errorf("Unexpected counts: %+v", stats)
+	}
+}
+
+// TestScoreGarbled 测试公开的乱码评分接口与自定义模式
+func TestScoreGarbled(t *testing.T) {
+	if score := ScoreGarbled("正常的中文文本 with ASCII"); score != 0 {
+		t.Errorf("Expected clean text to score 0, got %.2f", score)
+	}
+	if LooksGarbled("正常的中文文本") {
+		t.Error("Expected clean text not to look garbled")
+	}
+
+	garbled := "ÿþ文件��"
+	if !LooksGarbled(garbled) {
+		t.Errorf("Expected %q to look garbled (score %.2f)", garbled, ScoreGarbled(garbled))
+	}
+
+	scorer := NewGarbledScorer(GarbledPattern{Name: "question_marks", Pattern: regexp.MustCompile(`\?{3,}`)})
+	if scorer.Score("??? ???") != 1 || ScoreGarbled("??? ???") != 0 {
+		t.Error("Expected custom pattern to apply only to the custom scorer")
+	}
+}
+
+// TestGarbledPatternLibrary 测试内置乱码模式库与配置的额外模式
+func TestGarbledPatternLibrary(t *testing.T) {
+	for _, text := range []string{"ä¸­æ–‡", "cafÃ©", "锟斤拷锟斤拷", "烫烫烫烫", "浣犲ソ锛屼笘鐣"} {
+		if !LooksGarbled(text) {
+			t.Errorf("Expected %q to look garbled (score %.2f)", text, ScoreGarbled(text))
+		}
+	}
+	for _, text := range []string{"café", "中文，标点。", "naïve résumé", "Hello, world"} {
+		if LooksGarbled(text) {
+			t.Errorf("Expected %q not to look garbled (score %.2f)", text, ScoreGarbled(text))
+		}
+	}
+
+	config := GetDefaultDetectorConfig()
+	config.GarbledPatterns = []GarbledPattern{{Name: "placeholder", Runes: "□", Weight: 0.5}}
+	detector := NewDetector(config).(*defaultDetector)
+	if score := detector.scoreGarbledText("文本□□"); score != 0.5 {
+		t.Errorf("Expected configured pattern to reduce score to 0.5, got %.2f", score)
+	}
+	if score := NewDetector().(*defaultDetector).scoreGarbledText("文本□□"); score != 1 {
+		t.Errorf("Expected default detector to ignore configured pattern, got %.2f", score)
+	}
+}
+
+func TestExtractDeclaredCharset(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		contentType string
+		expected    string
+	}{
+		{"HTTP header", "<html></html>", "text/html; charset=GB2312", EncodingGB2312},
+		{"XML declaration", `<?xml version="1.0" encoding="Shift_JIS"?><root/>`, "", EncodingShiftJIS},
+		{"CSS charset", `@charset "windows-1251"; body {}`, "", EncodingWindows1251},
+		{"HTML meta", `<html><head><meta charset="utf-8"></head>`, "", EncodingUTF8},
+		{"HTML http-equiv", `<meta http-equiv="Content-Type" content="text/html; charset=big5">`, "", EncodingBIG5},
+		{"Python magic comment", "#!/usr/bin/env python\n# -*- coding: cp936 -*-\nprint(1)\n", "", EncodingGBK},
+		{"Ruby magic comment", "# encoding: euc-jp\nputs 1\n", "", EncodingEUCJP},
+		{"Unknown charset", `<meta charset="x-custom">`, "", "x-custom"},
+	}
+
+	for _, test := range tests {
+		declared, ok := ExtractDeclaredCharset([]byte(test.data), test.contentType)
+		if !ok || declared != test.expected {
+			t.Errorf("%s: expected %s, got %q (%v)", test.name, test.expected, declared, ok)
+		}
+	}
+
+	// 魔法注释只在前两行有效
+	if declared, ok := ExtractDeclaredCharset([]byte("a = 1\nb = 2\n# coding: gbk\n"), ""); ok {
+		t.Errorf("Expected no declaration after line two, got %s", declared)
+	}
+}
+
+func TestDeclarationHint(t *testing.T) {
+	gbk, err := NewDefault().Convert([]byte("# -*- coding: gbk -*-\nprint('中文测试')\n"), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := NewDetector().DetectEncoding(gbk)
+	if err != nil {
+		t.Fatalf("DetectEncoding failed: %v", err)
+	}
+	if result.Encoding != EncodingGBK || result.Details.Method != MethodDeclaration {
+		t.Errorf("Expected GBK from declaration, got %s via %s", result.Encoding, result.Details.Method)
+	}
+
+	// 声明与内容不符时忽略声明
+	stale, err := NewDefault().Convert([]byte("<meta charset=\"utf-8\">中文测试内容"), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := GetDefaultDetectorConfig()
+	config.MinConfidence = 0
+	if result, err := NewDetector(config).DetectEncoding(stale); err == nil && result.Details != nil && result.Details.Method == MethodDeclaration {
+		t.Errorf("Expected mismatching declaration to be ignored, got %s", result.Encoding)
+	}
+
+	// 单字节编码能解码任何数据，双字节文本误标为单字节编码时不采用声明
+	mislabelled, err := NewDefault().Convert([]byte("<meta charset=\"iso-8859-1\">中文测试内容，声明的字符集是错误的"), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := NewDetector(config).DetectEncoding(mislabelled); err == nil && result.Encoding == EncodingISO88591 {
+		t.Errorf("Expected single-byte declaration on GBK text to be ignored, got %s via %s", result.Encoding, result.Details.Method)
+	}
+
+	latin1, err := NewDefault().Convert([]byte("<meta charset=\"iso-8859-1\">Café crème brûlée, naïve résumé"), EncodingUTF8, EncodingISO88591)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = NewDetector(config).DetectEncoding(latin1)
+	if err != nil || result.Encoding != EncodingISO88591 || result.Details.Method != MethodDeclaration {
+		t.Errorf("Expected ISO-8859-1 from declaration, got %+v (%v)", result, err)
+	}
+}
+
+// TestDetectWithContentHints 测试按 WHATWG 顺序结合编码提示检测编码
+func TestDetectWithContentHints(t *testing.T) {
+	page, err := NewDefault().Convert([]byte("<html><head><meta charset=\"gb2312\"></head><body>中文网页内容</body></html>"), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 传输层声明与内容不符时采用文档内的 meta 声明（gb2312 按 WHATWG 解析为 GBK）
+	detector := NewDetector()
+	result, err := detector.(ContentHintDetector).DetectWithContentHints(page, "text/html; charset=utf-8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Encoding != EncodingGBK || result.Details.HintSource != HintSourceMeta || result.Details.Method != MethodDeclaration {
+		t.Errorf("Expected GBK from meta, got %s via %s", result.Encoding, result.Details.HintSource)
+	}
+	if hints := result.Details.Hints; len(hints) != 2 || hints[0].Source != HintSourceHeader || hints[0].Accepted || !hints[1].Accepted {
+		t.Errorf("Unexpected hints %+v", hints)
+	}
+
+	// 传输层声明优先
+	latin := []byte("<p>caf\xe9</p>")
+	result, err = detector.(ContentHintDetector).DetectWithContentHints(latin, "text/html; charset=ISO-8859-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Encoding != EncodingWindows1252 || result.Details.HintSource != HintSourceHeader || result.Details.Method != MethodContentType {
+		t.Errorf("Expected WINDOWS-1252 from header, got %s via %s", result.Encoding, result.Details.HintSource)
+	}
+
+	// 误标为旧编码的 UTF-8 按字节特征检测
+	result, err = detector.(ContentHintDetector).DetectWithContentHints([]byte("<meta charset=\"iso-8859-1\">中文网页内容"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Encoding != EncodingUTF8 || result.Details.HintSource != HintSourceHeuristic {
+		t.Errorf("Expected UTF-8 from heuristics, got %s via %s", result.Encoding, result.Details.HintSource)
+	}
+	if hints := result.Details.Hints; len(hints) != 1 || hints[0].Encoding != EncodingWindows1252 || hints[0].Accepted {
+		t.Errorf("Unexpected hints %+v", hints)
+	}
+}
+
+// TestRepairText 测试乱码修复与修复链说明
+func TestRepairText(t *testing.T) {
+	// misdecode 模拟把 actual 编码的字节误按 decodedAs 解码
+	misdecode := func(text, actual, decodedAs string) string {
+		data := []byte(text)
+		if actual != EncodingUTF8 {
+			var err error
+			if data, err = NewDefault().Convert(data, EncodingUTF8, actual); err != nil {
+				t.Fatal(err)
+			}
+		}
+		decoded, err := NewDefault().Convert(data, decodedAs, EncodingUTF8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(decoded)
+	}
+
+	once := misdecode("中文测试", EncodingUTF8, EncodingWindows1252)
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+		steps    int
+	}{
+		{"UTF-8 as Windows-1252", once, "中文测试", 1},
+		{"UTF-8 as GBK", "浣犲ソ", "你好", 1},
+		{"GBK as Latin-1", misdecode("中文", EncodingGBK, EncodingISO88591), "中文", 1},
+		{"double encoded", misdecode(once, EncodingUTF8, EncodingWindows1252), "中文测试", 2},
+	}
+	for _, test := range tests {
+		result := RepairText(test.text)
+		if result.Text != test.expected || len(result.Steps) != test.steps {
+			t.Errorf("%s: expected %q in %d steps, got %q (%s)", test.name, test.expected, test.steps, result.Text, result.Description())
+		}
+		if result.ScoreAfter >= result.ScoreBefore {
+			t.Errorf("%s: expected score to improve, got %.2f -> %.2f", test.name, result.ScoreBefore, result.ScoreAfter)
+		}
+	}
+	if description := RepairText(once).Description(); description != "UTF-8 decoded as WINDOWS-1252" {
+		t.Errorf("Unexpected description %q", description)
+	}
+
+	// 正常文本保持不变
+	for _, text := range []string{"café", "Größe über alles", "正常的中文文本", "こんにちは", "Hello, world"} {
+		if result := RepairText(text); result.Repaired() || result.Text != text {
+			t.Errorf("Expected %q to stay unchanged, got %q (%s)", text, result.Text, result.Description())
+		}
+	}
+
+	// RepairBytes 按原编码输出修复后的数据
+	result, err := RepairBytes([]byte("浣犲ソ"), EncodingUTF8)
+	if err != nil {
+		t.Fatalf("RepairBytes failed: %v", err)
+	}
+	if string(result.Data) != "你好" {
+		t.Errorf("Expected repaired data %q, got %q", "你好", result.Data)
+	}
+}
+
+// TestCustomAlphabet 测试自定义字符表替代内置的字符有效性范围
+func TestCustomAlphabet(t *testing.T) {
+	config := GetDefaultDetectorConfig()
+	config.Alphabets = []Alphabet{{
+		Name:   "scientific",
+		Ranges: []*unicode.RangeTable{ASCIIPrintable, unicode.Greek},
+		Runes:  "°±µ",
+	}}
+	detector := NewDetector(config).(*defaultDetector)
+	if score := detector.scoreCharacterValidity("α=0.05, 25.3°C\n"); score != 1 {
+		t.Errorf("Expected scientific text to be fully valid, got %.2f", score)
+	}
+	if score := detector.scoreCharacterValidity("中文"); score != 0 {
+		t.Errorf("Expected Chinese text to be invalid for the alphabet, got %.2f", score)
+	}
+
+	text := "Sample,Temp,Size,Tol\nA1,25.3°C,1.20µm,±0.05\nA2,26.1°C,1.35µm,±0.07\n"
+	data, err := NewDefault().Convert([]byte(text), EncodingUTF8, EncodingISO88591)
+	if err != nil {
+		t.Fatal(err)
+	}
+	candidates, err := NewDetector(config).(CandidateDetector).DetectAllEncodings(data)
+	if err != nil {
+		t.Fatalf("DetectAllEncodings failed: %v", err)
+	}
+	best := candidates[0]
+	if best.Encoding != EncodingISO88591 || best.ConvertedText != text || best.Score.ValidityScore != 0.2 {
+		t.Errorf("Expected ISO-8859-1 with full validity, got %s (validity %.2f)", best.Encoding, best.Score.ValidityScore)
+	}
+	for _, candidate := range candidates[1:] {
+		// 与 ISO-8859-1 在这些字节上映射相同的编码（如 ISO-8859-9）得到相同文本，不参与比较
+		if candidate.ConvertedText == text {
+			continue
+		}
+		if !candidate.Score.ConversionFailed && candidate.Score.ValidityScore >= best.Score.ValidityScore {
+			t.Errorf("Expected %s to score lower validity, got %.2f", candidate.Encoding, candidate.Score.ValidityScore)
+		}
+	}
+}
+
+// TestTieredCandidateScoring 测试分级评分只对得分最高的候选转换完整样本
+func TestTieredCandidateScoring(t *testing.T) {
+	text := strings.Repeat("这是一段用于测试分级评分的中文内容，包含常见的汉字。\n", 40)
+	data, err := NewDefault().Convert([]byte(text), EncodingUTF8, EncodingGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	full, err := NewDetector().(CandidateDetector).DetectAllEncodings(data)
+	if err != nil {
+		t.Fatalf("DetectAllEncodings failed: %v", err)
+	}
+
+	config := GetDefaultDetectorConfig()
+	config.PrescoreSize = 256
+	config.PrescoreTopK = 1
+	tiered, err := NewDetector(config).(CandidateDetector).DetectAllEncodings(data)
+	if err != nil {
+		t.Fatalf("DetectAllEncodings failed: %v", err)
+	}
+	if len(tiered) < 2 {
+		t.Fatalf("Expected several candidates, got %d", len(tiered))
+	}
+
+	if tiered[0].Encoding != full[0].Encoding || tiered[0].ConvertedText != full[0].ConvertedText {
+		t.Errorf("Expected top candidate %s to be scored on the full sample, got %s", full[0].Encoding, tiered[0].Encoding)
+	}
+	for _, candidate := range tiered[1:] {
+		if len(candidate.ConvertedText) >= len(full[0].ConvertedText) {
+			t.Errorf("Expected %s to be scored on the slice only, got %d bytes", candidate.Encoding, len(candidate.ConvertedText))
+		}
+	}
+}
+
+func TestFailureSampling(t *testing.T) {
+	var buf strings.Builder
+	config := GetDefaultDetectorConfig()
+	config.EnableCache = false
+	config.MinConfidence = 0.99
+	config.FailureSampling = &FailureSamplingConfig{
+		Sink:          NewJSONLinesSampleSink(&buf),
+		MaxSampleSize: 8,
+		Hash:          true,
+		Anonymize:     true,
+	}
+	detector := NewDetector(config)
+
+	// 检测成功且置信度足够的数据不采集
+	if _, err := detector.DetectEncoding([]byte("plain ascii text")); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("Expected no sample for confident detection, got %s", buf.String())
+	}
+
+	data := []byte{'A', 'b', '1', 0x81, 0xfe, 0xc3, 0x28, 0xa0, 0xff, 0x9d}
+	if _, err := detector.DetectEncoding(data); err == nil {
+		t.Fatal("Expected detection failure")
+	}
+
+	var sample FailureSample
+	if err := json.Unmarshal([]byte(buf.String()), &sample); err != nil {
+		t.Fatalf("Expected one JSON line, got %q: %v", buf.String(), err)
+	}
+	if sample.Size != len(data) || sample.Error == "" || len(sample.Hash) != 64 {
+		t.Errorf("Unexpected sample %+v", sample)
+	}
+	if string(sample.Data) != "Xx0\x81\xfe\xc3(\xa0" {
+		t.Errorf("Expected truncated anonymized data, got %q", sample.Data)
+	}
+
+	// 双字节编码中 ASCII 范围的尾字节保持不变，UTF-8 中的 ASCII 字母照常脱敏
+	anonymize := &FailureSamplingConfig{Anonymize: true, MaxSampleSize: 6}
+	for _, tc := range []struct {
+		data, want string
+	}{
+		{"ab\xb3\x6fcd", "xx\xb3\x6fxx"}, // Big5
+		{"A1\x83\x41B", "X0\x83\x41X"},   // Shift_JIS
+		{"中ab", "中xx"},                   // UTF-8
+		{"中a中", "中x\xe4\xb8"},            // 截断的 UTF-8
+	} {
+		if got := captureSample([]byte(tc.data), anonymize); string(got) != tc.want {
+			t.Errorf("captureSample(%q) = %q, want %q", tc.data, got, tc.want)
+		}
+	}
+}
+
+func TestBruteForceDecode(t *testing.T) {
+	for _, tc := range []struct {
+		text     string
+		encoding string
+	}{
+		{"损坏的重要字符串：客户名称", EncodingGBK},
+		{"Привет, мир", EncodingWindows1251},
+	} {
+		data, err := NewDefault().Convert([]byte(tc.text), EncodingUTF8, tc.encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs := BruteForceDecode(data, EncodingUTF8)
+		if len(outputs) < 30 {
+			t.Fatalf("Expected a candidate per supported encoding, got %d", len(outputs))
+		}
+
+		// 排名第一的候选恢复出原文，解码结果相同的编码合并在一起
+		best := outputs[0]
+		names := append([]string{best.Encoding}, best.Equivalent...)
+		if best.Text != tc.text || string(best.Output) != tc.text || !slices.Contains(names, tc.encoding) {
+			t.Errorf("Unexpected best candidate %s %v: %q", best.Encoding, best.Equivalent, best.Preview)
+		}
+	}
+
+	// 输出按目标编码编码，无法表示的字符计入 LostRunes
+	data, _ := NewDefault().Convert([]byte("Привет"), EncodingUTF8, EncodingWindows1251)
+	for _, output := range BruteForceDecode(data, EncodingISO88591) {
+		if output.Encoding == EncodingWindows1251 && output.LostRunes["П"] != 1 {
+			t.Errorf("Expected lost runes for %s, got %v", output.Encoding, output.LostRunes)
+		}
+	}
+}
+
+// TestScoringLanguageModels 测试可配置的评分权重和语言模型
+func TestScoringLanguageModels(t *testing.T) {
+	data, err := NewDefault().Convert([]byte("Привет, мир! Это проверка кодировки для русского текста."), EncodingUTF8, EncodingWindows1251)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 默认只有中文、日文、韩文模型，加入西里尔文模型后俄文文本才能获得文字得分
+	config := GetDefaultDetectorConfig()
+	config.Scoring = GetDefaultScoringConfig()
+	config.Scoring.Models = append(config.Scoring.Models, CyrillicLanguageModel(), WesternEuropeanLanguageModel())
+	result, err := NewDetector(config).SmartDetectEncoding(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Encoding != EncodingWindows1251 {
+		t.Errorf("Expected %s with the Cyrillic model, got %s", EncodingWindows1251, result.Encoding)
+	}
+
+	// 模型得分：目标文字占比与常用字符覆盖率
+	for _, tc := range []struct {
+		model *LanguageModel
+		text  string
+	}{
+		{JapaneseLanguageModel(), "これは日本語のテキストです"},
+		{KoreanLanguageModel(), "이것은 한국어 텍스트입니다"},
+		{CyrillicLanguageModel(), "Это русский текст"},
+		{WesternEuropeanLanguageModel(), "Ceci est un texte français"},
+	} {
+		if score := tc.model.Score(tc.text); score < 0.5 {
+			t.Errorf("Expected %s model to accept %q, got %.2f", tc.model.Name, tc.text, score)
+		}
+		if score := ChineseLanguageModel().Score(tc.text); tc.model.Name != LanguageJapanese && score != 0 {
+			t.Errorf("Expected Chinese model to reject %q, got %.2f", tc.text, score)
+		}
+	}
+}
+
+// TestJapaneseKoreanHeuristics 测试日文、韩文文件名的字节特征启发式
+func TestJapaneseKoreanHeuristics(t *testing.T) {
+	d := NewDetector().(*defaultDetector)
+	for _, tc := range []struct {
+		name     string
+		encoding string
+	}{
+		{"議事録.txt", EncodingShiftJIS},
+		{"写真フォルダ", EncodingShiftJIS},
+		{"これは日本語のファイルです.txt", EncodingShiftJIS},
+		{"회의록.txt", EncodingEUCKR},
+		{"사진", EncodingEUCKR},
+		{"会议纪要.txt", EncodingGBK},
+		{"（暗恋）《时擦》作者：笙离.txt", EncodingGBK},
+	} {
+		data, err := NewDefault().Convert([]byte(tc.name), EncodingUTF8, tc.encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := d.SmartDetectEncoding(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Encoding != tc.encoding && !(tc.encoding == EncodingGBK && result.Encoding == EncodingGB18030) {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.encoding, result.Encoding)
+		}
+	}
+
+	// 假名和谚文的字节特征
+	sjis, _ := NewDefault().Convert([]byte("ひらがなとカタカナ"), EncodingUTF8, EncodingShiftJIS)
+	eucjp, _ := NewDefault().Convert([]byte("ひらがなとカタカナ"), EncodingUTF8, EncodingEUCJP)
+	euckr, _ := NewDefault().Convert([]byte("한국어 텍스트"), EncodingUTF8, EncodingEUCKR)
+	gbk, _ := NewDefault().Convert([]byte("这是一段中文文本"), EncodingUTF8, EncodingGBK)
+	if !d.containsJapaneseBytes(sjis) || !d.containsJapaneseBytes(eucjp) || d.containsJapaneseBytes(euckr) {
+		t.Error("Unexpected Japanese byte heuristic result")
+	}
+	if !d.containsKoreanBytes(euckr) || d.containsKoreanBytes(gbk) || d.containsKoreanBytes(sjis) {
+		t.Error("Unexpected Korean byte heuristic result")
+	}
+}
@@ -17,6 +17,7 @@ type defaultStreamProcessor struct {
 	processor Processor
 	config    *ProcessorConfig
 	bufferPool sync.Pool
+	lifecycle lifecycle
 }
 
 // streamReader 包装转换后的读取器
@@ -57,22 +58,54 @@ func NewStreamProcessor(config *ProcessorConfig) StreamProcessor {
 
 // ProcessReader 处理输入流
 func (sp *defaultStreamProcessor) ProcessReader(ctx context.Context, r io.Reader, sourceEncoding, targetEncoding string) (io.Reader, error) {
+	if err := sp.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer sp.lifecycle.release()
+
 	if sourceEncoding == "" {
 		// 需要自动检测编码，先读取样本
 		return sp.processReaderWithDetection(ctx, r, targetEncoding)
 	}
 
 	// 直接创建转换读取器
-	return sp.createTransformReader(r, sourceEncoding, targetEncoding)
+	return sp.createTransformReader(r, sourceEncoding, targetEncoding, nil)
 }
 
 // ProcessWriter 创建转换写入器
 func (sp *defaultStreamProcessor) ProcessWriter(ctx context.Context, w io.Writer, sourceEncoding, targetEncoding string) (io.Writer, error) {
+	if err := sp.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer sp.lifecycle.release()
+
 	return sp.createTransformWriter(w, sourceEncoding, targetEncoding)
 }
 
 // ProcessReaderWriter 处理读写流
 func (sp *defaultStreamProcessor) ProcessReaderWriter(ctx context.Context, r io.Reader, w io.Writer, options *StreamOptions) (*StreamResult, error) {
+	if err := sp.lifecycle.acquire(); err != nil {
+		return nil, err
+	}
+	defer sp.lifecycle.release()
+
+	ctx, span := startSpan(ctx, sp.config, SpanProcessStream)
+	start := time.Now()
+	result, err := sp.processReaderWriter(ctx, r, w, options)
+	sp.recordStream(ctx, start, result, err)
+	if err == nil {
+		span.SetAttributes(
+			SpanAttribute{Key: AttributeSourceEncoding, Value: result.SourceEncoding},
+			SpanAttribute{Key: AttributeTargetEncoding, Value: result.TargetEncoding},
+			SpanAttribute{Key: AttributeBytes, Value: result.BytesRead},
+		)
+	}
+	endSpan(span, err)
+	return result, err
+}
+
+// processReaderWriter 按数据块转换读写流（调用方需已持有生命周期）
+func (sp *defaultStreamProcessor) processReaderWriter(ctx context.Context, r io.Reader, w io.Writer, options *StreamOptions) (*StreamResult, error) {
 	if options == nil {
 		options = &StreamOptions{
 			TargetEncoding:      EncodingUTF8,
@@ -81,44 +114,115 @@ func (sp *defaultStreamProcessor) ProcessReaderWriter(ctx context.Context, r io.
 			StrictMode:          false,
 		}
 	}
+	if source, target := canonicalEncodingName(options.SourceEncoding), canonicalEncodingName(options.TargetEncoding); source != options.SourceEncoding || target != options.TargetEncoding {
+		resolved := *options
+		resolved.SourceEncoding, resolved.TargetEncoding = source, target
+		options = &resolved
+	}
 
 	start := time.Now()
 	var bytesRead, bytesWritten int64
 	var sourceEncoding string
 	var errorCount int
+	var memory MemoryUsage
+	var quality streamQuality
+
+	// 目标编码带 BOM 时只在第一个数据块前写入 BOM，后续数据块按 continuationEncoding 转换；
+	// 源编码同理，首块之后不再检测 BOM
+	chunkTarget := options.TargetEncoding
+	var chunkSource string
+
+	// 数据块末尾不完整的多字节字符留到下一块转换
+	var pending []byte
+
+	// 有状态编码整个流共用一个转换器
+	var transcoder *statefulTranscoder
+
+	// 启用 NormalizeLineEndings 时跨数据块统一换行符（数据块末尾的 CR 留到下一块判断是否为 CRLF）
+	var normalizer *lineEndingTransformer
+	// 第一个数据块去除源编码 BOM，第一个输出块按 BOM 策略处理目标编码 BOM
+	var bom *chunkBOM
+	if c, ok := sp.converter(); ok {
+		normalizer = c.lineEndingNormalizer(options.TargetEncoding)
+		bom = &chunkBOM{converter: c}
+	}
 
 	// 如果需要自动检测编码
 	if options.SourceEncoding == "" {
-		detected, sample, err := sp.detectEncodingFromStream(r, options.DetectionSampleSize)
+		detected, sample, err := sp.detectEncodingFromStream(ctx, r, sp.limitToMemory(options.DetectionSampleSize))
 		if err != nil {
 			return nil, fmt.Errorf("failed to detect encoding from stream: %w", err)
 		}
 		sourceEncoding = detected
+		chunkSource = detected
+		memory.InputBytes += int64(cap(sample))
+		if transcoder, err = sp.newStatefulTranscoder(sourceEncoding, options.TargetEncoding, options.StrictMode); err != nil {
+			return nil, err
+		}
 		
 		// 先写入检测样本
 		if len(sample) > 0 {
-			convertedSample, err := sp.processor.Convert(sample, sourceEncoding, options.TargetEncoding)
+			bytesRead += int64(len(sample))
+			split := len(sample)
+			if transcoder == nil {
+				split = completePrefix(sample, chunkSource)
+			}
+			pending = bytes.Clone(sample[split:])
+			sample = sample[:split]
+
+			var trace conversionTrace
+			var convertedSample []byte
+			body := bom.source(sample, chunkSource)
+			if transcoder != nil {
+				convertedSample, err = transcoder.convert(body, false, &trace)
+			} else {
+				convertedSample, err = sp.convertChunk(body, chunkSource, chunkTarget, options.StrictMode, &trace)
+			}
+			memory.observeChunk(trace.usage)
+			quality.observeSource(sample, &trace)
+			if !options.StrictMode {
+				if err := checkErrorThreshold(quality.errors(), int64(len(sample)), options.MaxErrors, options.MaxErrorRate); err != nil {
+					return nil, fmt.Errorf("conversion aborted in detection sample: %w", err)
+				}
+			}
 			if err != nil {
 				if !options.StrictMode {
 					errorCount++
 				} else {
+					streamErrorPosition(err, int64(len(sample)-len(body)))
 					return nil, fmt.Errorf("failed to convert detection sample: %w", err)
 				}
 			} else {
+				convertedSample = bom.output(convertedSample, options.TargetEncoding)
+				if normalizer != nil {
+					convertedSample = normalizer.normalizeChunk(convertedSample, false)
+				}
 				n, err := w.Write(convertedSample)
 				if err != nil {
 					return nil, fmt.Errorf("failed to write converted sample: %w", err)
 				}
 				bytesWritten += int64(n)
+				quality.observeOutput(convertedSample[:n], options.TargetEncoding)
+				if n > 0 {
+					chunkTarget = continuationEncoding(chunkTarget, convertedSample)
+				}
+			}
+			if len(sample) > 0 {
+				chunkSource = continuationEncoding(chunkSource, sample)
 			}
-			bytesRead += int64(len(sample))
 		}
 	} else {
 		sourceEncoding = options.SourceEncoding
+		chunkSource = sourceEncoding
+		var err error
+		if transcoder, err = sp.newStatefulTranscoder(sourceEncoding, options.TargetEncoding, options.StrictMode); err != nil {
+			return nil, err
+		}
 	}
 
 	// 处理剩余数据
-	buffer := make([]byte, options.BufferSize)
+	buffer := make([]byte, sp.streamBufferSize(options.BufferSize))
+	memory.InputBytes += int64(len(buffer))
 	for {
 		select {
 		case <-ctx.Done():
@@ -127,13 +231,44 @@ func (sp *defaultStreamProcessor) ProcessReaderWriter(ctx context.Context, r io.
 		}
 
 		n, err := r.Read(buffer)
-		if n > 0 {
+		if n > 0 || err == io.EOF && (len(pending) > 0 || transcoder != nil) {
 			bytesRead += int64(n)
-			
+
+			// 拼接上一块留下的不完整字符，数据未结束时同样留下本块末尾不完整的字符
+			chunk := buffer[:n]
+			if len(pending) > 0 {
+				chunk = append(pending, chunk...)
+			}
+			split := len(chunk)
+			if err != io.EOF && transcoder == nil {
+				split = completePrefix(chunk, chunkSource)
+			}
+			pending = bytes.Clone(chunk[split:])
+			chunk = chunk[:split]
+
 			// 转换数据
-			converted, convertErr := sp.processor.Convert(buffer[:n], sourceEncoding, options.TargetEncoding)
+			var trace conversionTrace
+			var converted []byte
+			var convertErr error
+			body := bom.source(chunk, chunkSource)
+			if transcoder != nil {
+				converted, convertErr = transcoder.convert(body, err == io.EOF, &trace)
+			} else {
+				converted, convertErr = sp.convertChunk(body, chunkSource, chunkTarget, options.StrictMode, &trace)
+			}
+			memory.observeChunk(trace.usage)
+			quality.observeSource(chunk, &trace)
+			if len(chunk) > 0 {
+				chunkSource = continuationEncoding(chunkSource, chunk)
+			}
+			if !options.StrictMode {
+				if err := checkErrorThreshold(quality.errors(), bytesRead, options.MaxErrors, options.MaxErrorRate); err != nil {
+					return nil, fmt.Errorf("conversion aborted at byte %d: %w", bytesRead, err)
+				}
+			}
 			if convertErr != nil {
 				if options.StrictMode {
+					streamErrorPosition(convertErr, bytesRead-int64(len(body)+len(pending)))
 					return nil, fmt.Errorf("conversion failed at byte %d: %w", bytesRead, convertErr)
 				}
 				errorCount++
@@ -141,12 +276,21 @@ func (sp *defaultStreamProcessor) ProcessReaderWriter(ctx context.Context, r io.
 				continue
 			}
 
+			converted = bom.output(converted, options.TargetEncoding)
+			if normalizer != nil {
+				converted = normalizer.normalizeChunk(converted, false)
+			}
+
 			// 写入转换后的数据
 			written, writeErr := w.Write(converted)
 			if writeErr != nil {
 				return nil, fmt.Errorf("write failed: %w", writeErr)
 			}
 			bytesWritten += int64(written)
+			quality.observeOutput(converted[:written], options.TargetEncoding)
+			if written > 0 {
+				chunkTarget = continuationEncoding(chunkTarget, converted)
+			}
 		}
 
 		if err == io.EOF {
@@ -157,16 +301,219 @@ func (sp *defaultStreamProcessor) ProcessReaderWriter(ctx context.Context, r io.
 		}
 	}
 
+	// 写出暂存的末尾数据
+	var lineEndingsConverted int64
+	if normalizer != nil {
+		if tail := normalizer.normalizeChunk(nil, true); len(tail) > 0 {
+			n, err := w.Write(tail)
+			if err != nil {
+				return nil, fmt.Errorf("write failed: %w", err)
+			}
+			bytesWritten += int64(n)
+			quality.observeOutput(tail[:n], options.TargetEncoding)
+		}
+		lineEndingsConverted = normalizer.converted
+	}
+
+	if memory.PeakBytes < memory.InputBytes {
+		memory.PeakBytes = memory.InputBytes
+	}
+
 	return &StreamResult{
-		BytesRead:      bytesRead,
-		BytesWritten:   bytesWritten,
-		SourceEncoding: sourceEncoding,
-		TargetEncoding: options.TargetEncoding,
-		ProcessingTime: time.Since(start),
-		ErrorCount:     errorCount,
+		BytesRead:            bytesRead,
+		BytesWritten:         bytesWritten,
+		SourceEncoding:       sourceEncoding,
+		TargetEncoding:       options.TargetEncoding,
+		ProcessingTime:       time.Since(start),
+		ErrorCount:           errorCount,
+		Memory:               memory,
+		LinesProcessed:       quality.lines(),
+		CharactersReplaced:   quality.replaced,
+		InvalidSequences:     quality.invalid,
+		SourceBOMSeen:        quality.sourceBOM,
+		TargetBOMSeen:        quality.targetBOM,
+		LostRunes:            quality.lost,
+		LineEndingsConverted: lineEndingsConverted,
+		Warnings:             qualityWarnings(quality.replaced, quality.invalid),
 	}, nil
 }
 
+// Drain 停止接受新的流处理请求，等待进行中的流处理完成后关闭底层处理器
+func (sp *defaultStreamProcessor) Drain(ctx context.Context) error {
+	var closeErr error
+	if err := sp.lifecycle.drain(ctx, func() {
+		closeErr = closeComponent(ctx, sp.processor)
+	}); err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// Close 关闭流处理器并等待进行中的流处理完成
+func (sp *defaultStreamProcessor) Close() error {
+	return sp.Drain(context.Background())
+}
+
+// converter 返回底层的默认转换器（使用自定义处理器时返回 false）
+func (sp *defaultStreamProcessor) converter() (*defaultConverter, bool) {
+	if p, ok := sp.processor.(*defaultProcessor); ok {
+		c, ok := p.converter.(*defaultConverter)
+		return c, ok
+	}
+	return nil, false
+}
+
+// recordStream 向配置的性能监控器记录一次流处理（按上下文中的指标标签记录，成功时同时记录读取的字节数和近似内存占用）
+func (sp *defaultStreamProcessor) recordStream(ctx context.Context, start time.Time, result *StreamResult, err error) {
+	c, ok := sp.converter()
+	if !ok || c.config.Metrics == nil {
+		return
+	}
+	labels := MetricsLabelsFromContext(ctx)
+	recordOperation(c.config.Metrics, OperationProcess, start, err, labels)
+	if err == nil {
+		recordBytes(c.config.Metrics, result.BytesRead, labels)
+		recordMemory(c.config.Metrics, result.Memory, labels)
+	}
+}
+
+// convertChunk 转换流中的单个数据块（不应用末尾换行符策略），并记录该块的近似内存占用和质量统计
+//
+// strict 为 true 时即使转换器配置为非严格模式也按严格模式转换。
+func (sp *defaultStreamProcessor) convertChunk(data []byte, from, to string, strict bool, trace *conversionTrace) ([]byte, error) {
+	if c, ok := sp.converter(); ok {
+		if strict {
+			c = c.withStrictMode()
+		}
+		return c.convertBytes(data, from, to, trace)
+	}
+
+	result, err := sp.processor.Convert(data, from, to)
+	if err == nil {
+		trace.memory().finish(len(data), cap(result))
+	}
+	return result, err
+}
+
+// chunkBOM 分块转换时按与 Convert 相同的规则处理 BOM（nil 表示使用自定义处理器，不处理）
+type chunkBOM struct {
+	converter  *defaultConverter
+	sourceDone bool
+	outputDone bool
+	hadBOM     bool
+}
+
+// source 去除第一个非空数据块开头的源编码 BOM
+func (b *chunkBOM) source(chunk []byte, from string) []byte {
+	if b == nil || b.sourceDone || len(chunk) == 0 {
+		return chunk
+	}
+	b.sourceDone = true
+	chunk, b.hadBOM = b.converter.splitSourceBOM(chunk, from)
+	return chunk
+}
+
+// output 按 BOM 策略处理第一个数据块转换结果开头的目标编码 BOM
+func (b *chunkBOM) output(converted []byte, to string) []byte {
+	if b == nil || b.outputDone || !b.sourceDone {
+		return converted
+	}
+	b.outputDone = true
+	return b.converter.applyOutputBOM(converted, to, b.hadBOM, nil)
+}
+
+// statefulTranscoder 整个流共用一个解码器和编码器的转换器
+//
+// ISO-2022-JP/KR、HZ-GB-2312 的转义状态跨越数据块，逐块新建转换器会丢失状态（解码时丢弃后续数据块的字符，
+// 编码时每块重复写入转义序列）。数据块末尾不完整的字节序列由 transform.Writer 留到下一块。
+type statefulTranscoder struct {
+	writer *transform.Writer
+	output bytes.Buffer
+	trace  conversionTrace
+	name   string
+	closed bool
+}
+
+// newStatefulTranscoder 源编码或目标编码为有状态编码时创建共用的转换器（否则返回 nil）
+//
+// 使用自定义处理器时无法保持转换状态，返回错误而不是逐块转换出错误的结果。
+func (sp *defaultStreamProcessor) newStatefulTranscoder(from, to string, strict bool) (*statefulTranscoder, error) {
+	if !statefulEncodings[canonicalEncodingName(from)] && !statefulEncodings[canonicalEncodingName(to)] {
+		return nil, nil
+	}
+	c, ok := sp.converter()
+	if !ok {
+		return nil, &EncodingError{
+			Op:       OperationConvert,
+			Encoding: fmt.Sprintf("%s->%s", from, to),
+			Err:      fmt.Errorf("streaming a stateful encoding requires the default converter: %w", ErrUnsupportedEncoding),
+		}
+	}
+	if strict {
+		c = c.withStrictMode()
+	}
+
+	t := &statefulTranscoder{name: fmt.Sprintf("%s->%s", from, to)}
+	codec, err := c.newCodecTransformer(from, to, &t.trace)
+	if err != nil {
+		return nil, err
+	}
+	if codec == nil {
+		return nil, nil
+	}
+	t.writer = transform.NewWriter(&t.output, codec)
+	return t, nil
+}
+
+// convert 转换一个数据块，返回目前可以输出的数据；atEOF 为 true 时输出缓冲的数据并结束编码器状态
+//
+// 该块的质量统计和内存占用记录到 trace。
+func (t *statefulTranscoder) convert(chunk []byte, atEOF bool, trace *conversionTrace) ([]byte, error) {
+	if t.closed {
+		return []byte{}, nil
+	}
+	_, err := t.writer.Write(chunk)
+	if err == nil && atEOF {
+		t.closed = true
+		err = t.writer.Close()
+	}
+
+	trace.invalidSequences, trace.replacedChars, trace.lostRunes = t.trace.invalidSequences, t.trace.replacedChars, t.trace.lostRunes
+	t.trace.invalidSequences, t.trace.replacedChars, t.trace.lostRunes = 0, 0, nil
+	if err != nil {
+		t.closed = true
+		return nil, &EncodingError{Op: OperationConvert, Encoding: t.name, Err: err}
+	}
+
+	converted := bytes.Clone(t.output.Bytes())
+	if converted == nil {
+		converted = []byte{}
+	}
+	t.output.Reset()
+	trace.memory().finish(len(chunk), cap(converted))
+	return converted, nil
+}
+
+// streamBufferSize 返回读取缓冲区大小：未指定时使用转换器配置的 BufferSize，并受 MaxMemoryUsage 限制
+func (sp *defaultStreamProcessor) streamBufferSize(requested int) int {
+	size := requested
+	if size <= 0 {
+		size = DefaultBufferSize
+		if c, ok := sp.converter(); ok && c.config.BufferSize > 0 {
+			size = c.config.BufferSize
+		}
+	}
+	return sp.limitToMemory(size)
+}
+
+// limitToMemory 将单次读取的大小限制在转换器配置的 MaxMemoryUsage 以内
+func (sp *defaultStreamProcessor) limitToMemory(size int) int {
+	if c, ok := sp.converter(); ok && c.config.MaxMemoryUsage > 0 && int64(size) > c.config.MaxMemoryUsage {
+		return int(c.config.MaxMemoryUsage)
+	}
+	return size
+}
+
 // processReaderWithDetection 处理需要检测编码的读取器
 func (sp *defaultStreamProcessor) processReaderWithDetection(ctx context.Context, r io.Reader, targetEncoding string) (io.Reader, error) {
 	// 创建缓冲读取器
@@ -188,7 +535,7 @@ func (sp *defaultStreamProcessor) processReaderWithDetection(ctx context.Context
 	}
 
 	// 检测编码
-	result, err := sp.processor.DetectEncoding(sample[:n])
+	result, err := sp.detect(ctx, sample[:n])
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect encoding: %w", err)
 	}
@@ -203,11 +550,11 @@ func (sp *defaultStreamProcessor) processReaderWithDetection(ctx context.Context
 		bufReader,
 	)
 
-	return sp.createTransformReader(multiReader, result.Encoding, targetEncoding)
+	return sp.createTransformReader(multiReader, result.Encoding, targetEncoding, nil)
 }
 
 // detectEncodingFromStream 从流中检测编码
-func (sp *defaultStreamProcessor) detectEncodingFromStream(r io.Reader, sampleSize int) (string, []byte, error) {
+func (sp *defaultStreamProcessor) detectEncodingFromStream(ctx context.Context, r io.Reader, sampleSize int) (string, []byte, error) {
 	sample := make([]byte, sampleSize)
 	n, err := r.Read(sample)
 	if err != nil && err != io.EOF {
@@ -218,7 +565,7 @@ func (sp *defaultStreamProcessor) detectEncodingFromStream(r io.Reader, sampleSi
 		return EncodingUTF8, []byte{}, nil
 	}
 
-	result, err := sp.processor.DetectEncoding(sample[:n])
+	result, err := sp.detect(ctx, sample[:n])
 	if err != nil {
 		return "", nil, err
 	}
@@ -226,78 +573,47 @@ func (sp *defaultStreamProcessor) detectEncodingFromStream(r io.Reader, sampleSi
 	return result.Encoding, sample[:n], nil
 }
 
-// createTransformReader 创建转换读取器
-func (sp *defaultStreamProcessor) createTransformReader(r io.Reader, sourceEncoding, targetEncoding string) (io.Reader, error) {
-	if sourceEncoding == targetEncoding {
-		return r, nil
-	}
-
-	// 获取转换器
-	converter, ok := sp.processor.(*defaultProcessor)
-	if !ok {
-		return nil, fmt.Errorf("invalid processor type")
-	}
-
-	// 获取源编码解码器
-	decoder, err := converter.converter.(*defaultConverter).getDecoder(sourceEncoding)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get decoder for %s: %w", sourceEncoding, err)
+// detect 检测流样本的编码（启用 SmartDetection 时使用智能检测，否则按上下文检测以记录指标标签）
+func (sp *defaultStreamProcessor) detect(ctx context.Context, sample []byte) (*DetectionResult, error) {
+	if sp.config.SmartDetection {
+		return sp.processor.SmartDetectEncoding(sample)
 	}
+	return detectEncodingContext(ctx, sp.processor, sample)
+}
 
-	// 获取目标编码编码器
-	encoder, err := converter.converter.(*defaultConverter).getEncoder(targetEncoding)
+// createTransformReader 创建转换读取器
+//
+// 转换规则与整块转换一致（严格模式、替换字符、编码白名单等）；trace 不为 nil 时记录无效字节序列数和替换字符数。
+func (sp *defaultStreamProcessor) createTransformReader(r io.Reader, sourceEncoding, targetEncoding string, trace *conversionTrace) (io.Reader, error) {
+	transformer, err := sp.streamTransformer(sourceEncoding, targetEncoding, trace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get encoder for %s: %w", targetEncoding, err)
+		return nil, err
 	}
-
-	// 创建转换链
-	var transformer transform.Transformer
-	if sourceEncoding == EncodingUTF8 {
-		transformer = encoder
-	} else if targetEncoding == EncodingUTF8 {
-		transformer = decoder
-	} else {
-		transformer = transform.Chain(decoder, encoder)
+	if transformer == nil {
+		return r, nil
 	}
-
 	return transform.NewReader(r, transformer), nil
 }
 
 // createTransformWriter 创建转换写入器
 func (sp *defaultStreamProcessor) createTransformWriter(w io.Writer, sourceEncoding, targetEncoding string) (io.Writer, error) {
-	if sourceEncoding == targetEncoding {
+	transformer, err := sp.streamTransformer(sourceEncoding, targetEncoding, nil)
+	if err != nil {
+		return nil, err
+	}
+	if transformer == nil {
 		return w, nil
 	}
+	return transform.NewWriter(w, transformer), nil
+}
 
-	// 获取转换器
-	converter, ok := sp.processor.(*defaultProcessor)
+// streamTransformer 通过底层转换器创建流式转换器（源编码和目标编码相同时返回 nil）
+func (sp *defaultStreamProcessor) streamTransformer(sourceEncoding, targetEncoding string, trace *conversionTrace) (transform.Transformer, error) {
+	c, ok := sp.converter()
 	if !ok {
 		return nil, fmt.Errorf("invalid processor type")
 	}
-
-	// 获取源编码解码器
-	decoder, err := converter.converter.(*defaultConverter).getDecoder(sourceEncoding)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get decoder for %s: %w", sourceEncoding, err)
-	}
-
-	// 获取目标编码编码器
-	encoder, err := converter.converter.(*defaultConverter).getEncoder(targetEncoding)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get encoder for %s: %w", targetEncoding, err)
-	}
-
-	// 创建转换链
-	var transformer transform.Transformer
-	if sourceEncoding == EncodingUTF8 {
-		transformer = encoder
-	} else if targetEncoding == EncodingUTF8 {
-		transformer = decoder
-	} else {
-		transformer = transform.Chain(decoder, encoder)
-	}
-
-	return transform.NewWriter(w, transformer), nil
+	return c.newStreamTransformer(sourceEncoding, targetEncoding, trace)
 }
 
 // bytesReaderAt 实现 io.ReaderAt 接口
@@ -353,4 +669,61 @@ func (sw *streamWriter) Write(p []byte) (n int, err error) {
 	}
 
 	return len(p), nil
-}
\ No newline at end of file
+}
+// streamQuality 汇总流处理中各数据块的质量统计
+type streamQuality struct {
+	breaks    int64
+	openLine  bool
+	replaced  int64
+	invalid   int64
+	sourceBOM bool
+	targetBOM bool
+	sawSource bool
+	sawOutput bool
+	lost      map[string]int64
+}
+
+// observeSource 记录一个源数据块的转换统计
+func (q *streamQuality) observeSource(chunk []byte, trace *conversionTrace) {
+	if !q.sawSource && len(chunk) > 0 {
+		q.sourceBOM = hasBOM(chunk)
+		q.sawSource = true
+	}
+	q.replaced += trace.replacedChars
+	q.invalid += trace.invalidSequences
+	q.lost = mergeLostRunes(q.lost, trace.lostRunes)
+}
+
+// observeOutput 记录一个已写入的输出数据块
+func (q *streamQuality) observeOutput(chunk []byte, targetEncoding string) {
+	if len(chunk) == 0 {
+		return
+	}
+	if !q.sawOutput {
+		q.targetBOM = hasBOM(chunk)
+		q.sawOutput = true
+	}
+	q.breaks += int64(countLineBreaks(chunk, targetEncoding))
+	q.openLine = !bytes.HasSuffix(chunk, encodedLineBreak(targetEncoding, '\n'))
+}
+
+// errors 返回替换字符与无效字节序列总数
+func (q *streamQuality) errors() int64 {
+	return q.replaced + q.invalid
+}
+
+// lines 返回输出的行数（末尾没有换行符的最后一行也计入）
+func (q *streamQuality) lines() int64 {
+	if q.openLine {
+		return q.breaks + 1
+	}
+	return q.breaks
+}
+
+// hasBOM 检查数据是否以 UTF-8、UTF-16 或 UTF-32 的 BOM 开头
+func hasBOM(data []byte) bool {
+	return bytes.HasPrefix(data, utf8BOM) ||
+		bytes.HasPrefix(data, []byte{0xFF, 0xFE}) ||
+		bytes.HasPrefix(data, []byte{0xFE, 0xFF}) ||
+		bytes.HasPrefix(data, []byte{0x00, 0x00, 0xFE, 0xFF})
+}
@@ -0,0 +1,76 @@
+package encoding
+
+import "context"
+
+// TracerName 处理器向 TracerProvider 请求追踪器时使用的名称
+const TracerName = "github.com/mirbf/encoding-processor"
+
+// 追踪跨度名称
+const (
+	SpanDetectEncoding = "encoding.DetectEncoding"
+	SpanConvert        = "encoding.Convert"
+	SpanSmartConvert   = "encoding.SmartConvert"
+	SpanProcessFile    = "encoding.ProcessFile"
+	SpanProcessStream  = "encoding.ProcessStream"
+)
+
+// 追踪跨度属性名
+const (
+	AttributeSourceEncoding = "encoding.source"     // 源编码（检测时为检测结果）
+	AttributeTargetEncoding = "encoding.target"     // 目标编码
+	AttributeBytes          = "encoding.bytes"      // 处理的字节数
+	AttributeConfidence     = "encoding.confidence" // 检测置信度
+)
+
+// SpanAttribute 追踪跨度属性（Value 为 string、int64 或 float64）
+type SpanAttribute struct {
+	Key   string
+	Value interface{}
+}
+
+// noopSpan 未配置 TracerProvider 时使用的空跨度
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...SpanAttribute) {}
+func (noopSpan) RecordError(error)              {}
+func (noopSpan) End()                           {}
+
+// startSpan 使用配置的 TracerProvider 开始跨度并设置初始属性（未配置时返回空跨度）
+func startSpan(ctx context.Context, config *ProcessorConfig, name string, attributes ...SpanAttribute) (context.Context, Span) {
+	if config == nil || config.TracerProvider == nil {
+		return ctx, noopSpan{}
+	}
+	ctx, span := config.TracerProvider.Tracer(TracerName).Start(ctx, name)
+	if len(attributes) > 0 {
+		span.SetAttributes(attributes...)
+	}
+	return ctx, span
+}
+
+// endSpan 记录错误（如有）并结束跨度
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// endDetectionSpan 记录检测结果或错误并结束跨度
+func endDetectionSpan(span Span, result *DetectionResult, err error) {
+	if err == nil && result != nil {
+		span.SetAttributes(
+			SpanAttribute{Key: AttributeSourceEncoding, Value: result.Encoding},
+			SpanAttribute{Key: AttributeConfidence, Value: result.Confidence},
+		)
+	}
+	endSpan(span, err)
+}
+
+// conversionAttributes 返回转换跨度的初始属性
+func conversionAttributes(from, to string, size int) []SpanAttribute {
+	return []SpanAttribute{
+		{Key: AttributeSourceEncoding, Value: from},
+		{Key: AttributeTargetEncoding, Value: to},
+		{Key: AttributeBytes, Value: int64(size)},
+	}
+}
@@ -0,0 +1,226 @@
+package encoding
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/mirbf/encoding-processor/converter"
+	"golang.org/x/text/transform"
+)
+
+// utf8BOM UTF-8 编码的 U+FEFF
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// contextTransformer 在每次转换前检查上下文，上下文取消或超时后返回上下文错误
+type contextTransformer struct {
+	ctx context.Context
+	transform.Transformer
+}
+
+// Transform 实现 transform.Transformer 接口
+func (t *contextTransformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	if err := t.ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+	return t.Transformer.Transform(dst, src, atEOF)
+}
+
+// Option NewTransformer 的配置选项
+type Option func(*transformerOptions)
+
+// transformerOptions NewTransformer 的配置
+type transformerOptions struct {
+	replacement string
+	strict      bool
+	bom         string
+}
+
+// WithReplacement 设置目标编码无法表示的字符的替换字符串（默认 "?"）
+func WithReplacement(replacement string) Option {
+	return func(o *transformerOptions) {
+		o.replacement = replacement
+	}
+}
+
+// WithStrict 目标编码无法表示字符时返回错误而不是替换
+func WithStrict() Option {
+	return func(o *transformerOptions) {
+		o.strict = true
+	}
+}
+
+// WithBOM 设置 BOM 处理策略（BOMPreserve、BOMStrip、BOMAdd，默认 BOMPreserve）
+//
+// BOMAdd 仅对 Unicode 目标编码生效。
+func WithBOM(policy string) Option {
+	return func(o *transformerOptions) {
+		o.bom = policy
+	}
+}
+
+// NewTransformer 创建 from 编码到 to 编码的 transform.Transformer
+//
+// 返回的转换器可直接用于 transform.NewReader、transform.NewWriter 等 x/text 管道，
+// 不是并发安全的，每个管道应使用独立的实例。
+func NewTransformer(from, to string, opts ...Option) (transform.Transformer, error) {
+	options := transformerOptions{
+		replacement: DefaultInvalidChar,
+		bom:         BOMPreserve,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	switch options.bom {
+	case BOMPreserve, BOMStrip, BOMAdd:
+	default:
+		return nil, fmt.Errorf("%w: unknown BOM policy %q", ErrInvalidConfiguration, options.bom)
+	}
+
+	c := NewConverter(nil).(*defaultConverter)
+	decoder, err := c.getDecoder(from)
+	if err != nil {
+		return nil, &EncodingError{Op: OperationConvert, Encoding: from, Err: err}
+	}
+	encoder, err := c.getEncoder(to)
+	if err != nil {
+		return nil, &EncodingError{Op: OperationConvert, Encoding: to, Err: err}
+	}
+
+	// UTF-8-BOM、UTF-16、UTF-32 的编码器自行写入 BOM：中间文本的 U+FEFF 一律去除以免重复，
+	// 去除 BOM 时改用不写 BOM 的编码器
+	target := c.resolveName(to)
+	bomless := bomlessEncodingName(target)
+	if bomless != "" && options.bom == BOMStrip {
+		enc, err := converter.Lookup(bomless)
+		if err != nil {
+			return nil, &EncodingError{Op: OperationConvert, Encoding: to, Err: err}
+		}
+		encoder = enc.NewEncoder()
+	}
+
+	transformers := []transform.Transformer{decoder}
+
+	switch {
+	case options.bom == BOMStrip || bomless != "":
+		transformers = append(transformers, &leadingBOMTransformer{})
+	case options.bom == BOMAdd && isUnicodeEncoding(target):
+		transformers = append(transformers, &leadingBOMTransformer{add: true})
+	}
+
+	if !options.strict {
+		replacement, _, err := transform.Bytes(encoder, []byte(options.replacement))
+		replacement = bytes.TrimPrefix(replacement, encodedBOM(target))
+		if err != nil {
+			return nil, fmt.Errorf("%w: replacement %q cannot be encoded in %s", ErrInvalidConfiguration, options.replacement, to)
+		}
+		encoder.Reset()
+		encoder = &replacingEncoder{encoder: encoder, replacement: replacement}
+	}
+	transformers = append(transformers, encoder)
+
+	return transform.Chain(transformers...), nil
+}
+
+// isUnicodeEncoding 检查编码是否为 Unicode 编码（可以携带 BOM）
+func isUnicodeEncoding(name string) bool {
+	switch name {
+	case EncodingUTF8, EncodingUTF8BOM, EncodingUTF16, EncodingUTF16LE, EncodingUTF16BE,
+		EncodingUTF32, EncodingUTF32LE, EncodingUTF32BE:
+		return true
+	}
+	return false
+}
+
+// leadingBOMTransformer 在 UTF-8 中间文本开头去除或补充 U+FEFF
+type leadingBOMTransformer struct {
+	add  bool
+	done bool
+}
+
+// Transform 实现 transform.Transformer 接口
+func (t *leadingBOMTransformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	if !t.done {
+		if len(src) < len(utf8BOM) && !atEOF && bytes.HasPrefix(utf8BOM, src) {
+			return 0, 0, transform.ErrShortSrc
+		}
+
+		hasBOM := bytes.HasPrefix(src, utf8BOM)
+		switch {
+		case hasBOM && !t.add:
+			nSrc = len(utf8BOM)
+		case !hasBOM && t.add:
+			if len(dst) < len(utf8BOM) {
+				return 0, 0, transform.ErrShortDst
+			}
+			nDst = copy(dst, utf8BOM)
+		}
+		t.done = true
+	}
+
+	n := copy(dst[nDst:], src[nSrc:])
+	nDst += n
+	nSrc += n
+	if nSrc < len(src) {
+		err = transform.ErrShortDst
+	}
+	return nDst, nSrc, err
+}
+
+// Reset 实现 transform.Transformer 接口
+func (t *leadingBOMTransformer) Reset() {
+	t.done = false
+}
+
+// replacingEncoder 将目标编码无法表示的字符替换为预先编码的替换字节
+//
+// trace 不为 nil 时统计替换的字符数，无效的 UTF-8 输入计为无效字节序列。
+type replacingEncoder struct {
+	encoder     transform.Transformer
+	replacement []byte
+	substitute  func(r rune) []byte // 按字符生成替换字节（设置后优先于 replacement）
+	trace       *conversionTrace
+}
+
+// Transform 实现 transform.Transformer 接口
+func (e *replacingEncoder) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for {
+		n, m, err := e.encoder.Transform(dst[nDst:], src[nSrc:], atEOF)
+		nDst += n
+		nSrc += m
+		if err == nil || err == transform.ErrShortDst || err == transform.ErrShortSrc {
+			return nDst, nSrc, err
+		}
+
+		// 跳过无法表示的字符并写入替换字节
+		r, size := utf8.DecodeRune(src[nSrc:])
+		if size == 0 {
+			return nDst, nSrc, err
+		}
+		replacement := e.replacement
+		if e.substitute != nil {
+			replacement = e.substitute(r)
+		}
+		if len(dst)-nDst < len(replacement) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], replacement)
+		nSrc += size
+
+		switch {
+		case e.trace == nil:
+		case r == utf8.RuneError && size == 1:
+			e.trace.invalidSequences++
+		case r != utf8.RuneError:
+			// 解码阶段产生的 U+FFFD 已计为无效字节序列
+			e.trace.recordLost(r)
+		}
+	}
+}
+
+// Reset 实现 transform.Transformer 接口
+func (e *replacingEncoder) Reset() {
+	e.encoder.Reset()
+}
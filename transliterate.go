@@ -0,0 +1,167 @@
+package encoding
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mirbf/encoding-processor/converter"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultTransliterationSuffix 默认的 ASCII 音译副本文件名后缀
+const DefaultTransliterationSuffix = ".ascii"
+
+// TransliterationOptions ASCII 音译副本选项
+//
+// 启用后在输出文件旁额外写入一份音译为 ASCII 的副本，供无法显示 CJK 等字符的系统使用。
+// 副本由解码后的源文本生成，沿用同一次检测结果，不会重新检测编码；目标编码无法表示而在输出文件中
+// 被替换的字符仍按音译规则转写。
+type TransliterationOptions struct {
+	// Suffix 副本文件名后缀，插入扩展名之前（默认 ".ascii"，如 a.txt -> a.ascii.txt）
+	Suffix string `json:"suffix,omitempty"`
+
+	// Replacement 无法音译的字符的替换字符串（默认 "?"）
+	Replacement string `json:"replacement,omitempty"`
+
+	// Transliterator 自定义音译（如汉字转拼音），优先于内置规则；返回 false 时使用内置规则
+	Transliterator func(r rune) (string, bool) `json:"-"`
+}
+
+// asciiTransliterations 兼容分解后仍不是 ASCII 的常见字符的音译
+var asciiTransliterations = map[rune]string{
+	'ß': "ss", 'Æ': "AE", 'æ': "ae", 'Ø': "O", 'ø': "o", 'Œ': "OE", 'œ': "oe",
+	'Đ': "D", 'đ': "d", 'Ð': "D", 'ð': "d", 'Ł': "L", 'ł': "l", 'Þ': "Th", 'þ': "th", 'ı': "i",
+	'‘': "'", '’': "'", '‚': "'", '“': "\"", '”': "\"", '„': "\"",
+	'«': "<<", '»': ">>", '‹': "<", '›': ">",
+	'‐': "-", '–': "-", '—': "-", '―': "-", '−': "-",
+	'•': "*", '·': ".", '×': "x", '÷': "/", '⁄': "/",
+	'©': "(c)", '®': "(R)", '€': "EUR", '£': "GBP", '¥': "JPY",
+	'。': ".", '、': ",", '・': ".",
+	'「': "\"", '」': "\"", '『': "\"", '』': "\"",
+	'【': "[", '】': "]", '〔': "[", '〕': "]", '《': "<<", '》': ">>", '〈': "<", '〉': ">",
+}
+
+// TransliterationPath 返回输出文件对应的音译副本路径（suffix 为空时使用 DefaultTransliterationSuffix）
+func TransliterationPath(outputFile, suffix string) string {
+	if suffix == "" {
+		suffix = DefaultTransliterationSuffix
+	}
+	ext := filepath.Ext(outputFile)
+	return strings.TrimSuffix(outputFile, ext) + suffix + ext
+}
+
+// TransliterateASCII 将文本音译为 ASCII：去除拉丁字母的变音符号、全角字符转为半角、
+// 常见连字和标点替换为 ASCII 形式，其余字符替换为 replacement
+func TransliterateASCII(text, replacement string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if s, ok := transliterateRune(r, nil); ok {
+			b.WriteString(s)
+		} else {
+			b.WriteString(replacement)
+		}
+	}
+	return b.String()
+}
+
+// transliterateRune 将单个字符音译为 ASCII（custom 优先），无法音译时返回 false
+func transliterateRune(r rune, custom func(rune) (string, bool)) (string, bool) {
+	if r < utf8.RuneSelf {
+		return string(r), true
+	}
+	if custom != nil {
+		if s, ok := custom(r); ok {
+			return s, true
+		}
+	}
+	if s, ok := asciiTransliterations[r]; ok {
+		return s, true
+	}
+	switch {
+	case r == '\uFEFF':
+		return "", true
+	case unicode.Is(unicode.Zs, r):
+		return " ", true
+	}
+
+	// 兼容分解：é -> e + U+0301、Ａ -> A、ﬁ -> fi
+	var b strings.Builder
+	for _, d := range norm.NFKD.String(string(r)) {
+		switch {
+		case d < utf8.RuneSelf:
+			b.WriteRune(d)
+		case unicode.Is(unicode.Mn, d):
+		default:
+			s, ok := asciiTransliterations[d]
+			if !ok {
+				return "", false
+			}
+			b.WriteString(s)
+		}
+	}
+	if b.Len() == 0 {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// writeTransliteration 由解码后的 UTF-8 文本生成输出文件的 ASCII 音译副本，返回副本路径和无法音译的字符数
+//
+// 文本逐字符流式音译，通过临时文件和重命名原子地写入副本。
+func writeTransliteration(outputFile string, text io.Reader, options *TransliterationOptions) (string, int64, error) {
+	path := TransliterationPath(outputFile, options.Suffix)
+	replacement := options.Replacement
+	if replacement == "" {
+		replacement = converter.DefaultReplacement
+	}
+
+	output, err := createTempFile(path, 0644)
+	if err != nil {
+		return "", 0, &FileOperationError{Op: "transliterate", File: path, Err: err}
+	}
+	tempFile := output.Name()
+	writer := bufio.NewWriter(output)
+	untransliterated, err := transliterateStream(bufio.NewReader(text), writer, replacement, options.Transliterator)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempFile, path)
+	}
+	if err != nil {
+		os.Remove(tempFile)
+		return "", 0, &FileOperationError{Op: "transliterate", File: path, Err: err}
+	}
+	return path, untransliterated, nil
+}
+
+// transliterateStream 逐字符音译 UTF-8 文本，返回无法音译的字符数
+func transliterateStream(r *bufio.Reader, w *bufio.Writer, replacement string, custom func(rune) (string, bool)) (int64, error) {
+	var untransliterated int64
+	for {
+		char, _, err := r.ReadRune()
+		if err == io.EOF {
+			return untransliterated, nil
+		}
+		if err != nil {
+			return untransliterated, err
+		}
+
+		s, ok := transliterateRune(char, custom)
+		if !ok {
+			s = replacement
+			untransliterated++
+		}
+		if _, err := w.WriteString(s); err != nil {
+			return untransliterated, err
+		}
+	}
+}
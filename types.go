@@ -1,6 +1,9 @@
 package encoding
 
-import "time"
+import (
+	"os"
+	"time"
+)
 
 // DetectionResult 编码检测结果结构
 type DetectionResult struct {
@@ -13,8 +16,8 @@ type DetectionResult struct {
 	// Language 检测到的语言（可选）
 	Language string `json:"language,omitempty"`
 
-	// Details 详细信息（可选）
-	Details map[string]interface{} `json:"details,omitempty"`
+	// Details 检测详情（可选）
+	Details *DetectionDetails `json:"details,omitempty"`
 }
 
 // ConvertResult 编码转换结果结构
@@ -33,6 +36,60 @@ type ConvertResult struct {
 
 	// ConversionTime 转换耗时
 	ConversionTime time.Duration `json:"conversion_time"`
+
+	// SourceFinalNewline 源数据是否以换行符结尾
+	SourceFinalNewline bool `json:"source_final_newline"`
+
+	// TargetFinalNewline 转换结果是否以换行符结尾
+	TargetFinalNewline bool `json:"target_final_newline"`
+
+	// PositionMap 源数据与输出数据的位置映射（需配置 PositionMapInterval）
+	PositionMap *PositionMap `json:"position_map,omitempty"`
+
+	// Memory 转换的近似内存占用
+	Memory MemoryUsage `json:"memory"`
+
+	// LostRunes 因目标编码无法表示而被替换或丢弃的字符及其次数
+	LostRunes map[string]int64 `json:"lost_runes,omitempty"`
+
+	// Replacements 替换总数及各处替换在源数据中的字节偏移（没有替换时为 nil）
+	Replacements *ReplacementReport `json:"replacements,omitempty"`
+
+	// Alternative 检测结果存在歧义时按次优候选编码转换的结果（需配置 AlternativeMargin）
+	Alternative *AlternativeConversion `json:"alternative,omitempty"`
+
+	// BOMStripped 源数据的 BOM 是否在输出中被去除
+	BOMStripped bool `json:"bom_stripped,omitempty"`
+
+	// BOMAdded 是否为输出补充了源数据没有的 BOM
+	BOMAdded bool `json:"bom_added,omitempty"`
+
+	// LineEndingsConverted 被统一为目标换行符的换行符数量（需启用 NormalizeLineEndings）
+	LineEndingsConverted int64 `json:"line_endings_converted,omitempty"`
+
+	// Warnings 转换中的非致命问题（如字符被替换、源数据中有无效字节序列）
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// ConvertOptions 单次转换选项（覆盖转换器配置）
+type ConvertOptions struct {
+	// BOMPolicy 输出 BOM 策略（BOMPreserve、BOMStrip、BOMAdd，为空时使用转换器配置）
+	BOMPolicy string `json:"bom_policy,omitempty"`
+}
+
+// AlternativeConversion 次优候选编码的转换结果，供交互式工具同时展示并由用户选择
+type AlternativeConversion struct {
+	// Encoding 次优候选编码
+	Encoding string `json:"encoding"`
+
+	// Data 按次优候选编码转换后的数据
+	Data []byte `json:"-"`
+
+	// Score 次优候选的综合得分
+	Score float64 `json:"score"`
+
+	// Margin 首选编码与次优候选的综合得分差距
+	Margin float64 `json:"margin"`
 }
 
 // StringConvertResult 字符串转换结果
@@ -72,6 +129,12 @@ type StreamOptions struct {
 
 	// StrictMode 严格模式（遇到无法转换字符时报错，默认 false）
 	StrictMode bool `json:"strict_mode"`
+
+	// MaxErrors 非严格模式下允许的最大错误数（替换字符与无效字节序列，0 表示不限制）
+	MaxErrors int64 `json:"max_errors"`
+
+	// MaxErrorRate 非严格模式下允许的最大错误率（错误数 / 已读取字节数，0 表示不限制）
+	MaxErrorRate float64 `json:"max_error_rate"`
 }
 
 // StreamResult 流处理结果
@@ -93,10 +156,40 @@ type StreamResult struct {
 
 	// ErrorCount 转换错误次数
 	ErrorCount int `json:"error_count"`
+
+	// Memory 流处理的近似内存占用（峰值为常驻缓冲区加上最大单块占用）
+	Memory MemoryUsage `json:"memory"`
+
+	// LinesProcessed 输出的行数（按 LF 计数，末尾没有换行符的最后一行也计入）
+	LinesProcessed int64 `json:"lines_processed"`
+
+	// CharactersReplaced 因目标编码无法表示而被替换的字符数
+	CharactersReplaced int64 `json:"characters_replaced"`
+
+	// InvalidSequences 源数据中无法解码的字节序列数
+	InvalidSequences int64 `json:"invalid_sequences"`
+
+	// SourceBOMSeen 源数据是否以 BOM 开头
+	SourceBOMSeen bool `json:"source_bom_seen"`
+
+	// TargetBOMSeen 输出数据是否以 BOM 开头
+	TargetBOMSeen bool `json:"target_bom_seen"`
+
+	// LostRunes 因目标编码无法表示而被替换或丢弃的字符及其次数
+	LostRunes map[string]int64 `json:"lost_runes,omitempty"`
+
+	// LineEndingsConverted 被统一为目标换行符的换行符数量（需启用 NormalizeLineEndings）
+	LineEndingsConverted int64 `json:"line_endings_converted,omitempty"`
+
+	// Warnings 流处理中的非致命问题（如字符被替换、源数据中有无效字节序列）
+	Warnings []Warning `json:"warnings,omitempty"`
 }
 
 // FileProcessOptions 文件处理选项
 type FileProcessOptions struct {
+	// SourceEncoding 源编码（为空时自动检测；指定时跳过检测和置信度检查）
+	SourceEncoding string `json:"source_encoding,omitempty"`
+
 	// TargetEncoding 目标编码（默认 UTF-8）
 	TargetEncoding string `json:"target_encoding"`
 
@@ -115,14 +208,73 @@ type FileProcessOptions struct {
 	// BufferSize 缓冲区大小（字节，默认 8192）
 	BufferSize int `json:"buffer_size"`
 
-	// PreserveMode 是否保持文件权限（默认 true）
+	// PreserveMode 是否保持文件权限（默认 true，包括 setuid、setgid 和 sticky 位）
 	PreserveMode bool `json:"preserve_mode"`
 
+	// OutputMode 输出文件权限（非 0 时优先于 PreserveMode；均未指定时新文件按 0644 创建并受 umask 限制）
+	OutputMode os.FileMode `json:"output_mode,omitempty"`
+
 	// PreserveTime 是否保持文件时间戳（默认 true）
 	PreserveTime bool `json:"preserve_time"`
 
 	// DryRun 试运行模式，不实际修改文件（默认 false）
 	DryRun bool `json:"dry_run"`
+
+	// DiffPreview 试运行时执行转换并在结果中给出转换前后的字符级差异（默认 false）
+	DiffPreview bool `json:"diff_preview,omitempty"`
+
+	// WriteSidecar 是否在输出文件旁写入 .encmeta.json 元数据文件（默认 false）
+	WriteSidecar bool `json:"write_sidecar"`
+
+	// MaxFileSize 覆盖处理器配置的文件大小硬限制（0 表示使用配置值，负数表示无限制）
+	MaxFileSize int64 `json:"max_file_size,omitempty"`
+
+	// StreamingThreshold 覆盖处理器配置的流式处理阈值（0 表示使用配置值，负数表示不切换）
+	StreamingThreshold int64 `json:"streaming_threshold,omitempty"`
+
+	// BOMPolicy 输出文件的 BOM 处理策略（BOMPreserve、BOMStrip、BOMAdd，默认 BOMPreserve）
+	// BOMAdd 仅对 Unicode 目标编码生效；批量处理时通过 BatchOptions.FileOptions 统一去除或补充 BOM
+	BOMPolicy string `json:"bom_policy,omitempty"`
+
+	// ExpectedLanguage 预期的文本语言（ISO 639-1，如 "ru"）；设置后解码文本的语言得分
+	// 低于 MinLanguageScore 时放弃转换，避免以错误的编码提交已知语言的文件
+	ExpectedLanguage string `json:"expected_language,omitempty"`
+
+	// MinLanguageScore 最小语言得分（见 ValidateAsLanguage，0 表示使用默认值 0.75）
+	MinLanguageScore float64 `json:"min_language_score,omitempty"`
+
+	// FallbackToLatin1 检测失败或置信度不足时按 ISO-8859-1 逐字节解码（不丢失数据）而不是报错，
+	// 结果标记为 raw-latin1 passthrough（FileProcessResult.Passthrough），便于流水线继续处理并在之后复查
+	FallbackToLatin1 bool `json:"fallback_to_latin1,omitempty"`
+
+	// AllowBinary 是否处理疑似二进制的文件（默认拒绝并返回 ErrBinaryFile，避免图片等文件
+	// 被按 ISO-8859-1 透传或误检测为单字节编码而损坏）
+	AllowBinary bool `json:"allow_binary,omitempty"`
+
+	// CharsetDeclaration 样式表 @charset 声明和脚本 BOM 的处理策略（CharsetDeclarationPreserve、
+	// CharsetDeclarationRewrite、CharsetDeclarationRemove，默认保持原样；超过 StreamingThreshold 的样式表
+	// 和脚本无法流式改写，返回 ErrInvalidConfiguration）。
+	// 改写时 @charset 声明改为目标编码（UTF-16、UTF-32 由 BOM 标识，删除声明）；改写或删除时
+	// 转换为 UTF-8 的 JS 文件去除开头的 BOM，避免打包工具拼接出错
+	CharsetDeclaration string `json:"charset_declaration,omitempty"`
+
+	// WriteCharsetTag 是否将目标编码写入输出文件的编码扩展属性（Linux 为 user.charset，
+	// macOS 为 com.apple.TextEncoding），供编辑器和 Finder 识别；写入失败时返回错误
+	WriteCharsetTag bool `json:"write_charset_tag,omitempty"`
+
+	// UseCharsetTag 是否参考输入文件的编码扩展属性检测编码（数据能按属性中的编码无损解码时采用）
+	UseCharsetTag bool `json:"use_charset_tag,omitempty"`
+
+	// Provenance 转换来源注释头选项（为 nil 时不插入也不去除注释头；有注释语法的文件超过
+	// StreamingThreshold 时无法流式插入，返回 ErrInvalidConfiguration）
+	Provenance *ProvenanceOptions `json:"provenance,omitempty"`
+
+	// Transliteration ASCII 音译副本选项（为 nil 时不生成副本；试运行时不生成）
+	Transliteration *TransliterationOptions `json:"transliteration,omitempty"`
+
+	// Idempotency 幂等转换选项（为 nil 时不启用）。多个工作者（可能位于不同机器）通过共享存储
+	// 处理同一批文件时，以路径和内容校验和为键，相同内容只转换一次，其余工作者跳过
+	Idempotency *IdempotencyOptions `json:"idempotency,omitempty"`
 }
 
 // FileProcessResult 文件处理结果
@@ -136,6 +288,9 @@ type FileProcessResult struct {
 	// BackupFile 备份文件路径（如果创建了备份）
 	BackupFile string `json:"backup_file,omitempty"`
 
+	// SidecarFile 元数据旁路文件路径（如果写入了旁路文件）
+	SidecarFile string `json:"sidecar_file,omitempty"`
+
 	// SourceEncoding 检测到的源编码
 	SourceEncoding string `json:"source_encoding"`
 
@@ -150,6 +305,155 @@ type FileProcessResult struct {
 
 	// DetectionConfidence 编码检测置信度
 	DetectionConfidence float64 `json:"detection_confidence"`
+
+	// Streamed 是否因超过流式处理阈值而使用了流式处理
+	Streamed bool `json:"streamed,omitempty"`
+
+	// Diff 试运行时转换前后的字符级差异（仅在 DiffPreview 启用时生成）
+	Diff *TextDiff `json:"diff,omitempty"`
+
+	// WouldChange 试运行时实际处理是否会改变文件内容
+	WouldChange bool `json:"would_change,omitempty"`
+
+	// EstimatedSize 试运行时预计的输出字节数（流式处理的大文件按开头样本的转换结果估算）
+	EstimatedSize int64 `json:"estimated_size,omitempty"`
+
+	// LostRunes 因目标编码无法表示而被替换或丢弃的字符及其次数
+	LostRunes map[string]int64 `json:"lost_runes,omitempty"`
+
+	// Passthrough 是否因无法检测编码而按 ISO-8859-1 透传（raw-latin1 passthrough，需要复查）
+	Passthrough bool `json:"passthrough,omitempty"`
+
+	// TransliteratedFile ASCII 音译副本路径（如果生成了副本）
+	TransliteratedFile string `json:"transliterated_file,omitempty"`
+
+	// Untransliterated 音译副本中无法音译而被替换的字符数
+	Untransliterated int64 `json:"untransliterated,omitempty"`
+
+	// AlreadyProcessed 是否因其他工作者已转换相同内容而跳过
+	AlreadyProcessed bool `json:"already_processed,omitempty"`
+
+	// Warnings 文件处理中的非致命问题（如时间戳未能保持、扩展属性丢失、低置信度透传、字符被替换）
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// BatchJob 批量处理中的单个文件任务
+type BatchJob struct {
+	// Path 文件路径
+	Path string `json:"path"`
+
+	// Size 文件大小
+	Size int64 `json:"size"`
+
+	// ModTime 文件修改时间
+	ModTime time.Time `json:"mod_time"`
+
+	// options 目录选项覆盖后的处理选项（为 nil 时使用 BatchOptions.FileOptions）
+	options *FileProcessOptions
+}
+
+// BatchOptions 批量处理选项
+type BatchOptions struct {
+	// FileOptions 单个文件的处理选项（默认与 ProcessFile 相同）
+	FileOptions *FileProcessOptions `json:"file_options,omitempty"`
+
+	// Concurrency 普通文件的并发工作者数量（默认 1），单个文件的错误或 panic 不影响其他文件
+	Concurrency int `json:"concurrency"`
+
+	// Order 调度顺序（默认按输入顺序）
+	Order string `json:"order"`
+
+	// Less 自定义调度比较函数（设置后优先于 Order）
+	Less func(a, b *BatchJob) bool `json:"-"`
+
+	// HugeFileThreshold 超大文件阈值（字节），达到阈值的文件由专用工作者处理，0 表示不区分
+	HugeFileThreshold int64 `json:"huge_file_threshold"`
+
+	// OnFileDone 单个文件处理完成时的回调（可能被多个工作者并发调用）
+	OnFileDone func(result *BatchFileResult) `json:"-"`
+
+	// DirOptionsRoot 目录选项文件的查找边界；非空时读取该目录树中的 .encproc.json，
+	// 按文件所在子树覆盖目标编码、BOM 策略并过滤文件（根目录之外的文件不受影响）
+	DirOptionsRoot string `json:"dir_options_root,omitempty"`
+
+	// Include 目录处理时仅处理匹配的文件（filepath.Match 语法，不区分大小写；
+	// 不含路径分隔符时匹配文件名，否则匹配相对于处理目录的路径）
+	Include []string `json:"include,omitempty"`
+
+	// Exclude 目录处理时不处理匹配的文件（语法同 Include，优先于 Include）
+	Exclude []string `json:"exclude,omitempty"`
+
+	// Extensions 目录处理时仅处理这些扩展名的文件（如 ".txt"，不区分大小写，为空表示不限制）
+	Extensions []string `json:"extensions,omitempty"`
+
+	// MaxDepth 目录处理时的最大遍历深度（1 表示只处理目录下的文件，0 表示不限制）
+	MaxDepth int `json:"max_depth,omitempty"`
+
+	// SkipBinary 目录处理时跳过疑似二进制的文件（按文件开头的样本判断，如图片、可执行文件），
+	// 跳过的文件记录在 BatchResult.Skipped 中
+	SkipBinary bool `json:"skip_binary,omitempty"`
+
+	// Manifest 已转换文件清单（为 nil 时不启用）：大小、修改时间和目标编码与记录一致的文件
+	// 不读取内容直接跳过（结果的 AlreadyProcessed 为 true），转换成功的文件在处理结束时写入清单
+	Manifest *ConversionManifest `json:"-"`
+}
+
+// BatchFileResult 批量处理中单个文件的结果
+type BatchFileResult struct {
+	// Job 文件任务
+	Job *BatchJob `json:"job"`
+
+	// Result 处理结果（失败时为 nil）
+	Result *FileProcessResult `json:"result,omitempty"`
+
+	// Err 处理错误
+	Err error `json:"-"`
+
+	// Error 错误信息
+	Error string `json:"error,omitempty"`
+
+	// ContextBoosted 是否在检测置信度不足后按同目录兄弟文件的主导编码重新处理
+	ContextBoosted bool `json:"context_boosted,omitempty"`
+}
+
+// BatchResult 批量处理结果
+type BatchResult struct {
+	// Results 各文件结果（按完成顺序）
+	Results []*BatchFileResult `json:"results"`
+
+	// SuccessCount 成功处理的文件数
+	SuccessCount int `json:"success_count"`
+
+	// FailureCount 处理失败的文件数
+	FailureCount int `json:"failure_count"`
+
+	// Skipped 被目录选项文件过滤掉的文件、跳过的二进制文件（SkipBinary 或 ErrBinaryFile），
+	// 以及其他工作者正在转换的文件（ErrConversionInProgress）
+	Skipped []string `json:"skipped,omitempty"`
+
+	// Passthrough 因无法检测编码而按 ISO-8859-1 透传、需要复查的文件
+	Passthrough []string `json:"passthrough,omitempty"`
+
+	// Cancelled 因上下文取消而未处理的文件
+	Cancelled []string `json:"cancelled,omitempty"`
+
+	// AlreadyProcessed 因其他工作者已转换相同内容而跳过的文件（计入成功）
+	AlreadyProcessed []string `json:"already_processed,omitempty"`
+
+	// Warnings 成功处理的文件产生的全部警告
+	Warnings []Warning `json:"warnings,omitempty"`
+
+	// TotalBytes 成功处理的字节数
+	TotalBytes int64 `json:"total_bytes"`
+
+	// SourceEncodings 成功处理的文件按源编码统计的数量
+	SourceEncodings map[string]int `json:"source_encodings,omitempty"`
+
+	// LostRunes 所有文件中因目标编码无法表示而被替换或丢弃的字符及其次数
+	LostRunes map[string]int64 `json:"lost_runes,omitempty"`
+
+	// Duration 总耗时
+	Duration time.Duration `json:"duration"`
 }
 
 // ProcessingStats 处理统计信息
@@ -172,9 +476,30 @@ type ProcessingStats struct {
 	// AverageProcessingSpeed 平均处理速度（字节/秒）
 	AverageProcessingSpeed float64 `json:"average_processing_speed"`
 
+	// PeakMemoryUsage 单次操作的最大峰值内存占用（字节）
+	PeakMemoryUsage int64 `json:"peak_memory_usage"`
+
+	// TotalMemoryUsage 所有操作峰值内存占用之和（字节）
+	TotalMemoryUsage int64 `json:"total_memory_usage"`
+
+	// MemoryOperations 记录了内存占用的操作数
+	MemoryOperations int64 `json:"memory_operations"`
+
 	// EncodingDistribution 编码分布统计
 	EncodingDistribution map[string]int64 `json:"encoding_distribution"`
 
+	// LostRunes 因目标编码无法表示而被替换或丢弃的字符及其次数
+	LostRunes map[string]int64 `json:"lost_runes,omitempty"`
+
+	// CacheHits 检测结果缓存命中次数
+	CacheHits int64 `json:"cache_hits"`
+
+	// CacheMisses 检测结果缓存未命中次数（包括条目已过期）
+	CacheMisses int64 `json:"cache_misses"`
+
+	// CacheEvictions 因缓存已满而淘汰的条目数
+	CacheEvictions int64 `json:"cache_evictions"`
+
 	// StartTime 统计开始时间
 	StartTime time.Time `json:"start_time"`
 
@@ -0,0 +1,150 @@
+package encoding
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	"github.com/mirbf/encoding-processor/converter"
+	"golang.org/x/text/encoding"
+)
+
+// ValidationViolation 一处无效字节序列
+type ValidationViolation struct {
+	// Offset 无效字节序列在数据中的字节偏移
+	Offset int64 `json:"offset"`
+
+	// Bytes 无效字节序列（解码器作为一个整体替换为 U+FFFD 的字节）
+	Bytes []byte `json:"bytes"`
+}
+
+// String 返回便于日志记录的描述，如 "offset 12: 81 20"
+func (v ValidationViolation) String() string {
+	return fmt.Sprintf("offset %d: % X", v.Offset, v.Bytes)
+}
+
+// ValidationResult 编码校验结果
+type ValidationResult struct {
+	// Encoding 校验使用的编码
+	Encoding string `json:"encoding"`
+
+	// Valid 数据在该编码下是否完全有效
+	Valid bool `json:"valid"`
+
+	// Size 校验的字节数
+	Size int64 `json:"size"`
+
+	// ViolationCount 无效字节序列的总数（可能多于 Violations 的长度）
+	ViolationCount int `json:"violation_count"`
+
+	// Violations 按偏移递增排列的前 MaxViolations 处无效字节序列
+	Violations []ValidationViolation `json:"violations,omitempty"`
+}
+
+// defaultValidator 默认编码校验器实现
+type defaultValidator struct {
+	config *ValidatorConfig
+}
+
+// NewValidator 创建编码校验器
+func NewValidator(config ...*ValidatorConfig) Validator {
+	cfg := GetDefaultValidatorConfig()
+	if len(config) > 0 && config[0] != nil {
+		cfg = config[0]
+	}
+	return &defaultValidator{config: cfg}
+}
+
+// ValidateBytes 校验数据在指定编码下是否有效
+//
+// 与转换不同，校验不替换也不跳过无效字节，而是报告每处无效字节序列的偏移和内容；
+// 数据中按该编码正确编码的 U+FFFD 不视为无效。
+func (v *defaultValidator) ValidateBytes(data []byte, encodingName string) (*ValidationResult, error) {
+	name := canonicalEncodingName(encodingName)
+	enc, err := converter.Lookup(name)
+	if err != nil {
+		return nil, &EncodingError{Op: OperationValidate, Encoding: encodingName, Err: err}
+	}
+
+	result := &ValidationResult{Encoding: name, Valid: true, Size: int64(len(data))}
+
+	// 整体解码不产生替换字符时数据有效，无需逐字符定位
+	if decoded, err := enc.NewDecoder().Bytes(data); err == nil && !bytes.ContainsRune(decoded, utf8.RuneError) {
+		return result, nil
+	}
+
+	replacement := encodedReplacementChar(enc)
+	decoder := enc.NewDecoder()
+	var runeBuf [2 * utf8.UTFMax]byte
+	for offset := 0; offset < len(data); {
+		nDst, nSrc := decodeOneChar(decoder, runeBuf[:], data[offset:])
+		if nSrc == 0 {
+			nSrc, nDst = 1, copy(runeBuf[:], string(utf8.RuneError))
+		}
+		consumed := data[offset : offset+nSrc]
+		if bytes.ContainsRune(runeBuf[:nDst], utf8.RuneError) && !bytes.Equal(consumed, replacement) {
+			result.Valid = false
+			result.ViolationCount++
+			if v.config.MaxViolations < 0 || len(result.Violations) < v.maxViolations() {
+				result.Violations = append(result.Violations, ValidationViolation{
+					Offset: int64(offset),
+					Bytes:  append([]byte(nil), consumed...),
+				})
+			}
+		}
+		offset += nSrc
+	}
+
+	return result, nil
+}
+
+// ValidateFile 校验文件内容在指定编码下是否有效
+func (v *defaultValidator) ValidateFile(filename, encodingName string) (*ValidationResult, error) {
+	if v.config.MaxFileSize > 0 {
+		info, err := os.Stat(filename)
+		if err != nil {
+			return nil, &FileOperationError{Op: OperationValidate, File: filename, Err: err}
+		}
+		if info.Size() > v.config.MaxFileSize {
+			return nil, &FileOperationError{Op: OperationValidate, File: filename, Err: ErrFileTooLarge}
+		}
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, &FileOperationError{Op: OperationValidate, File: filename, Err: err}
+	}
+
+	result, err := v.ValidateBytes(data, encodingName)
+	if err != nil {
+		if encErr, ok := err.(*EncodingError); ok {
+			encErr.File = filename
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// maxViolations 返回报告的最大无效字节序列数量
+func (v *defaultValidator) maxViolations() int {
+	if v.config.MaxViolations == 0 {
+		return DefaultMaxViolations
+	}
+	return v.config.MaxViolations
+}
+
+// encodedReplacementChar 返回 U+FFFD 在该编码下的字节序列（无法编码时返回 nil）
+//
+// 以 "A" 开头编码后去掉 "A" 的编码结果，排除 BOM 和转义序列等只在文本开头写入的字节。
+func encodedReplacementChar(enc encoding.Encoding) []byte {
+	prefix, err := enc.NewEncoder().Bytes([]byte("A"))
+	if err != nil {
+		return nil
+	}
+	encoded, err := enc.NewEncoder().Bytes([]byte("A\uFFFD"))
+	if err != nil || !bytes.HasPrefix(encoded, prefix) {
+		return nil
+	}
+	return encoded[len(prefix):]
+}
@@ -0,0 +1,100 @@
+package encoding
+
+import "fmt"
+
+// 警告代码（Warning.Code）
+const (
+	WarningTimestampNotPreserved = "timestamp_not_preserved" // 无法恢复输出文件的时间戳
+	WarningXattrDropped          = "xattr_dropped"           // 替换文件时丢失了原有的扩展属性
+	WarningLowConfidence         = "low_confidence"          // 以低于检测阈值的置信度采用了源编码
+	WarningCharactersReplaced    = "characters_replaced"     // 目标编码无法表示的字符被替换或丢弃
+	WarningInvalidSequences      = "invalid_sequences"       // 源数据中有无法解码的字节序列
+)
+
+// Warning 不影响处理结果的非致命问题
+//
+// 警告与错误分开收集：操作本身已经成功，但结果可能与预期不完全一致，调用方可以据此记录日志或复查。
+type Warning struct {
+	// Code 警告代码（Warning* 常量）
+	Code string `json:"code"`
+
+	// Message 警告说明
+	Message string `json:"message"`
+
+	// File 相关文件（与文件无关时为空）
+	File string `json:"file,omitempty"`
+
+	// Count 相关数量（如被替换的字符数，不适用时为 0）
+	Count int64 `json:"count,omitempty"`
+}
+
+// String 实现 fmt.Stringer 接口
+func (w Warning) String() string {
+	if w.File != "" {
+		return fmt.Sprintf("%s: %s: %s", w.Code, w.File, w.Message)
+	}
+	return fmt.Sprintf("%s: %s", w.Code, w.Message)
+}
+
+// qualityWarnings 按替换字符数和无效字节序列数生成转换质量警告
+func qualityWarnings(replaced, invalid int64) []Warning {
+	var warnings []Warning
+	if replaced > 0 {
+		warnings = append(warnings, Warning{
+			Code:    WarningCharactersReplaced,
+			Message: fmt.Sprintf("%d characters not representable in the target encoding were replaced", replaced),
+			Count:   replaced,
+		})
+	}
+	if invalid > 0 {
+		warnings = append(warnings, Warning{
+			Code:    WarningInvalidSequences,
+			Message: fmt.Sprintf("%d invalid byte sequences in the source data were replaced", invalid),
+			Count:   invalid,
+		})
+	}
+	return warnings
+}
+
+// warnings 返回转换质量警告（t 为 nil 时返回 nil）
+func (t *conversionTrace) warnings() []Warning {
+	if t == nil {
+		return nil
+	}
+	return qualityWarnings(t.replacedChars, t.invalidSequences)
+}
+
+// withFile 为警告设置相关文件
+func withFile(warnings []Warning, file string) []Warning {
+	for i := range warnings {
+		warnings[i].File = file
+	}
+	return warnings
+}
+
+// passthroughWarning 按 ISO-8859-1 透传时的低置信度警告
+func passthroughWarning(file string) Warning {
+	return Warning{
+		Code:    WarningLowConfidence,
+		Message: "encoding could not be detected with sufficient confidence; passed through as ISO-8859-1",
+		File:    file,
+	}
+}
+
+// droppedXattrs 返回替换文件时会丢失的扩展属性（文件不存在或无法列出时返回 nil）
+//
+// 启用 WriteCharsetTag 时编码标记会在替换后重新写入，不计入丢失的属性。
+func droppedXattrs(path string, options *FileProcessOptions) []string {
+	names, err := listXattrs(path)
+	if err != nil {
+		return nil
+	}
+	var dropped []string
+	for _, name := range names {
+		if options.WriteCharsetTag && (name == XattrCharset || name == XattrAppleTextEncoding) {
+			continue
+		}
+		dropped = append(dropped, name)
+	}
+	return dropped
+}